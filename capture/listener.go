@@ -0,0 +1,94 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// ListenerMode 是监听端的工作模式。
+type ListenerMode string
+
+const (
+	// ListenerRegular 常规代理:按首包自动识别 HTTP / HTTPS CONNECT / SOCKS5。
+	ListenerRegular ListenerMode = "regular"
+	// ListenerSOCKS5 只接受 SOCKS5 握手。
+	ListenerSOCKS5 ListenerMode = "socks5"
+	// ListenerReverse 反向代理:请求一律转发到固定上游(见 types.ReverseConfig)。
+	ListenerReverse ListenerMode = "reverse"
+	// ListenerTransparent 透明代理(见 types.TransparentConfig)。
+	ListenerTransparent ListenerMode = "transparent"
+)
+
+// ParseListenerMode 校验并返回监听端模式;空串按常规代理处理。
+func ParseListenerMode(s string) (ListenerMode, error) {
+	switch m := ListenerMode(s); m {
+	case "":
+		return ListenerRegular, nil
+	case ListenerRegular, ListenerSOCKS5, ListenerReverse, ListenerTransparent:
+		return m, nil
+	default:
+		return ListenerRegular, fmt.Errorf("unknown listener mode %q (want regular, socks5, reverse or transparent)", s)
+	}
+}
+
+// listenerModeOf 读取配置声明的监听端模式;配置未实现 types.ListenerConfig 时为常规代理。
+func listenerModeOf(config Config) ListenerMode {
+	lc, ok := config.(types.ListenerConfig)
+	if !ok {
+		return ListenerRegular
+	}
+	m, _ := ParseListenerMode(lc.GetListenerMode())
+	return m
+}
+
+// listenerIDOf 读取配置声明的监听端标识;主监听端为空串。
+func listenerIDOf(config Config) string {
+	if lc, ok := config.(types.ListenerConfig); ok {
+		return lc.GetListenerID()
+	}
+	return ""
+}
+
+// reverseTargetOf 读取配置声明的反向代理上游映射;配置未实现 types.ReverseConfig 时为 nil。
+func reverseTargetOf(config Config) *types.ReverseTarget {
+	rc, ok := config.(types.ReverseConfig)
+	if !ok {
+		return nil
+	}
+	return rc.GetReverseTarget()
+}
+
+// listenerConn 为连接附上所属监听端的标识与反向代理上游映射,并透传内层连接的原始目标。
+type listenerConn struct {
+	net.Conn
+	id      string
+	reverse *types.ReverseTarget
+}
+
+func (c *listenerConn) ListenerID() string                  { return c.id }
+func (c *listenerConn) ReverseTarget() *types.ReverseTarget { return c.reverse }
+
+// OriginalDestination 透传被包装连接的原始目标(透明代理模式)。
+func (c *listenerConn) OriginalDestination() string {
+	return types.OriginalDestination(c.Conn)
+}
+
+// CloseWrite 透传半关闭。
+func (c *listenerConn) CloseWrite() error {
+	return types.CloseWrite(c.Conn)
+}
+
+// wrapListener 为附加监听端的连接附上监听端信息;主监听端(id 为空且非反代)原样返回。
+func wrapListener(conn net.Conn, id string, reverse *types.ReverseTarget) net.Conn {
+	if id == "" && reverse == nil {
+		return conn
+	}
+	return &listenerConn{Conn: conn, id: id, reverse: reverse}
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package capture
+
+import (
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+func TestParseListenerMode(t *testing.T) {
+	for s, want := range map[string]ListenerMode{
+		"":            ListenerRegular,
+		"regular":     ListenerRegular,
+		"socks5":      ListenerSOCKS5,
+		"reverse":     ListenerReverse,
+		"transparent": ListenerTransparent,
+	} {
+		if m, err := ParseListenerMode(s); err != nil || m != want {
+			t.Errorf("ParseListenerMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseListenerMode("http"); err == nil {
+		t.Error("unknown mode should be rejected")
+	}
+}
+
+// TestListenerConnForwardsMetadata 锁定监听端包装在透明包装之外、限速包装之内时,
+// 处理器仍能同时拿到监听端标识、反代上游与原始目标。
+func TestListenerConnForwardsMetadata(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	if wrapListener(a, "", nil) != a {
+		t.Fatal("main listener connections should not be wrapped")
+	}
+
+	rt := &types.ReverseTarget{Upstream: &url.URL{Scheme: "http", Host: "127.0.0.1:3000"}}
+	inner := &transparentConn{Conn: a, dst: "198.51.100.1:443"}
+	wrapped := wrapThrottleConn(wrapListener(inner, "lan", rt))
+	if got := types.ListenerID(wrapped); got != "lan" {
+		t.Fatalf("ListenerID = %q", got)
+	}
+	if got := types.ReverseTargetOf(wrapped); got != rt {
+		t.Fatalf("ReverseTargetOf = %v", got)
+	}
+	if got := types.OriginalDestination(wrapped); got != "198.51.100.1:443" {
+		t.Fatalf("OriginalDestination = %q", got)
+	}
+	if got := types.ListenerID(a); got != "" {
+		t.Fatalf("plain conn reported listener %q", got)
+	}
+}
@@ -83,8 +83,16 @@ func (h *SimplePacketHandler) HandleConnection(conn net.Conn, info *types.Connec
 	// 每连接日志属于热路径,降为 Debug,避免高并发时日志写入拖慢连接处理。
 	h.LogDebug("处理新连接: %s -> %s", info.RemoteAddr, info.LocalAddr)
 
-	// 尝试检测协议类型
-	protocol := h.registry.DetectProtocol(connection.GetReader(), h)
+	// 尝试检测协议类型。透明代理的客户端不知道代理存在:首包既不会是代理握手,也未必
+	// 由客户端先发,交给 HTTP 处理器按原始目标限时嗅探分流(TLS / 明文 HTTP / 其余中继)。
+	// 反向代理端口只服务 HTTP(S),同样直接交给 HTTP 处理器;SOCKS5 专用端口不做探测。
+	protocol := "HTTP"
+	switch {
+	case listenerModeOf(h.config) == ListenerSOCKS5:
+		protocol = "SOCKS5"
+	case types.OriginalDestination(conn) == "" && types.ReverseTargetOf(conn) == nil:
+		protocol = h.registry.DetectProtocol(conn, connection.GetReader(), h)
+	}
 	h.LogDebug("检测到协议: %s", protocol)
 
 	// 获取处理器并处理连接
@@ -0,0 +1,377 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"bufio"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+type querySinkFunc func(*flow.DNSQuery)
+
+func (f querySinkFunc) RecordDNSQuery(q *flow.DNSQuery) { f(q) }
+
+// captureQueries 注入一个收集查询记录的接收器,并在用例结束时复位包级设置。
+func captureQueries(t *testing.T) <-chan *flow.DNSQuery {
+	t.Helper()
+	ch := make(chan *flow.DNSQuery, 16)
+	SetQuerySink(querySinkFunc(func(q *flow.DNSQuery) { ch <- q }))
+	t.Cleanup(func() {
+		SetQuerySink(nil)
+		SetHosts(nil)
+		SetUpstream("")
+	})
+	return ch
+}
+
+func nextQuery(t *testing.T, ch <-chan *flow.DNSQuery) *flow.DNSQuery {
+	t.Helper()
+	select {
+	case q := <-ch:
+		return q
+	case <-time.After(5 * time.Second):
+		t.Fatal("查询没有被记录")
+		return nil
+	}
+}
+
+func buildQuery(t *testing.T, id uint16, name string, typ dnsmessage.Type) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	_ = b.StartQuestions()
+	if err := b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// parseAnswers 解析应答,返回应答码与 A / AAAA 地址。
+func parseAnswers(t *testing.T, msg []byte) (dnsmessage.Header, []string) {
+	t.Helper()
+	var m dnsmessage.Message
+	if err := m.Unpack(msg); err != nil {
+		t.Fatalf("应答无法解析: %v", err)
+	}
+	var addrs []string
+	for _, rr := range m.Answers {
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A).String())
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA).String())
+		}
+	}
+	return m.Header, addrs
+}
+
+// startUpstream 启动一个把所有 A 查询解析为 203.0.113.9 的 UDP 上游,返回地址与收到的查询数。
+func startUpstream(t *testing.T) (string, func() int) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	var mu sync.Mutex
+	count := 0
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var m dnsmessage.Message
+			if m.Unpack(buf[:n]) != nil {
+				continue
+			}
+			mu.Lock()
+			count++
+			mu.Unlock()
+			m.Header.Response = true
+			q := m.Questions[0]
+			m.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   &dnsmessage.AResource{A: [4]byte{203, 0, 113, 9}},
+			}}
+			resp, _ := m.Pack()
+			_, _ = pc.WriteTo(resp, from)
+		}
+	}()
+	return pc.LocalAddr().String(), func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	h, err := ParseHosts(`
+# 指向 Sniffy
+192.168.1.10  api.example.com  Static.Example.com.
+::ffff:192.168.1.11 *.cdn.example.com
+fd00::10 api.example.com   # 同一域名可同时给出 v6 地址
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 3 {
+		t.Fatalf("Len = %d", h.Len())
+	}
+	for name, want := range map[string][]string{
+		"api.example.com":      {"192.168.1.10", "fd00::10"},
+		"static.example.com":   {"192.168.1.10"},
+		"API.EXAMPLE.COM.":     {"192.168.1.10", "fd00::10"},
+		"img.cdn.example.com":  {"192.168.1.11"},
+		"a.b.cdn.example.com":  {"192.168.1.11"},
+		"cdn.example.com":      nil,
+		"other.example.com":    nil,
+		"api.example.com.evil": nil,
+	} {
+		addrs, ok := h.Lookup(name)
+		if ok != (want != nil) {
+			t.Errorf("Lookup(%q) ok = %v", name, ok)
+			continue
+		}
+		var got []string
+		for _, a := range addrs {
+			got = append(got, a.String())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Lookup(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	for _, bad := range []string{"192.168.1.10", "not-an-ip host", "10.0.0.1 a*b.example"} {
+		if _, err := ParseHosts(bad); err == nil {
+			t.Errorf("ParseHosts(%q) 应报错", bad)
+		}
+	}
+	if _, ok := (*Hosts)(nil).Lookup("x"); ok {
+		t.Error("nil 覆盖表不应命中")
+	}
+}
+
+func TestParseUpstream(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                "",
+		" 1.1.1.1 ":       "1.1.1.1:53",
+		"1.1.1.1:5353":    "1.1.1.1:5353",
+		"dns.example":     "dns.example:53",
+		"2606:4700::1111": "[2606:4700::1111]:53",
+		"[2606:4700::1]":  "[2606:4700::1]:53",
+	} {
+		if got, err := ParseUpstream(in); err != nil || got != want {
+			t.Errorf("ParseUpstream(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseUpstream("a:b:c"); err == nil {
+		t.Error("无效地址应报错")
+	}
+}
+
+func TestServerOverridesAndForwards(t *testing.T) {
+	queries := captureQueries(t)
+	up, upCount := startUpstream(t)
+	SetUpstream(up)
+	hosts, err := ParseHosts("192.168.1.10 api.example.com\nfd00::10 api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetHosts(hosts)
+
+	srv := NewServer("127.0.0.1:0")
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = srv.Stop() })
+
+	conn, err := net.Dial("udp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	exchangeUDP := func(id uint16, name string, typ dnsmessage.Type) (dnsmessage.Header, []string) {
+		t.Helper()
+		if _, err := conn.Write(buildQuery(t, id, name, typ)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, maxMessageSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parseAnswers(t, buf[:n])
+	}
+
+	// 覆盖表命中:按查询类型取对应族的地址,不经上游。
+	if h, addrs := exchangeUDP(1, "api.example.com.", dnsmessage.TypeA); h.ID != 1 || !h.Authoritative || !slices.Equal(addrs, []string{"192.168.1.10"}) {
+		t.Fatalf("覆盖 A 应答 = %+v %v", h, addrs)
+	}
+	if _, addrs := exchangeUDP(2, "api.example.com.", dnsmessage.TypeAAAA); !slices.Equal(addrs, []string{"fd00::10"}) {
+		t.Fatalf("覆盖 AAAA 应答 = %v", addrs)
+	}
+	if h, addrs := exchangeUDP(3, "api.example.com.", dnsmessage.TypeHTTPS); h.RCode != dnsmessage.RCodeSuccess || len(addrs) != 0 {
+		t.Fatalf("覆盖域名的其它类型应为空结果: %+v %v", h, addrs)
+	}
+	if upCount() != 0 {
+		t.Fatal("命中覆盖表的查询不应转发上游")
+	}
+	q := nextQuery(t, queries)
+	if q.Name != "api.example.com" || q.Type != "A" || q.Transport != "udp" || !q.Overridden || q.RCode != "NOERROR" ||
+		len(q.Answers) != 1 || q.Answers[0].Data != "192.168.1.10" || q.ClientAddr == "" {
+		t.Fatalf("覆盖查询记录 = %+v", q)
+	}
+	nextQuery(t, queries)
+	if q := nextQuery(t, queries); q.Type != "HTTPS" {
+		t.Fatalf("HTTPS 查询记录类型 = %q", q.Type)
+	}
+
+	// 未命中:转发上游并记录上游应答。
+	if h, addrs := exchangeUDP(4, "www.example.org.", dnsmessage.TypeA); h.ID != 4 || !slices.Equal(addrs, []string{"203.0.113.9"}) {
+		t.Fatalf("转发应答 = %+v %v", h, addrs)
+	}
+	q = nextQuery(t, queries)
+	if q.Overridden || q.Upstream != up || len(q.Answers) != 1 || q.Answers[0].TTL != 300 || q.Answers[0].Data != "203.0.113.9" {
+		t.Fatalf("转发查询记录 = %+v", q)
+	}
+
+	// 覆盖表热替换即时生效。
+	SetHosts(nil)
+	if _, addrs := exchangeUDP(5, "api.example.com.", dnsmessage.TypeA); !slices.Equal(addrs, []string{"203.0.113.9"}) {
+		t.Fatalf("清空覆盖表后应转发上游: %v", addrs)
+	}
+	nextQuery(t, queries)
+
+	// TCP 端口与 UDP 同号,按长度前缀收发。
+	tc, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Close()
+	_ = tc.SetDeadline(time.Now().Add(5 * time.Second))
+	SetHosts(hosts)
+	for id := uint16(10); id < 12; id++ { // 同一连接上连续两条查询
+		if err := writeMessage(tc, buildQuery(t, id, "api.example.com.", dnsmessage.TypeA)); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := readMessage(bufio.NewReader(tc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h, addrs := parseAnswers(t, resp); h.ID != id || !slices.Equal(addrs, []string{"192.168.1.10"}) {
+			t.Fatalf("TCP 应答 = %+v %v", h, addrs)
+		}
+		if q := nextQuery(t, queries); q.Transport != "tcp" {
+			t.Fatalf("TCP 查询记录 = %+v", q)
+		}
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Addr() != "" {
+		t.Fatal("停止后 Addr 应为空")
+	}
+}
+
+func TestAnswerWithoutUpstream(t *testing.T) {
+	queries := captureQueries(t)
+
+	h, _ := parseAnswers(t, answer(buildQuery(t, 7, "www.example.org.", dnsmessage.TypeA), nil, "udp"))
+	if h.ID != 7 || h.RCode != dnsmessage.RCodeServerFailure {
+		t.Fatalf("无上游时应答 = %+v", h)
+	}
+	if q := nextQuery(t, queries); q.RCode != "SERVFAIL" || q.Error == "" {
+		t.Fatalf("无上游查询记录 = %+v", q)
+	}
+
+	if answer([]byte{0x01}, nil, "udp") != nil {
+		t.Fatal("残缺消息应被丢弃")
+	}
+	resp := buildQuery(t, 8, "www.example.org.", dnsmessage.TypeA)
+	resp[2] |= 0x80 // QR=1:应答不是查询
+	if answer(resp, nil, "udp") != nil {
+		t.Fatal("应答消息应被丢弃")
+	}
+	select {
+	case q := <-queries:
+		t.Fatalf("丢弃的消息不应被记录: %+v", q)
+	default:
+	}
+}
+
+func TestLooksLikeTCPQuery(t *testing.T) {
+	msg := buildQuery(t, 1, "example.com.", dnsmessage.TypeA)
+	framed := append([]byte{byte(len(msg) >> 8), byte(len(msg))}, msg...)
+	if !LooksLikeTCPQuery(framed) {
+		t.Fatal("DNS over TCP 查询未被识别")
+	}
+	if LooksLikeTCPQuery(make([]byte, 16)) || LooksLikeTCPQuery(framed[:10]) {
+		t.Fatal("非查询数据被误判")
+	}
+}
+
+// TestServerBoundsUDPInflight 同时处理中的 UDP 查询达到上限后,新到的数据报被丢弃而不是
+// 各起一个 goroutine 去查上游。
+func TestServerBoundsUDPInflight(t *testing.T) {
+	prev := maxUDPInflight
+	maxUDPInflight = 2
+	t.Cleanup(func() { maxUDPInflight = prev })
+
+	// 只收不答的上游:每条转发的查询都会占住一个处理名额直到上游超时。
+	up, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { up.Close() })
+	received := make(chan struct{}, 16)
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			if _, _, err := up.ReadFrom(buf); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	}()
+	SetUpstream(up.LocalAddr().String())
+	SetHosts(nil)
+	t.Cleanup(func() { SetUpstream("") })
+
+	srv := NewServer("127.0.0.1:0")
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = srv.Stop() })
+	conn, err := net.Dial("udp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for i := 0; i < 6; i++ {
+		if _, err := conn.Write(buildQuery(t, uint16(i+1), "flood.example.com.", dnsmessage.TypeA)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := len(received); n != 2 {
+		t.Fatalf("转发到上游的查询 = %d, want 2", n)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Hosts 是 DNS 覆盖表:命中的域名不再转发上游,直接以表中的地址应答。
+type Hosts struct {
+	exact    map[string][]netip.Addr
+	wildcard map[string][]netip.Addr // 键为去掉 "*." 的后缀
+}
+
+// ParseHosts 解析 hosts 文件格式的覆盖表:每行 "地址 域名 [域名...]",# 起注释。
+// 域名可写作 *.example.com,匹配 example.com 的任意层级子域(不含裸域本身)。
+// 同一域名出现多次时地址累加,故可为一个域名同时给出 IPv4 与 IPv6 地址。
+func ParseHosts(text string) (*Hosts, error) {
+	h := &Hosts{exact: make(map[string][]netip.Addr), wildcard: make(map[string][]netip.Addr)}
+	for i, line := range strings.Split(text, "\n") {
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("第 %d 行缺少域名: %q", i+1, line)
+		}
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行地址无效: %q", i+1, fields[0])
+		}
+		addr = addr.Unmap()
+		for _, name := range fields[1:] {
+			name = canonicalName(name)
+			if suffix, ok := strings.CutPrefix(name, "*."); ok && suffix != "" {
+				h.wildcard[suffix] = append(h.wildcard[suffix], addr)
+				continue
+			}
+			if name == "" || strings.Contains(name, "*") {
+				return nil, fmt.Errorf("第 %d 行域名无效: %q", i+1, name)
+			}
+			h.exact[name] = append(h.exact[name], addr)
+		}
+	}
+	return h, nil
+}
+
+// Len 返回覆盖表中的域名条目数。
+func (h *Hosts) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.exact) + len(h.wildcard)
+}
+
+// Lookup 返回域名的覆盖地址;ok 为 false 表示未命中。精确条目优先于通配,通配取最长后缀。
+func (h *Hosts) Lookup(name string) (addrs []netip.Addr, ok bool) {
+	if h == nil {
+		return nil, false
+	}
+	name = canonicalName(name)
+	if addrs, ok := h.exact[name]; ok {
+		return addrs, true
+	}
+	for rest := name; ; {
+		i := strings.IndexByte(rest, '.')
+		if i < 0 {
+			return nil, false
+		}
+		rest = rest[i+1:]
+		if addrs, ok := h.wildcard[rest]; ok {
+			return addrs, true
+		}
+	}
+}
+
+// canonicalName 小写并去掉结尾的点。
+func canonicalName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
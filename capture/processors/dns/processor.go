@@ -0,0 +1,56 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package dns 实现内置 DNS 服务端:按 hosts 格式的覆盖表把选定域名解析到指定地址
+// (通常就是 Sniffy 所在主机,配合透明代理拦截设备流量),其余查询转发上游,每次查询
+// 记为一条 flow.DNSQuery。设备只需把 DNS 服务器改成 Sniffy 即可被拦截。
+package dns
+
+import (
+	"encoding/binary"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// Processor 应答代理端口上探测到的 DNS over TCP 连接。
+type Processor struct {
+	conn types.Connection
+}
+
+// New 创建 DNS 处理器。
+func New(conn types.Connection) types.ProtocolProcessor {
+	return &Processor{conn: conn}
+}
+
+// GetProtocolName 返回协议名称。
+func (p *Processor) GetProtocolName() string { return "DNS" }
+
+// Process 依次应答连接上的查询,与独立 DNS 服务端的 TCP 端共用解析逻辑。
+// 内置 DNS 服务端关闭(见 SetProxyPortEnabled)时不应答,直接关闭连接。
+func (p *Processor) Process() error {
+	if !ProxyPortEnabled() {
+		p.conn.GetServer().LogDebug("内置 DNS 未开启,关闭 DNS over TCP 连接")
+		return nil
+	}
+	p.conn.GetServer().LogDebug("开始处理 DNS over TCP 连接")
+	ServeStream(p.conn.GetConn(), p.conn.GetReader())
+	return nil
+}
+
+// LooksLikeTCPQuery 报告 header(连接上的前 14 字节以上)是否像一条 DNS over TCP 标准查询:
+// 长度前缀足以容纳头部与一个问题,QR=0、OPCODE=0,恰好一个问题且没有应答 / 授权段。
+func LooksLikeTCPQuery(header []byte) bool {
+	if len(header) < 14 {
+		return false
+	}
+	msgLen := binary.BigEndian.Uint16(header[0:2])
+	flags := header[4]
+	qd := binary.BigEndian.Uint16(header[6:8])
+	an := binary.BigEndian.Uint16(header[8:10])
+	ns := binary.BigEndian.Uint16(header[10:12])
+	ar := binary.BigEndian.Uint16(header[12:14])
+	return msgLen >= 12+5 && flags&0x80 == 0 && (flags>>3)&0x0f == 0 &&
+		qd == 1 && an == 0 && ns == 0 && ar <= 1
+}
@@ -0,0 +1,347 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+const (
+	// upstreamTimeout 是单次上游查询(含建连)的超时。
+	upstreamTimeout = 5 * time.Second
+	// overrideTTL 是覆盖应答的 TTL:足够短,改了覆盖表后设备很快就会重新查询。
+	overrideTTL = 60
+	// maxMessageSize 是 DNS 消息的上限(TCP 长度前缀所能表示的最大值)。
+	maxMessageSize = 65535
+)
+
+// QuerySink 由 service 实现,解析器经此记录每次查询(消费者定义接口,避免反向依赖)。
+type QuerySink interface {
+	RecordDNSQuery(q *flow.DNSQuery)
+}
+
+var (
+	querySink    atomic.Pointer[QuerySink]
+	hostsTable   atomic.Pointer[Hosts]
+	upstreamAddr atomic.Pointer[string]
+	// proxyPortDNS 为 true 时代理端口上探测到的 DNS over TCP 连接才被应答,随内置 DNS 服务端开关。
+	proxyPortDNS atomic.Bool
+)
+
+// SetQuerySink 注入 DNS 查询接收器(装配层调用);传入 nil 时不再记录。
+func SetQuerySink(s QuerySink) {
+	if s == nil {
+		querySink.Store(nil)
+		return
+	}
+	querySink.Store(&s)
+}
+
+// SetProxyPortEnabled 开关代理端口上的 DNS over TCP 应答;关闭时这类连接被直接关闭。
+func SetProxyPortEnabled(enabled bool) { proxyPortDNS.Store(enabled) }
+
+// ProxyPortEnabled 报告代理端口上的 DNS over TCP 应答是否开启。
+func ProxyPortEnabled() bool { return proxyPortDNS.Load() }
+
+// SetHosts 替换覆盖表,对之后的查询即时生效;nil 表示不覆盖任何域名。
+func SetHosts(h *Hosts) { hostsTable.Store(h) }
+
+// SetUpstream 设置上游解析器地址(host:port,见 ParseUpstream);空串表示不转发,
+// 未命中覆盖表的查询一律应答 SERVFAIL。
+func SetUpstream(addr string) { upstreamAddr.Store(&addr) }
+
+// ParseUpstream 校验并规整上游解析器地址:省略端口时补 53。
+func ParseUpstream(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		// 裸 IPv6 地址含冒号,先按地址解析再补端口。
+		if a, perr := netip.ParseAddr(strings.Trim(s, "[]")); perr == nil {
+			return netip.AddrPortFrom(a, 53).String(), nil
+		}
+		if strings.Contains(s, ":") {
+			return "", fmt.Errorf("上游解析器地址无效: %q", s)
+		}
+		return net.JoinHostPort(s, "53"), nil
+	}
+	return s, nil
+}
+
+func upstream() string {
+	if p := upstreamAddr.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+var errNoUpstream = errors.New("未配置上游解析器")
+
+// answer 应答一条 DNS 查询消息并记录。命中覆盖表的以表中地址应答,其余原样转发到上游,
+// 上游不可用时应答 SERVFAIL。连头部都解析不了的消息返回 nil,调用方直接丢弃。
+func answer(query []byte, client net.Addr, transport string) []byte {
+	start := time.Now()
+	var p dnsmessage.Parser
+	hdr, err := p.Start(query)
+	if err != nil {
+		return nil
+	}
+	if hdr.Response {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil {
+		return errorReply(hdr, nil, dnsmessage.RCodeFormatError)
+	}
+	if hdr.OpCode != 0 {
+		return errorReply(hdr, &q, dnsmessage.RCodeNotImplemented)
+	}
+
+	rec := &flow.DNSQuery{
+		ID:        flow.NewID(),
+		Transport: transport,
+		Name:      canonicalName(q.Name.String()),
+		Type:      typeName(q.Type),
+		StartTime: start,
+		Answers:   []flow.DNSAnswer{},
+	}
+	if client != nil {
+		rec.ClientAddr = client.String()
+	}
+
+	var resp []byte
+	if addrs, ok := hostsTable.Load().Lookup(rec.Name); ok {
+		rec.Overridden = true
+		resp = overrideReply(hdr, q, addrs)
+	} else {
+		rec.Upstream = upstream()
+		resp, err = exchange(rec.Upstream, transport, hdr.ID, query)
+		if err != nil {
+			rec.Error = err.Error()
+			resp = errorReply(hdr, &q, dnsmessage.RCodeServerFailure)
+		}
+	}
+	summarize(rec, resp)
+	rec.DurationMs = time.Since(start).Milliseconds()
+	if s := querySink.Load(); s != nil {
+		(*s).RecordDNSQuery(rec)
+	}
+	return resp
+}
+
+// replyHeader 由查询头派生应答头。
+func replyHeader(q dnsmessage.Header, rcode dnsmessage.RCode) dnsmessage.Header {
+	return dnsmessage.Header{
+		ID:                 q.ID,
+		Response:           true,
+		OpCode:             q.OpCode,
+		RecursionDesired:   q.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	}
+}
+
+// errorReply 构造只含问题段(可为空)的错误应答。
+func errorReply(hdr dnsmessage.Header, q *dnsmessage.Question, rcode dnsmessage.RCode) []byte {
+	b := dnsmessage.NewBuilder(nil, replyHeader(hdr, rcode))
+	if q != nil {
+		_ = b.StartQuestions()
+		_ = b.Question(*q)
+	}
+	msg, _ := b.Finish()
+	return msg
+}
+
+// overrideReply 以覆盖地址构造权威应答:A 查询取 IPv4 地址,AAAA 取 IPv6 地址;其余类型
+// 以及没有对应族地址的查询应答 NOERROR 空结果,设备只会连到覆盖表给出的地址。
+func overrideReply(hdr dnsmessage.Header, q dnsmessage.Question, addrs []netip.Addr) []byte {
+	h := replyHeader(hdr, dnsmessage.RCodeSuccess)
+	h.Authoritative = true
+	b := dnsmessage.NewBuilder(nil, h)
+	b.EnableCompression()
+	_ = b.StartQuestions()
+	_ = b.Question(q)
+	_ = b.StartAnswers()
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Class: q.Class, TTL: overrideTTL}
+	for _, a := range addrs {
+		switch {
+		case q.Type == dnsmessage.TypeA && a.Is4():
+			_ = b.AResource(rh, dnsmessage.AResource{A: a.As4()})
+		case q.Type == dnsmessage.TypeAAAA && a.Is6():
+			_ = b.AAAAResource(rh, dnsmessage.AAAAResource{AAAA: a.As16()})
+		}
+	}
+	msg, _ := b.Finish()
+	return msg
+}
+
+// exchange 把查询原样转发给上游并取回应答,沿用客户端的传输方式:UDP 上被截断的应答
+// 照样回给客户端,由它按 TC 位改走 TCP 重试。
+func exchange(addr, transport string, id uint16, query []byte) ([]byte, error) {
+	if addr == "" {
+		return nil, errNoUpstream
+	}
+	conn, err := net.DialTimeout(transport, addr, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	if transport == "tcp" {
+		if err := writeMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// 丢弃 ID 对不上的迟到或伪造应答。
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return append([]byte(nil), buf[:n]...), nil
+		}
+	}
+}
+
+// readMessage 读取一条带 2 字节长度前缀的 DNS 消息(TCP 传输)。
+func readMessage(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeMessage 以 2 字节长度前缀写出一条 DNS 消息(TCP 传输)。
+func writeMessage(w io.Writer, msg []byte) error {
+	if len(msg) > maxMessageSize {
+		return errors.New("DNS 消息过长")
+	}
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+// summarize 把应答的应答码与应答段摘要写进记录;解析失败时保留已取到的部分。
+func summarize(rec *flow.DNSQuery, resp []byte) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(resp)
+	if err != nil {
+		return
+	}
+	rec.RCode = rcodeName(hdr.RCode)
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			return
+		}
+		a := flow.DNSAnswer{Name: canonicalName(h.Name.String()), Type: typeName(h.Type), TTL: h.TTL}
+		switch h.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return
+			}
+			a.Data = netip.AddrFrom4(r.A).String()
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return
+			}
+			a.Data = netip.AddrFrom16(r.AAAA).String()
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				return
+			}
+			a.Data = canonicalName(r.CNAME.String())
+		case dnsmessage.TypeNS:
+			r, err := p.NSResource()
+			if err != nil {
+				return
+			}
+			a.Data = canonicalName(r.NS.String())
+		case dnsmessage.TypePTR:
+			r, err := p.PTRResource()
+			if err != nil {
+				return
+			}
+			a.Data = canonicalName(r.PTR.String())
+		case dnsmessage.TypeMX:
+			r, err := p.MXResource()
+			if err != nil {
+				return
+			}
+			a.Data = strconv.Itoa(int(r.Pref)) + " " + canonicalName(r.MX.String())
+		case dnsmessage.TypeTXT:
+			r, err := p.TXTResource()
+			if err != nil {
+				return
+			}
+			a.Data = strings.Join(r.TXT, " ")
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return
+			}
+		}
+		rec.Answers = append(rec.Answers, a)
+	}
+}
+
+// typeName 返回记录类型的惯用名(A、AAAA、HTTPS…),未知类型为十进制数值。
+func typeName(t dnsmessage.Type) string {
+	return strings.TrimPrefix(t.String(), "Type")
+}
+
+// rcodeName 返回应答码的惯用名(NOERROR、NXDOMAIN…),未知应答码为十进制数值。
+func rcodeName(r dnsmessage.RCode) string {
+	switch r {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	default:
+		return strconv.Itoa(int(r))
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// tcpIdleTimeout 是 DNS over TCP 连接上两条查询之间的最长空闲时间(RFC 7766 建议为秒级)。
+const tcpIdleTimeout = 10 * time.Second
+
+// maxUDPInflight 限制同时处理中的 UDP 查询数;已满时新到的数据报直接丢弃(客户端会重试),
+// 以免 UDP 洪泛变成无界的 goroutine 与上游查询。
+var maxUDPInflight = 256
+
+// Server 是内置 DNS 服务端:在同一端口上同时监听 UDP 与 TCP,按覆盖表应答或转发上游,
+// 每次查询经 QuerySink 记录。覆盖表、上游与接收器均为包级设置,多个 Server 共用。
+type Server struct {
+	addr   string
+	logger types.Logger
+
+	mu  sync.Mutex
+	udp net.PacketConn
+	tcp net.Listener
+	wg  sync.WaitGroup
+}
+
+// NewServer 创建监听 addr(host:port)的 DNS 服务端;端口为 0 时由系统分配,TCP 随 UDP 取同一端口。
+func NewServer(addr string) *Server {
+	return &Server{addr: addr}
+}
+
+// SetLogger 设置日志器。
+func (s *Server) SetLogger(l types.Logger) { s.logger = l }
+
+// Start 绑定端口并开始服务。
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.udp != nil {
+		return errors.New("DNS 服务端已在运行")
+	}
+	pc, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("DNS 服务端监听 UDP %s 失败: %w", s.addr, err)
+	}
+	host, _, _ := net.SplitHostPort(s.addr)
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		_ = pc.Close()
+		return fmt.Errorf("DNS 服务端监听 TCP %s 失败: %w", s.addr, err)
+	}
+	s.udp, s.tcp = pc, ln
+	s.wg.Add(2)
+	go s.serveUDP(pc)
+	go s.serveTCP(ln)
+	s.logInfo("DNS 服务端已启动: %s", pc.LocalAddr())
+	return nil
+}
+
+// Stop 关闭监听并等待收包 / accept 循环退出;进行中的查询各自在超时内结束。
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	pc, ln := s.udp, s.tcp
+	s.udp, s.tcp = nil, nil
+	s.mu.Unlock()
+	if pc == nil {
+		return nil
+	}
+	err := errors.Join(pc.Close(), ln.Close())
+	s.wg.Wait()
+	return err
+}
+
+// Addr 返回运行中服务端的 UDP 监听地址,未运行时为空串。
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.udp == nil {
+		return ""
+	}
+	return s.udp.LocalAddr().String()
+}
+
+func (s *Server) serveUDP(pc net.PacketConn) {
+	defer s.wg.Done()
+	sem := make(chan struct{}, maxUDPInflight)
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			continue
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			defer func() { <-sem }()
+			if resp := answer(query, from, "udp"); resp != nil {
+				_, _ = pc.WriteTo(resp, from)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP(ln net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go func() {
+			defer conn.Close()
+			ServeStream(conn, bufio.NewReader(conn))
+		}()
+	}
+}
+
+// ServeStream 在一条 TCP 连接上按 RFC 7766 依次应答带长度前缀的查询,直到对端关闭、
+// 空闲超时或收到无法应答的消息。reader 为连接上的缓冲读取器(协议探测可能已预读)。
+func ServeStream(conn net.Conn, reader *bufio.Reader) {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readMessage(reader)
+		if err != nil {
+			return
+		}
+		_ = conn.SetReadDeadline(time.Time{})
+		resp := answer(query, conn.RemoteAddr(), "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) logInfo(format string, args ...any) {
+	if s.logger != nil {
+		s.logger.Info(format, args...)
+	}
+}
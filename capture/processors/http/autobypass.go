@@ -0,0 +1,241 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 握手失败自动绕过。
+//
+// 固定证书(pinning)的客户端每次都会拒绝伪造证书,MITM 只会让它一再失败。同一主机在
+// 窗口内握手失败达到阈值后,把它加入运行时的自动绕过列表:之后到它的连接一律直通不解密,
+// 直到被手动重置。只有客户端以告警明确拒绝本端证书的失败才计数(见 isCertRejection);
+// 客户端断开、握手超时、扫描器发完 ClientHello 即走等与固定证书无关,不计。
+//
+// 尚未信任本端 CA 的客户端(首次配置时的常态)对每个主机都会发出同样的告警,这不是固定证书。
+// 故只计已与本端完成过 MITM 握手的客户端(按来源 IP)发来的拒绝:它信任本端 CA,仍拒绝某个
+// 主机的证书,才说明该主机被固定。
+
+const (
+	defaultAutoBypassThreshold = 3
+	defaultAutoBypassWindow    = time.Minute
+	// maxAutoBypassTracked 限制正在计数的主机数,防止大量一次性失败撑大内存。
+	maxAutoBypassTracked = 4096
+)
+
+// AutoBypassEntry 是一条因握手反复失败而自动改为直通的主机。
+type AutoBypassEntry struct {
+	Host      string    `json:"host"`
+	Failures  int       `json:"failures"`            // 触发时窗口内的失败次数;从持久化恢复的为 0
+	LastError string    `json:"lastError,omitempty"` // 最后一次握手失败的原因
+	Since     time.Time `json:"since"`               // 加入时间;从持久化恢复的为零值
+}
+
+type autoBypassState struct {
+	mu        sync.Mutex
+	enabled   bool
+	threshold int
+	window    time.Duration
+	failures  map[string][]time.Time
+	hosts     map[string]AutoBypassEntry
+	trusted   map[string]struct{} // 完成过 MITM 握手的客户端 IP
+	onChange  func([]AutoBypassEntry)
+}
+
+var autoBypass = &autoBypassState{
+	threshold: defaultAutoBypassThreshold,
+	window:    defaultAutoBypassWindow,
+	failures:  make(map[string][]time.Time),
+	hosts:     make(map[string]AutoBypassEntry),
+	trusted:   make(map[string]struct{}),
+}
+
+// SetAutoBypass 由引擎层下发自动绕过开关、阈值与计数窗口;非正值取默认。关闭时已在列表中的
+// 主机恢复解密(列表保留,重新开启后继续生效)。运行时即时生效、并发安全。
+func SetAutoBypass(enabled bool, threshold int, window time.Duration) {
+	if threshold <= 0 {
+		threshold = defaultAutoBypassThreshold
+	}
+	if window <= 0 {
+		window = defaultAutoBypassWindow
+	}
+	a := autoBypass
+	a.mu.Lock()
+	a.enabled, a.threshold, a.window = enabled, threshold, window
+	clear(a.failures)
+	a.mu.Unlock()
+}
+
+// SetAutoBypassListener 注册列表变化的回调(用于持久化),参数为变化后的完整列表。
+func SetAutoBypassListener(fn func([]AutoBypassEntry)) {
+	autoBypass.mu.Lock()
+	autoBypass.onChange = fn
+	autoBypass.mu.Unlock()
+}
+
+// AutoBypassHosts 返回当前自动绕过的主机,按主机名排序。
+func AutoBypassHosts() []AutoBypassEntry {
+	autoBypass.mu.Lock()
+	defer autoBypass.mu.Unlock()
+	return autoBypass.snapshotLocked()
+}
+
+// RestoreAutoBypass 以持久化的主机名整体替换列表,不触发变化回调。
+func RestoreAutoBypass(hosts []string) {
+	a := autoBypass
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clear(a.hosts)
+	for _, h := range hosts {
+		if h = hostOnly(strings.TrimSpace(h)); h != "" {
+			a.hosts[h] = AutoBypassEntry{Host: h}
+		}
+	}
+}
+
+// ResetAutoBypass 把主机移出列表并清零其失败计数;host 为空时清空全部。返回是否有变化。
+func ResetAutoBypass(host string) bool {
+	a := autoBypass
+	a.mu.Lock()
+	changed := false
+	if host == "" {
+		changed = len(a.hosts) > 0
+		clear(a.hosts)
+		clear(a.failures)
+	} else {
+		host = hostOnly(host)
+		_, changed = a.hosts[host]
+		delete(a.hosts, host)
+		delete(a.failures, host)
+	}
+	a.notifyUnlock(changed)
+	return changed
+}
+
+// autoBypassed 报告到该主机(已去端口、小写)的连接是否应跳过解密。
+func autoBypassed(host string) bool {
+	a := autoBypass
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled {
+		return false
+	}
+	_, ok := a.hosts[host]
+	return ok
+}
+
+// certRejectAlerts 是客户端拒绝本端证书时发出的告警:bad_certificate、unknown_ca、
+// certificate_unknown。crypto/tls 不导出告警类型,只能按其错误文本识别。
+var certRejectAlerts = []string{
+	"tls: bad certificate",
+	"tls: unknown certificate authority",
+	"tls: unknown certificate",
+}
+
+// isCertRejection 报告 MITM 握手失败是否因客户端发来告警拒绝了本端证书。
+func isCertRejection(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "remote error" || opErr.Err == nil {
+		return false
+	}
+	return slices.Contains(certRejectAlerts, opErr.Err.Error())
+}
+
+// remoteIP 返回连接来源的 IP,用于区分客户端;取不到时为空串。
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// noteHandshakeSuccess 记下 client(来源 IP)已完成一次 MITM 握手,即它信任本端 CA。
+func noteHandshakeSuccess(client string) {
+	if client == "" {
+		return
+	}
+	a := autoBypass
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.trusted[client]; ok {
+		return
+	}
+	if len(a.trusted) >= maxAutoBypassTracked {
+		clear(a.trusted)
+	}
+	a.trusted[client] = struct{}{}
+}
+
+// noteHandshakeFailure 记一次 client 到 hostport 的 MITM 握手失败;窗口内达到阈值即加入列表。
+// client 未完成过 MITM 握手(尚未信任本端 CA)时不计。
+func noteHandshakeFailure(client, hostport string, cause error) {
+	host := hostOnly(hostport)
+	if host == "" {
+		return
+	}
+	a := autoBypass
+	a.mu.Lock()
+	if _, ok := a.trusted[client]; !a.enabled || !ok {
+		a.mu.Unlock()
+		return
+	}
+	if _, ok := a.hosts[host]; ok {
+		a.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	cutoff := now.Add(-a.window)
+	recent := slices.DeleteFunc(a.failures[host], func(t time.Time) bool { return t.Before(cutoff) })
+	recent = append(recent, now)
+	if len(recent) < a.threshold {
+		if _, tracked := a.failures[host]; !tracked && len(a.failures) >= maxAutoBypassTracked {
+			clear(a.failures)
+		}
+		a.failures[host] = recent
+		a.mu.Unlock()
+		return
+	}
+	delete(a.failures, host)
+	e := AutoBypassEntry{Host: host, Failures: len(recent), Since: now}
+	if cause != nil {
+		e.LastError = cause.Error()
+	}
+	a.hosts[host] = e
+	a.notifyUnlock(true)
+}
+
+// notifyUnlock 释放锁,changed 时在锁外以新列表调用变化回调。调用方持有 mu。
+func (a *autoBypassState) notifyUnlock(changed bool) {
+	fn := a.onChange
+	var list []AutoBypassEntry
+	if changed && fn != nil {
+		list = a.snapshotLocked()
+	}
+	a.mu.Unlock()
+	if changed && fn != nil {
+		fn(list)
+	}
+}
+
+func (a *autoBypassState) snapshotLocked() []AutoBypassEntry {
+	out := make([]AutoBypassEntry, 0, len(a.hosts))
+	for _, e := range a.hosts {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(x, y AutoBypassEntry) int { return strings.Compare(x.Host, y.Host) })
+	return out
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// trustedClient 是测试中已完成过 MITM 握手(信任本端 CA)的客户端 IP。
+const trustedClient = "198.51.100.7"
+
+// resetAutoBypassState 把包级自动绕过状态恢复为默认(关闭、列表为空、无回调、无受信客户端),
+// 并把 trustedClient 记为受信。
+func resetAutoBypassState(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		SetAutoBypass(false, 0, 0)
+		SetAutoBypassListener(nil)
+		ResetAutoBypass("")
+		autoBypass.mu.Lock()
+		clear(autoBypass.trusted)
+		autoBypass.mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+	noteHandshakeSuccess(trustedClient)
+}
+
+// TestAutoBypassThreshold 窗口内失败达到阈值才加入列表,之后 shouldDecrypt 对该主机返回 false。
+func TestAutoBypassThreshold(t *testing.T) {
+	resetAutoBypassState(t)
+	var notified [][]AutoBypassEntry
+	SetAutoBypassListener(func(list []AutoBypassEntry) { notified = append(notified, list) })
+	SetAutoBypass(true, 3, time.Minute)
+
+	cause := errors.New("remote error: tls: bad certificate")
+	noteHandshakeFailure(trustedClient, "pinned.example.com:443", cause)
+	noteHandshakeFailure(trustedClient, "pinned.example.com:443", cause)
+	if !shouldDecrypt("pinned.example.com:443") || len(notified) != 0 {
+		t.Fatal("未达阈值就被绕过")
+	}
+	noteHandshakeFailure(trustedClient, "PINNED.example.com:443", cause)
+	if shouldDecrypt("pinned.example.com:443") {
+		t.Fatal("达到阈值后仍解密")
+	}
+	if !shouldDecrypt("other.example.com:443") {
+		t.Fatal("其它主机不应受影响")
+	}
+	list := AutoBypassHosts()
+	if len(list) != 1 || list[0].Host != "pinned.example.com" || list[0].Failures != 3 || list[0].LastError != cause.Error() {
+		t.Fatalf("列表 = %+v", list)
+	}
+	if len(notified) != 1 || len(notified[0]) != 1 {
+		t.Fatalf("变化回调 = %+v", notified)
+	}
+
+	// 关闭后列表保留但不再生效;重新开启继续生效。
+	SetAutoBypass(false, 3, time.Minute)
+	if !shouldDecrypt("pinned.example.com:443") {
+		t.Fatal("关闭后仍绕过")
+	}
+	SetAutoBypass(true, 3, time.Minute)
+	if shouldDecrypt("pinned.example.com:443") {
+		t.Fatal("重新开启后列表未生效")
+	}
+}
+
+// TestAutoBypassWindow 超出窗口的失败不计数;关闭时不计数。
+func TestAutoBypassWindow(t *testing.T) {
+	resetAutoBypassState(t)
+	SetAutoBypass(true, 2, time.Minute)
+	noteHandshakeFailure(trustedClient, "a.example.com:443", nil)
+	autoBypass.mu.Lock()
+	autoBypass.failures["a.example.com"][0] = time.Now().Add(-2 * time.Minute)
+	autoBypass.mu.Unlock()
+	noteHandshakeFailure(trustedClient, "a.example.com:443", nil)
+	if len(AutoBypassHosts()) != 0 {
+		t.Fatal("窗口外的失败被计入")
+	}
+
+	SetAutoBypass(false, 1, time.Minute)
+	noteHandshakeFailure(trustedClient, "b.example.com:443", nil)
+	SetAutoBypass(true, 1, time.Minute)
+	if len(AutoBypassHosts()) != 0 {
+		t.Fatal("关闭期间的失败被计入")
+	}
+}
+
+// TestAutoBypassResetAndRestore 逐条 / 整体重置恢复解密;恢复持久化列表不触发回调。
+func TestAutoBypassResetAndRestore(t *testing.T) {
+	resetAutoBypassState(t)
+	calls := 0
+	SetAutoBypassListener(func([]AutoBypassEntry) { calls++ })
+	SetAutoBypass(true, 1, time.Minute)
+
+	RestoreAutoBypass([]string{"b.example.com", " a.example.com:443 ", ""})
+	if got := AutoBypassHosts(); len(got) != 2 || got[0].Host != "a.example.com" || got[1].Host != "b.example.com" {
+		t.Fatalf("恢复后 = %+v", got)
+	}
+	if calls != 0 {
+		t.Fatal("恢复不应触发回调")
+	}
+	if !ResetAutoBypass("a.example.com:443") || ResetAutoBypass("a.example.com") {
+		t.Fatal("重置返回值不对")
+	}
+	if !shouldDecrypt("a.example.com:443") || shouldDecrypt("b.example.com:443") {
+		t.Fatal("逐条重置结果不对")
+	}
+	noteHandshakeFailure(trustedClient, "c.example.com:443", nil)
+	if !ResetAutoBypass("") || len(AutoBypassHosts()) != 0 || calls != 3 {
+		t.Fatalf("整体重置后 = %+v, 回调 %d 次", AutoBypassHosts(), calls)
+	}
+	if got := AutoBypassHosts(); !slices.Equal(got, []AutoBypassEntry{}) {
+		t.Fatalf("清空后 = %+v", got)
+	}
+}
+
+// closeAfterWrite 写出首个记录(ClientHello)后即关闭,模拟发完 ClientHello 就断开的客户端。
+type closeAfterWrite struct{ net.Conn }
+
+func (c closeAfterWrite) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	_ = c.Conn.Close()
+	return n, err
+}
+
+// TestAutoBypassCountsOnlyCertRejection 只有受信客户端以告警拒绝本端证书才计入自动绕过;
+// 发完 ClientHello 即断开(对端 EOF)不计,尚未信任本端 CA 的客户端的拒绝也不计。
+func TestAutoBypassCountsOnlyCertRejection(t *testing.T) {
+	resetAutoBypassState(t)
+	restoreImportedServerCerts(t)
+	SetImportedServerCerts(nil)
+	SetAutoBypass(true, 1, time.Minute)
+
+	handshake := func(host string, client func(net.Conn)) error {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听失败: %v", err)
+		}
+		defer ln.Close()
+		go func() {
+			c, err := net.Dial("tcp", ln.Addr().String())
+			if err == nil {
+				client(c)
+			}
+		}()
+		raw, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("accept 失败: %v", err)
+		}
+		defer raw.Close()
+		server := newMockServer()
+		conn := newMockConnection(raw, server)
+		p := New(conn).(*Processor)
+		p.request = &http.Request{Method: http.MethodConnect, Host: host + ":443"}
+		return p.handleTlsHandshake(server, conn.GetReader())
+	}
+	clientWith := func(cfg *tls.Config) func(net.Conn) {
+		return func(c net.Conn) {
+			tc := tls.Client(c, cfg)
+			_ = tc.Handshake()
+			_ = tc.Close()
+		}
+	}
+
+	err := handshake("eof.example.com", func(c net.Conn) {
+		_ = tls.Client(closeAfterWrite{c}, &tls.Config{ServerName: "eof.example.com"}).Handshake()
+	})
+	if err == nil || isCertRejection(err) {
+		t.Fatalf("EOF 不应视为证书被拒: %v", err)
+	}
+	if !shouldDecrypt("eof.example.com:443") {
+		t.Fatal("对端断开被计入自动绕过")
+	}
+
+	// 尚未信任本端 CA 的客户端对每个主机都以告警拒绝,不应让主机被绕过。
+	for range 3 {
+		err = handshake("untrusted.example.com", clientWith(&tls.Config{ServerName: "untrusted.example.com", RootCAs: x509.NewCertPool()}))
+		if !isCertRejection(err) {
+			t.Fatalf("客户端告警应视为证书被拒: %v", err)
+		}
+	}
+	if !shouldDecrypt("untrusted.example.com:443") {
+		t.Fatal("未信任 CA 的客户端让主机被绕过")
+	}
+
+	// 同一客户端信任本端 CA 后,仍拒绝某主机的证书(固定证书)才计入。
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA().GetCA())
+	// 握手成功后客户端即关闭,随后的 HTTP 读取以 EOF 结束,返回值不看。
+	_ = handshake("ok.example.com", clientWith(&tls.Config{ServerName: "ok.example.com", RootCAs: roots}))
+	pinned := &tls.Config{
+		ServerName: "pinned.example.com",
+		RootCAs:    roots,
+		VerifyPeerCertificate: func([][]byte, [][]*x509.Certificate) error {
+			return errors.New("证书与固定的公钥不符")
+		},
+	}
+	if err := handshake("pinned.example.com", clientWith(pinned)); !isCertRejection(err) {
+		t.Fatalf("客户端告警应视为证书被拒: %v", err)
+	}
+	if shouldDecrypt("pinned.example.com:443") {
+		t.Fatal("受信客户端的证书被拒未计入自动绕过")
+	}
+	if !shouldDecrypt("ok.example.com:443") || !shouldDecrypt("untrusted.example.com:443") {
+		t.Fatal("其它主机不应受影响")
+	}
+}
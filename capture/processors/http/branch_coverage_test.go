@@ -553,7 +553,7 @@ func TestStreamRecorderAndDispatchEdgeBranches(t *testing.T) {
 		return flow.ContinueDecision()
 	}})
 	activePipeline = modify
-	out, err := emitStreamMessage(nil, "url", flow.WSServerToClient, flow.StreamChunk, "", []byte("old"), []byte("old"))
+	out, err := emitStreamMessage(nil, "url", flow.WSServerToClient, flow.StreamChunk, "", nil, []byte("old"), []byte("old"))
 	if err != nil || string(out) != "changed" {
 		t.Fatalf("chunk modification = %q, %v", out, err)
 	}
@@ -564,7 +564,7 @@ func TestStreamRecorderAndDispatchEdgeBranches(t *testing.T) {
 	}})
 	activePipeline = abort
 	compressed := grpcFrame{Raw: grpcFrameBytes([]byte("compressed"), true), Payload: []byte("compressed"), Compressed: true}
-	if _, err := emitStreamMessageGRPC(nil, "url", flow.WSServerToClient, compressed, compressed.Payload, compressed.Raw); !errors.Is(err, errStreamAbort) {
+	if _, err := emitStreamMessageGRPC(nil, "url", flow.WSServerToClient, flow.StreamGRPC, compressed); !errors.Is(err, errStreamAbort) {
 		t.Fatalf("compressed grpc abort = %v", err)
 	}
 
@@ -586,18 +586,18 @@ func TestStreamRecorderAndDispatchEdgeBranches(t *testing.T) {
 	if err := dispatchChunk(nil, "url", flow.WSServerToClient, flow.StreamGRPC, &sseScanner{}, &grpcScanner{}, overflowHeader, &failingStreamWriter{chunkErr: wantErr}); !errors.Is(err, wantErr) {
 		t.Fatalf("grpc overflow dispatch error = %v", err)
 	}
-	if err := pumpResponseStream(silentServer{}, nil, "url", flow.StreamSSE, strings.NewReader("data: partial"), &failingStreamWriter{chunkErr: wantErr}); !errors.Is(err, wantErr) {
+	if err := pumpResponseStream(silentServer{}, nil, "url", flow.StreamSSE, nil, strings.NewReader("data: partial"), &failingStreamWriter{chunkErr: wantErr}); !errors.Is(err, wantErr) {
 		t.Fatalf("stream leftover error = %v", err)
 	}
-	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, &grpcScanner{}, grpcFrameBytes([]byte("frame"), false), errorWriter{err: wantErr}); !errors.Is(err, wantErr) {
+	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, flow.StreamGRPC, &grpcScanner{}, grpcFrameBytes([]byte("frame"), false), errorWriter{err: wantErr}); !errors.Is(err, wantErr) {
 		t.Fatalf("grpc request pump error = %v", err)
 	}
 	activePipeline = abort
-	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, &grpcScanner{}, grpcFrameBytes([]byte("frame"), false), io.Discard); !errors.Is(err, errStreamAbort) {
+	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, flow.StreamGRPC, &grpcScanner{}, grpcFrameBytes([]byte("frame"), false), io.Discard); !errors.Is(err, errStreamAbort) {
 		t.Fatalf("grpc request abort = %v", err)
 	}
 	activePipeline = nil
-	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, &grpcScanner{}, overflowHeader, errorWriter{err: wantErr}); !errors.Is(err, wantErr) {
+	if err := pumpGRPCFrames(nil, "url", flow.WSClientToServer, flow.StreamGRPC, &grpcScanner{}, overflowHeader, errorWriter{err: wantErr}); !errors.Is(err, wantErr) {
 		t.Fatalf("grpc request overflow error = %v", err)
 	}
 
@@ -0,0 +1,56 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"crypto/tls"
+	"regexp"
+	"sync/atomic"
+)
+
+// UpstreamClientCert 是一张出示给上游的客户端证书(mTLS)及其生效的主机通配模式。
+type UpstreamClientCert struct {
+	Hosts []string // 通配模式同解密范围
+	Cert  *tls.Certificate
+}
+
+type upstreamClientCert struct {
+	hosts []*regexp.Regexp
+	cert  *tls.Certificate
+}
+
+// upstreamClientCertsPtr 持有当前的客户端证书列表;nil 或空表示不出示。
+var upstreamClientCertsPtr atomic.Pointer[[]upstreamClientCert]
+
+// SetUpstreamClientCerts 由引擎层下发上游客户端证书,运行时即时生效(对此后新建的上游连接)、并发安全。
+// 多张证书都匹配同一主机时取列表中靠前的。
+func SetUpstreamClientCerts(certs []UpstreamClientCert) {
+	list := make([]upstreamClientCert, 0, len(certs))
+	for _, c := range certs {
+		if c.Cert == nil || len(c.Cert.Certificate) == 0 {
+			continue
+		}
+		if hosts := compileHostPatterns(c.Hosts); len(hosts) > 0 {
+			list = append(list, upstreamClientCert{hosts: hosts, cert: c.Cert})
+		}
+	}
+	upstreamClientCertsPtr.Store(&list)
+}
+
+// UpstreamClientCertFor 返回向 host 出示的客户端证书,无匹配时为 nil。供保真转发器调用。
+func UpstreamClientCertFor(host string) *tls.Certificate {
+	p := upstreamClientCertsPtr.Load()
+	if p == nil {
+		return nil
+	}
+	host = hostOnly(host)
+	for _, c := range *p {
+		if matchAnyHost(c.hosts, host) {
+			return c.cert
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+// TestUpstreamClientCertFor 按主机通配模式选证书,多张匹配时取靠前的;无主机或空证书的条目被忽略。
+func TestUpstreamClientCertFor(t *testing.T) {
+	a := &tls.Certificate{Certificate: [][]byte{{1}}}
+	b := &tls.Certificate{Certificate: [][]byte{{2}}}
+	SetUpstreamClientCerts([]UpstreamClientCert{
+		{Hosts: []string{"api.example.com"}, Cert: a},
+		{Hosts: []string{"*.example.com"}, Cert: b},
+		{Hosts: nil, Cert: a},
+		{Hosts: []string{"other.test"}, Cert: &tls.Certificate{}},
+	})
+	t.Cleanup(func() { SetUpstreamClientCerts(nil) })
+
+	for host, want := range map[string]*tls.Certificate{
+		"api.example.com":     a,
+		"API.example.com:443": a,
+		"www.example.com":     b,
+		"example.org":         nil,
+		"other.test":          nil,
+	} {
+		if got := UpstreamClientCertFor(host); got != want {
+			t.Errorf("UpstreamClientCertFor(%q) = %p, want %p", host, got, want)
+		}
+	}
+}
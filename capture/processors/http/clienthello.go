@@ -0,0 +1,249 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// ClientHello 是从 TLS ClientHello 中解析出的字段:SNI / ALPN 用于路由,其余按线上
+// 原始顺序保留(含 GREASE),用于计算 JA3 / JA4 指纹。
+type ClientHello struct {
+	ServerName string   // SNI,未携带时为空
+	ALPN       []string // 客户端提议的应用层协议
+
+	Version             uint16   // legacy_version
+	Ciphers             []uint16 // cipher_suites
+	Extensions          []uint16 // 扩展类型
+	Curves              []uint16 // supported_groups
+	PointFormats        []uint8  // ec_point_formats
+	SignatureAlgorithms []uint16 // signature_algorithms
+	SupportedVersions   []uint16 // supported_versions
+}
+
+const (
+	tlsRecordHeaderLen  = 5
+	handshakeTypeHello  = 0x01
+	extServerName       = 0x0000
+	extALPN             = 0x0010
+	extSupportedGroups  = 0x000a
+	extPointFormats     = 0x000b
+	extSignatureAlgs    = 0x000d
+	extSupportedVersion = 0x002b
+	serverNameTypeHost  = 0x00
+	handshakeHeaderLen  = 4
+	maxClientHelloBytes = 64 * 1024
+)
+
+var errNotClientHello = errors.New("不是有效的 TLS ClientHello")
+
+// PeekClientHello 在不消费字节的前提下从 reader 窥探并解析 ClientHello,之后的 TLS 握手
+// 仍从头读到完整的记录。ClientHello 可能跨多个记录(如携带后量子密钥共享时),按记录
+// 拼接到握手消息完整为止,上限受 reader 缓冲大小约束。client 非 nil 时以
+// TLSHandshakeTimeout 限制等待。协议探测阶段(Registry)与处理器各解析一次,代价可忽略。
+func PeekClientHello(client net.Conn, reader *bufio.Reader) (*ClientHello, error) {
+	if client != nil {
+		_ = client.SetReadDeadline(time.Now().Add(TLSHandshakeTimeout))
+		defer client.SetReadDeadline(time.Time{})
+	}
+
+	var msg []byte
+	offset := 0
+	for {
+		head, err := reader.Peek(offset + tlsRecordHeaderLen)
+		if err != nil {
+			return nil, err
+		}
+		rec := head[offset:]
+		if rec[0] != TLSHandshakeRecordType {
+			return nil, errNotClientHello
+		}
+		n := int(binary.BigEndian.Uint16(rec[3:5]))
+		end := offset + tlsRecordHeaderLen + n
+		if end > reader.Size() || end > maxClientHelloBytes {
+			return nil, errNotClientHello
+		}
+		full, err := reader.Peek(end)
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, full[offset+tlsRecordHeaderLen:]...)
+		offset = end
+
+		if len(msg) < handshakeHeaderLen {
+			continue
+		}
+		if msg[0] != handshakeTypeHello {
+			return nil, errNotClientHello
+		}
+		want := handshakeHeaderLen + (int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3]))
+		if len(msg) >= want {
+			return parseClientHello(msg[handshakeHeaderLen:want])
+		}
+	}
+}
+
+// parseClientHello 解析 ClientHello 消息体(不含 4 字节握手头)。
+func parseClientHello(body []byte) (*ClientHello, error) {
+	s := helloReader(body)
+	hello := &ClientHello{}
+	version, ok := s.uint16()
+	// legacy_version(2) + random(32)
+	if !ok || !s.skip(32) {
+		return nil, errNotClientHello
+	}
+	hello.Version = version
+	if _, ok := s.vector(1); !ok { // session_id
+		return nil, errNotClientHello
+	}
+	ciphers, ok := s.vector(2)
+	if !ok {
+		return nil, errNotClientHello
+	}
+	hello.Ciphers = ciphers.uint16s()
+	if _, ok := s.vector(1); !ok { // compression_methods
+		return nil, errNotClientHello
+	}
+	if len(s) == 0 {
+		return hello, nil // 没有扩展的古老客户端
+	}
+	exts, ok := s.vector(2)
+	if !ok {
+		return nil, errNotClientHello
+	}
+	for len(exts) > 0 {
+		typ, ok := exts.uint16()
+		if !ok {
+			return nil, errNotClientHello
+		}
+		data, ok := exts.vector(2)
+		if !ok {
+			return nil, errNotClientHello
+		}
+		hello.Extensions = append(hello.Extensions, typ)
+		switch typ {
+		case extServerName:
+			hello.ServerName = parseServerName(data)
+		case extALPN:
+			hello.ALPN = parseALPN(data)
+		case extSupportedGroups:
+			if list, ok := data.vector(2); ok {
+				hello.Curves = list.uint16s()
+			}
+		case extPointFormats:
+			if list, ok := data.vector(1); ok {
+				hello.PointFormats = append([]uint8(nil), list...)
+			}
+		case extSignatureAlgs:
+			if list, ok := data.vector(2); ok {
+				hello.SignatureAlgorithms = list.uint16s()
+			}
+		case extSupportedVersion:
+			if list, ok := data.vector(1); ok {
+				hello.SupportedVersions = list.uint16s()
+			}
+		}
+	}
+	return hello, nil
+}
+
+// parseServerName 取 server_name 列表中第一个 host_name。
+func parseServerName(data helloReader) string {
+	list, ok := data.vector(2)
+	if !ok {
+		return ""
+	}
+	for len(list) > 0 {
+		typ, ok := list.uint8()
+		if !ok {
+			return ""
+		}
+		name, ok := list.vector(2)
+		if !ok {
+			return ""
+		}
+		if typ == serverNameTypeHost {
+			return string(name)
+		}
+	}
+	return ""
+}
+
+func parseALPN(data helloReader) []string {
+	list, ok := data.vector(2)
+	if !ok {
+		return nil
+	}
+	var protos []string
+	for len(list) > 0 {
+		p, ok := list.vector(1)
+		if !ok {
+			return protos
+		}
+		protos = append(protos, string(p))
+	}
+	return protos
+}
+
+// helloReader 是按 TLS 表示语言读取字段的游标,越界时返回 false 而不 panic。
+type helloReader []byte
+
+func (r *helloReader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *helloReader) uint8() (uint8, bool) {
+	if len(*r) < 1 {
+		return 0, false
+	}
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v, true
+}
+
+func (r *helloReader) uint16() (uint16, bool) {
+	if len(*r) < 2 {
+		return 0, false
+	}
+	v := binary.BigEndian.Uint16(*r)
+	*r = (*r)[2:]
+	return v, true
+}
+
+// uint16s 把剩余字节按大端 uint16 列表读出(奇数尾字节忽略)。
+func (r helloReader) uint16s() []uint16 {
+	out := make([]uint16, 0, len(r)/2)
+	for len(r) >= 2 {
+		v, _ := r.uint16()
+		out = append(out, v)
+	}
+	return out
+}
+
+// vector 读取一个以 lenBytes(1 或 2)字节长度为前缀的变长字段。
+func (r *helloReader) vector(lenBytes int) (helloReader, bool) {
+	if len(*r) < lenBytes {
+		return nil, false
+	}
+	n := int((*r)[0])
+	if lenBytes == 2 {
+		n = int(binary.BigEndian.Uint16(*r))
+	}
+	rest := (*r)[lenBytes:]
+	if len(rest) < n {
+		return nil, false
+	}
+	*r = rest[n:]
+	return rest[:n], true
+}
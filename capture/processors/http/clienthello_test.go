@@ -0,0 +1,56 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"slices"
+	"testing"
+)
+
+// TestPeekClientHello 用真实 crypto/tls 客户端产出的 ClientHello 锁定 SNI/ALPN 解析,
+// 且窥探不消费字节:随后的 TLS 握手仍要从记录头读起。
+func TestPeekClientHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_ = tls.Client(client, &tls.Config{
+			ServerName: "api.example.com",
+			NextProtos: []string{"h2", "http/1.1"},
+		}).Handshake()
+	}()
+
+	r := bufio.NewReaderSize(server, 64*1024)
+	hello, err := PeekClientHello(server, r)
+	if err != nil {
+		t.Fatalf("PeekClientHello: %v", err)
+	}
+	if hello.ServerName != "api.example.com" {
+		t.Fatalf("ServerName = %q", hello.ServerName)
+	}
+	if !slices.Equal(hello.ALPN, []string{"h2", "http/1.1"}) {
+		t.Fatalf("ALPN = %v", hello.ALPN)
+	}
+	if first, _ := r.Peek(1); first[0] != TLSHandshakeRecordType {
+		t.Fatalf("peek consumed the record header: 0x%02x", first[0])
+	}
+}
+
+func TestParseClientHelloRejectsTruncated(t *testing.T) {
+	for name, body := range map[string][]byte{
+		"empty":          nil,
+		"short random":   make([]byte, 20),
+		"session id":     append(make([]byte, 34), 0x20),
+		"cipher suites":  append(make([]byte, 35), 0x00, 0x10),
+		"extension list": append(make([]byte, 35), 0x00, 0x02, 0x13, 0x01, 0x01, 0x00, 0x00, 0x40),
+	} {
+		if _, err := parseClientHello(body); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -25,8 +25,13 @@ const (
 	// TLS相关常量
 	TLSHandshakeTimeout  = 30 * time.Second // TLS握手超时
 	TLSConnectionTimeout = 5 * time.Minute  // TLS连接超时
+	TunnelSniffTimeout   = 3 * time.Second  // 非 HTTP 入口隧道等待客户端首包的时长
 
 	// HTTP响应模板
 	ConnectEstablishedResponse = "HTTP/1.1 200 Connection Established\r\n\r\n"
 	BadGatewayResponse         = "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 15\r\n\r\n502 Bad Gateway"
+
+	// StatusInvalidUpstreamCert 是上游证书未通过校验时写回客户端的状态码
+	// (沿用 Cloudflare 的 526 Invalid SSL Certificate),以区别于一般的 502。
+	StatusInvalidUpstreamCert = 526
 )
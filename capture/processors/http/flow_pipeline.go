@@ -23,7 +23,7 @@ import (
 //   - writeFlowResponse:写回正常 / mock 响应。
 //   - writeAbort:写回阻断响应;StatusOnAbort==0 时由实现决定如何「直接中断」
 //     (h1 直接关连接,h2 以 RST_STREAM 中断本流)。
-//   - writeBadGateway:上游请求失败时写回 502。
+//   - writeBadGateway:上游请求失败时写回 502(证书被拒改经 writeAbort 回 526,见 failUpstream)。
 type clientResponder interface {
 	writeFlowResponse(f *flow.Flow, req *http.Request) error
 	writeAbort(d flow.Decision) error
@@ -49,11 +49,12 @@ type clientResponder interface {
 //
 // activePipeline 为 nil(独立测试 / 未装配管道)时按 Continue 处理,退化为纯转发。
 func runFlowPipeline(server types.Server, request *http.Request, protocol string, clientAddr, proxyAddr net.Addr, r clientResponder) error {
-	// gRPC 双向流:在读取请求体之前接管(否则 BuildRequestFlow 的 io.ReadAll(req.Body)
-	// 会在双向/客户端流上死锁)。仅限 h2 —— 双向/客户端流只存在于 h2,且 h2 无原始头序列
-	// 可保真,自建出站请求不损失保真度;h1 的 gRPC-web(单向)仍走原有保真转发(经
-	// OrderedHeaders 按客户端原始头序列/大小写写线),其服务端流式响应由响应侧中继处理。
-	if grpcRequest(request.Header) && request.ProtoMajor == 2 {
+	// RPC 双向流(gRPC / gRPC-Web / Connect 流式):在读取请求体之前接管(否则
+	// BuildRequestFlow 的 io.ReadAll(req.Body) 会在双向/客户端流上死锁)。仅限 h2 —— 双向/
+	// 客户端流只存在于 h2,且 h2 无原始头序列可保真,自建出站请求不损失保真度;h1 的
+	// gRPC-Web / Connect(单向)仍走原有保真转发(经 OrderedHeaders 按客户端原始头序列/
+	// 大小写写线),其服务端流式响应由响应侧中继处理。
+	if rpcRequestKind(request.Header) != "" && request.ProtoMajor == 2 {
 		if sw, ok := r.streamWriter(); ok {
 			return runGRPCStream(server, request, protocol, clientAddr, proxyAddr, r, sw)
 		}
@@ -117,10 +118,10 @@ func runFlowPipeline(server types.Server, request *http.Request, protocol string
 	resp, err := client.Do(request)
 	if err != nil {
 		server.LogError("请求失败: %v", err)
-		f.State = flow.StateErrored
-		f.Error = err.Error()
-		finishFlow(f)
-		return r.writeBadGateway()
+		if rc, ok := flow.ResponseCaptureFrom(request.Context()); ok {
+			f.SetUpstreamRoute(rc.Route) // 失败时记下所走路由,便于判断是否代理不可达
+		}
+		return failUpstream(f, err, r)
 	}
 	defer resp.Body.Close()
 
@@ -166,6 +167,17 @@ func runFlowPipeline(server types.Server, request *http.Request, protocol string
 	return err
 }
 
+// failUpstream 记录上游请求失败并写回客户端:上游证书未通过校验时回 526 并附上原因,
+// 其余回 502。
+func failUpstream(f *flow.Flow, err error, r clientResponder) error {
+	f.FailUpstream(err)
+	finishFlow(f)
+	if f.State == flow.StateCertRejected {
+		return r.writeAbort(flow.AbortDecision(StatusInvalidUpstreamCert, f.Error))
+	}
+	return r.writeBadGateway()
+}
+
 // finishFlow 记录 flow 完成(容忍 flowSink 为 nil 的独立测试场景)。
 func finishFlow(f *flow.Flow) {
 	if f.Timing.CompletedAt.IsZero() {
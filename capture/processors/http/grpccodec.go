@@ -0,0 +1,107 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// gRPC 消息级压缩(grpc-encoding)。
+//
+// 与 Content-Encoding 不同,它作用在单条消息上:帧首字节为 1 的消息按发送方在头部
+// 声明的 grpc-encoding 压缩,同一条流里也可以夹带未压缩(首字节 0)的消息。发送方
+// 选用的编码必然在对端的 grpc-accept-encoding 之内,因此改写后按同一编码重新压缩即可,
+// 无需再协商。
+
+// grpcEncoding 取一个方向的消息压缩编码(小写);未声明或为 identity 时返回空串。
+func grpcEncoding(h http.Header) string {
+	enc := strings.ToLower(strings.TrimSpace(h.Get("Grpc-Encoding")))
+	if enc == "identity" {
+		return ""
+	}
+	return enc
+}
+
+// errGRPCCodec 表示不支持的 grpc-encoding,此类压缩帧按原样透传。
+var errGRPCCodec = errors.New("不支持的 gRPC 消息压缩编码")
+
+// decompressGRPC 按 encoding 解压一条消息,解压结果超过 grpcMaxMessage 视为失败。
+// deflate 按规范是 zlib 格式,也兼容个别实现发出的裸 deflate 流。
+func decompressGRPC(encoding string, b []byte) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		if zr, err := zlib.NewReader(bytes.NewReader(b)); err == nil {
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(bytes.NewReader(b))
+			defer fr.Close()
+			r = fr
+		}
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(b), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w: %q", errGRPCCodec, encoding)
+	}
+	out, err := io.ReadAll(io.LimitReader(r, grpcMaxMessage+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > grpcMaxMessage {
+		return nil, errors.New("gRPC 消息解压后超过上限")
+	}
+	return out, nil
+}
+
+// compressGRPC 按 encoding 压缩一条消息,供改写后重建压缩帧。
+func compressGRPC(encoding string, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("%w: %q", errGRPCCodec, encoding)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
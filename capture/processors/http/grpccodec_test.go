@@ -0,0 +1,75 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGRPCCodecRoundTrip(t *testing.T) {
+	msg := bytes.Repeat([]byte("sniffy grpc "), 64)
+	for _, enc := range []string{"gzip", "deflate", "zstd"} {
+		c, err := compressGRPC(enc, msg)
+		if err != nil {
+			t.Fatalf("%s 压缩失败: %v", enc, err)
+		}
+		plain, err := decompressGRPC(enc, c)
+		if err != nil || !bytes.Equal(plain, msg) {
+			t.Fatalf("%s 往返失败: %v", enc, err)
+		}
+	}
+
+	// deflate 兼容裸 deflate 流。
+	var raw bytes.Buffer
+	w, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	_, _ = w.Write(msg)
+	_ = w.Close()
+	if plain, err := decompressGRPC("deflate", raw.Bytes()); err != nil || !bytes.Equal(plain, msg) {
+		t.Fatalf("裸 deflate 解压失败: %v", err)
+	}
+
+	if _, err := decompressGRPC("snappy", msg); !errors.Is(err, errGRPCCodec) {
+		t.Fatalf("未知编码 err = %v", err)
+	}
+	if _, err := decompressGRPC("gzip", []byte("not gzip")); err == nil {
+		t.Fatal("损坏的 gzip 应失败")
+	}
+
+	// 解压炸弹:超过单消息上限即失败。
+	bomb, _ := compressGRPC("gzip", make([]byte, grpcMaxMessage+1))
+	if _, err := decompressGRPC("gzip", bomb); err == nil {
+		t.Fatal("解压后超限应失败")
+	}
+}
+
+func TestGRPCEncodingHeader(t *testing.T) {
+	cases := map[string]string{"": "", "identity": "", " GZIP ": "gzip", "zstd": "zstd"}
+	for in, want := range cases {
+		h := http.Header{}
+		if in != "" {
+			h.Set("Grpc-Encoding", in)
+		}
+		if got := grpcEncoding(h); got != want {
+			t.Errorf("grpcEncoding(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReframeGRPCEncoded(t *testing.T) {
+	if got := reframeGRPCEncoded("", []byte("x")); !bytes.Equal(got, grpcFrameBytes([]byte("x"), false)) {
+		t.Fatalf("无编码应为未压缩帧: %v", got)
+	}
+	// 不支持的编码退回未压缩帧。
+	if got := reframeGRPCEncoded("snappy", []byte("x")); !bytes.Equal(got, grpcFrameBytes([]byte("x"), false)) {
+		t.Fatalf("不支持的编码应退回未压缩帧: %v", got)
+	}
+	fr := (&grpcScanner{encoding: "gzip"}).push(reframeGRPCEncoded("gzip", []byte("x")))
+	if len(fr) != 1 || !fr[0].Compressed || fr[0].Encoding != "gzip" {
+		t.Fatalf("压缩帧 = %+v", fr)
+	}
+}
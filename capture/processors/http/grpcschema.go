@@ -0,0 +1,54 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/protoschema"
+)
+
+// protoRegistry 按方法路径解码 gRPC 消息;为 nil 时 gRPC 消息不带 JSON 视图。
+var protoRegistry *protoschema.Registry
+
+// SetProtoRegistry 注入由引擎层持有的 protobuf 描述符注册表。
+func SetProtoRegistry(r *protoschema.Registry) { protoRegistry = r }
+
+// decodeGRPCMessage 返回一条 gRPC 消息的 JSON 视图与 schema;方法未知时顺带经上游客户端
+// 触发服务端反射查询(开启时),此后的消息即可按类型解码。JSON 编解码的消息(gRPC-Web /
+// Connect 的 +json)本身可读,不再解码。
+func decodeGRPCMessage(rawURL, direction string, payload []byte) (view, schema string) {
+	if protoRegistry == nil || json.Valid(payload) {
+		return "", ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ""
+	}
+	if protoRegistry.Method(u.Path) == nil && u.Host != "" {
+		protoRegistry.Discover(sharedStreamClient, u.Scheme+"://"+u.Host, u.Path)
+	}
+	out, schema, ok := protoRegistry.Decode(u.Path, direction == flow.WSClientToServer, payload)
+	if !ok {
+		return "", ""
+	}
+	return string(out), schema
+}
+
+// encodeGRPCMessage 把插件改写后的 JSON 视图重新编码为消息载荷。
+func encodeGRPCMessage(rawURL, direction, schema, view string) ([]byte, error) {
+	if protoRegistry == nil || schema == "" {
+		return nil, errors.New("gRPC 消息没有可重新编码的视图")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return protoRegistry.Encode(u.Path, direction == flow.WSClientToServer, schema, []byte(view))
+}
@@ -25,15 +25,18 @@ import (
 //
 // 每个 h2 stream 即一条独立 Flow,多个 stream 共享同一连接、由 ServeConn 并发驱动;
 // 管道以 RWMutex 快照实现且 Flow 互不共享,故并发安全。ServeConn 阻塞到连接结束才返回。
-func serveHTTP2(server types.Server, conn net.Conn) error {
+// reverse 非 nil 时(反向代理监听端)每个 stream 都改写到其上游;listener 为连接所属的
+// 附加监听端标识,tlsFP 为连接的客户端 TLS 指纹,二者都记入每条 Flow。
+func serveHTTP2(server types.Server, conn net.Conn, reverse *types.ReverseTarget, listener string, tlsFP *flow.TLSFingerprint) error {
+	tap := newH2Tap(conn)
 	srv := &http2.Server{
 		// h2 是长连接:整连接空闲到点回收以防 goroutine / 连接泄漏(活跃 stream 会刷新该计时)。
 		IdleTimeout: TLSConnectionTimeout,
 		// 慢速 / 失联的读端会让响应写阻塞在流控窗口上;无法写出时回收连接。
 		WriteByteTimeout: TLSConnectionTimeout,
 	}
-	srv.ServeConn(conn, &http2.ServeConnOpts{
-		Handler: &h2Handler{server: server, conn: conn},
+	srv.ServeConn(tap, &http2.ServeConnOpts{
+		Handler: &h2Handler{server: server, conn: conn, tap: tap, reverse: reverse, listener: listener, tlsFP: tlsFP},
 		// 每条 stream 的请求读取上限。h2 分流时清掉了连接级绝对超时(tls.go),这里用
 		// ReadTimeout 给每条流的请求读取(含 BuildRequestFlow 里的 io.ReadAll(req.Body))设界,
 		// 防止停滞的流(slowloris / 永不半关的客户端流式请求)无限占用 goroutine 与连接。
@@ -44,14 +47,34 @@ func serveHTTP2(server types.Server, conn net.Conn) error {
 
 // h2Handler 把单个 h2 stream 适配进共享的 flow 管道。
 type h2Handler struct {
-	server types.Server
-	conn   net.Conn
+	server   types.Server
+	conn     net.Conn
+	tap      *h2Tap // 帧旁路,提供头部顺序与 h2 线缆特征;可为 nil
+	reverse  *types.ReverseTarget
+	listener string
+	tlsFP    *flow.TLSFingerprint
 }
 
 // ServeHTTP 处理一个 h2 stream:补全 URL 后交给 runFlowPipeline,响应经 h2Responder 写回。
 func (h *h2Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// h2 的伪头 :authority/:path 已由 http2 映射到 r.Host / r.URL.Path;
 	// 补全 scheme/host 供转发与 UI 展示,并清空 RequestURI(出站请求要求)。
+	protocol := flow.ProtoHTTPS
+	// 认领帧旁路抓到的头部顺序与 h2 线缆特征,供保真转发器在上游 h2 连接上回放。
+	// 须在下面改写 r.Host / RequestURI 之前进行(按原始伪头匹配)。
+	if fp, raw, ok := h.tap.claim(r); ok {
+		r = r.WithContext(flow.WithH2Fingerprint(flow.WithRawHeaders(r.Context(), raw), fp))
+	}
+	if h.listener != "" {
+		r = r.WithContext(flow.WithListener(r.Context(), h.listener))
+	}
+	if h.tlsFP != nil {
+		r = r.WithContext(flow.WithTLSFingerprint(r.Context(), h.tlsFP))
+	}
+	if h.reverse != nil {
+		r = reverseRequest(r, h.reverse)
+		protocol = reverseProtocol(r)
+	}
 	if r.URL.Scheme == "" {
 		r.URL.Scheme = "https"
 	}
@@ -67,7 +90,7 @@ func (h *h2Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := &h2Responder{w: w}
-	if err := runFlowPipeline(h.server, r, flow.ProtoHTTPS, h.conn.RemoteAddr(), h.conn.LocalAddr(), resp); err != nil {
+	if err := runFlowPipeline(h.server, r, protocol, h.conn.RemoteAddr(), h.conn.LocalAddr(), resp); err != nil {
 		// 响应头可能已经发出，不能再写错误响应。让 http2 框架只复位当前 stream，
 		// 避免把截断的流当作正常 END_STREAM，也不影响同连接上的其它 stream。
 		panic(http.ErrAbortHandler)
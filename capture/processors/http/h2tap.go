@@ -0,0 +1,268 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// h2 入站的帧旁路。
+//
+// http2.Server 把每个 stream 呈现为 *http.Request,头部已是 map:伪头顺序、普通头顺序、
+// 客户端的 SETTINGS 与 WINDOW_UPDATE 全都不可见,而这些恰是反爬系统识别 h2 客户端的指纹。
+// 故在连接上包一层:服务端读到的字节原样交给它,同时另解一份帧,抓取这些特征,
+// 由 handler 按请求认领后经 ctx 交给保真转发器。解码用独立的 hpack.Decoder,输入字节与
+// 服务端相同,动态表同步演进。解析出错即停止旁路,不影响服务端本身。
+
+const (
+	h2TapMaxHeaderFrame = 1 << 20 // 旁路愿意缓冲的单个头部帧上限,超出即放弃旁路
+	h2TapMaxPending     = 256     // 未被认领的请求记录上限(被服务端拒绝的 stream 不会被认领)
+)
+
+// h2TapRecord 是一个 stream 首个头块的解析结果。
+type h2TapRecord struct {
+	method, authority, path string
+	pseudo                  []string
+	headers                 [][2]string
+	priority                *flow.H2Priority
+}
+
+// h2TapParser 增量解析客户端发往服务端的 h2 字节流。
+type h2TapParser struct {
+	preface int    // 尚未跳过的连接前言字节数
+	buf     []byte // 当前帧(9 字节帧头 + 载荷)
+	skip    int    // 不关心的帧载荷(DATA 等)尚需跳过的字节数
+	failed  bool
+
+	dec          *hpack.Decoder
+	settings     []flow.H2Setting
+	settingsSeen bool
+	windowUpdate uint32
+	windowSeen   bool
+
+	// 跨 CONTINUATION 累积中的头块。
+	block      []byte
+	blockID    uint32
+	blockPrio  *flow.H2Priority
+	lastStream uint32
+
+	pending []h2TapRecord
+}
+
+func newH2TapParser() *h2TapParser {
+	return &h2TapParser{preface: len(http2.ClientPreface), dec: hpack.NewDecoder(4096, nil)}
+}
+
+func (p *h2TapParser) feed(b []byte) {
+	for len(b) > 0 && !p.failed {
+		switch {
+		case p.preface > 0:
+			n := min(p.preface, len(b))
+			p.preface -= n
+			b = b[n:]
+		case p.skip > 0:
+			n := min(p.skip, len(b))
+			p.skip -= n
+			b = b[n:]
+		case len(p.buf) < 9:
+			n := min(9-len(p.buf), len(b))
+			p.buf = append(p.buf, b[:n]...)
+			b = b[n:]
+			if len(p.buf) == 9 {
+				p.frameHeader()
+			}
+		default:
+			total := 9 + h2FrameLen(p.buf)
+			n := min(total-len(p.buf), len(b))
+			p.buf = append(p.buf, b[:n]...)
+			b = b[n:]
+			if len(p.buf) == total {
+				p.frame()
+				p.buf = p.buf[:0]
+			}
+		}
+	}
+}
+
+func h2FrameLen(hdr []byte) int {
+	return int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+}
+
+// frameHeader 在读满 9 字节帧头后决定:跳过载荷、放弃旁路,或继续缓冲。
+func (p *h2TapParser) frameHeader() {
+	length := h2FrameLen(p.buf)
+	switch http2.FrameType(p.buf[3]) {
+	case http2.FrameSettings, http2.FrameWindowUpdate, http2.FrameHeaders, http2.FrameContinuation:
+		if length > h2TapMaxHeaderFrame {
+			p.failed = true
+			return
+		}
+		if length == 0 {
+			p.frame()
+			p.buf = p.buf[:0]
+		}
+	default:
+		p.skip = length
+		p.buf = p.buf[:0]
+	}
+}
+
+func (p *h2TapParser) frame() {
+	typ := http2.FrameType(p.buf[3])
+	flags := http2.Flags(p.buf[4])
+	id := binary.BigEndian.Uint32(p.buf[5:9]) & (1<<31 - 1)
+	payload := p.buf[9:]
+	switch typ {
+	case http2.FrameSettings:
+		if flags.Has(http2.FlagSettingsAck) || p.settingsSeen {
+			return
+		}
+		p.settingsSeen = true
+		for i := 0; i+6 <= len(payload); i += 6 {
+			p.settings = append(p.settings, flow.H2Setting{
+				ID:  binary.BigEndian.Uint16(payload[i:]),
+				Val: binary.BigEndian.Uint32(payload[i+2:]),
+			})
+		}
+	case http2.FrameWindowUpdate:
+		if id == 0 && !p.windowSeen && len(payload) == 4 {
+			p.windowSeen = true
+			p.windowUpdate = binary.BigEndian.Uint32(payload) & (1<<31 - 1)
+		}
+	case http2.FrameHeaders:
+		var prio *flow.H2Priority
+		if flags.Has(http2.FlagHeadersPadded) {
+			if len(payload) < 1 || int(payload[0]) > len(payload)-1 {
+				p.failed = true
+				return
+			}
+			pad := int(payload[0])
+			payload = payload[1 : len(payload)-pad]
+		}
+		if flags.Has(http2.FlagHeadersPriority) {
+			if len(payload) < 5 {
+				p.failed = true
+				return
+			}
+			dep := binary.BigEndian.Uint32(payload)
+			prio = &flow.H2Priority{StreamDep: dep & (1<<31 - 1), Exclusive: dep>>31 == 1, Weight: payload[4]}
+			payload = payload[5:]
+		}
+		p.block = append(p.block[:0], payload...)
+		p.blockID, p.blockPrio = id, prio
+		if flags.Has(http2.FlagHeadersEndHeaders) {
+			p.headerBlock()
+		}
+	case http2.FrameContinuation:
+		if id != p.blockID || len(p.block)+len(payload) > h2TapMaxHeaderFrame {
+			p.failed = true
+			return
+		}
+		p.block = append(p.block, payload...)
+		if flags.Has(http2.FlagContinuationEndHeaders) {
+			p.headerBlock()
+		}
+	}
+}
+
+// headerBlock 解码一个完整头块。每个头块都必须解码以保持动态表同步;只有 stream 的
+// 首个头块(请求头)会被记录,之后同一 stream 上的是请求尾部。
+func (p *h2TapParser) headerBlock() {
+	fields, err := p.dec.DecodeFull(p.block)
+	p.block = p.block[:0]
+	if err != nil {
+		p.failed = true
+		return
+	}
+	if p.blockID <= p.lastStream {
+		return
+	}
+	p.lastStream = p.blockID
+	rec := h2TapRecord{priority: p.blockPrio}
+	for _, f := range fields {
+		if !f.IsPseudo() {
+			rec.headers = append(rec.headers, [2]string{f.Name, f.Value})
+			continue
+		}
+		rec.pseudo = append(rec.pseudo, f.Name)
+		switch f.Name {
+		case ":method":
+			rec.method = f.Value
+		case ":authority":
+			rec.authority = f.Value
+		case ":path":
+			rec.path = f.Value
+		}
+	}
+	if len(p.pending) >= h2TapMaxPending {
+		p.pending = p.pending[1:]
+	}
+	p.pending = append(p.pending, rec)
+}
+
+// h2Tap 包装 h2 入站连接,把服务端读到的字节同时喂给旁路解析器。
+type h2Tap struct {
+	net.Conn
+	mu sync.Mutex
+	p  *h2TapParser
+}
+
+func newH2Tap(conn net.Conn) *h2Tap {
+	return &h2Tap{Conn: conn, p: newH2TapParser()}
+}
+
+func (t *h2Tap) Read(b []byte) (int, error) {
+	n, err := t.Conn.Read(b)
+	if n > 0 {
+		// 先解析再返回:服务端据这些字节派发 handler 之前,对应的记录已经就绪。
+		t.mu.Lock()
+		t.p.feed(b[:n])
+		t.mu.Unlock()
+	}
+	return n, err
+}
+
+// ConnectionState 透出底层 TLS 状态:http2.Server 据此填充 r.TLS 并校验 TLS 版本。
+func (t *h2Tap) ConnectionState() tls.ConnectionState {
+	if cs, ok := t.Conn.(interface{ ConnectionState() tls.ConnectionState }); ok {
+		return cs.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+// claim 认领与 r 对应的请求记录(同一方法、authority、path 中 stream ID 最小的一条),
+// 返回其 h2 线缆特征与普通头序列。旁路已失效或找不到记录时返回 false。
+func (t *h2Tap) claim(r *http.Request) (*flow.H2Fingerprint, [][2]string, bool) {
+	if t == nil {
+		return nil, nil, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.p
+	for i, rec := range p.pending {
+		if rec.method != r.Method || rec.authority != r.Host || rec.path != r.RequestURI {
+			continue
+		}
+		p.pending = append(p.pending[:i], p.pending[i+1:]...)
+		fp := &flow.H2Fingerprint{
+			Settings:     append([]flow.H2Setting(nil), p.settings...),
+			WindowUpdate: p.windowUpdate,
+			PseudoOrder:  rec.pseudo,
+			Priority:     rec.priority,
+		}
+		return fp, rec.headers, true
+	}
+	return nil, nil, false
+}
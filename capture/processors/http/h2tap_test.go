@@ -0,0 +1,130 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// clientH2Bytes 构造一段客户端 h2 字节流:前言、SETTINGS、WINDOW_UPDATE、带优先级且跨
+// CONTINUATION 的请求头、DATA、请求尾部,以及第二个复用动态表的请求。
+func clientH2Bytes(t *testing.T) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	out.WriteString(http2.ClientPreface)
+	fr := http2.NewFramer(&out, nil)
+	var hb bytes.Buffer
+	enc := hpack.NewEncoder(&hb)
+	block := func(fields ...string) []byte {
+		hb.Reset()
+		for i := 0; i < len(fields); i += 2 {
+			if err := enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return append([]byte(nil), hb.Bytes()...)
+	}
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(fr.WriteSettings(
+		http2.Setting{ID: http2.SettingHeaderTableSize, Val: 65536},
+		http2.Setting{ID: http2.SettingEnablePush, Val: 0},
+		http2.Setting{ID: http2.SettingInitialWindowSize, Val: 6291456},
+	))
+	must(fr.WriteWindowUpdate(0, 15663105))
+
+	b := block(":method", "POST", ":authority", "example.com", ":scheme", "https", ":path", "/up?x=1",
+		"user-agent", "UA", "accept", "*/*")
+	must(fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: b[:4],
+		Priority:      http2.PriorityParam{Exclusive: true, Weight: 255},
+	}))
+	must(fr.WriteContinuation(1, true, b[4:]))
+	must(fr.WriteData(1, false, []byte("body")))
+	must(fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: block("x-trailer", "1"), EndStream: true, EndHeaders: true}))
+	must(fr.WriteSettingsAck())
+
+	b = block(":method", "GET", ":scheme", "https", ":path", "/next", ":authority", "example.com", "user-agent", "UA")
+	must(fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 3, BlockFragment: b, EndStream: true, EndHeaders: true, PadLength: 7}))
+	return out.Bytes()
+}
+
+// TestH2TapParser 逐字节喂入也应抓到完整的连接特征与每个请求的伪头顺序、头序列。
+func TestH2TapParser(t *testing.T) {
+	for _, step := range []int{1, 7, 1 << 20} {
+		p := newH2TapParser()
+		data := clientH2Bytes(t)
+		for i := 0; i < len(data); i += step {
+			p.feed(data[i:min(i+step, len(data))])
+		}
+		if p.failed {
+			t.Fatalf("step=%d 解析不应失败", step)
+		}
+		tap := &h2Tap{p: p}
+
+		r := &http.Request{Method: "POST", Host: "example.com", RequestURI: "/up?x=1"}
+		fp, raw, ok := tap.claim(r)
+		if !ok {
+			t.Fatalf("step=%d 应认领到首个请求", step)
+		}
+		if len(fp.Settings) != 3 || fp.Settings[2].Val != 6291456 || fp.WindowUpdate != 15663105 {
+			t.Fatalf("连接特征不符: %+v", fp)
+		}
+		if fp.Priority == nil || !fp.Priority.Exclusive || fp.Priority.Weight != 255 {
+			t.Fatalf("优先级不符: %+v", fp.Priority)
+		}
+		if len(raw) != 2 || raw[0] != [2]string{"user-agent", "UA"} || raw[1] != [2]string{"accept", "*/*"} {
+			t.Fatalf("头序列不符(尾部不应混入): %v", raw)
+		}
+		if _, _, ok := tap.claim(r); ok {
+			t.Fatal("同一记录不应被认领两次")
+		}
+
+		fp, _, ok = tap.claim(&http.Request{Method: "GET", Host: "example.com", RequestURI: "/next"})
+		if !ok {
+			t.Fatalf("step=%d 应认领到第二个请求", step)
+		}
+		if got := fp.PseudoOrder; len(got) != 4 || got[1] != ":scheme" || got[3] != ":authority" {
+			t.Fatalf("伪头顺序不符: %v", got)
+		}
+		if fp.Priority != nil {
+			t.Fatalf("未带优先级的 HEADERS 不应有优先级: %+v", fp.Priority)
+		}
+	}
+}
+
+func TestH2TapClaimNil(t *testing.T) {
+	var tap *h2Tap
+	if _, _, ok := tap.claim(&http.Request{}); ok {
+		t.Fatal("nil 旁路不应认领")
+	}
+}
+
+// 损坏的头块使旁路失效,但之后的输入不会再被解析(也不会 panic)。
+func TestH2TapParserGivesUpOnGarbage(t *testing.T) {
+	p := newH2TapParser()
+	var out bytes.Buffer
+	out.WriteString(http2.ClientPreface)
+	fr := http2.NewFramer(&out, nil)
+	_ = fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: []byte{0xff, 0xff, 0xff, 0xff}, EndHeaders: true})
+	p.feed(out.Bytes())
+	if !p.failed {
+		t.Fatal("非法头块应使旁路失效")
+	}
+	p.feed(bytes.Repeat([]byte{0}, 64))
+	if len(p.pending) != 0 {
+		t.Fatal("失效后不应再产出记录")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// 主机映射:拨号上游时把目标主机换成另一地址(类似 hosts 文件),请求本身不变。
+//
+// 只改变实际连接的地址:TLS 的 SNI、证书校验所用的主机名与 Host 头仍是原目标,
+// 这与改写请求 URL 的 redirect 规则动作不同。保真转发、回退 Transport、直通隧道与
+// WebSocket / TCP / MQTT 上游共用同一张表;上游路由仍按原目标主机选取,路由到上游代理
+// 的目标不做映射(由代理解析)。
+
+// HostMapping 是一条映射:目标主机匹配 Hosts 中任一项时改连 Target。
+// Hosts 为主机通配模式(同解密范围);Target 为 IP 或主机名,可带端口,不带时沿用原端口。
+type HostMapping struct {
+	Hosts  []string
+	Target string
+}
+
+type hostMapping struct {
+	hosts []*regexp.Regexp
+	host  string
+	port  string // 空表示沿用原端口
+}
+
+// hostMappingsPtr 持有当前映射表;nil 或空表示不做映射。
+var hostMappingsPtr atomic.Pointer[[]hostMapping]
+
+// SetHostMappings 由引擎层下发映射表,运行时即时生效(对此后新建的上游连接)、并发安全。
+// 没有有效主机模式或目标无法解析的条目被忽略。
+func SetHostMappings(mappings []HostMapping) {
+	list := make([]hostMapping, 0, len(mappings))
+	for _, m := range mappings {
+		host, port, err := splitMapTarget(m.Target)
+		if err != nil {
+			continue
+		}
+		hosts := compileHostPatterns(m.Hosts)
+		if len(hosts) == 0 {
+			continue
+		}
+		list = append(list, hostMapping{hosts: hosts, host: host, port: port})
+	}
+	hostMappingsPtr.Store(&list)
+}
+
+// MapUpstreamAddr 返回拨号 hostport 时实际应连接的地址:按顺序首条命中的映射,都不命中时原样返回。
+func MapUpstreamAddr(hostport string) string {
+	p := hostMappingsPtr.Load()
+	if p == nil || len(*p) == 0 {
+		return hostport
+	}
+	host := hostOnly(hostport)
+	for i := range *p {
+		m := &(*p)[i]
+		if !matchAnyHost(m.hosts, host) {
+			continue
+		}
+		port := m.port
+		if port == "" {
+			_, port, _ = net.SplitHostPort(hostport)
+		}
+		if port == "" {
+			return m.host
+		}
+		return net.JoinHostPort(m.host, port)
+	}
+	return hostport
+}
+
+// ParseHostMappings 解析文本形式的映射表:每行「主机模式[,主机模式...] 目标」,目标为 IP、
+// 主机名或 host:port(IPv6 带端口时写作 [addr]:port)。空行与 # 开头的注释行忽略;
+// 出错时返回带行号的错误。
+func ParseHostMappings(text string) ([]HostMapping, error) {
+	var out []HostMapping
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("第 %d 行: 应为「主机模式 目标」", i+1)
+		}
+		if _, _, err := splitMapTarget(fields[1]); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+		}
+		out = append(out, HostMapping{Hosts: strings.Split(fields[0], ","), Target: fields[1]})
+	}
+	return out, nil
+}
+
+// splitMapTarget 把映射目标拆为主机与端口(可为空)。
+func splitMapTarget(s string) (host, port string, err error) {
+	s = strings.TrimSpace(s)
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host, port = h, p
+	} else if strings.Count(s, ":") > 1 || !strings.Contains(s, ":") {
+		host = strings.Trim(s, "[]") // 不带端口的主机名或 IPv6 字面量
+	} else {
+		return "", "", fmt.Errorf("目标 %q 无法解析", s)
+	}
+	if host == "" || strings.ContainsAny(host, "/*") || (strings.Contains(host, ":") && net.ParseIP(host) == nil) {
+		return "", "", fmt.Errorf("目标 %q 缺少有效主机", s)
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", errors.New("目标端口须为 1-65535")
+		}
+	}
+	return host, port, nil
+}
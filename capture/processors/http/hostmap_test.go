@@ -0,0 +1,80 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+// TestHostMappings 按顺序首条命中;目标不带端口时沿用原端口;直通隧道连到映射地址。
+func TestHostMappings(t *testing.T) {
+	t.Cleanup(func() { SetHostMappings(nil) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			_, _ = c.Write([]byte("ok"))
+			_ = c.Close()
+		}
+	}()
+
+	mappings, err := ParseHostMappings(`
+# 注释
+api.example.test        ` + ln.Addr().String() + `
+*.example.test,dev.test 10.0.0.1
+v6.test                 [::1]:8443
+`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	SetHostMappings(mappings)
+
+	cases := []struct{ addr, want string }{
+		{"api.example.test:443", ln.Addr().String()},
+		{"API.Example.Test:80", ln.Addr().String()},
+		{"example.test:443", "10.0.0.1:443"},
+		{"a.b.example.test:8080", "10.0.0.1:8080"},
+		{"dev.test:80", "10.0.0.1:80"},
+		{"v6.test:443", "[::1]:8443"},
+		{"other.test:443", "other.test:443"},
+	}
+	for _, c := range cases {
+		if got := MapUpstreamAddr(c.addr); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.addr, got, c.want)
+		}
+	}
+
+	conn, err := DialTunnel("api.example.test:443")
+	if err != nil {
+		t.Fatalf("DialTunnel: %v", err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 2)
+	if _, err := conn.Read(buf); err != nil || string(buf) != "ok" {
+		t.Fatalf("read = %q, %v", buf, err)
+	}
+}
+
+// TestParseHostMappingsErrors 格式错误与非法目标带行号报错。
+func TestParseHostMappingsErrors(t *testing.T) {
+	for _, text := range []string{
+		"a.test",
+		"a.test 1.2.3.4 extra",
+		"\na.test 1.2.3.4:0",
+		"a.test host:http",
+		"a.test :443",
+		"a.test *.other.test",
+		"a.test a:b:c",
+	} {
+		_, err := ParseHostMappings(text)
+		if err == nil || !strings.Contains(err.Error(), "行") {
+			t.Fatalf("%q: 应报错: %v", text, err)
+		}
+	}
+}
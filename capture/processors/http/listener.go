@@ -0,0 +1,106 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// ListenerPolicy 是单个附加监听端覆盖全局的设置;字段为 nil 时沿用全局(SetProxyAuth /
+// SetDecryptScope)。
+type ListenerPolicy struct {
+	ProxyAuth    *ProxyAuthSettings
+	DecryptScope *DecryptScopeSettings
+}
+
+// ProxyAuthSettings 是监听端要求客户端出示的 Basic 凭据。
+type ProxyAuthSettings struct {
+	Enabled  bool
+	Username string
+	Password string
+}
+
+// DecryptScopeSettings 是监听端的 HTTPS 解密范围,语义同 SetDecryptScope 的参数。
+type DecryptScopeSettings struct {
+	Enabled bool
+	Mode    string
+	Allow   []string
+	Deny    []string
+}
+
+// listenerPolicy 是预编译后的监听端设置。
+type listenerPolicy struct {
+	auth  *proxyAuthConfig
+	scope *decryptScope
+}
+
+// listenerPolicies 按监听端标识持有设置,写时复制:连接热路径只做一次原子读。
+var (
+	listenerPoliciesMu sync.Mutex
+	listenerPolicies   atomic.Pointer[map[string]*listenerPolicy]
+)
+
+// SetListenerPolicy 设置(pol 为 nil 时清除)某个附加监听端的覆盖设置,对新连接与新请求
+// 即时生效。
+func SetListenerPolicy(id string, pol *ListenerPolicy) {
+	listenerPoliciesMu.Lock()
+	defer listenerPoliciesMu.Unlock()
+	next := make(map[string]*listenerPolicy)
+	if cur := listenerPolicies.Load(); cur != nil {
+		maps.Copy(next, *cur)
+	}
+	if pol == nil {
+		delete(next, id)
+	} else {
+		lp := &listenerPolicy{}
+		if a := pol.ProxyAuth; a != nil {
+			lp.auth = &proxyAuthConfig{enabled: a.Enabled, username: a.Username, password: a.Password}
+		}
+		if sc := pol.DecryptScope; sc != nil {
+			lp.scope = &decryptScope{
+				enabled: sc.Enabled,
+				mode:    sc.Mode,
+				allow:   compileHostPatterns(sc.Allow),
+				deny:    compileHostPatterns(sc.Deny),
+			}
+		}
+		next[id] = lp
+	}
+	listenerPolicies.Store(&next)
+}
+
+func policyOf(listener string) *listenerPolicy {
+	if listener == "" {
+		return nil
+	}
+	if cur := listenerPolicies.Load(); cur != nil {
+		return (*cur)[listener]
+	}
+	return nil
+}
+
+// proxyAuthFor 返回监听端生效的客户端认证:有覆盖取覆盖,否则取全局。
+func proxyAuthFor(listener string) *proxyAuthConfig {
+	if lp := policyOf(listener); lp != nil && lp.auth != nil {
+		return lp.auth
+	}
+	return listenerProxyAuth.Load()
+}
+
+// decryptScopeFor 返回监听端生效的解密范围:有覆盖取覆盖,否则取全局(可能为 nil)。
+func decryptScopeFor(listener string) *decryptScope {
+	if lp := policyOf(listener); lp != nil && lp.scope != nil {
+		return lp.scope
+	}
+	return decryptScopePtr.Load()
+}
+
+// shouldDecrypt 按本连接所属监听端的解密范围判断目标是否应被 MITM 解密。
+func (p *Processor) shouldDecrypt(hostport string) bool {
+	return shouldDecryptFor(p.listener, hostport)
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// idConn 模拟附加监听端 accept 到的连接。
+type idConn struct {
+	*mockConn
+	id string
+}
+
+func (c *idConn) ListenerID() string { return c.id }
+
+func TestListenerPolicyOverridesProxyAuth(t *testing.T) {
+	SetProxyAuth(true, "sniffy", "s3cret")
+	SetListenerPolicy("open", &ListenerPolicy{ProxyAuth: &ProxyAuthSettings{}})
+	SetListenerPolicy("lan", &ListenerPolicy{ProxyAuth: &ProxyAuthSettings{Enabled: true, Username: "lan", Password: "pw"}})
+	SetListenerPolicy("inherit", &ListenerPolicy{})
+	t.Cleanup(func() {
+		SetProxyAuth(false, "", "")
+		for _, id := range []string{"open", "lan", "inherit"} {
+			SetListenerPolicy(id, nil)
+		}
+	})
+
+	for listener, want := range map[string]bool{"": true, "open": false, "lan": true, "inherit": true, "unknown": true} {
+		if got := ProxyAuthRequiredFor(listener); got != want {
+			t.Errorf("ProxyAuthRequiredFor(%q) = %v, want %v", listener, got, want)
+		}
+	}
+	if !CheckProxyCredentialsFor("lan", "lan", "pw") || CheckProxyCredentialsFor("lan", "sniffy", "s3cret") {
+		t.Error("lan listener should only accept its own credentials")
+	}
+	if !CheckProxyCredentialsFor("inherit", "sniffy", "s3cret") {
+		t.Error("listener without an auth override should accept the global credentials")
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "http://example.test/", nil)
+	r.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("lan:pw")))
+	if !proxyAuthFor("lan").authorize(r) || proxyAuthFor("").authorize(r) {
+		t.Error("credentials should be checked against the listener's own settings")
+	}
+
+	SetListenerPolicy("open", nil)
+	if !ProxyAuthRequiredFor("open") {
+		t.Error("clearing the override should fall back to the global settings")
+	}
+}
+
+// TestListenerWithoutAuthSkipsChallenge 锁定全局开启认证时,关闭认证的附加监听端上的
+// 代理请求不会收到 407。
+func TestListenerWithoutAuthSkipsChallenge(t *testing.T) {
+	SetProxyAuth(true, "sniffy", "s3cret")
+	SetListenerPolicy("open", &ListenerPolicy{ProxyAuth: &ProxyAuthSettings{}})
+	t.Cleanup(func() {
+		SetProxyAuth(false, "", "")
+		SetListenerPolicy("open", nil)
+	})
+
+	conn := newMockConn("CONNECT example.test:443 HTTP/1.1\r\nHost: example.test:443\r\n\r\n")
+	p := New(newMockConnection(&idConn{mockConn: conn, id: "open"}, newMockServer())).(*Processor)
+	if p.listener != "open" {
+		t.Fatalf("listener = %q", p.listener)
+	}
+	_ = p.handleHttpProtocol(p.conn.GetServer(), p.conn.GetReader(), p.conn.GetWriter())
+	if got := conn.writeBuffer.String(); strings.Contains(got, "407") {
+		t.Fatalf("response = %q", got)
+	}
+}
+
+func TestListenerPolicyOverridesDecryptScope(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "allow", []string{"*.example.com"}, nil)
+	SetListenerPolicy("all", &ListenerPolicy{DecryptScope: &DecryptScopeSettings{Enabled: true, Mode: "all"}})
+	SetListenerPolicy("deny", &ListenerPolicy{DecryptScope: &DecryptScopeSettings{Enabled: true, Mode: "deny", Deny: []string{"bank.test"}}})
+	t.Cleanup(func() {
+		SetListenerPolicy("all", nil)
+		SetListenerPolicy("deny", nil)
+	})
+
+	for _, tc := range []struct {
+		listener, host string
+		want           bool
+	}{
+		{"", "api.example.com:443", true},
+		{"", "bank.test:443", false},
+		{"all", "bank.test:443", true},
+		{"deny", "bank.test:443", false},
+		{"deny", "other.test:443", true},
+		{"unknown", "other.test:443", false},
+	} {
+		if got := shouldDecryptFor(tc.listener, tc.host); got != tc.want {
+			t.Errorf("shouldDecryptFor(%q, %q) = %v, want %v", tc.listener, tc.host, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/pac"
+)
+
+type pacHosts struct {
+	include, exclude []string
+}
+
+// pacHostsPtr 持有 PAC 脚本的主机范围;nil 表示不提供 PAC。
+var pacHostsPtr atomic.Pointer[pacHosts]
+
+// SetPAC 由引擎层下发是否在代理端口提供 PAC 脚本及其主机范围(见 pac.Script),
+// 运行时即时生效、并发安全。
+func SetPAC(enabled bool, include, exclude []string) {
+	if !enabled {
+		pacHostsPtr.Store(nil)
+		return
+	}
+	pacHostsPtr.Store(&pacHosts{
+		include: append([]string(nil), include...),
+		exclude: append([]string(nil), exclude...),
+	})
+}
+
+// isDirectPACRequest 判断请求是否为直接发给代理端口(而非经代理转发)的 PAC 拉取。
+// 隧道内、透明与反代模式下的 origin-form 请求属于源站,不在此列。
+func (p *Processor) isDirectPACRequest(request *http.Request) bool {
+	return pacHostsPtr.Load() != nil && !p.proxyTunnel && !p.isHttps && p.originalDst == "" && p.reverse == nil &&
+		request.URL.Host == "" && isPACFetch(request)
+}
+
+// isPACFetch 判断请求是否在拉取 PAC 脚本。
+func isPACFetch(request *http.Request) bool {
+	return (request.Method == http.MethodGet || request.Method == http.MethodHead) && pac.IsPath(request.URL.Path)
+}
+
+// servePAC 返回 PAC 脚本,其中的代理地址取客户端连入本端口所用的地址。未开启时回 404。
+func (p *Processor) servePAC(server types.Server, request *http.Request) error {
+	p.closeAfterResponse = true
+	p.armWriteDeadline(server)
+	hosts := pacHostsPtr.Load()
+	if hosts == nil {
+		return p.writeRawResponse("HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+	}
+	proxy := request.Host
+	if conn := p.conn.GetConn(); conn != nil {
+		if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok && !addr.IP.IsUnspecified() {
+			proxy = addr.String()
+		}
+	}
+	server.LogDebug("返回 PAC 脚本(代理 %s)", proxy)
+	script := pac.Script(proxy, hosts.include, hosts.exclude)
+	writer := p.conn.GetWriter()
+	fmt.Fprintf(writer,
+		"HTTP/1.1 200 OK\r\nContent-Type: %s\r\nCache-Control: no-cache\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+		pac.ContentType, len(script),
+	)
+	if request.Method != http.MethodHead {
+		_, _ = writer.Write(script)
+	}
+	return writer.Flush()
+}
@@ -292,9 +292,18 @@ func runPassthroughResponse(server types.Server, f *flow.Flow, resp *http.Respon
 
 	var statusLine string
 	var rawHead [][2]string
-	if rc, ok := flow.ResponseCaptureFrom(request.Context()); ok && len(rc.Headers) > 0 {
-		statusLine, rawHead = rc.StatusLine, rc.Headers
-		f.Response.RawHeaders = rc.Headers
+	var trace *flow.PhaseTrace
+	if rc, ok := flow.ResponseCaptureFrom(request.Context()); ok {
+		f.SetUpstreamTLSFingerprint(rc.UpstreamTLS)
+		f.SetClientCert(rc.ClientCert)
+		f.SetUpstreamConn(rc.UpstreamConn)
+		f.SetUpstreamRoute(rc.Route)
+		f.SetPhases(rc.Trace.Phases(time.Time{})) // body 读尽时再补上接收耗时
+		trace = rc.Trace
+		if len(rc.Headers) > 0 {
+			statusLine, rawHead = rc.StatusLine, rc.Headers
+			f.Response.RawHeaders = rc.Headers
+		}
 	}
 
 	// 响应阶段插件(头部级:可改头 / abort;此时无完整 body)。
@@ -351,6 +360,7 @@ func runPassthroughResponse(server types.Server, f *flow.Flow, resp *http.Respon
 		}
 	}
 
+	f.SetPhases(trace.Phases(time.Now()))
 	f.Timing.DurationMs = time.Since(f.Timing.RequestAt).Milliseconds()
 	finishFlow(f)
 	return cerr
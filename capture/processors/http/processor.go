@@ -149,6 +149,14 @@ type Processor struct {
 	// proxyTunnel 表示当前连接已通过 CONNECT 建立隧道:隧道内的请求属于已认证的这一条
 	// 连接,不该被要求再次出示 Proxy-Authorization —— 它本就不属于被隧道的那个请求。
 	proxyTunnel bool
+	// originalDst 是透明代理模式下连接的原始目标 host:port,常规代理时为空。
+	originalDst string
+	// reverse 是反向代理监听端的上游映射,常规代理时为 nil。
+	reverse *types.ReverseTarget
+	// listener 是连接所属附加监听端的标识,主监听端为空;据此取监听端的认证与解密范围。
+	listener string
+	// tlsFP 是 TLS 握手时解析的客户端 ClientHello 指纹,明文连接为 nil。
+	tlsFP *flow.TLSFingerprint
 
 	// closeAfterResponse 表示当前请求处理完后不能继续复用客户端连接。它覆盖无法从
 	// request.Close 推导出的关闭场景:代理自己生成的无响应体阻断、请求体读到一半失败
@@ -158,9 +166,11 @@ type Processor struct {
 
 // New 创建新的HTTP处理器
 func New(conn types.Connection) types.ProtocolProcessor {
-	return &Processor{
-		conn: conn,
+	p := &Processor{conn: conn}
+	if c := conn.GetConn(); c != nil {
+		p.listener = types.ListenerID(c)
 	}
+	return p
 }
 
 // GetProtocolName 返回协议名称
@@ -174,6 +184,21 @@ func (p *Processor) Process() error {
 	reader := p.conn.GetReader()
 	writer := p.conn.GetWriter()
 
+	if rt := types.ReverseTargetOf(p.conn.GetConn()); rt != nil {
+		return p.serveReverse(server, rt)
+	}
+	if dst := types.OriginalDestination(p.conn.GetConn()); dst != "" {
+		return p.serveTransparent(server, dst)
+	}
+	if bufferedTLS(reader) {
+		// 客户端直接对代理端口说 TLS(DNS 劫持、反代前置等):没有 CONNECT 可供认证。
+		if ProxyAuthRequiredFor(p.listener) {
+			server.LogError("%v", errDirectTLSNeedAuth)
+			return errDirectTLSNeedAuth
+		}
+		return p.serveClientTLS(server, reader, "")
+	}
+
 	// 执行具体的HTTP协议处理逻辑
 	return p.handleHttpProtocol(server, reader, writer)
 }
@@ -202,9 +227,15 @@ func (p *Processor) handleHttpProtocol(server types.Server, reader *bufio.Reader
 
 		server.LogDebug("请求的域名是：" + request.Host)
 
+		// PAC 脚本先于认证放行:设为自动代理的设备直接(不经代理)拉取脚本,带不上代理凭据。
+		// 脚本只含代理地址与主机范围,不触达内网、不进抓包记录。
+		if p.isDirectPACRequest(request) {
+			return p.servePAC(server, request)
+		}
+
 		// 校验必须早于 CONNECT 建隧道、flow 管道与 cert.sniffy，否则未认证的客户端
 		// 已经能探测内网、写进抓包记录或触发插件副作用。
-		if !p.proxyTunnel && !checkProxyAuthorization(request) {
+		if !p.proxyTunnel && !proxyAuthFor(p.listener).authorize(request) {
 			p.closeAfterResponse = true
 			p.armWriteDeadline(server)
 			if err := writeProxyAuthChallenge(writer); err != nil {
@@ -216,6 +247,20 @@ func (p *Processor) handleHttpProtocol(server types.Server, reader *bufio.Reader
 
 		// 凭据到此为止：这一跳已经用完，源站、抓包记录与插件都不该再看到它。
 		request = stripProxyAuthorization(request)
+		if p.reverse != nil {
+			// 反代端口对客户端就是源站,不提供隧道。
+			if request.Method == http.MethodConnect {
+				p.armWriteDeadline(server)
+				return p.writeRawResponse("HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			}
+			request = reverseRequest(request, p.reverse)
+		}
+		if p.listener != "" {
+			request = request.WithContext(flow.WithListener(request.Context(), p.listener))
+		}
+		if p.tlsFP != nil {
+			request = request.WithContext(flow.WithTLSFingerprint(request.Context(), p.tlsFP))
+		}
 		p.request = request
 		p.closeAfterResponse = false
 
@@ -379,7 +424,7 @@ func (p *Processor) handleConnect(server types.Server, reader *bufio.Reader, wri
 	}
 
 	// 解密范围:目标主机不在范围内(或 MITM 总开关关闭)则直通盲转发,不做 TLS 终止与抓包。
-	if !shouldDecrypt(p.request.Host) {
+	if !p.shouldDecrypt(p.request.Host) {
 		server.LogDebug("目标 %s 不在解密范围，直通转发", p.request.Host)
 		return p.tunnel(server, reader)
 	}
@@ -412,7 +457,11 @@ func (p *Processor) handleConnect(server types.Server, reader *bufio.Reader, wri
 
 func (p *Processor) handleWebSocket(server types.Server) error {
 	// 创建WebSocket处理器并委托处理
-	return websocket.New(p.conn, p.request, p.isHttps).Process(server)
+	secure := p.isHttps
+	if p.reverse != nil {
+		secure = p.request.URL.Scheme == "https"
+	}
+	return websocket.New(p.conn, p.request, secure).Process(server)
 }
 
 // handleTlsHandshake 处理TLS握手
@@ -430,6 +479,9 @@ func (p *Processor) handleRequest(server types.Server) error {
 	}
 
 	if isCertDomain(request.Host) {
+		if isPACFetch(request) {
+			return p.servePAC(server, request)
+		}
 		return p.serveIOSProfile(server)
 	}
 
@@ -444,7 +496,7 @@ func (p *Processor) handleRequest(server types.Server) error {
 }
 
 // certMagicDomain 是 iOS 证书安装的魔法域名：手机设好代理后 Safari 访问此域名，
-// 代理直接返回 .mobileconfig，无需真实 DNS 解析。
+// 代理直接返回 .mobileconfig，无需真实 DNS 解析。其下的 /proxy.pac、/wpad.dat 返回 PAC 脚本。
 const certMagicDomain = "cert.sniffy"
 
 func isCertDomain(host string) bool {
@@ -503,6 +555,9 @@ func (p *Processor) normalizeRequestURL(request *http.Request) {
 	}
 	if request.URL.Host == "" {
 		request.URL.Host = request.Host
+		if p.originalDst != "" {
+			request.URL.Host = p.transparentHost(request.Host)
+		}
 	}
 	request.RequestURI = ""
 }
@@ -537,6 +592,9 @@ func (p *Processor) handleViaPipeline(server types.Server, request *http.Request
 	if p.isHttps {
 		protocol = flow.ProtoHTTPS
 	}
+	if p.reverse != nil {
+		protocol = reverseProtocol(request)
+	}
 	var clientAddr, proxyAddr net.Addr
 	if conn := p.conn.GetConn(); conn != nil {
 		clientAddr, proxyAddr = conn.RemoteAddr(), conn.LocalAddr()
@@ -595,6 +653,8 @@ func (p *Processor) recordTLSFailure(host string, cause error) {
 		hostname = h
 	}
 	f := flow.New(flow.ProtoHTTPS)
+	f.Listener = p.listener
+	f.SetTLSFingerprint(p.tlsFP) // 握手失败常因客户端固定证书,指纹有助于辨认是哪个应用
 	f.State = flow.StateErrored
 	f.Error = "TLS 握手失败: " + cause.Error()
 	f.Request = &flow.Request{
@@ -40,10 +40,14 @@ func SetProxyAuth(enabled bool, username, password string) {
 	listenerProxyAuth.Store(&proxyAuthConfig{enabled: enabled, username: username, password: password})
 }
 
-// checkProxyAuthorization 校验 Proxy-Authorization,只接受 Basic,解析后定长比较两侧,
-// 避免把畸形的 scheme 或凭据当成通过。
+// checkProxyAuthorization 按全局设置校验 Proxy-Authorization。
 func checkProxyAuthorization(r *http.Request) bool {
-	c := listenerProxyAuth.Load()
+	return listenerProxyAuth.Load().authorize(r)
+}
+
+// authorize 校验 Proxy-Authorization,只接受 Basic,解析后定长比较两侧,避免把畸形的
+// scheme 或凭据当成通过。c 为 nil 或未开启时恒通过。
+func (c *proxyAuthConfig) authorize(r *http.Request) bool {
 	if c == nil || !c.enabled {
 		return true
 	}
@@ -63,12 +67,37 @@ func checkProxyAuthorization(r *http.Request) bool {
 	if len(parts) != 2 {
 		return false
 	}
-	// 两侧都先算完再合并:用 && 短路会因「是否继续比较密码」而泄漏用户名是否命中。
-	userOK := constantTimeEqualString(parts[0], c.username)
-	passOK := constantTimeEqualString(parts[1], c.password)
+	return c.matches(parts[0], parts[1])
+}
+
+// matches 比对一组用户名/密码。两侧都先算完再合并:用 && 短路会因「是否继续比较密码」
+// 而泄漏用户名是否命中。
+func (c *proxyAuthConfig) matches(username, password string) bool {
+	userOK := constantTimeEqualString(username, c.username)
+	passOK := constantTimeEqualString(password, c.password)
 	return userOK && passOK
 }
 
+// ProxyAuthRequiredFor 报告给定监听端(见 SetListenerPolicy,空串为主监听端)当前是否要求
+// 客户端认证,供 SOCKS5 等其它入口协商认证方式。
+func ProxyAuthRequiredFor(listener string) bool {
+	c := proxyAuthFor(listener)
+	return c != nil && c.enabled
+}
+
+// CheckProxyCredentialsFor 按给定监听端的设置,用与 Proxy-Authorization 相同的口径校验一组
+// 明文凭据(SOCKS5 的 RFC 1929 子协商即走此处)。未开启认证时恒通过。
+func CheckProxyCredentialsFor(listener, username, password string) bool {
+	c := proxyAuthFor(listener)
+	if c == nil || !c.enabled {
+		return true
+	}
+	if c.username == "" || c.password == "" {
+		return false
+	}
+	return c.matches(username, password)
+}
+
 // constantTimeEqualString 先摘要再定长比较。直接对原文调用 ConstantTimeCompare
 // 会在长度不等时立刻返回,把凭据长度暴露给计时观测;摘要把两侧统一成 32 字节。
 func constantTimeEqualString(got, want string) bool {
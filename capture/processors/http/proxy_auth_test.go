@@ -319,3 +319,29 @@ type testStringFlusher struct{ *strings.Builder }
 
 func (w *testStringFlusher) WriteString(s string) (int, error) { return w.Builder.WriteString(s) }
 func (w *testStringFlusher) Flush() error                      { return nil }
+
+// TestCheckProxyCredentials 锁定 SOCKS5 等入口使用的明文凭据校验与 Proxy-Authorization 同口径。
+func TestCheckProxyCredentials(t *testing.T) {
+	t.Cleanup(func() { SetProxyAuth(false, "", "") })
+
+	SetProxyAuth(false, "", "")
+	if ProxyAuthRequiredFor("") || !CheckProxyCredentialsFor("", "any", "thing") {
+		t.Fatal("关闭认证时应不要求且恒通过")
+	}
+
+	SetProxyAuth(true, "sniffy", "s3cret")
+	if !ProxyAuthRequiredFor("") {
+		t.Fatal("开启认证后应要求凭据")
+	}
+	if !CheckProxyCredentialsFor("", "sniffy", "s3cret") {
+		t.Fatal("正确凭据被拒")
+	}
+	if CheckProxyCredentialsFor("", "sniffy", "wrong") || CheckProxyCredentialsFor("", "", "") {
+		t.Fatal("错误凭据被放行")
+	}
+
+	SetProxyAuth(true, "sniffy", "")
+	if CheckProxyCredentialsFor("", "sniffy", "") {
+		t.Fatal("凭据不全时仍放行了客户端")
+	}
+}
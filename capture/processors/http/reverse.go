@@ -0,0 +1,125 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// serveReverse 处理反向代理监听端的连接:客户端把 sniffy 当作源站直连,不发 CONNECT,
+// 也不出示 Proxy-Authorization。rt.TLS 时先终结 TLS,证书按 SNI 主机(没有 SNI 时按客户端
+// 连入的本机地址)选取,优先用导入的服务端证书,否则由 CA 现签;之后的每个请求都改写到
+// 上游,照常经 flow 管道(规则、插件、断点)转发。
+func (p *Processor) serveReverse(server types.Server, rt *types.ReverseTarget) error {
+	p.reverse = rt
+	p.proxyTunnel = true
+	reader := p.conn.GetReader()
+	if !rt.TLS {
+		return p.handleHttpProtocol(server, reader, p.conn.GetWriter())
+	}
+
+	client := p.conn.GetConn()
+	hello, err := PeekClientHello(client, reader)
+	if err != nil {
+		server.LogError("反向代理端口要求 TLS,读取 ClientHello 失败: %v", err)
+		return err
+	}
+	host := hello.ServerName
+	if host == "" {
+		host = localHost(client)
+	}
+	p.request = &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: host},
+		Host:   host,
+		Header: make(http.Header),
+	}
+	p.isHttps = true
+	return p.handleTlsHandshake(server, reader)
+}
+
+// localHost 返回客户端连入的本机地址(不含端口),取不到时为空串。
+func localHost(conn net.Conn) string {
+	if conn == nil || conn.LocalAddr() == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(conn.LocalAddr().String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// reverseRequest 把入站请求改写到上游:scheme/host 取上游,路径拼在上游基础路径之后,
+// 上游自带的查询参数排在请求参数之前。未要求保留 Host 时 Host 头(含原始头序列中的那一项)
+// 一并改为上游主机,否则上游按虚拟主机路由会落到别的站点。
+func reverseRequest(r *http.Request, rt *types.ReverseTarget) *http.Request {
+	up := rt.Upstream
+	r.URL.Scheme = up.Scheme
+	r.URL.Host = up.Host
+	r.URL.Path, r.URL.RawPath = joinURLPath(up, r.URL)
+	switch {
+	case up.RawQuery == "":
+	case r.URL.RawQuery == "":
+		r.URL.RawQuery = up.RawQuery
+	default:
+		r.URL.RawQuery = up.RawQuery + "&" + r.URL.RawQuery
+	}
+	r.RequestURI = ""
+	if rt.PreserveHost {
+		return r
+	}
+	r.Host = up.Host
+	if raw, ok := flow.RawHeadersFrom(r.Context()); ok {
+		rewritten := make([][2]string, len(raw))
+		for i, kv := range raw {
+			if strings.EqualFold(kv[0], "Host") {
+				kv[1] = up.Host
+			}
+			rewritten[i] = kv
+		}
+		r = r.WithContext(flow.WithRawHeaders(r.Context(), rewritten))
+	}
+	return r
+}
+
+// joinURLPath 把请求路径拼在上游基础路径之后,两者交界处只保留一个斜杠;请求带有转义
+// 形式(RawPath)时同步拼出转义路径。
+func joinURLPath(base, req *url.URL) (path, rawPath string) {
+	if base.Path == "" || base.Path == "/" {
+		return req.Path, req.RawPath
+	}
+	if req.RawPath == "" && base.RawPath == "" {
+		return singleJoiningSlash(base.Path, req.Path), ""
+	}
+	return singleJoiningSlash(base.Path, req.Path), singleJoiningSlash(base.EscapedPath(), req.EscapedPath())
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash && b != "":
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// reverseProtocol 按上游 scheme 返回 flow 协议:反代的 flow 记录的是发往上游的请求。
+func reverseProtocol(r *http.Request) string {
+	if r.URL.Scheme == "https" {
+		return flow.ProtoHTTPS
+	}
+	return flow.ProtoHTTP
+}
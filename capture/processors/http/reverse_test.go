@@ -0,0 +1,162 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// revConn 模拟反向代理监听端 accept 到的连接。
+type revConn struct {
+	net.Conn
+	target *types.ReverseTarget
+}
+
+func (c *revConn) ReverseTarget() *types.ReverseTarget { return c.target }
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestReverseRequest(t *testing.T) {
+	for _, tc := range []struct {
+		upstream, target string
+		preserve         bool
+		wantURL          string
+		wantHost         string
+	}{
+		{"http://127.0.0.1:3000", "/users?id=1", false, "http://127.0.0.1:3000/users?id=1", "127.0.0.1:3000"},
+		{"https://api.example/v1/", "/users", false, "https://api.example/v1/users", "api.example"},
+		{"https://api.example/v1", "/users", false, "https://api.example/v1/users", "api.example"},
+		{"http://api.example/v1?key=k", "/a?b=c", false, "http://api.example/v1/a?key=k&b=c", "api.example"},
+		{"http://api.example?key=k", "/", true, "http://api.example/?key=k", "app.local"},
+		{"http://api.example/base", "/a%2Fb", false, "http://api.example/base/a%2Fb", "api.example"},
+	} {
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(
+			"GET " + tc.target + " HTTP/1.1\r\nHost: app.local\r\n\r\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(flow.WithRawHeaders(req.Context(), [][2]string{{"host", "app.local"}, {"Accept", "*/*"}}))
+		rt := &types.ReverseTarget{Upstream: mustURL(t, tc.upstream), PreserveHost: tc.preserve}
+		got := reverseRequest(req, rt)
+		if got.URL.String() != tc.wantURL || got.Host != tc.wantHost || got.RequestURI != "" {
+			t.Errorf("%s + %s: url=%s host=%s, want %s %s", tc.upstream, tc.target, got.URL, got.Host, tc.wantURL, tc.wantHost)
+		}
+		raw, _ := flow.RawHeadersFrom(got.Context())
+		if raw[0] != [2]string{"host", tc.wantHost} {
+			t.Errorf("%s: raw Host header = %v, want %s (original casing kept)", tc.upstream, raw[0], tc.wantHost)
+		}
+	}
+}
+
+// startReverse 在管道一端以反代连接运行处理器,返回客户端一端与处理结果。
+func startReverse(t *testing.T, rt *types.ReverseTarget) (net.Conn, chan error) {
+	t.Helper()
+	client, proxySide := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	conn := types.NewConnection(&revConn{Conn: proxySide, target: rt}, newMockServer())
+	done := make(chan error, 1)
+	go func() { done <- New(conn).Process() }()
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	return client, done
+}
+
+// echoOrigin 回显上游实际收到的 Host 与请求路径。
+func echoOrigin(t *testing.T) *httptest.Server {
+	t.Helper()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL.RequestURI())
+	}))
+	t.Cleanup(origin.Close)
+	return origin
+}
+
+func readBody(t *testing.T, r *bufio.Reader, req *http.Request) string {
+	t.Helper()
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestServeReverseForwardsToUpstream(t *testing.T) {
+	origin := echoOrigin(t)
+	rt := &types.ReverseTarget{Upstream: mustURL(t, origin.URL+"/base?k=v")}
+	client, _ := startReverse(t, rt)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://app.local/users?id=1", nil)
+	if err := req.Write(client); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.TrimPrefix(origin.URL, "http://") + " /base/users?k=v&id=1"
+	if got := readBody(t, bufio.NewReader(client), req); got != want {
+		t.Fatalf("origin saw %q, want %q", got, want)
+	}
+}
+
+func TestServeReverseRejectsConnect(t *testing.T) {
+	rt := &types.ReverseTarget{Upstream: mustURL(t, "http://127.0.0.1:1")}
+	client, done := startReverse(t, rt)
+
+	if _, err := io.WriteString(client, "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(client), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("CONNECT on reverse port = %d, want 405", resp.StatusCode)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+}
+
+// TestServeReverseTerminatesTLS 锁定 TLS 终结:证书按 SNI 签发,解密后的请求保留 Host 转发到上游。
+func TestServeReverseTerminatesTLS(t *testing.T) {
+	origin := echoOrigin(t)
+	rt := &types.ReverseTarget{Upstream: mustURL(t, origin.URL), TLS: true, PreserveHost: true}
+	client, _ := startReverse(t, rt)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA().GetCA())
+	tc := tls.Client(client, &tls.Config{ServerName: "dev.local", RootCAs: roots, NextProtos: []string{"http/1.1"}})
+	if err := tc.Handshake(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://dev.local/ping", nil)
+	if err := req.Write(tc); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBody(t, bufio.NewReader(tc), req); got != "dev.local /ping" {
+		t.Fatalf("origin saw %q, want %q", got, "dev.local /ping")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// gRPC-Web 与 Connect 的分帧。
+//
+// 两者都沿用 gRPC 的 5 字节信封(1 字节标志 + 4 字节大端长度),差别在标志位与承载:
+//   - gRPC-Web:HTTP/1.1 上也可用;尾部(grpc-status 等)不走 HTTP trailer,而是作为
+//     标志位 0x80 的最后一帧嵌在 body 里,内容是 HTTP/1 头格式的文本。-text 变体把整个
+//     body 做 base64(每条消息单独编码后拼接,中途可出现填充)。
+//   - Connect:流式 RPC 用 application/connect+<codec>,结束帧标志位 0x02,内容是 JSON
+//     (错误与尾部元数据);unary RPC 不带信封,整个 body 即一条消息,压缩走 Content-Encoding。
+
+// 结束帧标志位。
+const (
+	grpcWebTrailerFlag   = 0x80
+	connectEndStreamFlag = 0x02
+)
+
+// isGRPCWebContentType 判断 Content-Type 是否为 gRPC-Web(含 -text 变体)。
+func isGRPCWebContentType(ct string) bool {
+	b := contentTypeBase(ct)
+	return b == "application/grpc-web" || strings.HasPrefix(b, "application/grpc-web+") ||
+		b == "application/grpc-web-text" || strings.HasPrefix(b, "application/grpc-web-text+")
+}
+
+// isGRPCWebText 判断 Content-Type 是否为 base64 承载的 gRPC-Web(application/grpc-web-text*)。
+func isGRPCWebText(ct string) bool {
+	return strings.HasPrefix(contentTypeBase(ct), "application/grpc-web-text")
+}
+
+// isConnectStreamContentType 判断 Content-Type 是否为 Connect 流式 RPC(application/connect+<codec>)。
+func isConnectStreamContentType(ct string) bool {
+	return strings.HasPrefix(contentTypeBase(ct), "application/connect+")
+}
+
+// isConnectUnary 判断一次请求/响应是否为 Connect unary:请求带 Connect-Protocol-Version,
+// 且 body 是裸的 proto / JSON 消息。
+func isConnectUnary(reqHeader http.Header, ct string) bool {
+	if reqHeader.Get("Connect-Protocol-Version") == "" {
+		return false
+	}
+	b := contentTypeBase(ct)
+	return b == "application/proto" || b == "application/json"
+}
+
+// rpcRequestKind 据请求 Content-Type 判定带信封的 RPC 流类型(gRPC / gRPC-Web / Connect 流式),
+// 其余返回空串。Connect unary 的请求体就是一条完整消息,按普通请求转发,这里不算。
+func rpcRequestKind(h http.Header) string {
+	ct := h.Get("Content-Type")
+	switch {
+	case isGRPCWebContentType(ct):
+		return flow.StreamGRPCWeb
+	case isGRPCContentType(ct):
+		return flow.StreamGRPC
+	case isConnectStreamContentType(ct):
+		return flow.StreamConnect
+	}
+	return ""
+}
+
+// connectEncoding 取 Connect 流式消息的压缩编码(Connect-Content-Encoding);identity 视为空。
+func connectEncoding(h http.Header) string {
+	enc := strings.ToLower(strings.TrimSpace(h.Get("Connect-Content-Encoding")))
+	if enc == "identity" {
+		return ""
+	}
+	return enc
+}
+
+// newRPCScanner 按流类型与该方向的头部构造帧扫描器。
+func newRPCScanner(kind string, h http.Header) *grpcScanner {
+	s := &grpcScanner{}
+	ct := h.Get("Content-Type")
+	switch kind {
+	case flow.StreamGRPCWeb:
+		s.encoding = grpcEncoding(h)
+		s.endFlag = grpcWebTrailerFlag
+		if isGRPCWebText(ct) {
+			s.text = &base64Stream{}
+		}
+	case flow.StreamConnect:
+		if isConnectStreamContentType(ct) {
+			s.encoding = connectEncoding(h)
+			s.endFlag = connectEndStreamFlag
+		} else {
+			s.unary = true
+		}
+	default:
+		s.encoding = grpcEncoding(h)
+	}
+	return s
+}
+
+// rpcEndEvent 返回结束帧记录时使用的 EventType。
+func rpcEndEvent(kind string) string {
+	if kind == flow.StreamConnect {
+		return "end"
+	}
+	return "trailers"
+}
+
+// base64Stream 增量解码 gRPC-Web text 的 base64 body。按 4 字符一组解码,不足一组的
+// 留到下次;每组独立解码,因而容忍中途的填充。一旦遇到非法输入或放弃解析,后续
+// 字节累积到 tail 原样透传。
+type base64Stream struct {
+	pending []byte // 不足 4 字符的残留
+	tail    []byte // 放弃解码后待原样透传的文本
+	broken  bool
+}
+
+// decode 解码 p(连同上次残留)中完整的 4 字符组;输入非法时返回 ok=false 且不改动状态。
+func (d *base64Stream) decode(p []byte) ([]byte, bool) {
+	in := append(append([]byte(nil), d.pending...), p...)
+	n := len(in) / 4 * 4
+	out := make([]byte, 0, n/4*3)
+	var quad [3]byte
+	for i := 0; i < n; i += 4 {
+		m, err := base64.StdEncoding.Decode(quad[:], in[i:i+4])
+		if err != nil {
+			return nil, false
+		}
+		out = append(out, quad[:m]...)
+	}
+	d.pending = in[n:]
+	return out, true
+}
+
+// recordRPCFrame 仅记录一条 RPC 帧,不过钩子、不改写。用于结束帧(尾部元数据只供查看)
+// 与已整体缓冲转发的请求体消息。
+func recordRPCFrame(rec *streamRecorder, url, direction, kind string, fr grpcFrame) {
+	data, encoding := fr.Payload, ""
+	if fr.Compressed {
+		if plain, err := decompressGRPC(fr.Encoding, fr.Payload); err == nil {
+			data, encoding = plain, fr.Encoding
+		}
+	}
+	m := &flow.StreamMessage{
+		ID:        flow.NewID(),
+		FlowID:    rec.flowID(),
+		URL:       url,
+		Direction: direction,
+		Kind:      kind,
+		Encoding:  encoding,
+		Data:      data,
+		Timestamp: time.Now(),
+		Seq:       rec.nextSeq(),
+	}
+	if fr.End {
+		m.EventType = rpcEndEvent(kind)
+	} else {
+		m.JSON, m.Schema = decodeGRPCMessage(url, direction, data)
+	}
+	rec.add(m)
+}
+
+// recordRPCRequestBody 把已缓冲转发的请求体(HTTP/1.1 的 gRPC-Web / Connect)按消息记入会话。
+// 请求阶段插件已经看过整个 body,这里只补齐会话里 client->server 方向的消息。
+func recordRPCRequestBody(rec *streamRecorder, url, kind string, h http.Header, body []byte) {
+	if rec == nil || len(body) == 0 {
+		return
+	}
+	gc := newRPCScanner(kind, h)
+	frames := gc.push(body)
+	frames = append(frames, gc.finish()...)
+	for _, fr := range frames {
+		recordRPCFrame(rec, url, flow.WSClientToServer, kind, fr)
+	}
+}
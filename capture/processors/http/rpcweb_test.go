@@ -0,0 +1,226 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/pipeline"
+)
+
+// rpcFrameBytes 按任意标志位成帧(gRPC-Web 尾部帧、Connect 结束帧)。
+func rpcFrameBytes(flags byte, payload []byte) []byte {
+	out := make([]byte, 5+len(payload))
+	out[0] = flags
+	binary.BigEndian.PutUint32(out[1:5], uint32(len(payload)))
+	copy(out[5:], payload)
+	return out
+}
+
+func TestRPCContentTypeDetection(t *testing.T) {
+	kinds := map[string]string{
+		"application/grpc":                 flow.StreamGRPC,
+		"application/grpc+proto":           flow.StreamGRPC,
+		"application/grpc-web":             flow.StreamGRPCWeb,
+		"application/grpc-web+proto":       flow.StreamGRPCWeb,
+		"application/grpc-web-text":        flow.StreamGRPCWeb,
+		"application/grpc-web-text+proto":  flow.StreamGRPCWeb,
+		"application/connect+proto":        flow.StreamConnect,
+		"application/connect+json; x=1":    flow.StreamConnect,
+		"application/proto":                "",
+		"application/json":                 "",
+		"application/grpc-weird-extension": flow.StreamGRPC,
+	}
+	for ct, want := range kinds {
+		if got := rpcRequestKind(http.Header{"Content-Type": {ct}}); got != want {
+			t.Errorf("rpcRequestKind(%q)=%q want %q", ct, got, want)
+		}
+	}
+	if !isGRPCWebText("application/grpc-web-text+proto") || isGRPCWebText("application/grpc-web+proto") {
+		t.Error("isGRPCWebText 判定错误")
+	}
+}
+
+// Connect unary 的响应是裸 proto/JSON,只有请求带 Connect-Protocol-Version 时才按流中继。
+func TestDetectResponseStreamConnectUnary(t *testing.T) {
+	req := &http.Request{Header: http.Header{"Connect-Protocol-Version": {"1"}}}
+	resp := &http.Response{Header: http.Header{"Content-Type": {"application/json"}}, Request: req}
+	if got := detectResponseStream(resp); got != flow.StreamConnect {
+		t.Fatalf("Connect unary JSON 应判为 connect,得 %q", got)
+	}
+	resp.Request = &http.Request{Header: http.Header{}}
+	if got := detectResponseStream(resp); got != "" {
+		t.Fatalf("普通 JSON 响应不应判为流,得 %q", got)
+	}
+	resp.Request = nil
+	if got := detectResponseStream(resp); got != "" {
+		t.Fatalf("缺少请求时不应判为流,得 %q", got)
+	}
+}
+
+// gRPC-Web text:每条消息单独 base64 后拼接(中途带填充),任意切分读入都应正确分帧,尾部帧单独标记。
+func TestGRPCWebTextScanner(t *testing.T) {
+	msg := rpcFrameBytes(0, []byte("hello"))
+	trailer := rpcFrameBytes(grpcWebTrailerFlag, []byte("grpc-status: 0\r\n"))
+	body := base64.StdEncoding.EncodeToString(msg) + base64.StdEncoding.EncodeToString(trailer)
+
+	s := newRPCScanner(flow.StreamGRPCWeb, http.Header{"Content-Type": {"application/grpc-web-text+proto"}})
+	var frames []grpcFrame
+	for i := 0; i < len(body); i += 3 {
+		end := min(i+3, len(body))
+		frames = append(frames, s.push([]byte(body[i:end]))...)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("应解出 2 帧,得 %d", len(frames))
+	}
+	if string(frames[0].Payload) != "hello" || frames[0].End || frames[0].Compressed {
+		t.Fatalf("消息帧解析错误: %+v", frames[0])
+	}
+	if !frames[1].End || frames[1].Compressed || string(frames[1].Payload) != "grpc-status: 0\r\n" {
+		t.Fatalf("尾部帧解析错误: %+v", frames[1])
+	}
+	if got := string(s.wire(frames[0].Raw)); got != base64.StdEncoding.EncodeToString(msg) {
+		t.Fatalf("wire 应重新编码为 base64,得 %q", got)
+	}
+	if lo := s.flush(); len(lo) != 0 {
+		t.Fatalf("无残留时 flush 应为空,得 %q", lo)
+	}
+}
+
+// 非法 base64:已解出的字节重新编码,其余文本原样透传。
+func TestGRPCWebTextInvalidPassthrough(t *testing.T) {
+	s := newRPCScanner(flow.StreamGRPCWeb, http.Header{"Content-Type": {"application/grpc-web-text"}})
+	if fr := s.push([]byte("AAAA")); len(fr) != 0 {
+		t.Fatalf("不完整的帧不应产出: %+v", fr)
+	}
+	if fr := s.push([]byte("!!!!rest")); len(fr) != 0 || !s.overflow {
+		t.Fatal("非法输入应转为原样透传")
+	}
+	s.push([]byte("more"))
+	if got := string(s.flush()); got != "AAAA!!!!restmore" {
+		t.Fatalf("透传内容错误: %q", got)
+	}
+}
+
+func TestPumpResponseStreamGRPCWebText(t *testing.T) {
+	sink := &fakeStreamSink{}
+	withStreamSink(t, sink)
+	hooked := 0
+	p := pipeline.New(nil, nil)
+	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+		hooked++
+		m.Data = []byte("XX")
+		return flow.ContinueDecision()
+	}})
+	withPipeline(t, p)
+
+	trailer := rpcFrameBytes(grpcWebTrailerFlag, []byte("grpc-status: 0\r\n"))
+	body := base64.StdEncoding.EncodeToString(rpcFrameBytes(0, []byte("orig"))) + base64.StdEncoding.EncodeToString(trailer)
+	f := flow.New(flow.ProtoHTTP)
+	f.Request = &flow.Request{URL: "http://x/svc/M"}
+	rec := newStreamRecorder(f, flow.StreamGRPCWeb)
+	sw := &captureStreamWriter{}
+	hdr := http.Header{"Content-Type": {"application/grpc-web-text"}}
+	if err := pumpResponseStream(silentServer{}, rec, "http://x/svc/M", flow.StreamGRPCWeb, hdr, bytes.NewReader([]byte(body)), sw); err != nil {
+		t.Fatal(err)
+	}
+	rec.close()
+
+	if hooked != 1 {
+		t.Fatalf("尾部帧不应过钩子,钩子调用 %d 次", hooked)
+	}
+	want := base64.StdEncoding.EncodeToString(rpcFrameBytes(0, []byte("XX"))) + base64.StdEncoding.EncodeToString(trailer)
+	if got := string(sw.body()); got != want {
+		t.Fatalf("写回内容错误:\n got=%q\nwant=%q", got, want)
+	}
+	ss := sink.snapshot()
+	if ss == nil || len(ss.Messages) != 2 {
+		t.Fatalf("会话应记录 2 条消息,得 %+v", ss)
+	}
+	if m := ss.Messages[1]; m.Kind != flow.StreamGRPCWeb || m.EventType != "trailers" || string(m.Data) != "grpc-status: 0\r\n" {
+		t.Fatalf("尾部帧记录错误: %+v", m)
+	}
+}
+
+func TestPumpResponseStreamConnectEndStream(t *testing.T) {
+	sink := &fakeStreamSink{}
+	withStreamSink(t, sink)
+	p := pipeline.New(nil, nil)
+	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+		m.Data = bytes.ToUpper(m.Data)
+		return flow.ContinueDecision()
+	}})
+	withPipeline(t, p)
+
+	end := rpcFrameBytes(connectEndStreamFlag, []byte(`{"error":{"code":"not_found"}}`))
+	body := append(rpcFrameBytes(0, []byte(`{"n":1}`)), end...)
+	f := flow.New(flow.ProtoHTTP)
+	f.Request = &flow.Request{URL: "http://x/svc/M"}
+	rec := newStreamRecorder(f, flow.StreamConnect)
+	sw := &captureStreamWriter{}
+	hdr := http.Header{"Content-Type": {"application/connect+json"}}
+	if err := pumpResponseStream(silentServer{}, rec, "http://x/svc/M", flow.StreamConnect, hdr, bytes.NewReader(body), sw); err != nil {
+		t.Fatal(err)
+	}
+	rec.close()
+
+	want := append(rpcFrameBytes(0, []byte(`{"N":1}`)), end...)
+	if !bytes.Equal(sw.body(), want) {
+		t.Fatalf("写回内容错误: %q", sw.body())
+	}
+	ss := sink.snapshot()
+	if ss == nil || len(ss.Messages) != 2 || ss.Messages[1].EventType != "end" {
+		t.Fatalf("结束帧应记为 end 事件,得 %+v", ss)
+	}
+}
+
+// Connect unary:整个 body 是一条消息,读尽后过钩子,改写后原样写回新载荷(不加信封)。
+func TestPumpResponseStreamConnectUnary(t *testing.T) {
+	p := pipeline.New(nil, nil)
+	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+		m.Data = []byte(`{"ok":false}`)
+		return flow.ContinueDecision()
+	}})
+	withPipeline(t, p)
+
+	r := io.MultiReader(strings.NewReader(`{"ok":`), strings.NewReader(`true}`))
+	sw := &captureStreamWriter{}
+	hdr := http.Header{"Content-Type": {"application/json"}}
+	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamConnect, hdr, r, sw); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(sw.body()); got != `{"ok":false}` {
+		t.Fatalf("unary 改写后应写回新载荷,得 %q", got)
+	}
+}
+
+func TestRecordRPCRequestBody(t *testing.T) {
+	sink := &fakeStreamSink{}
+	withStreamSink(t, sink)
+	f := flow.New(flow.ProtoHTTP)
+	f.Request = &flow.Request{URL: "http://x/svc/M"}
+	rec := newStreamRecorder(f, flow.StreamGRPCWeb)
+
+	body := base64.StdEncoding.EncodeToString(rpcFrameBytes(0, []byte("req")))
+	recordRPCRequestBody(rec, "http://x/svc/M", flow.StreamGRPCWeb, http.Header{"Content-Type": {"application/grpc-web-text"}}, []byte(body))
+	recordRPCRequestBody(rec, "http://x/svc/M", flow.StreamConnect, http.Header{"Content-Type": {"application/json"}}, []byte(`{"a":1}`))
+
+	ss := sink.snapshot()
+	if ss == nil || len(ss.Messages) != 2 {
+		t.Fatalf("应记录 2 条请求消息,得 %+v", ss)
+	}
+	if m := ss.Messages[0]; m.Direction != flow.WSClientToServer || string(m.Data) != "req" {
+		t.Fatalf("gRPC-Web 请求消息错误: %+v", m)
+	}
+	if m := ss.Messages[1]; string(m.Data) != `{"a":1}` {
+		t.Fatalf("Connect unary 请求消息错误: %+v", m)
+	}
+}
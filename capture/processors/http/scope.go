@@ -54,11 +54,21 @@ func SetDecryptScope(enabled bool, mode string, allow, deny []string) {
 
 // shouldDecrypt 报告某个 CONNECT 目标(host:port)是否应被 MITM 解密。
 func shouldDecrypt(hostport string) bool {
-	sc := decryptScopePtr.Load()
+	return shouldDecryptFor("", hostport)
+}
+
+// shouldDecryptFor 同 shouldDecrypt,但按给定监听端(见 SetListenerPolicy)取解密范围。
+// 因握手反复失败而进入自动绕过列表的主机一律不解密。
+func shouldDecryptFor(listener, hostport string) bool {
+	host := hostOnly(hostport)
+	if autoBypassed(host) {
+		return false
+	}
+	sc := decryptScopeFor(listener)
 	if sc == nil {
 		return true
 	}
-	return sc.allows(hostOnly(hostport))
+	return sc.allows(host)
 }
 
 // hostOnly 去掉端口并小写,得到用于匹配的主机名。
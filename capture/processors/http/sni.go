@@ -0,0 +1,82 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// defaultTLSPort 是没有原始目标可参照时,按 SNI 拼出目标所用的端口。
+const defaultTLSPort = "443"
+
+var (
+	errNoTLSTarget       = errors.New("TLS 连接既没有 SNI 也没有原始目标,无法确定转发目标")
+	errDirectTLSNeedAuth = errors.New("已启用代理认证,拒绝无法出示凭据的直连 TLS")
+)
+
+// serveClientTLS 处理客户端直接发起、没有 CONNECT 在前的 TLS 连接(透明代理、DNS 劫持、
+// 客户端直连代理端口等)。目标取 ClientHello 的 SNI,端口沿用 dst 的(dst 为空时按 443);
+// 没有 SNI 时退回 dst。范围内 MITM,证书按 SNI 主机签发;范围外,或客户端只提议 HTTP 以外
+// 的 ALPN(MITM 后无从解析)时原样转发,有 dst 时连 dst,否则连 SNI 目标。
+func (p *Processor) serveClientTLS(server types.Server, reader *bufio.Reader, dst string) error {
+	client := p.conn.GetConn()
+	hello, err := PeekClientHello(client, reader)
+	if err != nil {
+		server.LogDebug("解析 ClientHello 失败: %v", err)
+		hello = &ClientHello{}
+	}
+
+	target := dst
+	if hello.ServerName != "" {
+		port := portOf(dst)
+		if port == "" {
+			port = defaultTLSPort
+		}
+		target = net.JoinHostPort(hello.ServerName, port)
+	}
+	if target == "" {
+		server.LogError("%v", errNoTLSTarget)
+		return errNoTLSTarget
+	}
+	p.request = &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: target},
+		Host:   target,
+		Header: make(http.Header),
+	}
+
+	if !p.shouldDecrypt(target) || !mitmCapableALPN(hello.ALPN) {
+		dial := dst
+		if dial == "" {
+			dial = target
+		}
+		server.LogDebug("TLS 目标 %s 不解密，直通转发到 %s", target, dial)
+		return p.relayTo(server, reader, dial)
+	}
+	server.LogDebug("按 SNI 解密 TLS: %s (ALPN %v)", target, hello.ALPN)
+	p.isHttps = true
+	return p.handleTlsHandshake(server, reader)
+}
+
+// mitmCapableALPN 报告 MITM 后能否解析该连接:客户端未提议 ALPN(默认 HTTP/1.1),或提议中
+// 含 h2 / http/1.1。
+func mitmCapableALPN(alpn []string) bool {
+	return len(alpn) == 0 || slices.Contains(alpn, "h2") || slices.Contains(alpn, "http/1.1")
+}
+
+// bufferedTLS 报告协议探测阶段已缓冲的首字节是否为 TLS 握手记录,即客户端直接对代理端口
+// 发起了 TLS。只看已缓冲字节,不为此阻塞读取。
+func bufferedTLS(reader *bufio.Reader) bool {
+	head, _ := reader.Peek(reader.Buffered())
+	return len(head) > 0 && head[0] == TLSHandshakeRecordType
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMitmCapableALPN(t *testing.T) {
+	for _, tc := range []struct {
+		alpn []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"h2", "http/1.1"}, true},
+		{[]string{"http/1.1"}, true},
+		{[]string{"imap"}, false},
+		{[]string{"mqtt", "x-custom"}, false},
+	} {
+		if got := mitmCapableALPN(tc.alpn); got != tc.want {
+			t.Errorf("mitmCapableALPN(%v) = %v, want %v", tc.alpn, got, tc.want)
+		}
+	}
+}
+
+// startDirectTLS 让客户端直接对代理说 TLS,并像协议探测那样先把首包读进缓冲。
+// 返回客户端连接、握手结果与 Process 的返回值。
+func startDirectTLS(t *testing.T, serverName string) (*tls.Conn, chan error, chan error) {
+	t.Helper()
+	client, proxySide := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA().GetCA())
+	tc := tls.Client(client, &tls.Config{ServerName: serverName, RootCAs: roots})
+	_ = tc.SetDeadline(time.Now().Add(5 * time.Second))
+	hs := make(chan error, 1)
+	go func() { hs <- tc.Handshake() }()
+
+	conn := newMockConnection(proxySide, newMockServer())
+	if _, err := conn.GetReader().Peek(1); err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- New(conn).Process() }()
+	return tc, hs, done
+}
+
+// TestProcessDirectTLSIssuesCertForSNI 锁定直连代理端口的 TLS 按 SNI 签发伪造证书并解密。
+func TestProcessDirectTLSIssuesCertForSNI(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "all", nil, nil)
+
+	tc, hs, done := startDirectTLS(t, "direct.example")
+	if err := <-hs; err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	state := tc.ConnectionState()
+	if err := state.PeerCertificates[0].VerifyHostname("direct.example"); err != nil {
+		t.Fatalf("cert not issued for SNI: %v", err)
+	}
+	_ = tc.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process did not return")
+	}
+}
+
+// TestProcessDirectTLSRefusedWhenAuthRequired 锁定开启代理认证后,无法出示凭据的直连 TLS
+// 不会被当作开放代理使用。
+func TestProcessDirectTLSRefusedWhenAuthRequired(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "all", nil, nil)
+	SetProxyAuth(true, "sniffy", "s3cret")
+	t.Cleanup(func() { SetProxyAuth(false, "", "") })
+
+	_, _, done := startDirectTLS(t, "direct.example")
+	select {
+	case err := <-done:
+		if !errors.Is(err, errDirectTLSNeedAuth) {
+			t.Fatalf("Process = %v, want errDirectTLSNeedAuth", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process did not return")
+	}
+}
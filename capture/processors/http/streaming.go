@@ -11,12 +11,14 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
 	"time"
@@ -62,23 +64,31 @@ func contentTypeBase(ct string) string {
 	return strings.ToLower(strings.TrimSpace(ct))
 }
 
-// isGRPCContentType 判断 Content-Type 是否为 gRPC(application/grpc 及其子类型)。
+// isGRPCContentType 判断 Content-Type 是否为原生 gRPC(application/grpc 及其子类型,
+// 不含 gRPC-Web,见 isGRPCWebContentType)。
 func isGRPCContentType(ct string) bool {
 	b := contentTypeBase(ct)
+	if isGRPCWebContentType(b) {
+		return false
+	}
 	return b == "application/grpc" || strings.HasPrefix(b, "application/grpc+") || strings.HasPrefix(b, "application/grpc-")
 }
 
-// grpcRequest 判断请求是否为 gRPC(据请求 Content-Type)。
-func grpcRequest(h http.Header) bool { return isGRPCContentType(h.Get("Content-Type")) }
-
 // detectResponseStream 据响应头判定是否为流式响应及其类型(空串表示非流)。
+// Connect unary 需结合请求头判定,取自 resp.Request(未设置时不识别)。
 func detectResponseStream(resp *http.Response) string {
 	ct := contentTypeBase(resp.Header.Get("Content-Type"))
 	switch {
 	case ct == "text/event-stream":
 		return flow.StreamSSE
+	case isGRPCWebContentType(ct):
+		return flow.StreamGRPCWeb
 	case isGRPCContentType(ct):
 		return flow.StreamGRPC
+	case isConnectStreamContentType(ct):
+		return flow.StreamConnect
+	case resp.Request != nil && isConnectUnary(resp.Request.Header, ct):
+		return flow.StreamConnect
 	case ct == "application/x-ndjson" || ct == "application/stream+json" || ct == "application/x-json-stream":
 		return flow.StreamChunk
 	}
@@ -86,9 +96,9 @@ func detectResponseStream(resp *http.Response) string {
 }
 
 // streamingIntent 据请求头预判该请求可能产出/承载流(用于选用无总超时的上游客户端)。
-// 命中 gRPC 或客户端显式 Accept: text/event-stream(EventSource 必带)。
+// 命中 gRPC / gRPC-Web / Connect 流式,或客户端显式 Accept: text/event-stream(EventSource 必带)。
 func streamingIntent(req *http.Request) bool {
-	if grpcRequest(req.Header) {
+	if rpcRequestKind(req.Header) != "" {
 		return true
 	}
 	return strings.Contains(strings.ToLower(req.Header.Get("Accept")), "text/event-stream")
@@ -203,23 +213,53 @@ func reserializeSSE(eventType string, data []byte) []byte {
 }
 
 // grpcFrame 是一条 gRPC length-prefixed 帧:Raw 含 5 字节前缀,Payload 为去前缀的消息。
+// Connect unary 没有信封,此时 Unary 为 true,Raw 与 Payload 都是整个 body。
 type grpcFrame struct {
 	Raw        []byte
 	Payload    []byte
 	Compressed bool
+	Encoding   string // 压缩帧所用的 grpc-encoding;未声明时为空
+	End        bool   // gRPC-Web 尾部帧 / Connect 结束帧
+	Unary      bool
 }
 
-// grpcScanner 增量解析 gRPC 帧流(1 字节压缩标志 + 4 字节大端长度 + 消息)。
+// grpcScanner 增量解析 gRPC 帧流(1 字节标志 + 4 字节大端长度 + 消息)。零值即原生 gRPC;
+// gRPC-Web / Connect 的差异由 newRPCScanner 配置。
 type grpcScanner struct {
 	buf      []byte
-	overflow bool // 命中超大长度:放弃逐帧解析,转为原样透传
+	overflow bool          // 命中超大长度:放弃逐帧解析,转为原样透传
+	encoding string        // 本方向的消息压缩编码,标注到压缩帧上
+	endFlag  byte          // 结束帧标志位(gRPC-Web 0x80,Connect 0x02);原生 gRPC 为 0
+	unary    bool          // Connect unary:整个 body 即一条消息,读尽后由 finish 产出
+	text     *base64Stream // gRPC-Web text:输入先做 base64 解码,写回时由 wire 重新编码
 }
 
 func (s *grpcScanner) push(p []byte) []grpcFrame {
+	if s.text != nil {
+		if s.text.broken {
+			s.text.tail = append(s.text.tail, p...)
+			return nil
+		}
+		plain, ok := s.text.decode(p)
+		if !ok { // 非法 base64:已解出的部分由 flush 重新编码,其余原样透传
+			s.text.broken = true
+			s.text.tail = append(append(s.text.tail, s.text.pending...), p...)
+			s.text.pending = nil
+			s.overflow = true
+			return nil
+		}
+		p = plain
+	}
 	s.buf = append(s.buf, p...)
 	if s.overflow {
 		return nil
 	}
+	if s.unary {
+		if len(s.buf) > grpcMaxMessage {
+			s.overflow = true
+		}
+		return nil
+	}
 	var out []grpcFrame
 	for {
 		if len(s.buf) < 5 {
@@ -228,6 +268,10 @@ func (s *grpcScanner) push(p []byte) []grpcFrame {
 		n := binary.BigEndian.Uint32(s.buf[1:5])
 		if int(n) > grpcMaxMessage {
 			s.overflow = true // 异常/超大:停止解析,后续 flush 原样透传
+			if s.text != nil {
+				s.text.broken = true
+				s.text.tail, s.text.pending = s.text.pending, nil
+			}
 			break
 		}
 		total := 5 + int(n)
@@ -235,19 +279,55 @@ func (s *grpcScanner) push(p []byte) []grpcFrame {
 			break
 		}
 		raw := append([]byte(nil), s.buf[:total]...)
-		out = append(out, grpcFrame{Raw: raw, Payload: raw[5:], Compressed: raw[0] != 0})
+		flags := raw[0]
+		fr := grpcFrame{Raw: raw, Payload: raw[5:]}
+		if s.endFlag != 0 {
+			fr.End = flags&s.endFlag != 0
+			fr.Compressed = flags&1 != 0
+		} else {
+			fr.Compressed = flags != 0
+		}
+		if fr.Compressed {
+			fr.Encoding = s.encoding
+		}
+		out = append(out, fr)
 		s.buf = s.buf[total:]
 	}
 	return out
 }
 
+// finish 在读尽 body 后产出 Connect unary 的那一条消息;其余模式返回 nil。
+func (s *grpcScanner) finish() []grpcFrame {
+	if !s.unary || s.overflow || len(s.buf) == 0 {
+		return nil
+	}
+	b := s.buf
+	s.buf = nil
+	return []grpcFrame{{Raw: b, Payload: b, Unary: true}}
+}
+
+// flush 返回残留字节(线缆形式):text 模式下重新编码已解出的部分,并接上未解码的文本。
 func (s *grpcScanner) flush() []byte {
 	b := s.buf
 	s.buf = nil
+	if s.text != nil {
+		b = append(s.wire(b), s.text.pending...)
+		b = append(b, s.text.tail...)
+		s.text.pending, s.text.tail = nil, nil
+	}
 	return b
 }
 
-// reframeGRPC 在插件改动了未压缩消息载荷时重建一帧(压缩帧不支持改写,调用方应回退原样)。
+// wire 把帧字节转为线缆形式:gRPC-Web text 逐帧重新做 base64(规范允许中途出现填充),
+// 其余原样返回。
+func (s *grpcScanner) wire(b []byte) []byte {
+	if s.text == nil || len(b) == 0 {
+		return b
+	}
+	return []byte(base64.StdEncoding.EncodeToString(b))
+}
+
+// reframeGRPC 在插件改动了未压缩消息载荷时重建一帧。
 func reframeGRPC(payload []byte) []byte {
 	out := make([]byte, 5+len(payload))
 	out[0] = 0
@@ -256,6 +336,21 @@ func reframeGRPC(payload []byte) []byte {
 	return out
 }
 
+// reframeGRPCEncoded 按原帧的 grpc-encoding 重新压缩改写后的消息并成帧;encoding 为空或
+// 压缩失败时退回未压缩帧(压缩标志为 0 的消息在任何编码下都合法)。
+func reframeGRPCEncoded(encoding string, payload []byte) []byte {
+	if encoding == "" {
+		return reframeGRPC(payload)
+	}
+	compressed, err := compressGRPC(encoding, payload)
+	if err != nil {
+		return reframeGRPC(payload)
+	}
+	out := reframeGRPC(compressed)
+	out[0] = 1
+	return out
+}
+
 // ============================ 会话记录器 ============================
 
 const maxStreamMessages = 500
@@ -568,11 +663,21 @@ func (w *h2StreamWriter) close() error { return nil } // h2 由框架在 handler
 // ============================ 中继引擎 ============================
 
 // emitStreamMessage 过插件钩子 + 记录,返回应写到客户端的字节(raw 表未改动时的原样回放)。
-// 插件 abort 时返回 errStreamAbort。
-func emitStreamMessage(rec *streamRecorder, url, direction, kind, eventType string, payload, raw []byte) ([]byte, error) {
+// RPC 消息(fr 非 nil)额外带 JSON 视图:插件改了视图(而非 Data)时按视图重新编码。
+// fr.Encoding 非空表示 payload 是按该编码解压后的消息,改写后按同一编码重新压缩;
+// Connect unary 无信封,改写后直接以新载荷回放。插件 abort 时返回 errStreamAbort。
+func emitStreamMessage(rec *streamRecorder, url, direction, kind, eventType string, fr *grpcFrame, payload, raw []byte) ([]byte, error) {
 	out := raw
 	data := payload
 	seq := rec.nextSeq()
+	encoding := ""
+	if fr != nil {
+		encoding = fr.Encoding
+	}
+	var view, schema string
+	if flow.IsRPCStream(kind) {
+		view, schema = decodeGRPCMessage(url, direction, payload)
+	}
 	if activePipeline != nil {
 		m := &flow.StreamMessage{
 			ID:        flow.NewID(),
@@ -581,7 +686,10 @@ func emitStreamMessage(rec *streamRecorder, url, direction, kind, eventType stri
 			Direction: direction,
 			Kind:      kind,
 			EventType: eventType,
+			Encoding:  encoding,
 			Data:      append([]byte(nil), payload...),
+			JSON:      view,
+			Schema:    schema,
 			Timestamp: time.Now(),
 			Seq:       seq,
 		}
@@ -589,16 +697,25 @@ func emitStreamMessage(rec *streamRecorder, url, direction, kind, eventType stri
 		if d.Kind == flow.Abort {
 			return nil, errStreamAbort
 		}
+		if flow.IsRPCStream(kind) && m.JSON != view && bytes.Equal(m.Data, payload) {
+			// 视图无法重新编码(JSON 非法、字段类型不符)时保留原消息。
+			if encoded, err := encodeGRPCMessage(url, direction, schema, m.JSON); err == nil {
+				m.Data = encoded
+			}
+		}
 		if !bytes.Equal(m.Data, payload) {
 			// 插件改写了载荷:按类型重建线缆字节。
-			switch kind {
-			case flow.StreamSSE:
+			switch {
+			case kind == flow.StreamSSE:
 				out = reserializeSSE(eventType, m.Data)
-			case flow.StreamGRPC:
-				out = reframeGRPC(m.Data) // 注:压缩帧由调用方保证不传入改写路径
+			case flow.IsRPCStream(kind) && (fr == nil || !fr.Unary):
+				out = reframeGRPCEncoded(encoding, m.Data)
 			default:
 				out = m.Data
 			}
+			if flow.IsRPCStream(kind) {
+				view, schema = decodeGRPCMessage(url, direction, m.Data)
+			}
 		}
 		data = m.Data
 	}
@@ -609,7 +726,10 @@ func emitStreamMessage(rec *streamRecorder, url, direction, kind, eventType stri
 		Direction: direction,
 		Kind:      kind,
 		EventType: eventType,
+		Encoding:  encoding,
 		Data:      data,
+		JSON:      view,
+		Schema:    schema,
 		Timestamp: time.Now(),
 		Seq:       seq,
 	})
@@ -623,11 +743,12 @@ func (r *streamRecorder) flowID() string {
 	return r.session.ID
 }
 
-// pumpResponseStream 单向中继上游响应体到客户端(SSE / chunk / gRPC 服务端方向)。
-// 逐消息解析、过钩子、记录、写回并 flush。读尽后回填响应尾部。
-func pumpResponseStream(server types.Server, rec *streamRecorder, url, kind string, body io.Reader, sw streamWriter) error {
+// pumpResponseStream 单向中继上游响应体到客户端(SSE / chunk / RPC 服务端方向)。
+// 逐消息解析、过钩子、记录、写回并 flush。读尽后回填响应尾部。header 是响应头,
+// RPC 流据此确定分帧方式与消息压缩编码(其余类型可为 nil)。
+func pumpResponseStream(server types.Server, rec *streamRecorder, url, kind string, header http.Header, body io.Reader, sw streamWriter) error {
 	sse := &sseScanner{}
-	grpc := &grpcScanner{}
+	grpc := newRPCScanner(kind, header)
 	buf := make([]byte, 32*1024)
 	for {
 		n, rerr := body.Read(buf)
@@ -637,6 +758,18 @@ func pumpResponseStream(server types.Server, rec *streamRecorder, url, kind stri
 			}
 		}
 		if rerr != nil {
+			if errors.Is(rerr, io.EOF) {
+				// Connect unary:整个 body 即一条消息,读尽才能交给钩子。
+				for _, fr := range grpc.finish() {
+					out, err := emitStreamMessageGRPC(rec, url, flow.WSServerToClient, kind, fr)
+					if err != nil {
+						return err
+					}
+					if err := sw.writeChunk(out); err != nil {
+						return err
+					}
+				}
+			}
 			// 把本次已读到但尚未组成完整消息的字节也交给客户端；若随后不是正常 EOF，
 			// 调用方会关闭 H1 连接或复位 H2 stream，客户端仍能察觉响应不完整。
 			if lo := leftover(kind, sse, grpc); len(lo) > 0 {
@@ -658,7 +791,7 @@ func dispatchChunk(rec *streamRecorder, url, direction, kind string, sse *sseSca
 	switch kind {
 	case flow.StreamSSE:
 		for _, ev := range sse.push(p) {
-			out, err := emitStreamMessage(rec, url, direction, kind, ev.Event, ev.Data, ev.Raw)
+			out, err := emitStreamMessage(rec, url, direction, kind, ev.Event, nil, ev.Data, ev.Raw)
 			if err != nil {
 				return err
 			}
@@ -666,16 +799,13 @@ func dispatchChunk(rec *streamRecorder, url, direction, kind string, sse *sseSca
 				return err
 			}
 		}
-	case flow.StreamGRPC:
+	case flow.StreamGRPC, flow.StreamGRPCWeb, flow.StreamConnect:
 		for _, fr := range grpc.push(p) {
-			// 压缩帧不参与改写(避免破坏 protobuf/压缩),仍记录并尊重 abort。
-			payload := fr.Payload
-			raw := fr.Raw
-			out, err := emitStreamMessageGRPC(rec, url, direction, fr, payload, raw)
+			out, err := emitStreamMessageGRPC(rec, url, direction, kind, fr)
 			if err != nil {
 				return err
 			}
-			if err := sw.writeChunk(out); err != nil {
+			if err := sw.writeChunk(grpc.wire(out)); err != nil {
 				return err
 			}
 		}
@@ -685,7 +815,7 @@ func dispatchChunk(rec *streamRecorder, url, direction, kind string, sse *sseSca
 			}
 		}
 	default: // chunk:原样按读入粒度透传并记录
-		out, err := emitStreamMessage(rec, url, direction, kind, "", p, p)
+		out, err := emitStreamMessage(rec, url, direction, kind, "", nil, p, p)
 		if err != nil {
 			return err
 		}
@@ -696,15 +826,25 @@ func dispatchChunk(rec *streamRecorder, url, direction, kind string, sse *sseSca
 	return nil
 }
 
-// emitStreamMessageGRPC 处理一条 gRPC 帧:压缩帧仅观察(不改写),非压缩帧可被插件改写。
-func emitStreamMessageGRPC(rec *streamRecorder, url, direction string, fr grpcFrame, payload, raw []byte) ([]byte, error) {
+// emitStreamMessageGRPC 处理一条 RPC 帧:压缩帧按声明的编码解压后与非压缩帧一样
+// 可被插件观察与改写;编码未知或解压失败的压缩帧仅观察(不改写)。gRPC-Web 尾部帧与
+// Connect 结束帧只记录,原样回放。
+func emitStreamMessageGRPC(rec *streamRecorder, url, direction, kind string, fr grpcFrame) ([]byte, error) {
+	payload, raw := fr.Payload, fr.Raw
+	if fr.End {
+		recordRPCFrame(rec, url, direction, kind, fr)
+		return raw, nil
+	}
 	if fr.Compressed {
-		// 压缩帧:仅记录与 abort,不改写(避免破坏压缩消息)。
+		if plain, err := decompressGRPC(fr.Encoding, payload); err == nil {
+			return emitStreamMessage(rec, url, direction, kind, "", &fr, plain, raw)
+		}
+		// 无法解压:仅记录与 abort,不改写(避免破坏压缩消息)。
 		seq := rec.nextSeq()
 		if activePipeline != nil {
 			hm := &flow.StreamMessage{
 				ID: flow.NewID(), FlowID: rec.flowID(), URL: url, Direction: direction,
-				Kind: flow.StreamGRPC, Data: append([]byte(nil), payload...), Timestamp: time.Now(), Seq: seq,
+				Kind: kind, Data: append([]byte(nil), payload...), Timestamp: time.Now(), Seq: seq,
 			}
 			if d := activePipeline.OnStreamMessage(context.Background(), hm); d.Kind == flow.Abort {
 				return nil, errStreamAbort
@@ -712,32 +852,32 @@ func emitStreamMessageGRPC(rec *streamRecorder, url, direction string, fr grpcFr
 		}
 		rec.add(&flow.StreamMessage{
 			ID: flow.NewID(), FlowID: rec.flowID(), URL: url, Direction: direction,
-			Kind: flow.StreamGRPC, Data: payload, Timestamp: time.Now(), Seq: seq,
+			Kind: kind, Data: payload, Timestamp: time.Now(), Seq: seq,
 		})
 		return raw, nil
 	}
-	return emitStreamMessage(rec, url, direction, flow.StreamGRPC, "", payload, raw)
+	return emitStreamMessage(rec, url, direction, kind, "", &fr, payload, raw)
 }
 
 // leftover 取扫描器结尾残留(EOF 时原样透传)。
 func leftover(kind string, sse *sseScanner, grpc *grpcScanner) []byte {
-	switch kind {
-	case flow.StreamSSE:
+	switch {
+	case kind == flow.StreamSSE:
 		return sse.flush()
-	case flow.StreamGRPC:
+	case flow.IsRPCStream(kind):
 		return grpc.flush()
 	}
 	return nil
 }
 
-// pumpGRPCFrames 把一段新读入字节切成 gRPC 帧并 emit + 写到 w(请求方向用)。
-func pumpGRPCFrames(rec *streamRecorder, url, direction string, gc *grpcScanner, p []byte, w io.Writer) error {
+// pumpGRPCFrames 把一段新读入字节切成 RPC 帧并 emit + 写到 w(请求方向用)。
+func pumpGRPCFrames(rec *streamRecorder, url, direction, kind string, gc *grpcScanner, p []byte, w io.Writer) error {
 	for _, fr := range gc.push(p) {
-		out, err := emitStreamMessageGRPC(rec, url, direction, fr, fr.Payload, fr.Raw)
+		out, err := emitStreamMessageGRPC(rec, url, direction, kind, fr)
 		if err != nil {
 			return err
 		}
-		if _, werr := w.Write(out); werr != nil {
+		if _, werr := w.Write(gc.wire(out)); werr != nil {
 			return werr
 		}
 	}
@@ -774,9 +914,18 @@ func runResponseStream(server types.Server, f *flow.Flow, kind string, resp *htt
 
 	var statusLine string
 	var rawHead [][2]string
-	if rc, ok := flow.ResponseCaptureFrom(request.Context()); ok && len(rc.Headers) > 0 {
-		statusLine, rawHead = rc.StatusLine, rc.Headers
-		f.Response.RawHeaders = rc.Headers
+	var trace *flow.PhaseTrace
+	if rc, ok := flow.ResponseCaptureFrom(request.Context()); ok {
+		f.SetUpstreamTLSFingerprint(rc.UpstreamTLS)
+		f.SetClientCert(rc.ClientCert)
+		f.SetUpstreamConn(rc.UpstreamConn)
+		f.SetUpstreamRoute(rc.Route)
+		f.SetPhases(rc.Trace.Phases(time.Time{})) // body 读尽时再补上接收耗时
+		trace = rc.Trace
+		if len(rc.Headers) > 0 {
+			statusLine, rawHead = rc.StatusLine, rc.Headers
+			f.Response.RawHeaders = rc.Headers
+		}
 	}
 
 	// 响应阶段插件(头部级:可改头 / abort;此时无完整 body)。
@@ -803,7 +952,12 @@ func runResponseStream(server types.Server, f *flow.Flow, kind string, resp *htt
 		url = f.Request.URL
 	}
 
-	perr := pumpResponseStream(server, rec, url, kind, bodyReader, sw)
+	if flow.IsRPCStream(kind) && f.Request != nil {
+		// HTTP/1.1 的 gRPC-Web / Connect:请求体已整体缓冲转发,这里补记其中的消息。
+		recordRPCRequestBody(rec, url, kind, request.Header, f.Request.Body)
+	}
+
+	perr := pumpResponseStream(server, rec, url, kind, resp.Header, bodyReader, sw)
 	if c, ok := bodyReader.(io.Closer); ok {
 		_ = c.Close() // 释放解码器资源(zstd 解码器持有 goroutine);resp.Body 的二次关闭是安全的
 	}
@@ -828,19 +982,25 @@ func runResponseStream(server types.Server, f *flow.Flow, kind string, resp *htt
 		f.Error = perr.Error()
 		r.disableReuse()
 	}
+	f.SetPhases(trace.Phases(time.Now()))
 	f.Timing.DurationMs = time.Since(f.Timing.RequestAt).Milliseconds()
 	finishFlow(f)
 	return perr
 }
 
-// runGRPCStream 处理 gRPC 双向流:在读取请求体之前接管,避免 io.ReadAll(req.Body) 死锁。
+// runGRPCStream 处理 h2 上的 RPC 双向流(gRPC / gRPC-Web / Connect 流式,据请求
+// Content-Type 区分):在读取请求体之前接管,避免 io.ReadAll(req.Body) 死锁。
 // 请求体经管道流式发往上游(逐帧过钩子/记录),响应体增量中继回客户端,两个方向并发。
 func runGRPCStream(server types.Server, request *http.Request, protocol string, clientAddr, proxyAddr net.Addr, r clientResponder, sw streamWriter) error {
+	kind := rpcRequestKind(request.Header)
+	if kind == "" {
+		kind = flow.StreamGRPC
+	}
 	f := buildStreamRequestFlow(request, protocol)
 	if clientAddr != nil {
 		f.Request.ClientIP = clientAddr.String()
 	}
-	f.Metadata["stream"] = flow.StreamGRPC
+	f.Metadata["stream"] = kind
 	if flowSink != nil {
 		flowSink.RecordFlowStarted(f)
 	}
@@ -857,8 +1017,11 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// 分阶段计时:双向流的请求体与响应交错,发送 / 等待两段仅供参考。
+	trace := &flow.PhaseTrace{}
+	ctx = httptrace.WithClientTrace(ctx, trace.ClientTrace())
 
-	rec := newStreamRecorder(f, flow.StreamGRPC)
+	rec := newStreamRecorder(f, kind)
 	url := f.Request.URL
 
 	pr, pw := io.Pipe()
@@ -879,12 +1042,12 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 	// 请求泵:client->server 逐帧解析/钩子/记录,写入管道供上游 transport 发送。
 	go func() {
 		defer pw.Close()
-		gc := &grpcScanner{}
+		gc := newRPCScanner(kind, outReq.Header)
 		buf := make([]byte, 32*1024)
 		for {
 			n, rerr := request.Body.Read(buf)
 			if n > 0 {
-				if perr := pumpGRPCFrames(rec, url, flow.WSClientToServer, gc, buf[:n], pw); perr != nil {
+				if perr := pumpGRPCFrames(rec, url, flow.WSClientToServer, kind, gc, buf[:n], pw); perr != nil {
 					_ = pw.CloseWithError(perr)
 					return
 				}
@@ -902,11 +1065,8 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 	if err != nil {
 		cancel()
 		server.LogError("gRPC 上游请求失败: %v", err)
-		f.State = flow.StateErrored
-		f.Error = err.Error()
 		rec.close()
-		finishFlow(f)
-		return r.writeBadGateway()
+		return failUpstream(f, err, r)
 	}
 	defer resp.Body.Close()
 
@@ -916,6 +1076,7 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 		StatusText: resp.Status,
 		Header:     flow.FromHTTPHeader(resp.Header),
 	}
+	f.SetPhases(trace.Phases(time.Time{}))
 	rec.setStatus(resp.StatusCode)
 
 	if activePipeline != nil {
@@ -938,7 +1099,7 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 		return err
 	}
 
-	perr := pumpResponseStream(server, rec, url, flow.StreamGRPC, resp.Body, sw)
+	perr := pumpResponseStream(server, rec, url, kind, resp.Header, resp.Body, sw)
 	if perr == nil {
 		if len(resp.Trailer) > 0 {
 			sw.setTrailer(resp.Trailer)
@@ -960,6 +1121,7 @@ func runGRPCStream(server types.Server, request *http.Request, protocol string,
 		f.Error = perr.Error()
 		r.disableReuse()
 	}
+	f.SetPhases(trace.Phases(time.Now()))
 	f.Timing.DurationMs = time.Since(f.Timing.RequestAt).Milliseconds()
 	finishFlow(f)
 	return perr
@@ -977,6 +1139,10 @@ func buildStreamRequestFlow(req *http.Request, protocol string) *flow.Flow {
 		Header:   flow.FromHTTPHeader(req.Header),
 		ClientIP: req.RemoteAddr,
 	}
+	f.Listener = flow.ListenerFrom(req.Context())
+	if fp, ok := flow.TLSFingerprintFrom(req.Context()); ok {
+		f.SetTLSFingerprint(fp)
+	}
 	return f
 }
 
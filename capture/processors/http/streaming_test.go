@@ -23,6 +23,7 @@ import (
 	"github.com/mintfog/sniffy/capture/types"
 	"github.com/mintfog/sniffy/internal/flow"
 	"github.com/mintfog/sniffy/internal/pipeline"
+	"github.com/mintfog/sniffy/internal/protoschema"
 )
 
 // ---- 测试替身 ----
@@ -226,6 +227,9 @@ func TestDetectResponseStream(t *testing.T) {
 		"text/event-stream; charset=u": flow.StreamSSE,
 		"application/grpc":             flow.StreamGRPC,
 		"application/grpc+proto":       flow.StreamGRPC,
+		"application/grpc-web+proto":   flow.StreamGRPCWeb,
+		"application/grpc-web-text":    flow.StreamGRPCWeb,
+		"application/connect+proto":    flow.StreamConnect,
 		"application/x-ndjson":         flow.StreamChunk,
 		"application/json":             "",
 		"text/html":                    "",
@@ -306,7 +310,7 @@ func TestPumpResponseStreamSSE(t *testing.T) {
 
 	body := bytes.NewReader([]byte("data: one\n\nevent: e\ndata: two\n\n"))
 	sw := &captureStreamWriter{}
-	if err := pumpResponseStream(silentServer{}, rec, "http://x/sse", flow.StreamSSE, body, sw); err != nil {
+	if err := pumpResponseStream(silentServer{}, rec, "http://x/sse", flow.StreamSSE, nil, body, sw); err != nil {
 		t.Fatal(err)
 	}
 	rec.close()
@@ -326,7 +330,7 @@ func TestPumpResponseStreamSSE(t *testing.T) {
 func TestPumpResponseStreamChunk(t *testing.T) {
 	body := bytes.NewReader([]byte(`{"a":1}` + "\n" + `{"b":2}` + "\n"))
 	sw := &captureStreamWriter{}
-	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamChunk, body, sw); err != nil {
+	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamChunk, nil, body, sw); err != nil {
 		t.Fatal(err)
 	}
 	if got := string(sw.body()); got != `{"a":1}`+"\n"+`{"b":2}`+"\n" {
@@ -346,7 +350,7 @@ func TestPumpResponseStreamSSEAbort(t *testing.T) {
 
 	body := bytes.NewReader([]byte("data: one\n\ndata: two\n\ndata: three\n\n"))
 	sw := &captureStreamWriter{}
-	err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, body, sw)
+	err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, nil, body, sw)
 	if err == nil {
 		t.Fatal("abort 应返回错误")
 	}
@@ -374,7 +378,7 @@ func TestPumpResponseStreamPropagatesUpstreamError(t *testing.T) {
 	body := &dataThenErrorReader{data: []byte("data: one\n\n"), err: wantErr}
 	sw := &captureStreamWriter{}
 
-	err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, body, sw)
+	err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, nil, body, sw)
 	if !errors.Is(err, wantErr) {
 		t.Fatalf("上游非 EOF 错误被吞掉: got %v, want %v", err, wantErr)
 	}
@@ -447,7 +451,7 @@ func TestPumpResponseStreamSSEModify(t *testing.T) {
 
 	body := bytes.NewReader([]byte("event: x\ndata: secret\n\n"))
 	sw := &captureStreamWriter{}
-	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, body, sw); err != nil {
+	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamSSE, nil, body, sw); err != nil {
 		t.Fatal(err)
 	}
 	if got := string(sw.body()); got != "event: x\ndata: REDACTED\n\n" {
@@ -465,7 +469,7 @@ func TestPumpResponseStreamGRPCModify(t *testing.T) {
 
 	body := bytes.NewReader(grpcFrameBytes([]byte("orig"), false))
 	sw := &captureStreamWriter{}
-	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamGRPC, body, sw); err != nil {
+	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamGRPC, nil, body, sw); err != nil {
 		t.Fatal(err)
 	}
 	want := grpcFrameBytes([]byte("XX"), false)
@@ -474,6 +478,7 @@ func TestPumpResponseStreamGRPCModify(t *testing.T) {
 	}
 }
 
+// 未声明 grpc-encoding 的压缩帧无法解压,只能原样透传。
 func TestGRPCCompressedFrameNotModified(t *testing.T) {
 	p := pipeline.New(nil, nil)
 	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
@@ -485,7 +490,7 @@ func TestGRPCCompressedFrameNotModified(t *testing.T) {
 	orig := grpcFrameBytes([]byte("compressed"), true)
 	body := bytes.NewReader(orig)
 	sw := &captureStreamWriter{}
-	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamGRPC, body, sw); err != nil {
+	if err := pumpResponseStream(silentServer{}, nil, "u", flow.StreamGRPC, nil, body, sw); err != nil {
 		t.Fatal(err)
 	}
 	if !bytes.Equal(sw.body(), orig) {
@@ -674,3 +679,143 @@ func TestRunGRPCStreamEndToEnd(t *testing.T) {
 // 确保 silentServer 满足接口。
 var _ types.Server = silentServer{}
 var _ net.Addr = (*net.TCPAddr)(nil)
+
+// TestEmitGRPCMessageJSONView gRPC 消息带 JSON 视图:插件改视图即按视图重新编码,
+// 视图非法时保留原消息;同时改了 data 则以 data 为准。
+func TestEmitGRPCMessageJSONView(t *testing.T) {
+	prev := protoRegistry
+	SetProtoRegistry(protoschema.New())
+	t.Cleanup(func() { SetProtoRegistry(prev) })
+
+	payload := []byte{0x0a, 0x03, 'o', 'l', 'd'} // 1:string "old"
+	raw := grpcFrameBytes(payload, false)
+	run := func(edit func(m *flow.StreamMessage)) []byte {
+		t.Helper()
+		p := pipeline.New(nil, nil)
+		p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+			if m.Schema != protoschema.WireSchema || m.JSON != `{"1:string":"old"}` {
+				t.Errorf("视图 = %q (%s)", m.JSON, m.Schema)
+			}
+			edit(m)
+			return flow.ContinueDecision()
+		}})
+		withPipeline(t, p)
+		out, err := emitStreamMessage(nil, "https://api.example/pkg.Svc/Call", flow.WSClientToServer, flow.StreamGRPC, "", &grpcFrame{}, payload, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := run(func(m *flow.StreamMessage) { m.JSON = `{"1:string":"new"}` })
+	if want := grpcFrameBytes([]byte{0x0a, 0x03, 'n', 'e', 'w'}, false); !bytes.Equal(out, want) {
+		t.Fatalf("改视图后 = %x, want %x", out, want)
+	}
+	if out := run(func(m *flow.StreamMessage) { m.JSON = `{"1:string":` }); !bytes.Equal(out, raw) {
+		t.Fatalf("非法视图应保留原消息,得 %x", out)
+	}
+	out = run(func(m *flow.StreamMessage) {
+		m.JSON = `{"1:string":"new"}`
+		m.Data = []byte{0x08, 0x01}
+	})
+	if want := grpcFrameBytes([]byte{0x08, 0x01}, false); !bytes.Equal(out, want) {
+		t.Fatalf("同时改 data 时应以 data 为准,得 %x", out)
+	}
+}
+
+// TestPumpResponseStreamGRPCCompressedModify 按 grpc-encoding 解压的消息交给插件与记录,
+// 改写后按同一编码重新压缩成帧。
+func TestPumpResponseStreamGRPCCompressedModify(t *testing.T) {
+	sink := &fakeStreamSink{}
+	withStreamSink(t, sink)
+	var seen []string
+	p := pipeline.New(nil, nil)
+	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+		seen = append(seen, m.Encoding+":"+string(m.Data))
+		if string(m.Data) == "orig" {
+			m.Data = []byte("XX")
+		}
+		return flow.ContinueDecision()
+	}})
+	withPipeline(t, p)
+
+	compressed, err := compressGRPC("gzip", []byte("orig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep, _ := compressGRPC("gzip", []byte("keep"))
+	body := bytes.NewReader(append(grpcFrameBytes(compressed, true), grpcFrameBytes(keep, true)...))
+	sw := &captureStreamWriter{}
+	f := flow.New(flow.ProtoHTTP)
+	f.Request = &flow.Request{URL: "http://x/svc/M"}
+	rec := newStreamRecorder(f, flow.StreamGRPC)
+	if err := pumpResponseStream(silentServer{}, rec, "http://x/svc/M", flow.StreamGRPC, http.Header{"Grpc-Encoding": {"gzip"}}, body, sw); err != nil {
+		t.Fatal(err)
+	}
+	rec.close()
+
+	if len(seen) != 2 || seen[0] != "gzip:orig" || seen[1] != "gzip:keep" {
+		t.Fatalf("插件应看到解压后的载荷,得 %q", seen)
+	}
+	frames := (&grpcScanner{encoding: "gzip"}).push(sw.body())
+	if len(frames) != 2 || !frames[0].Compressed {
+		t.Fatalf("改写后应仍为压缩帧: %+v", frames)
+	}
+	if plain, err := decompressGRPC("gzip", frames[0].Payload); err != nil || string(plain) != "XX" {
+		t.Fatalf("重新压缩的帧解压得 %q, %v", plain, err)
+	}
+	if !bytes.Equal(frames[1].Raw, grpcFrameBytes(keep, true)) {
+		t.Fatal("未改写的压缩帧应原样透传")
+	}
+	ss := sink.snapshot()
+	if ss == nil || len(ss.Messages) != 2 || string(ss.Messages[0].Data) != "XX" || ss.Messages[1].Encoding != "gzip" {
+		t.Fatalf("会话应记录解压后的载荷与编码,得 %+v", ss)
+	}
+}
+
+// TestRunGRPCStreamCompressedRequest 请求方向按请求头的 grpc-encoding 解压,改写后重新压缩发往上游。
+func TestRunGRPCStreamCompressedRequest(t *testing.T) {
+	p := pipeline.New(nil, nil)
+	p.Register(&testStreamHook{fn: func(m *flow.StreamMessage) flow.Decision {
+		if m.Direction == flow.WSClientToServer {
+			m.Data = bytes.ToUpper(m.Data)
+		}
+		return flow.ContinueDecision()
+	}})
+	withPipeline(t, p)
+
+	got := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		frames := (&grpcScanner{}).push(body)
+		if len(frames) == 1 && frames[0].Compressed {
+			plain, _ := decompressGRPC(r.Header.Get("Grpc-Encoding"), frames[0].Payload)
+			got <- plain
+		}
+		close(got)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer srv.Close()
+	prev := sharedStreamClient
+	sharedStreamClient = streamClientFrom(srv.Client())
+	t.Cleanup(func() { sharedStreamClient = prev })
+
+	msg, err := compressGRPC("zstd", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", srv.URL+"/svc/Method", bytes.NewReader(grpcFrameBytes(msg, true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Grpc-Encoding", "zstd")
+	sw := &captureStreamWriter{}
+	if err := runGRPCStream(silentServer{}, req, flow.ProtoHTTP, nil, nil, &fakeResponder{sw: sw}, sw); err != nil {
+		t.Fatal(err)
+	}
+	if plain := <-got; string(plain) != "HELLO" {
+		t.Fatalf("上游应收到按 zstd 重新压缩的改写消息,解压得 %q", plain)
+	}
+}
@@ -75,6 +75,13 @@ func (t *TLSHandler) handleTlsHandshake(server types.Server, reader *bufio.Reade
 		return err
 	}
 
+	// 记下客户端 ClientHello 指纹(JA3 / JA4),连接上的每个请求都带上它。窥探不消费字节。
+	if hello, err := PeekClientHello(nil, reader); err == nil {
+		t.processor.tlsFP = clientHelloFingerprint(hello)
+	} else {
+		server.LogDebug("解析 ClientHello 失败: %v", err)
+	}
+
 	// 创建TLS连接
 	connSsl := tls.Server(conn, &tls.Config{
 		Certificates: []tls.Certificate{*cert},
@@ -87,17 +94,21 @@ func (t *TLSHandler) handleTlsHandshake(server types.Server, reader *bufio.Reade
 	if err := connSsl.Handshake(); err != nil {
 		server.LogError("TLS握手失败: %v", err)
 		t.processor.recordTLSFailure(host, err)
+		if isCertRejection(err) {
+			noteHandshakeFailure(remoteIP(conn), host, err) // 反复拒绝本端证书(多为固定证书)的主机之后改为直通
+		}
 		return err
 	}
 
 	server.LogDebug("TLS握手成功")
+	noteHandshakeSuccess(remoteIP(conn))
 	t.processor.conn.SetConn(connSsl)
 
 	// 按 ALPN 协商结果分流:协商为 h2 时交给 HTTP/2 服务端处理,其余按 HTTP/1.1 处理。
 	if connSsl.ConnectionState().NegotiatedProtocol == "h2" {
 		server.LogDebug("ALPN 协商为 h2,启用 HTTP/2 处理")
 		_ = connSsl.SetDeadline(time.Time{}) // h2 为长连接,清除握手期设置的绝对超时
-		return serveHTTP2(server, connSsl)
+		return serveHTTP2(server, connSsl, t.processor.reverse, t.processor.listener, t.processor.tlsFP)
 	}
 
 	// 清除握手期的绝对截止时间。HTTP/1.1 连接现在可以跨多个请求复用，若保留一个
@@ -0,0 +1,189 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 客户端 TLS 指纹(JA3 / JA4)。
+//
+// 同一应用、同一 TLS 库版本发出的 ClientHello 在套件、扩展及其顺序上高度稳定,据此可以
+// 辨认发起请求的客户端库,也能解释上游反爬系统为何区别对待某些请求。
+//   - JA3:版本,套件,扩展,曲线,点格式(十进制,「-」连接,剔除 GREASE),再取 MD5。
+//   - JA4(TCP):t + 版本 + SNI 有无 + 套件数 + 扩展数 + ALPN 首尾字符,接排序后套件的
+//     SHA256 前 12 位,再接排序后扩展(不含 SNI / ALPN)与签名算法的 SHA256 前 12 位。
+
+// isGREASE 判断是否为 RFC 8701 的 GREASE 值(0x?a?a,高低字节相同)。
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func withoutGREASE(vs []uint16) []uint16 {
+	out := make([]uint16, 0, len(vs))
+	for _, v := range vs {
+		if !isGREASE(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// clientHelloFingerprint 由解析出的 ClientHello 计算指纹。
+func clientHelloFingerprint(h *ClientHello) *flow.TLSFingerprint {
+	if h == nil {
+		return nil
+	}
+	ja3 := ja3String(h)
+	sum := md5.Sum([]byte(ja3))
+	return &flow.TLSFingerprint{
+		JA3:        ja3,
+		JA3Hash:    hex.EncodeToString(sum[:]),
+		JA4:        ja4String(h),
+		SNI:        h.ServerName,
+		Version:    maxTLSVersion(h),
+		Ciphers:    h.Ciphers,
+		Extensions: h.Extensions,
+		ALPN:       h.ALPN,
+		Curves:     h.Curves,
+		Versions:   h.SupportedVersions,
+	}
+}
+
+func ja3String(h *ClientHello) string {
+	points := make([]uint16, len(h.PointFormats))
+	for i, p := range h.PointFormats {
+		points[i] = uint16(p)
+	}
+	return strings.Join([]string{
+		strconv.Itoa(int(h.Version)),
+		joinDecimal(withoutGREASE(h.Ciphers)),
+		joinDecimal(withoutGREASE(h.Extensions)),
+		joinDecimal(withoutGREASE(h.Curves)),
+		joinDecimal(points),
+	}, ",")
+}
+
+func joinDecimal(vs []uint16) string {
+	parts := make([]string, len(vs))
+	for i, v := range vs {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, "-")
+}
+
+func ja4String(h *ClientHello) string {
+	ciphers := withoutGREASE(h.Ciphers)
+	exts := withoutGREASE(h.Extensions)
+
+	sni := "i"
+	if h.ServerName != "" {
+		sni = "d"
+	}
+	a := fmt.Sprintf("t%s%s%02d%02d%s", ja4Version(maxTLSVersion(h)), sni,
+		min(len(ciphers), 99), min(len(exts), 99), ja4ALPN(h.ALPN))
+
+	slices.Sort(ciphers)
+	b := ja4Hash(joinHex(ciphers))
+
+	sorted := make([]uint16, 0, len(exts))
+	for _, e := range exts {
+		if e != extServerName && e != extALPN {
+			sorted = append(sorted, e)
+		}
+	}
+	slices.Sort(sorted)
+	c := "000000000000"
+	if len(sorted) > 0 {
+		in := joinHex(sorted)
+		if sigs := withoutGREASE(h.SignatureAlgorithms); len(sigs) > 0 {
+			in += "_" + joinHex(sigs)
+		}
+		c = ja4Hash(in)
+	}
+	return a + "_" + b + "_" + c
+}
+
+// maxTLSVersion 取客户端支持的最高版本:有 supported_versions 扩展时以其为准。
+func maxTLSVersion(h *ClientHello) uint16 {
+	v := h.Version
+	for _, sv := range withoutGREASE(h.SupportedVersions) {
+		if sv > v {
+			v = sv
+		}
+	}
+	return v
+}
+
+func ja4Version(v uint16) string {
+	switch v {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	}
+	return "00"
+}
+
+// ja4ALPN 取首个 ALPN 的首尾字符;含非字母数字时改取其十六进制表示的首尾字符。
+func ja4ALPN(alpn []string) string {
+	if len(alpn) == 0 || alpn[0] == "" {
+		return "00"
+	}
+	p := alpn[0]
+	first, last := p[0], p[len(p)-1]
+	if !isAlnum(first) || !isAlnum(last) {
+		hx := hex.EncodeToString([]byte(p))
+		return hx[:1] + hx[len(hx)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func joinHex(vs []uint16) string {
+	parts := make([]string, len(vs))
+	for i, v := range vs {
+		parts[i] = fmt.Sprintf("%04x", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// FingerprintClientHello 由一段以 ClientHello 记录开头的 TLS 字节流计算指纹,供保真转发器
+// 记录上游连接实际发出的 ClientHello。无法解析时返回 nil。
+func FingerprintClientHello(record []byte) *flow.TLSFingerprint {
+	hello, err := PeekClientHello(nil, bufio.NewReaderSize(bytes.NewReader(record), max(len(record), 16)))
+	if err != nil {
+		return nil
+	}
+	return clientHelloFingerprint(hello)
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+)
+
+// specHello 是 JA4 规范示例对应的 ClientHello(Chrome,TLS 1.3,h2)。
+func specHello() *ClientHello {
+	return &ClientHello{
+		ServerName: "example.com",
+		ALPN:       []string{"h2", "http/1.1"},
+		Version:    0x0303,
+		Ciphers: []uint16{0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8,
+			0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035},
+		Extensions: []uint16{0x0000, 0x0017, 0xff01, 0x000a, 0x000b, 0x0023, 0x0010, 0x0005,
+			0x000d, 0x0012, 0x0033, 0x002d, 0x002b, 0x001b, 0x0015, 0x4469},
+		Curves:              []uint16{0x001d, 0x0017, 0x0018},
+		PointFormats:        []uint8{0},
+		SignatureAlgorithms: []uint16{0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601},
+		SupportedVersions:   []uint16{0x0304, 0x0303},
+	}
+}
+
+func TestJA4KnownVector(t *testing.T) {
+	if got := ja4String(specHello()); got != "t13d1516h2_8daaf6152771_e5627efa2ab1" {
+		t.Fatalf("JA4 = %s", got)
+	}
+}
+
+// GREASE 值不计入 JA3 / JA4,但原始列表仍按线上顺序保留。
+func TestFingerprintIgnoresGREASE(t *testing.T) {
+	plain := clientHelloFingerprint(specHello())
+
+	h := specHello()
+	h.Ciphers = append([]uint16{0x0a0a}, h.Ciphers...)
+	h.Extensions = append([]uint16{0x1a1a}, append(h.Extensions, 0xfafa)...)
+	h.Curves = append([]uint16{0x2a2a}, h.Curves...)
+	h.SupportedVersions = append([]uint16{0x3a3a}, h.SupportedVersions...)
+	greased := clientHelloFingerprint(h)
+
+	if greased.JA3 != plain.JA3 || greased.JA3Hash != plain.JA3Hash || greased.JA4 != plain.JA4 {
+		t.Fatalf("GREASE 不应影响指纹:\n%+v\n%+v", plain, greased)
+	}
+	if greased.Ciphers[0] != 0x0a0a || len(greased.Extensions) != len(plain.Extensions)+2 {
+		t.Fatalf("原始列表应保留 GREASE: %v %v", greased.Ciphers, greased.Extensions)
+	}
+	if want := "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53," +
+		"0-23-65281-10-11-35-16-5-13-18-51-45-43-27-21-17513,29-23-24,0"; plain.JA3 != want {
+		t.Fatalf("JA3 = %s", plain.JA3)
+	}
+	if plain.Version != 0x0304 {
+		t.Fatalf("Version = %#x", plain.Version)
+	}
+}
+
+func TestJA4Parts(t *testing.T) {
+	if got := ja4ALPN([]string{"\xabx"}); got != "a8" {
+		t.Fatalf("非字母数字 ALPN = %s", got)
+	}
+	if got := ja4ALPN(nil); got != "00" {
+		t.Fatalf("无 ALPN = %s", got)
+	}
+	h := &ClientHello{Version: 0x0301, Ciphers: []uint16{0x002f}}
+	if got := ja4String(h); got != "t10i010000_"+ja4Hash("002f")+"_000000000000" {
+		t.Fatalf("无扩展 JA4 = %s", got)
+	}
+}
+
+// 真实 crypto/tls 客户端的 ClientHello 应能算出完整指纹。
+func TestClientHelloFingerprintFromGoClient(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: "api.example.com", NextProtos: []string{"h2"}}).Handshake()
+	}()
+
+	hello, err := PeekClientHello(server, bufio.NewReaderSize(server, 64*1024))
+	if err != nil {
+		t.Fatalf("PeekClientHello: %v", err)
+	}
+	fp := clientHelloFingerprint(hello)
+	if !strings.HasPrefix(fp.JA4, "t13d") || !strings.Contains(fp.JA4, "h2_") {
+		t.Fatalf("JA4 = %s", fp.JA4)
+	}
+	if len(fp.JA3Hash) != 32 || !strings.HasPrefix(fp.JA3, "771,") || fp.SNI != "api.example.com" {
+		t.Fatalf("指纹不符: %+v", fp)
+	}
+	if len(hello.Curves) == 0 || len(hello.SignatureAlgorithms) == 0 || len(hello.SupportedVersions) == 0 {
+		t.Fatalf("扩展字段未解析: %+v", hello)
+	}
+}
+
+// FingerprintClientHello 处理的是本端写出的原始字节(握手记录在前,后面可能还有别的记录)。
+func TestFingerprintClientHelloRecord(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: "up.example.com", NextProtos: []string{"http/1.1"}}).Handshake()
+	}()
+
+	buf := make([]byte, 64*1024)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := FingerprintClientHello(append(buf[:n:n], 0x14, 0x03, 0x03, 0x00, 0x01, 0x01))
+	if fp == nil || fp.SNI != "up.example.com" || len(fp.Curves) == 0 || len(fp.Versions) == 0 {
+		t.Fatalf("指纹 = %+v", fp)
+	}
+	if FingerprintClientHello([]byte{0x17, 0x03, 0x03}) != nil || FingerprintClientHello(nil) != nil {
+		t.Fatal("非 ClientHello 应返回 nil")
+	}
+}
+
+func TestMirrorTLSFor(t *testing.T) {
+	t.Cleanup(func() { SetTLSMirror(false, nil) })
+	if MirrorTLSFor("a.example.com") {
+		t.Fatal("默认不镜像")
+	}
+	SetTLSMirror(true, []string{" "})
+	if !MirrorTLSFor("anything.test") {
+		t.Fatal("主机范围为空时应对全部主机生效")
+	}
+	SetTLSMirror(true, []string{"*.example.com"})
+	if !MirrorTLSFor("A.Example.com") || MirrorTLSFor("example.org") {
+		t.Fatal("主机范围匹配不符")
+	}
+	SetTLSMirror(false, []string{"*.example.com"})
+	if MirrorTLSFor("a.example.com") {
+		t.Fatal("关闭后不应镜像")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// tlsMirror 决定上游连接是否镜像客户端的 TLS 参数。hosts 为空时对所有主机生效。
+type tlsMirror struct {
+	all   bool
+	hosts []*regexp.Regexp
+}
+
+// tlsMirrorPtr 持有当前镜像范围;nil 表示关闭。
+var tlsMirrorPtr atomic.Pointer[tlsMirror]
+
+// SetTLSMirror 由引擎层下发上游 TLS 镜像开关与主机范围(通配模式同解密范围;为空则全部主机)。
+// 运行时即时生效、并发安全。
+func SetTLSMirror(enabled bool, hosts []string) {
+	if !enabled {
+		tlsMirrorPtr.Store(nil)
+		return
+	}
+	all := true
+	for _, h := range hosts {
+		if strings.TrimSpace(h) != "" {
+			all = false
+		}
+	}
+	tlsMirrorPtr.Store(&tlsMirror{all: all, hosts: compileHostPatterns(hosts)})
+}
+
+// MirrorTLSFor 报告到 host 的上游连接是否应镜像客户端的 TLS 参数,供保真转发器调用。
+func MirrorTLSFor(host string) bool {
+	m := tlsMirrorPtr.Load()
+	if m == nil {
+		return false
+	}
+	return m.all || matchAnyHost(m.hosts, strings.ToLower(host))
+}
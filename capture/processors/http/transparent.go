@@ -0,0 +1,83 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bufio"
+	"net"
+
+	"github.com/mintfog/sniffy/capture/processors/mqtt"
+	"github.com/mintfog/sniffy/capture/processors/tcp"
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// serveTransparent 处理透明代理模式(REDIRECT/TPROXY)下的连接。客户端不知道代理存在:
+// 不会发 CONNECT,也不会出示 Proxy-Authorization,目标只能取自连接的原始目标 dst。
+// 首包嗅探后:TLS 交 serveClientTLS 按 SNI 处理;明文 HTTP 走常规请求循环,origin-form
+// 请求按 Host 头与 dst 补全目标;其余协议中继到 dst(MQTT 逐报文解析)。
+func (p *Processor) serveTransparent(server types.Server, dst string) error {
+	p.proxyTunnel = true
+	p.originalDst = dst
+	client := p.conn.GetConn()
+	reader := p.conn.GetReader()
+
+	switch sniffTunnel(client, reader) {
+	case tunnelTLS:
+		return p.serveClientTLS(server, reader, dst)
+	case tunnelHTTP:
+		return p.handleHttpProtocol(server, reader, p.conn.GetWriter())
+	case tunnelMQTT:
+		if !p.shouldDecrypt(dst) {
+			return p.relayTo(server, reader, dst)
+		}
+		return mqtt.Dial(p.conn, dst)
+	default:
+		if !p.shouldDecrypt(dst) {
+			return p.relayTo(server, reader, dst)
+		}
+		origin, err := dialTunnelTarget(dst)
+		if err != nil {
+			server.LogError("透明代理连接原始目标失败 %s: %v", dst, err)
+			return err
+		}
+		return tcp.Relay(p.conn, dst, origin)
+	}
+}
+
+// relayTo 拨通 dst 后原样双向转发,不抓包。
+func (p *Processor) relayTo(server types.Server, reader *bufio.Reader, dst string) error {
+	origin, err := dialTunnelTarget(dst)
+	if err != nil {
+		server.LogError("直通转发连接目标失败 %s: %v", dst, err)
+		return err
+	}
+	return p.relay(reader, origin)
+}
+
+// transparentHost 为透明模式下的 origin-form 请求确定目标 host[:port]:优先 Host 头(保留
+// 虚拟主机语义),缺端口时补上原始目标的非默认端口;没有 Host 头(HTTP/1.0)时直接用 dst。
+func (p *Processor) transparentHost(host string) string {
+	if host == "" {
+		return p.originalDst
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := portOf(p.originalDst)
+	if port == "" || (p.isHttps && port == "443") || (!p.isHttps && port == "80") {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// portOf 返回 host:port 的端口部分,解析失败时为空串。
+func portOf(hostport string) string {
+	_, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return ""
+	}
+	return port
+}
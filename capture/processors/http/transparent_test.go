@@ -0,0 +1,114 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// dstConn 模拟透明代理监听端 accept 到的连接。
+type dstConn struct {
+	net.Conn
+	dst string
+}
+
+func (c *dstConn) OriginalDestination() string { return c.dst }
+
+func TestTransparentHost(t *testing.T) {
+	for _, tc := range []struct {
+		dst, host string
+		https     bool
+		want      string
+	}{
+		{"203.0.113.5:80", "example.com", false, "example.com"},
+		{"203.0.113.5:8080", "example.com", false, "example.com:8080"},
+		{"203.0.113.5:8080", "example.com:9000", false, "example.com:9000"},
+		{"203.0.113.5:443", "example.com", true, "example.com"},
+		{"203.0.113.5:8443", "example.com", true, "example.com:8443"},
+		{"203.0.113.5:8080", "", false, "203.0.113.5:8080"},
+	} {
+		p := &Processor{originalDst: tc.dst, isHttps: tc.https}
+		if got := p.transparentHost(tc.host); got != tc.want {
+			t.Errorf("transparentHost(%q) with dst %s = %q, want %q", tc.host, tc.dst, got, tc.want)
+		}
+	}
+}
+
+// TestServeTransparentMITMUsesSNI 锁定透明模式下的 HTTPS:没有 CONNECT,伪造证书按
+// ClientHello 的 SNI 签发。
+func TestServeTransparentMITMUsesSNI(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "all", nil, nil)
+
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	conn := newMockConnection(&dstConn{Conn: proxySide, dst: "203.0.113.9:443"}, newMockServer())
+	done := make(chan error, 1)
+	go func() { done <- New(conn).Process() }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA().GetCA())
+	tc := tls.Client(client, &tls.Config{ServerName: "shop.example", RootCAs: roots})
+	_ = tc.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := tc.Handshake(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if err := tc.ConnectionState().PeerCertificates[0].VerifyHostname("shop.example"); err != nil {
+		t.Fatalf("MITM cert not issued for SNI: %v", err)
+	}
+
+	_ = tc.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process did not return after the client closed")
+	}
+}
+
+// TestServeTransparentTunnelsOutOfScopeTLS 锁定范围外的 TLS 原样转发到原始目标(而非 SNI
+// 解析出的地址),首个记录一字不差。
+func TestServeTransparentTunnelsOutOfScopeTLS(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "deny", nil, []string{"pinned.example"})
+	SetUpstreamProxyURL(nil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan byte, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		b := make([]byte, 1)
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.ReadFull(c, b); err == nil {
+			got <- b[0]
+		}
+	}()
+
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	conn := newMockConnection(&dstConn{Conn: proxySide, dst: ln.Addr().String()}, newMockServer())
+	go func() { _ = New(conn).Process() }()
+	go func() { _ = tls.Client(client, &tls.Config{ServerName: "pinned.example"}).Handshake() }()
+
+	select {
+	case b := <-got:
+		if b != TLSHandshakeRecordType {
+			t.Fatalf("origin got first byte 0x%02x", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("out-of-scope TLS was not relayed to the original destination")
+	}
+}
@@ -7,6 +7,7 @@ package http
 
 import (
 	"bufio"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -16,15 +17,18 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/mintfog/sniffy/capture/processors/mqtt"
+	"github.com/mintfog/sniffy/capture/processors/tcp"
 	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/forward"
 )
 
-// tunnelUpstream 持有当前上游代理地址(nil = 直连),供直通隧道使用。
-// 与引擎自建上游客户端各自独立:MITM 转发经客户端的 Proxy 闭包,直通隧道经此裸 URL 建 CONNECT。
-// 由 SetUpstreamProxyURL 原子写入,与引擎的 SetUpstreamProxy 保持同步。
+// tunnelUpstream 持有默认上游代理地址(nil = 直连),即上游路由表都不命中时的出站方式,
+// 供直通隧道与 MITM 转发(经 UpstreamProxyFor)共用。由 SetUpstreamProxyURL 原子写入,
+// 与引擎的 SetUpstreamProxy 保持同步。
 var tunnelUpstream atomic.Pointer[url.URL]
 
-// SetUpstreamProxyURL 下发直通隧道使用的上游代理地址(nil 表示直连)。
+// SetUpstreamProxyURL 下发默认上游代理地址(nil 表示直连)。
 func SetUpstreamProxyURL(u *url.URL) {
 	if u == nil {
 		tunnelUpstream.Store(nil)
@@ -34,7 +38,7 @@ func SetUpstreamProxyURL(u *url.URL) {
 	tunnelUpstream.Store(&cp)
 }
 
-// UpstreamProxyURL 返回直通隧道当前使用的上游代理地址(nil 表示直连)。
+// UpstreamProxyURL 返回默认上游代理地址(nil 表示直连)。
 // 与 SetUpstreamProxyURL 一样返回副本,避免调用方改动到共享的 URL。
 func UpstreamProxyURL() *url.URL {
 	u := tunnelUpstream.Load()
@@ -46,7 +50,7 @@ func UpstreamProxyURL() *url.URL {
 }
 
 // tunnel 对不在解密范围的 CONNECT 目标做盲转发:不终止 TLS、不抓包,原样在客户端与源站
-// 之间双向复制字节。配置了上游代理时经其 CONNECT 建立隧道,否则直连源站。
+// 之间双向复制字节。按上游路由经代理 CONNECT 建立隧道或直连源站。
 func (p *Processor) tunnel(server types.Server, reader *bufio.Reader) error {
 	host := p.request.Host
 	origin, err := dialTunnelTarget(host)
@@ -54,6 +58,11 @@ func (p *Processor) tunnel(server types.Server, reader *bufio.Reader) error {
 		server.LogError("直通隧道建立失败 %s: %v", host, err)
 		return err
 	}
+	return p.relay(reader, origin)
+}
+
+// relay 在客户端与已建立的源站连接之间双向复制字节,任一方向结束即收尾。
+func (p *Processor) relay(reader *bufio.Reader, origin net.Conn) error {
 	defer origin.Close()
 
 	client := p.conn.GetConn()
@@ -75,11 +84,131 @@ func (p *Processor) tunnel(server types.Server, reader *bufio.Reader) error {
 	return nil
 }
 
-// dialTunnelTarget 为直通隧道建立到 host(host:port)的连接:直连或经上游代理 CONNECT。
+// DialTunnel 按直通隧道的口径(按上游路由直连或经代理)拨通 host:port。
+// 供 SOCKS5 等需要先确认目标可达、再回复客户端的入口协议及 WebSocket 上游使用。
+func DialTunnel(host string) (net.Conn, error) {
+	return dialTunnelTarget(host)
+}
+
+// ServeTunnel 接管一条已由其它代理协议(如 SOCKS5 CONNECT)建立好、目标为 target
+// (host:port)的客户端隧道,沿用 HTTP CONNECT 之后的处理:范围外直通;范围内先嗅探
+// 首包,TLS 走 MITM、明文 HTTP 走 flow 管道,MQTT 经 MQTT 处理器逐报文中继,其余协议
+// (含服务端先发言的协议)经 TCP 处理器中继并记为 TCP 会话。
+//
+// origin 是调用方为确认可达性已拨通的源站连接,直通时直接复用,否则关闭;可为 nil。
+func ServeTunnel(conn types.Connection, target string, origin net.Conn) error {
+	p := &Processor{
+		conn: conn,
+		request: &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Host: target},
+			Host:   target,
+			Header: make(http.Header),
+		},
+		// 隧道已在入口协议上认证过,内层请求不再要求 Proxy-Authorization。
+		proxyTunnel: true,
+	}
+	server := conn.GetServer()
+	reader := conn.GetReader()
+	if client := conn.GetConn(); client != nil {
+		p.listener = types.ListenerID(client)
+		_ = client.SetDeadline(time.Time{})
+	}
+
+	if !p.shouldDecrypt(target) {
+		server.LogDebug("目标 %s 不在解密范围，直通转发", target)
+		return p.relayOrDial(server, reader, origin)
+	}
+
+	switch sniffTunnel(conn.GetConn(), reader) {
+	case tunnelTLS:
+		closeConn(origin)
+		p.isHttps = true
+		return p.handleTlsHandshake(server, reader)
+	case tunnelHTTP:
+		closeConn(origin)
+		return p.handleHttpProtocol(server, reader, conn.GetWriter())
+	case tunnelMQTT:
+		if origin == nil {
+			return mqtt.Dial(conn, target)
+		}
+		return mqtt.Relay(conn, target, origin)
+	default:
+		// 范围内的非 TLS/HTTP 流量照常中继,但按分块记为 TCP 会话。
+		if origin == nil {
+			var err error
+			if origin, err = dialTunnelTarget(target); err != nil {
+				server.LogError("直通隧道建立失败 %s: %v", target, err)
+				return err
+			}
+		}
+		return tcp.Relay(conn, target, origin)
+	}
+}
+
+// relayOrDial 直通转发:有现成的源站连接就复用,否则按 tunnel 的口径拨号。
+func (p *Processor) relayOrDial(server types.Server, reader *bufio.Reader, origin net.Conn) error {
+	if origin == nil {
+		return p.tunnel(server, reader)
+	}
+	return p.relay(reader, origin)
+}
+
+func closeConn(c net.Conn) {
+	if c != nil {
+		_ = c.Close()
+	}
+}
+
+// 隧道首包嗅探结果。
+const (
+	tunnelOpaque = iota
+	tunnelTLS
+	tunnelHTTP
+	tunnelMQTT
+)
+
+// tunnelHTTPMethods 是识别明文 HTTP 请求行所用的方法前缀(含尾随空格)。
+var tunnelHTTPMethods = []string{"GET ", "POST ", "PUT ", "HEAD ", "DELETE ", "OPTIONS ", "PATCH ", "TRACE "}
+
+// sniffTunnel 在 TunnelSniffTimeout 内窥探隧道首包。客户端迟迟不发言(SSH/SMTP 等
+// 服务端先发言的协议)时按不透明流处理;超时只影响本次 Peek,reader 之后仍可继续读。
+func sniffTunnel(client net.Conn, reader *bufio.Reader) int {
+	if client != nil {
+		_ = client.SetReadDeadline(time.Now().Add(TunnelSniffTimeout))
+		defer client.SetReadDeadline(time.Time{})
+	}
+	first, err := reader.Peek(1)
+	if err != nil {
+		return tunnelOpaque
+	}
+	if first[0] == TLSHandshakeRecordType {
+		return tunnelTLS
+	}
+	head, _ := reader.Peek(reader.Buffered())
+	for _, m := range tunnelHTTPMethods {
+		if len(head) >= len(m) && string(head[:len(m)]) == m {
+			return tunnelHTTP
+		}
+	}
+	if mqtt.IsConnect(head) {
+		return tunnelMQTT
+	}
+	return tunnelOpaque
+}
+
+// dialTunnelTarget 为直通隧道建立到 host(host:port)的连接:按上游路由直连、经 HTTP 代理
+// CONNECT 或经 SOCKS5 代理。直连时实际连接的地址按主机映射改写,经代理时目标交由代理解析。
 func dialTunnelTarget(host string) (net.Conn, error) {
-	up := tunnelUpstream.Load()
+	up := UpstreamProxyFor(host)
 	if up == nil {
-		return net.DialTimeout("tcp", host, TLSHandshakeTimeout)
+		return net.DialTimeout("tcp", MapUpstreamAddr(host), TLSHandshakeTimeout)
+	}
+	if forward.IsSOCKS(up) {
+		return forward.DialSOCKS5(context.Background(), up, host, TLSHandshakeTimeout)
+	}
+	if up.Scheme != "http" && up.Scheme != "https" {
+		return nil, fmt.Errorf("直通隧道不支持 %s 上游代理", up.Scheme)
 	}
 	praw, err := net.DialTimeout("tcp", proxyDialAddr(up), TLSHandshakeTimeout)
 	if err != nil {
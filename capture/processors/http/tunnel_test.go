@@ -0,0 +1,84 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSniffTunnel(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		want  int
+	}{
+		"tls":    {"\x16\x03\x01\x00\x05hello", tunnelTLS},
+		"http":   {"GET / HTTP/1.1\r\nHost: a\r\n\r\n", tunnelHTTP},
+		"put":    {"PUT /x HTTP/1.1\r\n\r\n", tunnelHTTP},
+		"mqtt":   {"\x10\x0c\x00\x04MQTT\x04\x02\x00\x3c\x00\x00", tunnelMQTT},
+		"opaque": {"SSH-2.0-OpenSSH_9.6\r\n", tunnelOpaque},
+		"prefix": {"GETX", tunnelOpaque},
+		"empty":  {"", tunnelOpaque},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.input))
+			if got := sniffTunnel(nil, r); got != tc.want {
+				t.Fatalf("sniffTunnel = %d, want %d", got, tc.want)
+			}
+			// 嗅探只能 Peek,不得消费客户端字节。
+			if rest, _ := io.ReadAll(r); string(rest) != tc.input {
+				t.Fatalf("sniff consumed input: %q", rest)
+			}
+		})
+	}
+}
+
+// TestServeTunnelRelaysOpaqueStream 锁定 SOCKS5 入口交来的非 TLS/HTTP 流量原样直通,
+// 且复用调用方已拨通的源站连接。
+func TestServeTunnelRelaysOpaqueStream(t *testing.T) {
+	restoreScopeGlobals(t)
+	SetDecryptScope(true, "all", nil, nil)
+
+	client, proxySide := net.Pipe()
+	originProxy, origin := net.Pipe()
+	defer client.Close()
+	defer origin.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeTunnel(newMockConnection(proxySide, newMockServer()), "198.51.100.7:22", originProxy)
+	}()
+
+	go func() { _, _ = client.Write([]byte("SSH-2.0-client\r\n")) }()
+	buf := make([]byte, len("SSH-2.0-client\r\n"))
+	_ = origin.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(origin, buf); err != nil {
+		t.Fatalf("origin read: %v", err)
+	}
+	if !bytes.Equal(buf, []byte("SSH-2.0-client\r\n")) {
+		t.Fatalf("origin got %q", buf)
+	}
+
+	go func() { _, _ = origin.Write([]byte("SSH-2.0-server\r\n")) }()
+	reply := make([]byte, len("SSH-2.0-server\r\n"))
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("client read: %v", err)
+	}
+	if string(reply) != "SSH-2.0-server\r\n" {
+		t.Fatalf("client got %q", reply)
+	}
+
+	_ = client.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeTunnel did not return after the client closed")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 上游路由:按目标主机选择出站方式(直连、HTTP 代理、SOCKS5 代理)。
+//
+// 路由表按顺序匹配,首条命中即用;都不命中时走默认上游(全局上游代理,未启用时直连)。
+// MITM 转发(保真转发器与回退 Transport 的 Proxy 闭包)与直通隧道共用同一张表。
+
+// UpstreamRoute 是一条路由:目标主机匹配 Hosts 中任一项时经 Proxy 出站,Proxy 为 nil 表示直连。
+// Hosts 为主机通配模式(同解密范围)或 CIDR;CIDR 只匹配以 IP 字面量访问的目标,不做 DNS 解析。
+type UpstreamRoute struct {
+	Hosts []string
+	Proxy *url.URL
+}
+
+type upstreamRoute struct {
+	hosts []*regexp.Regexp
+	nets  []*net.IPNet
+	proxy *url.URL
+}
+
+func (r *upstreamRoute) matches(host string) bool {
+	if matchAnyHost(r.hosts, host) {
+		return true
+	}
+	if len(r.nets) == 0 {
+		return false
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamRoutesPtr 持有当前路由表;nil 或空表示全部走默认上游。
+var upstreamRoutesPtr atomic.Pointer[[]upstreamRoute]
+
+// SetUpstreamRoutes 由引擎层下发路由表,运行时即时生效(对此后新建的上游连接)、并发安全。
+// 没有有效主机模式的条目被忽略。
+func SetUpstreamRoutes(routes []UpstreamRoute) {
+	list := make([]upstreamRoute, 0, len(routes))
+	for _, rt := range routes {
+		var r upstreamRoute
+		for _, h := range rt.Hosts {
+			if _, n, err := net.ParseCIDR(strings.TrimSpace(h)); err == nil {
+				r.nets = append(r.nets, n)
+			} else if re := compileHostPattern(h); re != nil {
+				r.hosts = append(r.hosts, re)
+			}
+		}
+		if len(r.hosts) == 0 && len(r.nets) == 0 {
+			continue
+		}
+		r.proxy = cloneURL(rt.Proxy)
+		list = append(list, r)
+	}
+	upstreamRoutesPtr.Store(&list)
+}
+
+// UpstreamProxyFor 返回访问 hostport 应经的上游代理(nil 表示直连),返回副本。
+// 供上游客户端的 Proxy 闭包与直通隧道调用。
+func UpstreamProxyFor(hostport string) *url.URL {
+	if p := upstreamRoutesPtr.Load(); p != nil {
+		host := hostOnly(hostport)
+		for i := range *p {
+			if (*p)[i].matches(host) {
+				return cloneURL((*p)[i].proxy)
+			}
+		}
+	}
+	return UpstreamProxyURL()
+}
+
+// ParseUpstreamRoutes 解析文本形式的路由表:每行「主机模式[,主机模式...] 目标」,目标为
+// DIRECT 或代理地址(http://、https://、socks5://、socks5h://,省略 scheme 按 http://)。
+// 空行与 # 开头的注释行忽略;出错时返回带行号的错误。
+func ParseUpstreamRoutes(text string) ([]UpstreamRoute, error) {
+	var out []UpstreamRoute
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("第 %d 行: 应为「主机模式 目标」", i+1)
+		}
+		proxy, err := parseRouteTarget(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+		}
+		out = append(out, UpstreamRoute{Hosts: strings.Split(fields[0], ","), Proxy: proxy})
+	}
+	return out, nil
+}
+
+// parseRouteTarget 解析路由目标;DIRECT 返回 nil。
+func parseRouteTarget(s string) (*url.URL, error) {
+	if strings.EqualFold(s, flow.RouteDirect) {
+		return nil, nil
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.New("代理地址无法解析") // 不回显原文:其中可能带有凭据
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("不支持的代理类型 %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("代理地址缺少主机")
+	}
+	return u, nil
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	cp := *u
+	return &cp
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestUpstreamRoutes 按顺序首条命中;通配与 CIDR 均可匹配;DIRECT 直连;都不命中时走默认上游。
+func TestUpstreamRoutes(t *testing.T) {
+	t.Cleanup(func() {
+		SetUpstreamRoutes(nil)
+		SetUpstreamProxyURL(nil)
+	})
+	routes, err := ParseUpstreamRoutes(`
+# 注释
+*.corp.test,10.0.0.0/8  DIRECT
+api.corp.test           socks5h://u:p@127.0.0.1:1080
+*.test                  127.0.0.1:8080
+`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	SetUpstreamRoutes(routes)
+	def, _ := url.Parse("http://default.proxy:3128")
+	SetUpstreamProxyURL(def)
+
+	cases := []struct{ host, want string }{
+		{"api.corp.test:443", ""}, // 首条命中优先于其后更具体的规则
+		{"10.1.2.3:80", ""},
+		{"a.test:443", "http://127.0.0.1:8080"},
+		{"example.com:443", "http://default.proxy:3128"},
+		{"11.0.0.1:80", "http://default.proxy:3128"},
+	}
+	for _, c := range cases {
+		got := ""
+		if u := UpstreamProxyFor(c.host); u != nil {
+			got = u.String()
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.host, got, c.want)
+		}
+	}
+
+	if routes[1].Proxy.Scheme != "socks5h" || routes[1].Proxy.User.Username() != "u" {
+		t.Fatalf("socks5h 目标 = %v", routes[1].Proxy)
+	}
+	// 返回副本,调用方修改不影响路由表。
+	UpstreamProxyFor("a.test:443").Host = "evil:1"
+	if u := UpstreamProxyFor("a.test:443"); u.Host != "127.0.0.1:8080" {
+		t.Fatalf("路由表被改写: %v", u)
+	}
+}
+
+// TestParseUpstreamRoutesErrors 格式错误带行号,且错误信息不回显可能含凭据的地址。
+func TestParseUpstreamRoutesErrors(t *testing.T) {
+	for _, text := range []string{
+		"a.test",
+		"a.test DIRECT extra",
+		"\na.test ftp://u:secret@h:21",
+		"a.test http://u:secret@%zz",
+		"a.test socks5://",
+	} {
+		_, err := ParseUpstreamRoutes(text)
+		if err == nil {
+			t.Fatalf("%q: 应报错", text)
+		}
+		if !strings.Contains(err.Error(), "行") || strings.Contains(err.Error(), "secret") {
+			t.Fatalf("%q: %v", text, err)
+		}
+	}
+}
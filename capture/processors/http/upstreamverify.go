@@ -0,0 +1,87 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync/atomic"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 上游证书校验模式。
+const (
+	UpstreamVerifyOff     = "off"     // 不校验(历史行为)
+	UpstreamVerifyWarn    = "warn"    // 校验并把结论记入 flow,但照常转发
+	UpstreamVerifyEnforce = "enforce" // 校验未通过即中止握手,请求不发出
+)
+
+type upstreamVerifier struct {
+	mode  string
+	roots *x509.CertPool // 系统根 + 用户追加的根
+}
+
+// upstreamVerifierPtr 持有当前校验配置;nil 表示 off。
+var upstreamVerifierPtr atomic.Pointer[upstreamVerifier]
+
+// SetUpstreamVerify 由引擎层下发上游证书校验模式与追加信任的根证书(在系统根之外),
+// 运行时即时生效(对此后新建的上游连接)、并发安全。未知模式按 off 处理。
+func SetUpstreamVerify(mode string, roots []*x509.Certificate) {
+	if mode != UpstreamVerifyWarn && mode != UpstreamVerifyEnforce {
+		upstreamVerifierPtr.Store(nil)
+		return
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, c := range roots {
+		pool.AddCert(c)
+	}
+	upstreamVerifierPtr.Store(&upstreamVerifier{mode: mode, roots: pool})
+}
+
+// VerifyUpstreamConn 在 enforce 模式下校验上游证书,未通过时返回 *flow.UpstreamCertError;
+// 其它模式恒返回 nil。host 为空时只校验证书链。供保真转发器在握手时调用。
+func VerifyUpstreamConn(host string, cs tls.ConnectionState) error {
+	v := upstreamVerifierPtr.Load()
+	if v == nil || v.mode != UpstreamVerifyEnforce {
+		return nil
+	}
+	if err := v.verify(host, cs); err != nil {
+		uc := flow.UpstreamConnOf(cs)
+		uc.Verified, uc.VerifyError = true, err.Error()
+		return &flow.UpstreamCertError{Host: host, Conn: uc, Err: err}
+	}
+	return nil
+}
+
+// UpstreamConnInfo 生成上游 TLS 连接摘要;开启校验时附上校验结论。供保真转发器调用。
+func UpstreamConnInfo(host string, cs tls.ConnectionState) *flow.UpstreamConn {
+	uc := flow.UpstreamConnOf(cs)
+	if v := upstreamVerifierPtr.Load(); v != nil {
+		uc.Verified = true
+		if err := v.verify(host, cs); err != nil {
+			uc.VerifyError = err.Error()
+		}
+	}
+	return uc
+}
+
+// verify 以系统根与追加的根校验证书链与主机名。
+func (v *upstreamVerifier) verify(host string, cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("上游未提供证书")
+	}
+	inter := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		inter.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{DNSName: host, Roots: v.roots, Intermediates: inter})
+	return err
+}
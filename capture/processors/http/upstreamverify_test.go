@@ -0,0 +1,83 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestUpstreamVerify off 不校验;warn 只记结论;enforce 未通过时返回 UpstreamCertError。
+// 追加的根证书生效,主机名不符同样不通过。
+func TestUpstreamVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	cs := conn.ConnectionState()
+	_ = conn.Close()
+	t.Cleanup(func() { SetUpstreamVerify(UpstreamVerifyOff, nil) })
+
+	SetUpstreamVerify(UpstreamVerifyOff, nil)
+	if err := VerifyUpstreamConn("127.0.0.1", cs); err != nil {
+		t.Fatalf("off: %v", err)
+	}
+	if uc := UpstreamConnInfo("127.0.0.1", cs); uc.Verified || len(uc.Chain) == 0 || uc.Version == 0 {
+		t.Fatalf("off: 摘要 = %+v", uc)
+	}
+
+	SetUpstreamVerify(UpstreamVerifyWarn, nil)
+	if err := VerifyUpstreamConn("127.0.0.1", cs); err != nil {
+		t.Fatalf("warn 不应拦截: %v", err)
+	}
+	if uc := UpstreamConnInfo("127.0.0.1", cs); !uc.Verified || uc.VerifyError == "" {
+		t.Fatalf("warn: 未受信任的证书应记下失败原因: %+v", uc)
+	}
+
+	SetUpstreamVerify(UpstreamVerifyEnforce, nil)
+	var certErr *flow.UpstreamCertError
+	if err := VerifyUpstreamConn("127.0.0.1", cs); !errors.As(err, &certErr) || certErr.Conn.VerifyError == "" {
+		t.Fatalf("enforce: %v", err)
+	}
+
+	SetUpstreamVerify(UpstreamVerifyEnforce, []*x509.Certificate{srv.Certificate()})
+	if err := VerifyUpstreamConn("127.0.0.1", cs); err != nil {
+		t.Fatalf("追加根证书后应通过: %v", err)
+	}
+	if uc := UpstreamConnInfo("127.0.0.1", cs); !uc.Verified || uc.VerifyError != "" {
+		t.Fatalf("摘要 = %+v", uc)
+	}
+	if err := VerifyUpstreamConn("other.test", cs); !errors.As(err, &certErr) {
+		t.Fatalf("主机名不符应不通过: %v", err)
+	}
+}
+
+// TestFailUpstreamCertRejected 证书被拒记为 cert_rejected 并回 526 附原因,其余上游失败仍回 502。
+func TestFailUpstreamCertRejected(t *testing.T) {
+	f := flow.New(flow.ProtoHTTPS)
+	r := &branchResponder{}
+	certErr := &flow.UpstreamCertError{Host: "a.test", Conn: &flow.UpstreamConn{Verified: true}, Err: errors.New("expired")}
+	if err := failUpstream(f, fmt.Errorf("wrapped: %w", certErr), r); err != nil {
+		t.Fatal(err)
+	}
+	if f.State != flow.StateCertRejected || f.UpstreamConn() == nil || r.aborted == nil ||
+		r.aborted.StatusOnAbort != StatusInvalidUpstreamCert || r.aborted.Reason != certErr.Error() || r.badGatewayCalls != 0 {
+		t.Fatalf("state=%s aborted=%+v", f.State, r.aborted)
+	}
+
+	f, r = flow.New(flow.ProtoHTTPS), &branchResponder{}
+	_ = failUpstream(f, errors.New("dial refused"), r)
+	if f.State != flow.StateErrored || f.Error != "dial refused" || r.badGatewayCalls != 1 || r.aborted != nil {
+		t.Fatalf("state=%s error=%q", f.State, f.Error)
+	}
+}
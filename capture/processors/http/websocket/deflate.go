@@ -0,0 +1,213 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// permessage-deflate(RFC 7692)支持。
+//
+// 协商由两端自行完成:握手保真透传,代理只从上游的 101 响应里读出服务端接受的参数。
+// 压缩消息(首帧 RSV1)整条收齐后解压,再交插件与记录;未改动时原帧照转,改动后以
+// 单帧重新压缩发出。发送方保留上下文(context takeover)时,接收方的滑动窗口由此前
+// 转发的明文构成 —— 一旦某条消息被改写或丢弃,两端窗口就此分叉,该方向之后的每条
+// 压缩消息都必须解压后重新压缩,不能再照转原帧。
+
+// rsv1 是帧首字节中标记「压缩消息」的 RSV1 位。
+const rsv1 = 0x40
+
+// deflateWindow 是 DEFLATE 的最大滑动窗口(2^15)。
+const deflateWindow = 1 << 15
+
+// deflateTail 补回发送方按 RFC 7692 去掉的同步刷新尾(00 00 ff ff),再追加一个空的
+// 最终块,让 flate 读取器在消息末尾正常返回 EOF。
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// deflateParams 是服务端在 101 响应中接受的 permessage-deflate 参数。
+type deflateParams struct {
+	serverNoContextTakeover bool
+	clientNoContextTakeover bool
+	serverMaxWindowBits     int
+	clientMaxWindowBits     int
+}
+
+// negotiatedDeflate 从上游握手响应的原始头块中解析 permessage-deflate;未协商时返回 nil。
+func negotiatedDeflate(resp []byte) *deflateParams {
+	br := bufio.NewReader(bytes.NewReader(resp))
+	if _, err := br.ReadString('\n'); err != nil { // 状态行
+		return nil
+	}
+	hdr, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil && len(hdr) == 0 {
+		return nil
+	}
+	for _, v := range hdr.Values("Sec-Websocket-Extensions") {
+		for _, ext := range strings.Split(v, ",") {
+			if dp := parseDeflateExtension(ext); dp != nil {
+				return dp
+			}
+		}
+	}
+	return nil
+}
+
+// parseDeflateExtension 解析单个扩展项,如
+// "permessage-deflate; client_max_window_bits=10; server_no_context_takeover"。
+func parseDeflateExtension(ext string) *deflateParams {
+	parts := strings.Split(ext, ";")
+	if !strings.EqualFold(strings.TrimSpace(parts[0]), "permessage-deflate") {
+		return nil
+	}
+	dp := &deflateParams{serverMaxWindowBits: 15, clientMaxWindowBits: 15}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "server_no_context_takeover":
+			dp.serverNoContextTakeover = true
+		case "client_no_context_takeover":
+			dp.clientNoContextTakeover = true
+		case "server_max_window_bits":
+			dp.serverMaxWindowBits = windowBits(value)
+		case "client_max_window_bits":
+			dp.clientMaxWindowBits = windowBits(value)
+		}
+	}
+	return dp
+}
+
+// windowBits 解析窗口位数参数,缺省或非法时按 15。
+func windowBits(v string) int {
+	if n, err := strconv.Atoi(v); err == nil && n >= 8 && n <= 15 {
+		return n
+	}
+	return 15
+}
+
+// deflateState 是单方向的压缩消息处理状态,只由该方向的 pumpFrames 访问。
+type deflateState struct {
+	// takeover 表示发送方跨消息保留压缩上下文,dict 为其最近 32KB 明文。
+	takeover bool
+	dict     []byte
+	// level 是重新压缩所用的级别:接收方窗口小于 32KB 时只做 Huffman 编码,
+	// 不产生可能越出其窗口的回溯引用。
+	level int
+	// diverged 表示接收方窗口已与发送方分叉(见文件头注释)。
+	diverged bool
+	// broken 表示解压失败过:字典已不可信,此后原样透传,不再解读。
+	broken bool
+
+	frames []wsFrame // 正在收齐的压缩消息的各分片
+	size   int
+}
+
+// newDeflateStates 按协商参数为两个方向建立状态:客户端 → 上游由客户端压缩、上游解压,
+// 上游 → 客户端反之。未协商时返回 nil。
+func newDeflateStates(dp *deflateParams) map[string]*deflateState {
+	if dp == nil {
+		return nil
+	}
+	level := func(receiverBits int) int {
+		if receiverBits < 15 {
+			return flate.HuffmanOnly
+		}
+		return flate.DefaultCompression
+	}
+	return map[string]*deflateState{
+		flow.WSClientToServer: {takeover: !dp.clientNoContextTakeover, level: level(dp.clientMaxWindowBits)},
+		flow.WSServerToClient: {takeover: !dp.serverNoContextTakeover, level: level(dp.serverMaxWindowBits)},
+	}
+}
+
+// owns 报告 fr 是否属于压缩消息:RSV1 置位的文本/二进制首帧,或正在收齐的消息的续帧。
+// 控制帧可插在分片之间,照常单独转发。d 为 nil(未协商)或已解压失败时一律为 false。
+func (d *deflateState) owns(fr wsFrame) bool {
+	if d == nil || d.broken {
+		return false
+	}
+	if len(d.frames) > 0 {
+		return fr.opcode == opContinuation
+	}
+	return fr.rsv&rsv1 != 0 && (fr.opcode == opText || fr.opcode == opBinary)
+}
+
+// add 追加一个分片,返回收齐的消息是否已到末帧。
+func (d *deflateState) add(fr wsFrame) (bool, error) {
+	d.size += len(fr.payload)
+	if d.size > maxFramePayload {
+		return false, fmt.Errorf("websocket: 压缩消息过大 (%d 字节)", d.size)
+	}
+	d.frames = append(d.frames, fr)
+	return fr.fin, nil
+}
+
+// take 取出收齐的分片并重置。
+func (d *deflateState) take() []wsFrame {
+	frames := d.frames
+	d.frames, d.size = nil, 0
+	return frames
+}
+
+// inflate 解压一条消息的压缩数据;发送方保留上下文时以此前的明文为字典,并更新字典。
+func (d *deflateState) inflate(compressed []byte) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(compressed), bytes.NewReader(deflateTail))
+	var dict []byte
+	if d.takeover {
+		dict = d.dict
+	}
+	r := flate.NewReaderDict(src, dict)
+	defer r.Close()
+	plain, err := io.ReadAll(io.LimitReader(r, maxFramePayload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(plain) > maxFramePayload {
+		return nil, errors.New("websocket: 解压后的消息过大")
+	}
+	if d.takeover {
+		d.dict = appendWindow(d.dict, plain)
+	}
+	return plain, nil
+}
+
+// appendWindow 把 p 追加到字典,只保留最近 32KB。
+func appendWindow(dict, p []byte) []byte {
+	if len(p) >= deflateWindow {
+		return append(dict[:0], p[len(p)-deflateWindow:]...)
+	}
+	if over := len(dict) + len(p) - deflateWindow; over > 0 {
+		dict = append(dict[:0], dict[over:]...)
+	}
+	return append(dict, p...)
+}
+
+// deflate 以全新上下文压缩一条消息并去掉同步刷新尾。不引用历史数据,
+// 故无论接收方是否保留上下文都能正确解压。
+func deflate(plain []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4]), nil
+}
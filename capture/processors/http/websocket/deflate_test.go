@@ -0,0 +1,195 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/pipeline"
+)
+
+func TestNegotiatedDeflate(t *testing.T) {
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Extensions: x-webkit-foo, permessage-deflate; client_no_context_takeover; server_max_window_bits=10; client_max_window_bits\r\n" +
+		"\r\n"
+	dp := negotiatedDeflate([]byte(resp))
+	if dp == nil {
+		t.Fatal("应解析出 permessage-deflate")
+	}
+	want := deflateParams{clientNoContextTakeover: true, serverMaxWindowBits: 10, clientMaxWindowBits: 15}
+	if *dp != want {
+		t.Fatalf("参数 = %+v, want %+v", *dp, want)
+	}
+	states := newDeflateStates(dp)
+	if c2s := states[flow.WSClientToServer]; c2s.takeover || c2s.level != flate.DefaultCompression {
+		t.Fatalf("客户端→上游状态 = %+v", c2s)
+	}
+	if s2c := states[flow.WSServerToClient]; !s2c.takeover || s2c.level != flate.HuffmanOnly {
+		t.Fatalf("上游→客户端状态 = %+v", s2c)
+	}
+
+	if negotiatedDeflate([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n")) != nil {
+		t.Fatal("未协商扩展时应返回 nil")
+	}
+}
+
+// deflateSender 模拟保留上下文的发送方:整个连接共用一个压缩器,每条消息同步刷新后去尾。
+type deflateSender struct {
+	buf bytes.Buffer
+	w   *flate.Writer
+}
+
+func newDeflateSender(t *testing.T) *deflateSender {
+	s := &deflateSender{}
+	w, err := flate.NewWriter(&s.buf, flate.BestCompression)
+	if err != nil {
+		t.Fatalf("flate.NewWriter: %v", err)
+	}
+	s.w = w
+	return s
+}
+
+func (s *deflateSender) message(t *testing.T, plain string) []byte {
+	s.buf.Reset()
+	if _, err := s.w.Write([]byte(plain)); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if err := s.w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	return bytes.TrimSuffix(append([]byte(nil), s.buf.Bytes()...), deflateTail[:4])
+}
+
+// seenWSHook 记下插件看到的消息,并可选地改写。
+type seenWSHook struct {
+	seen    []string
+	rewrite []byte
+}
+
+func (*seenWSHook) Name() string      { return "seen-ws-hook" }
+func (*seenWSHook) Priority() int     { return 0 }
+func (*seenWSHook) Enabled() bool     { return true }
+func (*seenWSHook) Match(string) bool { return true }
+func (h *seenWSHook) OnWebSocketMessage(_ context.Context, m *flow.WSMessage) flow.Decision {
+	h.seen = append(h.seen, string(m.Data))
+	if h.rewrite != nil {
+		m.Data = append([]byte(nil), h.rewrite...)
+	}
+	return flow.ContinueDecision()
+}
+
+// compressedStream 构造两条保留上下文的压缩消息(第二条回溯引用第一条),第一条拆成两片,
+// 中间插一个 ping。另返回原帧照转时的预期输出:ping 不等消息收齐,先行转发。
+func compressedStream(t *testing.T, first, second string) (src, verbatim []byte) {
+	sender := newDeflateSender(t)
+	m1, m2 := sender.message(t, first), sender.message(t, second)
+	head := wsFrame{rsv: rsv1, opcode: opText, payload: m1[:len(m1)/2]}
+	ping := wsFrame{fin: true, opcode: opPing, payload: []byte("p")}
+	tail := wsFrame{fin: true, opcode: opContinuation, payload: m1[len(m1)/2:]}
+	next := wsFrame{fin: true, rsv: rsv1, opcode: opText, payload: m2}
+	encode := func(frames ...wsFrame) []byte {
+		var buf bytes.Buffer
+		for _, fr := range frames {
+			if err := writeFrame(&buf, fr, false); err != nil {
+				t.Fatalf("writeFrame: %v", err)
+			}
+		}
+		return buf.Bytes()
+	}
+	return encode(head, ping, tail, next), encode(ping, head, tail, next)
+}
+
+func newDeflateProcessor(t *testing.T, hook *seenWSHook) *Processor {
+	previous := activePipeline
+	t.Cleanup(func() { activePipeline = previous })
+	pl := pipeline.New(nil, nil)
+	pl.Register(hook)
+	activePipeline = pl
+
+	request, _ := http.NewRequest(http.MethodGet, "http://example.test/socket", nil)
+	p := New(newMockConnection(newMockConn(""), newMockServer()), request, false)
+	p.targetURL = "ws://example.test/socket"
+	p.deflate = newDeflateStates(&deflateParams{serverMaxWindowBits: 15, clientMaxWindowBits: 15})
+	return p
+}
+
+func TestPumpFramesInflatesForPlugins(t *testing.T) {
+	const first, second = `{"event":"hello","data":"world"}`, `{"event":"hello","data":"again"}`
+	hook := &seenWSHook{}
+	p := newDeflateProcessor(t, hook)
+	src, verbatim := compressedStream(t, first, second)
+
+	var dst bytes.Buffer
+	p.pumpFrames(newMockServer(), bufio.NewReader(bytes.NewReader(src)), &dst, false, flow.WSServerToClient)
+
+	if len(hook.seen) != 2 || hook.seen[0] != first || hook.seen[1] != second {
+		t.Fatalf("插件应看到解压后的明文, got %q", hook.seen)
+	}
+	if !bytes.Equal(dst.Bytes(), verbatim) {
+		t.Fatal("未改动的压缩消息应原帧照转")
+	}
+}
+
+func TestPumpFramesRedeflatesModified(t *testing.T) {
+	const rewrite = `{"event":"patched"}`
+	hook := &seenWSHook{rewrite: []byte(rewrite)}
+	p := newDeflateProcessor(t, hook)
+	src, _ := compressedStream(t, `{"event":"hello","data":"world"}`, `{"event":"hello","data":"again"}`)
+
+	var dst bytes.Buffer
+	p.pumpFrames(newMockServer(), bufio.NewReader(bytes.NewReader(src)), &dst, false, flow.WSServerToClient)
+
+	// 接收方保留上下文解压:改写后窗口已分叉,两条消息都须能按转发出的字节独立解出。
+	receiver := &deflateState{takeover: true}
+	var got []string
+	for dst.Len() > 0 {
+		fr, err := readFrame(&dst)
+		if err != nil {
+			t.Fatalf("readFrame: %v", err)
+		}
+		if fr.opcode == opPing {
+			continue
+		}
+		if !fr.fin || fr.rsv != rsv1 || fr.opcode != opText {
+			t.Fatalf("改写后应以单个压缩文本帧发出, got %+v", fr)
+		}
+		plain, err := receiver.inflate(fr.payload)
+		if err != nil {
+			t.Fatalf("接收方解压失败: %v", err)
+		}
+		got = append(got, string(plain))
+	}
+	if len(got) != 2 || got[0] != rewrite || got[1] != rewrite {
+		t.Fatalf("接收方解出 %q, want 两条 %q", got, rewrite)
+	}
+	if !p.deflate[flow.WSServerToClient].diverged {
+		t.Fatal("保留上下文的方向被改写后应标记为分叉")
+	}
+}
+
+func TestPumpFramesBrokenDeflatePassesThrough(t *testing.T) {
+	hook := &seenWSHook{}
+	p := newDeflateProcessor(t, hook)
+	var src bytes.Buffer
+	if err := writeFrame(&src, wsFrame{fin: true, rsv: rsv1, opcode: opBinary, payload: []byte{0xff, 0xff, 0xff}}, false); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+	raw := append([]byte(nil), src.Bytes()...)
+
+	var dst bytes.Buffer
+	p.pumpFrames(newMockServer(), bufio.NewReader(&src), &dst, false, flow.WSClientToServer)
+	if !bytes.Equal(dst.Bytes(), raw) {
+		t.Fatal("解压失败的消息应原样转发")
+	}
+	if len(hook.seen) != 0 || !p.deflate[flow.WSClientToServer].broken {
+		t.Fatalf("解压失败后不应交插件并应停止解读, seen=%q", hook.seen)
+	}
+}
@@ -84,7 +84,7 @@ func TestWSRecorderLifecycleAndSnapshotLimit(t *testing.T) {
 	}
 
 	SetWSSink(nil)
-	if newWSRecorder("ws://example.test") != nil {
+	if newWSRecorder("ws://example.test", "") != nil {
 		t.Fatal("newWSRecorder should return nil without a sink")
 	}
 	var nilRecorder *wsRecorder
@@ -98,7 +98,7 @@ func TestWSRecorderLifecycleAndSnapshotLimit(t *testing.T) {
 
 	sink := &recordingWSSink{}
 	SetWSSink(sink)
-	recorder := newWSRecorder("ws://example.test/socket")
+	recorder := newWSRecorder("ws://example.test/socket", "")
 	if recorder == nil || recorder.id() == "" {
 		t.Fatal("newWSRecorder did not create an open session")
 	}
@@ -14,7 +14,7 @@ import (
 
 // 最小 RFC 6455 帧编解码:供「保真」WebSocket 代理逐帧透传。
 // 相比 x/net/websocket 的 Conn(会按 PayloadType 重定文本/二进制、且握手由库合成),
-// 这里按帧原样转发,保留 FIN / opcode / 分片边界,只在数据帧上做插件拦截 / 记录。
+// 这里按帧原样转发,保留 FIN / RSV / opcode / 分片边界,只在数据帧上做插件拦截 / 记录。
 
 const (
 	opContinuation = 0x0
@@ -31,6 +31,7 @@ const maxFramePayload = 64 << 20
 // wsFrame 是一个已解掩(若来时带掩码)的 WebSocket 帧。
 type wsFrame struct {
 	fin     bool
+	rsv     byte // RSV1-3 位(原位,未移位),由扩展定义;RSV1 标记 permessage-deflate 压缩消息
 	opcode  byte
 	payload []byte
 }
@@ -47,7 +48,8 @@ func readFrame(r io.Reader) (wsFrame, error) {
 		return wsFrame{}, err
 	}
 	fin := h[0]&0x80 != 0
-	opcode := h[0] & 0x0f // RSV 位(扩展)不解析,透明转发
+	rsv := h[0] & 0x70
+	opcode := h[0] & 0x0f
 	masked := h[1]&0x80 != 0
 	n := uint64(h[1] & 0x7f)
 	switch n {
@@ -82,13 +84,13 @@ func readFrame(r io.Reader) (wsFrame, error) {
 			payload[i] ^= mask[i&3]
 		}
 	}
-	return wsFrame{fin: fin, opcode: opcode, payload: payload}, nil
+	return wsFrame{fin: fin, rsv: rsv, opcode: opcode, payload: payload}, nil
 }
 
 // writeFrame 把一帧写到 w。mask=true 时按「客户端」角色加掩(发往上游必须加掩);
 // mask=false 时按「服务端」角色不加掩(发往客户端必须不加掩)。
 func writeFrame(w io.Writer, f wsFrame, mask bool) error {
-	b0 := f.opcode
+	b0 := f.rsv&0x70 | f.opcode
 	if f.fin {
 		b0 |= 0x80
 	}
@@ -102,3 +102,18 @@ func TestFrameControlAndFragmentPreserved(t *testing.T) {
 		}
 	}
 }
+
+// TestFrameRSVPreserved 校验 RSV 位(扩展,如 permessage-deflate 的 RSV1)往返保留。
+func TestFrameRSVPreserved(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, wsFrame{fin: true, rsv: rsv1, opcode: opText, payload: []byte("x")}, true); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+	if b := buf.Bytes()[0]; b != 0xc1 { // FIN + RSV1 + text
+		t.Fatalf("首字节应为 0xc1, 实得 0x%02x", b)
+	}
+	got, err := readFrame(&buf)
+	if err != nil || got.rsv != rsv1 || got.opcode != opText {
+		t.Fatalf("readFrame = %+v, err %v", got, err)
+	}
+}
@@ -40,7 +40,7 @@ func TestHandleFrameDataRecordsWithoutPipeline(t *testing.T) {
 	request, _ := http.NewRequest(http.MethodGet, "http://example.test/ws", nil)
 	processor := New(newMockConnection(newMockConn(""), server), request, false)
 	processor.targetURL = "ws://example.test/ws"
-	processor.recorder = newWSRecorder(processor.targetURL)
+	processor.recorder = newWSRecorder(processor.targetURL, "")
 
 	data, drop := processor.handleFrameData([]byte("hello"), flow.WSClientToServer, server)
 	if drop || string(data) != "hello" {
@@ -121,7 +121,7 @@ func TestResolveProcessAsyncSkipsWithoutRecorderOrConn(t *testing.T) {
 	noRecorder.resolveProcessAsync()
 
 	withRecorder := New(&mockConnection{server: server}, &http.Request{}, false)
-	withRecorder.recorder = newWSRecorder("ws://example.test/ws")
+	withRecorder.recorder = newWSRecorder("ws://example.test/ws", "")
 	sink.drain() // 丢掉 newWSRecorder 的首次登记快照
 	withRecorder.resolveProcessAsync()
 
@@ -163,7 +163,7 @@ func TestResolveProcessAsyncAttachesProcess(t *testing.T) {
 	sink := newGapSignalSink()
 	wsSink = sink
 	processor := New(newMockConnection(accepted, newMockServer()), &http.Request{}, false)
-	processor.recorder = newWSRecorder("ws://example.test/ws")
+	processor.recorder = newWSRecorder("ws://example.test/ws", "")
 	sink.drain()
 	processor.resolveProcessAsync()
 
@@ -16,19 +16,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mintfog/sniffy/capture/processors/internal/relayio"
 	"github.com/mintfog/sniffy/internal/flow"
 )
 
 const wsDialTimeout = 30 * time.Second
 
+// Dialer 拨通上游 host:port。由引擎注入,使 WebSocket 上游与直通隧道共用上游代理等出站策略。
+type Dialer = relayio.Dialer
+
+var dialTarget Dialer = relayio.DialDirect
+
+// SetDialer 注入出站拨号函数;传入 nil 时保留现有值。
+func SetDialer(d Dialer) {
+	if d != nil {
+		dialTarget = d
+	}
+}
+
 // dialUpstreamFaithful 连接上游并以「保真」方式转发客户端的 WebSocket 握手请求,
 // 返回:上游连接、其读缓冲、上游握手响应的原始字节、状态码。
 func (p *Processor) dialUpstreamFaithful() (net.Conn, *bufio.Reader, []byte, int, error) {
-	host := p.request.Host
-	if host == "" {
-		host = p.request.URL.Host
-	}
-	raw, err := (&net.Dialer{Timeout: wsDialTimeout}).Dial("tcp", wsHostPort(host, p.isHttps))
+	host := upstreamHost(p.request)
+	raw, err := dialTarget(wsHostPort(host, p.isHttps))
 	if err != nil {
 		return nil, nil, nil, 0, err
 	}
@@ -0,0 +1,170 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 向打开中的会话注入帧:proxyFrames 为每条已登记的会话开一个注入通道,外部经 Inject
+// 按会话 ID 投递,由会话自己的 goroutine 写到对应一端。注入帧不经插件管道,以原样
+// 字节测试两端对意外消息的反应;数据帧记入会话并标记 Injected。
+
+// ErrSessionNotFound 表示会话不存在或已结束。
+var ErrSessionNotFound = errors.New("websocket: 会话不存在或已关闭")
+
+// errMidMessage 表示该方向正在转发一条分片消息:数据帧插进分片之间会破坏消息边界。
+var errMidMessage = errors.New("websocket: 该方向有分片消息正在转发，请稍后重试")
+
+// invalidFrameError 标记调用方给出的帧不合法,可映射为 HTTP 400。
+type invalidFrameError struct{ msg string }
+
+func (e *invalidFrameError) Error() string      { return "websocket: " + e.msg }
+func (e *invalidFrameError) InvalidInput() bool { return true }
+
+// liveSessions 是会话 ID → *liveSession 的登记表。
+var liveSessions sync.Map
+
+// frameWriter 串行化一个方向的帧输出:转发与注入可能并发写同一端,帧必须整帧写出。
+type frameWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	mask bool
+	// fragmenting 表示最近转发的数据帧未置 FIN,分片消息尚未结束。
+	fragmenting bool
+}
+
+// write 写出一组帧(通常是一帧,或一条压缩消息的全部分片),期间不会插入注入帧。
+func (fw *frameWriter) write(frames ...wsFrame) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	for _, fr := range frames {
+		if err := writeFrame(fw.w, fr, fw.mask); err != nil {
+			return err
+		}
+		if fr.isData() {
+			fw.fragmenting = !fr.fin
+		}
+	}
+	return nil
+}
+
+// inject 写出一帧注入帧;分片消息转发中途拒绝数据帧,控制帧照常可插入。
+func (fw *frameWriter) inject(fr wsFrame) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.fragmenting && fr.isData() {
+		return errMidMessage
+	}
+	return writeFrame(fw.w, fr, fw.mask)
+}
+
+// injection 是一次注入请求,结果经 result 回传。
+type injection struct {
+	direction string
+	frame     wsFrame
+	result    chan error
+}
+
+// liveSession 是一条打开中的会话的注入入口。
+type liveSession struct {
+	out    map[string]*frameWriter // 按方向:client->server 写上游,server->client 写客户端
+	inject chan injection
+	done   chan struct{}
+}
+
+// output 返回 direction 方向的帧输出;会话未登记(如单测直接驱动 pumpFrames)时独立构造。
+func (p *Processor) output(direction string, dst io.Writer, mask bool) *frameWriter {
+	if p.live != nil {
+		return p.live.out[direction]
+	}
+	return &frameWriter{w: dst, mask: mask}
+}
+
+// serveInjections 处理投递到会话的注入请求,直到 done 关闭。
+func (p *Processor) serveInjections(live *liveSession) {
+	for {
+		select {
+		case req := <-live.inject:
+			err := live.out[req.direction].inject(req.frame)
+			if err == nil && req.frame.isData() {
+				p.recorder.recordInjected(req.direction, frameMessageType(req.frame), req.frame.payload)
+			}
+			req.result <- err
+		case <-live.done:
+			return
+		}
+	}
+}
+
+// Inject 向一条打开中的会话注入一帧。direction 为 flow.WSClientToServer(发往上游)或
+// flow.WSServerToClient(发往客户端);frameType 为 flow.WSText / WSBinary / WSPing /
+// WSPong / WSClose,关闭帧的 data 为原始载荷(2 字节状态码 + 原因)。
+func Inject(sessionID, direction, frameType string, data []byte) error {
+	if direction != flow.WSClientToServer && direction != flow.WSServerToClient {
+		return &invalidFrameError{msg: "未知方向 " + direction}
+	}
+	fr, err := injectedFrame(frameType, data)
+	if err != nil {
+		return err
+	}
+	v, ok := liveSessions.Load(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	live := v.(*liveSession)
+	req := injection{direction: direction, frame: fr, result: make(chan error, 1)}
+	select {
+	case live.inject <- req:
+	case <-live.done:
+		return ErrSessionNotFound
+	}
+	return <-req.result
+}
+
+// injectedFrame 按帧类型构造一帧完整(FIN)的注入帧。注入帧从不压缩:permessage-deflate
+// 下未压缩消息同样合法,且不影响两端的压缩上下文。
+func injectedFrame(frameType string, data []byte) (wsFrame, error) {
+	var op byte
+	switch frameType {
+	case flow.WSText:
+		op = opText
+	case flow.WSBinary:
+		op = opBinary
+	case flow.WSPing:
+		op = opPing
+	case flow.WSPong:
+		op = opPong
+	case flow.WSClose:
+		op = opClose
+	default:
+		return wsFrame{}, &invalidFrameError{msg: "未知帧类型 " + frameType}
+	}
+	fr := wsFrame{fin: true, opcode: op, payload: append([]byte(nil), data...)}
+	if !fr.isData() && len(data) > 125 {
+		return wsFrame{}, &invalidFrameError{msg: "控制帧载荷不能超过 125 字节"}
+	}
+	if op == opClose && len(data) == 1 {
+		return wsFrame{}, &invalidFrameError{msg: "关闭帧载荷须为空或以 2 字节状态码开头"}
+	}
+	if len(data) > maxFramePayload {
+		return wsFrame{}, &invalidFrameError{msg: "载荷过大"}
+	}
+	return fr, nil
+}
+
+// frameMessageType 返回注入数据帧记入会话的消息类型。与转发的消息按内容判定不同,
+// 注入帧按调用方指定的帧类型记录。
+func frameMessageType(fr wsFrame) string {
+	if fr.opcode == opText {
+		return flow.WSText
+	}
+	return flow.WSBinary
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestInjectIntoLiveSession 在 proxyFrames 运行期间向两个方向各注入一帧:发往上游的帧须加掩,
+// 发往客户端的不加掩;数据帧记入会话并标记 Injected。会话结束后注入返回 ErrSessionNotFound。
+func TestInjectIntoLiveSession(t *testing.T) {
+	prevPipeline, prevSink := activePipeline, wsSink
+	t.Cleanup(func() { activePipeline, wsSink = prevPipeline, prevSink })
+	activePipeline = nil
+	sink := &recordingWSSink{}
+	wsSink = sink
+
+	server := newMockServer()
+	request, _ := http.NewRequest(http.MethodGet, "http://example.test/ws", nil)
+	processor := New(newMockConnection(newMockConn(""), server), request, false)
+	processor.targetURL = "ws://example.test/ws"
+	processor.recorder = newWSRecorder(processor.targetURL, "")
+	id := processor.recorder.id()
+
+	clientProxy, clientPeer := net.Pipe()
+	upstreamProxy, upstreamPeer := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		processor.proxyFrames(server, clientProxy, bufio.NewReader(clientProxy), upstreamProxy, bufio.NewReader(upstreamProxy))
+	}()
+	waitLive(t, id)
+
+	injectAsync := func(direction, frameType string, data []byte) chan error {
+		errc := make(chan error, 1)
+		go func() { errc <- Inject(id, direction, frameType, data) }()
+		return errc
+	}
+
+	errc := injectAsync(flow.WSServerToClient, flow.WSText, []byte("to-client"))
+	raw := make([]byte, 2)
+	if _, err := clientPeer.Read(raw); err != nil || raw[0] != 0x81 || raw[1]&0x80 != 0 {
+		t.Fatalf("发往客户端的帧头 = %x, err %v(应为无掩码文本帧)", raw, err)
+	}
+	payload := make([]byte, raw[1])
+	if _, err := clientPeer.Read(payload); err != nil || string(payload) != "to-client" {
+		t.Fatalf("发往客户端的载荷 = %q, err %v", payload, err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Inject(server->client): %v", err)
+	}
+
+	errc = injectAsync(flow.WSClientToServer, flow.WSPing, []byte("hi"))
+	fr, err := readFrame(upstreamPeer)
+	if err != nil || fr.opcode != opPing || string(fr.payload) != "hi" {
+		t.Fatalf("发往上游的帧 = %+v, err %v", fr, err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Inject(client->server): %v", err)
+	}
+
+	recorded := sink.last()
+	if len(recorded.Messages) != 1 {
+		t.Fatalf("只有数据帧应记入会话, got %d 条", len(recorded.Messages))
+	}
+	if m := recorded.Messages[0]; !m.Injected || m.Direction != flow.WSServerToClient || string(m.Data) != "to-client" {
+		t.Fatalf("记录的注入消息 = %+v", m)
+	}
+
+	_ = clientPeer.Close()
+	_ = upstreamPeer.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("proxyFrames 未在两端关闭后返回")
+	}
+	if err := Inject(id, flow.WSServerToClient, flow.WSText, nil); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("会话结束后注入 err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func waitLive(t *testing.T, id string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := liveSessions.Load(id); ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("会话未登记注入入口")
+}
+
+func TestInjectRejectsInvalidFrames(t *testing.T) {
+	cases := []struct {
+		name, direction, frameType string
+		data                       []byte
+	}{
+		{"方向", "sideways", flow.WSText, nil},
+		{"类型", flow.WSClientToServer, "continuation", nil},
+		{"控制帧过长", flow.WSClientToServer, flow.WSPing, bytes.Repeat([]byte("x"), 126)},
+		{"关闭帧残缺状态码", flow.WSServerToClient, flow.WSClose, []byte{0x03}},
+	}
+	for _, tc := range cases {
+		err := Inject("any", tc.direction, tc.frameType, tc.data)
+		var invalid interface{ InvalidInput() bool }
+		if !errors.As(err, &invalid) {
+			t.Fatalf("%s: err = %v, want 输入错误", tc.name, err)
+		}
+	}
+	if err := Inject("missing", flow.WSClientToServer, flow.WSText, []byte("x")); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("未登记的会话 err = %v", err)
+	}
+}
+
+// TestFrameWriterRejectsDataMidMessage 分片消息转发中途只允许插入控制帧。
+func TestFrameWriterRejectsDataMidMessage(t *testing.T) {
+	var buf bytes.Buffer
+	fw := &frameWriter{w: &buf}
+	if err := fw.write(wsFrame{opcode: opText, payload: []byte("part")}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := fw.inject(wsFrame{fin: true, opcode: opText, payload: []byte("x")}); !errors.Is(err, errMidMessage) {
+		t.Fatalf("分片中途注入数据帧 err = %v", err)
+	}
+	if err := fw.inject(wsFrame{fin: true, opcode: opPing}); err != nil {
+		t.Fatalf("分片中途注入控制帧: %v", err)
+	}
+	if err := fw.write(wsFrame{fin: true, opcode: opContinuation, payload: []byte("end")}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := fw.inject(wsFrame{fin: true, opcode: opText, payload: []byte("x")}); err != nil {
+		t.Fatalf("消息结束后注入数据帧: %v", err)
+	}
+}
@@ -48,8 +48,9 @@ type wsRecorder struct {
 	session *flow.WSSession
 }
 
-// newWSRecorder 创建并登记一条处于 open 状态的会话。sink 未注入时返回 nil。
-func newWSRecorder(url string) *wsRecorder {
+// newWSRecorder 创建并登记一条处于 open 状态的会话,listener 为所属附加监听端标识。
+// sink 未注入时返回 nil。
+func newWSRecorder(url, listener string) *wsRecorder {
 	if wsSink == nil {
 		return nil
 	}
@@ -57,6 +58,7 @@ func newWSRecorder(url string) *wsRecorder {
 		session: &flow.WSSession{
 			ID:        flow.NewID(),
 			URL:       url,
+			Listener:  listener,
 			Status:    "open",
 			StartTime: time.Now(),
 			Messages:  make([]flow.WSMessage, 0, 16),
@@ -78,6 +80,15 @@ func (r *wsRecorder) id() string {
 
 // record 追加一条消息并推送更新。
 func (r *wsRecorder) record(direction, msgType string, data []byte) {
+	r.add(direction, msgType, data, false)
+}
+
+// recordInjected 追加一条经 Inject 注入的消息并推送更新。
+func (r *wsRecorder) recordInjected(direction, msgType string, data []byte) {
+	r.add(direction, msgType, data, true)
+}
+
+func (r *wsRecorder) add(direction, msgType string, data []byte, injected bool) {
 	if r == nil {
 		return
 	}
@@ -93,6 +104,7 @@ func (r *wsRecorder) record(direction, msgType string, data []byte) {
 		Type:      msgType,
 		Data:      append([]byte(nil), data...),
 		Timestamp: time.Now(),
+		Injected:  injected,
 	})
 	if len(s.Messages) > maxWSMessages {
 		s.Messages = append(s.Messages[:0], s.Messages[len(s.Messages)-maxWSMessages:]...)
@@ -7,6 +7,7 @@ package websocket
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"io"
 	"net"
@@ -26,6 +27,10 @@ type Processor struct {
 	isHttps   bool
 	recorder  *wsRecorder // 会话记录器(wsSink 注入时启用)
 	targetURL string      // 目标 WebSocket URL(供消息 URL 门控)
+	// deflate 是协商了 permessage-deflate 时按方向的解压状态;未协商时为 nil。
+	deflate map[string]*deflateState
+	// live 是会话的注入入口,仅在会话已登记(有 recorder)时于 proxyFrames 内建立。
+	live *liveSession
 }
 
 // New 创建新的WebSocket处理器
@@ -68,8 +73,11 @@ func (p *Processor) Process(server types.Server) error {
 	}
 
 	server.LogInfo("WebSocket连接建立成功，开始代理数据: %s", p.targetURL)
+	if p.deflate = newDeflateStates(negotiatedDeflate(respBytes)); p.deflate != nil {
+		server.LogDebug("WebSocket 已协商 permessage-deflate: %s", p.targetURL)
+	}
 	// 登记一条 WebSocket 会话(供 UI 实时展示),并异步补进程信息。
-	p.recorder = newWSRecorder(p.targetURL)
+	p.recorder = newWSRecorder(p.targetURL, flow.ListenerFrom(p.request.Context()))
 	p.resolveProcessAsync()
 	defer p.recorder.close()
 
@@ -105,6 +113,23 @@ func (p *Processor) proxyFrames(server types.Server, clientConn net.Conn, client
 		})
 	}
 
+	if id := p.recorder.id(); id != "" {
+		p.live = &liveSession{
+			out: map[string]*frameWriter{
+				flow.WSClientToServer: {w: upstream, mask: true},
+				flow.WSServerToClient: {w: clientConn},
+			},
+			inject: make(chan injection),
+			done:   make(chan struct{}),
+		}
+		liveSessions.Store(id, p.live)
+		go p.serveInjections(p.live)
+		defer func() {
+			liveSessions.Delete(id)
+			close(p.live.done)
+		}()
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 	// 客户端 → 上游:客户端帧带掩码;作为客户端发往上游须(重新)加掩。
@@ -125,7 +150,11 @@ func (p *Processor) proxyFrames(server types.Server, clientConn net.Conn, client
 
 // pumpFrames 从 src 逐帧读出:数据帧经插件拦截/记录(可被改写或丢弃),控制帧原样转发;
 // 再按 maskOut 角色写到 dst。收到 Close 帧时转发后结束本方向。
+// 压缩消息(permessage-deflate)先收齐全部分片,解压后整条交 forwardCompressed;
+// 其间插入的控制帧不等待,先行转发。
 func (p *Processor) pumpFrames(server types.Server, src *bufio.Reader, dst io.Writer, maskOut bool, direction string) {
+	pmd := p.deflate[direction]
+	out := p.output(direction, dst, maskOut)
 	for {
 		fr, err := readFrame(src)
 		if err != nil {
@@ -134,6 +163,21 @@ func (p *Processor) pumpFrames(server types.Server, src *bufio.Reader, dst io.Wr
 			}
 			return
 		}
+		if pmd.owns(fr) {
+			done, err := pmd.add(fr)
+			if err != nil {
+				server.LogDebug("WebSocket %s 读帧结束: %v", direction, err)
+				return
+			}
+			if !done {
+				continue
+			}
+			if err := p.forwardCompressed(server, pmd, out, direction); err != nil {
+				server.LogDebug("WebSocket %s 写帧失败: %v", direction, err)
+				return
+			}
+			continue
+		}
 		if fr.isData() {
 			data, drop := p.handleFrameData(fr.payload, direction, server)
 			if drop {
@@ -141,7 +185,7 @@ func (p *Processor) pumpFrames(server types.Server, src *bufio.Reader, dst io.Wr
 			}
 			fr.payload = data
 		}
-		if err := writeFrame(dst, fr, maskOut); err != nil {
+		if err := out.write(fr); err != nil {
 			server.LogDebug("WebSocket %s 写帧失败: %v", direction, err)
 			return
 		}
@@ -151,6 +195,41 @@ func (p *Processor) pumpFrames(server types.Server, src *bufio.Reader, dst io.Wr
 	}
 }
 
+// forwardCompressed 解压收齐的压缩消息,交插件/记录后转发。未改动且两端窗口一致时原帧照转,
+// 保持线缆字节不变;被改写或窗口已分叉时重新压缩成单帧发出。解压失败则原样转发,
+// 并停止解读该方向此后的压缩消息。
+func (p *Processor) forwardCompressed(server types.Server, pmd *deflateState, out *frameWriter, direction string) error {
+	frames := pmd.take()
+	var compressed []byte
+	for _, fr := range frames {
+		compressed = append(compressed, fr.payload...)
+	}
+	plain, err := pmd.inflate(compressed)
+	if err != nil {
+		server.LogDebug("WebSocket %s permessage-deflate 解压失败,此后原样转发: %v", direction, err)
+		pmd.broken = true
+		return out.write(frames...)
+	}
+
+	data, drop := p.handleFrameData(plain, direction, server)
+	modified := drop || !bytes.Equal(data, plain)
+	if modified && pmd.takeover {
+		// 发送方的压缩器仍以原明文为窗口,接收方却没收到它:此后只能逐条重新压缩。
+		pmd.diverged = true
+	}
+	if drop {
+		return nil
+	}
+	if !modified && !pmd.diverged {
+		return out.write(frames...)
+	}
+	payload, err := deflate(data, pmd.level)
+	if err != nil {
+		return err
+	}
+	return out.write(wsFrame{fin: true, rsv: frames[0].rsv, opcode: frames[0].opcode, payload: payload})
+}
+
 // handleFrameData 把数据帧 payload 送入插件管道并记录会话,
 // 返回(可能被改写的)payload 与是否应丢弃该帧。
 func (p *Processor) handleFrameData(data []byte, direction string, server types.Server) ([]byte, bool) {
@@ -196,11 +275,20 @@ func (p *Processor) buildWebSocketURL() string {
 	}
 	target := *p.request.URL // 复制,避免改动原始请求
 	target.Scheme = scheme
-	target.Host = p.request.Host
+	target.Host = upstreamHost(p.request)
 	target.Fragment = ""
 	return target.String()
 }
 
+// upstreamHost 返回握手要连接的上游 host[:port]:请求 URL 已带主机(绝对形式,或被反向代理
+// 改写到上游)时以其为准 —— 此时 Host 头可能被刻意保留为客户端原值;否则取 Host 头。
+func upstreamHost(r *http.Request) string {
+	if r.URL != nil && r.URL.Host != "" {
+		return r.URL.Host
+	}
+	return r.Host
+}
+
 // sendWebSocketError 发送WebSocket错误响应
 func (p *Processor) sendWebSocketError() error {
 	const errorResp = "HTTP/1.1 502 Bad Gateway\r\n" +
@@ -0,0 +1,40 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package relayio 汇集 TCP / MQTT / WebSocket 等中继处理器共用的出站拨号与收尾辅助。
+package relayio
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// DialTimeout 是直连目标的建连超时(未注入 Dialer 时使用)。
+const DialTimeout = 30 * time.Second
+
+// Dialer 拨通中继目标 host:port。由引擎注入,使各中继与直通隧道共用上游代理等出站策略。
+type Dialer func(target string) (net.Conn, error)
+
+// DialDirect 是未注入 Dialer 时的默认拨号:直连目标。
+func DialDirect(target string) (net.Conn, error) {
+	return net.DialTimeout("tcp", target, DialTimeout)
+}
+
+// CloseWrite 半关闭连接的写端;连接不支持(如经 TLS / 限速包装)时返回 false。
+func CloseWrite(c net.Conn) bool {
+	return types.CloseWrite(c) == nil
+}
+
+// Err 过滤掉「另一方向已关闭连接」造成的错误,它们是正常收尾的副产物。
+func Err(err error) error {
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
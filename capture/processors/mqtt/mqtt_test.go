@@ -0,0 +1,411 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/pipeline"
+)
+
+// ---- 报文构造 ----
+
+func str(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func connectPacket(version byte, clientID, username string) []byte {
+	name := "MQTT"
+	if version == 3 {
+		name = "MQIsdp"
+	}
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+	}
+	body := append(str(name), version, flags, 0x00, 0x3c)
+	if version == protocolV5 {
+		body = append(body, 0x00)
+	}
+	body = append(body, str(clientID)...)
+	if username != "" {
+		body = append(body, str(username)...)
+	}
+	return encodePacket(typeConnect<<4, body)
+}
+
+func publishPacket(topic string, qos byte, id uint16, props []byte, payload string) []byte {
+	body := str(topic)
+	if qos > 0 {
+		body = append(body, byte(id>>8), byte(id))
+	}
+	if props != nil {
+		body = append(appendRemainingLength(body, len(props)), props...)
+	}
+	body = append(body, payload...)
+	return encodePacket(typePublish<<4|qos<<1, body)
+}
+
+func readPacket(t *testing.T, r *bufio.Reader) (fixedHeader, []byte) {
+	t.Helper()
+	h, err := readFixedHeader(r)
+	if err != nil {
+		t.Fatalf("read fixed header: %v", err)
+	}
+	body := make([]byte, h.length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return h, body
+}
+
+// ---- 编解码 ----
+
+func TestIsConnect(t *testing.T) {
+	for name, tc := range map[string]struct {
+		head []byte
+		want bool
+	}{
+		"v311":      {connectPacket(4, "c", ""), true},
+		"v31":       {connectPacket(3, "c", ""), true},
+		"v5":        {connectPacket(5, "c", "u"), true},
+		"truncated": {connectPacket(4, "c", "")[:5], false},
+		"publish":   {publishPacket("a", 0, 0, nil, "x"), false},
+		"http":      {[]byte("GET / HTTP/1.1\r\n"), false},
+		"empty":     {nil, false},
+	} {
+		if got := IsConnect(tc.head); got != tc.want {
+			t.Errorf("%s: IsConnect = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestParseConnect(t *testing.T) {
+	for _, v := range []byte{3, 4, 5} {
+		pkt := connectPacket(v, "client-1", "alice")
+		h, body := readPacket(t, bufio.NewReader(bytes.NewReader(pkt)))
+		if h.kind() != typeConnect {
+			t.Fatalf("v%d: kind = %d", v, h.kind())
+		}
+		ci, err := parseConnect(body)
+		if err != nil {
+			t.Fatalf("v%d: parseConnect: %v", v, err)
+		}
+		if ci != (connectInfo{version: v, clientID: "client-1", username: "alice"}) {
+			t.Fatalf("v%d: connectInfo = %+v", v, ci)
+		}
+	}
+}
+
+func TestParsePublish(t *testing.T) {
+	// MQTT 5:用户属性 + 主题别名 3。
+	props := append([]byte{0x26}, append(str("k"), str("v")...)...)
+	props = append(props, 0x23, 0x00, 0x03)
+	pkt := publishPacket("sensors/t", 1, 42, props, "21.5")
+	h, body := readPacket(t, bufio.NewReader(bytes.NewReader(pkt)))
+	p, err := parsePublish(h.first, body, protocolV5)
+	if err != nil {
+		t.Fatalf("parsePublish: %v", err)
+	}
+	if p.topic != "sensors/t" || p.qos != 1 || p.packetID != 42 || p.alias != 3 || string(p.payload) != "21.5" {
+		t.Fatalf("publish = %+v", p)
+	}
+	// 改写载荷后重新编码,可变头原样保留。
+	out := encodePacket(h.first, append(slices.Clone(p.prefix), "99"...))
+	h2, body2 := readPacket(t, bufio.NewReader(bytes.NewReader(out)))
+	if p2, err := parsePublish(h2.first, body2, protocolV5); err != nil || p2.topic != "sensors/t" || string(p2.payload) != "99" {
+		t.Fatalf("re-encoded publish = %+v, %v", p2, err)
+	}
+
+	// 别名:带主题的登记,空主题的按别名查回。
+	d := &direction{}
+	d.resolveTopic(p)
+	if got := d.resolveTopic(publish{alias: 3}); got != "sensors/t" {
+		t.Fatalf("alias resolved to %q", got)
+	}
+}
+
+func TestParseTopics(t *testing.T) {
+	body := append([]byte{0x00, 0x05}, str("a/+")...)
+	body = append(body, 0x01)
+	body = append(body, str("b/#")...)
+	body = append(body, 0x00)
+	id, topics, err := parseTopics(body, 4, true)
+	if err != nil || id != 5 || !slices.Equal(topics, []string{"a/+", "b/#"}) {
+		t.Fatalf("parseTopics = %d %v %v", id, topics, err)
+	}
+}
+
+func TestRemainingLengthRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152, maxRemainingLength} {
+		raw := appendRemainingLength([]byte{typePingReq << 4}, n)
+		h, err := readFixedHeader(bufio.NewReader(io.MultiReader(bytes.NewReader(raw), strings.NewReader(""))))
+		if err != nil || h.length != n || !bytes.Equal(h.raw, raw) {
+			t.Fatalf("n=%d: header = %+v, %v", n, h, err)
+		}
+	}
+	if _, err := readFixedHeader(bufio.NewReader(bytes.NewReader([]byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x01}))); err != errMalformed {
+		t.Fatalf("5-byte remaining length: err = %v", err)
+	}
+}
+
+// ---- 中继 ----
+
+type testServer struct{}
+
+func (*testServer) GetConfig() types.Config              { return nil }
+func (*testServer) LogInfo(string, ...interface{})       {}
+func (*testServer) LogError(string, ...interface{})      {}
+func (*testServer) LogDebug(string, ...interface{})      {}
+func (*testServer) FormatDataPreview(data []byte) string { return string(data) }
+
+// dstConn 为连接附上原始目标,模拟透明模式下 accept 到的连接。
+type dstConn struct {
+	*net.TCPConn
+	dst string
+}
+
+func (c *dstConn) OriginalDestination() string { return c.dst }
+
+type recordingSink struct {
+	mu    sync.Mutex
+	snaps []*flow.MQTTSession
+}
+
+func (s *recordingSink) RecordMQTTSession(ms *flow.MQTTSession) {
+	s.mu.Lock()
+	s.snaps = append(s.snaps, ms)
+	s.mu.Unlock()
+}
+
+func (s *recordingSink) last() *flow.MQTTSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.snaps) == 0 {
+		return nil
+	}
+	return s.snaps[len(s.snaps)-1]
+}
+
+// publishHook 把主题 up 的载荷转大写,丢弃主题 drop 的报文。
+type publishHook struct{ urls []string }
+
+func (*publishHook) Name() string      { return "t" }
+func (*publishHook) Priority() int     { return 0 }
+func (*publishHook) Enabled() bool     { return true }
+func (*publishHook) Match(string) bool { return true }
+func (h *publishHook) OnMQTTMessage(_ context.Context, m *flow.MQTTMessage) flow.Decision {
+	h.urls = append(h.urls, m.URL)
+	switch m.Topic {
+	case "up":
+		m.Payload = bytes.ToUpper(m.Payload)
+	case "drop":
+		return flow.AbortDecision(0, "drop")
+	}
+	return flow.ContinueDecision()
+}
+
+// tcpPair 返回一对已连通的环回 TCP 连接(客户端侧, 代理侧)。
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(); server.Close() })
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+// startBroker 启动一个最小 broker:CONNECT 回 CONNACK,SUBSCRIBE 回 SUBACK 并推送一条
+// news 消息,其余报文原样收下;连接结束后把收到的报文(类型:主题:载荷)发到返回的通道。
+func startBroker(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan []string, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var seen []string
+		defer func() { got <- seen }()
+		r := bufio.NewReader(c)
+		for {
+			h, err := readFixedHeader(r)
+			if err != nil {
+				return
+			}
+			body := make([]byte, h.length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			entry := typeName(h.kind())
+			switch h.kind() {
+			case typeConnect:
+				_, _ = c.Write([]byte{typeConnAck << 4, 2, 0, 0})
+			case typeSubscribe:
+				_, _ = c.Write([]byte{typeSubAck << 4, 3, body[0], body[1], 0})
+				_, _ = c.Write(publishPacket("news", 0, 0, nil, "hello"))
+			case typePublish:
+				p, _ := parsePublish(h.first, body, 4)
+				entry = fmt.Sprintf("%s:%s:%s", entry, p.topic, p.payload)
+			}
+			seen = append(seen, entry)
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestRelayParsesAndRewritesPublish(t *testing.T) {
+	sink := &recordingSink{}
+	SetSessionSink(sink)
+	hook := &publishHook{}
+	pl := pipeline.New(nil, nil)
+	pl.Register(hook)
+	SetPipeline(pl)
+	t.Cleanup(func() { SetSessionSink(nil); SetPipeline(nil) })
+
+	broker, brokerSeen := startBroker(t)
+	client, proxySide := tcpPair(t)
+	conn := types.NewConnection(&dstConn{TCPConn: proxySide, dst: broker}, &testServer{})
+	done := make(chan error, 1)
+	go func() { done <- New(conn).Process() }()
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	cr := bufio.NewReader(client)
+	write := func(b []byte) {
+		if _, err := client.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(connectPacket(4, "c1", "alice"))
+	if h, _ := readPacket(t, cr); h.kind() != typeConnAck {
+		t.Fatalf("expected CONNACK, got %s", typeName(h.kind()))
+	}
+	sub := append([]byte{0x00, 0x01}, str("news")...)
+	write(encodePacket(typeSubscribe<<4|0x02, append(sub, 0x00)))
+	if h, _ := readPacket(t, cr); h.kind() != typeSubAck {
+		t.Fatalf("expected SUBACK, got %s", typeName(h.kind()))
+	}
+	h, body := readPacket(t, cr)
+	if p, _ := parsePublish(h.first, body, 4); h.kind() != typePublish || p.topic != "news" || string(p.payload) != "hello" {
+		t.Fatalf("expected news PUBLISH, got %s %+v", typeName(h.kind()), p)
+	}
+
+	write(publishPacket("up", 0, 0, nil, "hi"))
+	// 被丢弃的 QoS 1 报文由代理代答 PUBACK。
+	write(publishPacket("drop", 1, 7, nil, "secret"))
+	h, body = readPacket(t, cr)
+	if h.kind() != typePubAck || !bytes.Equal(body, []byte{0, 7}) {
+		t.Fatalf("expected PUBACK for dropped publish, got %s %x", typeName(h.kind()), body)
+	}
+	write([]byte{typeDisconnect << 4, 0})
+	_ = client.CloseWrite()
+
+	select {
+	case seen := <-brokerSeen:
+		want := []string{"CONNECT", "SUBSCRIBE", "PUBLISH:up:HI", "DISCONNECT"}
+		if !slices.Equal(seen, want) {
+			t.Fatalf("broker saw %v, want %v", seen, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("broker did not finish")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Process returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not finish")
+	}
+
+	s := sink.last()
+	if s == nil || s.Status != "closed" || s.Broker != broker || s.ClientID != "c1" || s.Username != "alice" || s.ProtocolVersion != 4 {
+		t.Fatalf("final session = %+v", s)
+	}
+	var kinds []string
+	for _, m := range s.Messages {
+		kinds = append(kinds, m.Type)
+		switch {
+		case m.Type == flow.MQTTSubscribe && !slices.Equal(m.Topics, []string{"news"}):
+			t.Errorf("SUBSCRIBE topics = %v", m.Topics)
+		case m.Topic == "up" && (!m.Modified || string(m.Payload) != "HI"):
+			t.Errorf("rewritten publish = %+v", m)
+		case m.Topic == "drop" && (!m.Dropped || m.QoS != 1 || m.PacketID != 7):
+			t.Errorf("dropped publish = %+v", m)
+		case m.Topic == "news" && (m.Direction != flow.WSServerToClient || string(m.Payload) != "hello"):
+			t.Errorf("inbound publish = %+v", m)
+		}
+	}
+	want := []string{"CONNECT", "CONNACK", "SUBSCRIBE", "SUBACK", "PUBLISH", "PUBLISH", "PUBLISH", "DISCONNECT"}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("timeline = %v, want %v", kinds, want)
+	}
+	if !slices.Contains(hook.urls, "mqtt://"+broker+"/up") {
+		t.Fatalf("hook urls = %v", hook.urls)
+	}
+}
+
+// 被丢弃的 QoS 2 报文:代理代答 PUBREC,并吞掉随后的 PUBREL、代答 PUBCOMP。
+func TestDropQoS2CompletesHandshake(t *testing.T) {
+	pl := pipeline.New(nil, nil)
+	pl.Register(&publishHook{})
+	SetPipeline(pl)
+	t.Cleanup(func() { SetPipeline(nil) })
+
+	r := &relay{broker: "b:1883"}
+	d := &direction{name: flow.WSClientToServer}
+	pkt := publishPacket("drop", 2, 9, nil, "x")
+	h, body := readPacket(t, bufio.NewReader(bytes.NewReader(pkt)))
+	out, reply := r.inspect(d, h, body)
+	if out != nil || !bytes.Equal(reply, ackPacket(typePubRec, 9)) {
+		t.Fatalf("publish: out=%x reply=%x", out, reply)
+	}
+	rel := []byte{typePubRel<<4 | 0x02, 2, 0, 9}
+	h, body = readPacket(t, bufio.NewReader(bytes.NewReader(rel)))
+	out, reply = r.inspect(d, h, body)
+	if out != nil || !bytes.Equal(reply, ackPacket(typePubComp, 9)) {
+		t.Fatalf("pubrel: out=%x reply=%x", out, reply)
+	}
+	// 之后同 ID 的 PUBREL 照常转发。
+	out, reply = r.inspect(d, h, body)
+	if !bytes.Equal(out, rel) || reply != nil {
+		t.Fatalf("second pubrel: out=%x reply=%x", out, reply)
+	}
+}
+
+func TestProcessRefusesSelfTarget(t *testing.T) {
+	_, proxySide := tcpPair(t)
+	conn := types.NewConnection(&dstConn{TCPConn: proxySide, dst: proxySide.LocalAddr().String()}, &testServer{})
+	if err := New(conn).Process(); err != errSelfTarget {
+		t.Fatalf("Process returned %v, want errSelfTarget", err)
+	}
+}
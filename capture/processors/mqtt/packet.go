@@ -0,0 +1,368 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 控制报文类型(固定头首字节的高 4 位)。
+const (
+	typeConnect     = 1
+	typeConnAck     = 2
+	typePublish     = 3
+	typePubAck      = 4
+	typePubRec      = 5
+	typePubRel      = 6
+	typePubComp     = 7
+	typeSubscribe   = 8
+	typeSubAck      = 9
+	typeUnsubscribe = 10
+	typeUnsubAck    = 11
+	typePingReq     = 12
+	typePingResp    = 13
+	typeDisconnect  = 14
+	typeAuth        = 15
+)
+
+// protocolV5 是 MQTT 5.0 的协议级别;3 = 3.1,4 = 3.1.1。
+const protocolV5 = 5
+
+// maxRemainingLength 是变长编码能表示的最大剩余长度(4 字节)。
+const maxRemainingLength = 268435455
+
+var typeNames = [16]string{
+	typeConnect:     flow.MQTTConnect,
+	typeConnAck:     flow.MQTTConnAck,
+	typePublish:     flow.MQTTPublish,
+	typePubAck:      flow.MQTTPubAck,
+	typePubRec:      flow.MQTTPubRec,
+	typePubRel:      flow.MQTTPubRel,
+	typePubComp:     flow.MQTTPubComp,
+	typeSubscribe:   flow.MQTTSubscribe,
+	typeSubAck:      flow.MQTTSubAck,
+	typeUnsubscribe: flow.MQTTUnsubscribe,
+	typeUnsubAck:    flow.MQTTUnsubAck,
+	typePingReq:     flow.MQTTPingReq,
+	typePingResp:    flow.MQTTPingResp,
+	typeDisconnect:  flow.MQTTDisconnect,
+	typeAuth:        flow.MQTTAuth,
+}
+
+// typeName 返回报文类型名,保留值记为 RESERVED。
+func typeName(t byte) string {
+	if n := typeNames[t&0x0f]; n != "" {
+		return n
+	}
+	return "RESERVED"
+}
+
+var errMalformed = errors.New("mqtt: 报文格式错误")
+
+// fixedHeader 是报文的固定头:首字节与剩余长度,raw 为其原始编码(2~5 字节)。
+type fixedHeader struct {
+	first  byte
+	length int
+	raw    []byte
+}
+
+func (h fixedHeader) kind() byte { return h.first >> 4 }
+
+// readFixedHeader 读取一个固定头。报文边界上的 EOF 原样返回 io.EOF。
+func readFixedHeader(r *bufio.Reader) (fixedHeader, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return fixedHeader{}, err
+	}
+	h := fixedHeader{first: first, raw: []byte{first}}
+	mul := 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return fixedHeader{}, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return fixedHeader{}, unexpectedEOF(err)
+		}
+		h.raw = append(h.raw, b)
+		h.length += int(b&0x7f) * mul
+		if b&0x80 == 0 {
+			return h, nil
+		}
+		mul *= 128
+	}
+}
+
+// appendRemainingLength 按变长编码追加剩余长度。
+func appendRemainingLength(dst []byte, n int) []byte {
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		dst = append(dst, b)
+		if n == 0 {
+			return dst
+		}
+	}
+}
+
+// encodePacket 以首字节与剩余部分拼出完整报文。
+func encodePacket(first byte, body []byte) []byte {
+	out := appendRemainingLength([]byte{first}, len(body))
+	return append(out, body...)
+}
+
+// uvarint 解码 MQTT 5 的变长整数(属性长度、订阅标识等),返回值与消耗的字节数。
+func uvarint(b []byte) (int, int, error) {
+	v, mul := 0, 1
+	for i := 0; i < 4 && i < len(b); i++ {
+		v += int(b[i]&0x7f) * mul
+		if b[i]&0x80 == 0 {
+			return v, i + 1, nil
+		}
+		mul *= 128
+	}
+	return 0, 0, errMalformed
+}
+
+// readString 读取 2 字节长度前缀的 UTF-8 字符串 / 二进制数据,返回其内容与剩余部分。
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errMalformed
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errMalformed
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// splitProperties 切出 MQTT 5 的属性段,返回属性内容与其后的剩余部分。
+func splitProperties(b []byte) ([]byte, []byte, error) {
+	n, used, err := uvarint(b)
+	if err != nil || len(b) < used+n {
+		return nil, nil, errMalformed
+	}
+	return b[used : used+n], b[used+n:], nil
+}
+
+// connectInfo 是从 CONNECT 中取出的会话元信息。
+type connectInfo struct {
+	version  byte
+	clientID string
+	username string
+}
+
+// parseConnect 解析 CONNECT 的可变头与载荷(只取展示所需字段,密码不记录)。
+func parseConnect(body []byte) (connectInfo, error) {
+	var ci connectInfo
+	name, rest, err := readString(body)
+	if err != nil {
+		return ci, err
+	}
+	if name != "MQTT" && name != "MQIsdp" {
+		return ci, fmt.Errorf("mqtt: 未知协议名 %q", name)
+	}
+	if len(rest) < 4 {
+		return ci, errMalformed
+	}
+	ci.version = rest[0]
+	flags := rest[1]
+	rest = rest[4:] // 级别、标志、保活时长
+	if ci.version == protocolV5 {
+		if _, rest, err = splitProperties(rest); err != nil {
+			return ci, err
+		}
+	}
+	if ci.clientID, rest, err = readString(rest); err != nil {
+		return ci, err
+	}
+	if flags&0x04 != 0 { // 遗嘱
+		if ci.version == protocolV5 {
+			if _, rest, err = splitProperties(rest); err != nil {
+				return ci, err
+			}
+		}
+		if _, rest, err = readString(rest); err != nil {
+			return ci, err
+		}
+		if _, rest, err = readString(rest); err != nil {
+			return ci, err
+		}
+	}
+	if flags&0x80 != 0 {
+		if ci.username, _, err = readString(rest); err != nil {
+			return ci, err
+		}
+	}
+	return ci, nil
+}
+
+// publish 是解析后的 PUBLISH 报文。prefix 为载荷之前的可变头原始字节,改写载荷时原样保留。
+type publish struct {
+	topic    string
+	alias    uint16 // MQTT 5 主题别名,0 表示未使用
+	qos      byte
+	retain   bool
+	dup      bool
+	packetID uint16
+	prefix   []byte
+	payload  []byte
+}
+
+// parsePublish 按协议级别解析 PUBLISH。
+func parsePublish(first byte, body []byte, version byte) (publish, error) {
+	p := publish{
+		qos:    (first >> 1) & 0x03,
+		retain: first&0x01 != 0,
+		dup:    first&0x08 != 0,
+	}
+	if p.qos == 3 {
+		return p, errMalformed
+	}
+	topic, rest, err := readString(body)
+	if err != nil {
+		return p, err
+	}
+	p.topic = topic
+	if p.qos > 0 {
+		if len(rest) < 2 {
+			return p, errMalformed
+		}
+		p.packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	if version == protocolV5 {
+		var props []byte
+		if props, rest, err = splitProperties(rest); err != nil {
+			return p, err
+		}
+		if p.alias, err = topicAlias(props); err != nil {
+			return p, err
+		}
+	}
+	p.prefix = body[:len(body)-len(rest)]
+	p.payload = rest
+	return p, nil
+}
+
+// topicAlias 在 PUBLISH 的属性中查找主题别名(0x23)。其余属性按类型跳过。
+func topicAlias(props []byte) (uint16, error) {
+	for len(props) > 0 {
+		id := props[0]
+		props = props[1:]
+		var n int
+		switch id {
+		case 0x01: // 载荷格式
+			n = 1
+		case 0x23: // 主题别名
+			if len(props) < 2 {
+				return 0, errMalformed
+			}
+			return binary.BigEndian.Uint16(props), nil
+		case 0x02: // 消息过期时间
+			n = 4
+		case 0x03, 0x08, 0x09: // 内容类型、响应主题、关联数据
+			if len(props) < 2 {
+				return 0, errMalformed
+			}
+			n = 2 + int(binary.BigEndian.Uint16(props))
+		case 0x26: // 用户属性(字符串对)
+			_, rest, err := readString(props)
+			if err != nil {
+				return 0, err
+			}
+			if _, rest, err = readString(rest); err != nil {
+				return 0, err
+			}
+			n = len(props) - len(rest)
+		case 0x0B: // 订阅标识
+			_, used, err := uvarint(props)
+			if err != nil {
+				return 0, err
+			}
+			n = used
+		default:
+			return 0, errMalformed
+		}
+		if len(props) < n {
+			return 0, errMalformed
+		}
+		props = props[n:]
+	}
+	return 0, nil
+}
+
+// parseTopics 解析 SUBSCRIBE / UNSUBSCRIBE 的报文标识与主题过滤器列表。
+// SUBSCRIBE 的每个过滤器后跟 1 字节订阅选项。
+func parseTopics(body []byte, version byte, withOptions bool) (uint16, []string, error) {
+	if len(body) < 2 {
+		return 0, nil, errMalformed
+	}
+	id := binary.BigEndian.Uint16(body)
+	rest := body[2:]
+	var err error
+	if version == protocolV5 {
+		if _, rest, err = splitProperties(rest); err != nil {
+			return id, nil, err
+		}
+	}
+	var topics []string
+	for len(rest) > 0 {
+		var t string
+		if t, rest, err = readString(rest); err != nil {
+			return id, topics, err
+		}
+		topics = append(topics, t)
+		if withOptions {
+			if len(rest) < 1 {
+				return id, topics, errMalformed
+			}
+			rest = rest[1:]
+		}
+	}
+	return id, topics, nil
+}
+
+// ackPacket 构造只带报文标识的应答(PUBACK / PUBREC / PUBCOMP),MQTT 5 下省略原因码即表示成功。
+func ackPacket(kind byte, id uint16) []byte {
+	return []byte{kind << 4, 2, byte(id >> 8), byte(id)}
+}
+
+// IsConnect 报告 head(连接上已缓冲的前若干字节)是否以一个 MQTT CONNECT 报文开头:
+// 首字节 0x10,随后是合法的剩余长度与协议名 "MQTT"(3.1.1 / 5)或 "MQIsdp"(3.1)。
+func IsConnect(head []byte) bool {
+	if len(head) < 2 || head[0] != typeConnect<<4 {
+		return false
+	}
+	i := 1
+	for ; i < len(head) && i <= 4; i++ {
+		if head[i]&0x80 == 0 {
+			break
+		}
+	}
+	if i > 4 || i >= len(head) {
+		return false
+	}
+	name, _, err := readString(head[i+1:])
+	return err == nil && (name == "MQTT" || name == "MQIsdp")
+}
+
+// unexpectedEOF 把报文中途的 EOF 转成 io.ErrUnexpectedEOF。
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package mqtt 实现 MQTT 3.1 / 3.1.1 / 5 的解析中继:把客户端连接逐个报文转发到 broker,
+// 解析 CONNECT / PUBLISH / SUBSCRIBE 等控制报文,记为一条 flow.MQTTSession 报文时间线;
+// PUBLISH 载荷经插件管道(pipeline.MQTTHook),可被改写或丢弃。
+package mqtt
+
+import (
+	"errors"
+
+	"github.com/mintfog/sniffy/capture/processors/internal/relayio"
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/pipeline"
+	"github.com/mintfog/sniffy/internal/procinfo"
+)
+
+// Dialer 拨通 broker host:port。由引擎注入,使 MQTT 中继与直通隧道共用上游代理等出站策略。
+type Dialer = relayio.Dialer
+
+var dialTarget Dialer = relayio.DialDirect
+
+// SetDialer 注入出站拨号函数;传入 nil 时保留现有值。
+func SetDialer(d Dialer) {
+	if d != nil {
+		dialTarget = d
+	}
+}
+
+// activePipeline 为 nil(独立测试 / 未装配管道)时 PUBLISH 原样转发。
+var activePipeline *pipeline.Pipeline
+
+// SetPipeline 注入插件管道。
+func SetPipeline(p *pipeline.Pipeline) { activePipeline = p }
+
+// processResolver 异步解析连接对应的发起进程,补全会话的进程信息(可为 nil)。
+var processResolver *procinfo.Resolver
+
+// SetProcessResolver 注入进程解析器(装配层调用)。
+func SetProcessResolver(r *procinfo.Resolver) { processResolver = r }
+
+// errSelfTarget 表示原始目标就是监听端自身:中继过去只会无限自连。
+var errSelfTarget = errors.New("mqtt: 原始目标指向代理自身")
+
+// Processor 处理代理端口上探测到 CONNECT 的连接(透明代理截获的 MQTT 流量)。
+type Processor struct {
+	conn types.Connection
+}
+
+// New 创建 MQTT 处理器。
+func New(conn types.Connection) types.ProtocolProcessor {
+	return &Processor{conn: conn}
+}
+
+// GetProtocolName 返回协议名称。
+func (p *Processor) GetProtocolName() string { return "MQTT" }
+
+// Process 把连接中继到它的原始目标。常规代理端口上的连接没有原始目标,只能关闭。
+func (p *Processor) Process() error {
+	server := p.conn.GetServer()
+	conn := p.conn.GetConn()
+	target := types.OriginalDestination(conn)
+	if target == "" {
+		server.LogInfo("MQTT 连接没有可中继的原始目标，关闭")
+		return nil
+	}
+	if conn.LocalAddr() != nil && target == conn.LocalAddr().String() {
+		server.LogError("拒绝中继 %s: %v", target, errSelfTarget)
+		return errSelfTarget
+	}
+	return Dial(p.conn, target)
+}
+
+// Dial 拨通 broker 后调用 Relay;拨号失败也记一条 error 状态的会话。
+func Dial(conn types.Connection, broker string) error {
+	origin, err := dialTarget(broker)
+	if err != nil {
+		conn.GetServer().LogError("MQTT 中继连接 broker 失败 %s: %v", broker, err)
+		if r := newSessionRecorder(conn, broker); r != nil {
+			r.close(err)
+		}
+		return err
+	}
+	conn.GetServer().LogDebug("MQTT 中继：%s -> %s", conn.GetConn().RemoteAddr(), broker)
+	return Relay(conn, broker, origin)
+}
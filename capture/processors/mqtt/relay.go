@@ -0,0 +1,244 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/processors/internal/relayio"
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// maxInspectSize 是缓冲解析的报文上限:剩余长度超过它的报文按字节直通,只记类型与大小,
+// 不经插件,避免为单个报文占用上百 MB 内存。
+const maxInspectSize = 16 << 20
+
+// peer 是中继的一端。转发来的报文与代理代答的应答可能从两个方向的 pump 并发写入,故以 mu 串行化。
+type peer struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (p *peer) write(b []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.conn.Write(b)
+	return err
+}
+
+// stream 写入固定头后从 src 直通 n 字节剩余部分。
+func (p *peer) stream(head []byte, src io.Reader, n int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.conn.Write(head); err != nil {
+		return err
+	}
+	_, err := io.CopyN(p.conn, src, int64(n))
+	return unexpectedEOF(err)
+}
+
+// direction 是单方向的解析状态,只由该方向的 pump 访问。
+type direction struct {
+	name     string // flow.WSClientToServer / flow.WSServerToClient
+	src      *bufio.Reader
+	from, to *peer
+	aliases  map[uint16]string // MQTT 5 主题别名(按发送方各自维护)
+	// swallow 记录被插件丢弃的 QoS 2 报文:代理已代答 PUBREC,发送方随后的 PUBREL
+	// 也不再转发,由代理代答 PUBCOMP,收发双方的报文标识都不会悬空。
+	swallow map[uint16]bool
+}
+
+// relay 是一条 MQTT 连接的中继状态。
+type relay struct {
+	broker  string
+	rec     *sessionRecorder
+	version atomic.Uint32 // CONNECT 中的协议级别,决定 MQTT 5 属性段的解析
+}
+
+// Relay 在客户端与已拨通的 broker 之间逐个报文中继,解析 CONNECT / PUBLISH / SUBSCRIBE 等
+// 控制报文并记入 MQTT 会话。PUBLISH 载荷(双向)经插件管道,可被改写或丢弃;丢弃 QoS 1/2
+// 报文时代理代发方完成应答,避免其重传。返回时两条连接都已关闭。
+func Relay(conn types.Connection, broker string, origin net.Conn) error {
+	client := conn.GetConn()
+	// 中继可为长连接,清除协商/嗅探阶段可能残留的读写超时。
+	_ = client.SetDeadline(time.Time{})
+	_ = origin.SetDeadline(time.Time{})
+
+	r := &relay{broker: broker, rec: newSessionRecorder(conn, broker)}
+	cp, op := &peer{conn: client}, &peer{conn: origin}
+
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			_ = origin.Close()
+			_ = client.Close()
+		})
+	}
+
+	errc := make(chan error, 2)
+	// 客户端 → broker:从 reader 读,先排空 bufio 缓冲(协议探测时已 Peek 的字节)再读裸连接。
+	go func() {
+		errc <- r.pump(&direction{name: flow.WSClientToServer, src: conn.GetReader(), from: cp, to: op}, closeBoth)
+	}()
+	go func() {
+		errc <- r.pump(&direction{name: flow.WSServerToClient, src: bufio.NewReader(origin), from: op, to: cp}, closeBoth)
+	}()
+
+	first := <-errc
+	second := <-errc
+	closeBoth()
+
+	err := first
+	if err == nil {
+		err = second
+	}
+	r.rec.close(err)
+	return nil
+}
+
+// pump 逐个读取 d.src 上的报文,检视后转发到 d.to。src 在报文边界正常结束时尝试半关闭
+// d.to 的写端,做不到就整条关闭;读写出错也整条关闭,唤醒另一方向阻塞中的读取。
+func (r *relay) pump(d *direction, closeBoth func()) error {
+	for {
+		h, err := readFixedHeader(d.src)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if !relayio.CloseWrite(d.to.conn) {
+					closeBoth()
+				}
+				return nil
+			}
+			closeBoth()
+			return relayio.Err(err)
+		}
+		if h.length > maxInspectSize {
+			r.rec.add(flow.MQTTMessage{Direction: d.name, Type: typeName(h.kind()), Size: len(h.raw) + h.length})
+			if err := d.to.stream(h.raw, d.src, h.length); err != nil {
+				closeBoth()
+				return relayio.Err(err)
+			}
+			continue
+		}
+		body := make([]byte, h.length)
+		if _, err := io.ReadFull(d.src, body); err != nil {
+			closeBoth()
+			return relayio.Err(unexpectedEOF(err))
+		}
+		out, reply := r.inspect(d, h, body)
+		if reply != nil {
+			if err := d.from.write(reply); err != nil {
+				closeBoth()
+				return relayio.Err(err)
+			}
+		}
+		if out != nil {
+			if err := d.to.write(out); err != nil {
+				closeBoth()
+				return relayio.Err(err)
+			}
+		}
+	}
+}
+
+// inspect 解析并记录一个报文,返回要转发的字节(nil 表示不转发)与要回给发送方的应答。
+// 解析失败的报文照常原样转发,只记类型与大小:格式问题留给两端自行处理。
+func (r *relay) inspect(d *direction, h fixedHeader, body []byte) (out, reply []byte) {
+	out = append(append(make([]byte, 0, len(h.raw)+len(body)), h.raw...), body...)
+	m := flow.MQTTMessage{Direction: d.name, Type: typeName(h.kind()), Size: len(out)}
+	version := byte(r.version.Load())
+
+	switch h.kind() {
+	case typeConnect:
+		if ci, err := parseConnect(body); err == nil {
+			r.version.Store(uint32(ci.version))
+			r.rec.connect(ci)
+		}
+	case typePublish:
+		p, err := parsePublish(h.first, body, version)
+		if err != nil {
+			break
+		}
+		m.Topic = d.resolveTopic(p)
+		m.QoS, m.Retain, m.Dup, m.PacketID = p.qos, p.retain, p.dup, p.packetID
+		m.URL = "mqtt://" + r.broker + "/" + m.Topic
+		m.Payload = p.payload
+		if activePipeline == nil {
+			break
+		}
+		m.Payload = append([]byte(nil), p.payload...) // 插件可能就地改写,不能动到 body
+		m.SessionID = r.rec.sessionID()
+		if activePipeline.OnMQTTMessage(context.Background(), &m).Kind == flow.Abort {
+			m.Dropped = true
+			r.rec.add(m)
+			return nil, d.dropReply(p)
+		}
+		if !bytes.Equal(m.Payload, p.payload) {
+			m.Modified = true
+			out = encodePacket(h.first, append(append([]byte(nil), p.prefix...), m.Payload...))
+			m.Size = len(out)
+		}
+	case typePubRel:
+		if len(body) < 2 {
+			break
+		}
+		m.PacketID = binary.BigEndian.Uint16(body)
+		if d.swallow[m.PacketID] {
+			delete(d.swallow, m.PacketID)
+			m.Dropped = true
+			r.rec.add(m)
+			return nil, ackPacket(typePubComp, m.PacketID)
+		}
+	case typeSubscribe, typeUnsubscribe:
+		m.PacketID, m.Topics, _ = parseTopics(body, version, h.kind() == typeSubscribe)
+	case typePubAck, typePubRec, typePubComp, typeSubAck, typeUnsubAck:
+		if len(body) >= 2 {
+			m.PacketID = binary.BigEndian.Uint16(body)
+		}
+	}
+	r.rec.add(m)
+	return out, nil
+}
+
+// resolveTopic 按 MQTT 5 主题别名补全主题:带主题的 PUBLISH 登记别名,空主题的按别名查回。
+func (d *direction) resolveTopic(p publish) string {
+	if p.alias == 0 {
+		return p.topic
+	}
+	if p.topic != "" {
+		if d.aliases == nil {
+			d.aliases = make(map[uint16]string)
+		}
+		d.aliases[p.alias] = p.topic
+		return p.topic
+	}
+	return d.aliases[p.alias]
+}
+
+// dropReply 为被丢弃的 PUBLISH 构造给发送方的应答:QoS 1 回 PUBACK,QoS 2 回 PUBREC
+// 并等待其 PUBREL;QoS 0 无需应答。
+func (d *direction) dropReply(p publish) []byte {
+	switch p.qos {
+	case 1:
+		return ackPacket(typePubAck, p.packetID)
+	case 2:
+		if d.swallow == nil {
+			d.swallow = make(map[uint16]bool)
+		}
+		d.swallow[p.packetID] = true
+		return ackPacket(typePubRec, p.packetID)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package mqtt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// SessionSink 由 service 实现,处理器经此记录/更新一条 MQTT 会话(消费者定义接口,避免反向依赖)。
+type SessionSink interface {
+	RecordMQTTSession(s *flow.MQTTSession)
+}
+
+var sessionSink SessionSink
+
+// SetSessionSink 注入 MQTT 会话接收器(装配层调用)。
+func SetSessionSink(s SessionSink) { sessionSink = s }
+
+const (
+	// maxSessionMessages 是单条会话保留的最近报文数,更早的报文只计入统计。
+	maxSessionMessages = 500
+	// maxPayloadData 是单条报文保留的载荷前缀,完整长度仍记在 Size。
+	maxPayloadData = 16 * 1024
+	// pushInterval 限制快照推送频率:遥测类客户端每秒可发成百上千条 PUBLISH,逐条推送
+	// 开销与事件量都不可接受。被节流的变化由延迟推送补上,关闭时总会推送最终状态。
+	pushInterval = 100 * time.Millisecond
+)
+
+// sessionRecorder 维护一条 MQTTSession,按节流向 sessionSink 推送快照。
+// 两个方向的 pump 共享同一 recorder,故以 mu 串行化。
+type sessionRecorder struct {
+	mu       sync.Mutex
+	session  *flow.MQTTSession
+	seq      int
+	lastPush time.Time
+	pending  *time.Timer
+	closed   bool
+}
+
+// newSessionRecorder 登记一条 open 状态的 MQTT 会话。sessionSink 未注入时返回 nil,
+// 此后所有方法都是空操作。
+func newSessionRecorder(conn types.Connection, broker string) *sessionRecorder {
+	if sessionSink == nil {
+		return nil
+	}
+	r := &sessionRecorder{session: &flow.MQTTSession{
+		ID:        flow.NewID(),
+		Broker:    broker,
+		Status:    "open",
+		StartTime: time.Now(),
+		Messages:  make([]flow.MQTTMessage, 0, 16),
+	}}
+	client := conn.GetConn()
+	if client != nil {
+		r.session.Listener = types.ListenerID(client)
+	}
+	if client != nil && client.RemoteAddr() != nil {
+		r.session.ClientAddr = client.RemoteAddr().String()
+	}
+	r.mu.Lock()
+	r.pushLocked()
+	r.mu.Unlock()
+
+	if processResolver != nil && client != nil && client.RemoteAddr() != nil {
+		go func() {
+			if pi := processResolver.Resolve(client.RemoteAddr(), client.LocalAddr()); pi != nil {
+				r.setProcess(pi)
+			}
+		}()
+	}
+	return r
+}
+
+// sessionID 返回会话 ID(未记录时为空),供插件看到的报文关联会话。
+func (r *sessionRecorder) sessionID() string {
+	if r == nil {
+		return ""
+	}
+	return r.session.ID
+}
+
+// connect 记下 CONNECT 中的客户端标识、用户名与协议级别。
+func (r *sessionRecorder) connect(ci connectInfo) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.session.ClientID = ci.clientID
+	r.session.Username = ci.username
+	r.session.ProtocolVersion = ci.version
+	r.schedulePushLocked()
+}
+
+// add 追加一条报文,补上 ID、序号与时间戳;载荷只保留前缀。
+func (r *sessionRecorder) add(m flow.MQTTMessage) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.session
+	if len(m.Payload) > maxPayloadData {
+		m.Payload = m.Payload[:maxPayloadData]
+		m.Truncated = true
+	}
+	m.Payload = append([]byte(nil), m.Payload...)
+	m.ID = flow.NewID()
+	m.SessionID = s.ID
+	m.Timestamp = time.Now()
+	m.Seq = r.seq
+	r.seq++
+	s.Messages = append(s.Messages, m)
+	s.MessageCount++
+	if len(s.Messages) > maxSessionMessages {
+		s.Messages = append(s.Messages[:0], s.Messages[len(s.Messages)-maxSessionMessages:]...)
+	}
+	r.schedulePushLocked()
+}
+
+// setProcess 补上发起进程信息并推送。
+func (r *sessionRecorder) setProcess(pi *flow.ProcessInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.session.Process = pi
+	if !r.closed {
+		r.schedulePushLocked()
+	} else {
+		r.pushLocked()
+	}
+}
+
+// close 标记会话结束并推送最终状态;err 非 nil 时记为 error。
+func (r *sessionRecorder) close(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.pending != nil {
+		r.pending.Stop()
+		r.pending = nil
+	}
+	now := time.Now()
+	r.session.EndTime = &now
+	r.session.Status = "closed"
+	if err != nil {
+		r.session.Status = "error"
+		r.session.Error = err.Error()
+	}
+	r.pushLocked()
+}
+
+// schedulePushLocked 距上次推送已满 pushInterval 时立即推送,否则安排一次延迟推送。
+func (r *sessionRecorder) schedulePushLocked() {
+	wait := pushInterval - time.Since(r.lastPush)
+	if wait <= 0 {
+		r.pushLocked()
+		return
+	}
+	if r.pending != nil {
+		return
+	}
+	r.pending = time.AfterFunc(wait, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.pending = nil
+		if !r.closed {
+			r.pushLocked()
+		}
+	})
+}
+
+// pushLocked 向 sessionSink 推送当前会话的快照。报文切片独立复制,之后的追加/裁剪不会
+// 影响已推送的快照;持锁推送保证同一会话的快照按时间先后到达。
+func (r *sessionRecorder) pushLocked() {
+	r.lastPush = time.Now()
+	s := *r.session
+	s.Messages = make([]flow.MQTTMessage, len(r.session.Messages))
+	copy(s.Messages, r.session.Messages) // Payload 写入后不再改动,共享底层数组即可
+	if r.session.EndTime != nil {
+		t := *r.session.EndTime
+		s.EndTime = &t
+	}
+	sessionSink.RecordMQTTSession(&s)
+}
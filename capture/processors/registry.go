@@ -7,8 +7,11 @@ package processors
 
 import (
 	"bufio"
+	"net"
 
+	"github.com/mintfog/sniffy/capture/processors/dns"
 	"github.com/mintfog/sniffy/capture/processors/http"
+	"github.com/mintfog/sniffy/capture/processors/mqtt"
 	"github.com/mintfog/sniffy/capture/processors/socks5"
 	"github.com/mintfog/sniffy/capture/processors/tcp"
 	"github.com/mintfog/sniffy/capture/types"
@@ -36,6 +39,8 @@ func (r *Registry) RegisterDefaults() {
 	r.Register("HTTP", http.New)
 	r.Register("SOCKS5", socks5.New)
 	r.Register("TCP", tcp.New)
+	r.Register("DNS", dns.New)
+	r.Register("MQTT", mqtt.New)
 }
 
 // Register 注册处理器工厂
@@ -57,8 +62,9 @@ func (r *Registry) GetProcessor(protocolName string, conn types.Connection) type
 	return tcp.New(conn)
 }
 
-// DetectProtocol 根据连接数据检测协议类型
-func (r *Registry) DetectProtocol(reader *bufio.Reader, server types.Server) string {
+// DetectProtocol 根据连接数据检测协议类型。conn 为 reader 底层的连接,用于给需要读满
+// 整条记录的探测设置读超时;为 nil 时不设超时。
+func (r *Registry) DetectProtocol(conn net.Conn, reader *bufio.Reader, server types.Server) string {
 	// 协议检测：先读取第一个字节判断基础协议类型
 	firstByte, err := reader.Peek(1)
 	if err != nil {
@@ -77,7 +83,7 @@ func (r *Registry) DetectProtocol(reader *bufio.Reader, server types.Server) str
 	// TLS/SSL协议检测
 	case TLSHandshake, TLSAlert, TLSAppData:
 		// 进行更详细的TLS检测
-		return r.detectTLSProtocol(reader, server)
+		return r.detectTLSProtocol(conn, reader, server)
 	// SSH协议检测
 	case SSHVersion:
 		return r.detectSSHProtocol(reader, server)
@@ -86,7 +92,7 @@ func (r *Registry) DetectProtocol(reader *bufio.Reader, server types.Server) str
 		return r.detectNumericProtocol(reader, server)
 	// MQTT协议检测
 	case MQTTConnect:
-		return "TCP"
+		return r.detectMQTTProtocol(reader, server)
 	// 其他字节值需要更深入检测
 	default:
 		// RDP协议检测
@@ -94,15 +100,43 @@ func (r *Registry) DetectProtocol(reader *bufio.Reader, server types.Server) str
 			return "TCP"
 		}
 		// 如果前面都没匹配，进行更高级的协议检测
-		return r.detectAdvancedProtocol(reader, server)
+		return r.detectAdvancedProtocol(conn, reader, server)
 	}
 }
 
-// detectTLSProtocol 检测TLS协议
-func (r *Registry) detectTLSProtocol(reader *bufio.Reader, server types.Server) string {
+// detectTLSProtocol 检测TLS协议:客户端直接对代理端口发起的 TLS 握手若带有 SNI,
+// 交给 HTTP 处理器按 SNI 解密或直通;取不到目标的(无 SNI、告警/应用数据记录)回退到TCP处理器。
+// 读取 ClientHello 期间按 TLSHandshakeTimeout 设读超时,结束后清除,避免只发半条记录的客户端
+// 让探测无限挂起。
+func (r *Registry) detectTLSProtocol(conn net.Conn, reader *bufio.Reader, server types.Server) string {
 	// TLS/SSL协议检测
 	server.LogInfo("检测到TLS/SSL协议")
-	return "TCP" // TLS流量回退到TCP处理器
+	first, err := reader.Peek(1)
+	if err != nil || first[0] != TLSHandshake {
+		return "TCP"
+	}
+	hello, err := http.PeekClientHello(conn, reader)
+	if err != nil {
+		server.LogDebug("解析 TLS ClientHello 失败: %v", err)
+		return "TCP"
+	}
+	if hello.ServerName == "" {
+		server.LogDebug("TLS ClientHello 未携带 SNI,无法确定目标")
+		return "TCP"
+	}
+	server.LogDebug("TLS ClientHello: SNI=%s ALPN=%v", hello.ServerName, hello.ALPN)
+	return "HTTP"
+}
+
+// detectMQTTProtocol 检测MQTT协议:首字节之后须是合法的 CONNECT(协议名 MQTT / MQIsdp)。
+// 只看已缓冲的字节,不为凑够长度阻塞。
+func (r *Registry) detectMQTTProtocol(reader *bufio.Reader, server types.Server) string {
+	head, _ := reader.Peek(reader.Buffered())
+	if !mqtt.IsConnect(head) {
+		return "TCP"
+	}
+	server.LogInfo("检测到MQTT协议")
+	return "MQTT"
 }
 
 // detectSSHProtocol 检测SSH协议
@@ -151,17 +185,28 @@ func (r *Registry) detectNumericProtocol(reader *bufio.Reader, server types.Serv
 }
 
 // detectAdvancedProtocol 高级协议检测
-func (r *Registry) detectAdvancedProtocol(reader *bufio.Reader, server types.Server) string {
+func (r *Registry) detectAdvancedProtocol(conn net.Conn, reader *bufio.Reader, server types.Server) string {
 	// 读取更多字节进行高级协议检测
 	header, err := reader.Peek(16)
 	if err != nil {
 		return "TCP"
 	}
 
-	// DNS协议检测（通常在UDP上，但也可能在TCP上）
-	if len(header) >= 12 {
-		// DNS查询头部检测
-		server.LogInfo("进行高级协议检测")
+	server.LogDebug("进行高级协议检测")
+
+	// DNS协议检测（通常在UDP上，但也可能在TCP上）:TCP 上的查询以 2 字节长度前缀开头。
+	// 只在内置 DNS 开启时应答;DNS 查询无从出示代理凭据,要求认证的监听端不应答。
+	if dns.LooksLikeTCPQuery(header) {
+		server.LogDebug("检测到 DNS over TCP 查询")
+		if !dns.ProxyPortEnabled() {
+			server.LogDebug("内置 DNS 未开启,不应答")
+			return "TCP"
+		}
+		if http.ProxyAuthRequiredFor(types.ListenerID(conn)) {
+			server.LogDebug("监听端要求代理认证,不应答 DNS over TCP")
+			return "TCP"
+		}
+		return "DNS"
 	}
 
 	// 默认返回TCP
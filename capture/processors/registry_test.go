@@ -5,11 +5,19 @@ package processors
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"net"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/mintfog/sniffy/capture/processors/dns"
+	"github.com/mintfog/sniffy/capture/processors/http"
+	"github.com/mintfog/sniffy/capture/processors/mqtt"
 	"github.com/mintfog/sniffy/capture/processors/tcp"
 	"github.com/mintfog/sniffy/capture/types"
 )
@@ -93,7 +101,7 @@ func TestRegistryDetectProtocol(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server := &testServer{}
-			got := r.DetectProtocol(bufio.NewReader(strings.NewReader(tt.data)), server)
+			got := r.DetectProtocol(nil, bufio.NewReader(strings.NewReader(tt.data)), server)
 			want := "TCP"
 			if tt.name == "http" {
 				want = "HTTP"
@@ -109,3 +117,194 @@ func TestRegistryDetectProtocol(t *testing.T) {
 		})
 	}
 }
+
+// clientHelloRecord 抓取 crypto/tls 客户端发出的首个 TLS 记录(ClientHello)。
+func clientHelloRecord(t *testing.T, serverName string) []byte {
+	t.Helper()
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}).Handshake()
+		client.Close()
+	}()
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(server, head); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(head[3])<<8|int(head[4]))
+	if _, err := io.ReadFull(server, body); err != nil {
+		t.Fatal(err)
+	}
+	return append(head, body...)
+}
+
+// dnsQuery 是一条 DNS over TCP 查询:长度前缀 29 + 标准查询头(ID=0x1234, RD, QDCOUNT=1)
+// + example.com A IN。
+func dnsQuery() []byte {
+	query := []byte{0x00, 0x1d, 0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	return append(query, "\x07example\x03com\x00\x00\x01\x00\x01"...)
+}
+
+// enableProxyPortDNS 在测试期间开启代理端口上的 DNS 应答。
+func enableProxyPortDNS(t *testing.T) {
+	t.Helper()
+	dns.SetProxyPortEnabled(true)
+	t.Cleanup(func() { dns.SetProxyPortEnabled(false) })
+}
+
+func TestRegistryDetectDNSOverTCP(t *testing.T) {
+	r := NewRegistry()
+	enableProxyPortDNS(t)
+	query := dnsQuery()
+	if got := r.DetectProtocol(nil, bufio.NewReader(bytes.NewReader(query)), &testServer{}); got != "DNS" {
+		t.Fatalf("DetectProtocol() = %q, want DNS", got)
+	}
+	if _, ok := r.GetProcessor("DNS", nil).(*dns.Processor); !ok {
+		t.Fatal("DNS processor is not registered")
+	}
+
+	response := slices.Clone(query)
+	response[4] |= 0x80 // QR=1
+	if got := r.DetectProtocol(nil, bufio.NewReader(bytes.NewReader(response)), &testServer{}); got != "TCP" {
+		t.Fatalf("DNS response detected as %q", got)
+	}
+}
+
+func TestRegistryDetectMQTTConnect(t *testing.T) {
+	r := NewRegistry()
+	for name, tc := range map[string]struct {
+		data string
+		want string
+	}{
+		"v311":     {"\x10\x0c\x00\x04MQTT\x04\x02\x00\x3c\x00\x00", "MQTT"},
+		"v31":      {"\x10\x0e\x00\x06MQIsdp\x03\x02\x00\x3c\x00\x00", "MQTT"},
+		"bad name": {"\x10\x0c\x00\x04HTTP\x04\x02\x00\x3c\x00\x00", "TCP"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := r.DetectProtocol(nil, bufio.NewReader(strings.NewReader(tc.data)), &testServer{}); got != tc.want {
+				t.Fatalf("DetectProtocol() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, ok := r.GetProcessor("MQTT", nil).(*mqtt.Processor); !ok {
+		t.Fatal("MQTT processor is not registered")
+	}
+}
+
+func TestRegistryDetectTLSBySNI(t *testing.T) {
+	r := NewRegistry()
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"sni":       {clientHelloRecord(t, "api.example.com"), "HTTP"},
+		"no sni":    {clientHelloRecord(t, ""), "TCP"},
+		"alert":     {[]byte{TLSAlert, 0x03, 0x03, 0x00, 0x02, 0x02, 0x28}, "TCP"},
+		"truncated": {[]byte{TLSHandshake, 0x03, 0x01, 0x00, 0x10, 0x01}, "TCP"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewReader(tc.data))
+			if got := r.DetectProtocol(nil, reader, &testServer{}); got != tc.want {
+				t.Fatalf("DetectProtocol() = %q, want %q", got, tc.want)
+			}
+			// 探测只能 Peek,处理器还要从记录头读起。
+			if reader.Buffered() != len(tc.data) && tc.want == "HTTP" {
+				t.Fatalf("detection consumed bytes: %d buffered", reader.Buffered())
+			}
+		})
+	}
+}
+
+// deadlineConn 记录 SetReadDeadline 的调用。
+type deadlineConn struct {
+	net.Conn
+	deadlines []time.Time
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.deadlines = append(c.deadlines, t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+// TestRegistryDetectTLSSetsReadDeadline 窥探 ClientHello 期间设读超时,结束后清除;
+// 只发半条记录的客户端在超时后回退到 TCP。
+func TestRegistryDetectTLSSetsReadDeadline(t *testing.T) {
+	r := NewRegistry()
+	hello := clientHelloRecord(t, "api.example.com")
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"complete": {hello, "HTTP"},
+		"partial":  {hello[:len(hello)/2], "TCP"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			go func() { _, _ = client.Write(tc.data) }()
+			conn := &deadlineConn{Conn: server}
+			// partial 用例靠读超时结束;把已设置的超时提前,免得等满 TLSHandshakeTimeout。
+			if tc.want == "TCP" {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					_ = server.SetReadDeadline(time.Now())
+				}()
+			}
+			if got := r.DetectProtocol(conn, bufio.NewReader(conn), &testServer{}); got != tc.want {
+				t.Fatalf("DetectProtocol() = %q, want %q", got, tc.want)
+			}
+			if len(conn.deadlines) != 2 || conn.deadlines[0].IsZero() || !conn.deadlines[1].IsZero() {
+				t.Fatalf("read deadlines = %v, want set then cleared", conn.deadlines)
+			}
+		})
+	}
+}
+
+// listenerConn 为连接附上监听端标识(types.ListenerConn)。
+type listenerConn struct {
+	net.Conn
+	id string
+}
+
+func (c *listenerConn) ListenerID() string { return c.id }
+
+// TestRegistryDNSOverTCPGated 内置 DNS 关闭,或监听端要求代理认证时,代理端口不应答 DNS。
+func TestRegistryDNSOverTCPGated(t *testing.T) {
+	r := NewRegistry()
+	detect := func(conn net.Conn) string {
+		return r.DetectProtocol(conn, bufio.NewReader(bytes.NewReader(dnsQuery())), &testServer{})
+	}
+	if got := detect(nil); got != "TCP" {
+		t.Fatalf("DNS 未开启时 = %q, want TCP", got)
+	}
+
+	enableProxyPortDNS(t)
+	t.Cleanup(func() { http.SetListenerPolicy("auth", nil) })
+	http.SetListenerPolicy("auth", &http.ListenerPolicy{ProxyAuth: &http.ProxyAuthSettings{Enabled: true, Username: "u", Password: "p"}})
+	if got := detect(&listenerConn{id: "auth"}); got != "TCP" {
+		t.Fatalf("要求认证的监听端 = %q, want TCP", got)
+	}
+	if got := detect(&listenerConn{id: "open"}); got != "DNS" {
+		t.Fatalf("无需认证的监听端 = %q, want DNS", got)
+	}
+}
+
+// TestDNSProcessorRefusesWhenDisabled 内置 DNS 关闭时处理器不应答,直接结束。
+func TestDNSProcessorRefusesWhenDisabled(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() { _, _ = client.Write(dnsQuery()) }()
+	conn := types.NewConnection(server, &testServer{})
+	defer conn.Close()
+	done := make(chan struct{})
+	go func() {
+		_ = dns.New(conn).Process()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("DNS 未开启时处理器仍在应答")
+	}
+}
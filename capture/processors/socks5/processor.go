@@ -3,14 +3,45 @@
 // Use of this source code is governed by an Apache 2.0
 // license that can be found in the LICENSE file.
 
+// Package socks5 实现 SOCKS5 入口(RFC 1928)及用户名/密码认证(RFC 1929)。
+// 只支持 CONNECT:隧道建好后交给 HTTP 处理器的 ServeTunnel,与 HTTP CONNECT 共用
+// 解密范围、TLS MITM 与 flow 管道。
 package socks5
 
 import (
 	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
 
+	httpproc "github.com/mintfog/sniffy/capture/processors/http"
 	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/socksproto"
 )
 
+// handshakeTimeout 是协商阶段未配置 ReadTimeout 时的兜底期限,避免半开连接占住 goroutine。
+const handshakeTimeout = 30 * time.Second
+
+var (
+	errBadVersion   = errors.New("socks5: 不支持的协议版本")
+	errNoMethod     = errors.New("socks5: 客户端未提供可接受的认证方式")
+	errAuthFailed   = errors.New("socks5: 用户名/密码认证失败")
+	errBadCommand   = errors.New("socks5: 仅支持 CONNECT 命令")
+	errBadAddrType  = errors.New("socks5: 不支持的地址类型")
+	errEmptyAddress = errors.New("socks5: 目标地址为空")
+)
+
+// dialTarget 拨通 CONNECT 目标,测试中可替换。
+var dialTarget = httpproc.DialTunnel
+
+// serveTunnel 接管建好的隧道,测试中可替换。
+var serveTunnel = httpproc.ServeTunnel
+
 // Processor SOCKS5协议处理器
 type Processor struct {
 	conn types.Connection
@@ -40,10 +71,245 @@ func (p *Processor) Process() error {
 	return p.handleSocks5Protocol(server, reader, writer)
 }
 
-// handleSocks5Protocol 处理SOCKS5协议的具体逻辑
+// handleSocks5Protocol 依次完成方法协商、(可选)认证与 CONNECT 请求,随后交出隧道。
 func (p *Processor) handleSocks5Protocol(server types.Server, reader *bufio.Reader, writer *bufio.Writer) error {
-	// SOCKS5协议处理逻辑
-	server.LogInfo("处理SOCKS5协议...")
+	p.armHandshakeDeadline(server)
+
+	if err := p.negotiate(reader, writer); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil // 只探测不发言的客户端:正常收尾
+		}
+		server.LogError("SOCKS5 协商失败: %v", err)
+		return err
+	}
+
+	target, err := p.readRequest(reader, writer)
+	if err != nil {
+		server.LogError("SOCKS5 请求无效: %v", err)
+		return err
+	}
+	server.LogDebug("SOCKS5 CONNECT 目标：%s", target)
+
+	origin, err := dialTarget(target)
+	if err != nil {
+		server.LogError("SOCKS5 连接目标失败 %s: %v", target, err)
+		_ = writeReply(writer, replyCodeFor(err), nil)
+		return err
+	}
+	if err := writeReply(writer, socksproto.RepSucceeded, origin.LocalAddr()); err != nil {
+		_ = origin.Close()
+		server.LogError("发送SOCKS5应答失败: %v", err)
+		return err
+	}
+	return serveTunnel(p.conn, target, origin)
+}
+
+// armHandshakeDeadline 为协商阶段设读写期限;隧道建好后由 ServeTunnel 清除。
+func (p *Processor) armHandshakeDeadline(server types.Server) {
+	conn := p.conn.GetConn()
+	if conn == nil {
+		return
+	}
+	d := handshakeTimeout
+	if cfg := server.GetConfig(); cfg != nil && cfg.GetReadTimeout() > 0 {
+		d = cfg.GetReadTimeout()
+	}
+	_ = conn.SetDeadline(time.Now().Add(d))
+}
+
+// negotiate 完成方法选择。监听端开启认证时只接受 RFC 1929 用户名/密码;未开启时优先
+// 无认证,客户端只提供用户名/密码时也照常接受(凭据此时恒通过)。
+func (p *Processor) negotiate(reader *bufio.Reader, writer *bufio.Writer) error {
+	var head [2]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		return err
+	}
+	if head[0] != socksproto.Version {
+		return fmt.Errorf("%w: 0x%02x", errBadVersion, head[0])
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(reader, methods); err != nil {
+		return err
+	}
 
+	method := selectMethod(methods, httpproc.ProxyAuthRequiredFor(p.listenerID()))
+	if err := writeAndFlush(writer, socksproto.Version, method); err != nil {
+		return err
+	}
+	switch method {
+	case socksproto.MethodNoAcceptable:
+		return errNoMethod
+	case socksproto.MethodUserPass:
+		return p.authenticate(reader, writer)
+	}
 	return nil
 }
+
+// listenerID 返回连接所属的附加监听端标识(主监听端为空),认证设置按监听端取。
+func (p *Processor) listenerID() string {
+	if c := p.conn.GetConn(); c != nil {
+		return types.ListenerID(c)
+	}
+	return ""
+}
+
+// selectMethod 从客户端提供的方法中选出本端使用的认证方式。
+func selectMethod(offered []byte, authRequired bool) byte {
+	hasNoAuth, hasUserPass := false, false
+	for _, m := range offered {
+		switch m {
+		case socksproto.MethodNoAuth:
+			hasNoAuth = true
+		case socksproto.MethodUserPass:
+			hasUserPass = true
+		}
+	}
+	switch {
+	case authRequired && hasUserPass:
+		return socksproto.MethodUserPass
+	case authRequired:
+		return socksproto.MethodNoAcceptable
+	case hasNoAuth:
+		return socksproto.MethodNoAuth
+	case hasUserPass:
+		return socksproto.MethodUserPass
+	default:
+		return socksproto.MethodNoAcceptable
+	}
+}
+
+// authenticate 执行 RFC 1929 子协商,凭据与 HTTP 入口的 Proxy-Authorization 共用。
+func (p *Processor) authenticate(reader *bufio.Reader, writer *bufio.Writer) error {
+	ver, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	if ver != socksproto.AuthVersion {
+		_ = writeAndFlush(writer, socksproto.AuthVersion, socksproto.AuthFailure)
+		return fmt.Errorf("socks5: 不支持的认证子协商版本 0x%02x", ver)
+	}
+	username, err := readLengthPrefixed(reader)
+	if err != nil {
+		return err
+	}
+	password, err := readLengthPrefixed(reader)
+	if err != nil {
+		return err
+	}
+	if !httpproc.CheckProxyCredentialsFor(p.listenerID(), username, password) {
+		// RFC 1929:认证失败后必须关闭连接。
+		_ = writeAndFlush(writer, socksproto.AuthVersion, socksproto.AuthFailure)
+		return errAuthFailed
+	}
+	return writeAndFlush(writer, socksproto.AuthVersion, socksproto.AuthSuccess)
+}
+
+// readRequest 读取 CONNECT 请求并返回 host:port 形式的目标;不支持的命令与地址类型
+// 在返回错误前先回复对应的应答码。
+func (p *Processor) readRequest(reader *bufio.Reader, writer *bufio.Writer) (string, error) {
+	var head [4]byte // VER CMD RSV ATYP
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		return "", err
+	}
+	if head[0] != socksproto.Version {
+		return "", fmt.Errorf("%w: 0x%02x", errBadVersion, head[0])
+	}
+
+	var host string
+	switch head[3] {
+	case socksproto.AtypIPv4:
+		var ip [net.IPv4len]byte
+		if _, err := io.ReadFull(reader, ip[:]); err != nil {
+			return "", err
+		}
+		host = net.IP(ip[:]).String()
+	case socksproto.AtypIPv6:
+		var ip [net.IPv6len]byte
+		if _, err := io.ReadFull(reader, ip[:]); err != nil {
+			return "", err
+		}
+		host = net.IP(ip[:]).String()
+	case socksproto.AtypDomain:
+		name, err := readLengthPrefixed(reader)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			_ = writeReply(writer, socksproto.RepHostUnreachable, nil)
+			return "", errEmptyAddress
+		}
+		host = name
+	default:
+		_ = writeReply(writer, socksproto.RepAddrNotSupported, nil)
+		return "", fmt.Errorf("%w: 0x%02x", errBadAddrType, head[3])
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(reader, port[:]); err != nil {
+		return "", err
+	}
+	// 命令放在读完整个请求后再判,保证应答之前请求字节已全部消费。
+	if head[1] != socksproto.CmdConnect {
+		_ = writeReply(writer, socksproto.RepCommandNotSupported, nil)
+		return "", fmt.Errorf("%w: 0x%02x", errBadCommand, head[1])
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// readLengthPrefixed 读取一个单字节长度前缀的字段(域名、用户名、密码)。
+func readLengthPrefixed(reader *bufio.Reader) (string, error) {
+	n, err := reader.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// writeReply 写出应答:VER REP RSV ATYP BND.ADDR BND.PORT。bound 不是 TCP 地址时
+// 以 0.0.0.0:0 占位。
+func writeReply(writer *bufio.Writer, rep byte, bound net.Addr) error {
+	ip, port := net.IPv4zero.To4(), 0
+	if tcp, ok := bound.(*net.TCPAddr); ok && tcp.IP != nil {
+		ip, port = tcp.IP, tcp.Port
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+	}
+	atyp := byte(socksproto.AtypIPv6)
+	if len(ip) == net.IPv4len {
+		atyp = socksproto.AtypIPv4
+	}
+	buf := make([]byte, 0, 6+len(ip))
+	buf = append(buf, socksproto.Version, rep, 0x00, atyp)
+	buf = append(buf, ip...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(port))
+	return writeAndFlush(writer, buf...)
+}
+
+func writeAndFlush(writer *bufio.Writer, b ...byte) error {
+	if _, err := writer.Write(b); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// replyCodeFor 把拨号错误映射为 RFC 1928 应答码。
+func replyCodeFor(err error) byte {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return socksproto.RepConnectionRefused
+	case errors.Is(err, syscall.ENETUNREACH):
+		return socksproto.RepNetworkUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.As(err, &dnsErr),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return socksproto.RepHostUnreachable
+	default:
+		return socksproto.RepGeneralFailure
+	}
+}
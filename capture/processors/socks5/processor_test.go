@@ -6,12 +6,17 @@ package socks5
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
 
+	httpproc "github.com/mintfog/sniffy/capture/processors/http"
 	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/socksproto"
 )
 
 type testConnection struct {
@@ -51,8 +56,7 @@ func TestProcessor(t *testing.T) {
 	if got := p.GetProtocolName(); got != "SOCKS5" {
 		t.Fatalf("GetProtocolName = %q", got)
 	}
-	// SOCKS5 协议处理目前是占位实现，只验证 Process 走通了连接上的 server，
-	// 不断言日志内容/条数，避免真正实现协议时测试因无关原因失败。
+	// 空输入:客户端连上即断开,按正常收尾处理。
 	if err := p.Process(); err != nil {
 		t.Fatalf("Process returned %v", err)
 	}
@@ -60,3 +64,156 @@ func TestProcessor(t *testing.T) {
 		t.Fatal("Process did not reach the server from the connection")
 	}
 }
+
+// scripted 以给定的客户端输入构造连接,并把写回客户端的字节收进 out。
+func scripted(input []byte) (*testConnection, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &testConnection{
+		server: &testServer{},
+		reader: bufio.NewReader(bytes.NewReader(input)),
+		writer: bufio.NewWriter(out),
+	}, out
+}
+
+// stubTunnel 替换拨号与隧道接管,返回记录下来的 CONNECT 目标。
+func stubTunnel(t *testing.T, dialErr error) *string {
+	t.Helper()
+	var got string
+	oldDial, oldServe := dialTarget, serveTunnel
+	dialTarget = func(host string) (net.Conn, error) {
+		got = host
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		c, _ := net.Pipe()
+		return c, nil
+	}
+	serveTunnel = func(_ types.Connection, target string, origin net.Conn) error {
+		_ = origin.Close()
+		return nil
+	}
+	t.Cleanup(func() { dialTarget, serveTunnel = oldDial, oldServe })
+	return &got
+}
+
+func connectRequest(atyp byte, addr []byte, port uint16) []byte {
+	b := []byte{socksproto.Version, socksproto.CmdConnect, 0x00, atyp}
+	if atyp == socksproto.AtypDomain {
+		b = append(b, byte(len(addr)))
+	}
+	b = append(b, addr...)
+	return append(b, byte(port>>8), byte(port))
+}
+
+func TestConnectTargets(t *testing.T) {
+	cases := []struct {
+		name string
+		atyp byte
+		addr []byte
+		want string
+	}{
+		{"ipv4", socksproto.AtypIPv4, []byte{10, 0, 0, 1}, "10.0.0.1:443"},
+		{"ipv6", socksproto.AtypIPv6, net.ParseIP("2001:db8::1").To16(), "[2001:db8::1]:443"},
+		{"domain", socksproto.AtypDomain, []byte("example.com"), "example.com:443"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := stubTunnel(t, nil)
+			in := append([]byte{socksproto.Version, 1, socksproto.MethodNoAuth}, connectRequest(tc.atyp, tc.addr, 443)...)
+			conn, out := scripted(in)
+			if err := New(conn).Process(); err != nil {
+				t.Fatalf("Process returned %v", err)
+			}
+			if *target != tc.want {
+				t.Fatalf("dialed %q, want %q", *target, tc.want)
+			}
+			got := out.Bytes()
+			if len(got) < 4 || !bytes.Equal(got[:2], []byte{socksproto.Version, socksproto.MethodNoAuth}) || got[3] != socksproto.RepSucceeded {
+				t.Fatalf("unexpected reply bytes % x", got)
+			}
+		})
+	}
+}
+
+func TestUsernamePasswordAuth(t *testing.T) {
+	httpproc.SetProxyAuth(true, "user", "secret")
+	t.Cleanup(func() { httpproc.SetProxyAuth(false, "", "") })
+
+	auth := func(user, pass string) []byte {
+		b := []byte{socksproto.AuthVersion, byte(len(user))}
+		b = append(b, user...)
+		b = append(b, byte(len(pass)))
+		return append(b, pass...)
+	}
+
+	t.Run("no acceptable method", func(t *testing.T) {
+		stubTunnel(t, nil)
+		conn, out := scripted([]byte{socksproto.Version, 1, socksproto.MethodNoAuth})
+		if err := New(conn).Process(); !errors.Is(err, errNoMethod) {
+			t.Fatalf("Process returned %v, want errNoMethod", err)
+		}
+		if !bytes.Equal(out.Bytes(), []byte{socksproto.Version, socksproto.MethodNoAcceptable}) {
+			t.Fatalf("reply % x", out.Bytes())
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		target := stubTunnel(t, nil)
+		in := append([]byte{socksproto.Version, 2, socksproto.MethodNoAuth, socksproto.MethodUserPass}, auth("user", "nope")...)
+		conn, out := scripted(in)
+		if err := New(conn).Process(); !errors.Is(err, errAuthFailed) {
+			t.Fatalf("Process returned %v, want errAuthFailed", err)
+		}
+		if !bytes.Equal(out.Bytes(), []byte{socksproto.Version, socksproto.MethodUserPass, socksproto.AuthVersion, socksproto.AuthFailure}) {
+			t.Fatalf("reply % x", out.Bytes())
+		}
+		if *target != "" {
+			t.Fatal("unauthenticated client reached the dialer")
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		target := stubTunnel(t, nil)
+		in := append([]byte{socksproto.Version, 1, socksproto.MethodUserPass}, auth("user", "secret")...)
+		in = append(in, connectRequest(socksproto.AtypDomain, []byte("example.com"), 80)...)
+		conn, out := scripted(in)
+		if err := New(conn).Process(); err != nil {
+			t.Fatalf("Process returned %v", err)
+		}
+		if *target != "example.com:80" {
+			t.Fatalf("dialed %q", *target)
+		}
+		if !bytes.HasPrefix(out.Bytes(), []byte{socksproto.Version, socksproto.MethodUserPass, socksproto.AuthVersion, socksproto.AuthSuccess, socksproto.Version, socksproto.RepSucceeded}) {
+			t.Fatalf("reply % x", out.Bytes())
+		}
+	})
+}
+
+func TestRequestErrorsReplyCodes(t *testing.T) {
+	greeting := []byte{socksproto.Version, 1, socksproto.MethodNoAuth}
+	cases := []struct {
+		name    string
+		request []byte
+		dialErr error
+		rep     byte
+	}{
+		{"bind not supported", []byte{socksproto.Version, 0x02, 0x00, socksproto.AtypIPv4, 127, 0, 0, 1, 0, 80}, nil, socksproto.RepCommandNotSupported},
+		{"unknown address type", []byte{socksproto.Version, socksproto.CmdConnect, 0x00, 0x09}, nil, socksproto.RepAddrNotSupported},
+		{"refused", connectRequest(socksproto.AtypIPv4, []byte{127, 0, 0, 1}, 1), &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, socksproto.RepConnectionRefused},
+		{"unresolvable", connectRequest(socksproto.AtypDomain, []byte("nx.invalid"), 80), &net.DNSError{Err: "no such host", Name: "nx.invalid"}, socksproto.RepHostUnreachable},
+		{"other", connectRequest(socksproto.AtypDomain, []byte("example.com"), 80), errors.New("upstream proxy said no"), socksproto.RepGeneralFailure},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stubTunnel(t, tc.dialErr)
+			conn, out := scripted(append(append([]byte{}, greeting...), tc.request...))
+			if err := New(conn).Process(); err == nil {
+				t.Fatal("Process succeeded on an invalid request")
+			}
+			got := out.Bytes()
+			if len(got) < 4 || got[3] != tc.rep {
+				t.Fatalf("reply % x, want REP 0x%02x", got, tc.rep)
+			}
+		})
+	}
+}
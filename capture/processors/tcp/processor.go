@@ -7,10 +7,34 @@ package tcp
 
 import (
 	"bufio"
+	"errors"
 
+	"github.com/mintfog/sniffy/capture/processors/internal/relayio"
 	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/procinfo"
 )
 
+// Dialer 拨通中继目标 host:port。由引擎注入,使 TCP 中继与直通隧道共用上游代理等出站策略。
+type Dialer = relayio.Dialer
+
+var dialTarget Dialer = relayio.DialDirect
+
+// SetDialer 注入出站拨号函数;传入 nil 时保留现有值。
+func SetDialer(d Dialer) {
+	if d != nil {
+		dialTarget = d
+	}
+}
+
+// processResolver 异步解析连接对应的发起进程,补全会话的进程信息(可为 nil)。
+var processResolver *procinfo.Resolver
+
+// SetProcessResolver 注入进程解析器(装配层调用)。
+func SetProcessResolver(r *procinfo.Resolver) { processResolver = r }
+
+// errSelfTarget 表示原始目标就是监听端自身:中继过去只会无限自连。
+var errSelfTarget = errors.New("tcp: 原始目标指向代理自身")
+
 // Processor TCP协议处理器
 type Processor struct {
 	Conn types.Connection
@@ -40,10 +64,28 @@ func (p *Processor) Process() error {
 	return p.handleTcpProtocol(server, reader, writer)
 }
 
-// handleTcpProtocol 处理TCP协议的具体逻辑
+// handleTcpProtocol 把连接中继到它的原始目标并记录为 TCP 会话。常规代理端口上的
+// 连接没有原始目标(客户端本就是冲着代理来的),识别不出协议时只能关闭。
 func (p *Processor) handleTcpProtocol(server types.Server, reader *bufio.Reader, writer *bufio.Writer) error {
-	// TCP协议处理逻辑
-	server.LogInfo("处理TCP协议...")
+	conn := p.Conn.GetConn()
+	target := types.OriginalDestination(conn)
+	if target == "" {
+		server.LogInfo("TCP 连接没有可中继的原始目标，关闭")
+		return nil
+	}
+	if conn.LocalAddr() != nil && target == conn.LocalAddr().String() {
+		server.LogError("拒绝中继 %s: %v", target, errSelfTarget)
+		return errSelfTarget
+	}
 
-	return nil
+	origin, err := dialTarget(target)
+	if err != nil {
+		server.LogError("TCP 中继连接目标失败 %s: %v", target, err)
+		if r := newSessionRecorder(p.Conn, target); r != nil {
+			r.close(err)
+		}
+		return err
+	}
+	server.LogDebug("TCP 中继：%s -> %s", conn.RemoteAddr(), target)
+	return Relay(p.Conn, target, origin)
 }
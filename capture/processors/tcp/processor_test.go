@@ -6,12 +6,17 @@ package tcp
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
 )
 
 type testConnection struct {
@@ -51,8 +56,7 @@ func TestProcessor(t *testing.T) {
 	if got := p.GetProtocolName(); got != "TCP" {
 		t.Fatalf("GetProtocolName = %q", got)
 	}
-	// TCP 协议处理目前是占位实现，只验证 Process 走通了连接上的 server，
-	// 不断言日志内容/条数，避免真正实现协议时测试因无关原因失败。
+	// 没有原始目标(常规代理端口):直接收尾。
 	if err := p.Process(); err != nil {
 		t.Fatalf("Process returned %v", err)
 	}
@@ -60,3 +64,170 @@ func TestProcessor(t *testing.T) {
 		t.Fatal("Process did not reach the server from the connection")
 	}
 }
+
+// dstConn 为连接附上原始目标,模拟透明模式下 accept 到的连接。
+type dstConn struct {
+	*net.TCPConn
+	dst string
+}
+
+func (c *dstConn) OriginalDestination() string { return c.dst }
+
+// recordingSink 收集推送的 TCP 会话快照。
+type recordingSink struct {
+	mu    sync.Mutex
+	snaps []*flow.TCPSession
+}
+
+func (s *recordingSink) RecordTCPSession(ts *flow.TCPSession) {
+	s.mu.Lock()
+	s.snaps = append(s.snaps, ts)
+	s.mu.Unlock()
+}
+
+func (s *recordingSink) last() *flow.TCPSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.snaps) == 0 {
+		return nil
+	}
+	return s.snaps[len(s.snaps)-1]
+}
+
+func useSink(t *testing.T) *recordingSink {
+	t.Helper()
+	sink := &recordingSink{}
+	SetSessionSink(sink)
+	t.Cleanup(func() { SetSessionSink(nil) })
+	return sink
+}
+
+// tcpPair 返回一对已连通的环回 TCP 连接(客户端侧, 代理侧)。
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(); server.Close() })
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+// startEchoOrigin 读完客户端发来的全部字节后回写 "pong:" 前缀的回显并关闭。
+func startEchoOrigin(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		data, _ := io.ReadAll(c)
+		_, _ = c.Write(append([]byte("pong:"), data...))
+	}()
+	return ln.Addr().String()
+}
+
+func TestRelayRecordsTCPSession(t *testing.T) {
+	sink := useSink(t)
+	target := startEchoOrigin(t)
+	client, proxySide := tcpPair(t)
+
+	conn := types.NewConnection(&dstConn{TCPConn: proxySide, dst: target}, &testServer{})
+	done := make(chan error, 1)
+	go func() { done <- New(conn).Process() }()
+
+	if _, err := client.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.CloseWrite() // 半关闭:源站读到 EOF 后才回写
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("client read: %v", err)
+	}
+	if string(reply) != "pong:ping" {
+		t.Fatalf("client got %q", reply)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Process returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not finish")
+	}
+
+	ts := sink.last()
+	if ts == nil || ts.Status != "closed" || ts.Target != target || ts.EndTime == nil {
+		t.Fatalf("final session = %+v", ts)
+	}
+	if ts.BytesSent != 4 || ts.BytesRecv != int64(len("pong:ping")) {
+		t.Fatalf("byte counters sent=%d recv=%d", ts.BytesSent, ts.BytesRecv)
+	}
+	if len(ts.Chunks) < 2 || ts.Chunks[0].Direction != flow.WSClientToServer || string(ts.Chunks[0].Data) != "ping" {
+		t.Fatalf("chunks = %+v", ts.Chunks)
+	}
+	if last := ts.Chunks[len(ts.Chunks)-1]; last.Direction != flow.WSServerToClient || last.Seq != len(ts.Chunks)-1 {
+		t.Fatalf("last chunk = %+v", last)
+	}
+}
+
+func TestProcessRefusesSelfTarget(t *testing.T) {
+	_, proxySide := tcpPair(t)
+	conn := types.NewConnection(&dstConn{TCPConn: proxySide, dst: proxySide.LocalAddr().String()}, &testServer{})
+	if err := New(conn).Process(); !errors.Is(err, errSelfTarget) {
+		t.Fatalf("Process returned %v, want errSelfTarget", err)
+	}
+}
+
+func TestProcessRecordsDialFailure(t *testing.T) {
+	sink := useSink(t)
+	old := dialTarget
+	dialTarget = func(string) (net.Conn, error) { return nil, errors.New("connection refused") }
+	t.Cleanup(func() { dialTarget = old })
+
+	_, proxySide := tcpPair(t)
+	conn := types.NewConnection(&dstConn{TCPConn: proxySide, dst: "192.0.2.1:7"}, &testServer{})
+	if err := New(conn).Process(); err == nil {
+		t.Fatal("Process succeeded although the dial failed")
+	}
+	if ts := sink.last(); ts == nil || ts.Status != "error" || ts.Error == "" {
+		t.Fatalf("dial failure not recorded: %+v", ts)
+	}
+}
+
+func TestRecorderTrimsChunks(t *testing.T) {
+	useSink(t)
+	r := newSessionRecorder(&testConnection{server: &testServer{}}, "x:1")
+	big := bytes.Repeat([]byte{'a'}, maxChunkData+10)
+	for i := 0; i < maxSessionChunks+5; i++ {
+		r.add(flow.WSClientToServer, big)
+	}
+	r.close(nil)
+	s := r.session
+	if len(s.Chunks) != maxSessionChunks || s.ChunkCount != maxSessionChunks+5 {
+		t.Fatalf("kept %d chunks, count %d", len(s.Chunks), s.ChunkCount)
+	}
+	c := s.Chunks[0]
+	if !c.Truncated || len(c.Data) != maxChunkData || c.Size != len(big) || c.Seq != 5 {
+		t.Fatalf("oldest kept chunk = seq %d size %d len %d truncated %v", c.Seq, c.Size, len(c.Data), c.Truncated)
+	}
+	if c.Offset != int64(5*len(big)) {
+		t.Fatalf("offset = %d", c.Offset)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/processors/internal/relayio"
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// relayBufferSize 是单方向每次读取的缓冲大小,也是单个分块的上限。
+const relayBufferSize = 32 * 1024
+
+// Relay 在客户端与已拨通的 origin 之间双向复制字节,每个读到的数据块都记入 TCP 会话。
+// 一侧读到 EOF 时对另一侧半关闭写端(对端支持时),让「先发完再等回复」的协议照常收尾;
+// 任一方向出错或无法半关闭则整条关闭。返回时两条连接都已关闭。
+func Relay(conn types.Connection, target string, origin net.Conn) error {
+	client := conn.GetConn()
+	// 中继可为长连接,清除协商/嗅探阶段可能残留的读写超时。
+	_ = client.SetDeadline(time.Time{})
+	_ = origin.SetDeadline(time.Time{})
+
+	rec := newSessionRecorder(conn, target)
+
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			_ = origin.Close()
+			_ = client.Close()
+		})
+	}
+
+	errc := make(chan error, 2)
+	// 客户端 → 源站:从 reader 读,先排空 bufio 缓冲(协议探测时已 Peek 的字节)再读裸连接。
+	go func() { errc <- pump(origin, conn.GetReader(), rec, flow.WSClientToServer, closeBoth) }()
+	go func() { errc <- pump(client, origin, rec, flow.WSServerToClient, closeBoth) }()
+
+	first := <-errc
+	second := <-errc
+	closeBoth()
+
+	err := first
+	if err == nil {
+		err = second
+	}
+	rec.close(err)
+	return nil
+}
+
+// pump 把 src 的字节逐块写到 dst 并记录。src 正常结束时尝试半关闭 dst 的写端,
+// 做不到就整条关闭;读写出错也整条关闭,唤醒另一方向阻塞中的读取。
+// 返回值只用于会话记录:对端关闭引起的错误不算异常。
+func pump(dst net.Conn, src io.Reader, rec *sessionRecorder, dir string, closeBoth func()) error {
+	buf := make([]byte, relayBufferSize)
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			rec.add(dir, buf[:n])
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				closeBoth()
+				return relayio.Err(werr)
+			}
+		}
+		if rerr == nil {
+			continue
+		}
+		if errors.Is(rerr, io.EOF) {
+			if !relayio.CloseWrite(dst) {
+				closeBoth()
+			}
+			return nil
+		}
+		closeBoth()
+		return relayio.Err(rerr)
+	}
+}
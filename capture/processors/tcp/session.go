@@ -0,0 +1,189 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// SessionSink 由 service 实现,处理器经此记录/更新一条 TCP 会话(消费者定义接口,避免反向依赖)。
+type SessionSink interface {
+	RecordTCPSession(s *flow.TCPSession)
+}
+
+var sessionSink SessionSink
+
+// SetSessionSink 注入 TCP 会话接收器(装配层调用)。
+func SetSessionSink(s SessionSink) { sessionSink = s }
+
+const (
+	// maxSessionChunks 是单条会话保留的最近分块数,更早的分块只计入统计。
+	maxSessionChunks = 500
+	// maxChunkData 是单个分块保留的数据前缀,完整长度仍记在 Size。
+	maxChunkData = 16 * 1024
+	// pushInterval 限制快照推送频率:批量传输时每个 32KB 分块都推一次快照,
+	// 开销与事件量都不可接受。被节流的变化由延迟推送补上,关闭时总会推送最终状态。
+	pushInterval = 100 * time.Millisecond
+)
+
+// sessionRecorder 维护一条 TCPSession,按节流向 sessionSink 推送快照。
+// 两个方向的 pump 共享同一 recorder,故以 mu 串行化。
+type sessionRecorder struct {
+	mu       sync.Mutex
+	session  *flow.TCPSession
+	seq      int
+	lastPush time.Time
+	pending  *time.Timer
+	closed   bool
+}
+
+// newSessionRecorder 登记一条 open 状态的 TCP 会话。sessionSink 未注入时返回 nil,
+// 此后所有方法都是空操作。
+func newSessionRecorder(conn types.Connection, target string) *sessionRecorder {
+	if sessionSink == nil {
+		return nil
+	}
+	r := &sessionRecorder{session: &flow.TCPSession{
+		ID:        flow.NewID(),
+		Target:    target,
+		Status:    "open",
+		StartTime: time.Now(),
+		Chunks:    make([]flow.TCPChunk, 0, 16),
+	}}
+	client := conn.GetConn()
+	if client != nil {
+		r.session.Listener = types.ListenerID(client)
+	}
+	if client != nil && client.RemoteAddr() != nil {
+		r.session.ClientAddr = client.RemoteAddr().String()
+	}
+	r.mu.Lock()
+	r.pushLocked()
+	r.mu.Unlock()
+
+	if processResolver != nil && client != nil && client.RemoteAddr() != nil {
+		go func() {
+			if pi := processResolver.Resolve(client.RemoteAddr(), client.LocalAddr()); pi != nil {
+				r.setProcess(pi)
+			}
+		}()
+	}
+	return r
+}
+
+// add 追加一个分块。
+func (r *sessionRecorder) add(dir string, data []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.session
+	var offset int64
+	if dir == flow.WSClientToServer {
+		offset = s.BytesSent
+		s.BytesSent += int64(len(data))
+	} else {
+		offset = s.BytesRecv
+		s.BytesRecv += int64(len(data))
+	}
+	kept := data
+	if len(kept) > maxChunkData {
+		kept = kept[:maxChunkData]
+	}
+	s.Chunks = append(s.Chunks, flow.TCPChunk{
+		ID:        flow.NewID(),
+		SessionID: s.ID,
+		Direction: dir,
+		Data:      append([]byte(nil), kept...),
+		Size:      len(data),
+		Truncated: len(kept) < len(data),
+		Offset:    offset,
+		Timestamp: time.Now(),
+		Seq:       r.seq,
+	})
+	r.seq++
+	s.ChunkCount++
+	if len(s.Chunks) > maxSessionChunks {
+		s.Chunks = append(s.Chunks[:0], s.Chunks[len(s.Chunks)-maxSessionChunks:]...)
+	}
+	r.schedulePushLocked()
+}
+
+// setProcess 补上发起进程信息并推送。
+func (r *sessionRecorder) setProcess(pi *flow.ProcessInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.session.Process = pi
+	if !r.closed {
+		r.schedulePushLocked()
+	} else {
+		r.pushLocked()
+	}
+}
+
+// close 标记会话结束并推送最终状态;err 非 nil 时记为 error。
+func (r *sessionRecorder) close(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.pending != nil {
+		r.pending.Stop()
+		r.pending = nil
+	}
+	now := time.Now()
+	r.session.EndTime = &now
+	r.session.Status = "closed"
+	if err != nil {
+		r.session.Status = "error"
+		r.session.Error = err.Error()
+	}
+	r.pushLocked()
+}
+
+// schedulePushLocked 距上次推送已满 pushInterval 时立即推送,否则安排一次延迟推送。
+func (r *sessionRecorder) schedulePushLocked() {
+	wait := pushInterval - time.Since(r.lastPush)
+	if wait <= 0 {
+		r.pushLocked()
+		return
+	}
+	if r.pending != nil {
+		return
+	}
+	r.pending = time.AfterFunc(wait, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.pending = nil
+		if !r.closed {
+			r.pushLocked()
+		}
+	})
+}
+
+// pushLocked 向 sessionSink 推送当前会话的快照。分块切片独立复制,之后的追加/裁剪不会
+// 影响已推送的快照;持锁推送保证同一会话的快照按时间先后到达。
+func (r *sessionRecorder) pushLocked() {
+	r.lastPush = time.Now()
+	s := *r.session
+	s.Chunks = make([]flow.TCPChunk, len(r.session.Chunks))
+	copy(s.Chunks, r.session.Chunks) // Data 写入后不再改动,共享底层数组即可
+	if r.session.EndTime != nil {
+		t := *r.session.EndTime
+		s.EndTime = &t
+	}
+	sessionSink.RecordTCPSession(&s)
+}
@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mintfog/sniffy/capture/types"
 	"github.com/mintfog/sniffy/pkg/process"
 )
 
@@ -35,6 +36,15 @@ type TCPListener struct {
 	logger          Logger
 	processDetector process.Detector // 进程检测器
 
+	// transparent 是本轮 Start 生效的透明代理模式,listenPort 是实际监听端口(用于识别
+	// 直连本端口的常规代理客户端)。二者只在 Start 中写入。
+	transparent TransparentMode
+	listenPort  int
+	// id 是监听端标识(主监听端为空),reverse 是反向代理上游映射(nil 为非反代)。
+	// 同样只在 Start 中写入。
+	id      string
+	reverse *types.ReverseTarget
+
 	// 活跃连接跟踪:用于在 Stop 时强制切断所有连接,使阻塞在读写上的
 	// 处理 goroutine 立即返回,避免等待连接自然结束而卡死退出。
 	connMu  sync.Mutex
@@ -99,13 +109,30 @@ func (tl *TCPListener) Start() error {
 		return fmt.Errorf("TCP listener is already running")
 	}
 
+	mode, err := transparentModeOf(tl.config)
+	if err != nil {
+		return err
+	}
+	control, err := transparentControl(mode)
+	if err != nil {
+		return err
+	}
+
 	addr := fmt.Sprintf("%s:%d", tl.config.GetAddress(), tl.config.GetPort())
-	listener, err := net.Listen("tcp", addr)
+	lc := net.ListenConfig{Control: control}
+	listener, err := lc.Listen(context.Background(), "tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to start TCP listener on %s: %w", addr, err)
 	}
 
 	tl.listener = listener
+	tl.transparent = mode
+	tl.id = listenerIDOf(tl.config)
+	tl.reverse = reverseTargetOf(tl.config)
+	tl.listenPort = 0
+	if a, ok := listener.Addr().(*net.TCPAddr); ok {
+		tl.listenPort = a.Port
+	}
 	tl.isRunning = true
 
 	// Stop 取消了上一轮的 ctx,重启必须换一个新的:否则 accept 循环一进入就从
@@ -128,7 +155,11 @@ func (tl *TCPListener) Start() error {
 		}
 	}
 
-	tl.logInfo("TCP listener started on %s", addr)
+	if mode != TransparentOff {
+		tl.logInfo("TCP listener started on %s (transparent: %s)", addr, mode)
+	} else {
+		tl.logInfo("TCP listener started on %s", addr)
+	}
 
 	// 启动接受连接的goroutine
 	tl.wg.Add(tl.config.GetThreads())
@@ -290,7 +321,11 @@ func (tl *TCPListener) handleConnection(conn net.Conn) {
 	defer tl.wg.Done()
 	defer tl.untrackConn(conn)
 	defer conn.Close()
-	conn = wrapThrottleConn(conn)
+	tl.mu.RLock()
+	mode, listenPort, id, reverse := tl.transparent, tl.listenPort, tl.id, tl.reverse
+	tl.mu.RUnlock()
+	// 透明包装须在限速包装之前:取原始目标要用到裸 socket。
+	conn = wrapThrottleConn(wrapListener(wrapTransparent(conn, mode, listenPort), id, reverse))
 
 	startTime := time.Now()
 
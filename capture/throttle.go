@@ -10,6 +10,8 @@ import (
 	"net"
 	"sync/atomic"
 	"time"
+
+	"github.com/mintfog/sniffy/capture/types"
 )
 
 const (
@@ -40,6 +42,26 @@ type throttleConn struct {
 	net.Conn
 }
 
+// OriginalDestination 透传被包装连接的原始目标(透明代理模式)。
+func (c *throttleConn) OriginalDestination() string {
+	return types.OriginalDestination(c.Conn)
+}
+
+// ReverseTarget 透传被包装连接的反向代理上游映射。
+func (c *throttleConn) ReverseTarget() *types.ReverseTarget {
+	return types.ReverseTargetOf(c.Conn)
+}
+
+// ListenerID 透传被包装连接所属的监听端标识。
+func (c *throttleConn) ListenerID() string {
+	return types.ListenerID(c.Conn)
+}
+
+// CloseWrite 透传半关闭;被包装连接不支持时返回错误。
+func (c *throttleConn) CloseWrite() error {
+	return types.CloseWrite(c.Conn)
+}
+
 func (c *throttleConn) Read(p []byte) (int, error) {
 	rate := throttleBytesPerSecond.Load()
 	if rate > 0 {
@@ -0,0 +1,83 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// TransparentMode 是监听器的透明代理模式。
+type TransparentMode string
+
+const (
+	// TransparentOff 常规代理:客户端显式配置代理并发起 CONNECT / SOCKS5 握手。
+	TransparentOff TransparentMode = ""
+	// TransparentRedirect 对应 iptables/nftables REDIRECT(DNAT 到本机),
+	// 原始目标经 SO_ORIGINAL_DST 从 conntrack 取回。
+	TransparentRedirect TransparentMode = "redirect"
+	// TransparentTProxy 对应 TPROXY:监听 socket 带 IP_TRANSPARENT,连接保留原始目标,
+	// 其本地地址即原始目标。
+	TransparentTProxy TransparentMode = "tproxy"
+)
+
+// ParseTransparentMode 校验并返回透明代理模式;空串表示关闭。
+func ParseTransparentMode(s string) (TransparentMode, error) {
+	switch m := TransparentMode(s); m {
+	case TransparentOff, TransparentRedirect, TransparentTProxy:
+		return m, nil
+	default:
+		return TransparentOff, fmt.Errorf("unknown transparent mode %q (want redirect or tproxy)", s)
+	}
+}
+
+// transparentModeOf 读取配置声明的透明代理模式;配置未实现 types.TransparentConfig 时为关闭。
+func transparentModeOf(config Config) (TransparentMode, error) {
+	tc, ok := config.(types.TransparentConfig)
+	if !ok {
+		return TransparentOff, nil
+	}
+	return ParseTransparentMode(tc.GetTransparentMode())
+}
+
+// transparentConn 为 accept 到的连接附上原始目标,供处理器按原始目标路由。
+type transparentConn struct {
+	net.Conn
+	dst string
+}
+
+func (c *transparentConn) OriginalDestination() string { return c.dst }
+
+// CloseWrite 透传半关闭,TCP 中继据此让「先发完再等回复」的协议照常收尾。
+func (c *transparentConn) CloseWrite() error {
+	return types.CloseWrite(c.Conn)
+}
+
+// wrapTransparent 在透明模式下取出连接的原始目标并包装。取不到,或连接本就是冲着监听端
+// 来的(客户端显式把代理指向了本端口)时原样返回,连接按常规代理处理:
+//   - REDIRECT 下直连的原始目标就是本地地址;
+//   - TPROXY 下本地地址恒为原始目标,只能以端口区分,故被 TPROXY 到与监听端同端口的流量
+//     也会按常规代理处理。
+func wrapTransparent(conn net.Conn, mode TransparentMode, listenPort int) net.Conn {
+	if mode == TransparentOff {
+		return conn
+	}
+	dst, err := originalDst(conn, mode)
+	if err != nil || dst == "" {
+		return conn
+	}
+	local, _ := conn.LocalAddr().(*net.TCPAddr)
+	switch {
+	case local == nil:
+	case mode == TransparentRedirect && dst == local.String():
+		return conn
+	case mode == TransparentTProxy && local.Port == listenPort:
+		return conn
+	}
+	return &transparentConn{Conn: conn, dst: dst}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ip6tSOOriginalDst 即 IP6T_SO_ORIGINAL_DST(linux/netfilter_ipv6/ip6_tables.h),x/sys 未导出。
+const ip6tSOOriginalDst = 80
+
+// transparentControl 返回监听 socket 的初始化钩子:TPROXY 需在 bind 前打开
+// IP_TRANSPARENT(需 CAP_NET_ADMIN),REDIRECT 无需额外设置。
+func transparentControl(mode TransparentMode) (func(network, address string, c syscall.RawConn) error, error) {
+	if mode != TransparentTProxy {
+		return nil, nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var serr error
+		err := c.Control(func(fd uintptr) {
+			if network == "tcp6" {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_TRANSPARENT, 1)
+				return
+			}
+			serr = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_TRANSPARENT, 1)
+		})
+		if err != nil {
+			return err
+		}
+		if serr != nil {
+			return fmt.Errorf("set IP_TRANSPARENT: %w", serr)
+		}
+		return nil
+	}, nil
+}
+
+// originalDst 返回连接的原始目标 host:port:TPROXY 下即本地地址,REDIRECT 下从
+// conntrack 经 SO_ORIGINAL_DST(IPv6 为 IP6T_SO_ORIGINAL_DST)取回。
+func originalDst(conn net.Conn, mode TransparentMode) (string, error) {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return "", fmt.Errorf("not a TCP connection: %T", conn)
+	}
+	if mode == TransparentTProxy {
+		return local.String(), nil
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return "", fmt.Errorf("no raw socket behind %T", conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	var dst string
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		if local.IP.To4() != nil {
+			// sockaddr_in 恰好落在 IPv6Mreq 的前 16 字节:family(2) port(2) addr(4)。
+			var m *unix.IPv6Mreq
+			if m, serr = unix.GetsockoptIPv6Mreq(int(fd), unix.SOL_IP, unix.SO_ORIGINAL_DST); serr == nil {
+				port := binary.BigEndian.Uint16(m.Multiaddr[2:4])
+				dst = net.JoinHostPort(net.IP(m.Multiaddr[4:8]).String(), strconv.Itoa(int(port)))
+			}
+			return
+		}
+		var info *unix.IPv6MTUInfo
+		if info, serr = unix.GetsockoptIPv6MTUInfo(int(fd), unix.SOL_IPV6, ip6tSOOriginalDst); serr == nil {
+			dst = net.JoinHostPort(net.IP(info.Addr.Addr[:]).String(), strconv.Itoa(int(ntohs(info.Addr.Port))))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if serr != nil {
+		return "", fmt.Errorf("get SO_ORIGINAL_DST: %w", serr)
+	}
+	return dst, nil
+}
+
+// ntohs 把按网络字节序存放的 uint16 字段转为主机值。
+func ntohs(v uint16) uint16 {
+	var b [2]byte
+	binary.NativeEndian.PutUint16(b[:], v)
+	return binary.BigEndian.Uint16(b[:])
+}
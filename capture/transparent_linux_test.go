@@ -0,0 +1,58 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package capture
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+func loopbackPair(t *testing.T) (server net.Conn, port int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	server, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestWrapTransparentTProxy(t *testing.T) {
+	conn, port := loopbackPair(t)
+
+	// 本地端口即监听端口:客户端是冲着代理来的,按常规代理处理。
+	if got := types.OriginalDestination(wrapTransparent(conn, TransparentTProxy, port)); got != "" {
+		t.Fatalf("direct client got destination %q", got)
+	}
+	// TPROXY 截获的连接保留原始目标,本地地址即原始目标。
+	if got := types.OriginalDestination(wrapTransparent(conn, TransparentTProxy, port+1)); got != conn.LocalAddr().String() {
+		t.Fatalf("OriginalDestination = %q, want %s", got, conn.LocalAddr())
+	}
+}
+
+// TestWrapTransparentRedirectWithoutNAT 锁定没有 conntrack 记录(未经 REDIRECT)的连接
+// 不被当作透明连接,也不影响常规代理客户端。
+func TestWrapTransparentRedirectWithoutNAT(t *testing.T) {
+	conn, port := loopbackPair(t)
+	if got := types.OriginalDestination(wrapTransparent(conn, TransparentRedirect, port)); got != "" {
+		t.Fatalf("un-NATed conn got destination %q", got)
+	}
+	if wrapTransparent(conn, TransparentOff, port) != conn {
+		t.Fatal("TransparentOff must not wrap")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package capture
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// errTransparentUnsupported 表示当前平台没有 REDIRECT/TPROXY 所需的内核接口。
+var errTransparentUnsupported = errors.New("transparent proxy mode is only supported on Linux")
+
+func transparentControl(mode TransparentMode) (func(network, address string, c syscall.RawConn) error, error) {
+	if mode != TransparentOff {
+		return nil, errTransparentUnsupported
+	}
+	return nil, nil
+}
+
+func originalDst(net.Conn, TransparentMode) (string, error) {
+	return "", errTransparentUnsupported
+}
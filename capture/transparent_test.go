@@ -0,0 +1,54 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package capture
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+type transparentTestConfig struct {
+	testConfig
+	mode string
+}
+
+func (c transparentTestConfig) GetTransparentMode() string { return c.mode }
+
+func TestParseTransparentMode(t *testing.T) {
+	for _, s := range []string{"", "redirect", "tproxy"} {
+		if m, err := ParseTransparentMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseTransparentMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseTransparentMode("nat"); err == nil {
+		t.Error("unknown mode should be rejected")
+	}
+}
+
+func TestStartRejectsUnknownTransparentMode(t *testing.T) {
+	tl := newTestTCPListener(defaultTestConfig())
+	tl.config = transparentTestConfig{testConfig: defaultTestConfig(), mode: "nat"}
+	if err := tl.Start(); err == nil {
+		_ = tl.Stop()
+		t.Fatal("Start should reject an unknown transparent mode")
+	}
+}
+
+// TestThrottleConnForwardsOriginalDestination 锁定限速包装不会吞掉原始目标:
+// 透明包装在内、限速包装在外,处理器只看得到最外层。
+func TestThrottleConnForwardsOriginalDestination(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	wrapped := wrapThrottleConn(&transparentConn{Conn: a, dst: "198.51.100.1:443"})
+	if got := types.OriginalDestination(wrapped); got != "198.51.100.1:443" {
+		t.Fatalf("OriginalDestination = %q", got)
+	}
+	if got := types.OriginalDestination(wrapThrottleConn(a)); got != "" {
+		t.Fatalf("plain conn reported destination %q", got)
+	}
+}
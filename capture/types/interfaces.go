@@ -7,7 +7,9 @@ package types
 
 import (
 	"bufio"
+	"errors"
 	"net"
+	"net/url"
 	"time"
 )
 
@@ -80,6 +82,12 @@ type Config interface {
 	GetThreads() int
 }
 
+// TransparentConfig 由需要透明代理模式的配置额外实现(可选接口,未实现即常规代理)。
+type TransparentConfig interface {
+	// GetTransparentMode 返回 "redirect"、"tproxy" 或空串(关闭)
+	GetTransparentMode() string
+}
+
 // Logger 日志接口
 type Logger interface {
 	// Info 信息日志
@@ -186,3 +194,87 @@ func (d PacketDirection) String() string {
 		return "unknown"
 	}
 }
+
+// DestinationConn 由知道客户端原始目标地址的连接实现(如透明代理模式下 accept 到的连接)。
+// TCP 处理器据此把识别不出的流量中继回原始目标。
+type DestinationConn interface {
+	net.Conn
+
+	// OriginalDestination 返回客户端本想连接的 host:port;未知时为空串
+	OriginalDestination() string
+}
+
+// OriginalDestination 返回连接的原始目标地址;连接未实现 DestinationConn 时为空串。
+func OriginalDestination(conn net.Conn) string {
+	if dc, ok := conn.(DestinationConn); ok {
+		return dc.OriginalDestination()
+	}
+	return ""
+}
+
+// CloseWrite 半关闭连接的写端;连接不支持半关闭时返回 errors.ErrUnsupported。
+// 包装连接的类型以它透传半关闭,TCP / MQTT 中继据此让「先发完再等回复」的协议照常收尾。
+func CloseWrite(conn net.Conn) error {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errors.ErrUnsupported
+}
+
+// ReverseTarget 描述反向代理监听端的固定上游映射。
+type ReverseTarget struct {
+	// Upstream 是上游基础 URL(scheme://host[:port][/base][?query]),入站请求的路径与查询
+	// 拼接在其后
+	Upstream *url.URL
+	// TLS 表示入站连接需先终结 TLS(证书优先取导入的服务端证书,否则由 CA 现签)
+	TLS bool
+	// PreserveHost 为真时保留客户端的 Host 头,否则改写为上游主机
+	PreserveHost bool
+}
+
+// ReverseConfig 由需要反向代理模式的配置额外实现(可选接口,返回 nil 即常规代理)。
+type ReverseConfig interface {
+	// GetReverseTarget 返回该监听端的上游映射
+	GetReverseTarget() *ReverseTarget
+}
+
+// ReverseConn 由反向代理监听端 accept 到的连接实现,HTTP 处理器据此把请求改写到上游。
+type ReverseConn interface {
+	net.Conn
+
+	// ReverseTarget 返回连接所属监听端的上游映射
+	ReverseTarget() *ReverseTarget
+}
+
+// ReverseTargetOf 返回连接的反向代理上游映射;连接未实现 ReverseConn 时为 nil。
+func ReverseTargetOf(conn net.Conn) *ReverseTarget {
+	if rc, ok := conn.(ReverseConn); ok {
+		return rc.ReverseTarget()
+	}
+	return nil
+}
+
+// ListenerConfig 由多监听端模式下的附加监听端配置实现(可选接口),声明监听端标识与工作模式。
+type ListenerConfig interface {
+	// GetListenerID 返回监听端标识,随连接带给处理器,用于按监听端取设置并标记 Flow
+	GetListenerID() string
+
+	// GetListenerMode 返回 "regular"、"socks5"、"reverse" 或 "transparent"
+	GetListenerMode() string
+}
+
+// ListenerConn 由附加监听端 accept 到的连接实现。
+type ListenerConn interface {
+	net.Conn
+
+	// ListenerID 返回连接所属监听端的标识
+	ListenerID() string
+}
+
+// ListenerID 返回连接所属监听端的标识;主监听端的连接(未实现 ListenerConn)为空串。
+func ListenerID(conn net.Conn) string {
+	if lc, ok := conn.(ListenerConn); ok {
+		return lc.ListenerID()
+	}
+	return ""
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net"
 	"time"
+
+	"github.com/mintfog/sniffy/capture"
 )
 
 // Config TCP监听器配置
@@ -36,6 +38,9 @@ type Config struct {
 
 	// Threads 线程数
 	Threads int `json:"threads" yaml:"threads"`
+
+	// Transparent 透明代理模式(仅 Linux):"redirect"、"tproxy" 或空(常规代理)
+	Transparent string `json:"transparent" yaml:"transparent"`
 }
 
 // DefaultConfig 返回默认配置
@@ -82,6 +87,11 @@ func (c *Config) GetThreads() int {
 	return c.Threads
 }
 
+// GetTransparentMode 实现 types.TransparentConfig。
+func (c *Config) GetTransparentMode() string {
+	return c.Transparent
+}
+
 // Validate 验证配置
 func (c *Config) Validate() error {
 	// 验证地址
@@ -113,6 +123,11 @@ func (c *Config) Validate() error {
 		c.BufferSize = 4096
 	}
 
+	// 验证透明代理模式
+	if _, err := capture.ParseTransparentMode(c.Transparent); err != nil {
+		return err
+	}
+
 	// 验证最大连接数
 	if c.MaxConnections < 0 {
 		c.MaxConnections = 0
@@ -136,5 +151,6 @@ func (c *Config) Clone() *Config {
 		MaxConnections: c.MaxConnections,
 		BufferSize:     c.BufferSize,
 		EnableLogging:  c.EnableLogging,
+		Transparent:    c.Transparent,
 	}
 }
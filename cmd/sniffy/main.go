@@ -23,6 +23,7 @@ import (
 var (
 	listenAddr  = flag.String("addr", "0.0.0.0", "代理监听地址")
 	listenPort  = flag.Int("port", 8080, "代理监听端口")
+	transparent = flag.String("transparent", "", "透明代理模式(仅 Linux):redirect 对应 iptables REDIRECT,tproxy 对应 TPROXY(需 CAP_NET_ADMIN)")
 	apiAddr     = flag.String("api-addr", "127.0.0.1", "管理 API 监听地址")
 	apiPort     = flag.Int("api-port", 8888, "管理 API(HTTP+WebSocket)端口")
 	apiTLSCert  = flag.String("api-tls-cert", "", "管理 API TLS 证书路径(与 -api-tls-key 同时提供则启用 HTTPS)")
@@ -49,6 +50,7 @@ func main() {
 		}
 	})
 	config.EnableLogging = *verbose
+	config.Transparent = *transparent
 	if err := config.Validate(); err != nil {
 		app.Fatalf("配置无效: %v", err)
 	}
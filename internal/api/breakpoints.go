@@ -6,6 +6,7 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
@@ -22,10 +23,29 @@ type breakpointGlobalState struct {
 }
 
 type breakpointRuleInput struct {
-	URL        string `json:"url"`
-	OnRequest  bool   `json:"onRequest"`
-	OnResponse bool   `json:"onResponse"`
-	Enabled    *bool  `json:"enabled"`
+	URL         string `json:"url"`
+	OnRequest   bool   `json:"onRequest"`
+	OnResponse  bool   `json:"onResponse"`
+	OnWebSocket bool   `json:"onWebSocket"`
+	Direction   string `json:"direction"`
+	Content     string `json:"content"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// validDirection 报告规则方向是否合法(空为双向)。
+func (in breakpointRuleInput) validDirection() bool {
+	return in.Direction == "" || in.Direction == flow.WSClientToServer || in.Direction == flow.WSServerToClient
+}
+
+// wsBreakpointState 是 WebSocket 消息全局断点开关。
+type wsBreakpointState struct {
+	Enabled bool `json:"enabled"`
+}
+
+// wsBreakpointEdit 是放行暂停的 WebSocket 消息时可选的新内容,口径同 service.WSFrameInput。
+type wsBreakpointEdit struct {
+	Data   *string `json:"data"` // 缺省表示不改
+	Binary bool    `json:"binary"`
 }
 
 func decodeBreakpointJSON(r *http.Request, dst any, allowEmpty bool) error {
@@ -117,11 +137,20 @@ func (s *Server) handleBreakpointRules(w http.ResponseWriter, r *http.Request) {
 			fail(w, http.StatusBadRequest, "url is required")
 			return
 		}
+		if !body.validDirection() {
+			fail(w, http.StatusBadRequest, "invalid direction")
+			return
+		}
 		enabled := true
 		if body.Enabled != nil {
 			enabled = *body.Enabled
 		}
 		created := bp.AddRuleWithEnabled(body.URL, body.OnRequest, body.OnResponse, enabled)
+		if body.OnWebSocket || body.Direction != "" || body.Content != "" {
+			if rule, found := bp.SetRuleWebSocket(created.ID, body.OnWebSocket, body.Direction, body.Content); found {
+				created = rule
+			}
+		}
 		ok(w, created)
 	default:
 		fail(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -190,7 +219,15 @@ func (s *Server) handleBreakpointRule(w http.ResponseWriter, r *http.Request) {
 			fail(w, http.StatusBadRequest, "url is required")
 			return
 		}
-		rule, found := bp.UpdateRuleFields(id, body.URL, body.OnRequest, body.OnResponse, body.Enabled)
+		if !body.validDirection() {
+			fail(w, http.StatusBadRequest, "invalid direction")
+			return
+		}
+		if _, found := bp.UpdateRuleFields(id, body.URL, body.OnRequest, body.OnResponse, body.Enabled); !found {
+			fail(w, http.StatusNotFound, "breakpoint rule not found")
+			return
+		}
+		rule, found := bp.SetRuleWebSocket(id, body.OnWebSocket, body.Direction, body.Content)
 		if !found {
 			fail(w, http.StatusNotFound, "breakpoint rule not found")
 			return
@@ -207,6 +244,42 @@ func (s *Server) handleBreakpointRule(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleBreakpointWebSocket 读取或设置「断在每条 WebSocket 消息」的全局开关。
+func (s *Server) handleBreakpointWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.pipe == nil {
+		fail(w, http.StatusNotImplemented, "breakpoints unavailable")
+		return
+	}
+	bp := s.pipe.Breakpoints()
+	switch r.Method {
+	case http.MethodGet:
+		ok(w, wsBreakpointState{Enabled: bp.WebSocketBreak()})
+	case http.MethodPut, http.MethodPost:
+		var body wsBreakpointState
+		if err := decodeBreakpointJSON(r, &body, false); err != nil {
+			fail(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		bp.SetWebSocketBreak(body.Enabled)
+		ok(w, body)
+	default:
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleBreakpointWSMessages 列出暂停中的 WebSocket 消息。
+func (s *Server) handleBreakpointWSMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.pipe == nil {
+		ok(w, []any{})
+		return
+	}
+	ok(w, s.pipe.Breakpoints().ListWS())
+}
+
 func breakpointRuleByID(bp *pipeline.BreakpointManager, id string) (*pipeline.BreakRule, bool) {
 	for _, rule := range bp.ListRules() {
 		if rule.ID == id {
@@ -248,6 +321,32 @@ func (s *Server) handleBreakpoint(w http.ResponseWriter, r *http.Request) {
 		} else {
 			fail(w, http.StatusNotFound, "breakpoint not found")
 		}
+	case "resume-message":
+		var edit wsBreakpointEdit
+		if err := decodeBreakpointJSON(r, &edit, true); err != nil {
+			fail(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		var data []byte
+		if edit.Data != nil {
+			data = []byte(*edit.Data)
+			if edit.Binary {
+				decoded, err := base64.StdEncoding.DecodeString(*edit.Data)
+				if err != nil {
+					fail(w, http.StatusBadRequest, "invalid base64 data")
+					return
+				}
+				data = decoded
+			}
+			if data == nil {
+				data = []byte{} // 显式清空内容,区别于「不改」
+			}
+		}
+		if s.pipe.Breakpoints().ResumeWS(id, data) {
+			ok(w, nil)
+		} else {
+			fail(w, http.StatusNotFound, "breakpoint not found")
+		}
 	case "abort":
 		if s.pipe.Breakpoints().Abort(id) {
 			ok(w, nil)
@@ -6,11 +6,14 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/mintfog/sniffy/internal/service"
 )
 
 func (s *Server) handleGetCA(w http.ResponseWriter, r *http.Request) {
@@ -221,3 +224,68 @@ func (s *Server) handleServerCerts(w http.ResponseWriter, r *http.Request) {
 		fail(w, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
+
+// handleClientCerts 管理出示给上游的客户端证书(mTLS):GET 列表(不含私钥)、POST 导入、DELETE 删除。
+// 导入体为 PEM(certPEM + keyPEM)或 PKCS#12(p12 为 base64,另带 password),hosts 为生效的主机通配模式。
+func (s *Server) handleClientCerts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ok(w, s.svc.ClientCerts())
+	case http.MethodPost, http.MethodPut:
+		var body struct {
+			CertPEM  string   `json:"certPEM"`
+			KeyPEM   string   `json:"keyPEM"`
+			P12      string   `json:"p12"`
+			Password string   `json:"password"`
+			Hosts    []string `json:"hosts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		var (
+			dto service.ClientCertDTO
+			err error
+		)
+		if body.P12 != "" {
+			data, derr := base64.StdEncoding.DecodeString(body.P12)
+			if derr != nil {
+				fail(w, http.StatusBadRequest, "invalid p12 base64")
+				return
+			}
+			dto, err = s.svc.ImportClientCertPKCS12(data, body.Password, body.Hosts)
+		} else {
+			dto, err = s.svc.ImportClientCert(body.CertPEM, body.KeyPEM, body.Hosts)
+		}
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ok(w, dto)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			fail(w, http.StatusBadRequest, "missing id")
+			return
+		}
+		s.svc.DeleteClientCert(id)
+		ok(w, map[string]any{"deleted": id})
+	default:
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAutoBypass 查看与重置因 MITM 握手反复失败而自动直通的主机。
+// DELETE 带 ?host= 时只移除该主机,不带时清空全部。
+func (s *Server) handleAutoBypass(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ok(w, s.svc.AutoBypassHosts())
+	case http.MethodDelete:
+		host := r.URL.Query().Get("host")
+		s.svc.ResetAutoBypass(host)
+		ok(w, map[string]any{"reset": host})
+	default:
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/mintfog/sniffy/internal/service"
 )
 
 type fakeCertificateManager struct {
@@ -306,3 +308,32 @@ func TestHandleImportCAClassifiesManagerErrors(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleAutoBypass 列表取自注入的回调;DELETE 带 host 只重置该主机,不带时清空。
+func TestHandleAutoBypass(t *testing.T) {
+	svc := service.New(nil, nil, "", "")
+	hosts := []service.AutoBypassHostDTO{{Host: "pinned.example.com", Failures: 3}}
+	var reset []string
+	svc.SetAutoBypassControl(func() []service.AutoBypassHostDTO { return hosts },
+		func(host string) { reset = append(reset, host) }, nil)
+	server := &Server{svc: svc}
+	mux := http.NewServeMux()
+	server.routes(mux)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/api/tls/auto-bypass"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"host":"pinned.example.com"`) {
+		t.Fatalf("GET = %d: %s", rec.Code, rec.Body.String())
+	}
+	do(http.MethodDelete, "/api/tls/auto-bypass?host=pinned.example.com")
+	do(http.MethodDelete, "/api/tls/auto-bypass")
+	if len(reset) != 2 || reset[0] != "pinned.example.com" || reset[1] != "" {
+		t.Fatalf("reset = %q", reset)
+	}
+	if rec := do(http.MethodPost, "/api/tls/auto-bypass"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST = %d", rec.Code)
+	}
+}
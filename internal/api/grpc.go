@@ -0,0 +1,68 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxProtoSetInputBytes 限制描述符集上传的请求体大小(含 JSON 形式的 base64 膨胀)。
+const maxProtoSetInputBytes = 24 << 20
+
+// handleProtoSets 管理解码 gRPC 消息用的 protobuf 描述符集:GET 列表、POST 上传、DELETE 删除。
+//
+// 上传接受两种请求体:application/json 的 {"name", "data"(base64)},或以 ?name= 命名的
+// 原始 FileDescriptorSet 字节(如 curl --data-binary @set.pb)。
+func (s *Server) handleProtoSets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ok(w, s.svc.ProtoSets())
+	case http.MethodPost, http.MethodPut:
+		r.Body = http.MaxBytesReader(w, r.Body, maxProtoSetInputBytes)
+		var body struct {
+			Name string `json:"name"`
+			Data []byte `json:"data"`
+		}
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/json" {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				fail(w, http.StatusBadRequest, "invalid json")
+				return
+			}
+		} else {
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				fail(w, http.StatusBadRequest, "invalid body")
+				return
+			}
+			body.Name, body.Data = r.URL.Query().Get("name"), data
+		}
+		dto, err := s.svc.ImportProtoSet(body.Name, body.Data)
+		if err != nil {
+			var invalid InvalidInputError
+			if errors.As(err, &invalid) && invalid.InvalidInput() {
+				fail(w, http.StatusBadRequest, err.Error())
+			} else {
+				fail(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		ok(w, dto)
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			fail(w, http.StatusBadRequest, "missing name")
+			return
+		}
+		s.svc.DeleteProtoSet(name)
+		ok(w, map[string]any{"deleted": name})
+	default:
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
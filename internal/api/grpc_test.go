@@ -0,0 +1,86 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/mintfog/sniffy/internal/service"
+)
+
+// TestProtoSetRoutes 描述符集可按原始字节或 JSON(base64)上传,列表、删除与输入错误映射 400。
+func TestProtoSetRoutes(t *testing.T) {
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:       proto.String("ping.proto"),
+		Package:    proto.String("ping"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Pinger"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Ping"),
+				InputType:  proto.String(".google.protobuf.Empty"),
+				OutputType: proto.String(".google.protobuf.Empty"),
+			}},
+		}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.New(nil, nil, "", "")
+	server := &Server{svc: svc}
+	mux := http.NewServeMux()
+	server.routes(mux)
+	do := func(method, path, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/grpc/descriptor-sets?name=raw", "application/octet-stream", set)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ping.Pinger"`) {
+		t.Fatalf("原始字节上传 = %d: %s", rec.Code, rec.Body.String())
+	}
+	body := `{"name":"json","data":"` + base64.StdEncoding.EncodeToString(set) + `"}`
+	if rec := do(http.MethodPost, "/api/grpc/descriptor-sets", "application/json; charset=utf-8", []byte(body)); rec.Code != http.StatusOK {
+		t.Fatalf("JSON 上传 = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/api/grpc/descriptor-sets", "", nil); !strings.Contains(rec.Body.String(), `"name":"json"`) || !strings.Contains(rec.Body.String(), `"name":"raw"`) {
+		t.Fatalf("GET = %s", rec.Body.String())
+	}
+
+	for _, c := range []struct {
+		path, contentType string
+		body              []byte
+	}{
+		{"/api/grpc/descriptor-sets?name=bad", "application/octet-stream", []byte("\xff\xff")},
+		{"/api/grpc/descriptor-sets", "application/octet-stream", set}, // 缺名称
+		{"/api/grpc/descriptor-sets", "application/json", []byte(`{"name":`)},
+	} {
+		if rec := do(http.MethodPost, c.path, c.contentType, c.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s %q = %d, want 400", c.path, c.body, rec.Code)
+		}
+	}
+
+	if rec := do(http.MethodDelete, "/api/grpc/descriptor-sets?name=raw", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("DELETE = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/grpc/descriptor-sets", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("DELETE 缺名称 = %d, want 400", rec.Code)
+	}
+	if list := svc.ProtoSets(); len(list) != 1 || list[0].Name != "json" {
+		t.Fatalf("删除后列表 = %+v", list)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/mintfog/sniffy/internal/pac"
+)
+
+// handlePAC 返回 PAC 脚本,代理地址取访问 API 所用的主机名加代理端口。未开启 PAC 时回 404。
+func (s *Server) handlePAC(w http.ResponseWriter, r *http.Request) {
+	if !isSafeMethod(r.Method) {
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	script, enabled := s.svc.PACScript(net.JoinHostPort(host, strconv.Itoa(s.svc.Config().Port)))
+	if !enabled {
+		fail(w, http.StatusNotFound, "pac disabled")
+		return
+	}
+	w.Header().Set("Content-Type", pac.ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(script)
+}
@@ -53,7 +53,7 @@ type CertificateManager interface {
 	ImportCA(data []byte, password string) (string, error)
 }
 
-// InvalidInputError 标记可安全映射为 HTTP 400 的证书导入错误。
+// InvalidInputError 标记可安全映射为 HTTP 400 的客户端输入错误(证书导入、WebSocket 注入等)。
 type InvalidInputError interface {
 	error
 	InvalidInput() bool
@@ -131,6 +131,14 @@ func (s *Server) routes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/stream-sessions", s.handleStreamSessions)
 	mux.HandleFunc("/api/stream-sessions/", s.handleStreamSession)
 
+	mux.HandleFunc("/api/tcp-sessions", s.handleTCPSessions)
+	mux.HandleFunc("/api/tcp-sessions/", s.handleTCPSession)
+
+	mux.HandleFunc("/api/mqtt-sessions", s.handleMQTTSessions)
+	mux.HandleFunc("/api/mqtt-sessions/", s.handleMQTTSession)
+	mux.HandleFunc("/api/dns-queries", s.handleDNSQueries)
+	mux.HandleFunc("/api/dns-queries/", s.handleDNSQuery)
+
 	mux.HandleFunc("/api/statistics", s.handleStatistics)
 
 	mux.HandleFunc("/api/config", s.handleConfig)
@@ -145,6 +153,10 @@ func (s *Server) routes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/certificate/export", s.handleExportCA)
 	mux.HandleFunc("/api/certificate/import", s.handleImportCA)
 	mux.HandleFunc("/api/server-certs", s.handleServerCerts)
+	mux.HandleFunc("/api/client-certs", s.handleClientCerts)
+	mux.HandleFunc("/api/tls/auto-bypass", s.handleAutoBypass)
+	mux.HandleFunc("/api/grpc/descriptor-sets", s.handleProtoSets)
+	mux.HandleFunc("/api/pac", s.handlePAC)
 
 	mux.HandleFunc("/api/intercept/rules", s.handleRules)
 	mux.HandleFunc("/api/intercept/rules/", s.handleRule)
@@ -156,6 +168,8 @@ func (s *Server) routes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/breakpoints/global", s.handleBreakpointGlobal)
 	mux.HandleFunc("/api/breakpoints/rules", s.handleBreakpointRules)
 	mux.HandleFunc("/api/breakpoints/rules/", s.handleBreakpointRule)
+	mux.HandleFunc("/api/breakpoints/websocket", s.handleBreakpointWebSocket)
+	mux.HandleFunc("/api/breakpoints/websocket/messages", s.handleBreakpointWSMessages)
 	mux.HandleFunc("/api/breakpoints/", s.handleBreakpoint)
 
 	mux.HandleFunc("/api/export", s.handleExport)
@@ -6,8 +6,14 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/service"
 )
 
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
@@ -102,6 +108,16 @@ func (s *Server) handleWSSessions(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleWSSession(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/websocket-sessions/")
+	if sid, isFrames := strings.CutSuffix(id, "/frames"); isFrames {
+		s.handleWSSendFrame(w, r, sid)
+		return
+	}
+	if rest, isResend := strings.CutSuffix(id, "/resend"); isResend {
+		if sid, mid, found := strings.Cut(rest, "/messages/"); found {
+			s.handleWSResendMessage(w, r, sid, mid)
+			return
+		}
+	}
 	sess, found := s.svc.WSSession(id)
 	if !found {
 		fail(w, http.StatusNotFound, "session not found")
@@ -110,6 +126,99 @@ func (s *Server) handleWSSession(w http.ResponseWriter, r *http.Request) {
 	ok(w, sess)
 }
 
+// maxWSFrameInputBytes 限制注入请求体大小(含 base64 膨胀)。
+const maxWSFrameInputBytes = 16 << 20
+
+// handleWSSendFrame 向打开中的 WebSocket 会话注入一帧。
+// POST /api/websocket-sessions/{id}/frames,请求体为 service.WSFrameInput。
+func (s *Server) handleWSSendFrame(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	in, err := decodeWSFrameInput(w, r)
+	if err != nil {
+		failWSFrameDecode(w, err)
+		return
+	}
+	if err := s.svc.SendWSFrame(id, in); err != nil {
+		failWSInject(w, err)
+		return
+	}
+	ok(w, nil)
+}
+
+// wsFrameInputError 是注入请求体字段不合法的错误,原样回给调用方。
+type wsFrameInputError string
+
+func (e wsFrameInputError) Error() string { return string(e) }
+
+// decodeWSFrameInput 解码注入请求体:须恰好一个 JSON 对象、不含未知字段,方向与帧类型
+// 取前端口径的合法值。载荷(base64、控制帧长度等)由服务层与注入方校验。
+func decodeWSFrameInput(w http.ResponseWriter, r *http.Request) (service.WSFrameInput, error) {
+	var in service.WSFrameInput
+	r.Body = http.MaxBytesReader(w, r.Body, maxWSFrameInputBytes)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		return in, err
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return in, errors.New("request body must contain exactly one JSON value")
+	}
+	if in.Direction != "outbound" && in.Direction != "inbound" {
+		return in, wsFrameInputError("direction 须为 outbound 或 inbound")
+	}
+	switch in.Type {
+	case flow.WSText, flow.WSBinary, flow.WSPing, flow.WSPong, flow.WSClose:
+	default:
+		return in, wsFrameInputError("type 须为 text、binary、ping、pong 或 close")
+	}
+	return in, nil
+}
+
+// failWSFrameDecode 把解码错误映射为 HTTP 状态:请求体过大 413,字段不合法回具体原因,其余 400。
+func failWSFrameDecode(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	var invalid wsFrameInputError
+	switch {
+	case errors.As(err, &maxErr):
+		fail(w, http.StatusRequestEntityTooLarge, "request body is too large")
+	case errors.As(err, &invalid):
+		fail(w, http.StatusBadRequest, invalid.Error())
+	default:
+		fail(w, http.StatusBadRequest, "invalid json")
+	}
+}
+
+// handleWSResendMessage 把会话中一条已捕获的消息按原方向再发一次。
+// POST /api/websocket-sessions/{id}/messages/{messageId}/resend。
+func (s *Server) handleWSResendMessage(w http.ResponseWriter, r *http.Request, id, messageID string) {
+	if r.Method != http.MethodPost {
+		fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := s.svc.ResendWSMessage(id, messageID); err != nil {
+		failWSInject(w, err)
+		return
+	}
+	ok(w, nil)
+}
+
+// failWSInject 把注入错误映射为 HTTP 状态:输入问题 400,会话/消息不存在 404,
+// 其余(会话刚关闭、分片消息转发中、写失败)409。
+func failWSInject(w http.ResponseWriter, err error) {
+	var invalid InvalidInputError
+	switch {
+	case errors.As(err, &invalid) && invalid.InvalidInput():
+		fail(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWSSessionNotFound), errors.Is(err, service.ErrWSMessageNotFound):
+		fail(w, http.StatusNotFound, err.Error())
+	default:
+		fail(w, http.StatusConflict, err.Error())
+	}
+}
+
 func (s *Server) handleStreamSessions(w http.ResponseWriter, r *http.Request) {
 	page, pageSize := pageParams(r)
 	list, total := s.svc.StreamSessions(page, pageSize)
@@ -125,3 +234,51 @@ func (s *Server) handleStreamSession(w http.ResponseWriter, r *http.Request) {
 	}
 	ok(w, sess)
 }
+
+func (s *Server) handleTCPSessions(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+	list, total := s.svc.TCPSessions(page, pageSize)
+	paginated(w, list, total, page, pageSize)
+}
+
+func (s *Server) handleTCPSession(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/tcp-sessions/")
+	sess, found := s.svc.TCPSession(id)
+	if !found {
+		fail(w, http.StatusNotFound, "session not found")
+		return
+	}
+	ok(w, sess)
+}
+
+func (s *Server) handleMQTTSessions(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+	list, total := s.svc.MQTTSessions(page, pageSize)
+	paginated(w, list, total, page, pageSize)
+}
+
+func (s *Server) handleMQTTSession(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/mqtt-sessions/")
+	sess, found := s.svc.MQTTSession(id)
+	if !found {
+		fail(w, http.StatusNotFound, "session not found")
+		return
+	}
+	ok(w, sess)
+}
+
+func (s *Server) handleDNSQueries(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+	list, total := s.svc.DNSQueries(page, pageSize)
+	paginated(w, list, total, page, pageSize)
+}
+
+func (s *Server) handleDNSQuery(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/dns-queries/")
+	q, found := s.svc.DNSQuery(id)
+	if !found {
+		fail(w, http.StatusNotFound, "dns query not found")
+		return
+	}
+	ok(w, q)
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/pipeline"
+)
+
+// TestWSBreakpointRoutes 覆盖 WebSocket 消息断点的开关、规则字段、列表与带编辑的放行。
+func TestWSBreakpointRoutes(t *testing.T) {
+	pipe := pipeline.New(nil, nil)
+	server := &Server{pipe: pipe}
+	mux := http.NewServeMux()
+	server.routes(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/api/breakpoints/websocket", `{"enabled":true}`); rec.Code != http.StatusOK || !pipe.Breakpoints().WebSocketBreak() {
+		t.Fatalf("PUT websocket = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/api/breakpoints/websocket", ""); !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Errorf("GET websocket = %s", rec.Body.String())
+	}
+
+	rec := do(http.MethodPost, "/api/breakpoints/rules", `{"url":"chat.example","onWebSocket":true,"direction":"client->server","content":"login"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"onWebSocket":true`) || !strings.Contains(rec.Body.String(), `"content":"login"`) {
+		t.Fatalf("POST rule = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/breakpoints/rules", `{"url":"x","direction":"up"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("非法方向 = %d, want 400", rec.Code)
+	}
+	rules := pipe.Breakpoints().ListRules()
+	if len(rules) != 1 || rules[0].Direction != flow.WSClientToServer {
+		t.Fatalf("规则 = %+v", rules)
+	}
+	if rec := do(http.MethodPut, "/api/breakpoints/rules/"+rules[0].ID, `{"url":"chat.example","onWebSocket":false}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT rule = %d: %s", rec.Code, rec.Body.String())
+	}
+	if r := pipe.Breakpoints().ListRules()[0]; r.OnWebSocket || r.Direction != "" || r.Content != "" {
+		t.Errorf("PUT 后规则 = %+v", r)
+	}
+
+	m := &flow.WSMessage{ID: flow.NewID(), URL: "wss://chat.example/ws", Direction: flow.WSServerToClient, Type: flow.WSBinary, Data: []byte{1, 2}}
+	done := make(chan bool, 1)
+	go func() { done <- pipe.Breakpoints().PauseWS(m) }()
+	deadline := time.Now().Add(3 * time.Second)
+	var listed []flow.WSMessage
+	for time.Now().Before(deadline) && len(listed) == 0 {
+		rec := do(http.MethodGet, "/api/breakpoints/websocket/messages", "")
+		var env struct {
+			Data []flow.WSMessage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+			t.Fatalf("解析列表: %v: %s", err, rec.Body.String())
+		}
+		listed = env.Data
+		time.Sleep(time.Millisecond)
+	}
+	if len(listed) != 1 || listed[0].ID != m.ID || listed[0].PausedAt != flow.PhaseMessage {
+		t.Fatalf("暂停列表 = %+v", listed)
+	}
+
+	if rec := do(http.MethodPost, "/api/breakpoints/"+m.ID+"/resume-message", `{"data":"%%%","binary":true}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("非法 base64 = %d, want 400", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/breakpoints/"+m.ID+"/resume-message", `{"data":"AwQF","binary":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("resume-message = %d: %s", rec.Code, rec.Body.String())
+	}
+	if drop := <-done; drop || string(m.Data) != "\x03\x04\x05" {
+		t.Errorf("放行: drop=%v Data=%v", drop, m.Data)
+	}
+	if rec := do(http.MethodPost, "/api/breakpoints/"+m.ID+"/resume-message", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("重复放行 = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/service"
+)
+
+// TestWSInjectRoutes 注入与重发路由挂在 /api/websocket-sessions/ 之下,错误按类型映射状态码。
+func TestWSInjectRoutes(t *testing.T) {
+	svc := service.New(nil, nil, "", "")
+	var sent []string
+	svc.SetWSInjector(func(id, direction, frameType string, data []byte) error {
+		if string(data) == "busy" {
+			return errors.New("mid message")
+		}
+		sent = append(sent, id+" "+direction+" "+frameType+" "+string(data))
+		return nil
+	})
+	svc.RecordWSSession(&flow.WSSession{
+		ID: "ws1", Status: "open", StartTime: time.Now(),
+		Messages: []flow.WSMessage{{ID: "m1", Direction: flow.WSClientToServer, Type: flow.WSText, Data: []byte("again")}},
+	})
+	server := &Server{svc: svc}
+
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"text","data":"hello"}`, http.StatusOK},
+		{http.MethodPost, "/api/websocket-sessions/ws1/messages/m1/resend", "", http.StatusOK},
+		{http.MethodGet, "/api/websocket-sessions/ws1/frames", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"up","type":"text"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"txt"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"text","extra":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"text"}{}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", ``, http.StatusBadRequest},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"text","data":"` + strings.Repeat("x", maxWSFrameInputBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/api/websocket-sessions/nope/frames", `{"direction":"inbound","type":"text"}`, http.StatusNotFound},
+		{http.MethodPost, "/api/websocket-sessions/ws1/messages/nope/resend", "", http.StatusNotFound},
+		{http.MethodPost, "/api/websocket-sessions/ws1/frames", `{"direction":"inbound","type":"text","data":"busy"}`, http.StatusConflict},
+		{http.MethodGet, "/api/websocket-sessions/ws1", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		server.handleWSSession(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d: %s", tc.method, tc.path, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+	want := []string{"ws1 server->client text hello", "ws1 client->server text again"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Fatalf("注入 = %q, want %q", sent, want)
+	}
+}
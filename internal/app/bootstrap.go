@@ -9,8 +9,10 @@ package app
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/mintfog/sniffy/ca"
+	httpproc "github.com/mintfog/sniffy/capture/processors/http"
 	"github.com/mintfog/sniffy/capture/types"
 	"github.com/mintfog/sniffy/internal/bodycache"
 	"github.com/mintfog/sniffy/internal/core"
@@ -72,6 +74,22 @@ func Build(cfg types.Config, verbose bool) (*App, error) {
 	if err := engine.SetUpstreamProxy(svc.Config().EffectiveUpstream()); err != nil {
 		logger.Error("应用上游代理失败: %v", service.RedactUpstreamError(err))
 	}
+	// 上游路由表:有误时保留原路由并告警。
+	svc.SetUpstreamRoutesApplier(func(table string) error {
+		err := engine.SetUpstreamRoutes(table)
+		if err != nil {
+			logger.Error("应用上游路由表失败: %v", err)
+		}
+		return err
+	})
+	// 主机映射表:同样有误时保留原映射并告警。
+	svc.SetHostMappingsApplier(func(table string) error {
+		err := engine.SetHostMappings(table)
+		if err != nil {
+			logger.Error("应用主机映射表失败: %v", err)
+		}
+		return err
+	})
 	// 监听端可能绑定 0.0.0.0，必须在接受外部流量前恢复持久化凭据。凭据不全时的
 	// fail-closed（见 SetProxyAuth）表现为「代理突然全不通」，故告警指出原因。
 	svc.SetProxyAuthApplier(func(enabled bool, username, password string) error {
@@ -108,8 +126,66 @@ func Build(cfg types.Config, verbose bool) (*App, error) {
 	// SetPassthroughApplier 内部即以持久化值应用一次。
 	svc.SetPassthroughApplier(engine.SetPassthrough)
 
+	// 反向代理监听端:SetReverseProxyApplier 内部即以持久化值应用一次;此时引擎尚未 Start,
+	// 只记下设置,随引擎一并启动。
+	svc.SetReverseProxyApplier(func(enabled bool, port int, upstream string, terminateTLS, preserveHost bool) error {
+		err := engine.SetReverseProxy(enabled, port, upstream, terminateTLS, preserveHost)
+		if err != nil {
+			logger.Error("应用反向代理设置失败: %v", err)
+		}
+		return err
+	})
+
+	// 附加监听端:同样在注入时以持久化值应用一次,随引擎一并启动。
+	svc.SetListenersApplier(func(ls []service.ListenerConfig) error {
+		err := engine.SetListeners(listenerSpecs(ls, logger))
+		if err != nil {
+			logger.Error("应用监听端设置失败: %v", err)
+		}
+		return err
+	})
+
+	// 内置 DNS 服务端:同样在注入时以持久化值应用一次,随引擎一并启动。
+	svc.SetDNSApplier(func(enabled bool, port int, upstream, hosts string) error {
+		err := engine.SetDNSServer(enabled, port, upstream, hosts)
+		if err != nil {
+			logger.Error("应用 DNS 服务端设置失败: %v", err)
+		}
+		return err
+	})
+
 	// 导入的服务端证书(应对固定证书场景):接到引擎,SetServerCertsApplier 内部即以持久化值应用一次。
 	svc.SetServerCertsApplier(engine.SetImportedServerCerts)
+	// 出示给上游的客户端证书(mTLS)。
+	svc.SetClientCertsApplier(func(bs []service.ClientCertBinding) error {
+		certs := make([]httpproc.UpstreamClientCert, len(bs))
+		for i, b := range bs {
+			certs[i] = httpproc.UpstreamClientCert{Hosts: b.Hosts, Cert: b.Cert}
+		}
+		return engine.SetUpstreamClientCerts(certs)
+	})
+
+	// gRPC 消息解码:上传的描述符集与服务端反射开关,注入时即以持久化值应用一次。
+	svc.SetProtoSetsApplier(func(sets map[string][]byte) error {
+		err := engine.SetProtoDescriptorSets(sets)
+		if err != nil {
+			logger.Warn("部分 protobuf 描述符集无法加载: %v", err)
+		}
+		return err
+	})
+	svc.SetGRPCReflectionApplier(engine.SetGRPCReflection)
+	// 上游 TLS 参数镜像(应对服务端的 TLS 指纹校验)。
+	svc.SetTLSMirrorApplier(engine.SetTLSMirror)
+	// 代理端口上的 PAC 脚本(供设为「自动代理」的设备拉取)。
+	svc.SetPACApplier(engine.SetPAC)
+	// 上游证书校验(off / warn / enforce)与追加信任的根证书。
+	svc.SetUpstreamVerifyApplier(engine.SetUpstreamVerify)
+	// 握手反复失败(多为固定证书)的主机自动改为直通;列表变化回写配置,开启持久化时重启后恢复。
+	svc.SetAutoBypassApplier(engine.SetAutoBypass)
+	svc.SetAutoBypassControl(func() []service.AutoBypassHostDTO {
+		return autoBypassDTOs(engine.AutoBypassHosts())
+	}, engine.ResetAutoBypass, engine.RestoreAutoBypass)
+	engine.OnAutoBypassChange(svc.RecordAutoBypassHosts)
 
 	// 事件适配器:pipeline 不直接依赖 core,经函数把事件投递到总线。
 	emit := func(t string, payload any) {
@@ -136,6 +212,10 @@ func Build(cfg types.Config, verbose bool) (*App, error) {
 	engine.SetPipeline(pipe)
 	engine.SetFlowSink(svc)
 	engine.SetStreamSink(svc)
+	engine.SetTCPSink(svc)
+	engine.SetMQTTSink(svc)
+	engine.SetDNSSink(svc)
+	svc.SetWSInjector(engine.InjectWSFrame)
 
 	// 进程解析器(best-effort):创建失败则跳过进程补全,不影响抓包。
 	if resolver := procinfo.NewResolver(); resolver != nil {
@@ -167,3 +247,15 @@ func (a *App) Stop() error {
 	FlushLogs()
 	return err
 }
+
+// autoBypassDTOs 把 HTTP 处理器的自动绕过条目转成 service 层 DTO。
+func autoBypassDTOs(list []httpproc.AutoBypassEntry) []service.AutoBypassHostDTO {
+	out := make([]service.AutoBypassHostDTO, len(list))
+	for i, e := range list {
+		out[i] = service.AutoBypassHostDTO{Host: e.Host, Failures: e.Failures, LastError: e.LastError}
+		if !e.Since.IsZero() {
+			out[i].Since = e.Since.Format(time.RFC3339)
+		}
+	}
+	return out
+}
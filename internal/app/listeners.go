@@ -0,0 +1,60 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/mintfog/sniffy/capture"
+	httpproc "github.com/mintfog/sniffy/capture/processors/http"
+	"github.com/mintfog/sniffy/capture/types"
+	"github.com/mintfog/sniffy/internal/core"
+	"github.com/mintfog/sniffy/internal/service"
+)
+
+// listenerSpecs 把持久化的附加监听端配置转换为引擎设置,只取已启用的条目。反代上游地址
+// 无效的条目记日志后跳过,其余监听端照常生效。
+func listenerSpecs(ls []service.ListenerConfig, logger *Logger) []core.ListenerSpec {
+	specs := make([]core.ListenerSpec, 0, len(ls))
+	for _, l := range ls {
+		if !l.Enabled {
+			continue
+		}
+		spec := core.ListenerSpec{
+			ID:          l.ID,
+			Mode:        capture.ListenerMode(l.Mode),
+			Address:     l.Address,
+			Port:        l.Port,
+			Transparent: l.Transparent,
+		}
+		if spec.Mode == capture.ListenerReverse {
+			u, err := core.ParseReverseUpstream(l.Upstream)
+			if err != nil {
+				logger.Error("监听端 %s: %v", l.ID, err)
+				continue
+			}
+			spec.Reverse = &types.ReverseTarget{Upstream: u, TLS: l.TLS, PreserveHost: l.PreserveHost}
+		}
+		switch l.ProxyAuth {
+		case "on":
+			spec.ProxyAuth = &httpproc.ProxyAuthSettings{Enabled: true, Username: l.ProxyUsername, Password: l.ProxyPassword}
+		case "off":
+			spec.ProxyAuth = &httpproc.ProxyAuthSettings{}
+		}
+		switch l.DecryptScope {
+		case "":
+		case "off":
+			spec.DecryptScope = &httpproc.DecryptScopeSettings{}
+		default:
+			spec.DecryptScope = &httpproc.DecryptScopeSettings{
+				Enabled: true,
+				Mode:    l.DecryptScope,
+				Allow:   l.DecryptAllow,
+				Deny:    l.DecryptDeny,
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
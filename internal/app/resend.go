@@ -106,8 +106,10 @@ func (a *App) runResend(nf *flow.Flow) {
 	nf.State = flow.StateAwaitingResponse
 	resp, err := a.Engine.UpstreamClient().Do(req)
 	if err != nil {
-		nf.State = flow.StateErrored
-		nf.Error = err.Error()
+		if rc, ok := flow.ResponseCaptureFrom(req.Context()); ok {
+			nf.SetUpstreamRoute(rc.Route)
+		}
+		nf.FailUpstream(err)
 		a.finishResend(nf)
 		return
 	}
@@ -0,0 +1,90 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	dnsproc "github.com/mintfog/sniffy/capture/processors/dns"
+)
+
+// SetDNSSink 注入 DNS 查询接收器(由 service 实现)到 DNS 处理器。
+func (e *Engine) SetDNSSink(s dnsproc.QuerySink) { dnsproc.SetQuerySink(s) }
+
+// SetDNSServer 开启(或关闭)内置 DNS 服务端:在主监听地址的 port 上同时监听 UDP 与 TCP,
+// hosts(hosts 文件格式)中的域名以表中地址应答,其余转发到 upstream(省略端口时为 53)。
+// 覆盖表与上游即时生效;端口变化时重建监听。引擎未运行时只记下设置,随 Start 启动。
+// 代理端口上探测到的 DNS over TCP 连接随同一开关应答;关闭时覆盖表与上游只校验、不下发。
+func (e *Engine) SetDNSServer(enabled bool, port int, upstream, hosts string) error {
+	up, err := dnsproc.ParseUpstream(upstream)
+	if err != nil {
+		return err
+	}
+	table, err := dnsproc.ParseHosts(hosts)
+	if err != nil {
+		return fmt.Errorf("DNS 覆盖表: %w", err)
+	}
+	if enabled && (port < 0 || port > 65535) {
+		return fmt.Errorf("DNS 端口无效: %d", port)
+	}
+	if enabled {
+		dnsproc.SetUpstream(up)
+		dnsproc.SetHosts(table)
+	}
+	dnsproc.SetProxyPortEnabled(enabled)
+
+	addr := ""
+	if enabled {
+		addr = net.JoinHostPort(e.config.GetAddress(), strconv.Itoa(port))
+	}
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	if addr == e.dnsAddr && (e.dns != nil || !e.running) {
+		return nil
+	}
+	e.stopDNSLocked()
+	e.dnsAddr = addr
+	if !e.running {
+		return nil
+	}
+	return e.startDNSLocked()
+}
+
+// startDNSLocked 按记下的地址启动 DNS 服务端(未开启时为空操作)。调用方持有 extraMu。
+func (e *Engine) startDNSLocked() error {
+	if e.dnsAddr == "" || e.dns != nil {
+		return nil
+	}
+	s := dnsproc.NewServer(e.dnsAddr)
+	if e.logger != nil {
+		s.SetLogger(e.logger)
+	}
+	if err := s.Start(); err != nil {
+		return err
+	}
+	e.dns = s
+	return nil
+}
+
+// stopDNSLocked 停止运行中的 DNS 服务端,设置保留。调用方持有 extraMu。
+func (e *Engine) stopDNSLocked() {
+	if e.dns != nil {
+		_ = e.dns.Stop()
+		e.dns = nil
+	}
+}
+
+// DNSAddr 返回运行中 DNS 服务端的监听地址,未运行时为空串。
+func (e *Engine) DNSAddr() string {
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	if e.dns == nil {
+		return ""
+	}
+	return e.dns.Addr()
+}
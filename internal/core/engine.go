@@ -15,6 +15,7 @@ package core
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"net/http"
 	"net/url"
@@ -22,15 +23,21 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/mintfog/sniffy/ca"
 	"github.com/mintfog/sniffy/capture"
+	dnsproc "github.com/mintfog/sniffy/capture/processors/dns"
 	httpproc "github.com/mintfog/sniffy/capture/processors/http"
+	wsproc "github.com/mintfog/sniffy/capture/processors/http/websocket"
+	mqttproc "github.com/mintfog/sniffy/capture/processors/mqtt"
+	tcpproc "github.com/mintfog/sniffy/capture/processors/tcp"
 	"github.com/mintfog/sniffy/capture/types"
 	"github.com/mintfog/sniffy/internal/bodycache"
 	"github.com/mintfog/sniffy/internal/forward"
 	"github.com/mintfog/sniffy/internal/pipeline"
 	"github.com/mintfog/sniffy/internal/procinfo"
+	"github.com/mintfog/sniffy/internal/protoschema"
 )
 
 // Engine 抓包引擎。
@@ -39,12 +46,22 @@ type Engine struct {
 	caMu     sync.RWMutex
 	ca       ca.CA
 	upstream *http.Client
-	// upstreamProxy 持有当前上游代理地址(nil = 直连)。由 SetUpstreamProxy 原子写入,
-	// 被 upstream 客户端 Transport 的 Proxy 闭包并发读取,故全程无锁竞态,可运行时即时切换。
+	// upstreamProxy 持有当前默认上游代理地址(nil = 直连),供 SetUpstreamProxy 判断是否变更。
+	// 实际选路(含路由表)由 httpproc.UpstreamProxyFor 完成,被 Proxy 闭包并发读取。
 	upstreamProxy atomic.Pointer[url.URL]
 	listener      *capture.TCPListener
 	bus           *EventBus
 	logger        types.Logger
+	// protos 解码 gRPC 消息的 protobuf 描述符注册表(上传的描述符集 + 服务端反射)。
+	protos *protoschema.Registry
+
+	// extraMu 保护附加监听端(见 SetListeners / SetReverseProxy)与 DNS 服务端
+	// (见 SetDNSServer)的设置与生命周期,running 记录引擎是否已 Start。
+	extraMu sync.Mutex
+	extras  map[string]*extraListener
+	dnsAddr string // DNS 服务端的监听地址,空串为关闭
+	dns     *dnsproc.Server
+	running bool
 }
 
 // NewEngine 构造引擎:注入 CA 与上游客户端,把它们交给 http 处理器,
@@ -53,6 +70,8 @@ func NewEngine(config types.Config, opts ...Option) (*Engine, error) {
 	e := &Engine{
 		config: config,
 		bus:    NewEventBus(),
+		extras: make(map[string]*extraListener),
+		protos: protoschema.New(),
 	}
 	for _, o := range opts {
 		o(e)
@@ -68,6 +87,11 @@ func NewEngine(config types.Config, opts ...Option) (*Engine, error) {
 	// 把引擎拥有的 CA 与上游客户端注入处理器,确立所有权。
 	httpproc.SetCA(e.ca)
 	httpproc.SetUpstreamClient(e.upstream)
+	httpproc.SetProtoRegistry(e.protos)
+	// TCP / MQTT 中继、WebSocket 上游与直通隧道共用出站策略(上游代理等)。
+	tcpproc.SetDialer(httpproc.DialTunnel)
+	mqttproc.SetDialer(httpproc.DialTunnel)
+	wsproc.SetDialer(httpproc.DialTunnel)
 
 	e.listener = capture.NewTCPListener(config)
 	if e.logger != nil {
@@ -77,14 +101,14 @@ func NewEngine(config types.Config, opts ...Option) (*Engine, error) {
 }
 
 // buildUpstreamClient 复刻历史上 http 处理器 init() 中的连接池配置,
-// 并把 Transport.Proxy 接到 e.upstreamProxy,使上游代理可在运行时即时切换。
+// 并把 Transport.Proxy 接到上游路由,使上游代理与路由表可在运行时即时切换。
 func (e *Engine) buildUpstreamClient() *http.Client {
-	proxy := func(*http.Request) (*url.URL, error) { return e.upstreamProxy.Load(), nil }
+	proxy := func(req *http.Request) (*url.URL, error) { return httpproc.UpstreamProxyFor(req.URL.Host), nil }
 	tlsCfg := &tls.Config{InsecureSkipVerify: true}
 
 	// 标准 Transport:作为「无法保真转发」时的回退(h2、Upgrade、超大头、握手失败等)。
 	fallback := &http.Transport{
-		// 每次请求读取当前上游代理(nil 表示直连);写入由 SetUpstreamProxy 原子完成。
+		// 每次请求按路由选取上游代理(nil 表示直连);写入由 SetUpstreamProxy / SetUpstreamRoutes 原子完成。
 		Proxy:           proxy,
 		TLSClientConfig: tlsCfg.Clone(),
 		// 自定义 TLSClientConfig 会让 net/http 默认禁用 HTTP/2;显式开启,使代理可对
@@ -103,8 +127,9 @@ func (e *Engine) buildUpstreamClient() *http.Client {
 		ExpectContinueTimeout: httpproc.ExpectContinueTimeout,
 	}
 
-	// 无侵入保真转发:HTTP/1.x 请求按客户端原始头顺序/大小写写线,绕开 http.Transport 的
-	// 排序/规范化/注入;无法保真的情形自动回退到上面的 fallback。
+	// 无侵入保真转发:HTTP/1.x 请求按客户端原始头顺序/大小写写线,h2 请求另按客户端的
+	// 伪头顺序与 SETTINGS 等帧特征走上游 h2,绕开 http.Transport 的排序/规范化/注入;
+	// 无法保真的情形自动回退到上面的 fallback。
 	return &http.Client{
 		Transport: forward.New(forward.Config{
 			Fallback:          fallback,
@@ -115,6 +140,12 @@ func (e *Engine) buildUpstreamClient() *http.Client {
 			RespHeaderTimeout: httpproc.ResponseHeaderTimeout,
 			IdleConnTimeout:   httpproc.IdleConnTimeout,
 			MaxIdlePerHost:    httpproc.MaxIdleConnsPerHost,
+			MirrorTLS:         httpproc.MirrorTLSFor,
+			HelloFingerprint:  httpproc.FingerprintClientHello,
+			ClientCert:        httpproc.UpstreamClientCertFor,
+			VerifyUpstream:    httpproc.VerifyUpstreamConn,
+			UpstreamConn:      httpproc.UpstreamConnInfo,
+			MapAddr:           httpproc.MapUpstreamAddr,
 			Disabled:          faithfulDisabled(),
 		}),
 		Timeout: httpproc.ClientTimeout,
@@ -147,18 +178,84 @@ func (e *Engine) SetUpstreamProxy(addr string) error {
 		next = u
 	}
 	prev := e.upstreamProxy.Swap(next)
-	// 直通隧道(不解密的 CONNECT)不经上游客户端,单独同步裸 URL 供其建 CONNECT。
+	// 作为路由表都不命中时的默认上游,MITM 转发与直通隧道(不解密的 CONNECT)共用。
 	httpproc.SetUpstreamProxyURL(next)
 	if !sameURL(prev, next) {
-		// 切换代理后丢弃指向旧上游的空闲连接(forward.Transport.CloseIdleConnections
-		// 会一并清理其内部回退 *http.Transport 的空闲连接)。
-		if tr, ok := e.upstream.Transport.(interface{ CloseIdleConnections() }); ok {
-			tr.CloseIdleConnections()
-		}
+		e.closeIdleUpstream()
 	}
 	return nil
 }
 
+// SetUpstreamRoutes 下发上游路由表(文本形式,见 httpproc.ParseUpstreamRoutes),运行时即时生效。
+// 表有误时返回错误且保留原路由。
+func (e *Engine) SetUpstreamRoutes(table string) error {
+	routes, err := httpproc.ParseUpstreamRoutes(table)
+	if err != nil {
+		return err
+	}
+	httpproc.SetUpstreamRoutes(routes)
+	e.closeIdleUpstream()
+	return nil
+}
+
+// SetHostMappings 下发主机映射表(文本形式,见 httpproc.ParseHostMappings),运行时即时生效。
+// 表有误时返回错误且保留原映射。
+func (e *Engine) SetHostMappings(table string) error {
+	mappings, err := httpproc.ParseHostMappings(table)
+	if err != nil {
+		return err
+	}
+	httpproc.SetHostMappings(mappings)
+	e.closeIdleUpstream()
+	return nil
+}
+
+// SetPAC 开关代理端口上的 PAC 脚本,include / exclude 为主机通配模式(见 pac.Script)。
+func (e *Engine) SetPAC(enabled bool, include, exclude []string) error {
+	httpproc.SetPAC(enabled, include, exclude)
+	return nil
+}
+
+// closeIdleUpstream 在切换上游后丢弃指向旧上游的空闲连接(forward.Transport.CloseIdleConnections
+// 会一并清理其内部回退 *http.Transport 的空闲连接)。
+func (e *Engine) closeIdleUpstream() {
+	if tr, ok := e.upstream.Transport.(interface{ CloseIdleConnections() }); ok {
+		tr.CloseIdleConnections()
+	}
+}
+
+// SetTLSMirror 开关「上游连接镜像客户端 TLS 参数」,hosts 为主机通配模式(为空则全部主机)。
+func (e *Engine) SetTLSMirror(enabled bool, hosts []string) error {
+	httpproc.SetTLSMirror(enabled, hosts)
+	return nil
+}
+
+// SetAutoBypass 开关「握手反复失败的主机自动改为直通」:window 内失败 threshold 次即加入列表。
+func (e *Engine) SetAutoBypass(enabled bool, threshold int, window time.Duration) error {
+	httpproc.SetAutoBypass(enabled, threshold, window)
+	return nil
+}
+
+// AutoBypassHosts 返回当前自动绕过解密的主机。
+func (e *Engine) AutoBypassHosts() []httpproc.AutoBypassEntry { return httpproc.AutoBypassHosts() }
+
+// ResetAutoBypass 把主机移出自动绕过列表;host 为空时清空。
+func (e *Engine) ResetAutoBypass(host string) { httpproc.ResetAutoBypass(host) }
+
+// RestoreAutoBypass 以持久化的主机名恢复自动绕过列表。
+func (e *Engine) RestoreAutoBypass(hosts []string) { httpproc.RestoreAutoBypass(hosts) }
+
+// OnAutoBypassChange 注册自动绕过列表变化的回调(参数为变化后的主机名),供持久化。
+func (e *Engine) OnAutoBypassChange(fn func(hosts []string)) {
+	httpproc.SetAutoBypassListener(func(list []httpproc.AutoBypassEntry) {
+		hosts := make([]string, len(list))
+		for i, en := range list {
+			hosts[i] = en.Host
+		}
+		fn(hosts)
+	})
+}
+
 // SetDecryptScope 下发 HTTPS 解密范围到 HTTP 处理器,运行时即时生效。
 // enabled 为「启用 HTTPS MITM」总开关;mode 取 "all"/"allow"/"deny";allow/deny 为主机通配模式。
 func (e *Engine) SetDecryptScope(enabled bool, mode string, allow, deny []string) error {
@@ -199,6 +296,22 @@ func (e *Engine) SetImportedServerCerts(certs []*tls.Certificate) error {
 	return nil
 }
 
+// SetUpstreamClientCerts 下发出示给上游的客户端证书(mTLS),并丢弃空闲的上游连接,
+// 使此后的请求按新证书重新握手。
+func (e *Engine) SetUpstreamClientCerts(certs []httpproc.UpstreamClientCert) error {
+	httpproc.SetUpstreamClientCerts(certs)
+	e.closeIdleUpstream()
+	return nil
+}
+
+// SetUpstreamVerify 下发上游证书校验模式("off"/"warn"/"enforce")与追加信任的根证书,并丢弃空闲的
+// 上游连接,使此后的请求按新设置重新握手、重新校验。
+func (e *Engine) SetUpstreamVerify(mode string, roots []*x509.Certificate) error {
+	httpproc.SetUpstreamVerify(mode, roots)
+	e.closeIdleUpstream()
+	return nil
+}
+
 // sameURL 比较两个代理 URL 是否等价(含双 nil)。
 func sameURL(a, b *url.URL) bool {
 	if a == nil || b == nil {
@@ -207,8 +320,11 @@ func sameURL(a, b *url.URL) bool {
 	return a.String() == b.String()
 }
 
-// SetPipeline 注入插件管道到 HTTP 处理器。
-func (e *Engine) SetPipeline(p *pipeline.Pipeline) { httpproc.SetPipeline(p) }
+// SetPipeline 注入插件管道到 HTTP 与 MQTT 处理器。
+func (e *Engine) SetPipeline(p *pipeline.Pipeline) {
+	httpproc.SetPipeline(p)
+	mqttproc.SetPipeline(p)
+}
 
 // SetFlowSink 注入 flow 接收器(由 service 实现)到 HTTP 处理器。
 func (e *Engine) SetFlowSink(s httpproc.FlowSink) { httpproc.SetFlowSink(s) }
@@ -216,14 +332,59 @@ func (e *Engine) SetFlowSink(s httpproc.FlowSink) { httpproc.SetFlowSink(s) }
 // SetStreamSink 注入流式会话接收器(由 service 实现)到 HTTP 处理器。
 func (e *Engine) SetStreamSink(s httpproc.StreamSink) { httpproc.SetStreamSink(s) }
 
-// SetProcessResolver 注入进程解析器到 HTTP / WebSocket 处理器。
-func (e *Engine) SetProcessResolver(r *procinfo.Resolver) { httpproc.SetProcessResolver(r) }
+// SetTCPSink 注入 TCP 会话接收器(由 service 实现)到 TCP 处理器。
+func (e *Engine) SetTCPSink(s tcpproc.SessionSink) { tcpproc.SetSessionSink(s) }
+
+// SetMQTTSink 注入 MQTT 会话接收器(由 service 实现)到 MQTT 处理器。
+func (e *Engine) SetMQTTSink(s mqttproc.SessionSink) { mqttproc.SetSessionSink(s) }
+
+// SetProtoDescriptorSets 以 name → FileDescriptorSet 字节整体替换用于解码 gRPC 消息的描述符集。
+func (e *Engine) SetProtoDescriptorSets(sets map[string][]byte) error {
+	return e.protos.SetDescriptorSets(sets)
+}
+
+// SetGRPCReflection 开关「遇到未知 gRPC 方法时经上游查询服务端反射」。
+func (e *Engine) SetGRPCReflection(enabled bool) error {
+	e.protos.SetReflection(enabled)
+	return nil
+}
+
+// InjectWSFrame 向一条打开中的 WebSocket 会话注入一帧(语义见 websocket.Inject)。
+func (e *Engine) InjectWSFrame(sessionID, direction, frameType string, data []byte) error {
+	return wsproc.Inject(sessionID, direction, frameType, data)
+}
+
+// SetProcessResolver 注入进程解析器到 HTTP / WebSocket / TCP / MQTT 处理器。
+func (e *Engine) SetProcessResolver(r *procinfo.Resolver) {
+	httpproc.SetProcessResolver(r)
+	tcpproc.SetProcessResolver(r)
+	mqttproc.SetProcessResolver(r)
+}
 
-// Start 启动抓包监听。
-func (e *Engine) Start() error { return e.listener.Start() }
+// Start 启动抓包监听,并一并启动已配置的附加监听端与 DNS 服务端。它们起不来(如端口被
+// 占用)只记日志,不拖累主代理。
+func (e *Engine) Start() error {
+	if err := e.listener.Start(); err != nil {
+		return err
+	}
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	e.running = true
+	if err := errors.Join(e.startExtrasLocked(), e.startDNSLocked()); err != nil && e.logger != nil {
+		e.logger.Error("%v", err)
+	}
+	return nil
+}
 
-// Stop 停止抓包监听。
-func (e *Engine) Stop() error { return e.listener.Stop() }
+// Stop 停止抓包监听(含全部附加监听端与 DNS 服务端)。
+func (e *Engine) Stop() error {
+	e.extraMu.Lock()
+	e.running = false
+	e.stopExtrasLocked()
+	e.stopDNSLocked()
+	e.extraMu.Unlock()
+	return e.listener.Stop()
+}
 
 // Bus 返回事件总线,供 service 层订阅。
 func (e *Engine) Bus() *EventBus { return e.bus }
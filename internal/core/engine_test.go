@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/mintfog/sniffy/ca"
+	"github.com/mintfog/sniffy/capture"
 	httpproc "github.com/mintfog/sniffy/capture/processors/http"
 	"github.com/mintfog/sniffy/capture/types"
 	"github.com/mintfog/sniffy/internal/bodycache"
@@ -179,6 +180,69 @@ func TestEngineStartStopLifecycle(t *testing.T) {
 	assertProxies(t, engine, origin.URL, "重启后")
 }
 
+// TestEngineReverseProxy 锁定反向代理监听端:随引擎启停,直连它的请求被改写到上游,
+// 运行中改设置会重建监听端,关闭后不再监听。
+func TestEngineReverseProxy(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host + " " + r.URL.Path))
+	}))
+	defer origin.Close()
+
+	cfg := &coreTestConfig{address: "127.0.0.1", port: 0, threads: 1}
+	engine, err := NewEngine(cfg, WithCA(newCoreTestCA(t)), WithLogger(&coreTestLogger{}))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	for _, bad := range []string{"", "localhost:3000", "ftp://host", "http://", "http://u:p@host"} {
+		if err := engine.SetReverseProxy(true, 0, bad, false, false); err == nil {
+			t.Errorf("上游 %q 应被拒绝", bad)
+		}
+	}
+	if err := engine.SetReverseProxy(true, 0, origin.URL+"/api", false, false); err != nil {
+		t.Fatalf("SetReverseProxy: %v", err)
+	}
+	if engine.ReverseListener() != nil {
+		t.Fatal("引擎未启动时不应监听反代端口")
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = engine.Stop() })
+
+	get := func(stage string) string {
+		t.Helper()
+		l := engine.ReverseListener()
+		if l == nil {
+			t.Fatalf("%s: 反代监听端未运行", stage)
+		}
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Get("http://" + l.GetAddress() + "/users")
+		if err != nil {
+			t.Fatalf("%s: 请求反代端口失败: %v", stage, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	upHost := strings.TrimPrefix(origin.URL, "http://")
+	if got := get("启动后"); got != upHost+" /api/users" {
+		t.Fatalf("上游收到 %q", got)
+	}
+
+	if err := engine.SetReverseProxy(true, 0, origin.URL, false, false); err != nil {
+		t.Fatalf("运行中改上游: %v", err)
+	}
+	if got := get("改设置后"); got != upHost+" /users" {
+		t.Fatalf("改设置后上游收到 %q", got)
+	}
+
+	if err := engine.SetReverseProxy(false, 0, "", false, false); err != nil {
+		t.Fatalf("关闭反代: %v", err)
+	}
+	if engine.ReverseListener() != nil {
+		t.Fatal("关闭后反代监听端仍在运行")
+	}
+}
+
 // assertProxies 经引擎的监听端口发一次代理请求,确认它确实在转发而不只是端口开着。
 func assertProxies(t *testing.T, engine *Engine, targetURL, stage string) {
 	t.Helper()
@@ -822,3 +886,168 @@ func TestUpstreamProxyEndToEnd(t *testing.T) {
 		t.Fatal("请求未经过上游代理")
 	}
 }
+
+func TestEngineListeners(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("origin-ok"))
+	}))
+	defer origin.Close()
+
+	cfg := &coreTestConfig{address: "127.0.0.1", port: 0, threads: 1}
+	engine, err := NewEngine(cfg, WithCA(newCoreTestCA(t)), WithLogger(&coreTestLogger{}))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	if err := engine.SetListeners([]ListenerSpec{{ID: "lan", Mode: capture.ListenerRegular}}); err != nil {
+		t.Fatalf("SetListeners: %v", err)
+	}
+	if engine.ListenerByID("lan") != nil {
+		t.Fatal("引擎未启动时不应监听附加端口")
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = engine.Stop() })
+
+	get := func(stage string) {
+		t.Helper()
+		l := engine.ListenerByID("lan")
+		if l == nil {
+			t.Fatalf("%s: 附加监听端未运行", stage)
+		}
+		proxyURL, _ := url.Parse("http://" + l.GetAddress())
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}, Timeout: 10 * time.Second}
+		defer client.CloseIdleConnections()
+		resp, err := client.Get(origin.URL)
+		if err != nil {
+			t.Fatalf("%s: 经附加监听端请求失败: %v", stage, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || string(body) != "origin-ok" {
+			t.Fatalf("%s: 响应 = %d/%q", stage, resp.StatusCode, body)
+		}
+	}
+	get("启动后")
+	assertProxies(t, engine, origin.URL, "主监听端")
+
+	// 只改认证不重建监听器:全局不开认证,监听端开启后客户端须出示凭据。
+	before := engine.ListenerByID("lan")
+	auth := &httpproc.ProxyAuthSettings{Enabled: true, Username: "u", Password: "p"}
+	if err := engine.SetListeners([]ListenerSpec{{ID: "lan", ProxyAuth: auth}}); err != nil {
+		t.Fatalf("SetListeners: %v", err)
+	}
+	if engine.ListenerByID("lan") != before {
+		t.Fatal("仅认证变化时不应重建监听器")
+	}
+	if !httpproc.ProxyAuthRequiredFor("lan") || httpproc.ProxyAuthRequiredFor("") {
+		t.Fatal("认证设置应只作用于该监听端")
+	}
+	if err := engine.SetListeners([]ListenerSpec{{ID: "lan"}}); err != nil {
+		t.Fatalf("SetListeners: %v", err)
+	}
+	get("清除认证后")
+
+	err = engine.SetListeners([]ListenerSpec{
+		{ID: "lan"},
+		{ID: "lan"},
+		{ID: ""},
+		{ID: "rev", Mode: capture.ListenerReverse},
+		{ID: "tp", Mode: capture.ListenerTransparent},
+		{ID: "bad", Mode: "http"},
+	})
+	if err == nil {
+		t.Fatal("重复或无效的监听端应报错")
+	}
+	if engine.ListenerByID("lan") == nil {
+		t.Fatal("无效项不应影响有效的监听端")
+	}
+	for _, id := range []string{"rev", "tp", "bad"} {
+		if engine.ListenerByID(id) != nil {
+			t.Errorf("无效监听端 %s 不应启动", id)
+		}
+	}
+
+	if err := engine.SetListeners(nil); err != nil {
+		t.Fatalf("SetListeners(nil): %v", err)
+	}
+	if engine.ListenerByID("lan") != nil {
+		t.Fatal("移除后附加监听端仍在运行")
+	}
+	assertProxies(t, engine, origin.URL, "移除附加监听端后")
+}
+
+type probeDNSSink struct{ queries chan *flow.DNSQuery }
+
+func (s *probeDNSSink) RecordDNSQuery(q *flow.DNSQuery) { s.queries <- q }
+
+func TestEngineDNSServer(t *testing.T) {
+	cfg := &coreTestConfig{address: "127.0.0.1", port: 0, threads: 1}
+	engine, err := NewEngine(cfg, WithCA(newCoreTestCA(t)), WithLogger(&coreTestLogger{}))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	sink := &probeDNSSink{queries: make(chan *flow.DNSQuery, 8)}
+	engine.SetDNSSink(sink)
+	t.Cleanup(func() {
+		engine.SetDNSSink(nil)
+		_ = engine.SetDNSServer(false, 0, "", "")
+	})
+
+	if err := engine.SetDNSServer(true, 0, "", "not-an-ip example.com"); err == nil {
+		t.Fatal("无效覆盖表应被拒绝")
+	}
+	if err := engine.SetDNSServer(true, 0, "a:b:c", ""); err == nil {
+		t.Fatal("无效上游应被拒绝")
+	}
+	if err := engine.SetDNSServer(true, 0, "", "192.168.1.10 api.example.com"); err != nil {
+		t.Fatalf("SetDNSServer: %v", err)
+	}
+	if engine.DNSAddr() != "" {
+		t.Fatal("引擎未启动时不应监听 DNS 端口")
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = engine.Stop() })
+
+	addr := engine.DNSAddr()
+	if addr == "" {
+		t.Fatal("DNS 服务端未随引擎启动")
+	}
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ips, err := resolver.LookupIP(ctx, "ip4", "api.example.com")
+	if err != nil || len(ips) != 1 || ips[0].String() != "192.168.1.10" {
+		t.Fatalf("覆盖解析 = %v, %v", ips, err)
+	}
+	select {
+	case q := <-sink.queries:
+		if q.Name != "api.example.com" || !q.Overridden {
+			t.Fatalf("查询记录 = %+v", q)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("查询未被记录")
+	}
+
+	// 只改覆盖表不重建监听。
+	if err := engine.SetDNSServer(true, 0, "", "192.168.1.11 api.example.com"); err != nil {
+		t.Fatalf("SetDNSServer: %v", err)
+	}
+	if engine.DNSAddr() != addr {
+		t.Fatal("仅覆盖表变化时不应重建 DNS 监听")
+	}
+	if ips, err := resolver.LookupIP(ctx, "ip4", "api.example.com"); err != nil || len(ips) != 1 || ips[0].String() != "192.168.1.11" {
+		t.Fatalf("覆盖表热替换后 = %v, %v", ips, err)
+	}
+
+	if err := engine.SetDNSServer(false, 0, "", ""); err != nil {
+		t.Fatalf("关闭 DNS 服务端: %v", err)
+	}
+	if engine.DNSAddr() != "" {
+		t.Fatal("关闭后 DNS 服务端仍在运行")
+	}
+}
@@ -18,6 +18,9 @@ const (
 	EventBreakpointResolved EventType = "breakpoint_resolved" // 断点已放行/超时
 	EventWSMessage          EventType = "ws_message"          // WebSocket 消息
 	EventStreamMessage      EventType = "stream_message"      // 流式消息(SSE / gRPC / 分块流)
+	EventTCPSession         EventType = "tcp_session"         // 原始 TCP 中继会话的分块时间线
+	EventMQTTSession        EventType = "mqtt_session"        // MQTT 中继会话的报文时间线
+	EventDNSQuery           EventType = "dns_query"           // 内置 DNS 服务端应答的一次查询
 	EventConnStarted        EventType = "conn_started"        //
 	EventConnEnded          EventType = "conn_ended"          //
 	EventStatsTick          EventType = "stats_tick"          // 周期统计快照
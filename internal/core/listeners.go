@@ -0,0 +1,281 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/mintfog/sniffy/capture"
+	httpproc "github.com/mintfog/sniffy/capture/processors/http"
+	"github.com/mintfog/sniffy/capture/types"
+)
+
+// reverseProxyID 是 SetReverseProxy 管理的反向代理监听端的保留标识。
+const reverseProxyID = "reverse-proxy"
+
+// ListenerSpec 描述一个附加监听端。主监听端仍由 NewEngine 的配置决定,附加监听端与其
+// 共用插件管道、规则、断点与会话存储,每条 Flow 以 Listener 字段标明来自哪个监听端。
+type ListenerSpec struct {
+	ID      string
+	Mode    capture.ListenerMode
+	Address string // 为空时沿用主监听地址
+	Port    int    // 为 0 时由系统分配
+	// Transparent 是 transparent 模式下的 "redirect" / "tproxy"。
+	Transparent string
+	// Reverse 是 reverse 模式下的上游映射。
+	Reverse *types.ReverseTarget
+	// ProxyAuth / DecryptScope 为 nil 时沿用全局设置。
+	ProxyAuth    *httpproc.ProxyAuthSettings
+	DecryptScope *httpproc.DecryptScopeSettings
+}
+
+// validate 校验监听端设置的自洽性并规整模式;绑定失败等运行期错误留给 Start 报告。
+func (s *ListenerSpec) validate() error {
+	if strings.TrimSpace(s.ID) == "" {
+		return errors.New("监听端缺少标识")
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("监听端 %s 端口无效: %d", s.ID, s.Port)
+	}
+	m, err := capture.ParseListenerMode(string(s.Mode))
+	if err != nil {
+		return fmt.Errorf("监听端 %s: %w", s.ID, err)
+	}
+	s.Mode = m // 空串规整为 regular,免得 sameBinding 把两种写法当成绑定变化
+	switch s.Mode {
+	case capture.ListenerReverse:
+		if s.Reverse == nil || s.Reverse.Upstream == nil {
+			return fmt.Errorf("监听端 %s 为反向代理模式但未配置上游", s.ID)
+		}
+	case capture.ListenerTransparent:
+		if m, err := capture.ParseTransparentMode(s.Transparent); err != nil || m == capture.TransparentOff {
+			return fmt.Errorf("监听端 %s 为透明代理模式但未指定 redirect / tproxy", s.ID)
+		}
+	}
+	return nil
+}
+
+// sameBinding 报告两份设置的监听端是否无需重建:只有认证与解密范围不同的话,原地更新即可。
+func (s *ListenerSpec) sameBinding(o *ListenerSpec) bool {
+	a, b := *s, *o
+	a.ProxyAuth, a.DecryptScope, b.ProxyAuth, b.DecryptScope = nil, nil, nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// policy 返回下发到 HTTP 处理器的监听端覆盖设置。
+func (s *ListenerSpec) policy() *httpproc.ListenerPolicy {
+	return &httpproc.ListenerPolicy{ProxyAuth: s.ProxyAuth, DecryptScope: s.DecryptScope}
+}
+
+// listenConfig 是附加监听端的配置:超时、缓冲等沿用主配置,地址与端口取 spec,并按模式
+// 实现透明代理 / 反向代理 / 监听端标识等可选接口。以接口嵌入主配置,主配置实现的可选接口
+// 不会被带过来。
+type listenConfig struct {
+	types.Config
+	spec ListenerSpec
+}
+
+func (c *listenConfig) GetAddress() string {
+	if c.spec.Address != "" {
+		return c.spec.Address
+	}
+	return c.Config.GetAddress()
+}
+
+func (c *listenConfig) GetPort() int            { return c.spec.Port }
+func (c *listenConfig) GetListenerID() string   { return c.spec.ID }
+func (c *listenConfig) GetListenerMode() string { return string(c.spec.Mode) }
+
+func (c *listenConfig) GetTransparentMode() string {
+	if c.spec.Mode != capture.ListenerTransparent {
+		return ""
+	}
+	return c.spec.Transparent
+}
+
+func (c *listenConfig) GetReverseTarget() *types.ReverseTarget {
+	if c.spec.Mode != capture.ListenerReverse {
+		return nil
+	}
+	return c.spec.Reverse
+}
+
+// extraListener 是一个附加监听端的当前设置与(引擎运行中时的)监听器。
+type extraListener struct {
+	spec     ListenerSpec
+	listener *capture.TCPListener
+}
+
+// SetListeners 以 specs 整体替换附加监听端(SetReverseProxy 管理的反代监听端除外):
+// 已不在列表中的停止并移除;新增的启动;绑定参数变化的单独重建;仅认证 / 解密范围变化的
+// 原地更新,不打断其上的连接。引擎未运行时只记下设置,随 Start 启动。
+// 设置无效或启动失败的监听端不影响其余监听端(无效的按移除处理),错误合并返回。
+func (e *Engine) SetListeners(specs []ListenerSpec) error {
+	var errs []error
+	valid := make([]ListenerSpec, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, s := range specs {
+		if err := s.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if s.ID == reverseProxyID || seen[s.ID] {
+			errs = append(errs, fmt.Errorf("监听端标识重复: %s", s.ID))
+			continue
+		}
+		seen[s.ID] = true
+		valid = append(valid, s)
+	}
+
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	for id := range e.extras {
+		if id != reverseProxyID && !seen[id] {
+			e.removeListenerLocked(id)
+		}
+	}
+	for _, s := range valid {
+		errs = append(errs, e.upsertListenerLocked(s))
+	}
+	return errors.Join(errs...)
+}
+
+// upsertListenerLocked 新增或更新一个附加监听端。调用方持有 extraMu。
+func (e *Engine) upsertListenerLocked(spec ListenerSpec) error {
+	httpproc.SetListenerPolicy(spec.ID, spec.policy())
+	if cur, ok := e.extras[spec.ID]; ok {
+		if cur.spec.sameBinding(&spec) {
+			cur.spec = spec
+			return nil
+		}
+		e.removeListenerLocked(spec.ID)
+		httpproc.SetListenerPolicy(spec.ID, spec.policy())
+	}
+	x := &extraListener{spec: spec}
+	e.extras[spec.ID] = x
+	if !e.running {
+		return nil
+	}
+	return e.startListenerLocked(x)
+}
+
+// removeListenerLocked 停止并移除一个附加监听端。调用方持有 extraMu。
+func (e *Engine) removeListenerLocked(id string) {
+	x, ok := e.extras[id]
+	if !ok {
+		return
+	}
+	if x.listener != nil {
+		_ = x.listener.Stop()
+	}
+	delete(e.extras, id)
+	httpproc.SetListenerPolicy(id, nil)
+}
+
+// startListenerLocked 按设置新建并启动监听器。调用方持有 extraMu。
+func (e *Engine) startListenerLocked(x *extraListener) error {
+	l := capture.NewTCPListener(&listenConfig{Config: e.config, spec: x.spec})
+	if e.logger != nil {
+		l.SetLogger(e.logger)
+	}
+	if err := l.Start(); err != nil {
+		return fmt.Errorf("启动监听端 %s 失败: %w", x.spec.ID, err)
+	}
+	x.listener = l
+	return nil
+}
+
+// startExtrasLocked 随引擎 Start 启动全部附加监听端,返回各自的启动错误。
+func (e *Engine) startExtrasLocked() error {
+	var errs []error
+	for _, x := range e.extras {
+		if x.listener == nil {
+			errs = append(errs, e.startListenerLocked(x))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopExtrasLocked 随引擎 Stop 停止全部附加监听端,设置保留供下次 Start。
+func (e *Engine) stopExtrasLocked() {
+	for _, x := range e.extras {
+		if x.listener != nil {
+			_ = x.listener.Stop()
+			x.listener = nil
+		}
+	}
+}
+
+// ListenerByID 返回运行中的附加监听端,不存在或未运行时为 nil。
+func (e *Engine) ListenerByID(id string) *capture.TCPListener {
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	if x, ok := e.extras[id]; ok {
+		return x.listener
+	}
+	return nil
+}
+
+// ParseReverseUpstream 校验并解析反向代理的上游基础 URL:须为带主机的 http(s) 地址。
+func ParseReverseUpstream(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("未配置反向代理上游地址")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("反向代理上游须为 http:// 或 https:// 地址: %q", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("反向代理上游缺少主机: %q", raw)
+	}
+	if u.User != nil || u.Fragment != "" {
+		return nil, fmt.Errorf("反向代理上游不能包含账号密码或片段: %q", raw)
+	}
+	return u, nil
+}
+
+// SetReverseProxy 开启(或关闭)反向代理监听端:port 上收到的请求一律转发到 upstream 基础 URL,
+// 照常经过插件管道、重写规则与断点。terminateTLS 时入站先终结 TLS;preserveHost 时保留客户端
+// Host 头。它是 SetListeners 之外单独管理的一个 reverse 模式附加监听端,绑定主监听地址。
+// port 为 0 时由系统分配。
+func (e *Engine) SetReverseProxy(enabled bool, port int, upstream string, terminateTLS, preserveHost bool) error {
+	if !enabled {
+		e.extraMu.Lock()
+		defer e.extraMu.Unlock()
+		e.removeListenerLocked(reverseProxyID)
+		return nil
+	}
+	if port != 0 && port == e.config.GetPort() {
+		return fmt.Errorf("反向代理端口 %d 与代理监听端口冲突", port)
+	}
+	u, err := ParseReverseUpstream(upstream)
+	if err != nil {
+		return err
+	}
+	spec := ListenerSpec{
+		ID:      reverseProxyID,
+		Mode:    capture.ListenerReverse,
+		Port:    port,
+		Reverse: &types.ReverseTarget{Upstream: u, TLS: terminateTLS, PreserveHost: preserveHost},
+	}
+	if err := spec.validate(); err != nil {
+		return err
+	}
+	e.extraMu.Lock()
+	defer e.extraMu.Unlock()
+	return e.upsertListenerLocked(spec)
+}
+
+// ReverseListener 返回运行中的反向代理监听端,未开启时为 nil。
+func (e *Engine) ReverseListener() *capture.TCPListener { return e.ListenerByID(reverseProxyID) }
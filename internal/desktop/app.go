@@ -9,7 +9,11 @@ package desktop
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
+	"strconv"
 	"sync"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -55,16 +59,28 @@ func New(a *app.App) *Bridge {
 }
 
 // applySystemProxy 按开关把系统代理指向引擎实际监听端口(127.0.0.1:port)或释放。
-// 启用时先乐观置位,确保即便部分网络服务设置失败,退出时也会尝试清理。
+// 开启 PAC 且选择「系统代理用 PAC」时改设自动代理配置 URL,并撤掉此前指向本程序的固定代理;
+// 反之亦然。启用时先乐观置位,确保即便部分网络服务设置失败,退出时也会尝试清理。
 func (b *Bridge) applySystemProxy(enabled bool) error {
 	var err error
+	port := b.app.Engine.Config().GetPort()
 	if enabled {
 		b.setSysProxyOn(true)
-		err = sysproxy.Set(sysProxyHost, b.app.Engine.Config().GetPort())
+		if b.sysProxyPAC() {
+			if sysproxy.PointsTo(sysProxyHost, port) {
+				err = sysproxy.Clear()
+			}
+			err = errors.Join(err, sysproxy.SetPAC(sysProxyPACURL(port)))
+		} else {
+			if sysproxy.PointsToPAC(sysProxyPACURL(port)) {
+				err = sysproxy.ClearPAC()
+			}
+			err = errors.Join(err, sysproxy.Set(sysProxyHost, port))
+		}
 	} else {
 		// 仅在确实清除成功后才置位:Clear 失败时保持 sysProxyOn=true,让退出钩子兜底重试,
 		// 避免系统代理残留指向已失效端口。
-		if err = sysproxy.Clear(); err == nil {
+		if err = b.clearSystemProxy(port); err == nil {
 			b.setSysProxyOn(false)
 		}
 	}
@@ -74,6 +90,35 @@ func (b *Bridge) applySystemProxy(enabled bool) error {
 	return err
 }
 
+// sysProxyPAC 报告系统代理是否应设为 PAC URL:需同时开启 PAC 脚本与「系统代理用 PAC」。
+func (b *Bridge) sysProxyPAC() bool {
+	c := b.app.Service.Config()
+	return c.PAC && c.SystemProxyPAC
+}
+
+// sysProxyPACURL 是系统代理使用的 PAC 地址,由代理端口直接提供。
+func sysProxyPACURL(port int) string {
+	return fmt.Sprintf("http://%s/proxy.pac", net.JoinHostPort(sysProxyHost, strconv.Itoa(port)))
+}
+
+// sysProxyPointsHere 报告系统代理(固定代理或 PAC URL)当前是否指向本程序。
+func sysProxyPointsHere(port int) bool {
+	return sysproxy.PointsTo(sysProxyHost, port) || sysproxy.PointsToPAC(sysProxyPACURL(port))
+}
+
+// clearSystemProxy 释放系统代理:撤掉指向本程序的 PAC URL;固定代理沿用历史行为直接关闭,
+// 但 PAC 模式下只在它指向本程序时关闭,以免动到用户自己的设置。
+func (b *Bridge) clearSystemProxy(port int) error {
+	var errs []error
+	if sysproxy.PointsToPAC(sysProxyPACURL(port)) {
+		errs = append(errs, sysproxy.ClearPAC())
+	}
+	if !b.sysProxyPAC() || sysproxy.PointsTo(sysProxyHost, port) {
+		errs = append(errs, sysproxy.Clear())
+	}
+	return errors.Join(errs...)
+}
+
 func (b *Bridge) setSysProxyOn(v bool) {
 	b.sysProxyMu.Lock()
 	b.sysProxyOn = v
@@ -94,9 +139,9 @@ func (b *Bridge) ServiceStartup(_ context.Context, _ application.ServiceOptions)
 	auto := b.app.Service.Config().AutoProxy
 	if auto {
 		_ = b.applySystemProxy(true)
-	} else if sysproxy.PointsTo(sysProxyHost, b.app.Engine.Config().GetPort()) {
+	} else if port := b.app.Engine.Config().GetPort(); sysProxyPointsHere(port) {
 		// 上次异常退出可能残留指向本程序的系统代理;本次不自动启用,清掉以保持一致。
-		if err := sysproxy.Clear(); err != nil {
+		if err := b.clearSystemProxy(port); err != nil {
 			b.app.Logger.Warn("清理残留系统代理失败: %v", err)
 		}
 	}
@@ -120,10 +165,11 @@ func (b *Bridge) releaseSystemProxy() {
 	b.sysProxyMu.Lock()
 	on := b.sysProxyOn
 	b.sysProxyMu.Unlock()
-	if !on && !sysproxy.PointsTo(sysProxyHost, b.app.Engine.Config().GetPort()) {
+	port := b.app.Engine.Config().GetPort()
+	if !on && !sysProxyPointsHere(port) {
 		return
 	}
-	if err := sysproxy.Clear(); err != nil {
+	if err := b.clearSystemProxy(port); err != nil {
 		b.app.Logger.Warn("退出时清除系统代理失败: %v", err)
 		return
 	}
@@ -208,6 +254,16 @@ func (b *Bridge) GetWSSession(id string) *service.WSSessionDTOType {
 	return &s
 }
 
+// SendWSFrame 向打开中的 WebSocket 会话注入一帧(文本/二进制/ping/pong/close,任一方向)。
+func (b *Bridge) SendWSFrame(sessionID string, in service.WSFrameInput) error {
+	return b.app.Service.SendWSFrame(sessionID, in)
+}
+
+// ResendWSMessage 把会话中一条已捕获的消息按原方向再发一次。
+func (b *Bridge) ResendWSMessage(sessionID, messageID string) error {
+	return b.app.Service.ResendWSMessage(sessionID, messageID)
+}
+
 // StreamSessionPage 是分页流式会话返回。
 type StreamSessionPage struct {
 	Data  []service.StreamSessionDTOType `json:"data"`
@@ -229,6 +285,69 @@ func (b *Bridge) GetStreamSession(id string) *service.StreamSessionDTOType {
 	return &s
 }
 
+// TCPSessionPage 是分页 TCP 会话返回。
+type TCPSessionPage struct {
+	Data  []service.TCPSessionDTOType `json:"data"`
+	Total int                         `json:"total"`
+}
+
+// GetTCPSessions 回填已捕获的原始 TCP 中继会话(实时更新经 tcp_session 事件推送)。
+func (b *Bridge) GetTCPSessions(page, pageSize int) TCPSessionPage {
+	list, total := b.app.Service.TCPSessions(page, pageSize)
+	return TCPSessionPage{Data: list, Total: total}
+}
+
+// GetTCPSession 返回单个 TCP 会话(含保留的全部分块)。
+func (b *Bridge) GetTCPSession(id string) *service.TCPSessionDTOType {
+	s, ok := b.app.Service.TCPSession(id)
+	if !ok {
+		return nil
+	}
+	return &s
+}
+
+// MQTTSessionPage 是分页 MQTT 会话返回。
+type MQTTSessionPage struct {
+	Data  []service.MQTTSessionDTOType `json:"data"`
+	Total int                          `json:"total"`
+}
+
+// GetMQTTSessions 回填已捕获的 MQTT 会话(实时更新经 mqtt_session 事件推送)。
+func (b *Bridge) GetMQTTSessions(page, pageSize int) MQTTSessionPage {
+	list, total := b.app.Service.MQTTSessions(page, pageSize)
+	return MQTTSessionPage{Data: list, Total: total}
+}
+
+// GetMQTTSession 返回单个 MQTT 会话(含保留的全部报文)。
+func (b *Bridge) GetMQTTSession(id string) *service.MQTTSessionDTOType {
+	s, ok := b.app.Service.MQTTSession(id)
+	if !ok {
+		return nil
+	}
+	return &s
+}
+
+// DNSQueryPage 是分页 DNS 查询返回。
+type DNSQueryPage struct {
+	Data  []service.DNSQueryDTOType `json:"data"`
+	Total int                       `json:"total"`
+}
+
+// GetDNSQueries 回填内置 DNS 服务端记录的查询(实时更新经 dns_query 事件推送)。
+func (b *Bridge) GetDNSQueries(page, pageSize int) DNSQueryPage {
+	list, total := b.app.Service.DNSQueries(page, pageSize)
+	return DNSQueryPage{Data: list, Total: total}
+}
+
+// GetDNSQuery 返回单条 DNS 查询记录。
+func (b *Bridge) GetDNSQuery(id string) *service.DNSQueryDTOType {
+	q, ok := b.app.Service.DNSQuery(id)
+	if !ok {
+		return nil
+	}
+	return &q
+}
+
 func (b *Bridge) GetStatistics() service.StatisticsDTO { return b.app.Service.Statistics() }
 
 func (b *Bridge) GetConfig() service.ConfigView { return service.PublicConfig(b.app.Service.Config()) }
@@ -312,6 +431,65 @@ func (b *Bridge) ImportServerCert(certPEM, keyPEM string) (*service.ServerCertDT
 // DeleteServerCert 按证书指纹删除导入证书。
 func (b *Bridge) DeleteServerCert(id string) { b.app.Service.DeleteServerCert(id) }
 
+// ---- 上游客户端证书(mTLS) ----
+
+// GetClientCerts 返回出示给上游的客户端证书摘要(不含私钥)。
+func (b *Bridge) GetClientCerts() []service.ClientCertDTO { return b.app.Service.ClientCerts() }
+
+// ImportClientCert 校验并保存一张 PEM 客户端证书 + 私钥,对 hosts 匹配的上游生效;失败 reject。
+func (b *Bridge) ImportClientCert(certPEM, keyPEM string, hosts []string) (*service.ClientCertDTO, error) {
+	dto, err := b.app.Service.ImportClientCert(certPEM, keyPEM, hosts)
+	if err != nil {
+		return nil, err
+	}
+	return &dto, nil
+}
+
+// ImportClientCertPKCS12 同 ImportClientCert,证书与私钥取自 PKCS#12(data 为 base64)。
+func (b *Bridge) ImportClientCertPKCS12(data, password string, hosts []string) (*service.ClientCertDTO, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS#12 不是有效的 base64: %w", err)
+	}
+	dto, err := b.app.Service.ImportClientCertPKCS12(raw, password, hosts)
+	if err != nil {
+		return nil, err
+	}
+	return &dto, nil
+}
+
+// DeleteClientCert 按证书指纹删除客户端证书。
+func (b *Bridge) DeleteClientCert(id string) { b.app.Service.DeleteClientCert(id) }
+
+// GetAutoBypassHosts 返回因 MITM 握手反复失败而自动直通的主机。
+func (b *Bridge) GetAutoBypassHosts() []service.AutoBypassHostDTO {
+	return b.app.Service.AutoBypassHosts()
+}
+
+// ResetAutoBypass 把主机移出自动绕过列表;host 为空时清空全部。
+func (b *Bridge) ResetAutoBypass(host string) { b.app.Service.ResetAutoBypass(host) }
+
+// ---- protobuf 描述符集(解码 gRPC 消息) ----
+
+// GetProtoSets 返回已上传的描述符集摘要。
+func (b *Bridge) GetProtoSets() []service.ProtoSetDTO { return b.app.Service.ProtoSets() }
+
+// ImportProtoSet 校验并保存一个 FileDescriptorSet(data 为 base64),同名覆盖;无效时 reject。
+func (b *Bridge) ImportProtoSet(name, data string) (*service.ProtoSetDTO, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("描述符集不是有效的 base64: %w", err)
+	}
+	dto, err := b.app.Service.ImportProtoSet(name, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &dto, nil
+}
+
+// DeleteProtoSet 按名称删除描述符集。
+func (b *Bridge) DeleteProtoSet(name string) { b.app.Service.DeleteProtoSet(name) }
+
 // ---- 插件 ----
 
 func (b *Bridge) GetPlugins() []map[string]any {
@@ -398,6 +576,34 @@ func (b *Bridge) GetGlobalBreak() GlobalBreakState {
 	return GlobalBreakState{OnRequest: onReq, OnResponse: onResp}
 }
 
+// ResumeWSBreakpoint 放行暂停的 WebSocket 消息;data 为 nil 表示不改,binary 时为 base64。
+func (b *Bridge) ResumeWSBreakpoint(id string, data *string, binary bool) (bool, error) {
+	var payload []byte
+	if data != nil {
+		payload = []byte(*data)
+		if binary {
+			decoded, err := base64.StdEncoding.DecodeString(*data)
+			if err != nil {
+				return false, fmt.Errorf("二进制内容须为 base64: %w", err)
+			}
+			payload = decoded
+		}
+		if payload == nil {
+			payload = []byte{}
+		}
+	}
+	return b.app.Pipeline.Breakpoints().ResumeWS(id, payload), nil
+}
+func (b *Bridge) GetWSBreakpoints() []*flow.WSMessage {
+	return b.app.Pipeline.Breakpoints().ListWS()
+}
+func (b *Bridge) SetWebSocketBreak(on bool) {
+	b.app.Pipeline.Breakpoints().SetWebSocketBreak(on)
+}
+func (b *Bridge) GetWebSocketBreak() bool {
+	return b.app.Pipeline.Breakpoints().WebSocketBreak()
+}
+
 // ---- URL 断点规则 ----
 
 func (b *Bridge) GetBreakRules() []*pipeline.BreakRule {
@@ -414,3 +620,9 @@ func (b *Bridge) ToggleBreakRule(id string, enabled bool) bool {
 	return ok
 }
 func (b *Bridge) DeleteBreakRule(id string) { b.app.Pipeline.Breakpoints().DeleteRule(id) }
+
+// SetBreakRuleWebSocket 设置规则是否断 WebSocket 消息及其方向/内容过滤。
+func (b *Bridge) SetBreakRuleWebSocket(id string, on bool, direction, content string) bool {
+	_, ok := b.app.Pipeline.Breakpoints().SetRuleWebSocket(id, on, direction, content)
+	return ok
+}
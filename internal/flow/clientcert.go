@@ -0,0 +1,61 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+)
+
+// MetaClientCert 是 Flow.Metadata 中按主机选用的上游客户端证书的键,值为 *ClientCertInfo。
+const MetaClientCert = "clientCert"
+
+// ClientCertInfo 是对上游出示的客户端证书(mTLS)摘要。
+type ClientCertInfo struct {
+	ID      string `json:"id"` // 叶子证书 DER 的 SHA-256 hex
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+}
+
+// ClientCertInfoOf 由证书对计算摘要;c 为 nil 或叶子证书无法解析时返回 nil。
+func ClientCertInfoOf(c *tls.Certificate) *ClientCertInfo {
+	if c == nil || len(c.Certificate) == 0 {
+		return nil
+	}
+	leaf := c.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			return nil
+		}
+		leaf = parsed
+	}
+	sum := sha256.Sum256(leaf.Raw)
+	return &ClientCertInfo{
+		ID:      hex.EncodeToString(sum[:]),
+		Subject: leaf.Subject.CommonName,
+		Issuer:  leaf.Issuer.CommonName,
+	}
+}
+
+// SetClientCert 把选用的上游客户端证书记入 Metadata。
+func (f *Flow) SetClientCert(c *ClientCertInfo) {
+	if c == nil {
+		return
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata[MetaClientCert] = c
+}
+
+// ClientCert 返回为本请求选用的上游客户端证书;未配置或无匹配时为 nil。
+func (f *Flow) ClientCert() *ClientCertInfo {
+	c, _ := f.Metadata[MetaClientCert].(*ClientCertInfo)
+	return c
+}
@@ -0,0 +1,37 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import "time"
+
+// DNS 查询记录的数据契约。
+//
+// 内置 DNS 服务端收到的每个查询记为一条 DNSQuery:问题、应答摘要,以及应答来自本地覆盖
+// 表还是上游解析器。设备只需把 DNS 指向 Sniffy,即可看到它解析了哪些域名。
+
+// DNSAnswer 是应答中的一条资源记录摘要。
+type DNSAnswer struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // A|AAAA|CNAME|...
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"` // 地址或目标域名;不认识的类型为空
+}
+
+// DNSQuery 表示一次 DNS 查询及其应答(用于 UI 展示与存储)。
+type DNSQuery struct {
+	ID         string      `json:"id"`
+	ClientAddr string      `json:"clientAddr"`
+	Transport  string      `json:"transport"` // udp|tcp
+	Name       string      `json:"name"`      // 查询域名,不含结尾的点
+	Type       string      `json:"type"`      // 查询类型,如 A、AAAA、HTTPS
+	RCode      string      `json:"rcode"`     // 应答码,如 NOERROR、NXDOMAIN、SERVFAIL
+	Answers    []DNSAnswer `json:"answers"`
+	Overridden bool        `json:"overridden,omitempty"` // 应答来自本地覆盖表
+	Upstream   string      `json:"upstream,omitempty"`   // 转发到的上游解析器
+	Error      string      `json:"error,omitempty"`
+	StartTime  time.Time   `json:"startTime"`
+	DurationMs int64       `json:"durationMs"`
+}
@@ -28,15 +28,17 @@ const (
 	StateBlocked            FlowState = "blocked"              // 被插件 abort 阻断
 	StateMocked             FlowState = "mocked"               // 由插件 mock 直接响应(未打上游)
 	StateErrored            FlowState = "errored"              // 处理过程中出错
+	StateCertRejected       FlowState = "cert_rejected"        // 上游证书校验未通过,请求未发出
 	StatePausedAtBreakpoint FlowState = "paused_at_breakpoint" // 命中断点,等待 UI 手动放行
 )
 
-// Phase 表示拦截发生的阶段(请求 / 响应)。
+// Phase 表示拦截发生的阶段(请求 / 响应 / WebSocket 消息)。
 type Phase string
 
 const (
 	PhaseRequest  Phase = "request"
 	PhaseResponse Phase = "response"
+	PhaseMessage  Phase = "message" // WebSocket 单条消息
 )
 
 // Protocol 取值。
@@ -51,6 +53,7 @@ const (
 type Flow struct {
 	ID       string         `json:"id"`                 // 请求读入时生成,替代脆弱的 URL 配对
 	ConnID   string         `json:"connId,omitempty"`   // 所属连接,用于分组与 WebSocket
+	Listener string         `json:"listener,omitempty"` // 到达的附加监听端标识,主监听端为空
 	Protocol string         `json:"protocol"`           // http|https|ws|wss
 	Request  *Request       `json:"request"`            //
 	Response *Response      `json:"response,omitempty"` // 上游响应或 mock 之前为 nil
@@ -86,7 +89,7 @@ type Request struct {
 	ClientIP string              `json:"clientIp,omitempty"`
 
 	// RawHeaders 是客户端线上原始请求头序列(保留顺序与原始大小写,含重复头)。
-	// 仅在读取侧(HTTP/1.x)抓得到;h2 入站或头部过大时为空。Header 是供插件/UI/规则
+	// 由读取侧(HTTP/1.x)或 h2 帧旁路抓取,后者头名为小写;头部过大时为空。Header 是供插件/UI/规则
 	// 编辑的规范化视图,RawHeaders 仅用于出站时按原样回放顺序/大小写,二者不互相覆盖。
 	RawHeaders [][2]string `json:"rawHeaders,omitempty"`
 
@@ -208,6 +211,7 @@ type Timing struct {
 	CompletedAt time.Time `json:"completedAt,omitempty"`
 	DurationMs  int64     `json:"durationMs,omitempty"`
 	TTFBMs      int64     `json:"ttfbMs,omitempty"`
+	Phases      *Phases   `json:"phases,omitempty"` // 上游请求的分阶段耗时;只整体替换,Clone 可共享
 }
 
 // NewID 生成一个 16 字节的随机十六进制 ID。
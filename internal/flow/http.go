@@ -12,6 +12,7 @@ import (
 	"net/textproto"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // 这些转换函数是 Flow 契约与 net/http 之间的桥,集中处理 MITM 改写 body 的正确性。
@@ -59,7 +60,12 @@ func BuildRequestFlow(req *http.Request, protocol string) (*Flow, error) {
 		Body:     decoded,
 		ClientIP: req.RemoteAddr,
 	}
-	// 读取侧抓到的原始头序列(顺序+大小写),供出站时按原样回放。h2/超大头时为空。
+	f.Listener = ListenerFrom(req.Context())
+	if fp, ok := TLSFingerprintFrom(req.Context()); ok {
+		f.SetTLSFingerprint(fp)
+	}
+	// 读取侧抓到的原始头序列(顺序+大小写),供出站时按原样回放。超大头时为空;h2 入站的
+	// 头名是线上的小写形式。
 	if rawHdr, ok := RawHeadersFrom(req.Context()); ok {
 		f.Request.RawHeaders = rawHdr
 	}
@@ -74,9 +80,9 @@ func BuildRequestFlow(req *http.Request, protocol string) (*Flow, error) {
 // ApplyRequestToHTTP 把(可能被插件改过的)Flow.Request 写回 *http.Request,
 // 以 identity 方式重建 body 并修正长度/编码头,供转发上游。
 //
-// 返回最终要发送的 *http.Request:当读取侧抓到了原始头序列(HTTP/1.x)时,会把
-// 「最终线缆头序列」经 ctx 附在请求上,供保真转发器(internal/forward)按原样写线
-// (顺序+大小写保真);其余情形(h2 入站、合成请求)退化为标准 net/http 行为。
+// 返回最终要发送的 *http.Request:当读取侧抓到了原始头序列(HTTP/1.x,或 h2 入站的
+// 帧旁路)时,会把「最终线缆头序列」经 ctx 附在请求上,供保真转发器(internal/forward)
+// 按原样写线(顺序+大小写保真);其余情形(合成请求等)退化为标准 net/http 行为。
 // 因 ctx 的写入会复制 *http.Request,调用方必须改用返回的请求。
 func ApplyRequestToHTTP(f *Flow, req *http.Request) *http.Request {
 	r := f.Request
@@ -167,6 +173,7 @@ func CaptureResponseToFlow(f *Flow, resp *http.Response) {
 		raw, _ = io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 	}
+	readAt := time.Now()
 	ce := resp.Header.Get("Content-Encoding")
 	decoded, was := DecodeBody(raw, ce)
 	if was {
@@ -186,12 +193,21 @@ func CaptureResponseToFlow(f *Flow, resp *http.Response) {
 
 	// 保真写回客户端所需:上游响应原始状态行/头序列(由转发器经 ctx 回填),
 	// 以及原始编码体(供 body 未改动时原样回放)。回退 / h2 / mock 时收集器为空。
+	// 转发器另回填上游连接实际发出的 ClientHello 指纹、选用的客户端证书、TLS 连接摘要与上游路由,
+	// 一并记入 Metadata;分阶段计时记入 Timing。
 	if resp.Request != nil {
-		if rc, ok := ResponseCaptureFrom(resp.Request.Context()); ok && len(rc.Headers) > 0 {
-			f.Response.RawHeaders = rc.Headers
-			f.Response.SetOriginalHead(rc.StatusLine)
-			if ce != "" && len(raw) > 0 {
-				f.Response.SetOriginalBody(raw, decoded, ce)
+		if rc, ok := ResponseCaptureFrom(resp.Request.Context()); ok {
+			f.SetUpstreamTLSFingerprint(rc.UpstreamTLS)
+			f.SetClientCert(rc.ClientCert)
+			f.SetUpstreamConn(rc.UpstreamConn)
+			f.SetUpstreamRoute(rc.Route)
+			f.SetPhases(rc.Trace.Phases(readAt))
+			if len(rc.Headers) > 0 {
+				f.Response.RawHeaders = rc.Headers
+				f.Response.SetOriginalHead(rc.StatusLine)
+				if ce != "" && len(raw) > 0 {
+					f.Response.SetOriginalBody(raw, decoded, ce)
+				}
 			}
 		}
 	}
@@ -0,0 +1,73 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import "time"
+
+// MQTT 会话的数据契约。
+//
+// 识别为 MQTT 3.1 / 3.1.1 / 5 的连接在中继时逐个解析控制报文。仿照 WSSession,用
+// MQTTSession 承载「一条连接的报文时间线」:每个控制报文记为一条 MQTTMessage,Direction
+// 复用 WS 的取值,PUBLISH 额外带主题、QoS 与载荷。
+
+// MQTT 控制报文类型。
+const (
+	MQTTConnect     = "CONNECT"
+	MQTTConnAck     = "CONNACK"
+	MQTTPublish     = "PUBLISH"
+	MQTTPubAck      = "PUBACK"
+	MQTTPubRec      = "PUBREC"
+	MQTTPubRel      = "PUBREL"
+	MQTTPubComp     = "PUBCOMP"
+	MQTTSubscribe   = "SUBSCRIBE"
+	MQTTSubAck      = "SUBACK"
+	MQTTUnsubscribe = "UNSUBSCRIBE"
+	MQTTUnsubAck    = "UNSUBACK"
+	MQTTPingReq     = "PINGREQ"
+	MQTTPingResp    = "PINGRESP"
+	MQTTDisconnect  = "DISCONNECT"
+	MQTTAuth        = "AUTH"
+)
+
+// MQTTMessage 表示一个 MQTT 控制报文。
+type MQTTMessage struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	URL       string    `json:"url,omitempty"` // mqtt://broker/topic,供插件按 URL 门控
+	Direction string    `json:"direction"`     // client->server | server->client
+	Type      string    `json:"type"`          // CONNECT|PUBLISH|SUBSCRIBE|...
+	Topic     string    `json:"topic,omitempty"`
+	Topics    []string  `json:"topics,omitempty"` // SUBSCRIBE / UNSUBSCRIBE 的主题过滤器
+	QoS       byte      `json:"qos"`
+	Retain    bool      `json:"retain,omitempty"`
+	Dup       bool      `json:"dup,omitempty"`
+	PacketID  uint16    `json:"packetId,omitempty"`
+	Payload   []byte    `json:"payload,omitempty"`   // PUBLISH 载荷,可能被截断
+	Size      int       `json:"size"`                // 报文总字节数(含固定头)
+	Truncated bool      `json:"truncated,omitempty"` // Payload 只保留了前缀
+	Dropped   bool      `json:"dropped,omitempty"`   // 被插件丢弃,未转发
+	Modified  bool      `json:"modified,omitempty"`  // 载荷被插件改写
+	Timestamp time.Time `json:"timestamp"`
+	Seq       int       `json:"seq"` // 在本会话内的序号(从 0 递增)
+}
+
+// MQTTSession 表示一条 MQTT 连接的中继记录(用于 UI 展示与存储)。
+type MQTTSession struct {
+	ID              string        `json:"id"`
+	ClientAddr      string        `json:"clientAddr"`
+	Broker          string        `json:"broker"`             // 中继目标 host:port
+	Listener        string        `json:"listener,omitempty"` // 到达的附加监听端标识,主监听端为空
+	ClientID        string        `json:"clientId,omitempty"`
+	Username        string        `json:"username,omitempty"`
+	ProtocolVersion byte          `json:"protocolVersion,omitempty"` // 3=3.1,4=3.1.1,5=5.0
+	Status          string        `json:"status"`                    // open|closed|error
+	Error           string        `json:"error,omitempty"`
+	StartTime       time.Time     `json:"startTime"`
+	EndTime         *time.Time    `json:"endTime,omitempty"`
+	MessageCount    int           `json:"messageCount"` // 累计报文数(Messages 只保留最近一段)
+	Messages        []MQTTMessage `json:"messages"`
+	Process         *ProcessInfo  `json:"process,omitempty"`
+}
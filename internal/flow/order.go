@@ -17,6 +17,8 @@ import (
 //     存入 Flow.Request.RawHeaders。
 //   - ApplyRequestToHTTP 把(可能被插件改过的)头值表与原始顺序合并成最终线缆序列,
 //     再经 ctx 交给保真转发器(internal/forward)按原样写线。
+//   - h2 入站由帧旁路另外抓取伪头顺序、SETTINGS 等连接特征(H2Fingerprint),同样经 ctx
+//     交给转发器,在上游 h2 连接上回放。
 //
 // net/http 的 Header 是 map、写出时按字母排序并规范化名字大小写,无任何 hook 可改,
 // 故保真只能绕开它:把「顺序+大小写」作为旁路信息单独保存与回放。
@@ -24,18 +26,43 @@ import (
 type rawHeadersKeyT struct{}
 type orderedHeadersKeyT struct{}
 type respCaptureKeyT struct{}
+type listenerKeyT struct{}
+type h2FingerprintKeyT struct{}
 
 var (
 	rawHeadersKey     rawHeadersKeyT
 	orderedHeadersKey orderedHeadersKeyT
 	respCaptureKey    respCaptureKeyT
+	listenerKey       listenerKeyT
+	h2FingerprintKey  h2FingerprintKeyT
 )
 
+// WithListener 把请求到达的监听端标识放进 ctx,BuildRequestFlow 据此标记 Flow.Listener。
+func WithListener(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, listenerKey, id)
+}
+
+// ListenerFrom 取出监听端标识(未装入时为空串)。
+func ListenerFrom(ctx context.Context) string {
+	id, _ := ctx.Value(listenerKey).(string)
+	return id
+}
+
 // ResponseCapture 收集上游响应的原始状态行与头序列(顺序+大小写),由保真转发器
 // (internal/forward)在读到响应头时填充,供响应写回客户端时按原样回放。经请求 ctx 传递。
 type ResponseCapture struct {
 	StatusLine string      // 如 "HTTP/1.1 200 OK"
 	Headers    [][2]string // 原始头序列
+	// UpstreamTLS 是承载本请求的上游连接发出的 ClientHello 指纹(https 且经保真转发时)。
+	UpstreamTLS *TLSFingerprint
+	// ClientCert 是按主机选用、上游要求时出示的客户端证书(https 且配置了匹配的证书时)。
+	ClientCert *ClientCertInfo
+	// UpstreamConn 是承载本请求的上游 TLS 连接摘要(版本、套件、ALPN、证书链与校验结论)。
+	UpstreamConn *UpstreamConn
+	// Trace 收集上游请求的分阶段时间点(DNS、建连、TLS、发送、等待),由转发器装入。
+	Trace *PhaseTrace
+	// Route 是本请求选用的上游路由:RouteDirect 或不含凭据的代理地址。
+	Route string
 }
 
 // WithResponseCapture 在请求 ctx 中装入响应头收集器,供转发器读到响应头时回填。
@@ -65,7 +92,7 @@ func WithOrderedHeaders(ctx context.Context, ordered [][2]string) context.Contex
 	return context.WithValue(ctx, orderedHeadersKey, ordered)
 }
 
-// OrderedHeadersFrom 取出最终线缆头序列;缺省(如 h2 入站、头部过大无法保真)返回 false,
+// OrderedHeadersFrom 取出最终线缆头序列;缺省(如头部过大无法保真、合成请求)返回 false,
 // 转发器据此回退到标准 net/http。
 func OrderedHeadersFrom(ctx context.Context) ([][2]string, bool) {
 	v, ok := ctx.Value(orderedHeadersKey).([][2]string)
@@ -75,6 +102,40 @@ func OrderedHeadersFrom(ctx context.Context) ([][2]string, bool) {
 	return v, true
 }
 
+// H2Setting 是客户端 SETTINGS 帧里的一项参数。
+type H2Setting struct {
+	ID  uint16
+	Val uint32
+}
+
+// H2Priority 是 HEADERS 帧携带的流优先级(Weight 为线缆值 0~255)。
+type H2Priority struct {
+	StreamDep uint32
+	Exclusive bool
+	Weight    uint8
+}
+
+// H2Fingerprint 是 h2 入站请求的线缆特征:连接前言后的 SETTINGS(按发送顺序)、连接级
+// WINDOW_UPDATE 增量,以及本请求 HEADERS 帧的伪头顺序与优先级。普通头的顺序与 HTTP/1.x
+// 一样经 RawHeaders / OrderedHeaders 传递,这里只补 h2 特有的部分。
+type H2Fingerprint struct {
+	Settings     []H2Setting
+	WindowUpdate uint32      // 0 表示客户端未发送连接级 WINDOW_UPDATE
+	PseudoOrder  []string    // 如 [":method", ":authority", ":scheme", ":path"]
+	Priority     *H2Priority // HEADERS 未带优先级时为 nil
+}
+
+// WithH2Fingerprint 把 h2 入站请求的线缆特征放进 ctx,保真转发器据此走 h2 上游并按原样回放。
+func WithH2Fingerprint(ctx context.Context, fp *H2Fingerprint) context.Context {
+	return context.WithValue(ctx, h2FingerprintKey, fp)
+}
+
+// H2FingerprintFrom 取出 h2 线缆特征;非 h2 入站或未能抓取时返回 false。
+func H2FingerprintFrom(ctx context.Context) (*H2Fingerprint, bool) {
+	fp, ok := ctx.Value(h2FingerprintKey).(*H2Fingerprint)
+	return fp, ok && fp != nil
+}
+
 // reconcileOrderedHeaders 把原始头序列(顺序+大小写)与当前头值表(可能被插件改过)
 // 合并成最终线缆序列:
 //   - 沿原始顺序逐项,用当前值回填、原样保留名字大小写;
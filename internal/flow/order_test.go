@@ -6,6 +6,7 @@
 package flow
 
 import (
+	"context"
 	"net/http"
 	"reflect"
 	"testing"
@@ -183,7 +184,7 @@ func TestUASentinelOnFallbackPath(t *testing.T) {
 	}
 }
 
-// TestNoRawHeadersNoOrdered 没有原始头序列(如 h2 入站)时不产出保真序列,转发器据此回退。
+// TestNoRawHeadersNoOrdered 没有原始头序列(如合成请求)时不产出保真序列,转发器据此回退。
 func TestNoRawHeadersNoOrdered(t *testing.T) {
 	f := New(ProtoHTTPS)
 	f.Request = &Request{
@@ -198,3 +199,35 @@ func TestNoRawHeadersNoOrdered(t *testing.T) {
 		t.Fatalf("无 RawHeaders 不应产出保真头序列")
 	}
 }
+
+// TestOrderedH2Inbound h2 入站:小写头名原样保留,Host 追加在尾部(转发器将其并入 :authority),
+// h2 线缆特征随 ctx 一并带到出站请求。
+func TestOrderedH2Inbound(t *testing.T) {
+	f := New(ProtoHTTPS)
+	f.Request = &Request{
+		Method: http.MethodGet,
+		URL:    "https://h/",
+		Host:   "h",
+		Header: map[string][]string{"User-Agent": {"x"}, "Accept": {"*/*"}},
+		RawHeaders: [][2]string{
+			{"user-agent", "x"},
+			{"accept", "*/*"},
+		},
+	}
+	fp := &H2Fingerprint{PseudoOrder: []string{":method", ":authority", ":scheme", ":path"}}
+	req, _ := http.NewRequest(http.MethodGet, "https://h/", nil)
+	req = req.WithContext(WithH2Fingerprint(req.Context(), fp))
+	req = ApplyRequestToHTTP(f, req)
+
+	got, _ := OrderedHeadersFrom(req.Context())
+	want := [][2]string{{"user-agent", "x"}, {"accept", "*/*"}, {"Host", "h"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("h2 头序列不符:\n got=%v\nwant=%v", got, want)
+	}
+	if gotFP, ok := H2FingerprintFrom(req.Context()); !ok || gotFP != fp {
+		t.Fatal("h2 线缆特征应随 ctx 保留")
+	}
+	if _, ok := H2FingerprintFrom(context.Background()); ok {
+		t.Fatal("空 ctx 不应取到 h2 线缆特征")
+	}
+}
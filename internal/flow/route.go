@@ -0,0 +1,29 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+// MetaUpstreamRoute 是 Flow.Metadata 中上游路由的键,值为 string。
+const MetaUpstreamRoute = "upstreamRoute"
+
+// RouteDirect 表示请求直连源站、未经上游代理。
+const RouteDirect = "DIRECT"
+
+// SetUpstreamRoute 记下请求选用的上游路由(RouteDirect 或不含凭据的代理地址);空串忽略。
+func (f *Flow) SetUpstreamRoute(route string) {
+	if route == "" {
+		return
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata[MetaUpstreamRoute] = route
+}
+
+// UpstreamRoute 返回请求选用的上游路由;未经转发器(mock、拦截等)时为空。
+func (f *Flow) UpstreamRoute() string {
+	r, _ := f.Metadata[MetaUpstreamRoute].(string)
+	return r
+}
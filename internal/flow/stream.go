@@ -16,11 +16,18 @@ import "time"
 
 // StreamKind 标识流的类型(决定消息如何分帧/展示)。
 const (
-	StreamSSE   = "sse"   // text/event-stream:服务端推送事件
-	StreamGRPC  = "grpc"  // application/grpc:h2 上的 length-prefixed 消息(可双向)
-	StreamChunk = "chunk" // 通用分块流(NDJSON / application/stream+json / 不定长 chunked)
+	StreamSSE     = "sse"      // text/event-stream:服务端推送事件
+	StreamGRPC    = "grpc"     // application/grpc:h2 上的 length-prefixed 消息(可双向)
+	StreamGRPCWeb = "grpc-web" // application/grpc-web(-text):浏览器 gRPC,尾部以帧的形式嵌在 body 里
+	StreamConnect = "connect"  // Connect 协议:application/connect+* 信封流,或 unary 的单条消息
+	StreamChunk   = "chunk"    // 通用分块流(NDJSON / application/stream+json / 不定长 chunked)
 )
 
+// IsRPCStream 报告 kind 是否为按 RPC 消息分帧的流(gRPC / gRPC-Web / Connect)。
+func IsRPCStream(kind string) bool {
+	return kind == StreamGRPC || kind == StreamGRPCWeb || kind == StreamConnect
+}
+
 // StreamMessage 表示一条流消息(单向一帧)。Direction 复用 WS 的取值,便于前端统一展示:
 //   - server->client:响应方向(SSE 事件、gRPC 服务端消息)。
 //   - client->server:请求方向(gRPC 客户端消息,双向流)。
@@ -30,9 +37,12 @@ type StreamMessage struct {
 	ConnID    string    `json:"connId,omitempty"`    //
 	URL       string    `json:"url,omitempty"`       //
 	Direction string    `json:"direction"`           // client->server | server->client
-	Kind      string    `json:"kind"`                // sse|grpc|chunk
-	EventType string    `json:"eventType,omitempty"` // SSE 的 event: 名;其余为空
+	Kind      string    `json:"kind"`                // sse|grpc|grpc-web|connect|chunk
+	EventType string    `json:"eventType,omitempty"` // SSE 的 event: 名;gRPC-Web 尾部帧为 trailers,Connect 结束帧为 end
+	Encoding  string    `json:"encoding,omitempty"`  // gRPC:压缩帧的 grpc-encoding,此时 Data 为解压后的载荷
 	Data      []byte    `json:"data"`                // SSE:事件原文;gRPC:消息载荷(去 5 字节前缀);chunk:原始分块
+	JSON      string    `json:"json,omitempty"`      // gRPC:protobuf 解码后的 JSON 视图,解不了时为空
+	Schema    string    `json:"schema,omitempty"`    // gRPC:视图的消息类型全名;无描述符时为 "wire"(线缆格式)
 	Timestamp time.Time `json:"timestamp"`           //
 	Seq       int       `json:"seq"`                 // 在本会话内的序号(从 0 递增)
 }
@@ -41,7 +51,7 @@ type StreamMessage struct {
 type StreamSession struct {
 	ID           string          `json:"id"` // == 所属 Flow.ID
 	URL          string          `json:"url"`
-	Kind         string          `json:"kind"`             // sse|grpc|chunk
+	Kind         string          `json:"kind"`             // sse|grpc|grpc-web|connect|chunk
 	Method       string          `json:"method,omitempty"` // 请求方法(GET/POST...)
 	StatusCode   int             `json:"statusCode,omitempty"`
 	Status       string          `json:"status"` // open|closed
@@ -0,0 +1,44 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import "time"
+
+// 原始 TCP 会话的数据契约。
+//
+// 识别不出应用层协议的连接(透明模式下的非 HTTP 流量、SOCKS5 隧道内的私有协议等)
+// 只做字节中继。仿照 WSSession,用 TCPSession 承载「一条连接的分块时间线」:每次从
+// 某一方向读到的数据记为一个 TCPChunk,Direction 复用 WS 的取值,便于前端统一展示。
+
+// TCPChunk 表示一次从某一方向读到的数据块。
+type TCPChunk struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Direction string    `json:"direction"`           // client->server | server->client
+	Data      []byte    `json:"data"`                // 可能被截断,完整长度见 Size
+	Size      int       `json:"size"`                // 本块实际字节数
+	Truncated bool      `json:"truncated,omitempty"` // Data 只保留了前缀
+	Offset    int64     `json:"offset"`              // 本块在该方向字节流中的起始偏移
+	Timestamp time.Time `json:"timestamp"`           //
+	Seq       int       `json:"seq"`                 // 在本会话内的序号(从 0 递增)
+}
+
+// TCPSession 表示一条原始 TCP 连接的中继记录(用于 UI 展示与存储)。
+type TCPSession struct {
+	ID         string       `json:"id"`
+	ClientAddr string       `json:"clientAddr"`
+	Target     string       `json:"target"`             // 中继目标 host:port
+	Listener   string       `json:"listener,omitempty"` // 到达的附加监听端标识,主监听端为空
+	Status     string       `json:"status"`             // open|closed|error
+	Error      string       `json:"error,omitempty"`
+	StartTime  time.Time    `json:"startTime"`
+	EndTime    *time.Time   `json:"endTime,omitempty"`
+	BytesSent  int64        `json:"bytesSent"`     // client->server 累计字节
+	BytesRecv  int64        `json:"bytesReceived"` // server->client 累计字节
+	ChunkCount int          `json:"chunkCount"`    // 累计分块数(Chunks 只保留最近一段)
+	Chunks     []TCPChunk   `json:"chunks"`
+	Process    *ProcessInfo `json:"process,omitempty"`
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Phases 是一次上游请求按阶段拆分的耗时(毫秒,口径同 HAR timings)。
+// 某阶段未发生时为 -1:复用连接没有 DNS / 建连 / TLS,目标为 IP 时没有 DNS,明文请求没有 TLS。
+type Phases struct {
+	DNSMs      float64 `json:"dnsMs"`
+	ConnectMs  float64 `json:"connectMs"` // 仅 TCP 建连(经上游代理时含 CONNECT 隧道),不含 TLS
+	TLSMs      float64 `json:"tlsMs"`
+	SendMs     float64 `json:"sendMs"`               // 取得连接到请求写完
+	WaitMs     float64 `json:"waitMs"`               // 请求写完到响应首字节
+	ReceiveMs  float64 `json:"receiveMs"`            // 响应首字节到 body 读尽;body 尚未读尽时为 -1
+	Reused     bool    `json:"reused"`               // 是否复用了已有连接
+	RemoteAddr string  `json:"remoteAddr,omitempty"` // 实际连接的对端地址(经代理时为代理地址)
+}
+
+// PhaseTrace 经 httptrace 收集一次上游请求各阶段的时间点,由转发器装入请求 ctx、
+// 经 ResponseCapture 交回。回调可能来自 Transport 的拨号 goroutine,故加锁。
+type PhaseTrace struct {
+	mu                  sync.Mutex
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	gotConn, wrote      time.Time
+	firstByte           time.Time
+	reused              bool
+	remote              string
+}
+
+// ClientTrace 返回回填本收集器的 httptrace 钩子。
+func (p *PhaseTrace) ClientTrace() *httptrace.ClientTrace {
+	stamp := func(t *time.Time, keepFirst bool) {
+		p.mu.Lock()
+		if !keepFirst || t.IsZero() {
+			*t = time.Now()
+		}
+		p.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { stamp(&p.dnsStart, false) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { stamp(&p.dnsDone, false) },
+		// 多地址拨号(Happy Eyeballs)会多次回调:取首次开始与末次完成。
+		ConnectStart:      func(string, string) { stamp(&p.connStart, true) },
+		ConnectDone:       func(string, string, error) { stamp(&p.connDone, false) },
+		TLSHandshakeStart: func() { stamp(&p.tlsStart, false) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { stamp(&p.tlsDone, false) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			p.gotConn = time.Now()
+			p.reused = info.Reused
+			if info.Conn != nil {
+				p.remote = info.Conn.RemoteAddr().String()
+			}
+			p.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { stamp(&p.wrote, false) },
+		GotFirstResponseByte: func() { stamp(&p.firstByte, false) },
+	}
+}
+
+// Phases 按收集到的时间点算出各阶段耗时;end 为 body 读尽的时刻,零值表示尚未读尽。
+// 未取得连接(或 p 为 nil)时返回 nil。
+func (p *PhaseTrace) Phases(end time.Time) *Phases {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gotConn.IsZero() {
+		return nil
+	}
+	ph := &Phases{
+		DNSMs:      -1,
+		ConnectMs:  -1,
+		TLSMs:      -1,
+		SendMs:     span(p.gotConn, p.wrote),
+		WaitMs:     span(p.wrote, p.firstByte),
+		ReceiveMs:  span(p.firstByte, end),
+		Reused:     p.reused,
+		RemoteAddr: p.remote,
+	}
+	// 复用连接时残留的拨号时间点属于先前失败的尝试,不计入本请求。
+	if !p.reused {
+		ph.DNSMs = span(p.dnsStart, p.dnsDone)
+		ph.ConnectMs = span(p.connStart, p.connDone)
+		ph.TLSMs = span(p.tlsStart, p.tlsDone)
+	}
+	return ph
+}
+
+// span 返回 from 到 to 的毫秒数;任一端缺失时为 -1。
+func span(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return -1
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// SetPhases 记下上游请求的阶段耗时,并据此补上 TTFBMs(建连起到响应首字节,不含插件处理)。
+func (f *Flow) SetPhases(ph *Phases) {
+	if ph == nil {
+		return
+	}
+	f.Timing.Phases = ph
+	ttfb := 0.0
+	for _, ms := range []float64{ph.DNSMs, ph.ConnectMs, ph.TLSMs, ph.SendMs, ph.WaitMs} {
+		if ms > 0 {
+			ttfb += ms
+		}
+	}
+	f.Timing.TTFBMs = int64(ttfb)
+}
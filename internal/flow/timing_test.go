@@ -0,0 +1,50 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPhaseTraceToFlow 时间点按阶段折算;复用连接不计残留的拨号时间点;
+// CaptureResponseToFlow 读尽 body 后记入 Timing 并补上 TTFB。
+func TestPhaseTraceToFlow(t *testing.T) {
+	p := &PhaseTrace{}
+	ct := p.ClientTrace()
+	if p.Phases(time.Now()) != nil {
+		t.Fatal("未取得连接时应为 nil")
+	}
+	ct.ConnectStart("tcp", "x")
+	time.Sleep(2 * time.Millisecond)
+	ct.ConnectDone("tcp", "x", nil)
+	ct.GotConn(httptrace.GotConnInfo{Reused: true})
+	ct.WroteRequest(httptrace.WroteRequestInfo{})
+	ct.GotFirstResponseByte()
+	if ph := p.Phases(time.Time{}); ph.ConnectMs != -1 || ph.DNSMs != -1 || ph.WaitMs < 0 || ph.ReceiveMs != -1 {
+		t.Fatalf("复用连接: %+v", ph)
+	}
+
+	ct.GotConn(httptrace.GotConnInfo{})
+	ct.WroteRequest(httptrace.WroteRequestInfo{})
+	time.Sleep(2 * time.Millisecond)
+	ct.GotFirstResponseByte()
+
+	rc := &ResponseCapture{Trace: p}
+	req, _ := http.NewRequestWithContext(WithResponseCapture(context.Background(), rc), "GET", "http://a.test/", nil)
+	f := New(ProtoHTTP)
+	CaptureResponseToFlow(f, &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("x")), Request: req})
+	ph := f.Timing.Phases
+	if ph == nil || ph.Reused || ph.ConnectMs < 2 || ph.WaitMs < 2 || ph.ReceiveMs < 0 || ph.TLSMs != -1 {
+		t.Fatalf("新连接: %+v", ph)
+	}
+	if f.Timing.TTFBMs < 4 {
+		t.Fatalf("TTFBMs = %d", f.Timing.TTFBMs)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import "context"
+
+const (
+	// MetaTLSFingerprint 是 Flow.Metadata 中客户端 TLS 指纹的键,值为 *TLSFingerprint。
+	MetaTLSFingerprint = "tls"
+	// MetaUpstreamTLS 是上游连接实际发出的 ClientHello 指纹的键,值为 *TLSFingerprint。
+	MetaUpstreamTLS = "upstreamTls"
+)
+
+// TLSFingerprint 是客户端 ClientHello 的指纹:JA3 / JA4 摘要,以及据以计算的原始列表
+// (按线上顺序,含 GREASE)。同一 TLS 连接上的所有请求共享同一份,只读。
+type TLSFingerprint struct {
+	JA3        string   `json:"ja3"`     // JA3 原文:版本,套件,扩展,曲线,点格式
+	JA3Hash    string   `json:"ja3Hash"` // JA3 原文的 MD5
+	JA4        string   `json:"ja4"`
+	SNI        string   `json:"sni,omitempty"`
+	Version    uint16   `json:"version"` // 客户端支持的最高版本(supported_versions 优先)
+	Ciphers    []uint16 `json:"ciphers"`
+	Extensions []uint16 `json:"extensions"`
+	ALPN       []string `json:"alpn,omitempty"`
+	Curves     []uint16 `json:"curves,omitempty"`   // supported_groups
+	Versions   []uint16 `json:"versions,omitempty"` // supported_versions
+}
+
+type tlsFingerprintKeyT struct{}
+
+var tlsFingerprintKey tlsFingerprintKeyT
+
+// WithTLSFingerprint 把请求所在 TLS 连接的客户端指纹放进 ctx,BuildRequestFlow 据此写入 Metadata。
+func WithTLSFingerprint(ctx context.Context, fp *TLSFingerprint) context.Context {
+	return context.WithValue(ctx, tlsFingerprintKey, fp)
+}
+
+// TLSFingerprintFrom 取出客户端 TLS 指纹;明文请求或未能解析 ClientHello 时返回 false。
+func TLSFingerprintFrom(ctx context.Context) (*TLSFingerprint, bool) {
+	fp, ok := ctx.Value(tlsFingerprintKey).(*TLSFingerprint)
+	return fp, ok && fp != nil
+}
+
+// SetTLSFingerprint 把客户端 TLS 指纹记入 Metadata。
+func (f *Flow) SetTLSFingerprint(fp *TLSFingerprint) {
+	if fp == nil {
+		return
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata[MetaTLSFingerprint] = fp
+}
+
+// TLSFingerprint 返回记入 Metadata 的客户端 TLS 指纹,没有时为 nil。
+func (f *Flow) TLSFingerprint() *TLSFingerprint {
+	fp, _ := f.Metadata[MetaTLSFingerprint].(*TLSFingerprint)
+	return fp
+}
+
+// SetUpstreamTLSFingerprint 把上游连接发出的 ClientHello 指纹记入 Metadata。
+func (f *Flow) SetUpstreamTLSFingerprint(fp *TLSFingerprint) {
+	if fp == nil {
+		return
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata[MetaUpstreamTLS] = fp
+}
+
+// UpstreamTLSFingerprint 返回上游连接发出的 ClientHello 指纹;未经保真转发器或明文请求时为 nil。
+func (f *Flow) UpstreamTLSFingerprint() *TLSFingerprint {
+	fp, _ := f.Metadata[MetaUpstreamTLS].(*TLSFingerprint)
+	return fp
+}
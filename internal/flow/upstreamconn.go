@@ -0,0 +1,104 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// MetaUpstreamConn 是 Flow.Metadata 中上游 TLS 连接摘要的键,值为 *UpstreamConn。
+const MetaUpstreamConn = "upstreamConn"
+
+// UpstreamCert 是上游证书链中一张证书的摘要。
+type UpstreamCert struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	SHA256    string    `json:"sha256"` // DER 的 SHA-256 hex
+}
+
+// UpstreamConn 是上游 TLS 连接的摘要。同一连接上的所有请求共享同一份,只读。
+type UpstreamConn struct {
+	Version     uint16         `json:"version"`
+	CipherSuite uint16         `json:"cipherSuite"`
+	ALPN        string         `json:"alpn,omitempty"`
+	Chain       []UpstreamCert `json:"chain,omitempty"` // 对端发来的证书链,叶子在前
+	// Verified 表示是否校验过证书(校验模式为 off 时为 false);VerifyError 为校验失败的原因。
+	Verified    bool   `json:"verified"`
+	VerifyError string `json:"verifyError,omitempty"`
+}
+
+// UpstreamConnOf 由握手结果生成连接摘要(不含校验结论)。
+func UpstreamConnOf(cs tls.ConnectionState) *UpstreamConn {
+	uc := &UpstreamConn{
+		Version:     cs.Version,
+		CipherSuite: cs.CipherSuite,
+		ALPN:        cs.NegotiatedProtocol,
+		Chain:       make([]UpstreamCert, 0, len(cs.PeerCertificates)),
+	}
+	for _, c := range cs.PeerCertificates {
+		sum := sha256.Sum256(c.Raw)
+		uc.Chain = append(uc.Chain, UpstreamCert{
+			Subject:   c.Subject.String(),
+			Issuer:    c.Issuer.String(),
+			DNSNames:  c.DNSNames,
+			NotBefore: c.NotBefore,
+			NotAfter:  c.NotAfter,
+			SHA256:    hex.EncodeToString(sum[:]),
+		})
+	}
+	return uc
+}
+
+// UpstreamCertError 是强制校验模式下上游证书未通过校验的错误:握手被中止,请求没有发出。
+type UpstreamCertError struct {
+	Host string
+	Conn *UpstreamConn // 被拒连接的摘要(含证书链)
+	Err  error
+}
+
+func (e *UpstreamCertError) Error() string {
+	return "上游证书校验失败 (" + e.Host + "): " + e.Err.Error()
+}
+
+func (e *UpstreamCertError) Unwrap() error { return e.Err }
+
+// SetUpstreamConn 把上游 TLS 连接摘要记入 Metadata。
+func (f *Flow) SetUpstreamConn(uc *UpstreamConn) {
+	if uc == nil {
+		return
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata[MetaUpstreamConn] = uc
+}
+
+// UpstreamConn 返回承载本请求的上游 TLS 连接摘要;明文请求或未建立 TLS 时为 nil。
+func (f *Flow) UpstreamConn() *UpstreamConn {
+	uc, _ := f.Metadata[MetaUpstreamConn].(*UpstreamConn)
+	return uc
+}
+
+// FailUpstream 把上游请求失败记到 Flow 上:证书校验未通过记为 StateCertRejected 并附上被拒
+// 连接的证书链,其余记为 StateErrored。
+func (f *Flow) FailUpstream(err error) {
+	var certErr *UpstreamCertError
+	if errors.As(err, &certErr) {
+		f.State = StateCertRejected
+		f.Error = certErr.Error()
+		f.SetUpstreamConn(certErr.Conn)
+		return
+	}
+	f.State = StateErrored
+	f.Error = err.Error()
+}
@@ -25,20 +25,23 @@ const (
 // WSMessage 表示一条 WebSocket 消息(单向一帧)。
 type WSMessage struct {
 	ID        string    `json:"id"`
-	FlowID    string    `json:"flowId"`           // 所属 WebSocket 会话(升级请求的 Flow)
-	ConnID    string    `json:"connId,omitempty"` //
-	URL       string    `json:"url,omitempty"`    //
-	Direction string    `json:"direction"`        // client->server | server->client
-	Type      string    `json:"type"`             // text|binary|close|ping|pong
-	Data      []byte    `json:"data"`             //
-	Timestamp time.Time `json:"timestamp"`        //
+	FlowID    string    `json:"flowId"`             // 所属 WebSocket 会话(升级请求的 Flow)
+	ConnID    string    `json:"connId,omitempty"`   //
+	URL       string    `json:"url,omitempty"`      //
+	Direction string    `json:"direction"`          // client->server | server->client
+	Type      string    `json:"type"`               // text|binary|close|ping|pong
+	Data      []byte    `json:"data"`               //
+	Timestamp time.Time `json:"timestamp"`          //
+	Injected  bool      `json:"injected,omitempty"` // 经 API 注入 / 重发,而非两端实际发送
+	PausedAt  Phase     `json:"pausedAt,omitempty"` // 断点载荷标记(恒为 PhaseMessage)
 }
 
 // WSSession 表示一条 WebSocket 会话(用于 UI 展示与存储)。
 type WSSession struct {
 	ID           string       `json:"id"`
 	URL          string       `json:"url"`
-	Status       string       `json:"status"` // open|closed
+	Listener     string       `json:"listener,omitempty"` // 到达的附加监听端标识,主监听端为空
+	Status       string       `json:"status"`             // open|closed
 	StartTime    time.Time    `json:"startTime"`
 	EndTime      *time.Time   `json:"endTime,omitempty"`
 	MessageCount int          `json:"messageCount"`
@@ -0,0 +1,70 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 上游客户端证书(mTLS)。
+//
+// 证书按目标主机选用(Config.ClientCert),只在上游于握手中索要时出示。保真路径在构造 TLS 配置时
+// 已知目标主机;回退的标准 Transport 握手时拿不到主机名,由 RoundTrip 把主机经 ctx 传过去
+// (标准 Transport 的建连 ctx 保留请求 ctx 的值)。
+
+type clientCertHostKeyT struct{}
+
+var clientCertHostKey clientCertHostKeyT
+
+// clientCertificate 返回向 host 出示客户端证书的回调;无匹配证书时出示空证书,即不做客户端认证。
+func (t *Transport) clientCertificate(host string) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if c := t.cfg.ClientCert(host); c != nil {
+			return c, nil
+		}
+		return &tls.Certificate{}, nil
+	}
+}
+
+// installFallbackClientCert 让回退的标准 Transport 同样按主机出示客户端证书。Fallback 不是
+// *http.Transport 时由调用方自理。
+func installFallbackClientCert(fallback http.RoundTripper, pick func(host string) *tls.Certificate) {
+	tr, ok := fallback.(*http.Transport)
+	if !ok {
+		return
+	}
+	var cfg *tls.Config
+	if tr.TLSClientConfig != nil {
+		cfg = tr.TLSClientConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if host, _ := cri.Context().Value(clientCertHostKey).(string); host != "" {
+			if c := pick(host); c != nil {
+				return c, nil
+			}
+		}
+		return &tls.Certificate{}, nil
+	}
+	tr.TLSClientConfig = cfg
+}
+
+// withClientCert 为 https 请求记下按主机选用的客户端证书,并把主机放进 ctx 供回退路径握手时选用。
+func (t *Transport) withClientCert(req *http.Request) *http.Request {
+	if t.cfg.ClientCert == nil || req.URL.Scheme != "https" {
+		return req
+	}
+	host := hostname(req.URL)
+	if rc, ok := flow.ResponseCaptureFrom(req.Context()); ok {
+		rc.ClientCert = flow.ClientCertInfoOf(t.cfg.ClientCert(host))
+	}
+	return req.WithContext(context.WithValue(req.Context(), clientCertHostKey, host))
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestClientCertUpstream 要求客户端证书的上游:保真路径与回退路径都按主机出示证书,
+// 选用的证书经 ResponseCapture 回填;无匹配证书时不出示。
+func TestClientCertUpstream(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Peer", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	cert := selfSignedCert(t) // CN 为 127.0.0.1
+	var pick *tls.Certificate
+	tr := New(Config{
+		Fallback:        &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		ClientCert: func(host string) *tls.Certificate {
+			if host == "127.0.0.1" {
+				return pick
+			}
+			return nil
+		},
+	})
+	defer tr.CloseIdleConnections()
+
+	roundTrip := func(faithful bool) (string, *flow.ResponseCapture) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+"/", nil) // 无保真头序列:走回退
+		if faithful {
+			req = mkReq(t, "GET", srv.URL+"/", nil, [][2]string{{"Host", srv.Listener.Addr().String()}})
+		}
+		rc := &flow.ResponseCapture{}
+		resp, err := tr.RoundTrip(req.WithContext(flow.WithResponseCapture(req.Context(), rc)))
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return resp.Header.Get("X-Peer"), rc
+	}
+
+	if peer, rc := roundTrip(true); peer != "" || rc.ClientCert != nil {
+		t.Fatalf("无匹配证书时不应出示: peer=%q, rc=%+v", peer, rc.ClientCert)
+	}
+	tr.CloseIdleConnections()
+
+	pick = &cert
+	for _, faithful := range []bool{true, false} {
+		peer, rc := roundTrip(faithful)
+		if peer != "127.0.0.1" {
+			t.Fatalf("faithful=%v: 上游看到的客户端证书 = %q", faithful, peer)
+		}
+		if rc.ClientCert == nil || rc.ClientCert.Subject != "127.0.0.1" || rc.ClientCert.ID == "" {
+			t.Fatalf("faithful=%v: 未回填选用的证书: %+v", faithful, rc.ClientCert)
+		}
+	}
+}
@@ -0,0 +1,896 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// HTTP/2 保真转发。
+//
+// 标准 h2 客户端按自己的顺序写伪头、发送自己的 SETTINGS / WINDOW_UPDATE / 优先级,
+// 头名一律小写且顺序由实现决定 —— 这组特征正是反爬系统识别 h2 客户端的指纹。h2 入站时
+// 读取侧经 ctx 带来了客户端的线缆特征(flow.H2Fingerprint)与普通头序列(OrderedHeaders),
+// 这里在自管连接上用 Framer + HPACK 直接写帧,按原样回放。
+//
+// 连接按 (目标, 代理, SETTINGS) 复用,一条连接上多路复用多个 stream;读循环独立运行,
+// 请求体的发送受对端流控窗口约束,不存在 HTTP/1.x 那种先写后读的死锁,故不受
+// MaxFaithfulBody 限制。目标不支持 h2(ALPN 未协商出 h2)时回退到标准 Transport。
+
+// noH2TTL 是「目标不支持 h2」结论的缓存时长,期间同一目标的 h2 请求直接回退,免去重复握手。
+const noH2TTL = 10 * time.Minute
+
+var (
+	// errH2Unusable 表示连接在发出本请求的 HEADERS 之前已不可用(GOAWAY / 已断开 / stream 满),
+	// 请求可安全地换一条新连接重发。
+	errH2Unusable = errors.New("forward: h2 连接不可用")
+	// errNoH2 表示目标未协商出 h2。
+	errNoH2 = errors.New("forward: 目标不支持 h2")
+	// errH2BodyClosed 表示调用方在读尽之前关闭了响应体。
+	errH2BodyClosed = errors.New("forward: h2 响应体已关闭")
+)
+
+// defaultPseudoOrder 是客户端未带出伪头顺序时的写出顺序(与 Go 标准客户端一致)。
+var defaultPseudoOrder = []string{":method", ":authority", ":scheme", ":path"}
+
+// roundTripH2 在上游 h2 连接上按客户端的线缆特征转发一次请求。
+func (t *Transport) roundTripH2(req *http.Request, ordered [][2]string, fp *flow.H2Fingerprint, body []byte, proxyURL *url.URL) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.fallback(req, body) // 明文 h2c 不做保真
+	}
+	base := connKey(req.URL, proxyURL)
+	if t.knownNoH2(base) {
+		return t.fallback(req, body)
+	}
+	key := base + "\x00" + h2SettingsKey(fp) + mirrorKey(t.mirrorFor(req.Context(), hostname(req.URL)))
+	fields := h2RequestFields(req, ordered, fp)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		cc, err := t.getH2Conn(req.Context(), req.URL, proxyURL, key, fp)
+		if err != nil {
+			if errors.Is(err, errNoH2) {
+				t.markNoH2(base)
+			}
+			if cerr := req.Context().Err(); cerr != nil {
+				return nil, cerr
+			}
+			if isCertRejected(err) {
+				return nil, err // 证书被拒:回退路径同样会拒,不再重试
+			}
+			return t.fallback(req, body) // 建连/握手失败:请求尚未发出,可安全回退
+		}
+		recordUpstreamTLS(req.Context(), cc.hello)
+		recordUpstreamConn(req.Context(), cc.tlsc)
+		resp, err := cc.roundTrip(req, fields, fp, body)
+		if err == nil {
+			return resp, nil
+		}
+		if cerr := req.Context().Err(); cerr != nil {
+			return nil, cerr
+		}
+		if errors.Is(err, errH2Unusable) {
+			continue // 连接在发出请求前失效:换新连接重试
+		}
+		return nil, err
+	}
+	return t.fallback(req, body)
+}
+
+// h2RequestFields 按客户端的伪头顺序与普通头序列组装 HEADERS 的字段表。
+// 连接相关头在 h2 中非法,予以剔除;Host 并入 :authority;头名按 h2 要求小写。
+func h2RequestFields(req *http.Request, ordered [][2]string, fp *flow.H2Fingerprint) []hpack.HeaderField {
+	authority := req.Host
+	if authority == "" {
+		authority = req.URL.Host
+	}
+	regular := make([]hpack.HeaderField, 0, len(ordered))
+	for _, kv := range ordered {
+		name := strings.ToLower(kv[0])
+		switch name {
+		case "host":
+			authority = kv[1]
+			continue
+		case "connection", "proxy-connection", "keep-alive", "transfer-encoding", "upgrade":
+			continue
+		case "te":
+			if !strings.EqualFold(strings.TrimSpace(kv[1]), "trailers") {
+				continue
+			}
+		}
+		regular = append(regular, hpack.HeaderField{Name: name, Value: kv[1]})
+	}
+
+	pseudo := map[string]string{
+		":method":    req.Method,
+		":authority": authority,
+		":scheme":    "https",
+		":path":      req.URL.RequestURI(),
+	}
+	out := make([]hpack.HeaderField, 0, len(pseudo)+len(regular))
+	emit := func(names []string) {
+		for _, name := range names {
+			if v, ok := pseudo[name]; ok {
+				out = append(out, hpack.HeaderField{Name: name, Value: v})
+				delete(pseudo, name)
+			}
+		}
+	}
+	emit(fp.PseudoOrder)
+	emit(defaultPseudoOrder)
+	return append(out, regular...)
+}
+
+// h2SettingsKey 把连接级特征编码进连接池键:SETTINGS 不同的客户端不共用上游连接。
+func h2SettingsKey(fp *flow.H2Fingerprint) string {
+	var b strings.Builder
+	for _, s := range fp.Settings {
+		fmt.Fprintf(&b, "%d=%d;", s.ID, s.Val)
+	}
+	fmt.Fprintf(&b, "w=%d", fp.WindowUpdate)
+	return b.String()
+}
+
+func (t *Transport) knownNoH2(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.noH2[key]
+	if ok && time.Now().After(until) {
+		delete(t.noH2, key)
+		return false
+	}
+	return ok
+}
+
+func (t *Transport) markNoH2(key string) {
+	t.mu.Lock()
+	t.noH2[key] = time.Now().Add(noH2TTL)
+	t.mu.Unlock()
+}
+
+// getH2Conn 取一条还能再开 stream 的 h2 连接,没有则新建并放入池中。
+func (t *Transport) getH2Conn(ctx context.Context, u, proxyURL *url.URL, key string, fp *flow.H2Fingerprint) (*h2ClientConn, error) {
+	t.mu.Lock()
+	if cc := t.h2[key]; cc != nil && cc.canTakeStream() {
+		t.mu.Unlock()
+		traceGotConn(ctx, cc.conn, true)
+		return cc, nil
+	}
+	t.mu.Unlock()
+
+	conn, hello, err := t.dialH2(ctx, u, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := t.newH2ClientConn(conn, key, fp)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	cc.hello = hello
+	cc.tlsc = t.upstreamConnInfo(hostname(u), conn)
+	traceGotConn(ctx, conn, false)
+	t.mu.Lock()
+	old := t.h2[key]
+	t.h2[key] = cc
+	t.mu.Unlock()
+	if old != nil {
+		old.detach() // 旧连接不再接新 stream,在途的 stream 结束后关闭
+	}
+	return cc, nil
+}
+
+// removeH2 把失效的连接移出池。
+func (t *Transport) removeH2(cc *h2ClientConn) {
+	t.mu.Lock()
+	if t.h2[cc.key] == cc {
+		delete(t.h2, cc.key)
+	}
+	t.mu.Unlock()
+}
+
+// dialH2 建立到 https 目标的 TLS 连接并要求协商出 h2(ALPN 与浏览器一样同时通告 http/1.1;
+// 镜像时按客户端的列表),返回连接与本端发出的 ClientHello 指纹。
+func (t *Transport) dialH2(ctx context.Context, u, proxyURL *url.URL) (net.Conn, *flow.TLSFingerprint, error) {
+	raw, err := t.dialTLSTarget(ctx, u, proxyURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := t.clientTLSConfig(hostname(u), []string{"h2", "http/1.1"}, t.mirrorFor(ctx, hostname(u)))
+	tc, hello, err := t.handshake(ctx, raw, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tc.ConnectionState().NegotiatedProtocol != "h2" {
+		_ = tc.Close()
+		return nil, nil, errNoH2
+	}
+	return tc, hello, nil
+}
+
+// ============================ 连接 ============================
+
+// h2ClientConn 是一条上游 h2 连接。
+type h2ClientConn struct {
+	t    *Transport
+	key  string
+	conn net.Conn
+	bw   *bufio.Writer
+	fr   *http2.Framer
+
+	wmu    sync.Mutex // 串行化写帧;HPACK 编码须与写出顺序一致,也在其保护下进行
+	enc    *hpack.Encoder
+	encBuf bytes.Buffer
+
+	mu            sync.Mutex
+	cond          *sync.Cond // 发送窗口变化、stream 结束、连接失效时广播
+	streams       map[uint32]*h2Stream
+	nextID        uint32
+	sendWindow    int64  // 连接级发送窗口
+	initWindow    int64  // 对端 SETTINGS_INITIAL_WINDOW_SIZE
+	maxFrame      uint32 // 对端 SETTINGS_MAX_FRAME_SIZE
+	maxStreams    uint32 // 对端 SETTINGS_MAX_CONCURRENT_STREAMS
+	recvWindow    uint32 // 本端通告的 stream 接收窗口
+	connRecv      uint32 // 本端连接级接收窗口
+	connUnacked   uint32 // 已收到、尚未以 WINDOW_UPDATE 归还的连接级字节
+	goAway        bool
+	detached      bool                 // 已被池中新连接替换,空闲即关闭
+	hello         *flow.TLSFingerprint // 建连时发出的 ClientHello 指纹
+	tlsc          *flow.UpstreamConn   // TLS 连接摘要
+	err           error
+	idleTimer     *time.Timer
+	headerTimeout time.Duration
+}
+
+// newH2ClientConn 发送连接前言、客户端的 SETTINGS 与 WINDOW_UPDATE,并启动读循环。
+func (t *Transport) newH2ClientConn(conn net.Conn, key string, fp *flow.H2Fingerprint) (*h2ClientConn, error) {
+	cc := &h2ClientConn{
+		t:             t,
+		key:           key,
+		conn:          conn,
+		bw:            bufio.NewWriter(conn),
+		streams:       make(map[uint32]*h2Stream),
+		nextID:        1,
+		sendWindow:    65535,
+		initWindow:    65535,
+		maxFrame:      16384,
+		maxStreams:    100,
+		recvWindow:    65535,
+		connRecv:      65535 + fp.WindowUpdate,
+		headerTimeout: t.cfg.RespHeaderTimeout,
+	}
+	cc.cond = sync.NewCond(&cc.mu)
+	cc.fr = http2.NewFramer(cc.bw, conn)
+	cc.enc = hpack.NewEncoder(&cc.encBuf)
+
+	settings := make([]http2.Setting, 0, len(fp.Settings))
+	tableSize := uint32(4096)
+	for _, s := range fp.Settings {
+		settings = append(settings, http2.Setting{ID: http2.SettingID(s.ID), Val: s.Val})
+		switch http2.SettingID(s.ID) {
+		case http2.SettingInitialWindowSize:
+			cc.recvWindow = s.Val
+		case http2.SettingHeaderTableSize:
+			tableSize = s.Val
+		}
+	}
+	cc.fr.ReadMetaHeaders = hpack.NewDecoder(tableSize, nil)
+	cc.fr.MaxHeaderListSize = 10 << 20
+	cc.fr.SetMaxReadFrameSize(1<<24 - 1)
+
+	cc.wmu.Lock()
+	cc.armWrite()
+	_, err := cc.bw.WriteString(http2.ClientPreface)
+	if err == nil {
+		err = cc.fr.WriteSettings(settings...)
+	}
+	if err == nil && fp.WindowUpdate > 0 {
+		err = cc.fr.WriteWindowUpdate(0, fp.WindowUpdate)
+	}
+	if err == nil {
+		err = cc.bw.Flush()
+	}
+	cc.wmu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	go cc.readLoop()
+	return cc, nil
+}
+
+// armWrite 给本次写出设停滞期限,避免不读的对端把持有 wmu 的写者永久挂住。调用方持有 wmu。
+func (cc *h2ClientConn) armWrite() {
+	if cc.headerTimeout > 0 {
+		_ = cc.conn.SetWriteDeadline(time.Now().Add(cc.headerTimeout))
+	}
+}
+
+// canTakeStream 报告连接能否再开一个 stream。
+func (cc *h2ClientConn) canTakeStream() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.usableLocked()
+}
+
+func (cc *h2ClientConn) usableLocked() bool {
+	return cc.err == nil && !cc.goAway && !cc.detached &&
+		uint32(len(cc.streams)) < cc.maxStreams && cc.nextID < 1<<31
+}
+
+// detach 让连接不再接新 stream;当前已空闲则立即关闭。
+func (cc *h2ClientConn) detach() {
+	cc.mu.Lock()
+	cc.detached = true
+	idle := len(cc.streams) == 0
+	cc.mu.Unlock()
+	if idle {
+		cc.fail(errH2Unusable)
+	}
+}
+
+// closeIfIdle 关闭空闲的连接(CloseIdleConnections 与空闲计时器调用)。
+func (cc *h2ClientConn) closeIfIdle() {
+	cc.mu.Lock()
+	idle := len(cc.streams) == 0
+	cc.mu.Unlock()
+	if idle {
+		cc.fail(errH2Unusable)
+	}
+}
+
+// fail 使连接失效:所有在途 stream 以 err 结束,关闭底层连接并移出池。可重复调用。
+func (cc *h2ClientConn) fail(err error) {
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	cc.mu.Lock()
+	first := cc.err == nil
+	if first {
+		cc.err = err
+	}
+	for id, s := range cc.streams {
+		s.abortLocked(err)
+		delete(cc.streams, id)
+	}
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	if first {
+		_ = cc.conn.Close()
+		cc.t.removeH2(cc)
+	}
+}
+
+// streamDoneLocked 在 stream 移出后调用:连接空闲时按配置回收。调用方持有 mu。
+func (cc *h2ClientConn) streamDoneLocked() {
+	cc.cond.Broadcast()
+	if len(cc.streams) > 0 || cc.err != nil {
+		return
+	}
+	if cc.detached || cc.goAway {
+		go cc.fail(errH2Unusable)
+		return
+	}
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	cc.idleTimer = time.AfterFunc(cc.t.cfg.IdleConnTimeout, cc.closeIfIdle)
+}
+
+// removeStream 把 stream 移出连接(若仍在)。返回是否由本次调用移出。
+func (cc *h2ClientConn) removeStream(s *h2Stream, err error) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.streams[s.id] != s {
+		return false
+	}
+	delete(cc.streams, s.id)
+	s.abortLocked(err)
+	cc.streamDoneLocked()
+	return true
+}
+
+// resetStream 由本端提前结束 stream(ctx 取消、未读尽即关闭、等待响应头超时)。
+func (cc *h2ClientConn) resetStream(s *h2Stream, err error) {
+	if cc.removeStream(s, err) {
+		cc.wmu.Lock()
+		cc.armWrite()
+		if cc.fr.WriteRSTStream(s.id, http2.ErrCodeCancel) == nil {
+			_ = cc.bw.Flush()
+		}
+		cc.wmu.Unlock()
+	}
+}
+
+// writeWindowUpdate 归还接收窗口;写失败由读循环发现并收尾。
+func (cc *h2ClientConn) writeWindowUpdate(id, n uint32) {
+	cc.wmu.Lock()
+	cc.armWrite()
+	if cc.fr.WriteWindowUpdate(id, n) == nil {
+		_ = cc.bw.Flush()
+	}
+	cc.wmu.Unlock()
+}
+
+// ============================ 请求 ============================
+
+// h2Stream 是连接上的一个请求。respReady / gotHeaders / done 与 body 状态均受 cc.mu 保护。
+type h2Stream struct {
+	id         uint32
+	cc         *h2ClientConn
+	req        *http.Request
+	sendWindow int64
+
+	respCh     chan struct{} // 响应头到达或 stream 出错时关闭
+	respReady  bool
+	resp       *http.Response
+	err        error
+	gotHeaders bool
+	done       bool
+
+	body *h2Body
+	stop func() bool // 解除 ctx 取消监听
+}
+
+// abortLocked 以 err 结束 stream:尚未拿到响应头的等待方收到错误,读响应体的一方在
+// 读完已缓冲数据后收到错误。调用方持有 cc.mu。
+func (s *h2Stream) abortLocked(err error) {
+	s.done = true
+	if !s.respReady {
+		s.respReady = true
+		s.err = err
+		close(s.respCh)
+	}
+	if s.body.err == nil {
+		s.body.err = err
+	}
+	s.body.cond.Broadcast()
+}
+
+// roundTrip 在连接上开一个 stream:写 HEADERS(与 DATA),等待响应头。
+//
+// 分配 stream ID 与写出 HEADERS 在同一次 wmu 持有内完成:新 stream 的 HEADERS 必须按 ID
+// 递增顺序上线(RFC 7540 §5.1.1),否则对端以 PROTOCOL_ERROR 关闭整条连接。
+func (cc *h2ClientConn) roundTrip(req *http.Request, fields []hpack.HeaderField, fp *flow.H2Fingerprint, body []byte) (*http.Response, error) {
+	cc.wmu.Lock()
+	cc.mu.Lock()
+	if !cc.usableLocked() {
+		cc.mu.Unlock()
+		cc.wmu.Unlock()
+		return nil, errH2Unusable
+	}
+	s := &h2Stream{
+		id:         cc.nextID,
+		cc:         cc,
+		req:        req,
+		sendWindow: cc.initWindow,
+		respCh:     make(chan struct{}),
+	}
+	s.body = &h2Body{s: s, cond: sync.NewCond(&cc.mu)}
+	cc.nextID += 2
+	cc.streams[s.id] = s
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	cc.mu.Unlock()
+
+	err := cc.writeHeadersLocked(s.id, fields, fp.Priority, len(body) == 0)
+	cc.wmu.Unlock()
+	if err != nil {
+		cc.fail(err)
+		return nil, err
+	}
+	ctx := req.Context()
+	s.stop = context.AfterFunc(ctx, func() { cc.resetStream(s, ctx.Err()) })
+
+	if len(body) > 0 {
+		if err := cc.writeBody(s, body); err != nil {
+			cc.resetStream(s, err)
+			s.stop()
+			return nil, err
+		}
+	}
+	traceWroteRequest(ctx, nil)
+
+	var timeout <-chan time.Time
+	if cc.headerTimeout > 0 {
+		timer := time.NewTimer(cc.headerTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-s.respCh:
+	case <-timeout:
+		cc.resetStream(s, errors.New("forward: 等待 h2 响应头超时"))
+	}
+	cc.mu.Lock()
+	resp, err := s.resp, s.err
+	cc.mu.Unlock()
+	if err != nil {
+		s.stop()
+		return nil, err
+	}
+	traceFirstByte(ctx) // 响应头由读循环收下,此处即本 stream 首次拿到响应
+	return resp, nil
+}
+
+// writeHeadersLocked 编码并写出 HEADERS(超过对端帧上限时续以 CONTINUATION)。调用方持有 wmu。
+func (cc *h2ClientConn) writeHeadersLocked(id uint32, fields []hpack.HeaderField, prio *flow.H2Priority, endStream bool) error {
+	cc.encBuf.Reset()
+	for _, f := range fields {
+		if err := cc.enc.WriteField(f); err != nil {
+			return err
+		}
+	}
+	block := cc.encBuf.Bytes()
+	cc.mu.Lock()
+	maxFrame := int(cc.maxFrame)
+	cc.mu.Unlock()
+
+	first := block
+	if len(first) > maxFrame {
+		first = first[:maxFrame]
+	}
+	p := http2.HeadersFrameParam{
+		StreamID:      id,
+		BlockFragment: first,
+		EndStream:     endStream,
+		EndHeaders:    len(first) == len(block),
+	}
+	if prio != nil {
+		p.Priority = http2.PriorityParam{StreamDep: prio.StreamDep, Exclusive: prio.Exclusive, Weight: prio.Weight}
+	}
+	cc.armWrite()
+	if err := cc.fr.WriteHeaders(p); err != nil {
+		return err
+	}
+	for rest := block[len(first):]; len(rest) > 0; {
+		n := min(len(rest), maxFrame)
+		if err := cc.fr.WriteContinuation(id, n == len(rest), rest[:n]); err != nil {
+			return err
+		}
+		rest = rest[n:]
+	}
+	return cc.bw.Flush()
+}
+
+// writeBody 按连接级与 stream 级发送窗口分帧写出请求体,最后一帧带 END_STREAM。
+// 对端提前结束 stream(如先给出响应并 RST)时停止发送、不视为错误。
+func (cc *h2ClientConn) writeBody(s *h2Stream, body []byte) error {
+	for len(body) > 0 {
+		cc.mu.Lock()
+		for !s.done && cc.err == nil && (cc.sendWindow <= 0 || s.sendWindow <= 0) {
+			cc.cond.Wait()
+		}
+		if s.done || cc.err != nil {
+			err := cc.err
+			cc.mu.Unlock()
+			if err != nil && !s.gotHeaders {
+				return err
+			}
+			return nil
+		}
+		n := min(int64(len(body)), cc.sendWindow, s.sendWindow, int64(cc.maxFrame))
+		cc.sendWindow -= n
+		s.sendWindow -= n
+		cc.mu.Unlock()
+
+		chunk := body[:n]
+		body = body[n:]
+		cc.wmu.Lock()
+		cc.armWrite()
+		err := cc.fr.WriteData(s.id, len(body) == 0, chunk)
+		if err == nil {
+			err = cc.bw.Flush()
+		}
+		cc.wmu.Unlock()
+		if err != nil {
+			cc.fail(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================ 读循环 ============================
+
+func (cc *h2ClientConn) readLoop() {
+	var err error
+	for {
+		var f http2.Frame
+		if f, err = cc.fr.ReadFrame(); err != nil {
+			var se http2.StreamError
+			if errors.As(err, &se) {
+				// 单个 stream 的协议错误(如响应头超限):只重置该 stream,连接继续。
+				cc.mu.Lock()
+				s := cc.streams[se.StreamID]
+				cc.mu.Unlock()
+				if s != nil {
+					cc.resetStream(s, se)
+				}
+				continue
+			}
+			break
+		}
+		if err = cc.handleFrame(f); err != nil {
+			break
+		}
+	}
+	cc.fail(err)
+}
+
+func (cc *h2ClientConn) handleFrame(f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		if f.IsAck() {
+			return nil
+		}
+		return cc.applySettings(f)
+	case *http2.PingFrame:
+		if f.IsAck() {
+			return nil
+		}
+		cc.wmu.Lock()
+		defer cc.wmu.Unlock()
+		cc.armWrite()
+		if err := cc.fr.WritePing(true, f.Data); err != nil {
+			return err
+		}
+		return cc.bw.Flush()
+	case *http2.WindowUpdateFrame:
+		cc.mu.Lock()
+		if f.StreamID == 0 {
+			cc.sendWindow += int64(f.Increment)
+		} else if s := cc.streams[f.StreamID]; s != nil {
+			s.sendWindow += int64(f.Increment)
+		}
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	case *http2.MetaHeadersFrame:
+		cc.onHeaders(f)
+	case *http2.DataFrame:
+		cc.onData(f)
+	case *http2.RSTStreamFrame:
+		cc.mu.Lock()
+		s := cc.streams[f.StreamID]
+		cc.mu.Unlock()
+		if s != nil {
+			var err error = http2.StreamError{StreamID: f.StreamID, Code: f.ErrCode}
+			if f.ErrCode == http2.ErrCodeRefusedStream {
+				err = fmt.Errorf("%w: %v", errH2Unusable, err) // 对端未处理该 stream,可重发
+			}
+			cc.removeStream(s, err)
+		}
+	case *http2.GoAwayFrame:
+		cc.mu.Lock()
+		cc.goAway = true
+		var late []*h2Stream
+		for id, s := range cc.streams {
+			if id > f.LastStreamID {
+				late = append(late, s)
+			}
+		}
+		cc.mu.Unlock()
+		for _, s := range late {
+			// 编号超出 LastStreamID 的 stream 未被对端处理,可换连接重发。
+			cc.removeStream(s, fmt.Errorf("%w: 上游 GOAWAY(%v)", errH2Unusable, f.ErrCode))
+		}
+	}
+	return nil
+}
+
+// applySettings 应用对端 SETTINGS 并确认。
+func (cc *h2ClientConn) applySettings(f *http2.SettingsFrame) error {
+	var tableSize uint32
+	hasTable := false
+	cc.mu.Lock()
+	err := f.ForeachSetting(func(s http2.Setting) error {
+		switch s.ID {
+		case http2.SettingInitialWindowSize:
+			delta := int64(s.Val) - cc.initWindow
+			cc.initWindow = int64(s.Val)
+			for _, st := range cc.streams {
+				st.sendWindow += delta
+			}
+		case http2.SettingMaxFrameSize:
+			cc.maxFrame = s.Val
+		case http2.SettingMaxConcurrentStreams:
+			cc.maxStreams = s.Val
+		case http2.SettingHeaderTableSize:
+			tableSize, hasTable = s.Val, true
+		}
+		return nil
+	})
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	if hasTable {
+		cc.enc.SetMaxDynamicTableSizeLimit(tableSize)
+	}
+	cc.armWrite()
+	if err := cc.fr.WriteSettingsAck(); err != nil {
+		return err
+	}
+	return cc.bw.Flush()
+}
+
+// onHeaders 处理响应头(跳过 1xx)或响应尾部。
+func (cc *h2ClientConn) onHeaders(f *http2.MetaHeadersFrame) {
+	cc.mu.Lock()
+	s := cc.streams[f.StreamID]
+	if s == nil {
+		cc.mu.Unlock()
+		return
+	}
+	if !s.gotHeaders {
+		code, err := strconv.Atoi(f.PseudoValue("status"))
+		if err != nil {
+			cc.mu.Unlock()
+			cc.resetStream(s, fmt.Errorf("forward: h2 响应状态码非法 %q", f.PseudoValue("status")))
+			return
+		}
+		if code >= 100 && code < 200 {
+			cc.mu.Unlock()
+			return // 1xx 信息响应,等待最终响应
+		}
+		header := make(http.Header, len(f.Fields))
+		for _, hf := range f.RegularFields() {
+			header.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+		}
+		resp := &http.Response{
+			Status:        strconv.Itoa(code) + " " + http.StatusText(code),
+			StatusCode:    code,
+			Proto:         "HTTP/2.0",
+			ProtoMajor:    2,
+			Header:        header,
+			Body:          s.body,
+			ContentLength: -1,
+			Request:       s.req,
+		}
+		if cl := header.Get("Content-Length"); cl != "" {
+			if n, err := strconv.ParseInt(cl, 10, 64); err == nil {
+				resp.ContentLength = n
+			}
+		}
+		s.gotHeaders = true
+		s.resp = resp
+		s.respReady = true
+		close(s.respCh)
+	} else if s.resp != nil {
+		trailer := make(http.Header)
+		for _, hf := range f.RegularFields() {
+			trailer.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+		}
+		s.resp.Trailer = trailer // 先于 EOF 写入:调用方读到 EOF 后才会读取尾部
+	}
+	cc.mu.Unlock()
+	if f.StreamEnded() {
+		cc.finishStream(s)
+	}
+}
+
+// onData 把数据追加到响应体缓冲。连接级窗口在收到时即归还(单个慢读的 stream 只受其
+// stream 级窗口约束,不拖累整条连接);stream 级窗口在调用方读走后归还。
+func (cc *h2ClientConn) onData(f *http2.DataFrame) {
+	size := f.Header().Length
+	data := f.Data()
+	var connInc uint32
+	cc.mu.Lock()
+	cc.connUnacked += size
+	if cc.connUnacked >= cc.connRecv/2 {
+		connInc, cc.connUnacked = cc.connUnacked, 0
+	}
+	s := cc.streams[f.StreamID]
+	if s != nil && s.gotHeaders {
+		s.body.buf.Write(data)
+		s.body.unacked += size - uint32(len(data)) // 填充字节不会被读走,直接计入待归还
+		s.body.cond.Broadcast()
+	}
+	cc.mu.Unlock()
+	if connInc > 0 {
+		cc.writeWindowUpdate(0, connInc)
+	}
+	if s != nil && f.StreamEnded() {
+		cc.finishStream(s)
+	}
+}
+
+// finishStream 在收到 END_STREAM 后正常结束 stream。
+func (cc *h2ClientConn) finishStream(s *h2Stream) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.streams[s.id] != s {
+		return
+	}
+	delete(cc.streams, s.id)
+	s.abortLocked(io.EOF)
+	cc.streamDoneLocked()
+}
+
+// ============================ 响应体 ============================
+
+// h2Body 是 stream 的响应体:读循环写入缓冲,调用方读出时归还 stream 级接收窗口。
+// 状态受 cc.mu 保护,cond 绑定在 cc.mu 上。
+type h2Body struct {
+	s       *h2Stream
+	cond    *sync.Cond
+	buf     bytes.Buffer
+	err     error // io.EOF 表示正常结束
+	unacked uint32
+	closed  bool
+}
+
+func (b *h2Body) Read(p []byte) (int, error) {
+	cc := b.s.cc
+	cc.mu.Lock()
+	for b.buf.Len() == 0 && b.err == nil && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		cc.mu.Unlock()
+		return 0, errH2BodyClosed
+	}
+	if b.buf.Len() == 0 {
+		err := b.err
+		cc.mu.Unlock()
+		if b.s.stop != nil {
+			b.s.stop() // stream 已结束,解除 ctx 取消监听
+		}
+		return 0, err
+	}
+	n, _ := b.buf.Read(p)
+	b.unacked += uint32(n)
+	var inc uint32
+	if b.err == nil && b.unacked >= cc.recvWindow/2 {
+		inc, b.unacked = b.unacked, 0
+	}
+	cc.mu.Unlock()
+	if inc > 0 {
+		cc.writeWindowUpdate(b.s.id, inc)
+	}
+	return n, nil
+}
+
+// Close 未读尽时以 RST_STREAM 取消 stream。
+func (b *h2Body) Close() error {
+	cc := b.s.cc
+	cc.mu.Lock()
+	if b.closed {
+		cc.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.buf.Reset()
+	b.cond.Broadcast()
+	cc.mu.Unlock()
+	cc.resetStream(b.s, errH2BodyClosed)
+	if b.s.stop != nil {
+		b.s.stop()
+	}
+	return nil
+}
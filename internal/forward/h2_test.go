@@ -0,0 +1,393 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// chromeLikeFingerprint 是一组与主流浏览器相近、但与 Go 标准客户端明显不同的 h2 特征。
+func chromeLikeFingerprint() *flow.H2Fingerprint {
+	return &flow.H2Fingerprint{
+		Settings: []flow.H2Setting{
+			{ID: uint16(http2.SettingHeaderTableSize), Val: 65536},
+			{ID: uint16(http2.SettingEnablePush), Val: 0},
+			{ID: uint16(http2.SettingInitialWindowSize), Val: 6291456},
+			{ID: uint16(http2.SettingMaxHeaderListSize), Val: 262144},
+		},
+		WindowUpdate: 15663105,
+		PseudoOrder:  []string{":method", ":authority", ":scheme", ":path"},
+		Priority:     &flow.H2Priority{StreamDep: 0, Exclusive: true, Weight: 255},
+	}
+}
+
+func mkH2Req(t *testing.T, method, url string, body []byte, ordered [][2]string, fp *flow.H2Fingerprint) *http.Request {
+	t.Helper()
+	req := mkReq(t, method, url, body, ordered)
+	return req.WithContext(flow.WithH2Fingerprint(req.Context(), fp))
+}
+
+func TestH2RequestFields(t *testing.T) {
+	req := mkReq(t, "POST", "https://example.com/a?b=1", nil, nil)
+	ordered := [][2]string{
+		{"user-agent", "UA"},
+		{"Connection", "keep-alive"},
+		{"te", "gzip"},
+		{"accept", "*/*"},
+		{"Host", "example.com:8443"},
+		{"TE", "trailers"},
+	}
+	fp := &flow.H2Fingerprint{PseudoOrder: []string{":method", ":path", ":authority"}}
+	got := h2RequestFields(req, ordered, fp)
+	want := []hpack.HeaderField{
+		{Name: ":method", Value: "POST"},
+		{Name: ":path", Value: "/a?b=1"},
+		{Name: ":authority", Value: "example.com:8443"},
+		{Name: ":scheme", Value: "https"}, // 客户端未带出的伪头按默认顺序补在后面
+		{Name: "user-agent", Value: "UA"},
+		{Name: "accept", Value: "*/*"},
+		{Name: "te", Value: "trailers"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("字段数不符: %+v", got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Value != want[i].Value {
+			t.Fatalf("第 %d 项 = %s: %s, want %s: %s", i, got[i].Name, got[i].Value, want[i].Name, want[i].Value)
+		}
+	}
+}
+
+// h2Capture 是一个原始 TLS h2 服务端抓到的首个请求的线缆特征。
+type h2Capture struct {
+	settings     []http2.Setting
+	windowUpdate uint32
+	priority     http2.PriorityParam
+	fields       []hpack.HeaderField
+	body         []byte
+}
+
+// newRawH2Server 起一个只说 h2 的 TLS 服务端:逐帧记录客户端发来的连接特征与请求,
+// 回 200 + 请求体长度。
+func newRawH2Server(t *testing.T) (string, chan h2Capture) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t)},
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	out := make(chan h2Capture, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(c, preface); err != nil || string(preface) != http2.ClientPreface {
+			return
+		}
+		fr := http2.NewFramer(c, c)
+		fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+		_ = fr.WriteSettings()
+		var cp h2Capture
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if !f.IsAck() && cp.settings == nil {
+					_ = f.ForeachSetting(func(s http2.Setting) error {
+						cp.settings = append(cp.settings, s)
+						return nil
+					})
+					_ = fr.WriteSettingsAck()
+				}
+			case *http2.WindowUpdateFrame:
+				if f.StreamID == 0 && cp.windowUpdate == 0 {
+					cp.windowUpdate = f.Increment
+				}
+			case *http2.MetaHeadersFrame:
+				cp.priority = f.Priority
+				cp.fields = f.Fields
+				if f.StreamEnded() {
+					goto respond
+				}
+			case *http2.DataFrame:
+				cp.body = append(cp.body, f.Data()...)
+				if f.StreamEnded() {
+					goto respond
+				}
+			}
+			continue
+		respond:
+			out <- cp
+			var hb bytes.Buffer
+			enc := hpack.NewEncoder(&hb)
+			_ = enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+			_ = enc.WriteField(hpack.HeaderField{Name: "x-got", Value: strconv.Itoa(len(cp.body))})
+			_ = fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: hb.Bytes(), EndHeaders: true})
+			_ = fr.WriteData(1, true, []byte("ok"))
+			_, _ = io.Copy(io.Discard, c) // 等客户端关连接
+			return
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+// TestH2ReplaysClientFingerprint 是核心断言:上游看到的 SETTINGS(顺序与取值)、连接级
+// WINDOW_UPDATE、HEADERS 优先级、伪头顺序与普通头顺序都与客户端一致。
+func TestH2ReplaysClientFingerprint(t *testing.T) {
+	addr, got := newRawH2Server(t)
+	tr := New(Config{Fallback: &errRT{}, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	defer tr.CloseIdleConnections()
+
+	fp := chromeLikeFingerprint()
+	fp.PseudoOrder = []string{":method", ":authority", ":scheme", ":path"}
+	ordered := [][2]string{
+		{"sec-ch-ua", `"X"`},
+		{"user-agent", "UA"},
+		{"content-type", "text/plain"},
+		{"Content-Length", "5"},
+		{"Host", addr},
+	}
+	req := mkH2Req(t, "POST", "https://"+addr+"/p?q=1", []byte("hello"), ordered, fp)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.StatusCode != 200 || string(body) != "ok" || resp.Header.Get("X-Got") != "5" {
+		t.Fatalf("响应不符: %s %d %q %v", resp.Proto, resp.StatusCode, body, resp.Header)
+	}
+
+	cp := <-got
+	if len(cp.settings) != len(fp.Settings) {
+		t.Fatalf("SETTINGS 项数不符: %+v", cp.settings)
+	}
+	for i, s := range fp.Settings {
+		if uint16(cp.settings[i].ID) != s.ID || cp.settings[i].Val != s.Val {
+			t.Fatalf("SETTINGS 第 %d 项 = %v, want %+v", i, cp.settings[i], s)
+		}
+	}
+	if cp.windowUpdate != fp.WindowUpdate {
+		t.Fatalf("WINDOW_UPDATE = %d, want %d", cp.windowUpdate, fp.WindowUpdate)
+	}
+	if !cp.priority.Exclusive || cp.priority.Weight != 255 {
+		t.Fatalf("优先级不符: %+v", cp.priority)
+	}
+	var names []string
+	for _, f := range cp.fields {
+		names = append(names, f.Name)
+	}
+	want := ":method,:authority,:scheme,:path,sec-ch-ua,user-agent,content-type,content-length"
+	if strings.Join(names, ",") != want {
+		t.Fatalf("头序列不符:\n got=%s\nwant=%s", strings.Join(names, ","), want)
+	}
+	if string(cp.body) != "hello" {
+		t.Fatalf("请求体不符: %q", cp.body)
+	}
+}
+
+// TestH2InteropWithStdServer 对标准库 h2 服务端验证多路复用、双向流控(大请求体 / 大响应体)
+// 与响应尾部。
+func TestH2InteropWithStdServer(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 3<<20)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		w.Header().Set("Trailer", "X-Sum")
+		w.Header().Set("X-Proto", r.Proto)
+		w.Header().Set("X-UA", r.Header.Get("User-Agent"))
+		_, _ = w.Write(big)
+		w.Header().Set("X-Sum", strconv.FormatInt(n, 10))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	tr := New(Config{Fallback: &errRT{}, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	defer tr.CloseIdleConnections()
+
+	// 默认窗口(64KiB)下收发双向都需多轮 WINDOW_UPDATE。
+	fp := &flow.H2Fingerprint{Settings: []flow.H2Setting{{ID: uint16(http2.SettingEnablePush), Val: 0}}}
+	done := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			req := mkH2Req(t, "POST", srv.URL+"/up", big, [][2]string{{"user-agent", "UA"}, {"Host", host}}, fp)
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				done <- err
+				return
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			switch {
+			case err != nil:
+				done <- err
+			case len(b) != len(big) || resp.Header.Get("X-Proto") != "HTTP/2.0" || resp.Header.Get("X-UA") != "UA":
+				done <- io.ErrUnexpectedEOF
+			case resp.Trailer.Get("X-Sum") != strconv.Itoa(len(big)):
+				done <- io.ErrShortWrite
+			default:
+				done <- nil
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("并发 h2 请求失败: %v", err)
+			}
+		case <-time.After(20 * time.Second):
+			t.Fatal("h2 请求超时(流控死锁?)")
+		}
+	}
+	tr.mu.Lock()
+	pooled := len(tr.h2)
+	tr.mu.Unlock()
+	if pooled != 1 {
+		t.Fatalf("同一目标与指纹应复用一条 h2 连接,实有 %d 条", pooled)
+	}
+}
+
+// TestH2ConcurrentStreamsInIDOrder 高并发开 stream 时 HEADERS 须按 ID 递增上线,否则对端以
+// PROTOCOL_ERROR 关闭连接,进行中的请求全部失败。
+func TestH2ConcurrentStreamsInIDOrder(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	tr := New(Config{Fallback: &errRT{}, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	defer tr.CloseIdleConnections()
+	fp := &flow.H2Fingerprint{Settings: []flow.H2Setting{{ID: uint16(http2.SettingEnablePush), Val: 0}}}
+
+	// 先建好连接,使全部请求争用同一条 h2 连接。
+	resp, err := tr.RoundTrip(mkH2Req(t, "GET", srv.URL+"/warm", nil, [][2]string{{"Host", host}}, fp))
+	if err != nil {
+		t.Fatalf("预热: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	const workers, rounds = 200, 10
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			for j := 0; j < rounds; j++ {
+				path := "/" + strconv.Itoa(i) + "/" + strconv.Itoa(j)
+				resp, err := tr.RoundTrip(mkH2Req(t, "GET", srv.URL+path, nil, [][2]string{{"Host", host}}, fp))
+				if err != nil {
+					errs <- err
+					return
+				}
+				b, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if string(b) != path {
+					errs <- fmt.Errorf("%s: 响应体 %q", path, b)
+					return
+				}
+			}
+			errs <- nil
+		}(i)
+	}
+	for i := 0; i < workers; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("并发 h2 请求失败: %v", err)
+			}
+		case <-time.After(30 * time.Second):
+			t.Fatal("并发 h2 请求超时")
+		}
+	}
+}
+
+// TestH2FallsBackWhenUpstreamLacksH2 校验目标未协商出 h2 时回退,并缓存该结论免去重复握手。
+func TestH2FallsBackWhenUpstreamLacksH2(t *testing.T) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t)},
+		NextProtos:   []string{"http/1.1"},
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan struct{}, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.(*tls.Conn).Handshake()
+			_ = c.Close()
+			accepted <- struct{}{}
+		}
+	}()
+
+	fb := &recordRT{}
+	tr := New(Config{Fallback: fb, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	for i := 0; i < 2; i++ {
+		req := mkH2Req(t, "GET", "https://"+ln.Addr().String()+"/", nil, [][2]string{{"user-agent", "UA"}}, chromeLikeFingerprint())
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	if fb.called != 2 {
+		t.Fatalf("应两次回退,实得 %d", fb.called)
+	}
+	<-accepted
+	select {
+	case <-accepted:
+		t.Fatal("不支持 h2 的结论应被缓存,不应再次握手")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestH2PlainHTTPFallsBack 校验明文目标(h2c)不走 h2 保真路径。
+func TestH2PlainHTTPFallsBack(t *testing.T) {
+	fb := &recordRT{}
+	tr := New(Config{Fallback: fb})
+	req := mkH2Req(t, "GET", "http://127.0.0.1:1/", nil, [][2]string{{"user-agent", "UA"}}, chromeLikeFingerprint())
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	_ = resp.Body.Close()
+	if fb.called != 1 {
+		t.Fatalf("明文 h2 应回退,实得 %d", fb.called)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 主机映射(Config.MapAddr)。
+//
+// 映射只作用于直连目标,经上游代理时一律不映射(目标交由代理解析)。保真路径在直连建连处改写
+// 目标地址;回退的标准 Transport 经 DialContext 拨号,由 RoundTrip 在直连时把目标地址经 ctx
+// 传过去,只有拨向该目标时才改写。
+
+type dialTargetKeyT struct{}
+
+var dialTargetKey dialTargetKeyT
+
+// mapAddr 返回拨号 addr 时实际连接的地址。
+func (t *Transport) mapAddr(addr string) string {
+	if t.cfg.MapAddr == nil {
+		return addr
+	}
+	return t.cfg.MapAddr(addr)
+}
+
+// withDialTarget 把直连请求的目标地址放进 ctx,供回退路径拨号时识别。
+func (t *Transport) withDialTarget(req *http.Request) *http.Request {
+	if t.cfg.MapAddr == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), dialTargetKey, canonicalAddr(req.URL)))
+}
+
+// installFallbackDial 让回退的标准 Transport 拨向目标时同样按主机映射改写地址。Fallback 不是
+// *http.Transport 时由调用方自理。
+func installFallbackDial(fallback http.RoundTripper, mapAddr func(addr string) string) {
+	tr, ok := fallback.(*http.Transport)
+	if !ok {
+		return
+	}
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if target, _ := ctx.Value(dialTargetKey).(string); target != "" && strings.EqualFold(target, addr) {
+			addr = mapAddr(addr)
+		}
+		return dial(ctx, network, addr)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestMapAddr 主机映射只改变实际连接的地址:保真与回退路径都连到映射目标,
+// 源站看到的 SNI 与 Host 仍是原主机。
+func TestMapAddr(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sni := ""
+		if r.TLS != nil {
+			sni = r.TLS.ServerName
+		}
+		_, _ = io.WriteString(w, r.Host+" "+sni)
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	mapAddr := func(addr string) string {
+		switch addr {
+		case "plain.test:80":
+			return plain.Listener.Addr().String()
+		case "secure.test:443":
+			return secure.Listener.Addr().String()
+		}
+		return addr
+	}
+	for _, disabled := range []bool{false, true} {
+		var fallback http.RoundTripper = &errRT{}
+		if disabled {
+			fallback = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+		tr := New(Config{
+			Fallback:        fallback,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			MapAddr:         mapAddr,
+			Disabled:        disabled,
+		})
+		for _, c := range []struct{ url, host, want string }{
+			{"http://plain.test/a", "plain.test", "plain.test "},
+			{"https://secure.test/b", "secure.test", "secure.test secure.test"},
+		} {
+			resp, err := tr.RoundTrip(mkReq(t, "GET", c.url, nil, [][2]string{{"Host", c.host}}))
+			if err != nil {
+				t.Fatalf("disabled=%v %s: %v", disabled, c.url, err)
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if got := strings.TrimSpace(string(b)); got != strings.TrimSpace(c.want) {
+				t.Fatalf("disabled=%v %s: got %q, want %q", disabled, c.url, got, c.want)
+			}
+		}
+		tr.CloseIdleConnections()
+	}
+}
+
+// TestMapAddrSkippedBehindProxy 经上游代理时两条路径都不做映射,目标原样交给代理解析。
+func TestMapAddrSkippedBehindProxy(t *testing.T) {
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer secure.Close()
+	_, port, _ := net.SplitHostPort(secure.Listener.Addr().String())
+	sp := newSOCKSProxy(t, 0)
+	proxyURL := mustURL(t, "socks5h://user:pass@"+sp.ln.Addr().String())
+	target := "secure.test:" + port
+
+	for _, disabled := range []bool{false, true} {
+		var fallback http.RoundTripper = &errRT{}
+		if disabled {
+			fallback = &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Proxy:           http.ProxyURL(proxyURL),
+			}
+		}
+		tr := New(Config{
+			Fallback:        fallback,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			Proxy:           func(*http.Request) (*url.URL, error) { return proxyURL, nil },
+			MapAddr:         func(string) string { return "127.0.0.1:1" },
+			Disabled:        disabled,
+		})
+		resp, err := tr.RoundTrip(mkReq(t, "GET", "https://"+target+"/", nil, [][2]string{{"Host", target}}))
+		if err != nil {
+			t.Fatalf("disabled=%v: %v", disabled, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if got := <-sp.targets; got != "domain:"+target {
+			t.Fatalf("disabled=%v: 代理收到的目标 = %q", disabled, got)
+		}
+		tr.CloseIdleConnections()
+	}
+}
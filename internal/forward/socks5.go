@@ -0,0 +1,179 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/socksproto"
+)
+
+// SOCKS5 上游代理客户端:只做 CONNECT(RFC 1928),按需以用户名/密码认证(RFC 1929)。
+// socks5:// 在本地解析目标域名、向代理发送 IP;socks5h:// 把域名原样交给代理解析。
+//
+// 注意:回退的标准 http.Transport 对 socks5:// 同样把域名交给代理(与 socks5h:// 无异),
+// 因此 socks5:// 下经回退路径的请求不在本地解析。需要确定由代理解析时请用 socks5h://。
+
+// IsSOCKS 报告 proxyURL 是否为 SOCKS5 代理(socks5 / socks5h)。两者的解析差异只在本包
+// 的拨号中体现,回退的标准 Transport 一律由代理解析(见上)。
+func IsSOCKS(proxyURL *url.URL) bool {
+	return proxyURL != nil && (proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h")
+}
+
+// DialSOCKS5 经 SOCKS5 代理 proxyURL 建立到 target(host:port)的连接,代理地址缺省端口为 1080。
+// timeout 同时约束到代理的建连与协商;错误信息不含代理凭据。socks5:// 先在本地解析 target,
+// socks5h:// 把域名交给代理。
+func DialSOCKS5(ctx context.Context, proxyURL *url.URL, target string, timeout time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", canonicalAddr(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+	if err := socksConnect(ctx, conn, proxyURL, target, timeout); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// socksConnect 在已建立的代理连接上完成方法协商、认证与 CONNECT。
+func socksConnect(ctx context.Context, conn net.Conn, proxyURL *url.URL, target string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("socks5: 非法端口 %q", portStr)
+	}
+	addr, err := socksAddr(ctx, host, proxyURL.Scheme == "socks5h")
+	if err != nil {
+		return err
+	}
+
+	methods := []byte{socksproto.MethodNoAuth}
+	if proxyURL.User != nil {
+		methods = append(methods, socksproto.MethodUserPass)
+	}
+	if _, err := conn.Write(append([]byte{socksproto.Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	var buf [4]byte
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return err
+	}
+	if buf[0] != socksproto.Version {
+		return errors.New("socks5: 代理应答的协议版本不符")
+	}
+	switch buf[1] {
+	case socksproto.MethodNoAuth:
+	case socksproto.MethodUserPass:
+		if proxyURL.User == nil {
+			return errors.New("socks5: 代理要求认证")
+		}
+		if err := socksAuth(conn, proxyURL.User); err != nil {
+			return err
+		}
+	case socksproto.MethodNoAcceptable:
+		return errors.New("socks5: 代理不接受所提供的认证方式")
+	default:
+		return fmt.Errorf("socks5: 代理选择了未提供的认证方式 %#x", buf[1])
+	}
+
+	req := append([]byte{socksproto.Version, socksproto.CmdConnect, 0}, addr...)
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return err
+	}
+	if buf[0] != socksproto.Version {
+		return errors.New("socks5: 代理应答的协议版本不符")
+	}
+	if buf[1] != 0 {
+		return fmt.Errorf("socks5: 代理 CONNECT %s 失败: %s", target, socksproto.ReplyText(buf[1]))
+	}
+	// 跳过应答中的绑定地址与端口。
+	var skip int
+	switch buf[3] {
+	case socksproto.AtypIPv4:
+		skip = net.IPv4len
+	case socksproto.AtypIPv6:
+		skip = net.IPv6len
+	case socksproto.AtypDomain:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return err
+		}
+		skip = int(buf[0])
+	default:
+		return fmt.Errorf("socks5: 未知的绑定地址类型 %#x", buf[3])
+	}
+	_, err = io.CopyN(io.Discard, conn, int64(skip+2))
+	return err
+}
+
+// socksAuth 完成用户名/密码子协商(RFC 1929)。
+func socksAuth(conn net.Conn, user *url.Userinfo) error {
+	name := user.Username()
+	pass, _ := user.Password()
+	if len(name) == 0 || len(name) > 255 || len(pass) > 255 {
+		return errors.New("socks5: 用户名须为 1-255 字节、密码不超过 255 字节")
+	}
+	msg := append([]byte{socksproto.AuthVersion, byte(len(name))}, name...)
+	msg = append(msg, byte(len(pass)))
+	msg = append(msg, pass...)
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return err
+	}
+	if resp[0] != socksproto.AuthVersion {
+		return errors.New("socks5: 代理认证应答的子协商版本不符")
+	}
+	if resp[1] != socksproto.AuthSuccess {
+		return errors.New("socks5: 代理认证失败")
+	}
+	return nil
+}
+
+// socksAddr 编码目标地址(ATYP + 地址)。remoteDNS 为真时域名原样交给代理,否则先在本地解析。
+func socksAddr(ctx context.Context, host string, remoteDNS bool) ([]byte, error) {
+	ip := net.ParseIP(host)
+	if ip == nil && !remoteDNS {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		ip = ips[0]
+	}
+	if ip == nil {
+		if len(host) > 255 {
+			return nil, errors.New("socks5: 域名过长")
+		}
+		return append([]byte{socksproto.AtypDomain, byte(len(host))}, host...), nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return append([]byte{socksproto.AtypIPv4}, ip4...), nil
+	}
+	return append([]byte{socksproto.AtypIPv6}, ip.To16()...), nil
+}
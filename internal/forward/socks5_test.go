@@ -0,0 +1,201 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/socksproto"
+)
+
+// socksProxy 是测试用的 SOCKS5 代理:要求 user/pass 认证,记录收到的目标地址后中继到
+// 127.0.0.1 上的同一端口;reply 非 0 时以该应答码拒绝 CONNECT。
+type socksProxy struct {
+	ln      net.Listener
+	reply   byte
+	targets chan string
+}
+
+func newSOCKSProxy(t *testing.T, reply byte) *socksProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sp := &socksProxy{ln: ln, reply: reply, targets: make(chan string, 16)}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go sp.serve(c)
+		}
+	}()
+	return sp
+}
+
+func (sp *socksProxy) serve(c net.Conn) {
+	defer c.Close()
+	buf := make([]byte, 512)
+	if _, err := io.ReadFull(c, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(c, buf[:buf[1]]); err != nil {
+		return
+	}
+	_, _ = c.Write([]byte{socksproto.Version, socksproto.MethodUserPass})
+	// 用户名/密码子协商。
+	if _, err := io.ReadFull(c, buf[:2]); err != nil {
+		return
+	}
+	name := make([]byte, buf[1])
+	_, _ = io.ReadFull(c, name)
+	_, _ = io.ReadFull(c, buf[:1])
+	pass := make([]byte, buf[0])
+	_, _ = io.ReadFull(c, pass)
+	if string(name) != "user" || string(pass) != "pass" {
+		_, _ = c.Write([]byte{socksproto.AuthVersion, 1})
+		return
+	}
+	_, _ = c.Write([]byte{socksproto.AuthVersion, 0})
+
+	if _, err := io.ReadFull(c, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case socksproto.AtypIPv4:
+		_, _ = io.ReadFull(c, buf[:4])
+		host = "ip:" + net.IP(buf[:4]).String()
+	case socksproto.AtypDomain:
+		_, _ = io.ReadFull(c, buf[:1])
+		n := int(buf[0])
+		_, _ = io.ReadFull(c, buf[:n])
+		host = "domain:" + string(buf[:n])
+	default:
+		return
+	}
+	_, _ = io.ReadFull(c, buf[:2])
+	port := binary.BigEndian.Uint16(buf[:2])
+	sp.targets <- host + ":" + strconv.Itoa(int(port))
+	if sp.reply != 0 {
+		_, _ = c.Write([]byte{socksproto.Version, sp.reply, 0, socksproto.AtypIPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	up, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+	if err != nil {
+		return
+	}
+	defer up.Close()
+	// 绑定地址用域名形式,顺带覆盖客户端跳过变长地址的分支。
+	_, _ = c.Write([]byte{socksproto.Version, 0, 0, socksproto.AtypDomain, 1, 'x', 0, 0})
+	go func() { _, _ = io.Copy(up, c) }()
+	_, _ = io.Copy(c, up)
+}
+
+// TestSOCKS5RoundTrip 经 SOCKS5 代理的保真转发:http / https 目标都不回退,socks5 在本地解析
+// 目标、socks5h 把域名交给代理;认证失败与 CONNECT 被拒时错误可读且不含密码。
+func TestSOCKS5RoundTrip(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "plain "+r.RequestURI)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure "+r.RequestURI)
+	}))
+	defer secure.Close()
+	sp := newSOCKSProxy(t, 0)
+
+	var proxyURL *url.URL
+	tr := New(Config{
+		Fallback:        &errRT{},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Proxy:           func(*http.Request) (*url.URL, error) { return proxyURL, nil },
+	})
+	defer tr.CloseIdleConnections()
+	do := func(rawURL string) string {
+		t.Helper()
+		u := mustURL(t, rawURL)
+		resp, err := tr.RoundTrip(mkReq(t, "GET", rawURL, nil, [][2]string{{"Host", u.Host}}))
+		if err != nil {
+			t.Fatalf("RoundTrip %s: %v", rawURL, err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	_, plainPort, _ := net.SplitHostPort(plain.Listener.Addr().String())
+	_, securePort, _ := net.SplitHostPort(secure.Listener.Addr().String())
+
+	proxyURL = mustURL(t, "socks5://user:pass@"+sp.ln.Addr().String())
+	if got := do(plain.URL + "/a?x=1"); got != "plain /a?x=1" {
+		t.Fatalf("http via socks5 = %q", got)
+	}
+	if got := <-sp.targets; got != "ip:127.0.0.1:"+plainPort {
+		t.Fatalf("socks5 目标 = %q", got)
+	}
+
+	proxyURL = mustURL(t, "socks5h://user:pass@"+sp.ln.Addr().String())
+	if got := do("https://localhost:" + securePort + "/b"); got != "secure /b" {
+		t.Fatalf("https via socks5h = %q", got)
+	}
+	if got := <-sp.targets; got != "domain:localhost:"+securePort {
+		t.Fatalf("socks5h 目标 = %q", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	bad := mustURL(t, "socks5://user:secret@"+sp.ln.Addr().String())
+	if _, err := DialSOCKS5(ctx, bad, plain.Listener.Addr().String(), time.Second); err == nil ||
+		!strings.Contains(err.Error(), "认证失败") || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("认证失败应报错: %v", err)
+	}
+	refused := newSOCKSProxy(t, 0x05)
+	if _, err := DialSOCKS5(ctx, mustURL(t, "socks5://user:pass@"+refused.ln.Addr().String()), "127.0.0.1:1", time.Second); err == nil ||
+		!strings.Contains(err.Error(), "连接被拒绝") {
+		t.Fatalf("CONNECT 被拒应报错: %v", err)
+	}
+}
+
+// TestSOCKS5AuthReplyVersion 认证子协商应答的版本不是 0x01 时拒绝,即使状态码表示成功。
+func TestSOCKS5AuthReplyVersion(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		buf := make([]byte, 512)
+		_, _ = io.ReadFull(c, buf[:3]) // VER NMETHODS METHODS(2 个)
+		_, _ = io.ReadFull(c, buf[:1])
+		_, _ = c.Write([]byte{socksproto.Version, socksproto.MethodUserPass})
+		_, _ = c.Read(buf)
+		_, _ = c.Write([]byte{socksproto.Version, socksproto.AuthSuccess})
+		_, _ = c.Read(buf)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = DialSOCKS5(ctx, mustURL(t, "socks5://user:pass@"+ln.Addr().String()), "127.0.0.1:1", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "版本不符") {
+		t.Fatalf("认证应答版本不符应报错: %v", err)
+	}
+}
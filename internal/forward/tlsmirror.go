@@ -0,0 +1,212 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 上游 TLS 参数镜像。
+//
+// 开启后,对 https 目标按客户端 ClientHello 指纹(flow.TLSFingerprint)调整上游握手参数,
+// 让做 TLS 指纹校验的服务端看到与客户端尽量一致的 ClientHello。受 crypto/tls 所限,
+// 能镜像的只有:TLS 1.2 套件集合、版本范围、曲线集合、ALPN 列表与会话票据开关;
+// 套件 / 曲线顺序与扩展的种类、顺序仍由标准库决定。回退路径(标准 Transport)不镜像。
+
+const extSessionTicket = 0x0023
+
+// goCurves 是 crypto/tls 客户端能发出的曲线(含后量子混合组)。
+var goCurves = map[uint16]tls.CurveID{
+	uint16(tls.X25519):             tls.X25519,
+	uint16(tls.CurveP256):          tls.CurveP256,
+	uint16(tls.CurveP384):          tls.CurveP384,
+	uint16(tls.CurveP521):          tls.CurveP521,
+	uint16(tls.X25519MLKEM768):     tls.X25519MLKEM768,
+	uint16(tls.SecP256r1MLKEM768):  tls.SecP256r1MLKEM768,
+	uint16(tls.SecP384r1MLKEM1024): tls.SecP384r1MLKEM1024,
+}
+
+// goTLS12Suites 是 crypto/tls 可配置的 TLS 1.2 及以下套件(TLS 1.3 套件不可配置)。
+var goTLS12Suites = sync.OnceValue(func() map[uint16]bool {
+	out := make(map[uint16]bool)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, v := range s.SupportedVersions {
+			if v <= tls.VersionTLS12 {
+				out[s.ID] = true
+				break
+			}
+		}
+	}
+	return out
+})
+
+// mirrorFor 返回对该主机应镜像的客户端指纹;未开启镜像、主机不在范围内或请求不带指纹时为 nil。
+func (t *Transport) mirrorFor(ctx context.Context, host string) *flow.TLSFingerprint {
+	if t.cfg.MirrorTLS == nil || !t.cfg.MirrorTLS(host) {
+		return nil
+	}
+	fp, _ := flow.TLSFingerprintFrom(ctx)
+	return fp
+}
+
+// mirrorKey 把镜像的客户端指纹编进连接池键:ClientHello 不同的客户端不共用上游连接。
+func mirrorKey(fp *flow.TLSFingerprint) string {
+	if fp == nil {
+		return ""
+	}
+	return "\x00" + fp.JA3Hash + "|" + fp.JA4
+}
+
+// clientTLSConfig 构造发往 serverName 的 TLS 配置。alpn 为本路径能处理的协议;镜像时
+// 改用客户端的 ALPN 列表,但剔除本路径处理不了的协议(h1 路径不通告 h2)。
+func (t *Transport) clientTLSConfig(serverName string, alpn []string, mirror *flow.TLSFingerprint) *tls.Config {
+	var cfg *tls.Config
+	if t.cfg.TLSClientConfig != nil {
+		cfg = t.cfg.TLSClientConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.ServerName = serverName
+	cfg.NextProtos = alpn
+	if t.cfg.ClientCert != nil {
+		cfg.GetClientCertificate = t.clientCertificate(serverName)
+	}
+	cfg.VerifyConnection = t.verifyConnection(serverName)
+	if mirror != nil {
+		mirrorClientHello(cfg, mirror, alpn)
+	}
+	return cfg
+}
+
+// mirrorClientHello 按客户端指纹改写 cfg 中 crypto/tls 能表达的部分。客户端用到的值
+// 标准库都不支持时,对应项保持默认。
+func mirrorClientHello(cfg *tls.Config, fp *flow.TLSFingerprint, alpn []string) {
+	versions := slices.DeleteFunc(slices.Clone(fp.Versions), isGREASE)
+	if len(versions) == 0 {
+		versions = []uint16{fp.Version}
+	}
+	var lo, hi uint16
+	for _, v := range versions {
+		if v < tls.VersionTLS10 || v > tls.VersionTLS13 {
+			continue
+		}
+		if lo == 0 || v < lo {
+			lo = v
+		}
+		hi = max(hi, v)
+	}
+	if hi != 0 {
+		cfg.MinVersion, cfg.MaxVersion = lo, hi
+	}
+
+	var suites []uint16
+	for _, c := range fp.Ciphers {
+		if goTLS12Suites()[c] {
+			suites = append(suites, c)
+		}
+	}
+	if len(suites) > 0 {
+		cfg.CipherSuites = suites
+	}
+
+	var curves []tls.CurveID
+	for _, c := range fp.Curves {
+		if id, ok := goCurves[c]; ok {
+			curves = append(curves, id)
+		}
+	}
+	if len(curves) > 0 {
+		cfg.CurvePreferences = curves
+	}
+
+	var protos []string
+	for _, p := range fp.ALPN {
+		if slices.Contains(alpn, p) {
+			protos = append(protos, p)
+		}
+	}
+	cfg.NextProtos = protos
+
+	if !slices.Contains(fp.Extensions, extSessionTicket) {
+		cfg.SessionTicketsDisabled = true
+	}
+}
+
+// isGREASE 判断是否为 RFC 8701 的 GREASE 值。
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// handshake 在 raw 上完成 TLS 握手;配置了 HelloFingerprint 时一并记下本端发出的 ClientHello 指纹。
+// 失败时关闭 raw。
+func (t *Transport) handshake(ctx context.Context, raw net.Conn, cfg *tls.Config) (*tls.Conn, *flow.TLSFingerprint, error) {
+	var rec *helloRecorder
+	if t.cfg.HelloFingerprint != nil {
+		rec = &helloRecorder{Conn: raw}
+		raw = rec
+	}
+	tc := tls.Client(raw, cfg)
+	_ = tc.SetDeadline(time.Now().Add(t.cfg.TLSTimeout))
+	traceTLSStart(ctx)
+	err := tc.HandshakeContext(ctx)
+	traceTLSDone(ctx, tc.ConnectionState(), err)
+	if err != nil {
+		_ = raw.Close()
+		return nil, nil, err
+	}
+	_ = tc.SetDeadline(time.Time{})
+	if rec == nil {
+		return tc, nil, nil
+	}
+	return tc, t.cfg.HelloFingerprint(rec.stop()), nil
+}
+
+// helloRecorder 记下握手期间写出的字节(ClientHello 在最前),握手结束后不再记录。
+type helloRecorder struct {
+	net.Conn
+	mu   sync.Mutex
+	buf  []byte
+	done bool
+}
+
+// maxHelloRecord 限制记录量:ClientHello 连同后量子密钥共享也远小于此。
+const maxHelloRecord = 64 * 1024
+
+func (r *helloRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if !r.done && len(r.buf) < maxHelloRecord {
+		r.buf = append(r.buf, p[:min(len(p), maxHelloRecord-len(r.buf))]...)
+	}
+	r.mu.Unlock()
+	return r.Conn.Write(p)
+}
+
+// stop 结束记录并返回已记下的字节。
+func (r *helloRecorder) stop() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = true
+	b := r.buf
+	r.buf = nil
+	return b
+}
+
+// recordUpstreamTLS 把承载本请求的上游连接发出的 ClientHello 指纹回填给 flow.ResponseCapture。
+func recordUpstreamTLS(ctx context.Context, fp *flow.TLSFingerprint) {
+	if fp == nil {
+		return
+	}
+	if rc, ok := flow.ResponseCaptureFrom(ctx); ok {
+		rc.UpstreamTLS = fp
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestMirrorClientHello 只镜像 crypto/tls 能表达的部分:GREASE 与未知值被剔除,
+// ALPN 限于本路径能处理的协议。
+func TestMirrorClientHello(t *testing.T) {
+	fp := &flow.TLSFingerprint{
+		Version:    0x0303,
+		Versions:   []uint16{0x0a0a, 0x0304, 0x0303},
+		Ciphers:    []uint16{0x0a0a, 0x1301, 0xc02b, 0x1234, 0xc02f},
+		Curves:     []uint16{0x1a1a, uint16(tls.X25519MLKEM768), uint16(tls.X25519), 0x0100},
+		Extensions: []uint16{0x0000, 0x0010, 0x0023},
+		ALPN:       []string{"h2", "http/1.1"},
+	}
+	cfg := &tls.Config{}
+	mirrorClientHello(cfg, fp, []string{"http/1.1"})
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.MaxVersion != tls.VersionTLS13 {
+		t.Fatalf("版本范围 = %#x..%#x", cfg.MinVersion, cfg.MaxVersion)
+	}
+	if !slices.Equal(cfg.CipherSuites, []uint16{0xc02b, 0xc02f}) {
+		t.Fatalf("套件 = %#x", cfg.CipherSuites)
+	}
+	if !slices.Equal(cfg.CurvePreferences, []tls.CurveID{tls.X25519MLKEM768, tls.X25519}) {
+		t.Fatalf("曲线 = %v", cfg.CurvePreferences)
+	}
+	if !slices.Equal(cfg.NextProtos, []string{"http/1.1"}) || cfg.SessionTicketsDisabled {
+		t.Fatalf("ALPN = %v, 票据禁用 = %v", cfg.NextProtos, cfg.SessionTicketsDisabled)
+	}
+
+	// 没有 supported_versions 时以 legacy_version 为准;没带会话票据扩展则关闭票据。
+	cfg = &tls.Config{}
+	mirrorClientHello(cfg, &flow.TLSFingerprint{Version: 0x0303}, []string{"h2", "http/1.1"})
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.MaxVersion != tls.VersionTLS12 || !cfg.SessionTicketsDisabled {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+	if cfg.CipherSuites != nil || cfg.CurvePreferences != nil || cfg.NextProtos != nil {
+		t.Fatal("客户端未提供的项应保持默认")
+	}
+}
+
+// TestTLSMirrorUpstream 开启镜像的主机上,服务端看到按客户端指纹调整过的 ClientHello,
+// 且本端发出的 ClientHello 经 ResponseCapture 回填;未开启的主机保持默认握手。
+func TestTLSMirrorUpstream(t *testing.T) {
+	cert := selfSignedCert(t)
+	hellos := make(chan *tls.ClientHelloInfo, 4)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+		GetConfigForClient: func(h *tls.ClientHelloInfo) (*tls.Config, error) {
+			hellos <- h
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				br := bufio.NewReader(c)
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == "\r\n" {
+						_, _ = c.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+
+	var records [][]byte
+	mirror := true
+	tr := New(Config{
+		Fallback:        &errRT{},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		MirrorTLS:       func(host string) bool { return mirror && host == "127.0.0.1" },
+		HelloFingerprint: func(record []byte) *flow.TLSFingerprint {
+			records = append(records, record)
+			return &flow.TLSFingerprint{JA4: "upstream"}
+		},
+	})
+	client := &flow.TLSFingerprint{
+		JA3Hash:  "h",
+		Version:  0x0303,
+		Versions: []uint16{0x0303},
+		Ciphers:  []uint16{0x2a2a, 0xc02c, 0xc02b},
+		Curves:   []uint16{0x2a2a, uint16(tls.CurveP256)},
+		ALPN:     []string{"http/1.1"},
+	}
+	roundTrip := func() *flow.ResponseCapture {
+		t.Helper()
+		ordered := [][2]string{{"Host", ln.Addr().String()}}
+		req := mkReq(t, "GET", "https://"+ln.Addr().String()+"/", nil, ordered)
+		rc := &flow.ResponseCapture{}
+		ctx := flow.WithResponseCapture(flow.WithTLSFingerprint(req.Context(), client), rc)
+		resp, err := tr.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return rc
+	}
+
+	rc := roundTrip()
+	h := <-hellos
+	if !slices.Equal(h.SupportedVersions, []uint16{tls.VersionTLS12}) {
+		t.Fatalf("服务端看到的版本 = %#x", h.SupportedVersions)
+	}
+	got := slices.Clone(h.CipherSuites)
+	slices.Sort(got)
+	if !slices.Equal(got, []uint16{0xc02b, 0xc02c}) {
+		t.Fatalf("服务端看到的套件 = %#x", h.CipherSuites)
+	}
+	if !slices.Equal(h.SupportedCurves, []tls.CurveID{tls.CurveP256}) || !slices.Equal(h.SupportedProtos, []string{"http/1.1"}) {
+		t.Fatalf("服务端看到的曲线 / ALPN = %v / %v", h.SupportedCurves, h.SupportedProtos)
+	}
+	if rc.UpstreamTLS == nil || rc.UpstreamTLS.JA4 != "upstream" {
+		t.Fatalf("上游指纹未回填: %+v", rc.UpstreamTLS)
+	}
+	if len(records) != 1 || len(records[0]) < 5 || records[0][0] != 0x16 {
+		t.Fatalf("应记下以握手记录开头的字节: %d 条", len(records))
+	}
+
+	// 复用连接的请求同样回填建连时的指纹。
+	if rc := roundTrip(); rc.UpstreamTLS == nil || len(records) != 1 {
+		t.Fatalf("复用连接应沿用指纹且不重新握手: %+v, %d", rc.UpstreamTLS, len(records))
+	}
+
+	// 关闭镜像后池键不同,新建的连接按默认参数握手。
+	mirror = false
+	roundTrip()
+	if h := <-hellos; !slices.Contains(h.SupportedVersions, tls.VersionTLS13) {
+		t.Fatalf("未镜像时应按默认版本握手: %#x", h.SupportedVersions)
+	}
+}
+
+func TestMirrorForNeedsFingerprint(t *testing.T) {
+	tr := New(Config{Fallback: &errRT{}, MirrorTLS: func(string) bool { return true }})
+	if tr.mirrorFor(context.Background(), "a.com") != nil {
+		t.Fatal("请求不带客户端指纹时不应镜像")
+	}
+	if mirrorKey(nil) != "" {
+		t.Fatal("不镜像时池键不变")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 分阶段计时。
+//
+// 计时走 net/http/httptrace:回退的标准 Transport 自行回调全部钩子;保真路径的拨号经
+// net.Dialer 也会回调 DNS / 建连钩子,其余(TLS、取得连接、写完请求、响应首字节)由本包
+// 在对应位置补调。
+
+// withPhaseTrace 在请求 ctx 中装入分阶段计时钩子,收集结果经 flow.ResponseCapture 交回。
+// 未装入收集器或已装入计时时原样返回。
+func withPhaseTrace(req *http.Request) *http.Request {
+	rc, ok := flow.ResponseCaptureFrom(req.Context())
+	if !ok || rc.Trace != nil {
+		return req
+	}
+	rc.Trace = &flow.PhaseTrace{}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), rc.Trace.ClientTrace()))
+}
+
+func traceTLSStart(ctx context.Context) {
+	if tr := httptrace.ContextClientTrace(ctx); tr != nil && tr.TLSHandshakeStart != nil {
+		tr.TLSHandshakeStart()
+	}
+}
+
+func traceTLSDone(ctx context.Context, cs tls.ConnectionState, err error) {
+	if tr := httptrace.ContextClientTrace(ctx); tr != nil && tr.TLSHandshakeDone != nil {
+		tr.TLSHandshakeDone(cs, err)
+	}
+}
+
+func traceGotConn(ctx context.Context, c net.Conn, reused bool) {
+	if tr := httptrace.ContextClientTrace(ctx); tr != nil && tr.GotConn != nil {
+		tr.GotConn(httptrace.GotConnInfo{Conn: c, Reused: reused})
+	}
+}
+
+func traceWroteRequest(ctx context.Context, err error) {
+	if tr := httptrace.ContextClientTrace(ctx); tr != nil && tr.WroteRequest != nil {
+		tr.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+	}
+}
+
+func traceFirstByte(ctx context.Context) {
+	if tr := httptrace.ContextClientTrace(ctx); tr != nil && tr.GotFirstResponseByte != nil {
+		tr.GotFirstResponseByte()
+	}
+}
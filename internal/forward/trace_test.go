@@ -0,0 +1,65 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestPhaseTrace 保真路径与回退路径都回填分阶段计时:新连接有建连与 TLS,复用连接没有;
+// 目标为 IP 时没有 DNS。
+func TestPhaseTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	fb := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer fb.CloseIdleConnections()
+	tr := New(Config{Fallback: fb, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	defer tr.CloseIdleConnections()
+
+	roundTrip := func(r *http.Request) *flow.Phases {
+		t.Helper()
+		rc := &flow.ResponseCapture{}
+		resp, err := tr.RoundTrip(r.WithContext(flow.WithResponseCapture(r.Context(), rc)))
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		ph := rc.Trace.Phases(time.Now())
+		if ph == nil {
+			t.Fatal("未回填分阶段计时")
+		}
+		return ph
+	}
+	faithful := func() *http.Request {
+		return mkReq(t, "GET", srv.URL+"/", nil, [][2]string{{"Host", srv.Listener.Addr().String()}})
+	}
+	plain := func() *http.Request {
+		req, _ := http.NewRequest("GET", srv.URL+"/", nil)
+		return req
+	}
+
+	for name, newReq := range map[string]func() *http.Request{"faithful": faithful, "fallback": plain} {
+		ph := roundTrip(newReq())
+		if ph.Reused || ph.DNSMs != -1 || ph.ConnectMs < 0 || ph.TLSMs < 0 || ph.SendMs < 0 || ph.WaitMs < 0 || ph.ReceiveMs < 0 {
+			t.Fatalf("%s 新连接: %+v", name, ph)
+		}
+		if ph.RemoteAddr != srv.Listener.Addr().String() {
+			t.Fatalf("%s RemoteAddr = %q", name, ph.RemoteAddr)
+		}
+		ph = roundTrip(newReq())
+		if !ph.Reused || ph.ConnectMs != -1 || ph.TLSMs != -1 || ph.WaitMs < 0 {
+			t.Fatalf("%s 复用连接: %+v", name, ph)
+		}
+	}
+}
@@ -3,17 +3,20 @@
 // Use of this source code is governed by an Apache 2.0
 // license that can be found in the LICENSE file.
 
-// Package forward 提供「无侵入」的 HTTP/1.x 保真转发。
+// Package forward 提供「无侵入」的 HTTP 保真转发。
 //
 // 动机:Go 的 http.Transport 在写出请求时会把头按字母排序、规范化头名大小写、
 // 强制 Host 在最前、并按需注入 User-Agent / Accept-Encoding —— 这些都是反爬 / 防篡改
 // 系统会校验的指纹特征。stdlib 没有任何 hook 能保留原始顺序与大小写,故本包绕开
 // http.Transport 的请求序列化,直接在自管连接上按「最终线缆头序列」(由 flow 层经 ctx
 // 传入,保留客户端原始顺序+大小写)写线,从而做到 HTTP/1.x 请求无侵入透传。
+// h2 入站的请求另带 h2 线缆特征(flow.H2Fingerprint),在上游 h2 连接上回放(见 h2.go)。
+// https 上游连接还可按客户端的 ClientHello 指纹镜像 TLS 参数(见 tlsmirror.go)。
 //
-// 适用边界:仅处理 HTTP/1.x。下列情形自动回退到注入的标准 http.RoundTripper(Fallback):
-//   - ctx 中没有保真头序列(h2 入站、头块过大等);
-//   - 目标经 ALPN 只会 h2(此时对 http/1.1 的 TLS 握手会失败,按回退处理);
+// 下列情形自动回退到注入的标准 http.RoundTripper(Fallback):
+//   - ctx 中没有保真头序列(头块过大、合成请求等);
+//   - HTTP/1.x 入站而目标经 ALPN 只会 h2(此时对 http/1.1 的 TLS 握手会失败,按回退处理);
+//   - h2 入站而目标不支持 h2,或目标为明文 http;
 //   - CONNECT / Upgrade 等非普通请求;
 //   - 连接/握手等「尚未发出请求」的前置失败。
 package forward
@@ -35,6 +38,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"weak"
 
 	"github.com/mintfog/sniffy/internal/flow"
 )
@@ -45,7 +49,8 @@ type Config struct {
 	Fallback http.RoundTripper
 	// Proxy 返回某请求应使用的上游代理(nil 表示直连)。可为 nil(恒直连)。
 	Proxy func(*http.Request) (*url.URL, error)
-	// TLSClientConfig 用于 https 目标(本包会克隆并强制 ALPN 为 http/1.1)。可为 nil。
+	// TLSClientConfig 用于 https 目标(本包会克隆并改写 ALPN:h1 路径只通告 http/1.1,
+	// h2 路径通告 h2 与 http/1.1)。可为 nil。
 	TLSClientConfig *tls.Config
 
 	DialTimeout       time.Duration // 建连超时
@@ -60,16 +65,41 @@ type Config struct {
 	// 故以体积阈值换取健壮性。<=0 时取默认 1MiB。
 	MaxFaithfulBody int
 
+	// MirrorTLS 报告是否对该主机镜像客户端的 TLS 参数(见 tlsmirror.go)。可为 nil(不镜像)。
+	MirrorTLS func(host string) bool
+	// HelloFingerprint 由本端发出的 ClientHello 记录(握手期间写出的原始字节)计算指纹,
+	// 经 flow.ResponseCapture 记入 flow。可为 nil(不记录)。
+	HelloFingerprint func(record []byte) *flow.TLSFingerprint
+	// ClientCert 返回上游索要客户端证书(mTLS)时向该主机出示的证书,nil 表示不出示。
+	// 可为 nil。Fallback 为 *http.Transport 时 New 会在其 TLS 配置上一并装好(见 clientcert.go)。
+	ClientCert func(host string) *tls.Certificate
+	// VerifyUpstream 在握手收尾时校验上游证书(host 为目标主机,可能为空,见 verify.go),返回非 nil
+	// 即中止握手、不再回退;需按 flow.UpstreamCertError 返回才能被识别为证书被拒。可为 nil(不校验)。
+	// Fallback 为 *http.Transport 时 New 会在其 TLS 配置上一并装好。
+	VerifyUpstream func(host string, cs tls.ConnectionState) error
+	// UpstreamConn 由握手结果生成上游 TLS 连接摘要,经 flow.ResponseCapture 记入 flow。可为 nil(不记录)。
+	UpstreamConn func(host string, cs tls.ConnectionState) *flow.UpstreamConn
+	// MapAddr 返回拨号目标 addr(host:port)时实际连接的地址(主机映射),SNI 与 Host 头不变。
+	// 只作用于直连目标:经任何上游代理(HTTP、HTTPS、SOCKS5)时目标由代理解析,不做映射,
+	// 保真与回退路径一致。可为 nil(不映射)。Fallback 为 *http.Transport 时 New 会在其
+	// DialContext 上一并装好(见 mapaddr.go)。
+	MapAddr func(addr string) string
+
 	// Disabled 为 true 时一律走 Fallback(运维兜底开关)。
 	Disabled bool
 }
 
-// Transport 是保真 HTTP/1.x RoundTripper,内含简单的 keep-alive 连接池。
+// Transport 是保真 RoundTripper,内含简单的 keep-alive 连接池(h2 连接单独成池)。
 type Transport struct {
 	cfg Config
 
 	mu   sync.Mutex
 	idle map[string][]*persistConn
+	h2   map[string]*h2ClientConn // h2 保真连接,键见 roundTripH2
+	noH2 map[string]time.Time     // 未协商出 h2 的目标 → 该结论的过期时间
+
+	ipFallback map[string]*http.Transport                               // IP 目标 → 绑定该 IP 校验的回退克隆,见 verify.go
+	fbConnInfo map[weak.Pointer[tls.ConnectionState]]*flow.UpstreamConn // 回退路径的连接摘要缓存
 }
 
 // New 构造保真转发器并填充缺省超时。
@@ -92,7 +122,24 @@ func New(cfg Config) *Transport {
 	if cfg.MaxFaithfulBody <= 0 {
 		cfg.MaxFaithfulBody = 1 << 20 // 1MiB
 	}
-	return &Transport{cfg: cfg, idle: make(map[string][]*persistConn)}
+	if cfg.ClientCert != nil {
+		installFallbackClientCert(cfg.Fallback, cfg.ClientCert)
+	}
+	if cfg.VerifyUpstream != nil {
+		installFallbackVerify(cfg.Fallback, cfg.VerifyUpstream)
+	}
+	if cfg.MapAddr != nil {
+		installFallbackDial(cfg.Fallback, cfg.MapAddr)
+	}
+	return &Transport{
+		cfg:  cfg,
+		idle: make(map[string][]*persistConn),
+		h2:   make(map[string]*h2ClientConn),
+		noH2: make(map[string]time.Time),
+
+		ipFallback: make(map[string]*http.Transport),
+		fbConnInfo: make(map[weak.Pointer[tls.ConnectionState]]*flow.UpstreamConn),
+	}
 }
 
 // ResolveProxy 返回对该请求会选用的上游代理(nil=直连),供引擎层切换代理与自检使用。
@@ -109,6 +156,8 @@ type persistConn struct {
 	br     *bufio.Reader
 	key    string
 	idleAt time.Time
+	hello  *flow.TLSFingerprint // 建连时发出的 ClientHello 指纹(https 且开启记录时)
+	tlsc   *flow.UpstreamConn   // TLS 连接摘要(https 且开启记录时)
 	// broken 标记连接已不可复用。可能由读写出错的读取方与 ctx 取消守护(connGuard)
 	// 并发置位,故用原子量。
 	broken    atomic.Bool
@@ -165,8 +214,17 @@ func (g *connGuard) disarm() bool {
 
 // RoundTrip 实现 http.RoundTripper。
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = withPhaseTrace(t.withClientCert(req))
+	var proxyURL *url.URL
+	if t.cfg.Proxy != nil {
+		proxyURL, _ = t.cfg.Proxy(req)
+	}
+	if proxyURL == nil {
+		req = t.withDialTarget(req)
+	}
+	recordRoute(req.Context(), proxyURL)
 	ordered, ok := flow.OrderedHeadersFrom(req.Context())
-	if t.cfg.Disabled || !ok || req.Method == http.MethodConnect || isUpgrade(req) {
+	if t.cfg.Disabled || !ok || req.Method == http.MethodConnect || isUpgrade(req) || !dialableProxy(proxyURL) {
 		return t.fallback(req, nil)
 	}
 	// 读出 body(已是内存中的 identity 字节),以便回退 / 重试时可重发。
@@ -176,15 +234,14 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		_ = req.Body.Close()
 	}
 
+	if fp, ok := flow.H2FingerprintFrom(req.Context()); ok {
+		return t.roundTripH2(req, ordered, fp, body, proxyURL) // h2 入站:走上游 h2,回放客户端的帧特征
+	}
 	if len(body) > t.cfg.MaxFaithfulBody {
 		return t.fallback(req, body) // 超大请求体:交并发读写的标准 Transport 以防流控死锁
 	}
 
-	var proxyURL *url.URL
-	if t.cfg.Proxy != nil {
-		proxyURL, _ = t.cfg.Proxy(req)
-	}
-	absForm := proxyURL != nil && req.URL.Scheme == "http"
+	absForm := proxyURL != nil && !IsSOCKS(proxyURL) && req.URL.Scheme == "http"
 	if absForm {
 		ordered = withProxyAuthorization(ordered, proxyURL)
 	}
@@ -193,18 +250,26 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	for attempt := 0; attempt < 2; attempt++ {
 		pc, fromIdle, h2, err := t.acquire(req.Context(), req.URL, proxyURL)
 		if err != nil {
+			if isCertRejected(err) {
+				return nil, err // 证书被拒:回退路径同样会拒,不再重试
+			}
 			return t.fallback(req, body) // 建连/握手失败:请求尚未发出,可安全回退
 		}
 		if h2 {
 			pc.close()
 			return t.fallback(req, body) // 目标走 h2:本包只说 h1,交回退
 		}
+		recordUpstreamTLS(req.Context(), pc.hello)
+		recordUpstreamConn(req.Context(), pc.tlsc)
+		traceGotConn(req.Context(), pc.conn, fromIdle)
 
 		// 裸 conn 不感知 ctx;装守护,在 ctx 取消(含 http.Client.Timeout、客户端断开)时
 		// 关连接以打断阻塞的写 / 读头 / 读体。读头成功后守护移交响应体,继续覆盖读体阶段。
 		guard := newConnGuard(req.Context(), pc)
 
-		if err := writeFaithfulRequest(pc, req, ordered, body, absForm); err != nil {
+		err = writeFaithfulRequest(pc, req, ordered, body, absForm)
+		traceWroteRequest(req.Context(), err)
+		if err != nil {
 			guard.disarm()
 			pc.broken.Store(true)
 			pc.close()
@@ -268,13 +333,20 @@ func (t *Transport) fallback(req *http.Request, body []byte) (*http.Response, er
 		req.Body = io.NopCloser(bytes.NewReader(body))
 		req.ContentLength = int64(len(body))
 	}
-	return t.cfg.Fallback.RoundTrip(req)
+	resp, err := t.fallbackFor(req).RoundTrip(req)
+	if err != nil || resp.TLS == nil {
+		return resp, err
+	}
+	return t.checkFallbackTLS(req, resp)
 }
 
 // acquire 取一条可用连接:优先复用空闲连接,否则新建。
 // 返回 (连接, 是否来自空闲池, 是否协商成 h2, err)。
 func (t *Transport) acquire(ctx context.Context, u *url.URL, proxyURL *url.URL) (*persistConn, bool, bool, error) {
 	key := connKey(u, proxyURL)
+	if u.Scheme == "https" {
+		key += mirrorKey(t.mirrorFor(ctx, hostname(u)))
+	}
 	if pc := t.getIdle(key); pc != nil {
 		return pc, true, false, nil
 	}
@@ -284,70 +356,77 @@ func (t *Transport) acquire(ctx context.Context, u *url.URL, proxyURL *url.URL)
 
 // dial 新建一条到目标的连接(按需经上游代理、按需 TLS)。
 func (t *Transport) dial(ctx context.Context, u *url.URL, proxyURL *url.URL, key string) (*persistConn, bool, error) {
-	d := &net.Dialer{Timeout: t.cfg.DialTimeout}
-	target := canonicalAddr(u)
-
-	if proxyURL == nil {
-		raw, err := d.DialContext(ctx, "tcp", target)
+	if u.Scheme == "http" {
+		if IsSOCKS(proxyURL) {
+			raw, err := DialSOCKS5(ctx, proxyURL, canonicalAddr(u), t.cfg.DialTimeout)
+			if err != nil {
+				return nil, false, err
+			}
+			return newPersistConn(raw, key), false, nil
+		}
+		d := &net.Dialer{Timeout: t.cfg.DialTimeout}
+		addr := t.mapAddr(canonicalAddr(u))
+		if proxyURL != nil {
+			// 明文 http 经代理:用绝对形式请求行,直接走代理,无需 CONNECT / TLS。
+			addr = canonicalAddr(proxyURL)
+		}
+		raw, err := d.DialContext(ctx, "tcp", addr)
 		if err != nil {
 			return nil, false, err
 		}
-		if u.Scheme == "https" {
-			tc, h2, err := t.tlsHandshake(ctx, raw, hostname(u))
-			if err != nil || h2 {
-				return nil, h2, err
-			}
-			return newPersistConn(tc, key), false, nil
-		}
 		return newPersistConn(raw, key), false, nil
 	}
-
-	// 经上游代理。
-	praw, err := d.DialContext(ctx, "tcp", canonicalAddr(proxyURL))
+	raw, err := t.dialTLSTarget(ctx, u, proxyURL)
 	if err != nil {
 		return nil, false, err
 	}
-	if u.Scheme == "http" {
-		// 明文 http:用绝对形式请求行,直接走代理,无需 CONNECT / TLS。
-		return newPersistConn(praw, key), false, nil
+	tc, hello, h2, err := t.tlsHandshake(ctx, raw, hostname(u))
+	if err != nil || h2 {
+		return nil, h2, err
+	}
+	pc := newPersistConn(tc, key)
+	pc.hello = hello
+	pc.tlsc = t.upstreamConnInfo(hostname(u), tc)
+	return pc, false, nil
+}
+
+// dialTLSTarget 建立到 https 目标的原始连接(直连,或经上游代理的 CONNECT 隧道 / SOCKS5),
+// TLS 握手由调用方进行。
+func (t *Transport) dialTLSTarget(ctx context.Context, u *url.URL, proxyURL *url.URL) (net.Conn, error) {
+	d := &net.Dialer{Timeout: t.cfg.DialTimeout}
+	target := canonicalAddr(u)
+	if proxyURL == nil {
+		return d.DialContext(ctx, "tcp", t.mapAddr(target))
+	}
+	if IsSOCKS(proxyURL) {
+		return DialSOCKS5(ctx, proxyURL, target, t.cfg.DialTimeout)
+	}
+	praw, err := d.DialContext(ctx, "tcp", canonicalAddr(proxyURL))
+	if err != nil {
+		return nil, err
 	}
-	// https:先 CONNECT 隧道,再在隧道上 TLS。
 	if err := proxyConnect(praw, target, proxyURL, t.cfg.DialTimeout); err != nil {
 		_ = praw.Close()
-		return nil, false, err
-	}
-	tc, h2, err := t.tlsHandshake(ctx, praw, hostname(u))
-	if err != nil || h2 {
-		return nil, h2, err
+		return nil, err
 	}
-	return newPersistConn(tc, key), false, nil
+	return praw, nil
 }
 
-// tlsHandshake 在原始连接上完成 TLS 握手,只通告 http/1.1。
+// tlsHandshake 在原始连接上完成 TLS 握手,只通告 http/1.1(镜像时按客户端的 ALPN 列表,
+// 但不含 h2),并返回本端发出的 ClientHello 指纹。
 // 若对端协商成 h2(仅当其忽略 ALPN 时)则返回 h2=true,交由上层回退。
 // 对 h2-only 源站,只通告 http/1.1 的握手会失败,同样按回退处理。
-func (t *Transport) tlsHandshake(ctx context.Context, raw net.Conn, serverName string) (net.Conn, bool, error) {
-	var cfg *tls.Config
-	if t.cfg.TLSClientConfig != nil {
-		cfg = t.cfg.TLSClientConfig.Clone()
-	} else {
-		cfg = &tls.Config{}
-	}
-	cfg.ServerName = serverName
-	cfg.NextProtos = []string{"http/1.1"}
-
-	tc := tls.Client(raw, cfg)
-	_ = tc.SetDeadline(time.Now().Add(t.cfg.TLSTimeout))
-	if err := tc.HandshakeContext(ctx); err != nil {
-		_ = raw.Close()
-		return nil, false, err
+func (t *Transport) tlsHandshake(ctx context.Context, raw net.Conn, serverName string) (net.Conn, *flow.TLSFingerprint, bool, error) {
+	cfg := t.clientTLSConfig(serverName, []string{"http/1.1"}, t.mirrorFor(ctx, serverName))
+	tc, hello, err := t.handshake(ctx, raw, cfg)
+	if err != nil {
+		return nil, nil, false, err
 	}
-	_ = tc.SetDeadline(time.Time{})
 	if tc.ConnectionState().NegotiatedProtocol == "h2" {
 		_ = tc.Close()
-		return nil, true, nil
+		return nil, nil, true, nil
 	}
-	return tc, false, nil
+	return tc, hello, false, nil
 }
 
 func newPersistConn(conn net.Conn, key string) *persistConn {
@@ -442,6 +521,9 @@ func readResponse(pc *persistConn, req *http.Request, respHeaderTimeout time.Dur
 	if respHeaderTimeout > 0 {
 		_ = pc.conn.SetReadDeadline(time.Now().Add(respHeaderTimeout))
 	}
+	if _, err := pc.br.Peek(1); err == nil {
+		traceFirstByte(req.Context())
+	}
 	statusLine, rawHdr := peekResponseHead(pc.br)
 	resp, err := http.ReadResponse(pc.br, req)
 	if err != nil {
@@ -607,17 +689,49 @@ func (t *Transport) CloseIdleConnections() {
 	t.mu.Lock()
 	idle := t.idle
 	t.idle = make(map[string][]*persistConn)
+	h2 := make([]*h2ClientConn, 0, len(t.h2))
+	for _, cc := range t.h2 {
+		h2 = append(h2, cc)
+	}
+	ipFallback := t.ipFallback
+	t.ipFallback = make(map[string]*http.Transport)
 	t.mu.Unlock()
+	for _, c := range ipFallback {
+		c.CloseIdleConnections()
+	}
 	for _, list := range idle {
 		for _, pc := range list {
 			pc.close()
 		}
 	}
+	for _, cc := range h2 {
+		cc.closeIfIdle()
+	}
 	if c, ok := t.cfg.Fallback.(interface{ CloseIdleConnections() }); ok {
 		c.CloseIdleConnections()
 	}
 }
 
+// dialableProxy 报告本包能否经 proxyURL 出站:直连(nil)、HTTP(S) 代理(CONNECT / 绝对形式
+// 请求)或 SOCKS5 代理。其余交回退的标准 Transport。
+func dialableProxy(proxyURL *url.URL) bool {
+	return proxyURL == nil || proxyURL.Scheme == "http" || proxyURL.Scheme == "https" || IsSOCKS(proxyURL)
+}
+
+// recordRoute 把本请求选用的上游路由回填给 flow.ResponseCapture:直连记为 DIRECT,
+// 经代理记为不含凭据的代理地址。
+func recordRoute(ctx context.Context, proxyURL *url.URL) {
+	rc, ok := flow.ResponseCaptureFrom(ctx)
+	if !ok {
+		return
+	}
+	if proxyURL == nil {
+		rc.Route = flow.RouteDirect
+		return
+	}
+	rc.Route = proxyURL.Scheme + "://" + proxyURL.Host
+}
+
 // proxyConnect 经上游代理建立到 target 的 CONNECT 隧道。
 func proxyConnect(conn net.Conn, target string, proxyURL *url.URL, timeout time.Duration) error {
 	_ = conn.SetDeadline(time.Now().Add(timeout))
@@ -688,9 +802,12 @@ func connKey(u *url.URL, proxyURL *url.URL) string {
 func canonicalAddr(u *url.URL) string {
 	port := u.Port()
 	if port == "" {
-		if u.Scheme == "https" {
+		switch {
+		case u.Scheme == "https":
 			port = "443"
-		} else {
+		case IsSOCKS(u):
+			port = "1080"
+		default:
 			port = "80"
 		}
 	}
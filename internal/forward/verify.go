@@ -0,0 +1,148 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+	"weak"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// 上游证书校验与连接摘要。
+//
+// 校验(Config.VerifyUpstream)挂在 tls.Config.VerifyConnection 上,在握手收尾时进行,未通过即
+// 中止握手,请求不会发出,也不再回退重试。回退的标准 Transport 握手时只知道 SNI,而 IP 目标不带
+// SNI:这类请求改走按 IP 克隆的回退 Transport,其 VerifyConnection 绑定该 IP(见 fallbackFor)。
+//
+// 回退路径的连接摘要按 TLS 连接缓存:标准 Transport 让同一连接上的响应共用一份 *tls.ConnectionState,
+// 以其弱引用为键,连接回收后条目随之清除。
+
+// verifyConnection 返回握手时校验 host 证书的回调;未配置校验时为 nil。
+func (t *Transport) verifyConnection(host string) func(tls.ConnectionState) error {
+	if t.cfg.VerifyUpstream == nil {
+		return nil
+	}
+	return func(cs tls.ConnectionState) error { return t.cfg.VerifyUpstream(host, cs) }
+}
+
+// installFallbackVerify 让回退的标准 Transport 在握手时同样校验上游证书。Fallback 不是
+// *http.Transport 时由调用方自理。
+func installFallbackVerify(fallback http.RoundTripper, verify func(host string, cs tls.ConnectionState) error) {
+	tr, ok := fallback.(*http.Transport)
+	if !ok {
+		return
+	}
+	var cfg *tls.Config
+	if tr.TLSClientConfig != nil {
+		cfg = tr.TLSClientConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error { return verify(cs.ServerName, cs) }
+	tr.TLSClientConfig = cfg
+}
+
+// isCertRejected 报告 err 是否为上游证书未通过校验:此时换路径重试只会再被拒一次。
+func isCertRejected(err error) bool {
+	var certErr *flow.UpstreamCertError
+	return errors.As(err, &certErr)
+}
+
+// upstreamConnInfo 生成上游 TLS 连接的摘要;未配置 Config.UpstreamConn 或非 TLS 连接时为 nil。
+func (t *Transport) upstreamConnInfo(host string, c net.Conn) *flow.UpstreamConn {
+	tc, ok := c.(*tls.Conn)
+	if !ok || t.cfg.UpstreamConn == nil {
+		return nil
+	}
+	return t.cfg.UpstreamConn(host, tc.ConnectionState())
+}
+
+// maxIPFallbacks 是按 IP 克隆的回退 Transport 的上限,超出时淘汰任一已有克隆。
+const maxIPFallbacks = 64
+
+// fallbackFor 返回承载 req 的回退 RoundTripper:开启校验时 IP 字面量的 https 目标用按 IP 克隆的
+// 标准 Transport,使主机名校验同样发生在握手时、未通过则请求不发出。
+func (t *Transport) fallbackFor(req *http.Request) http.RoundTripper {
+	host := hostname(req.URL)
+	tr, ok := t.cfg.Fallback.(*http.Transport)
+	if !ok || t.cfg.VerifyUpstream == nil || req.URL.Scheme != "https" || net.ParseIP(host) == nil {
+		return t.cfg.Fallback
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c := t.ipFallback[host]; c != nil {
+		return c
+	}
+	if len(t.ipFallback) >= maxIPFallbacks {
+		for ip, c := range t.ipFallback {
+			c.CloseIdleConnections()
+			delete(t.ipFallback, ip)
+			break
+		}
+	}
+	c := tr.Clone()
+	if c.TLSClientConfig == nil {
+		c.TLSClientConfig = &tls.Config{}
+	}
+	verify := t.cfg.VerifyUpstream
+	c.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if cs.ServerName != "" {
+			return verify(cs.ServerName, cs) // 经 https 代理时与代理的握手
+		}
+		return verify(host, cs)
+	}
+	t.ipFallback[host] = c
+	return c
+}
+
+// checkFallbackTLS 记下承载回退请求的上游 TLS 连接摘要。
+func (t *Transport) checkFallbackTLS(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if t.cfg.UpstreamConn != nil {
+		recordUpstreamConn(req.Context(), t.fallbackConnInfo(hostname(req.URL), resp.TLS))
+	}
+	return resp, nil
+}
+
+// fallbackConnInfo 返回回退路径上 TLS 连接 cs 的摘要,同一连接只生成一次(其中的证书校验代价不低)。
+func (t *Transport) fallbackConnInfo(host string, cs *tls.ConnectionState) *flow.UpstreamConn {
+	key := weak.Make(cs)
+	t.mu.Lock()
+	uc, ok := t.fbConnInfo[key]
+	t.mu.Unlock()
+	if ok {
+		return uc
+	}
+	uc = t.cfg.UpstreamConn(host, *cs)
+	t.mu.Lock()
+	if _, ok := t.fbConnInfo[key]; !ok {
+		t.fbConnInfo[key] = uc
+		runtime.AddCleanup(cs, t.dropFallbackConnInfo, key)
+	}
+	t.mu.Unlock()
+	return uc
+}
+
+func (t *Transport) dropFallbackConnInfo(key weak.Pointer[tls.ConnectionState]) {
+	t.mu.Lock()
+	delete(t.fbConnInfo, key)
+	t.mu.Unlock()
+}
+
+// recordUpstreamConn 把承载本请求的上游 TLS 连接摘要回填给 flow.ResponseCapture。
+func recordUpstreamConn(ctx context.Context, uc *flow.UpstreamConn) {
+	if uc == nil {
+		return
+	}
+	if rc, ok := flow.ResponseCaptureFrom(ctx); ok {
+		rc.UpstreamConn = uc
+	}
+}
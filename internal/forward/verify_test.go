@@ -0,0 +1,141 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package forward
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// TestVerifyUpstream 证书未通过校验时保真路径与回退路径都在握手时中止、请求不发出,保真路径
+// 也不再回退;通过时连接摘要经 ResponseCapture 回填。
+func TestVerifyUpstream(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	var trusted atomic.Bool
+	var hosts []string
+	verify := func(host string, cs tls.ConnectionState) error {
+		hosts = append(hosts, host)
+		if trusted.Load() && bytes.Equal(cs.PeerCertificates[0].Raw, srv.Certificate().Raw) {
+			return nil
+		}
+		return &flow.UpstreamCertError{Host: host, Conn: flow.UpstreamConnOf(cs), Err: errors.New("untrusted")}
+	}
+	newTransport := func(fallback http.RoundTripper) *Transport {
+		return New(Config{
+			Fallback:        fallback,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			VerifyUpstream:  verify,
+			UpstreamConn: func(string, tls.ConnectionState) *flow.UpstreamConn {
+				return &flow.UpstreamConn{ALPN: "recorded"}
+			},
+		})
+	}
+	faithful := func() *http.Request {
+		return mkReq(t, "GET", srv.URL+"/", nil, [][2]string{{"Host", srv.Listener.Addr().String()}})
+	}
+
+	tr := newTransport(&errRT{})
+	defer tr.CloseIdleConnections()
+	_, err := tr.RoundTrip(faithful())
+	var certErr *flow.UpstreamCertError
+	if !errors.As(err, &certErr) || len(certErr.Conn.Chain) == 0 {
+		t.Fatalf("保真路径应以 UpstreamCertError 失败且不回退: %v", err)
+	}
+	if hosts[0] != "127.0.0.1" {
+		t.Fatalf("校验的主机 = %q", hosts[0])
+	}
+
+	fb := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer fb.CloseIdleConnections()
+	tr2 := newTransport(fb)
+	req, _ := http.NewRequest("GET", srv.URL+"/", nil)
+	if _, err := tr2.RoundTrip(req); !errors.As(err, &certErr) {
+		t.Fatalf("回退路径应以 UpstreamCertError 失败: %v", err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("证书被拒时请求不应发出, hits=%d", n)
+	}
+
+	trusted.Store(true)
+	for _, r := range []*http.Request{faithful(), req.Clone(req.Context())} {
+		rc := &flow.ResponseCapture{}
+		resp, err := tr2.RoundTrip(r.WithContext(flow.WithResponseCapture(r.Context(), rc)))
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if rc.UpstreamConn == nil || rc.UpstreamConn.ALPN != "recorded" {
+			t.Fatalf("未回填上游连接摘要: %+v", rc.UpstreamConn)
+		}
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("hits = %d, want 2", n)
+	}
+}
+
+// TestVerifyUpstreamFallbackIPTarget 回退路径上 IP 目标不带 SNI,主机名校验同样须在握手时进行:
+// 未通过时请求不发出。同一连接上的多个响应只生成一次连接摘要。
+func TestVerifyUpstreamFallbackIPTarget(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	var reject atomic.Bool
+	var infos atomic.Int32
+	fb := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	tr := New(Config{
+		Fallback:        fb,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		// 只有按目标 IP 校验才会失败,模拟证书链可信而主机名不符。
+		VerifyUpstream: func(host string, cs tls.ConnectionState) error {
+			if reject.Load() && host == "127.0.0.1" {
+				return &flow.UpstreamCertError{Host: host, Conn: flow.UpstreamConnOf(cs), Err: errors.New("name mismatch")}
+			}
+			return nil
+		},
+		UpstreamConn: func(string, tls.ConnectionState) *flow.UpstreamConn {
+			infos.Add(1)
+			return &flow.UpstreamConn{}
+		},
+	})
+	defer tr.CloseIdleConnections()
+
+	reject.Store(true)
+	req, _ := http.NewRequest("GET", srv.URL+"/", nil)
+	var certErr *flow.UpstreamCertError
+	if _, err := tr.RoundTrip(req); !errors.As(err, &certErr) {
+		t.Fatalf("回退路径应以 UpstreamCertError 失败: %v", err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("主机名未通过校验时请求不应发出, hits=%d", n)
+	}
+
+	reject.Store(false)
+	for i := 0; i < 3; i++ {
+		resp, err := tr.RoundTrip(req.Clone(req.Context()))
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("hits = %d, want 3", n)
+	}
+	if n := infos.Load(); n != 1 {
+		t.Fatalf("同一连接的摘要生成了 %d 次", n)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package pac 生成代理自动配置(PAC)脚本,使设为「自动代理」的设备只把关心的主机
+// 交给 Sniffy,其余直连。脚本由代理端口(直接访问或经 cert.sniffy)与管理 API 提供。
+//
+// 主机模式与解密范围同口径:* 通配,前缀 *. 同时匹配裸域与任意层级子域。
+package pac
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 提供 PAC 脚本的路径:proxy.pac 供手动填写的自动代理 URL,wpad.dat 是 WPAD 约定的文件名。
+const (
+	Path     = "/proxy.pac"
+	WPADPath = "/wpad.dat"
+)
+
+// ContentType 是 PAC 脚本的 MIME 类型。
+const ContentType = "application/x-ns-proxy-autoconfig"
+
+// IsPath 报告 path 是否为 PAC 脚本路径。
+func IsPath(path string) bool {
+	return path == Path || path == WPADPath
+}
+
+// Script 生成 PAC 脚本:命中 exclude 的主机直连;include 为空时其余主机全部经 proxy
+// (host:port),否则只有命中 include 的主机经 proxy。代理不可用时浏览器回落直连。
+func Script(proxy string, include, exclude []string) []byte {
+	var b strings.Builder
+	b.WriteString("// Sniffy 生成的代理自动配置脚本\n")
+	fmt.Fprintf(&b, "var proxy = %s;\n", jsValue("PROXY "+proxy+"; DIRECT"))
+	fmt.Fprintf(&b, "var include = %s;\n", jsValue(normalize(include)))
+	fmt.Fprintf(&b, "var exclude = %s;\n", jsValue(normalize(exclude)))
+	b.WriteString(`
+function hostMatch(host, pattern) {
+  if (pattern.substring(0, 2) == "*." && host == pattern.substring(2)) return true;
+  return shExpMatch(host, pattern);
+}
+
+function anyMatch(host, patterns) {
+  for (var i = 0; i < patterns.length; i++) {
+    if (hostMatch(host, patterns[i])) return true;
+  }
+  return false;
+}
+
+function FindProxyForURL(url, host) {
+  host = host.toLowerCase();
+  if (anyMatch(host, exclude)) return "DIRECT";
+  if (include.length == 0 || anyMatch(host, include)) return proxy;
+  return "DIRECT";
+}
+`)
+	return []byte(b.String())
+}
+
+// normalize 把主机模式转为小写并去掉空项,总是返回非 nil 切片(序列化为 [])。
+func normalize(pats []string) []string {
+	out := []string{}
+	for _, p := range pats {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// jsValue 把 v 编码为 JavaScript 字面量(JSON 是其子集)。
+func jsValue(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
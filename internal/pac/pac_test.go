@@ -0,0 +1,56 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pac
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+// TestScript 在 JS 引擎中执行生成的脚本:排除项优先直连;include 为空时其余全部经代理,
+// 否则只有命中的主机经代理;*. 前缀同时匹配裸域。
+func TestScript(t *testing.T) {
+	find := func(script []byte, host string) string {
+		t.Helper()
+		vm := goja.New()
+		// shExpMatch 是浏览器提供给 PAC 的内置函数,这里按 * 通配补上。
+		_ = vm.Set("shExpMatch", func(s, pattern string) bool {
+			expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+			return regexp.MustCompile(expr).MatchString(s)
+		})
+		if _, err := vm.RunString(string(script)); err != nil {
+			t.Fatalf("脚本无法执行: %v", err)
+		}
+		v, err := vm.RunString(`FindProxyForURL("https://` + host + `/", "` + host + `")`)
+		if err != nil {
+			t.Fatalf("FindProxyForURL: %v", err)
+		}
+		return v.String()
+	}
+	const proxied = "PROXY 10.0.0.2:8080; DIRECT"
+
+	s := Script("10.0.0.2:8080", []string{" *.Example.com ", "", "api.test"}, []string{"cdn.example.com"})
+	for host, want := range map[string]string{
+		"example.com":     proxied,
+		"a.b.example.com": proxied,
+		"API.test":        proxied,
+		"cdn.example.com": "DIRECT",
+		"other.test":      "DIRECT",
+	} {
+		if got := find(s, host); got != want {
+			t.Errorf("%s: got %q, want %q", host, got, want)
+		}
+	}
+
+	all := Script("10.0.0.2:8080", nil, []string{"*.lan"})
+	if got := find(all, "any.test"); got != proxied {
+		t.Errorf("include 为空时应全部经代理: %q", got)
+	}
+	if got := find(all, "nas.lan"); got != "DIRECT" {
+		t.Errorf("排除项应直连: %q", got)
+	}
+}
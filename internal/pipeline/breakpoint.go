@@ -6,6 +6,7 @@
 package pipeline
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
 	"sync"
@@ -35,22 +36,29 @@ const (
 type resumeMsg struct {
 	action ResumeAction
 	edited *flow.Flow
+	data   []byte // WebSocket 消息放行时 UI 编辑后的内容,nil 表示不改
 }
 
+// paused 是一个暂停项:HTTP flow(flow 非 nil)或 WebSocket 消息(ws 非 nil)。
 type paused struct {
 	flow   *flow.Flow
+	ws     *flow.WSMessage
 	phase  flow.Phase
 	resume chan resumeMsg
 }
 
 // BreakRule 是一条 URL 匹配的断点规则:命中的 flow 在所选阶段暂停。
 // URL 支持 * 通配(整串匹配);不含 * 时按子串包含匹配。
+// OnWebSocket 时规则也作用于该 URL 上 WebSocket 会话的消息,可再按方向与内容收窄。
 type BreakRule struct {
-	ID         string `json:"id"`
-	URL        string `json:"url"`
-	OnRequest  bool   `json:"onRequest"`
-	OnResponse bool   `json:"onResponse"`
-	Enabled    bool   `json:"enabled"`
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	OnRequest   bool   `json:"onRequest"`
+	OnResponse  bool   `json:"onResponse"`
+	OnWebSocket bool   `json:"onWebSocket"`
+	Direction   string `json:"direction,omitempty"` // flow.WSClientToServer / WSServerToClient,空为双向
+	Content     string `json:"content,omitempty"`   // 消息须包含的子串,空为不限
+	Enabled     bool   `json:"enabled"`
 
 	// 通配模式的编译缓存,持有 BreakpointManager.mu 时才可读写。
 	// reSrc 是编译时的模式串,URL 改动后与之不等,缓存自然失效。
@@ -66,9 +74,10 @@ type BreakpointManager struct {
 	timeout time.Duration
 	maxOpen int
 
-	// 全局断点开关(UI 可"断在请求/响应")。
-	breakRequest  bool
-	breakResponse bool
+	// 全局断点开关(UI 可"断在请求/响应/WebSocket 消息")。
+	breakRequest   bool
+	breakResponse  bool
+	breakWebSocket bool
 
 	// URL 匹配的断点规则(按 ID 有序)。
 	rules   []*BreakRule
@@ -135,6 +144,45 @@ func (b *BreakpointManager) ShouldBreakFor(url string, phase flow.Phase) bool {
 	return false
 }
 
+// SetWebSocketBreak 设置全局"断在每条 WebSocket 消息"开关。
+func (b *BreakpointManager) SetWebSocketBreak(on bool) {
+	b.mu.Lock()
+	b.breakWebSocket = on
+	b.mu.Unlock()
+}
+
+// WebSocketBreak 返回全局 WebSocket 消息断点开关。
+func (b *BreakpointManager) WebSocketBreak() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.breakWebSocket
+}
+
+// ShouldBreakWS 返回一条 WebSocket 消息是否应触发断点:全局开关打开,或任一启用且
+// OnWebSocket 的规则在 URL、方向与内容上都命中。
+func (b *BreakpointManager) ShouldBreakWS(m *flow.WSMessage) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.breakWebSocket {
+		return true
+	}
+	for _, r := range b.rules {
+		if !r.Enabled || !r.OnWebSocket {
+			continue
+		}
+		if r.Direction != "" && r.Direction != m.Direction {
+			continue
+		}
+		if r.Content != "" && !bytes.Contains(m.Data, []byte(r.Content)) {
+			continue
+		}
+		if r.matchesLocked(m.URL) {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *BreakpointManager) globalForLocked(phase flow.Phase) bool {
 	switch phase {
 	case flow.PhaseRequest:
@@ -224,6 +272,22 @@ func (b *BreakpointManager) ToggleRule(id string, enabled bool) (*BreakRule, boo
 	return nil, false
 }
 
+// SetRuleWebSocket 设置规则对 WebSocket 消息的匹配条件并返回更新后的副本。
+func (b *BreakpointManager) SetRuleWebSocket(id string, on bool, direction, content string) (*BreakRule, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, r := range b.rules {
+		if r.ID == id {
+			r.OnWebSocket = on
+			r.Direction = direction
+			r.Content = content
+			cp := *r
+			return &cp, true
+		}
+	}
+	return nil, false
+}
+
 // DeleteRule 删除一条规则，返回是否存在。
 func (b *BreakpointManager) DeleteRule(id string) bool {
 	b.mu.Lock()
@@ -327,6 +391,50 @@ func (b *BreakpointManager) Pause(f *flow.Flow, phase flow.Phase) (abort bool) {
 	}
 }
 
+// PauseWS 暂停一条 WebSocket 消息 —— 调用方即该方向的转发 goroutine,其后的帧随之停住 ——
+// 交 UI 编辑、丢弃或放行,与 Pause 共用超时与 maxOpen 名额。返回是否应丢弃该消息;
+// 放行时 UI 编辑后的内容就地写回 m.Data。超时失败开放,原样放行。
+func (b *BreakpointManager) PauseWS(m *flow.WSMessage) (drop bool) {
+	p := &paused{ws: m, phase: flow.PhaseMessage, resume: make(chan resumeMsg, 1)}
+
+	b.mu.Lock()
+	if len(b.paused) >= b.maxOpen {
+		b.mu.Unlock()
+		return false
+	}
+	b.paused[m.ID] = p
+	b.mu.Unlock()
+
+	defer func() {
+		b.unpublish(m.ID)
+		b.emit(evtBreakpointResolved, wsSnapshot(m))
+	}()
+	b.emit(evtBreakpointHit, wsSnapshot(m))
+
+	select {
+	case msg := <-p.resume:
+		b.unpublish(m.ID)
+		if msg.action == ResumeAbort {
+			return true
+		}
+		if msg.data != nil {
+			m.Data = msg.data
+		}
+		return false
+	case <-time.After(b.timeout):
+		b.unpublish(m.ID)
+		return false
+	}
+}
+
+// wsSnapshot 复制一条 WebSocket 消息作为断点载荷,与放行后的就地改写隔离。
+func wsSnapshot(m *flow.WSMessage) *flow.WSMessage {
+	cp := *m
+	cp.Data = append([]byte(nil), m.Data...)
+	cp.PausedAt = flow.PhaseMessage
+	return &cp
+}
+
 // unpublish 把 flow 从暂停列表摘除(幂等)。List() 在同一把锁下 Clone,故返回后本管理器
 // 不会再读到该 flow,可就地改写;但同一指针仍被 sessionStore 无锁读,那是既有约束。
 func (b *BreakpointManager) unpublish(id string) {
@@ -340,7 +448,12 @@ func (b *BreakpointManager) Resume(id string, edited *flow.Flow) bool {
 	return b.deliver(id, resumeMsg{action: ResumeContinue, edited: edited})
 }
 
-// Abort 阻断一个暂停的 flow。
+// ResumeWS 放行一条暂停的 WebSocket 消息;data 非 nil 时以其替换消息内容。
+func (b *BreakpointManager) ResumeWS(id string, data []byte) bool {
+	return b.deliver(id, resumeMsg{action: ResumeContinue, data: data})
+}
+
+// Abort 阻断一个暂停的 flow 或丢弃一条暂停的 WebSocket 消息。
 func (b *BreakpointManager) Abort(id string) bool {
 	return b.deliver(id, resumeMsg{action: ResumeAbort})
 }
@@ -366,6 +479,9 @@ func (b *BreakpointManager) List() []*flow.Flow {
 	defer b.mu.Unlock()
 	out := make([]*flow.Flow, 0, len(b.paused))
 	for _, p := range b.paused {
+		if p.flow == nil {
+			continue
+		}
 		item := p.flow.Clone()
 		item.PausedAt = p.phase
 		out = append(out, item)
@@ -373,6 +489,19 @@ func (b *BreakpointManager) List() []*flow.Flow {
 	return out
 }
 
+// ListWS 返回当前所有暂停中的 WebSocket 消息的快照。
+func (b *BreakpointManager) ListWS() []*flow.WSMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]*flow.WSMessage, 0)
+	for _, p := range b.paused {
+		if p.ws != nil {
+			out = append(out, wsSnapshot(p.ws))
+		}
+	}
+	return out
+}
+
 // mergeFlow 把 UI 编辑后的 src 合并进 dst(只覆盖请求/响应内容)。
 func mergeFlow(dst, src *flow.Flow) {
 	if src.Request != nil {
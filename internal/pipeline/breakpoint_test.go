@@ -825,3 +825,179 @@ func TestBreakpointManagerConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+// ---- WebSocket 消息断点 ----
+
+// waitPausedWS 轮询等待消息进入暂停列表。
+func waitPausedWS(t *testing.T, bm *BreakpointManager, id string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, m := range bm.ListWS() {
+			if m.ID == id {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("消息 %s 未在超时前进入断点暂停", id)
+}
+
+func newWSMessage(direction, data string) *flow.WSMessage {
+	return &flow.WSMessage{
+		ID:        flow.NewID(),
+		FlowID:    "sess",
+		URL:       "wss://chat.example/ws",
+		Direction: direction,
+		Type:      flow.WSText,
+		Data:      []byte(data),
+	}
+}
+
+// 放行时 UI 编辑的内容就地写回;暂停项只出现在 ListWS,不混进 HTTP 的 List。
+func TestPauseWSResumeWithEdit(t *testing.T) {
+	bm := NewBreakpointManager(nil)
+	m := newWSMessage(flow.WSClientToServer, "hello")
+
+	done := make(chan bool, 1)
+	go func() { done <- bm.PauseWS(m) }()
+	waitPausedWS(t, bm, m.ID)
+
+	if n := len(bm.List()); n != 0 {
+		t.Errorf("List() 不应包含 WebSocket 消息, got %d", n)
+	}
+	if got := bm.ListWS()[0]; got == m || got.PausedAt != flow.PhaseMessage || string(got.Data) != "hello" {
+		t.Errorf("ListWS() 快照 = %+v", got)
+	}
+	if !bm.ResumeWS(m.ID, []byte("edited")) {
+		t.Fatal("ResumeWS 应成功")
+	}
+	if drop := <-done; drop {
+		t.Fatal("放行不应丢弃")
+	}
+	if got := string(m.Data); got != "edited" {
+		t.Errorf("Data = %q, want %q", got, "edited")
+	}
+	if n := len(bm.ListWS()); n != 0 {
+		t.Errorf("放行后暂停列表应为空, got %d", n)
+	}
+}
+
+// 不带内容放行保持原文;Abort 即丢弃。
+func TestPauseWSResumeUnchangedAndAbort(t *testing.T) {
+	bm := NewBreakpointManager(nil)
+	m := newWSMessage(flow.WSServerToClient, "keep")
+	done := make(chan bool, 1)
+	go func() { done <- bm.PauseWS(m) }()
+	waitPausedWS(t, bm, m.ID)
+	bm.ResumeWS(m.ID, nil)
+	if drop := <-done; drop || string(m.Data) != "keep" {
+		t.Fatalf("原样放行: drop=%v Data=%q", drop, m.Data)
+	}
+
+	m2 := newWSMessage(flow.WSServerToClient, "drop me")
+	go func() { done <- bm.PauseWS(m2) }()
+	waitPausedWS(t, bm, m2.ID)
+	if !bm.Abort(m2.ID) {
+		t.Fatal("Abort 应成功")
+	}
+	if drop := <-done; !drop {
+		t.Fatal("Abort 后 PauseWS 应返回 true(丢弃)")
+	}
+}
+
+// WebSocket 消息与 HTTP flow 共用超时与 maxOpen 名额,两者都失败开放。
+func TestPauseWSSharesLimits(t *testing.T) {
+	bm := NewBreakpointManager(nil)
+	bm.maxOpen = 1
+	f := newReqFlow()
+	done := make(chan bool, 1)
+	go func() { done <- bm.Pause(f, flow.PhaseRequest) }()
+	waitPaused(t, bm, f.ID)
+
+	if drop := bm.PauseWS(newWSMessage(flow.WSClientToServer, "x")); drop {
+		t.Error("超上限应失败开放")
+	}
+	bm.Resume(f.ID, nil)
+	<-done
+
+	bm.timeout = 20 * time.Millisecond
+	m := newWSMessage(flow.WSClientToServer, "x")
+	if drop := bm.PauseWS(m); drop || string(m.Data) != "x" {
+		t.Errorf("超时应原样放行: drop=%v Data=%q", drop, m.Data)
+	}
+}
+
+// hit/resolved 载荷是带 PausedAt=message 的消息快照,前端据此区分 HTTP 暂停项。
+func TestPauseWSEmitsMessageSnapshots(t *testing.T) {
+	sink := &eventSink{}
+	bm := NewBreakpointManager(sink.emit)
+	m := newWSMessage(flow.WSClientToServer, "hi")
+	done := make(chan bool, 1)
+	go func() { done <- bm.PauseWS(m) }()
+	waitPausedWS(t, bm, m.ID)
+	bm.ResumeWS(m.ID, nil)
+	<-done
+
+	sink.waitTypes(t, "breakpoint_hit,breakpoint_resolved")
+	for _, e := range sink.snapshot() {
+		got, ok := e.payload.(*flow.WSMessage)
+		if !ok {
+			t.Fatalf("%s 载荷类型 = %T, want *flow.WSMessage", e.typ, e.payload)
+		}
+		if got == m || got.ID != m.ID || got.PausedAt != flow.PhaseMessage {
+			t.Errorf("%s 载荷 = %+v", e.typ, got)
+		}
+	}
+}
+
+// 规则按 URL、方向、内容逐项收窄;未勾选 OnWebSocket 的规则不作用于消息。
+func TestShouldBreakWSRuleFilters(t *testing.T) {
+	bm := NewBreakpointManager(nil)
+	out := newWSMessage(flow.WSClientToServer, `{"op":"login"}`)
+	in := newWSMessage(flow.WSServerToClient, `{"op":"login"}`)
+
+	r := bm.AddRule("chat.example", true, true)
+	if bm.ShouldBreakWS(out) {
+		t.Error("未开启 OnWebSocket 的规则不应断消息")
+	}
+	if _, ok := bm.SetRuleWebSocket(r.ID, true, flow.WSClientToServer, `"login"`); !ok {
+		t.Fatal("SetRuleWebSocket 应找到规则")
+	}
+	if !bm.ShouldBreakWS(out) {
+		t.Error("方向与内容都命中时应断")
+	}
+	if bm.ShouldBreakWS(in) {
+		t.Error("方向不符不应断")
+	}
+	if bm.ShouldBreakWS(newWSMessage(flow.WSClientToServer, "ping")) {
+		t.Error("内容不含子串不应断")
+	}
+	other := newWSMessage(flow.WSClientToServer, `{"op":"login"}`)
+	other.URL = "wss://other.example/ws"
+	if bm.ShouldBreakWS(other) {
+		t.Error("URL 不符不应断")
+	}
+	bm.ToggleRule(r.ID, false)
+	if bm.ShouldBreakWS(out) {
+		t.Error("禁用的规则不应断")
+	}
+	if _, ok := bm.SetRuleWebSocket("missing", true, "", ""); ok {
+		t.Error("不存在的规则应返回 false")
+	}
+
+	// 只断 WebSocket 的规则不影响该 URL 上的 HTTP 请求/响应。
+	wsOnly := bm.AddRule("chat.example", false, false)
+	bm.SetRuleWebSocket(wsOnly.ID, true, "", "")
+	if bm.ShouldBreakFor("https://chat.example/ws", flow.PhaseRequest) {
+		t.Error("仅 WebSocket 的规则不应断 HTTP 请求")
+	}
+	if !bm.ShouldBreakWS(in) {
+		t.Error("双向、不限内容的规则应断任一方向")
+	}
+
+	bm.SetWebSocketBreak(true)
+	if !bm.WebSocketBreak() || !bm.ShouldBreakWS(other) {
+		t.Error("全局开关打开时每条消息都应断")
+	}
+}
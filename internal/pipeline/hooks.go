@@ -42,13 +42,21 @@ type WSHook interface {
 }
 
 // StreamHook 在每条流消息(SSE 事件 / gRPC 消息 / 分块)上被调用。
-// 插件可就地修改 m.Data(SSE/分块可改写并回放;gRPC 仅在未压缩帧上改写),
+// 插件可就地修改 m.Data(SSE/分块可改写并回放;gRPC 压缩帧按 grpc-encoding 解压后交给插件,
+// 改写后按同一编码重新压缩,编码不支持的压缩帧只读),
 // 返回 Abort 可提前终止该流。
 type StreamHook interface {
 	Hook
 	OnStreamMessage(ctx context.Context, m *flow.StreamMessage) flow.Decision
 }
 
+// MQTTHook 在每个 MQTT PUBLISH 报文(双向)上被调用。插件可就地修改 m.Payload,
+// 返回 Abort 则丢弃该报文,不再转发。
+type MQTTHook interface {
+	Hook
+	OnMQTTMessage(ctx context.Context, m *flow.MQTTMessage) flow.Decision
+}
+
 // ConnHook 在连接开始/结束时被调用(可选)。
 type ConnHook interface {
 	Hook
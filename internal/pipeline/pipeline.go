@@ -36,12 +36,14 @@ type Pipeline struct {
 	respHooks   []ResponseHook
 	wsHooks     []WSHook
 	streamHooks []StreamHook
+	mqttHooks   []MQTTHook
 	connHooks   []ConnHook
 
 	coreReq    []RequestHook
 	coreResp   []ResponseHook
 	coreWS     []WSHook
 	coreStream []StreamHook
+	coreMQTT   []MQTTHook
 
 	bp     *BreakpointManager
 	logger Logger
@@ -81,6 +83,10 @@ func (p *Pipeline) Register(h Hook) {
 		p.streamHooks = append(p.streamHooks, sh)
 		sort.SliceStable(p.streamHooks, func(i, j int) bool { return p.streamHooks[i].Priority() < p.streamHooks[j].Priority() })
 	}
+	if mh, ok := h.(MQTTHook); ok {
+		p.mqttHooks = append(p.mqttHooks, mh)
+		sort.SliceStable(p.mqttHooks, func(i, j int) bool { return p.mqttHooks[i].Priority() < p.mqttHooks[j].Priority() })
+	}
 	if ch, ok := h.(ConnHook); ok {
 		p.connHooks = append(p.connHooks, ch)
 		sort.SliceStable(p.connHooks, func(i, j int) bool { return p.connHooks[i].Priority() < p.connHooks[j].Priority() })
@@ -104,6 +110,9 @@ func (p *Pipeline) RegisterCore(h Hook) {
 	if sh, ok := h.(StreamHook); ok {
 		p.coreStream = append(p.coreStream, sh)
 	}
+	if mh, ok := h.(MQTTHook); ok {
+		p.coreMQTT = append(p.coreMQTT, mh)
+	}
 }
 
 // Clear 清空所有已注册插件(热重载时使用)。核心钩子(RegisterCore)保留。
@@ -114,6 +123,7 @@ func (p *Pipeline) Clear() {
 	p.respHooks = nil
 	p.wsHooks = nil
 	p.streamHooks = nil
+	p.mqttHooks = nil
 	p.connHooks = nil
 }
 
@@ -157,6 +167,16 @@ func (p *Pipeline) snapshotStream() []StreamHook {
 	return out
 }
 
+func (p *Pipeline) snapshotMQTT() []MQTTHook {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]MQTTHook, 0, len(p.coreMQTT)+len(p.mqttHooks))
+	out = append(out, p.coreMQTT...)
+	out = append(out, p.mqttHooks...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Priority() < out[j].Priority() })
+	return out
+}
+
 // OnRequest 依次执行所有请求插件,合并处置;遇到 Abort/Mock 立即短路。
 // 断点单独记 pause 不并入 Merge —— 并入会被优先级更高的 Mock 静默吃掉。
 func (p *Pipeline) OnRequest(ctx context.Context, f *flow.Flow) flow.Decision {
@@ -224,9 +244,11 @@ func (p *Pipeline) maybePause(f *flow.Flow, phase flow.Phase, url string, decisi
 	return decision
 }
 
-// OnWebSocketMessage 依次执行 WS 插件,允许就地修改 m.Data。
+// OnWebSocketMessage 依次执行 WS 插件,允许就地修改 m.Data。钩子请求断点或断点开关/规则
+// 命中时,在插件之后同步挂起该消息(调用方即该方向的转发 goroutine);UI 丢弃则返回 Abort。
 func (p *Pipeline) OnWebSocketMessage(ctx context.Context, m *flow.WSMessage) flow.Decision {
 	decision := flow.ContinueDecision()
+	pause := false
 	for _, h := range p.snapshotWS() {
 		if !h.Enabled() || !h.Match(m.URL) {
 			continue
@@ -237,12 +259,23 @@ func (p *Pipeline) OnWebSocketMessage(ctx context.Context, m *flow.WSMessage) fl
 					p.logger.Error("ws 插件 %s panic: %v", h.Name(), r)
 				}
 			}()
-			decision = flow.Merge(decision, h.OnWebSocketMessage(ctx, m))
+			d := h.OnWebSocketMessage(ctx, m)
+			if d.Kind == flow.Breakpoint {
+				pause = true
+				return
+			}
+			decision = flow.Merge(decision, d)
 		}()
 		if decision.Kind == flow.Abort {
 			return decision
 		}
 	}
+	if !pause && !p.bp.ShouldBreakWS(m) {
+		return decision
+	}
+	if p.bp.PauseWS(m) {
+		return flow.AbortDecision(0, "aborted at breakpoint")
+	}
 	return decision
 }
 
@@ -268,6 +301,28 @@ func (p *Pipeline) OnStreamMessage(ctx context.Context, m *flow.StreamMessage) f
 	return decision
 }
 
+// OnMQTTMessage 依次执行 MQTT 插件,允许就地修改 m.Payload;遇到 Abort 立即短路(丢弃报文)。
+func (p *Pipeline) OnMQTTMessage(ctx context.Context, m *flow.MQTTMessage) flow.Decision {
+	decision := flow.ContinueDecision()
+	for _, h := range p.snapshotMQTT() {
+		if !h.Enabled() || !h.Match(m.URL) {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p.logger.Error("mqtt 插件 %s panic: %v", h.Name(), r)
+				}
+			}()
+			decision = flow.Merge(decision, h.OnMQTTMessage(ctx, m))
+		}()
+		if decision.Kind == flow.Abort {
+			return decision
+		}
+	}
+	return decision
+}
+
 // safeReq / safeResp 包裹插件调用,recover panic,失败开放为 Continue。
 func (p *Pipeline) safeReq(ctx context.Context, h RequestHook, f *flow.Flow) (d flow.Decision) {
 	d = flow.ContinueDecision()
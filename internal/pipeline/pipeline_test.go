@@ -84,6 +84,18 @@ func (h *streamHook) OnStreamMessage(_ context.Context, m *flow.StreamMessage) f
 	return h.fn(m)
 }
 
+type mqttHook struct {
+	stubHook
+	fn func(*flow.MQTTMessage) flow.Decision
+}
+
+func (h *mqttHook) OnMQTTMessage(_ context.Context, m *flow.MQTTMessage) flow.Decision {
+	if h.fn == nil {
+		return flow.ContinueDecision()
+	}
+	return h.fn(m)
+}
+
 type connHook struct {
 	stubHook
 }
@@ -755,6 +767,50 @@ func TestOnStreamMessageAbortShortCircuits(t *testing.T) {
 	}
 }
 
+// MQTT 钩子就地改写载荷;Abort 短路后续钩子。核心钩子不受 Clear 影响,且按 URL 门控。
+func TestOnMQTTMessage(t *testing.T) {
+	p := New(nil, nil)
+	p.RegisterCore(&mqttHook{
+		stubHook: stubHook{name: "core", priority: 0, matchFn: func(u string) bool { return strings.HasPrefix(u, "mqtt://broker/") }},
+		fn: func(m *flow.MQTTMessage) flow.Decision {
+			m.Payload = []byte(strings.ToUpper(string(m.Payload)))
+			return flow.ContinueDecision()
+		},
+	})
+	var calls []string
+	p.Register(&mqttHook{
+		stubHook: stubHook{name: "drop", priority: 1},
+		fn: func(m *flow.MQTTMessage) flow.Decision {
+			calls = append(calls, m.Topic)
+			if m.Topic == "secret" {
+				return flow.AbortDecision(0, "drop")
+			}
+			return flow.ContinueDecision()
+		},
+	})
+
+	m := &flow.MQTTMessage{URL: "mqtt://broker/a", Topic: "a", Payload: []byte("hi")}
+	if d := p.OnMQTTMessage(context.Background(), m); d.Kind != flow.Continue {
+		t.Errorf("处置 = %v, want continue", d.Kind)
+	}
+	if got := string(m.Payload); got != "HI" {
+		t.Errorf("Payload = %q, want %q", got, "HI")
+	}
+	if d := p.OnMQTTMessage(context.Background(), &flow.MQTTMessage{URL: "mqtt://other/secret", Topic: "secret", Payload: []byte("x")}); d.Kind != flow.Abort {
+		t.Errorf("处置 = %v, want abort", d.Kind)
+	}
+
+	p.Clear()
+	m = &flow.MQTTMessage{URL: "mqtt://broker/a", Topic: "a", Payload: []byte("ok")}
+	p.OnMQTTMessage(context.Background(), m)
+	if got := string(m.Payload); got != "OK" {
+		t.Errorf("Clear 后核心钩子应保留: Payload = %q", got)
+	}
+	if len(calls) != 2 {
+		t.Errorf("Clear 后插件钩子不应再被调用: calls = %v", calls)
+	}
+}
+
 // 无任何钩子时,WS/流消息原样放行。
 func TestNoHooksPassesThrough(t *testing.T) {
 	p := New(nil, nil)
@@ -987,3 +1043,50 @@ func TestOnRequestURLRulePauses(t *testing.T) {
 		t.Errorf("响应处置 = %v, want continue(规则不覆盖响应阶段)", d.Kind)
 	}
 }
+
+// 钩子返回 Breakpoint 时消息在全部钩子之后挂起;UI 丢弃即返回 Abort。
+func TestOnWebSocketMessageHookBreakpoint(t *testing.T) {
+	p := New(nil, nil)
+	p.Register(&wsHook{
+		stubHook: stubHook{name: "bp", priority: 0},
+		fn: func(*flow.WSMessage) flow.Decision {
+			return flow.BreakpointDecision(flow.PhaseMessage, "inspect")
+		},
+	})
+	m := &flow.WSMessage{ID: flow.NewID(), URL: "wss://x.com/", Type: flow.WSText, Data: []byte("hi")}
+
+	done := make(chan flow.Decision, 1)
+	go func() { done <- p.OnWebSocketMessage(context.Background(), m) }()
+	waitPausedWS(t, p.Breakpoints(), m.ID)
+	p.Breakpoints().Abort(m.ID)
+
+	if d := <-done; d.Kind != flow.Abort {
+		t.Errorf("处置 = %+v, want abort", d)
+	}
+}
+
+// 断点开关打开时,放行的内容是插件改写后再经 UI 编辑的结果。
+func TestOnWebSocketMessageGlobalBreakAfterHooks(t *testing.T) {
+	p := New(nil, nil)
+	p.Register(&wsHook{
+		stubHook: stubHook{name: "upper", priority: 0},
+		fn: func(m *flow.WSMessage) flow.Decision {
+			m.Data = []byte(strings.ToUpper(string(m.Data)))
+			return flow.ContinueDecision()
+		},
+	})
+	p.Breakpoints().SetWebSocketBreak(true)
+	m := &flow.WSMessage{ID: flow.NewID(), URL: "wss://x.com/", Type: flow.WSText, Data: []byte("hi")}
+
+	done := make(chan flow.Decision, 1)
+	go func() { done <- p.OnWebSocketMessage(context.Background(), m) }()
+	waitPausedWS(t, p.Breakpoints(), m.ID)
+	if got := string(p.Breakpoints().ListWS()[0].Data); got != "HI" {
+		t.Errorf("暂停时的内容 = %q, want %q(插件之后)", got, "HI")
+	}
+	p.Breakpoints().ResumeWS(m.ID, []byte("edited"))
+
+	if d := <-done; d.Kind != flow.Continue || string(m.Data) != "edited" {
+		t.Errorf("处置 = %+v, Data = %q", d, m.Data)
+	}
+}
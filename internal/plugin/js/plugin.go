@@ -9,7 +9,7 @@
 //   - 每个插件独占一个 goja.Runtime,由一个邮箱 goroutine 串行执行(goja 非协程安全)。
 //   - flow 以 JSON 进出 VM(JSON.parse/stringify),避免宿主对象绑定的边界陷阱,简单且正确。
 //   - 每次调用设超时,到点 Interrupt;脚本报错/超时一律失败开放(Continue),绝不影响代理。
-//   - 暴露给脚本的 API:onRequest/onResponse/onWebSocketMessage/onStreamMessage、
+//   - 暴露给脚本的 API:onRequest/onResponse/onWebSocketMessage/onStreamMessage/onMQTTMessage、
 //     mock()/abort()/setBreakpoint()、console.*、store.get/set(可落盘持久化)、settings、
 //     以及助手命名空间 base64/hex/url/query/header/crypto/jwt/json/time/utf8
 //     与 uuid()/randomId()/btoa()/atob()。
@@ -121,6 +121,7 @@ type jsFlow struct {
 	Body     string            `json:"body,omitempty"`
 	Response *jsResponse       `json:"response,omitempty"`
 	Process  *jsProcess        `json:"process,omitempty"`
+	TLS      *jsTLS            `json:"tls,omitempty"` // 客户端 TLS 指纹(只读),明文请求为空
 
 	// WS / 流(SSE / gRPC / 分块)专用字段。
 	Direction string `json:"direction,omitempty"`
@@ -128,6 +129,14 @@ type jsFlow struct {
 	Data      string `json:"data,omitempty"`
 	Kind      string `json:"kind,omitempty"`      // 流类型:sse|grpc|chunk
 	EventType string `json:"eventType,omitempty"` // SSE 的 event 名
+	Encoding  string `json:"encoding,omitempty"`  // gRPC 压缩帧的 grpc-encoding(只读;data 已解压)
+	JSON      string `json:"json,omitempty"`      // gRPC 消息的 protobuf JSON 视图(可改写)
+	Schema    string `json:"schema,omitempty"`    // JSON 视图的消息类型;"wire" 为线缆格式
+
+	// MQTT PUBLISH 专用字段(只读;data 为可改写的载荷)。
+	Topic  string `json:"topic,omitempty"`
+	QoS    byte   `json:"qos,omitempty"`
+	Retain bool   `json:"retain,omitempty"`
 }
 
 type jsResponse struct {
@@ -144,6 +153,17 @@ type jsProcess struct {
 	Path string `json:"path,omitempty"`
 }
 
+type jsTLS struct {
+	JA3        string   `json:"ja3"`
+	JA3Hash    string   `json:"ja3Hash"`
+	JA4        string   `json:"ja4"`
+	SNI        string   `json:"sni,omitempty"`
+	Version    uint16   `json:"version"`
+	Ciphers    []uint16 `json:"ciphers"`
+	Extensions []uint16 `json:"extensions"`
+	ALPN       []string `json:"alpn,omitempty"`
+}
+
 type jsDecision struct {
 	Kind   string `json:"kind"`
 	Status int    `json:"status"`
@@ -265,6 +285,7 @@ const driverSrc = `
     else if (__PHASE__ === 'response') { if (typeof onResponse === 'function') onResponse(flow); }
     else if (__PHASE__ === 'ws') { if (typeof onWebSocketMessage === 'function') onWebSocketMessage(flow); }
     else if (__PHASE__ === 'stream') { if (typeof onStreamMessage === 'function') onStreamMessage(flow); }
+    else if (__PHASE__ === 'mqtt') { if (typeof onMQTTMessage === 'function') onMQTTMessage(flow); }
   } catch (e) { if (e !== __STOP) { __log('error', 'plugin error: ' + e); } }
   __OUT__ = JSON.stringify({flow: flow, decision: __decision});
 })();
@@ -333,7 +354,7 @@ func (p *Plugin) initVM() error {
 	if _, err := vm.RunString(hostSetup); err != nil {
 		return err
 	}
-	// 运行用户脚本,定义 onRequest/onResponse/onWebSocketMessage/onStreamMessage。
+	// 运行用户脚本,定义 onRequest/onResponse/onWebSocketMessage/onStreamMessage/onMQTTMessage。
 	if _, err := vm.RunString(p.cfg.Source); err != nil {
 		return err
 	}
@@ -684,29 +705,71 @@ func (p *Plugin) OnWebSocketMessage(ctx context.Context, m *flow.WSMessage) flow
 	if json.Unmarshal(out, &res) == nil {
 		m.Data = []byte(res.Flow.Data)
 	}
-	return decisionFromJS(res.Decision, flow.PhaseRequest)
+	return decisionFromJS(res.Decision, flow.PhaseMessage)
 }
 
-// OnStreamMessage 执行流消息钩子(SSE / gRPC / 分块)。插件可就地改写 flow.data。
+// OnStreamMessage 执行流消息钩子(SSE / gRPC / 分块)。插件可就地改写 flow.data;
+// gRPC 消息还可改写 flow.json(protobuf 的 JSON 视图),由处理器重新编码。
 func (p *Plugin) OnStreamMessage(ctx context.Context, m *flow.StreamMessage) flow.Decision {
 	in, _ := json.Marshal(jsFlow{
 		Direction: m.Direction,
 		Kind:      m.Kind,
 		EventType: m.EventType,
+		Encoding:  m.Encoding,
 		Data:      string(m.Data),
+		JSON:      m.JSON,
+		Schema:    m.Schema,
 		URL:       m.URL,
 	})
 	out := p.dispatch("stream", in)
 	if out == nil {
 		return flow.ContinueDecision()
 	}
-	var res jsOut
+	var (
+		res  jsOut
+		seen jsFlow
+	)
+	// gRPC 载荷是二进制 protobuf,同 MQTT:只写回脚本真正改动的字段。
+	_ = json.Unmarshal(in, &seen)
 	if json.Unmarshal(out, &res) == nil {
-		m.Data = []byte(res.Flow.Data)
+		if res.Flow.Data != seen.Data {
+			m.Data = []byte(res.Flow.Data)
+		}
+		if res.Flow.JSON != seen.JSON {
+			m.JSON = res.Flow.JSON
+		}
 	}
 	return decisionFromJS(res.Decision, flow.PhaseResponse)
 }
 
+// OnMQTTMessage 执行 MQTT PUBLISH 钩子。插件可就地改写 flow.data(载荷),abort() 丢弃该报文。
+func (p *Plugin) OnMQTTMessage(ctx context.Context, m *flow.MQTTMessage) flow.Decision {
+	in, _ := json.Marshal(jsFlow{
+		Direction: m.Direction,
+		Type:      m.Type,
+		Topic:     m.Topic,
+		QoS:       m.QoS,
+		Retain:    m.Retain,
+		Data:      string(m.Payload),
+		URL:       m.URL,
+	})
+	out := p.dispatch("mqtt", in)
+	if out == nil {
+		return flow.ContinueDecision()
+	}
+	var (
+		res  jsOut
+		seen jsFlow
+	)
+	// MQTT 载荷常为二进制(protobuf 等),经 JSON 字符串进出 VM 会把非法 UTF-8 替换掉;
+	// 只有脚本真正改了 data 才写回,无操作的插件不得破坏原始字节。
+	_ = json.Unmarshal(in, &seen)
+	if json.Unmarshal(out, &res) == nil && res.Flow.Data != seen.Data {
+		m.Payload = []byte(res.Flow.Data)
+	}
+	return decisionFromJS(res.Decision, flow.PhaseRequest)
+}
+
 // Logs 返回最近的插件日志(结构化,供 UI 按级别过滤)。
 func (p *Plugin) Logs() []LogEntry {
 	p.logsMu.Lock()
@@ -835,6 +898,12 @@ func requestToJS(f *flow.Flow) jsFlow {
 	if p := f.Process(); p != nil {
 		v.Process = &jsProcess{Name: p.Name, PID: p.PID, Path: p.Path}
 	}
+	if fp := f.TLSFingerprint(); fp != nil {
+		v.TLS = &jsTLS{
+			JA3: fp.JA3, JA3Hash: fp.JA3Hash, JA4: fp.JA4, SNI: fp.SNI, Version: fp.Version,
+			Ciphers: fp.Ciphers, Extensions: fp.Extensions, ALPN: fp.ALPN,
+		}
+	}
 	return v
 }
 
@@ -262,3 +262,74 @@ func TestNoopResponsePreservesStruct(t *testing.T) {
 		t.Fatalf("Set-Cookie collapsed: %v", got)
 	}
 }
+
+// gRPC 流消息的 JSON 视图可读可改;只改视图时二进制载荷必须原样保留,交由处理器重新编码。
+func TestOnStreamMessageGRPCJSON(t *testing.T) {
+	p := mustPlugin(t, Config{ID: "grpc", Source: `function onStreamMessage(m){
+  if (m.kind !== 'grpc' || m.schema !== 'test.v1.HelloRequest') return;
+  var v = JSON.parse(m.json); v.name = 'edited'; m.json = JSON.stringify(v);
+}`})
+
+	bin := []byte{0x0a, 0x02, 0xff, 0xfe}
+	m := &flow.StreamMessage{URL: "https://h/test.v1.Greeter/SayHello", Kind: flow.StreamGRPC, Data: bin, JSON: `{"name":"x"}`, Schema: "test.v1.HelloRequest"}
+	p.OnStreamMessage(context.Background(), m)
+	if m.JSON != `{"name":"edited"}` {
+		t.Fatalf("json = %q", m.JSON)
+	}
+	if string(m.Data) != string(bin) {
+		t.Fatalf("plugin altered binary payload: %x", m.Data)
+	}
+
+	m = &flow.StreamMessage{Kind: flow.StreamGRPC, Data: bin, JSON: `{"1:bytes":"//4="}`, Schema: "wire"}
+	p.OnStreamMessage(context.Background(), m)
+	if m.JSON != `{"1:bytes":"//4="}` || string(m.Data) != string(bin) {
+		t.Fatalf("no-op plugin altered message: %q %x", m.JSON, m.Data)
+	}
+}
+
+// onMQTTMessage 可改写载荷或 abort() 丢弃;无操作时二进制载荷必须原样保留。
+func TestOnMQTTMessage(t *testing.T) {
+	p := mustPlugin(t, Config{ID: "mq", Source: `function onMQTTMessage(m){
+  if (m.topic === 'drop') { abort(); return; }
+  if (m.topic === 'up' && m.qos === 1) m.data = m.data.toUpperCase();
+}`})
+
+	m := &flow.MQTTMessage{URL: "mqtt://b/up", Type: flow.MQTTPublish, Topic: "up", QoS: 1, Payload: []byte("hi")}
+	if d := p.OnMQTTMessage(context.Background(), m); d.Kind != flow.Continue {
+		t.Fatalf("decision = %v, want Continue", d.Kind)
+	}
+	if string(m.Payload) != "HI" {
+		t.Fatalf("payload = %q, want HI", m.Payload)
+	}
+
+	bin := []byte{0x08, 0x96, 0x01, 0xff}
+	m = &flow.MQTTMessage{URL: "mqtt://b/raw", Type: flow.MQTTPublish, Topic: "raw", Payload: bin}
+	p.OnMQTTMessage(context.Background(), m)
+	if string(m.Payload) != string(bin) {
+		t.Fatalf("no-op plugin altered binary payload: %x", m.Payload)
+	}
+
+	m = &flow.MQTTMessage{URL: "mqtt://b/drop", Type: flow.MQTTPublish, Topic: "drop", Payload: []byte("x")}
+	if d := p.OnMQTTMessage(context.Background(), m); d.Kind != flow.Abort {
+		t.Fatalf("decision = %v, want Abort", d.Kind)
+	}
+}
+
+// 客户端 TLS 指纹以只读的 f.tls 暴露给脚本。
+func TestTLSFingerprintExposed(t *testing.T) {
+	p := mustPlugin(t, Config{ID: "tls", Source: `function onRequest(f){
+		if (f.tls && f.tls.ja4.indexOf('t13') === 0 && f.tls.alpn[0] === 'h2') { header.set(f.headers, 'X-JA3', f.tls.ja3Hash); }
+	}`})
+	f := newReqFlow()
+	f.SetTLSFingerprint(&flow.TLSFingerprint{JA3Hash: "abc", JA4: "t13d1516h2_x_y", ALPN: []string{"h2"}})
+	p.OnRequest(context.Background(), f)
+	if got := f.Request.Header["X-JA3"]; len(got) != 1 || got[0] != "abc" {
+		t.Fatalf("X-JA3 = %v", got)
+	}
+
+	plain := newReqFlow()
+	p.OnRequest(context.Background(), plain)
+	if _, ok := plain.Request.Header["X-JA3"]; ok {
+		t.Fatal("明文请求不应有 f.tls")
+	}
+}
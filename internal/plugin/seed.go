@@ -36,7 +36,7 @@ const exampleManifest = `{
 `
 
 const exampleScript = `// Sniffy 示例插件
-// 可用钩子: onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg)
+// 可用钩子: onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg) / onMQTTMessage(msg)
 // flow 字段: id, method, url, host, path, headers{}, body, response{status,statusText,headers,body}
 // 处置助手: mock({status,headers,body}) / abort({status,reason}) / setBreakpoint()
 // 宿主 API: console.log/info/warn/error, store.get/set, settings, notify(title,msg)
@@ -56,7 +56,7 @@ function onResponse(flow) {
 
 // newPluginTemplate 是「页面内新建插件」时的起始脚本。
 const newPluginTemplate = `// Sniffy 插件 —— 在此实现你的钩子。
-// 钩子:onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg)
+// 钩子:onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg) / onMQTTMessage(msg)
 // 处置:mock({status,headers,body}) / abort({status,reason}) / setBreakpoint()
 // 宿主:console.*, store.get/set, settings, notify(title,msg)
 // 助手:base64.*, hex.*, url.parse, query.*, header.*, uuid(), randomId(n)
@@ -0,0 +1,64 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package protoschema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Decode 把 path 方法的一条消息解码为 JSON 视图。request 为 true 取请求类型,否则取响应类型。
+// 返回视图与 schema:已知方法为消息类型全名,否则为 WireSchema。连线缆格式都解析不了
+// (如加密或非 protobuf 载荷)时返回 ok=false。
+func (r *Registry) Decode(path string, request bool, payload []byte) (view []byte, schema string, ok bool) {
+	if md, types := r.messageType(path, request); md != nil {
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(payload, msg); err == nil {
+			if out, err := (protojson.MarshalOptions{Resolver: types}).Marshal(msg); err == nil {
+				return out, string(md.FullName()), true
+			}
+		}
+	}
+	out, err := DecodeWire(payload)
+	if err != nil {
+		return nil, "", false
+	}
+	return out, WireSchema, true
+}
+
+// Encode 把(可能被改写过的)JSON 视图按 schema 重新编码为 protobuf 字节。schema 须是
+// Decode 对同一方法与方向给出的值。按类型编码时未知字段不会保留,故只应在视图确有改动时调用。
+func (r *Registry) Encode(path string, request bool, schema string, view []byte) ([]byte, error) {
+	if schema == WireSchema {
+		return EncodeWire(view)
+	}
+	md, types := r.messageType(path, request)
+	if md == nil || string(md.FullName()) != schema {
+		return nil, fmt.Errorf("protoschema: 方法 %s 的消息类型 %s 已不可用", path, schema)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal(view, msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+func (r *Registry) messageType(path string, request bool) (protoreflect.MessageDescriptor, *dynamicpb.Types) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m := r.methods[path]
+	if m == nil {
+		return nil, nil
+	}
+	if request {
+		return m.Input(), r.types
+	}
+	return m.Output(), r.types
+}
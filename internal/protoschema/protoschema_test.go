@@ -0,0 +1,311 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package protoschema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const greeterPath = "/test.v1.Greeter/SayHello"
+
+// greeterFile 构造一个最小的 greeter.proto 描述:HelloRequest{name, count, tags, sent}、
+// HelloReply{message},其中 sent 引用 well-known 的 Timestamp。
+func greeterFile() *descriptorpb.FileDescriptorProto {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	opt := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	rep := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/greeter.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("HelloRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+					field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, opt, ""),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, rep, ""),
+					field("sent", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, opt, ".google.protobuf.Timestamp"),
+				},
+			},
+			{
+				Name: proto.String("HelloReply"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("message", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("SayHello"),
+				InputType:  proto.String(".test.v1.HelloRequest"),
+				OutputType: proto.String(".test.v1.HelloReply"),
+			}},
+		}},
+	}
+}
+
+func greeterSet(t *testing.T) []byte {
+	t.Helper()
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{greeterFile()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// helloRequest 手工编码 HelloRequest{name, count, tags...}。
+func helloRequest(name string, count int, tags ...string) []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(count))
+	for _, tag := range tags {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+	return b
+}
+
+func TestParseDescriptorSet(t *testing.T) {
+	services, err := ParseDescriptorSet(greeterSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0] != "test.v1.Greeter" {
+		t.Fatalf("services = %v", services)
+	}
+
+	if _, err := ParseDescriptorSet([]byte("not a descriptor set\xff")); err == nil {
+		t.Fatal("garbage should be rejected")
+	}
+	noService := greeterFile()
+	noService.Service = nil
+	b, _ := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{noService}})
+	if _, err := ParseDescriptorSet(b); err == nil {
+		t.Fatal("set without services should be rejected")
+	}
+	missing := greeterFile()
+	missing.Dependency = append(missing.Dependency, "vendor/missing.proto")
+	b, _ = proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{missing}})
+	if _, err := ParseDescriptorSet(b); err == nil || !strings.Contains(err.Error(), "vendor/missing.proto") {
+		t.Fatalf("missing dependency err = %v", err)
+	}
+}
+
+func TestTypedDecodeEncodeRoundTrip(t *testing.T) {
+	r := New()
+	if err := r.SetDescriptorSets(map[string][]byte{"greeter": greeterSet(t)}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Method(greeterPath) == nil {
+		t.Fatal("method not indexed")
+	}
+	if got := r.Services(); len(got) != 1 || got[0] != "test.v1.Greeter" {
+		t.Fatalf("Services = %v", got)
+	}
+
+	view, schema, ok := r.Decode(greeterPath, true, helloRequest("sniffy", 3, "a", "b"))
+	if !ok || schema != "test.v1.HelloRequest" {
+		t.Fatalf("decode ok=%v schema=%q", ok, schema)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(view, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "sniffy" || m["count"] != float64(3) || len(m["tags"].([]any)) != 2 {
+		t.Fatalf("view = %s", view)
+	}
+
+	edited := []byte(`{"name":"edited","count":7,"sent":"2026-01-02T03:04:05Z"}`)
+	out, err := r.Encode(greeterPath, true, schema, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, _, _ = r.Decode(greeterPath, true, out)
+	if !bytes.Contains(view, []byte(`"edited"`)) || !bytes.Contains(view, []byte(`"2026-01-02T03:04:05Z"`)) {
+		t.Fatalf("re-decoded view = %s", view)
+	}
+
+	// 响应方向取输出类型。
+	reply := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "hi")
+	if _, schema, _ := r.Decode(greeterPath, false, reply); schema != "test.v1.HelloReply" {
+		t.Fatalf("response schema = %q", schema)
+	}
+	// schema 与当前描述不符(如描述符集已被删除)时拒绝编码。
+	if _, err := r.Encode(greeterPath, true, "test.v1.Other", edited); err == nil {
+		t.Fatal("stale schema should be rejected")
+	}
+
+	// 删除描述符集后退回线缆格式。
+	if err := r.SetDescriptorSets(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, schema, ok := r.Decode(greeterPath, true, helloRequest("x", 1)); !ok || schema != WireSchema {
+		t.Fatalf("after removal: ok=%v schema=%q", ok, schema)
+	}
+}
+
+func TestWireViewRoundTrip(t *testing.T) {
+	inner := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "nested")
+	b := helloRequest("sniffy", 150, "a", "b")
+	b = protowire.AppendTag(b, 5, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 0xdeadbeef)
+	b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, 1<<63)
+	b = protowire.AppendTag(b, 7, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{0xff, 0x00, 0x80})
+	b = protowire.AppendTag(b, 8, protowire.BytesType)
+	b = protowire.AppendBytes(b, inner)
+
+	view, err := DecodeWire(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"1:string":"sniffy"`, `"2:varint":150`, `"3:string":["a","b"]`, `"5:fixed32":3735928559`, `"6:fixed64":9223372036854775808`, `"7:bytes":"/wCA"`, `"8:message":{"1:string":"nested"}`} {
+		if !bytes.Contains(view, []byte(want)) {
+			t.Errorf("view %s missing %s", view, want)
+		}
+	}
+	out, err := EncodeWire(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, b) {
+		t.Fatalf("round trip mismatch:\n got %x\nwant %x", out, b)
+	}
+
+	// 改写后按键上的类型重新编码。
+	edited := bytes.Replace(view, []byte(`"sniffy"`), []byte(`"changed"`), 1)
+	out, err = EncodeWire(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("changed")) {
+		t.Fatalf("edited encode = %x", out)
+	}
+
+	if _, err := DecodeWire([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Fatal("truncated input should fail")
+	}
+	if _, err := EncodeWire([]byte(`{"x:varint":1}`)); err == nil {
+		t.Fatal("bad key should fail")
+	}
+}
+
+// reflectionServer 模拟 grpc.reflection.v1:只认 file_containing_symbol,返回 greeter.proto。
+func reflectionServer(t *testing.T, hits *int) *httptest.Server {
+	t.Helper()
+	fd, err := proto.Marshal(greeterFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != reflectionPaths[0] {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "12")
+			return
+		}
+		*hits++
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(r.Body)
+		req := body.Bytes()[5:]
+		num, _, n := protowire.ConsumeTag(req)
+		sym, _ := protowire.ConsumeString(req[n:])
+		var resp []byte
+		if num == reqFileContainingSymbol && sym == "test.v1.Greeter" {
+			files := protowire.AppendBytes(protowire.AppendTag(nil, fileDescriptorProto, protowire.BytesType), fd)
+			resp = protowire.AppendBytes(protowire.AppendTag(nil, respFileDescriptor, protowire.BytesType), files)
+		} else {
+			e := protowire.AppendVarint(protowire.AppendTag(nil, errorCode, protowire.VarintType), 5)
+			e = protowire.AppendString(protowire.AppendTag(e, errorMessage, protowire.BytesType), "not found")
+			resp = protowire.AppendBytes(protowire.AppendTag(nil, respError, protowire.BytesType), e)
+		}
+		frame := make([]byte, 5, 5+len(resp))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write(append(frame, resp...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+}
+
+func TestDiscoverViaReflection(t *testing.T) {
+	hits := 0
+	srv := reflectionServer(t, &hits)
+	defer srv.Close()
+
+	r := New()
+	r.Discover(srv.Client(), srv.URL, greeterPath) // 反射未开启:不查询
+	r.SetReflection(true)
+	r.Discover(srv.Client(), srv.URL, greeterPath)
+	r.Discover(srv.Client(), srv.URL, "/test.v1.Greeter/Other") // 同一服务只查一次
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Method(greeterPath) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("reflection did not resolve; errors = %v", r.ReflectionErrors())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hits != 1 {
+		t.Fatalf("reflection hits = %d, want 1", hits)
+	}
+	if _, schema, _ := r.Decode(greeterPath, true, helloRequest("x", 1)); schema != "test.v1.HelloRequest" {
+		t.Fatalf("schema = %q", schema)
+	}
+
+	// 服务端不认识的服务:失败被记录,不重复查询。
+	r.Discover(srv.Client(), srv.URL, "/other.Svc/Call")
+	deadline = time.Now().Add(5 * time.Second)
+	for len(r.ReflectionErrors()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("reflection failure not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if errs := r.ReflectionErrors(); !strings.Contains(errs[srv.URL+"|other.Svc"], "not found") {
+		t.Fatalf("errors = %v", errs)
+	}
+}
+
+func TestSplitMethodPath(t *testing.T) {
+	cases := map[string]bool{
+		"/pkg.Svc/Method": true,
+		"pkg.Svc/Method":  true,
+		"/pkg.Svc":        false,
+		"/pkg.Svc/a/b":    false,
+		"//Method":        false,
+	}
+	for in, want := range cases {
+		if _, _, ok := splitMethodPath(in); ok != want {
+			t.Errorf("splitMethodPath(%q) ok = %v, want %v", in, ok, want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package protoschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// gRPC 服务端反射(grpc.reflection.v1 / v1alpha)的最小客户端。
+//
+// 不引入 grpc-go:每次查询是一条只发一帧请求的 gRPC 调用,经调用方给的 HTTP 客户端
+// (即代理的上游客户端,沿用上游代理等设置)发出。消息用 protowire 手工编解码,只涉及
+// ServerReflectionRequest / ServerReflectionResponse 的少数字段。
+
+// reflectionPaths 按优先级列出反射服务的方法路径:v1 不可用时回退 v1alpha。
+var reflectionPaths = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// reflectionTimeout 是一次反射查询(含依赖补齐)的总时限。
+const reflectionTimeout = 15 * time.Second
+
+// maxReflectionRounds 限制按文件名补齐依赖的轮数。
+const maxReflectionRounds = 16
+
+// ServerReflectionRequest / Response 的字段号。
+const (
+	reqFileByFilename       = 3
+	reqFileContainingSymbol = 4
+	respFileDescriptor      = 4
+	respError               = 7
+	fileDescriptorProto     = 1
+	errorCode               = 1
+	errorMessage            = 2
+)
+
+// errUnimplemented 表示服务端未实现该版本的反射服务。
+var errUnimplemented = errors.New("服务端未启用反射")
+
+// fetchReflection 取回定义 service 的文件及其依赖。
+func fetchReflection(client *http.Client, base, service string) ([]*descriptorpb.FileDescriptorProto, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reflectionTimeout)
+	defer cancel()
+
+	var (
+		path string
+		raw  [][]byte
+		err  error
+	)
+	for _, path = range reflectionPaths {
+		raw, err = reflectionCall(ctx, client, base+path, reqFileContainingSymbol, service)
+		if !errors.Is(err, errUnimplemented) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fds []*descriptorpb.FileDescriptorProto
+	have := map[string]bool{}
+	add := func(raw [][]byte) error {
+		for _, b := range raw {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return fmt.Errorf("反射返回的文件描述无效: %w", err)
+			}
+			if !have[fd.GetName()] {
+				have[fd.GetName()] = true
+				fds = append(fds, fd)
+			}
+		}
+		return nil
+	}
+	if err := add(raw); err != nil {
+		return nil, err
+	}
+	// 服务端可能省略此前「已发送过」的依赖(按连接记忆),每次调用又是新流,故按需补取。
+	for round := 0; round < maxReflectionRounds; round++ {
+		missing := missingDeps(fds, have)
+		if len(missing) == 0 {
+			return fds, nil
+		}
+		for _, name := range missing {
+			raw, err := reflectionCall(ctx, client, base+path, reqFileByFilename, name)
+			if err != nil {
+				return nil, fmt.Errorf("取依赖 %s: %w", name, err)
+			}
+			if err := add(raw); err != nil {
+				return nil, err
+			}
+			have[name] = true // 即使服务端没返回该文件也不再重复请求
+		}
+	}
+	return fds, nil
+}
+
+// missingDeps 返回既未取回、也不在内置注册表中的依赖。
+func missingDeps(fds []*descriptorpb.FileDescriptorProto, have map[string]bool) []string {
+	var out []string
+	for _, fd := range fds {
+		for _, dep := range fd.GetDependency() {
+			if have[dep] {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				continue
+			}
+			out = append(out, dep)
+		}
+	}
+	return out
+}
+
+// reflectionCall 发一条反射请求(field 为请求 oneof 的字段号),返回响应中的文件描述字节。
+func reflectionCall(ctx context.Context, client *http.Client, url string, field protowire.Number, value string) ([][]byte, error) {
+	msg := protowire.AppendString(protowire.AppendTag(nil, field, protowire.BytesType), value)
+	frame := make([]byte, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(msg)))
+	copy(frame[5:], msg)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errUnimplemented
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("反射请求返回 HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	// 状态可能在头里(Trailers-Only)或尾部里。
+	status := resp.Header.Get("Grpc-Status")
+	if s := resp.Trailer.Get("Grpc-Status"); s != "" {
+		status = s
+	}
+	switch status {
+	case "", "0":
+	case "12": // UNIMPLEMENTED
+		return nil, errUnimplemented
+	default:
+		msg := resp.Header.Get("Grpc-Message")
+		if m := resp.Trailer.Get("Grpc-Message"); m != "" {
+			msg = m
+		}
+		return nil, fmt.Errorf("反射调用失败: grpc-status %s %s", status, msg)
+	}
+
+	var out [][]byte
+	for len(body) >= 5 {
+		n := int(binary.BigEndian.Uint32(body[1:5]))
+		if body[0] != 0 || len(body) < 5+n {
+			return nil, errors.New("反射响应帧无效")
+		}
+		files, err := parseReflectionResponse(body[5 : 5+n])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, files...)
+		body = body[5+n:]
+	}
+	return out, nil
+}
+
+// parseReflectionResponse 取出 ServerReflectionResponse 中的文件描述,或其错误响应。
+func parseReflectionResponse(b []byte) ([][]byte, error) {
+	var out [][]byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType || (num != respFileDescriptor && num != respError) {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == respError {
+			return nil, reflectionError(v)
+		}
+		files, err := repeatedBytes(v, fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, files...)
+	}
+	return out, nil
+}
+
+// reflectionError 把 ErrorResponse 转为错误。
+func reflectionError(b []byte) error {
+	var (
+		code uint64
+		msg  string
+	)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		switch {
+		case num == errorCode && typ == protowire.VarintType:
+			code, n = protowire.ConsumeVarint(b)
+		case num == errorMessage && typ == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			msg = string(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+	}
+	return fmt.Errorf("反射查询失败: code %d %s", code, msg)
+}
+
+// repeatedBytes 取出消息中 field 字段(repeated bytes)的全部值。
+func repeatedBytes(b []byte, field protowire.Number) ([][]byte, error) {
+	var out [][]byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == field && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			out = append(out, v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return out, nil
+}
@@ -0,0 +1,334 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package protoschema 把 gRPC 消息的 protobuf 字节解码为可编辑的 JSON 视图,并在视图
+// 被改写后重新编码。消息类型按方法路径(/pkg.Service/Method)从用户上传的
+// FileDescriptorSet 或经服务端反射取回的描述符中查找;查不到时退回线缆格式视图(见 wire.go)。
+package protoschema
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// 常用的 well-known 类型:用户编译描述符集时常不带 --include_imports,依赖从这里补齐。
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Registry 按 gRPC 方法路径索引请求/响应消息类型,并发安全。
+type Registry struct {
+	mu sync.RWMutex
+	// uploaded 是用户上传的描述符集(按名称),reflected 是反射取回的描述符(按上游 base URL)。
+	uploaded  map[string][]*descriptorpb.FileDescriptorProto
+	reflected map[string][]*descriptorpb.FileDescriptorProto
+
+	methods map[string]protoreflect.MethodDescriptor
+	types   *dynamicpb.Types
+
+	reflection bool
+	// attempts 记录每个「base URL + 服务」的反射结果:nil 为成功或进行中,非 nil 为失败原因。
+	// 每个服务只尝试一次,避免对不支持反射的服务端每条消息都发起查询。
+	attempts map[string]error
+}
+
+// New 创建一个空注册表。
+func New() *Registry {
+	r := &Registry{
+		uploaded:  map[string][]*descriptorpb.FileDescriptorProto{},
+		reflected: map[string][]*descriptorpb.FileDescriptorProto{},
+		attempts:  map[string]error{},
+	}
+	r.rebuildLocked()
+	return r
+}
+
+// ParseDescriptorSet 解析并校验一个 FileDescriptorSet(protoc --descriptor_set_out 的产物),
+// 返回其中定义的服务全名。依赖缺失(且不是内置的 well-known 类型)时返回错误。
+func ParseDescriptorSet(data []byte) ([]string, error) {
+	fds, err := unmarshalSet(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := buildFiles(fds); err != nil {
+		return nil, err
+	}
+	services := servicesOf(fds)
+	if len(services) == 0 {
+		return nil, errors.New("描述符集中没有定义任何 gRPC 服务")
+	}
+	return services, nil
+}
+
+func unmarshalSet(data []byte) ([]*descriptorpb.FileDescriptorProto, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("不是有效的 FileDescriptorSet: %w", err)
+	}
+	if len(set.File) == 0 {
+		return nil, errors.New("描述符集为空")
+	}
+	return set.File, nil
+}
+
+// SetDescriptorSets 以 name → FileDescriptorSet 字节整体替换上传的描述符集。无法解析的集合
+// 被跳过,其错误合并返回;其余照常生效。
+func (r *Registry) SetDescriptorSets(sets map[string][]byte) error {
+	uploaded := make(map[string][]*descriptorpb.FileDescriptorProto, len(sets))
+	var errs []error
+	for name, data := range sets {
+		fds, err := unmarshalSet(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		uploaded[name] = fds
+	}
+	r.mu.Lock()
+	r.uploaded = uploaded
+	err := r.rebuildLocked()
+	r.mu.Unlock()
+	return errors.Join(append(errs, err)...)
+}
+
+// SetReflection 开关「遇到未知方法时经上游查询服务端反射」。重新开启时清空失败记录,
+// 使此前失败的服务可以重试。
+func (r *Registry) SetReflection(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if on && !r.reflection {
+		r.attempts = map[string]error{}
+	}
+	r.reflection = on
+}
+
+// Method 返回路径对应的方法描述;未知时返回 nil。
+func (r *Registry) Method(path string) protoreflect.MethodDescriptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.methods[path]
+}
+
+// Services 返回当前可解码的服务全名(已排序)。
+func (r *Registry) Services() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := map[string]bool{}
+	for _, m := range r.methods {
+		seen[string(m.Parent().FullName())] = true
+	}
+	out := make([]string, 0, len(seen))
+	for s := range seen {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Discover 在开启反射且该服务尚未尝试过时,后台经 client 向 base(scheme://host)查询
+// path 所属服务的描述符。查询期间及失败后,该服务的消息按线缆格式解码。
+func (r *Registry) Discover(client *http.Client, base, path string) {
+	service, _, ok := splitMethodPath(path)
+	if !ok || client == nil {
+		return
+	}
+	key := base + "|" + service
+	r.mu.Lock()
+	if !r.reflection {
+		r.mu.Unlock()
+		return
+	}
+	if _, tried := r.attempts[key]; tried {
+		r.mu.Unlock()
+		return
+	}
+	r.attempts[key] = nil
+	r.mu.Unlock()
+
+	go func() {
+		fds, err := fetchReflection(client, base, service)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err == nil {
+			r.reflected[base] = mergeFiles(r.reflected[base], fds)
+			err = r.rebuildLocked()
+		}
+		if err != nil {
+			r.attempts[key] = err
+		}
+	}()
+}
+
+// ReflectionErrors 返回反射失败的服务及原因(键为「base URL|服务」)。
+func (r *Registry) ReflectionErrors() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := map[string]string{}
+	for k, err := range r.attempts {
+		if err != nil {
+			out[k] = err.Error()
+		}
+	}
+	return out
+}
+
+// rebuildLocked 由全部描述符重建方法索引:上传的优先,反射的补充;同一路径的文件只取首个。
+func (r *Registry) rebuildLocked() error {
+	var all []*descriptorpb.FileDescriptorProto
+	for _, name := range sortedKeys(r.uploaded) {
+		all = mergeFiles(all, r.uploaded[name])
+	}
+	for _, base := range sortedKeys(r.reflected) {
+		all = mergeFiles(all, r.reflected[base])
+	}
+	files, err := buildFiles(all)
+	methods := map[string]protoreflect.MethodDescriptor{}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		svcs := fd.Services()
+		for i := 0; i < svcs.Len(); i++ {
+			sd := svcs.Get(i)
+			ms := sd.Methods()
+			for j := 0; j < ms.Len(); j++ {
+				md := ms.Get(j)
+				methods["/"+string(sd.FullName())+"/"+string(md.Name())] = md
+			}
+		}
+		return true
+	})
+	r.methods = methods
+	r.types = dynamicpb.NewTypes(files)
+	return err
+}
+
+// buildFiles 按依赖顺序构建文件描述;本地缺失的依赖从内置注册表(well-known 类型)补。
+// 构建失败的文件被跳过(错误合并返回),不影响其余文件。
+func buildFiles(fds []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(fds))
+	for _, fd := range fds {
+		byName[fd.GetName()] = fd
+	}
+	files := new(protoregistry.Files)
+	res := resolver{files}
+	state := map[string]int{} // 0 未处理,1 处理中,2 完成
+	var errs []error
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		switch state[name] {
+		case 1:
+			errs = append(errs, fmt.Errorf("%s: 循环依赖", name))
+			return false
+		case 2:
+			_, err := res.FindFileByPath(name)
+			return err == nil
+		}
+		fd, ok := byName[name]
+		if !ok {
+			_, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			return err == nil
+		}
+		state[name] = 1
+		defer func() { state[name] = 2 }()
+		for _, dep := range fd.GetDependency() {
+			if !visit(dep) {
+				errs = append(errs, fmt.Errorf("%s: 缺少依赖 %s", name, dep))
+				return false
+			}
+		}
+		f, err := protodesc.NewFile(fd, res)
+		if err == nil {
+			err = files.RegisterFile(f)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return false
+		}
+		return true
+	}
+	for _, fd := range fds {
+		visit(fd.GetName())
+	}
+	return files, errors.Join(errs...)
+}
+
+// resolver 先查本地构建的文件,再查内置注册表。
+type resolver struct{ local *protoregistry.Files }
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.local.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.local.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// mergeFiles 把 add 中 dst 尚未包含的文件(按路径)追加到 dst。
+func mergeFiles(dst, add []*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	have := make(map[string]bool, len(dst))
+	for _, fd := range dst {
+		have[fd.GetName()] = true
+	}
+	for _, fd := range add {
+		if !have[fd.GetName()] {
+			have[fd.GetName()] = true
+			dst = append(dst, fd)
+		}
+	}
+	return dst
+}
+
+func servicesOf(fds []*descriptorpb.FileDescriptorProto) []string {
+	var out []string
+	for _, fd := range fds {
+		for _, sd := range fd.GetService() {
+			name := sd.GetName()
+			if pkg := fd.GetPackage(); pkg != "" {
+				name = pkg + "." + name
+			}
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedKeys(m map[string][]*descriptorpb.FileDescriptorProto) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitMethodPath 把 "/pkg.Service/Method" 拆成服务全名与方法名。
+func splitMethodPath(path string) (service, method string, ok bool) {
+	path = strings.TrimPrefix(path, "/")
+	service, method, ok = strings.Cut(path, "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return "", "", false
+	}
+	return service, method, true
+}
@@ -0,0 +1,272 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package protoschema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 无 schema 时的线缆格式视图。
+//
+// 键为「字段号:线缆类型」,值按类型展开:varint 为数字,fixed32/fixed64 为无符号数字,
+// 长度前缀字段依内容猜为 string(可打印 UTF-8)、message(能完整解析为嵌套消息)或
+// bytes(base64)。同一字段出现多次时值为数组。键带类型使视图可逆:插件改了值后按
+// 键上的类型重新编码,字段按字段号升序写出。
+
+// WireSchema 是线缆格式视图的 schema 名。
+const WireSchema = "wire"
+
+// maxWireDepth 限制嵌套消息的猜测深度,防止病态输入递归过深。
+const maxWireDepth = 32
+
+const (
+	wireVarint  = "varint"
+	wireFixed32 = "fixed32"
+	wireFixed64 = "fixed64"
+	wireString  = "string"
+	wireBytes   = "bytes"
+	wireMessage = "message"
+)
+
+// DecodeWire 把任意 protobuf 字节解码为线缆格式视图。
+func DecodeWire(b []byte) ([]byte, error) {
+	v, err := decodeWireMessage(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// wireObject 是有序的线缆视图对象:按首次出现的字段顺序序列化。
+type wireObject struct {
+	keys   []string
+	values map[string][]any
+}
+
+func (o *wireObject) add(key string, v any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = append(o.values[key], v)
+}
+
+func (o *wireObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		var val any = o.values[k]
+		if vs := o.values[k]; len(vs) == 1 {
+			val = vs[0]
+		}
+		vb, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeWireMessage(b []byte, depth int) (*wireObject, error) {
+	obj := &wireObject{values: map[string][]any{}}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		var (
+			key string
+			val any
+		)
+		switch typ {
+		case protowire.VarintType:
+			v, m := protowire.ConsumeVarint(b)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			key, val, n = wireVarint, json.Number(strconv.FormatUint(v, 10)), m
+		case protowire.Fixed32Type:
+			v, m := protowire.ConsumeFixed32(b)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			key, val, n = wireFixed32, json.Number(strconv.FormatUint(uint64(v), 10)), m
+		case protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(b)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			key, val, n = wireFixed64, json.Number(strconv.FormatUint(v, 10)), m
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			key, val = guessBytes(v, depth)
+			n = m
+		default:
+			// 组(group)已废弃,不支持;视为无法解析,由调用方回退为原始字节。
+			return nil, fmt.Errorf("protoschema: 不支持的线缆类型 %d", typ)
+		}
+		obj.add(strconv.Itoa(int(num))+":"+key, val)
+		b = b[n:]
+	}
+	return obj, nil
+}
+
+// guessBytes 猜测长度前缀字段的内容:可打印文本优先于嵌套消息,二者皆非时为 bytes。
+func guessBytes(v []byte, depth int) (string, any) {
+	if printable(v) {
+		return wireString, string(v)
+	}
+	if depth < maxWireDepth && len(v) > 0 {
+		if sub, err := decodeWireMessage(v, depth+1); err == nil {
+			return wireMessage, sub
+		}
+	}
+	return wireBytes, base64.StdEncoding.EncodeToString(v)
+}
+
+// printable 报告 v 是否为可打印的 UTF-8 文本(允许常见空白)。
+func printable(v []byte) bool {
+	if !utf8.Valid(v) {
+		return false
+	}
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeWire 把线缆格式视图重新编码为 protobuf 字节。
+func EncodeWire(view []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(view))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return encodeWireMessage(obj)
+}
+
+type wireField struct {
+	num protowire.Number
+	typ string
+	val any
+}
+
+func encodeWireMessage(obj map[string]any) ([]byte, error) {
+	fields := make([]wireField, 0, len(obj))
+	for k, v := range obj {
+		numStr, typ, ok := strings.Cut(k, ":")
+		num, err := strconv.Atoi(numStr)
+		if !ok || err != nil || !protowire.Number(num).IsValid() {
+			return nil, fmt.Errorf("protoschema: 非法字段键 %q", k)
+		}
+		fields = append(fields, wireField{num: protowire.Number(num), typ: typ, val: v})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].num < fields[j].num })
+
+	var out []byte
+	for _, f := range fields {
+		vals, ok := f.val.([]any)
+		if !ok {
+			vals = []any{f.val}
+		}
+		for _, v := range vals {
+			var err error
+			if out, err = appendWireValue(out, f.num, f.typ, v); err != nil {
+				return nil, fmt.Errorf("protoschema: 字段 %d: %w", f.num, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+func appendWireValue(out []byte, num protowire.Number, typ string, v any) ([]byte, error) {
+	switch typ {
+	case wireVarint, wireFixed32, wireFixed64:
+		n, err := wireUint(v)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case wireVarint:
+			return protowire.AppendVarint(protowire.AppendTag(out, num, protowire.VarintType), n), nil
+		case wireFixed32:
+			if n > 1<<32-1 {
+				return nil, errors.New("fixed32 越界")
+			}
+			return protowire.AppendFixed32(protowire.AppendTag(out, num, protowire.Fixed32Type), uint32(n)), nil
+		default:
+			return protowire.AppendFixed64(protowire.AppendTag(out, num, protowire.Fixed64Type), n), nil
+		}
+	case wireString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("string 字段的值须为字符串")
+		}
+		return protowire.AppendString(protowire.AppendTag(out, num, protowire.BytesType), s), nil
+	case wireBytes:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("bytes 字段的值须为 base64 字符串")
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendBytes(protowire.AppendTag(out, num, protowire.BytesType), b), nil
+	case wireMessage:
+		sub, ok := v.(map[string]any)
+		if !ok {
+			return nil, errors.New("message 字段的值须为对象")
+		}
+		b, err := encodeWireMessage(sub)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendBytes(protowire.AppendTag(out, num, protowire.BytesType), b), nil
+	}
+	return nil, fmt.Errorf("未知线缆类型 %q", typ)
+}
+
+// wireUint 把数字值转为 uint64;负数按 int64 的补码写出(与 protobuf 对 int32/int64 负值一致)。
+func wireUint(v any) (uint64, error) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.New("数值字段的值须为数字")
+	}
+	if u, err := strconv.ParseUint(num.String(), 10, 64); err == nil {
+		return u, nil
+	}
+	i, err := strconv.ParseInt(num.String(), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("非法整数 %s", num)
+	}
+	return uint64(i), nil
+}
@@ -282,11 +282,32 @@ func fieldValue(c service.InterceptCondition, f *flow.Flow) (string, bool) {
 			return "", false
 		}
 		return headerValue(f.Response.Header, c.HeaderName), true
+	case "ja3", "ja3_full", "ja4", "tls_sni", "tls_alpn":
+		return tlsFieldValue(c.Type, f.TLSFingerprint()), true
 	default:
 		return "", false
 	}
 }
 
+// tlsFieldValue 取客户端 TLS 指纹字段;明文请求没有指纹,按空串参与匹配(可用 not_exists 筛出)。
+func tlsFieldValue(typ string, fp *flow.TLSFingerprint) string {
+	if fp == nil {
+		return ""
+	}
+	switch typ {
+	case "ja3":
+		return fp.JA3Hash
+	case "ja3_full":
+		return fp.JA3
+	case "ja4":
+		return fp.JA4
+	case "tls_sni":
+		return fp.SNI
+	default: // tls_alpn
+		return strings.Join(fp.ALPN, ",")
+	}
+}
+
 func compare(op, field, val string, caseSensitive bool) bool {
 	switch op {
 	case "regex", "not_regex":
@@ -83,6 +83,18 @@ func TestFieldValueAllTypes(t *testing.T) {
 	check(service.InterceptCondition{Type: "content_type"}, "text/html", true)
 	check(service.InterceptCondition{Type: "response_status"}, "503", true)
 	check(service.InterceptCondition{Type: "response_header", HeaderName: "Server"}, "nginx", true)
+
+	// 明文请求没有 TLS 指纹:字段可用、取空串。
+	check(service.InterceptCondition{Type: "ja3"}, "", true)
+	f.SetTLSFingerprint(&flow.TLSFingerprint{
+		JA3: "771,4865,0,29,0", JA3Hash: "abc", JA4: "t13d0101h2_x_y",
+		SNI: "api.example.com", ALPN: []string{"h2", "http/1.1"},
+	})
+	check(service.InterceptCondition{Type: "ja3"}, "abc", true)
+	check(service.InterceptCondition{Type: "ja3_full"}, "771,4865,0,29,0", true)
+	check(service.InterceptCondition{Type: "ja4"}, "t13d0101h2_x_y", true)
+	check(service.InterceptCondition{Type: "tls_sni"}, "api.example.com", true)
+	check(service.InterceptCondition{Type: "tls_alpn"}, "h2,http/1.1", true)
 }
 
 func TestFieldValueInvalidURL(t *testing.T) {
@@ -0,0 +1,203 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+// clientCertFileName 是上游客户端证书(mTLS)的持久化文件名。含私钥,与导入的服务端证书一样
+// 单独以 0600 存放在证书目录。
+const clientCertFileName = "clientcerts.json"
+
+// ClientCert 是一张出示给上游的客户端证书(证书链 + 私钥的 PEM)及其生效的主机通配模式。
+// 客户端证书的 SAN 与目标主机无关,主机范围由用户指定。
+type ClientCert struct {
+	Hosts   []string `json:"hosts"`
+	CertPEM string   `json:"certPEM"`
+	KeyPEM  string   `json:"keyPEM"`
+}
+
+// ClientCertDTO 是暴露给前端/传输层的摘要,不含私钥。
+type ClientCertDTO struct {
+	ID       string   `json:"id"`    // 叶子证书指纹(SHA-256 hex),删除时按它引用
+	Hosts    []string `json:"hosts"` // 生效的主机通配模式
+	Subject  string   `json:"subject"`
+	Issuer   string   `json:"issuer"`
+	NotAfter string   `json:"notAfter"`
+}
+
+// ClientCertBinding 是下发给引擎的一张客户端证书(Leaf 已解析)及其主机通配模式。
+type ClientCertBinding struct {
+	Hosts []string
+	Cert  *tls.Certificate
+}
+
+type clientCertStore struct {
+	mu    sync.RWMutex
+	items []ClientCert
+	path  string // 持久化文件;为空则仅内存
+}
+
+func newClientCertStore(path string) *clientCertStore {
+	cs := &clientCertStore{path: path}
+	if path == "" {
+		return cs
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		var items []ClientCert
+		if json.Unmarshal(data, &items) == nil {
+			cs.items = items
+		}
+	}
+	return cs
+}
+
+func (cs *clientCertStore) save() error {
+	if cs.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cs.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cs.path, data)
+}
+
+// importPEM 校验证书与私钥成对且匹配后 upsert(同一张证书再次导入即更新主机范围),持久化并返回摘要。
+func (cs *clientCertStore) importPEM(certPEM, keyPEM string, hosts []string) (ClientCertDTO, error) {
+	hosts = cleanHostPatterns(hosts)
+	if len(hosts) == 0 {
+		return ClientCertDTO{}, errors.New("至少填写一个生效的主机")
+	}
+	entry := ClientCert{Hosts: hosts, CertPEM: certPEM, KeyPEM: keyPEM}
+	_, leaf, ok := parseEntry(ServerCert{CertPEM: certPEM, KeyPEM: keyPEM})
+	if !ok {
+		return ClientCertDTO{}, errors.New("证书与私钥无效或不匹配")
+	}
+	id := certFingerprint(leaf)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	prev := cs.items
+	kept := make([]ClientCert, 0, len(cs.items)+1)
+	for _, it := range cs.items {
+		if _, lf, ok := parseEntry(ServerCert{CertPEM: it.CertPEM, KeyPEM: it.KeyPEM}); ok && certFingerprint(lf) == id {
+			continue
+		}
+		kept = append(kept, it)
+	}
+	cs.items = append(kept, entry)
+	if err := cs.save(); err != nil {
+		cs.items = prev
+		return ClientCertDTO{}, fmt.Errorf("保存失败: %w", err)
+	}
+	return clientCertDTO(leaf, hosts), nil
+}
+
+// importPKCS12 解出 PKCS#12 中的私钥与证书链,转成 PEM 后按 importPEM 导入。
+func (cs *clientCertStore) importPKCS12(data []byte, password string, hosts []string) (ClientCertDTO, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return ClientCertDTO{}, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return ClientCertDTO{}, fmt.Errorf("不支持的私钥类型: %w", err)
+	}
+	var certPEM strings.Builder
+	for _, c := range append([]*x509.Certificate{leaf}, chain...) {
+		_ = pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return cs.importPEM(certPEM.String(), string(keyPEM), hosts)
+}
+
+// delete 按证书指纹删除对应记录。
+func (cs *clientCertStore) delete(id string) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for i, it := range cs.items {
+		if _, leaf, ok := parseEntry(ServerCert{CertPEM: it.CertPEM, KeyPEM: it.KeyPEM}); ok && certFingerprint(leaf) == id {
+			cs.items = append(cs.items[:i], cs.items[i+1:]...)
+			_ = cs.save() // best-effort,同 serverCertStore.delete
+			return
+		}
+	}
+}
+
+// dtos 返回全部客户端证书的摘要(不含私钥),跳过解析失败的条目。
+func (cs *clientCertStore) dtos() []ClientCertDTO {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	out := make([]ClientCertDTO, 0, len(cs.items))
+	for _, it := range cs.items {
+		if _, leaf, ok := parseEntry(ServerCert{CertPEM: it.CertPEM, KeyPEM: it.KeyPEM}); ok {
+			out = append(out, clientCertDTO(leaf, it.Hosts))
+		}
+	}
+	return out
+}
+
+// bindings 返回全部客户端证书及其主机模式,供下发到引擎;按导入先后排列,跳过解析失败的条目。
+func (cs *clientCertStore) bindings() []ClientCertBinding {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	out := make([]ClientCertBinding, 0, len(cs.items))
+	for _, it := range cs.items {
+		pair, leaf, ok := parseEntry(ServerCert{CertPEM: it.CertPEM, KeyPEM: it.KeyPEM})
+		if !ok {
+			continue
+		}
+		c := pair
+		c.Leaf = leaf
+		out = append(out, ClientCertBinding{Hosts: it.Hosts, Cert: &c})
+	}
+	return out
+}
+
+// cleanHostPatterns 去掉空白项并去重,保留顺序。
+func cleanHostPatterns(hosts []string) []string {
+	seen := make(map[string]struct{}, len(hosts))
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if _, dup := seen[h]; dup {
+			continue
+		}
+		seen[h] = struct{}{}
+		out = append(out, h)
+	}
+	return out
+}
+
+func clientCertDTO(leaf *x509.Certificate, hosts []string) ClientCertDTO {
+	return ClientCertDTO{
+		ID:       certFingerprint(leaf),
+		Hosts:    hosts,
+		Subject:  leaf.Subject.CommonName,
+		Issuer:   leaf.Issuer.CommonName,
+		NotAfter: leaf.NotAfter.Format(time.RFC3339),
+	}
+}
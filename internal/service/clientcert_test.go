@@ -0,0 +1,126 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+// TestClientCertStoreImportUpsertPersist 主机模式规整后保存;同一证书再次导入替换主机列表;
+// 重新加载后证书与主机仍在,删除后消失。
+func TestClientCertStoreImportUpsertPersist(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), clientCertFileName)
+	cs := newClientCertStore(path)
+	certPEM, keyPEM := genCert(t, "client-a")
+
+	dto, err := cs.importPEM(certPEM, keyPEM, []string{" API.Example.com ", "", "api.example.com", "*.corp.example.com"})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if dto.ID == "" || dto.Subject != "client-a" || !slices.Equal(dto.Hosts, []string{"api.example.com", "*.corp.example.com"}) {
+		t.Fatalf("摘要 = %+v", dto)
+	}
+	if _, err := cs.importPEM(certPEM, keyPEM, []string{"other.example.com"}); err != nil {
+		t.Fatalf("再次导入失败: %v", err)
+	}
+
+	reloaded := newClientCertStore(path)
+	list := reloaded.dtos()
+	if len(list) != 1 || !slices.Equal(list[0].Hosts, []string{"other.example.com"}) {
+		t.Fatalf("重新加载 = %+v", list)
+	}
+	bs := reloaded.bindings()
+	if len(bs) != 1 || bs[0].Cert == nil || bs[0].Cert.Leaf == nil || bs[0].Cert.Leaf.Subject.CommonName != "client-a" {
+		t.Fatalf("下发列表 = %+v", bs)
+	}
+
+	reloaded.delete(dto.ID)
+	if len(newClientCertStore(path).dtos()) != 0 {
+		t.Fatal("删除未落盘")
+	}
+}
+
+// TestClientCertStoreRejectsInvalid 缺主机、证书与私钥不匹配、PKCS#12 密码错误都拒绝导入。
+func TestClientCertStoreRejectsInvalid(t *testing.T) {
+	t.Parallel()
+	cs := newClientCertStore("")
+	certPEM, keyPEM := genCert(t, "client-a")
+	_, otherKey := genCert(t, "client-b")
+
+	if _, err := cs.importPEM(certPEM, keyPEM, []string{" "}); err == nil {
+		t.Fatal("缺主机应拒绝")
+	}
+	if _, err := cs.importPEM(certPEM, otherKey, []string{"a.example.com"}); err == nil {
+		t.Fatal("私钥不匹配应拒绝")
+	}
+	if _, err := cs.importPKCS12([]byte("not p12"), "", []string{"a.example.com"}); err == nil {
+		t.Fatal("无效 PKCS#12 应拒绝")
+	}
+	if len(cs.dtos()) != 0 {
+		t.Fatal("失败的导入不应留下条目")
+	}
+}
+
+// TestClientCertStoreImportPKCS12 PKCS#12 解出的证书与私钥转 PEM 后导入,与 PEM 导入等价。
+func TestClientCertStoreImportPKCS12(t *testing.T) {
+	t.Parallel()
+	certPEM, keyPEM := genCert(t, "client-p12")
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(certPEM))
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := pkcs12.Modern2023.Encode(pair.PrivateKey, leaf, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := newClientCertStore("")
+	if _, err := cs.importPKCS12(data, "wrong", []string{"a.example.com"}); err == nil {
+		t.Fatal("密码错误应拒绝")
+	}
+	dto, err := cs.importPKCS12(data, "secret", []string{"a.example.com"})
+	if err != nil {
+		t.Fatalf("导入 PKCS#12 失败: %v", err)
+	}
+	if dto.Subject != "client-p12" || dto.ID != certFingerprint(leaf) {
+		t.Fatalf("摘要 = %+v", dto)
+	}
+}
+
+// TestServiceClientCertApplyChain 注入时以持久化证书应用一次,导入与删除后都热下发。
+func TestServiceClientCertApplyChain(t *testing.T) {
+	t.Parallel()
+	svc := newTestService(t)
+	var applied [][]ClientCertBinding
+	svc.SetClientCertsApplier(func(bs []ClientCertBinding) error {
+		applied = append(applied, bs)
+		return nil
+	})
+	certPEM, keyPEM := genCert(t, "client-a")
+	dto, err := svc.ImportClientCert(certPEM, keyPEM, []string{"a.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.DeleteClientCert(dto.ID)
+
+	if len(applied) != 3 || len(applied[0]) != 0 || len(applied[1]) != 1 || len(applied[2]) != 0 {
+		t.Fatalf("下发序列 = %+v", applied)
+	}
+	if !slices.Equal(applied[1][0].Hosts, []string{"a.example.com"}) {
+		t.Fatalf("下发主机 = %v", applied[1][0].Hosts)
+	}
+}
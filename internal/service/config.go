@@ -6,7 +6,9 @@
 package service
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"net/url"
@@ -27,6 +29,22 @@ const (
 	// defaultLargeBodyKiB 是透传旁路的默认大小阈值(2MiB):再大的体缓冲起来,
 	// 首字节延迟与内存占用都开始明显。
 	defaultLargeBodyKiB int64 = 2048
+
+	// defaultReversePort 是反向代理监听端的默认端口。
+	defaultReversePort = 8081
+
+	// defaultAutoBypassThreshold / defaultAutoBypassWindowSec:同一主机 60 秒内握手失败 3 次
+	// 即自动改为直通。
+	defaultAutoBypassThreshold = 3
+	defaultAutoBypassWindowSec = 60
+
+	// defaultDNSPort / defaultDNSUpstream 是内置 DNS 服务端的默认端口与上游解析器。
+	// 设备的 DNS 设置通常不能改端口,故默认 53(多数系统上需要特权)。
+	defaultDNSPort     = 53
+	defaultDNSUpstream = "1.1.1.1:53"
+
+	// defaultUpstreamVerify 是上游证书校验的默认模式:校验并记入会话,但不拦截。
+	defaultUpstreamVerify = "warn"
 )
 
 // AppConfig 对应前端 SniffyConfig 的核心字段(可持久化)。
@@ -62,6 +80,56 @@ type AppConfig struct {
 	// 分别在 allow / deny 模式下生效。
 	DecryptAllow []string `json:"decryptAllow,omitempty"`
 	DecryptDeny  []string `json:"decryptDeny,omitempty"`
+	// AutoBypass 开启后,同一主机在 AutoBypassWindowSec 秒内 MITM 握手失败 AutoBypassThreshold 次
+	// (多为客户端固定证书),即加入自动绕过列表、之后直通不解密,直到手动重置。默认关闭。
+	// AutoBypassPersist 时列表存入 AutoBypassHosts,重启后恢复。
+	AutoBypass          bool     `json:"autoBypass"`
+	AutoBypassThreshold int      `json:"autoBypassThreshold"`
+	AutoBypassWindowSec int      `json:"autoBypassWindowSec"`
+	AutoBypassPersist   bool     `json:"autoBypassPersist"`
+	AutoBypassHosts     []string `json:"autoBypassHosts,omitempty"`
+	// ReverseProxy 开启反向代理监听端:ReversePort 上的请求一律转发到 ReverseUpstream
+	// (http(s) 基础 URL),客户端无需任何代理设置。ReverseTLS 时入站先终结 TLS;
+	// ReversePreserveHost 时保留客户端 Host 头,否则改写为上游主机。
+	ReverseProxy        bool   `json:"reverseProxy"`
+	ReversePort         int    `json:"reversePort"`
+	ReverseUpstream     string `json:"reverseUpstream"`
+	ReverseTLS          bool   `json:"reverseTLS"`
+	ReversePreserveHost bool   `json:"reversePreserveHost"`
+	// Listeners 是附加监听端列表,各自有独立的模式、绑定地址、客户端认证与解密范围。
+	Listeners []ListenerConfig `json:"listeners,omitempty"`
+	// DNSServer 开启内置 DNS 服务端(DNSPort 上的 UDP 与 TCP):DNSHosts(hosts 文件格式)
+	// 中的域名以表中地址应答,其余转发到 DNSUpstream。每次查询都会被记录。
+	DNSServer   bool   `json:"dnsServer"`
+	DNSPort     int    `json:"dnsPort"`
+	DNSUpstream string `json:"dnsUpstream"`
+	DNSHosts    string `json:"dnsHosts"`
+	// GRPCReflection 开启后,遇到描述符集里没有的 gRPC 方法时经上游客户端查询服务端反射,
+	// 取回描述符用于解码后续消息。会向服务端发出额外请求,故默认关闭。
+	GRPCReflection bool `json:"grpcReflection"`
+	// TLSMirror 开启后,https 上游连接按客户端 ClientHello 的套件、版本、曲线与 ALPN 握手,
+	// 以通过服务端的 TLS 指纹校验。TLSMirrorHosts 为主机通配模式,为空时对全部主机生效。
+	TLSMirror      bool     `json:"tlsMirror"`
+	TLSMirrorHosts []string `json:"tlsMirrorHosts,omitempty"`
+	// UpstreamVerify 为上游证书校验模式:"off" 不校验、"warn" 校验并把结论记入会话但照常转发、
+	// "enforce" 未通过即拒绝连接。UpstreamTrustRoots 为在系统根之外追加信任的根证书(PEM,可多张)。
+	UpstreamVerify     string `json:"upstreamVerify"`
+	UpstreamTrustRoots string `json:"upstreamTrustRoots,omitempty"`
+	// UpstreamRoutes 是上游路由表:每行「主机模式[,主机模式...] 目标」,主机模式为通配或 CIDR,
+	// 目标为 DIRECT 或代理地址(http/https/socks5/socks5h)。按顺序首条命中即用,都不命中时
+	// 走上面的上游代理(未启用时直连)。
+	UpstreamRoutes string `json:"upstreamRoutes,omitempty"`
+	// HostMappings 是主机映射表:每行「主机模式[,主机模式...] 目标」,目标为 IP、主机名或 host:port。
+	// 直连上游时命中的主机改连目标地址,SNI 与 Host 头不变;上游路由仍按原主机选取,
+	// 经上游代理出站的目标不做映射。
+	HostMappings string `json:"hostMappings,omitempty"`
+	// PAC 开启后代理端口(/proxy.pac、/wpad.dat 与 cert.sniffy 下同名路径)与管理 API 提供
+	// 代理自动配置脚本:命中 PACExclude 的主机直连,PACInclude 为空时其余全部经 Sniffy,否则
+	// 只有命中 PACInclude 的经 Sniffy。SystemProxyPAC 让系统代理改设为该脚本的 URL。
+	PAC            bool     `json:"pac"`
+	PACInclude     []string `json:"pacInclude,omitempty"`
+	PACExclude     []string `json:"pacExclude,omitempty"`
+	SystemProxyPAC bool     `json:"systemProxyPac"`
 	// Extra 保存前端可能附带的其它字段,原样回存。
 	Extra map[string]any `json:"-"`
 }
@@ -72,35 +140,63 @@ func defaultAppConfig() AppConfig {
 	return AppConfig{
 		Port: 8080, EnableHTTPS: true, Recording: true, SystemProxy: true, AutoProxy: true,
 		ThrottleKiBps: defaultThrottleKiBps, RunInBackground: true, DecryptScope: "all",
-		LargeBodyPassthrough: true, LargeBodyKiB: defaultLargeBodyKiB,
+		LargeBodyPassthrough: true, LargeBodyKiB: defaultLargeBodyKiB, ReversePort: defaultReversePort,
+		DNSPort: defaultDNSPort, DNSUpstream: defaultDNSUpstream,
+		AutoBypassThreshold: defaultAutoBypassThreshold, AutoBypassWindowSec: defaultAutoBypassWindowSec,
+		UpstreamVerify: defaultUpstreamVerify,
 	}
 }
 
 // ConfigView 是对外返回的配置视图。代理密码只在 Service 内部保存与使用,
 // IPC/API 只返回是否已设置,避免把秘密复制到前端状态或网络响应中。
 type ConfigView struct {
-	Port                 int      `json:"port"`
-	EnableHTTPS          bool     `json:"enableHTTPS"`
-	Recording            bool     `json:"recording"`
-	MaxFlows             int      `json:"maxFlows,omitempty"`
-	Upstream             bool     `json:"upstream"`
-	UpstreamAddr         string   `json:"upstreamAddr"`
-	UpstreamAuth         bool     `json:"upstreamAuth"`
-	UpstreamUsername     string   `json:"upstreamUsername"`
-	UpstreamPasswordSet  bool     `json:"upstreamPasswordSet"`
-	ProxyAuth            bool     `json:"proxyAuth"`
-	ProxyUsername        string   `json:"proxyUsername"`
-	ProxyPasswordSet     bool     `json:"proxyPasswordSet"`
-	SystemProxy          bool     `json:"systemProxy"`
-	AutoProxy            bool     `json:"autoSystemProxy"`
-	Throttle             bool     `json:"throttle"`
-	ThrottleKiBps        int64    `json:"throttleKiBps"`
-	LargeBodyPassthrough bool     `json:"largeBodyPassthrough"`
-	LargeBodyKiB         int64    `json:"largeBodyKiB"`
-	RunInBackground      bool     `json:"runInBackground"`
-	DecryptScope         string   `json:"decryptScope,omitempty"`
-	DecryptAllow         []string `json:"decryptAllow,omitempty"`
-	DecryptDeny          []string `json:"decryptDeny,omitempty"`
+	Port                 int            `json:"port"`
+	EnableHTTPS          bool           `json:"enableHTTPS"`
+	Recording            bool           `json:"recording"`
+	MaxFlows             int            `json:"maxFlows,omitempty"`
+	Upstream             bool           `json:"upstream"`
+	UpstreamAddr         string         `json:"upstreamAddr"`
+	UpstreamAuth         bool           `json:"upstreamAuth"`
+	UpstreamUsername     string         `json:"upstreamUsername"`
+	UpstreamPasswordSet  bool           `json:"upstreamPasswordSet"`
+	ProxyAuth            bool           `json:"proxyAuth"`
+	ProxyUsername        string         `json:"proxyUsername"`
+	ProxyPasswordSet     bool           `json:"proxyPasswordSet"`
+	SystemProxy          bool           `json:"systemProxy"`
+	AutoProxy            bool           `json:"autoSystemProxy"`
+	Throttle             bool           `json:"throttle"`
+	ThrottleKiBps        int64          `json:"throttleKiBps"`
+	LargeBodyPassthrough bool           `json:"largeBodyPassthrough"`
+	LargeBodyKiB         int64          `json:"largeBodyKiB"`
+	RunInBackground      bool           `json:"runInBackground"`
+	DecryptScope         string         `json:"decryptScope,omitempty"`
+	DecryptAllow         []string       `json:"decryptAllow,omitempty"`
+	DecryptDeny          []string       `json:"decryptDeny,omitempty"`
+	AutoBypass           bool           `json:"autoBypass"`
+	AutoBypassThreshold  int            `json:"autoBypassThreshold"`
+	AutoBypassWindowSec  int            `json:"autoBypassWindowSec"`
+	AutoBypassPersist    bool           `json:"autoBypassPersist"`
+	ReverseProxy         bool           `json:"reverseProxy"`
+	ReversePort          int            `json:"reversePort"`
+	ReverseUpstream      string         `json:"reverseUpstream"`
+	ReverseTLS           bool           `json:"reverseTLS"`
+	ReversePreserveHost  bool           `json:"reversePreserveHost"`
+	Listeners            []ListenerView `json:"listeners,omitempty"`
+	DNSServer            bool           `json:"dnsServer"`
+	DNSPort              int            `json:"dnsPort"`
+	DNSUpstream          string         `json:"dnsUpstream"`
+	DNSHosts             string         `json:"dnsHosts"`
+	GRPCReflection       bool           `json:"grpcReflection"`
+	TLSMirror            bool           `json:"tlsMirror"`
+	TLSMirrorHosts       []string       `json:"tlsMirrorHosts,omitempty"`
+	UpstreamVerify       string         `json:"upstreamVerify"`
+	UpstreamTrustRoots   string         `json:"upstreamTrustRoots,omitempty"`
+	UpstreamRoutes       string         `json:"upstreamRoutes,omitempty"`
+	HostMappings         string         `json:"hostMappings,omitempty"`
+	PAC                  bool           `json:"pac"`
+	PACInclude           []string       `json:"pacInclude,omitempty"`
+	PACExclude           []string       `json:"pacExclude,omitempty"`
+	SystemProxyPAC       bool           `json:"systemProxyPac"`
 }
 
 // PublicConfig 返回不含代理密码的配置视图,供 IPC/API 使用。
@@ -128,6 +224,31 @@ func PublicConfig(c AppConfig) ConfigView {
 		DecryptScope:         c.DecryptScope,
 		DecryptAllow:         append([]string(nil), c.DecryptAllow...),
 		DecryptDeny:          append([]string(nil), c.DecryptDeny...),
+		AutoBypass:           c.AutoBypass,
+		AutoBypassThreshold:  c.AutoBypassThreshold,
+		AutoBypassWindowSec:  c.AutoBypassWindowSec,
+		AutoBypassPersist:    c.AutoBypassPersist,
+		ReverseProxy:         c.ReverseProxy,
+		ReversePort:          c.ReversePort,
+		ReverseUpstream:      c.ReverseUpstream,
+		ReverseTLS:           c.ReverseTLS,
+		ReversePreserveHost:  c.ReversePreserveHost,
+		Listeners:            publicListeners(c.Listeners),
+		DNSServer:            c.DNSServer,
+		DNSPort:              c.DNSPort,
+		DNSUpstream:          c.DNSUpstream,
+		DNSHosts:             c.DNSHosts,
+		GRPCReflection:       c.GRPCReflection,
+		TLSMirror:            c.TLSMirror,
+		TLSMirrorHosts:       append([]string(nil), c.TLSMirrorHosts...),
+		UpstreamVerify:       c.UpstreamVerify,
+		UpstreamTrustRoots:   c.UpstreamTrustRoots,
+		UpstreamRoutes:       maskRoutePasswords(c.UpstreamRoutes),
+		HostMappings:         c.HostMappings,
+		PAC:                  c.PAC,
+		PACInclude:           append([]string(nil), c.PACInclude...),
+		PACExclude:           append([]string(nil), c.PACExclude...),
+		SystemProxyPAC:       c.SystemProxyPAC,
 	}
 }
 
@@ -185,6 +306,68 @@ func stripUpstreamUserinfo(addr string) string {
 	return trimmed
 }
 
+// routePasswordMask 在对外视图中替换路由表代理地址里的密码。
+const routePasswordMask = "REDACTED" // 只用不被转义的字符,视图里原样可读
+
+// maskRoutePasswords 把路由表中代理地址的密码换成掩码,账号与其余内容原样保留。
+func maskRoutePasswords(table string) string {
+	return mapRouteTargets(table, true, func(u *url.URL) {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), routePasswordMask)
+		}
+	})
+}
+
+// restoreRoutePasswords 把前端回存的路由表中仍是掩码的密码,按同一代理(scheme、账号、
+// 主机)从旧表取回;旧表里找不到对应代理时保留掩码原样。
+func restoreRoutePasswords(table, old string) string {
+	key := func(u *url.URL) string { return u.Scheme + "://" + u.User.Username() + "@" + u.Host }
+	saved := map[string]string{}
+	mapRouteTargets(old, false, func(u *url.URL) {
+		if p, ok := u.User.Password(); ok {
+			saved[key(u)] = p
+		}
+	})
+	return mapRouteTargets(table, false, func(u *url.URL) {
+		if p, ok := u.User.Password(); ok && p == routePasswordMask {
+			if orig, ok := saved[key(u)]; ok {
+				u.User = url.UserPassword(u.User.Username(), orig)
+			}
+		}
+	})
+}
+
+// mapRouteTargets 对路由表每行带 userinfo 的代理地址调用 fn 改写,其余内容保持原样。
+// 地址无法解析时 dropBad 为真则按字符串抹掉 userinfo(对外视图宁可丢失凭据也不外泄),否则原样保留。
+func mapRouteTargets(table string, dropBad bool, fn func(u *url.URL)) string {
+	lines := strings.Split(table, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") || !strings.Contains(fields[1], "@") {
+			continue
+		}
+		target := fields[1]
+		hadScheme := strings.Contains(target, "://")
+		if !hadScheme {
+			target = "http://" + target
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.User == nil {
+			if dropBad {
+				lines[i] = strings.Replace(line, fields[1], dropUserinfo(fields[1]), 1)
+			}
+			continue
+		}
+		fn(u)
+		out := u.String()
+		if !hadScheme {
+			out = strings.TrimPrefix(out, "http://")
+		}
+		lines[i] = strings.Replace(line, fields[1], out, 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // normalizeUpstreamConfig 把旧格式地址中的 userinfo 迁移到独立认证字段,并在关闭
 // 认证时删除独立保存的密码;返回是否需要把结果写回配置文件。
 // 旧版本只能靠地址内嵌凭据认证,单纯剥离会让升级后的上游认证静默失效。
@@ -315,6 +498,21 @@ func (cs *configStore) load() {
 		if c.LargeBodyKiB <= 0 {
 			c.LargeBodyKiB = defaultLargeBodyKiB
 		}
+		if c.ReversePort < 1 || c.ReversePort > 65535 {
+			c.ReversePort = defaultReversePort
+		}
+		if c.DNSPort < 1 || c.DNSPort > 65535 {
+			c.DNSPort = defaultDNSPort
+		}
+		if !validAutoBypassThreshold(c.AutoBypassThreshold) {
+			c.AutoBypassThreshold = defaultAutoBypassThreshold
+		}
+		if !validAutoBypassWindowSec(c.AutoBypassWindowSec) {
+			c.AutoBypassWindowSec = defaultAutoBypassWindowSec
+		}
+		if !validUpstreamVerify(c.UpstreamVerify) {
+			c.UpstreamVerify = defaultUpstreamVerify
+		}
 		cs.cfg = c
 		if normalized {
 			cs.save()
@@ -392,6 +590,14 @@ func (cs *configStore) setSystemProxy(on bool) {
 	cs.save()
 }
 
+// setAutoBypassHosts 仅更新并持久化自动绕过列表(引擎侧列表变化时回写),不触发应用动作。
+func (cs *configStore) setAutoBypassHosts(hosts []string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.cfg.AutoBypassHosts = hosts
+	cs.save()
+}
+
 // update 合并部分字段并持久化。
 //
 // 监听端口(port)允许前端修改并持久化:它是启动期确定的部署设置(默认值 <
@@ -466,6 +672,84 @@ func (cs *configStore) update(patch map[string]any) AppConfig {
 	if v, ok := patch["decryptDeny"]; ok {
 		cs.cfg.DecryptDeny = toStringSlice(v)
 	}
+	if v, ok := patch["autoBypass"].(bool); ok {
+		cs.cfg.AutoBypass = v
+	}
+	if v, ok := patch["autoBypassThreshold"].(float64); ok && validAutoBypassThreshold(int(v)) {
+		cs.cfg.AutoBypassThreshold = int(v)
+	}
+	if v, ok := patch["autoBypassWindowSec"].(float64); ok && validAutoBypassWindowSec(int(v)) {
+		cs.cfg.AutoBypassWindowSec = int(v)
+	}
+	if v, ok := patch["autoBypassPersist"].(bool); ok {
+		cs.cfg.AutoBypassPersist = v
+		if !v {
+			cs.cfg.AutoBypassHosts = nil
+		}
+	}
+	if v, ok := patch["reverseProxy"].(bool); ok {
+		cs.cfg.ReverseProxy = v
+	}
+	if v, ok := patch["reversePort"].(float64); ok && int(v) >= 1 && int(v) <= 65535 {
+		cs.cfg.ReversePort = int(v)
+	}
+	if v, ok := patch["reverseUpstream"].(string); ok {
+		cs.cfg.ReverseUpstream = strings.TrimSpace(v)
+	}
+	if v, ok := patch["reverseTLS"].(bool); ok {
+		cs.cfg.ReverseTLS = v
+	}
+	if v, ok := patch["reversePreserveHost"].(bool); ok {
+		cs.cfg.ReversePreserveHost = v
+	}
+	if v, ok := patch["listeners"]; ok {
+		cs.cfg.Listeners = parseListeners(v, cs.cfg.Listeners)
+	}
+	if v, ok := patch["dnsServer"].(bool); ok {
+		cs.cfg.DNSServer = v
+	}
+	if v, ok := patch["dnsPort"].(float64); ok && int(v) >= 1 && int(v) <= 65535 {
+		cs.cfg.DNSPort = int(v)
+	}
+	if v, ok := patch["dnsUpstream"].(string); ok {
+		cs.cfg.DNSUpstream = strings.TrimSpace(v)
+	}
+	if v, ok := patch["dnsHosts"].(string); ok {
+		cs.cfg.DNSHosts = v
+	}
+	if v, ok := patch["grpcReflection"].(bool); ok {
+		cs.cfg.GRPCReflection = v
+	}
+	if v, ok := patch["tlsMirror"].(bool); ok {
+		cs.cfg.TLSMirror = v
+	}
+	if v, ok := patch["tlsMirrorHosts"]; ok {
+		cs.cfg.TLSMirrorHosts = toStringSlice(v)
+	}
+	if v, ok := patch["upstreamVerify"].(string); ok && validUpstreamVerify(v) {
+		cs.cfg.UpstreamVerify = v
+	}
+	if v, ok := patch["upstreamTrustRoots"].(string); ok {
+		cs.cfg.UpstreamTrustRoots = v
+	}
+	if v, ok := patch["upstreamRoutes"].(string); ok {
+		cs.cfg.UpstreamRoutes = restoreRoutePasswords(v, cs.cfg.UpstreamRoutes)
+	}
+	if v, ok := patch["hostMappings"].(string); ok {
+		cs.cfg.HostMappings = v
+	}
+	if v, ok := patch["pac"].(bool); ok {
+		cs.cfg.PAC = v
+	}
+	if v, ok := patch["pacInclude"]; ok {
+		cs.cfg.PACInclude = toStringSlice(v)
+	}
+	if v, ok := patch["pacExclude"]; ok {
+		cs.cfg.PACExclude = toStringSlice(v)
+	}
+	if v, ok := patch["systemProxyPac"].(bool); ok {
+		cs.cfg.SystemProxyPAC = v
+	}
 	normalizeUpstreamConfig(&cs.cfg)
 	cs.save()
 	return cs.cfg
@@ -475,6 +759,31 @@ func validThrottleKiBps(v int64) bool {
 	return v >= minThrottleKiBps && v <= maxThrottleKiBps
 }
 
+func validAutoBypassThreshold(v int) bool { return v >= 1 && v <= 100 }
+
+func validAutoBypassWindowSec(v int) bool { return v >= 1 && v <= 24*60*60 }
+
+func validUpstreamVerify(v string) bool { return v == "off" || v == "warn" || v == "enforce" }
+
+// parseTrustRoots 解析 PEM 中的全部证书,跳过非证书块与无法解析的证书。
+func parseTrustRoots(data string) []*x509.Certificate {
+	var out []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return out
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if c, err := x509.ParseCertificate(block.Bytes); err == nil {
+			out = append(out, c)
+		}
+	}
+}
+
 func patchInt64(v any) (int64, bool) {
 	switch n := v.(type) {
 	case int:
@@ -0,0 +1,162 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"strings"
+
+	"github.com/mintfog/sniffy/internal/flow"
+)
+
+// ListenerConfig 描述一个附加监听端(主监听端仍是 AppConfig.Port)。各监听端共用同一会话
+// 存储、规则与插件,Flow 以 listener 字段标明来源。
+type ListenerConfig struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Mode 取 "regular"(自动识别 HTTP/HTTPS/SOCKS5)、"socks5"、"reverse"、"transparent"。
+	Mode    string `json:"mode"`
+	Address string `json:"address,omitempty"` // 为空时沿用主监听地址
+	Port    int    `json:"port"`
+	// Transparent 为 transparent 模式下的 "redirect" / "tproxy"(仅 Linux)。
+	Transparent string `json:"transparent,omitempty"`
+	// Upstream / TLS / PreserveHost 为 reverse 模式的上游基础 URL、入站 TLS 终结与保留 Host。
+	Upstream     string `json:"upstream,omitempty"`
+	TLS          bool   `json:"tls,omitempty"`
+	PreserveHost bool   `json:"preserveHost,omitempty"`
+	// ProxyAuth 取 ""(沿用全局)、"off"、"on";"on" 时使用本监听端自己的账号密码。
+	ProxyAuth     string `json:"proxyAuth,omitempty"`
+	ProxyUsername string `json:"proxyUsername,omitempty"`
+	ProxyPassword string `json:"proxyPassword,omitempty"` // 不进对外视图
+	// DecryptScope 取 ""(沿用全局)、"off"(不解密)、"all"、"allow"、"deny"。
+	DecryptScope string   `json:"decryptScope,omitempty"`
+	DecryptAllow []string `json:"decryptAllow,omitempty"`
+	DecryptDeny  []string `json:"decryptDeny,omitempty"`
+}
+
+// ListenerView 是附加监听端的对外视图,密码只以 proxyPasswordSet 暴露。
+type ListenerView struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name,omitempty"`
+	Enabled          bool     `json:"enabled"`
+	Mode             string   `json:"mode"`
+	Address          string   `json:"address,omitempty"`
+	Port             int      `json:"port"`
+	Transparent      string   `json:"transparent,omitempty"`
+	Upstream         string   `json:"upstream,omitempty"`
+	TLS              bool     `json:"tls,omitempty"`
+	PreserveHost     bool     `json:"preserveHost,omitempty"`
+	ProxyAuth        string   `json:"proxyAuth,omitempty"`
+	ProxyUsername    string   `json:"proxyUsername,omitempty"`
+	ProxyPasswordSet bool     `json:"proxyPasswordSet"`
+	DecryptScope     string   `json:"decryptScope,omitempty"`
+	DecryptAllow     []string `json:"decryptAllow,omitempty"`
+	DecryptDeny      []string `json:"decryptDeny,omitempty"`
+}
+
+func publicListeners(ls []ListenerConfig) []ListenerView {
+	if len(ls) == 0 {
+		return nil
+	}
+	out := make([]ListenerView, len(ls))
+	for i, l := range ls {
+		out[i] = ListenerView{
+			ID: l.ID, Name: l.Name, Enabled: l.Enabled, Mode: l.Mode,
+			Address: l.Address, Port: l.Port, Transparent: l.Transparent,
+			Upstream: l.Upstream, TLS: l.TLS, PreserveHost: l.PreserveHost,
+			ProxyAuth: l.ProxyAuth, ProxyUsername: l.ProxyUsername, ProxyPasswordSet: l.ProxyPassword != "",
+			DecryptScope: l.DecryptScope,
+			DecryptAllow: append([]string(nil), l.DecryptAllow...),
+			DecryptDeny:  append([]string(nil), l.DecryptDeny...),
+		}
+	}
+	return out
+}
+
+// parseListeners 把补丁中的 listeners 数组规整为完整列表。对外视图不含密码,前端回灌时
+// 条目也就不带 proxyPassword:按 id 找到原条目,以其为底合并,缺省字段(尤其密码)保持原值。
+// 没有 id 的条目视为新增并分配 id;非对象元素忽略。
+func parseListeners(v any, prev []ListenerConfig) []ListenerConfig {
+	items, ok := v.([]any)
+	if !ok {
+		return prev
+	}
+	byID := make(map[string]ListenerConfig, len(prev))
+	for _, l := range prev {
+		byID[l.ID] = l
+	}
+	out := make([]ListenerConfig, 0, len(items))
+	for _, it := range items {
+		m, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		id, _ := m["id"].(string)
+		l, found := byID[id]
+		if !found {
+			l = ListenerConfig{ID: id, Enabled: true}
+			if id == "" {
+				l.ID = "listener-" + flow.NewID()
+			}
+		}
+		mergeListener(&l, m)
+		out = append(out, l)
+	}
+	return out
+}
+
+// mergeListener 按与 configStore.update 相同的口径合并单个监听端的字段。
+func mergeListener(l *ListenerConfig, m map[string]any) {
+	if v, ok := m["name"].(string); ok {
+		l.Name = v
+	}
+	if v, ok := m["enabled"].(bool); ok {
+		l.Enabled = v
+	}
+	if v, ok := m["mode"].(string); ok {
+		l.Mode = v
+	}
+	if v, ok := m["address"].(string); ok {
+		l.Address = strings.TrimSpace(v)
+	}
+	if v, ok := m["port"].(float64); ok && int(v) >= 1 && int(v) <= 65535 {
+		l.Port = int(v)
+	}
+	if v, ok := m["transparent"].(string); ok {
+		l.Transparent = v
+	}
+	if v, ok := m["upstream"].(string); ok {
+		l.Upstream = strings.TrimSpace(v)
+	}
+	if v, ok := m["tls"].(bool); ok {
+		l.TLS = v
+	}
+	if v, ok := m["preserveHost"].(bool); ok {
+		l.PreserveHost = v
+	}
+	if v, ok := m["proxyAuth"].(string); ok {
+		l.ProxyAuth = v
+	}
+	if v, ok := m["proxyUsername"].(string); ok {
+		l.ProxyUsername = v
+	}
+	if v, ok := m["proxyPassword"].(string); ok {
+		l.ProxyPassword = v
+	}
+	if v, ok := m["decryptScope"].(string); ok {
+		l.DecryptScope = v
+	}
+	if v, ok := m["decryptAllow"]; ok {
+		l.DecryptAllow = toStringSlice(v)
+	}
+	if v, ok := m["decryptDeny"]; ok {
+		l.DecryptDeny = toStringSlice(v)
+	}
+	// 不再使用独立凭据时不留密码,与全局代理认证的规范化一致。
+	if l.ProxyAuth != "on" {
+		l.ProxyPassword = ""
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mintfog/sniffy/internal/protoschema"
+)
+
+// protoSetFileName 是上传的 protobuf 描述符集的持久化文件名。
+const protoSetFileName = "protosets.json"
+
+// maxProtoSetBytes 限制单个描述符集的大小;正常的 FileDescriptorSet 远小于此。
+const maxProtoSetBytes = 16 << 20
+
+// protoSet 是一个上传的 FileDescriptorSet(protoc --descriptor_set_out --include_imports 的产物)。
+type protoSet struct {
+	Name       string    `json:"name"`
+	Data       []byte    `json:"data"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// ProtoSetDTO 是描述符集的摘要:名称与其中定义的 gRPC 服务。
+type ProtoSetDTO struct {
+	Name       string   `json:"name"`
+	Services   []string `json:"services"`
+	Size       int      `json:"size"`
+	ImportedAt string   `json:"importedAt"`
+}
+
+type protoSetStore struct {
+	mu    sync.RWMutex
+	items []protoSet
+	path  string // 持久化文件;为空则仅内存
+}
+
+func newProtoSetStore(path string) *protoSetStore {
+	ps := &protoSetStore{path: path}
+	if path == "" {
+		return ps
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		var items []protoSet
+		if json.Unmarshal(data, &items) == nil {
+			ps.items = items
+		}
+	}
+	return ps
+}
+
+func (ps *protoSetStore) save() error {
+	if ps.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(ps.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ps.path, data, 0o644)
+}
+
+// put 按名称 upsert 一个描述符集并持久化;落盘失败时回滚。
+func (ps *protoSetStore) put(item protoSet) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	prev := ps.items
+	kept := make([]protoSet, 0, len(ps.items)+1)
+	for _, it := range ps.items {
+		if it.Name != item.Name {
+			kept = append(kept, it)
+		}
+	}
+	ps.items = append(kept, item)
+	if err := ps.save(); err != nil {
+		ps.items = prev
+		return fmt.Errorf("保存失败: %w", err)
+	}
+	return nil
+}
+
+func (ps *protoSetStore) delete(name string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for i, it := range ps.items {
+		if it.Name == name {
+			ps.items = append(ps.items[:i:i], ps.items[i+1:]...)
+			_ = ps.save()
+			return
+		}
+	}
+}
+
+// sets 返回 name → 描述符集字节,供下发到引擎。
+func (ps *protoSetStore) sets() map[string][]byte {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	out := make(map[string][]byte, len(ps.items))
+	for _, it := range ps.items {
+		out[it.Name] = it.Data
+	}
+	return out
+}
+
+func (ps *protoSetStore) dtos() []ProtoSetDTO {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	out := make([]ProtoSetDTO, 0, len(ps.items))
+	for _, it := range ps.items {
+		services, _ := protoschema.ParseDescriptorSet(it.Data)
+		if services == nil {
+			services = []string{}
+		}
+		out = append(out, ProtoSetDTO{
+			Name:       it.Name,
+			Services:   services,
+			Size:       len(it.Data),
+			ImportedAt: rfc3339(it.ImportedAt),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ---- protobuf 描述符集(解码 gRPC 消息) ----
+
+// SetProtoSetsApplier 注入「下发描述符集到引擎」的回调(装配层在 New 之后调用),
+// 注入后立即以当前持久化的描述符集应用一次。
+func (s *Service) SetProtoSetsApplier(fn func(map[string][]byte) error) {
+	s.applyProtoSetsFn = fn
+	s.applyProtoSets()
+}
+
+func (s *Service) applyProtoSets() {
+	if s.applyProtoSetsFn != nil {
+		_ = s.applyProtoSetsFn(s.protoSets.sets())
+	}
+}
+
+// ProtoSets 返回已上传的描述符集摘要。
+func (s *Service) ProtoSets() []ProtoSetDTO { return s.protoSets.dtos() }
+
+// ImportProtoSet 校验并保存一个 FileDescriptorSet,同名覆盖,随后热下发到引擎。
+// 内容无效、依赖缺失或不含任何服务时返回 invalid input 错误。
+func (s *Service) ImportProtoSet(name string, data []byte) (ProtoSetDTO, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ProtoSetDTO{}, invalidInput("描述符集名称不能为空")
+	}
+	if len(data) > maxProtoSetBytes {
+		return ProtoSetDTO{}, invalidInput("描述符集过大")
+	}
+	services, err := protoschema.ParseDescriptorSet(data)
+	if err != nil {
+		return ProtoSetDTO{}, invalidInput(err.Error())
+	}
+	item := protoSet{Name: name, Data: append([]byte(nil), data...), ImportedAt: time.Now()}
+	if err := s.protoSets.put(item); err != nil {
+		return ProtoSetDTO{}, err
+	}
+	s.applyProtoSets()
+	return ProtoSetDTO{Name: name, Services: services, Size: len(data), ImportedAt: rfc3339(item.ImportedAt)}, nil
+}
+
+// DeleteProtoSet 按名称删除描述符集并热下发。
+func (s *Service) DeleteProtoSet(name string) {
+	s.protoSets.delete(strings.TrimSpace(name))
+	s.applyProtoSets()
+}
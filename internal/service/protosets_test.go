@@ -0,0 +1,114 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// echoDescriptorSet 造一个只含 echo.Echo/Say(Msg) returns (Msg) 的 FileDescriptorSet。
+func echoDescriptorSet(t *testing.T) []byte {
+	t.Helper()
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("echo.proto"),
+		Package: proto.String("echo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("text"),
+				JsonName: proto.String("text"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Say"),
+				InputType:  proto.String(".echo.Msg"),
+				OutputType: proto.String(".echo.Msg"),
+			}},
+		}},
+	}
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fd}})
+	if err != nil {
+		t.Fatalf("编码描述符集失败: %v", err)
+	}
+	return b
+}
+
+func TestImportProtoSetAppliesAndUpserts(t *testing.T) {
+	svc := newTestService(t)
+	var applied []map[string][]byte
+	svc.SetProtoSetsApplier(func(sets map[string][]byte) error {
+		applied = append(applied, sets)
+		return nil
+	})
+	if len(applied) != 1 || len(applied[0]) != 0 {
+		t.Fatalf("注入时应以空集合应用一次,得到 %v", applied)
+	}
+
+	dto, err := svc.ImportProtoSet("  echo ", echoDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("导入有效描述符集应成功: %v", err)
+	}
+	if dto.Name != "echo" || len(dto.Services) != 1 || dto.Services[0] != "echo.Echo" {
+		t.Fatalf("摘要不符: %+v", dto)
+	}
+	if len(applied) != 2 || applied[1]["echo"] == nil {
+		t.Fatal("导入后应热下发到引擎")
+	}
+
+	// 同名覆盖,不新增条目。
+	if _, err := svc.ImportProtoSet("echo", echoDescriptorSet(t)); err != nil {
+		t.Fatal(err)
+	}
+	if list := svc.ProtoSets(); len(list) != 1 {
+		t.Fatalf("同名应覆盖,得到 %d 条", len(list))
+	}
+
+	svc.DeleteProtoSet("echo")
+	if len(svc.ProtoSets()) != 0 || len(applied[len(applied)-1]) != 0 {
+		t.Fatal("删除后列表与下发集合都应为空")
+	}
+}
+
+func TestImportProtoSetRejectsInvalid(t *testing.T) {
+	svc := newTestService(t)
+	cases := map[string][]byte{
+		"":        echoDescriptorSet(t),
+		"garbage": []byte("\xff\xff not protobuf"),
+		"empty":   nil,
+	}
+	for name, data := range cases {
+		var invalid interface{ InvalidInput() bool }
+		if _, err := svc.ImportProtoSet(name, data); !errors.As(err, &invalid) {
+			t.Errorf("ImportProtoSet(%q) err = %v, want 输入错误", name, err)
+		}
+	}
+	if len(svc.ProtoSets()) != 0 {
+		t.Fatal("无效输入不应落入列表")
+	}
+}
+
+func TestProtoSetStorePersists(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), protoSetFileName)
+	ps := newProtoSetStore(path)
+	if err := ps.put(protoSet{Name: "echo", Data: echoDescriptorSet(t)}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := newProtoSetStore(path)
+	dtos := reloaded.dtos()
+	if len(dtos) != 1 || dtos[0].Name != "echo" || len(dtos[0].Services) != 1 {
+		t.Fatalf("重新加载后应恢复描述符集,得到 %+v", dtos)
+	}
+}
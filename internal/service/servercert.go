@@ -78,11 +78,16 @@ func (cs *serverCertStore) save() error {
 	if err != nil {
 		return err
 	}
-	tmp := cs.path + ".tmp"
+	return writeFileAtomic(cs.path, data)
+}
+
+// writeFileAtomic 以 0600 原子写入 path:先写同目录 temp 文件,再 rename 覆盖。
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
 	if err := os.WriteFile(tmp, data, 0o600); err != nil {
 		return err
 	}
-	if err := os.Rename(tmp, cs.path); err != nil {
+	if err := os.Rename(tmp, path); err != nil {
 		_ = os.Remove(tmp)
 		return err
 	}
@@ -7,6 +7,9 @@ package service
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -17,6 +20,7 @@ import (
 	"github.com/mintfog/sniffy/internal/bodycache"
 	"github.com/mintfog/sniffy/internal/core"
 	"github.com/mintfog/sniffy/internal/flow"
+	"github.com/mintfog/sniffy/internal/pac"
 )
 
 // Service 是整个应用的唯一真相源。两种 transport(headless / 桌面)都只调用它。
@@ -24,11 +28,16 @@ type Service struct {
 	sessions    *sessionStore
 	ws          *wsStore
 	stream      *streamStore
+	tcp         *tcpStore
+	mqtt        *mqttStore
+	dns         *dnsStore
 	stats       *statsCollector
 	rules       *ruleStore
 	cfg         *configStore
 	cert        *certStore
 	serverCerts *serverCertStore
+	clientCerts *clientCertStore
+	protoSets   *protoSetStore
 	bus         *core.EventBus
 	recording   atomic.Bool
 	startTime   time.Time
@@ -44,6 +53,8 @@ type Service struct {
 	applyDecryptScope func(enabled bool, mode string, allow, deny []string) error
 	// applyServerCertsFn 由装配层注入,把导入的服务端证书下发给引擎。为 nil 时静默跳过。
 	applyServerCertsFn func([]*tls.Certificate) error
+	// applyClientCertsFn 由装配层注入,把出示给上游的客户端证书下发给引擎。为 nil 时静默跳过。
+	applyClientCertsFn func([]ClientCertBinding) error
 	// applySystemProxy 由桌面装配层注入,把系统代理指向监听端口(true)或释放(false)。
 	// 仅桌面端注入;headless 与浏览器预览下为 nil,静默跳过。
 	applySystemProxy func(enabled bool) error
@@ -51,6 +62,33 @@ type Service struct {
 	applyThrottle func(enabled bool, kibPerSecond int64) error
 	// applyPassthrough 由装配层注入,把大体积响应透传旁路的开关与阈值下发给 HTTP 处理器。
 	applyPassthrough func(enabled bool, thresholdBytes int64) error
+	// applyReverseProxy 由装配层注入,把反向代理监听端的设置下发给引擎。
+	applyReverseProxy func(enabled bool, port int, upstream string, terminateTLS, preserveHost bool) error
+	// applyListeners 由装配层注入,把附加监听端列表下发给引擎。
+	applyListeners func([]ListenerConfig) error
+	// applyDNS 由装配层注入,把内置 DNS 服务端的设置下发给引擎。
+	applyDNS func(enabled bool, port int, upstream, hosts string) error
+	// applyGRPCReflection 由装配层注入,把 gRPC 服务端反射开关下发给引擎。
+	applyGRPCReflection func(enabled bool) error
+	// applyTLSMirror 由装配层注入,把上游 TLS 镜像开关与主机范围下发给引擎。
+	applyTLSMirror func(enabled bool, hosts []string) error
+	// applyAutoBypass 由装配层注入,把握手失败自动绕过的开关、阈值与窗口下发给引擎。
+	applyAutoBypass func(enabled bool, threshold int, window time.Duration) error
+	// applyUpstreamVerify 由装配层注入,把上游证书校验模式与追加信任的根证书下发给引擎。
+	applyUpstreamVerify func(mode string, roots []*x509.Certificate) error
+	// applyUpstreamRoutes 由装配层注入,把上游路由表下发给引擎。
+	applyUpstreamRoutes func(table string) error
+	// applyHostMappings 由装配层注入,把主机映射表下发给引擎。
+	applyHostMappings func(table string) error
+	// applyPAC 由装配层注入,把 PAC 脚本开关与主机范围下发给引擎。
+	applyPAC func(enabled bool, include, exclude []string) error
+	// listAutoBypass / resetAutoBypass 由装配层注入,读取与重置引擎的自动绕过列表。为 nil 时列表为空。
+	listAutoBypass  func() []AutoBypassHostDTO
+	resetAutoBypass func(host string)
+	// applyProtoSetsFn 由装配层注入,把上传的 protobuf 描述符集下发给引擎。为 nil 时静默跳过。
+	applyProtoSetsFn func(map[string][]byte) error
+	// injectWS 由装配层注入,向打开中的 WebSocket 会话注入一帧。为 nil 时注入不可用。
+	injectWS func(sessionID, direction, frameType string, data []byte) error
 }
 
 // SetBodyCache 把会话淘汰接到响应体落盘副本的回收上(装配层调用):会话离开存储后
@@ -71,13 +109,15 @@ func (s *Service) SetBodyCache(c *bodycache.Cache) {
 
 // New 构造 Service。configDir 保存配置与规则，certDir 保存含私钥的证书数据；为空则仅内存。
 func New(c ca.CA, bus *core.EventBus, configDir, certDir string) *Service {
-	var rulesPath, configPath, serverCertPath string
+	var rulesPath, configPath, serverCertPath, clientCertPath, protoSetPath string
 	if configDir != "" {
 		rulesPath = filepath.Join(configDir, "rules.json")
 		configPath = filepath.Join(configDir, configFileName)
+		protoSetPath = filepath.Join(configDir, protoSetFileName)
 	}
 	if certDir != "" {
 		serverCertPath = filepath.Join(certDir, serverCertFileName)
+		clientCertPath = filepath.Join(certDir, clientCertFileName)
 	}
 	cfgStore := newConfigStore(configPath, defaultAppConfig())
 	cfg := cfgStore.get()
@@ -85,11 +125,16 @@ func New(c ca.CA, bus *core.EventBus, configDir, certDir string) *Service {
 		sessions:    newSessionStore(cfg.MaxFlows),
 		ws:          newWSStore(0),
 		stream:      newStreamStore(0),
+		tcp:         newTCPStore(0),
+		mqtt:        newMQTTStore(0),
+		dns:         newDNSStore(0),
 		stats:       newStatsCollector(),
 		rules:       newRuleStore(rulesPath),
 		cfg:         cfgStore,
 		cert:        newCertStore(c),
 		serverCerts: newServerCertStore(serverCertPath),
+		clientCerts: newClientCertStore(clientCertPath),
+		protoSets:   newProtoSetStore(protoSetPath),
 		bus:         bus,
 		startTime:   time.Now(),
 	}
@@ -330,6 +375,23 @@ func (s *Service) ClearSessions() { s.sessions.clear() }
 
 // ---- WebSocket 会话 ----
 
+var (
+	// ErrWSSessionNotFound 表示 WebSocket 会话不存在或已关闭。
+	ErrWSSessionNotFound = errors.New("WebSocket 会话不存在或已关闭")
+	// ErrWSMessageNotFound 表示会话中没有该消息(可能已被淘汰出最近消息窗口)。
+	ErrWSMessageNotFound = errors.New("WebSocket 消息不存在")
+
+	errWSInjectUnavailable = errors.New("WebSocket 注入不可用")
+)
+
+// invalidInputError 标记调用方输入问题,API 层据 InvalidInput 映射为 HTTP 400。
+type invalidInputError struct{ msg string }
+
+func (e *invalidInputError) Error() string      { return e.msg }
+func (e *invalidInputError) InvalidInput() bool { return true }
+
+func invalidInput(msg string) error { return &invalidInputError{msg: msg} }
+
 // RecordWSSession 存储/更新一条 WebSocket 会话并广播。
 func (s *Service) RecordWSSession(ws *flow.WSSession) {
 	if !s.recording.Load() {
@@ -358,6 +420,47 @@ func (s *Service) WSSession(id string) (WSSessionDTOType, bool) {
 	return WSSessionDTO(ws), true
 }
 
+// SendWSFrame 向一条打开中的 WebSocket 会话注入一帧。
+func (s *Service) SendWSFrame(sessionID string, in WSFrameInput) error {
+	direction, err := wsDirectionFromFrontend(in.Direction)
+	if err != nil {
+		return err
+	}
+	data := []byte(in.Data)
+	if in.Binary {
+		if data, err = base64.StdEncoding.DecodeString(in.Data); err != nil {
+			return invalidInput("data 不是合法的 base64")
+		}
+	}
+	return s.injectWSFrame(sessionID, direction, in.Type, data)
+}
+
+// ResendWSMessage 把会话中一条已捕获的消息按原方向、原类型再发一次。
+func (s *Service) ResendWSMessage(sessionID, messageID string) error {
+	ws, ok := s.ws.get(sessionID)
+	if !ok {
+		return ErrWSSessionNotFound
+	}
+	for _, m := range ws.Messages {
+		if m.ID == messageID {
+			return s.injectWSFrame(sessionID, m.Direction, m.Type, m.Data)
+		}
+	}
+	return ErrWSMessageNotFound
+}
+
+// injectWSFrame 确认会话仍在打开后交给注入回调。会话须已记入存储:UI 与 API 只能
+// 通过已记录的会话 ID 定位目标。
+func (s *Service) injectWSFrame(sessionID, direction, frameType string, data []byte) error {
+	if ws, ok := s.ws.get(sessionID); !ok || ws.Status != "open" {
+		return ErrWSSessionNotFound
+	}
+	if s.injectWS == nil {
+		return errWSInjectUnavailable
+	}
+	return s.injectWS(sessionID, direction, frameType, data)
+}
+
 // ---- 流式会话(SSE / gRPC / 分块流) ----
 
 // RecordStreamSession 存储/更新一条流会话并广播。
@@ -388,6 +491,96 @@ func (s *Service) StreamSession(id string) (StreamSessionDTOType, bool) {
 	return StreamSessionDTO(ss), true
 }
 
+// ---- TCP 会话(原始字节中继) ----
+
+// RecordTCPSession 存储/更新一条 TCP 会话并广播。
+func (s *Service) RecordTCPSession(ts *flow.TCPSession) {
+	if !s.recording.Load() {
+		return
+	}
+	s.tcp.put(ts)
+	s.emit(core.EventTCPSession, TCPSessionDTO(ts))
+}
+
+// TCPSessions 返回分页 TCP 会话。
+func (s *Service) TCPSessions(page, pageSize int) ([]TCPSessionDTOType, int) {
+	list, total := s.tcp.list(page, pageSize)
+	out := make([]TCPSessionDTOType, 0, len(list))
+	for _, ts := range list {
+		out = append(out, TCPSessionDTO(ts))
+	}
+	return out, total
+}
+
+// TCPSession 返回单个 TCP 会话。
+func (s *Service) TCPSession(id string) (TCPSessionDTOType, bool) {
+	ts, ok := s.tcp.get(id)
+	if !ok {
+		return TCPSessionDTOType{}, false
+	}
+	return TCPSessionDTO(ts), true
+}
+
+// ---- MQTT 会话(逐报文中继) ----
+
+// RecordMQTTSession 存储/更新一条 MQTT 会话并广播。
+func (s *Service) RecordMQTTSession(ms *flow.MQTTSession) {
+	if !s.recording.Load() {
+		return
+	}
+	s.mqtt.put(ms)
+	s.emit(core.EventMQTTSession, MQTTSessionDTO(ms))
+}
+
+// MQTTSessions 返回分页 MQTT 会话。
+func (s *Service) MQTTSessions(page, pageSize int) ([]MQTTSessionDTOType, int) {
+	list, total := s.mqtt.list(page, pageSize)
+	out := make([]MQTTSessionDTOType, 0, len(list))
+	for _, ms := range list {
+		out = append(out, MQTTSessionDTO(ms))
+	}
+	return out, total
+}
+
+// MQTTSession 返回单个 MQTT 会话。
+func (s *Service) MQTTSession(id string) (MQTTSessionDTOType, bool) {
+	ms, ok := s.mqtt.get(id)
+	if !ok {
+		return MQTTSessionDTOType{}, false
+	}
+	return MQTTSessionDTO(ms), true
+}
+
+// ---- DNS 查询(内置 DNS 服务端) ----
+
+// RecordDNSQuery 存储一条 DNS 查询记录并广播。
+func (s *Service) RecordDNSQuery(q *flow.DNSQuery) {
+	if !s.recording.Load() {
+		return
+	}
+	s.dns.put(q)
+	s.emit(core.EventDNSQuery, DNSQueryDTO(q))
+}
+
+// DNSQueries 返回分页 DNS 查询记录。
+func (s *Service) DNSQueries(page, pageSize int) ([]DNSQueryDTOType, int) {
+	list, total := s.dns.list(page, pageSize)
+	out := make([]DNSQueryDTOType, 0, len(list))
+	for _, q := range list {
+		out = append(out, DNSQueryDTO(q))
+	}
+	return out, total
+}
+
+// DNSQuery 返回单条 DNS 查询记录。
+func (s *Service) DNSQuery(id string) (DNSQueryDTOType, bool) {
+	q, ok := s.dns.get(id)
+	if !ok {
+		return DNSQueryDTOType{}, false
+	}
+	return DNSQueryDTO(q), true
+}
+
 // ---- 统计 ----
 
 // Statistics 返回统计快照。
@@ -456,6 +649,132 @@ func (s *Service) SetPassthroughApplier(fn func(enabled bool, thresholdBytes int
 	_ = fn(c.LargeBodyPassthrough, c.LargeBodyKiB*1024)
 }
 
+// SetReverseProxyApplier 注入「开关 / 重建反向代理监听端」的回调(装配层调用),
+// 并立即以持久化的当前配置应用一次。
+func (s *Service) SetReverseProxyApplier(fn func(enabled bool, port int, upstream string, terminateTLS, preserveHost bool) error) {
+	s.applyReverseProxy = fn
+	c := s.cfg.get()
+	_ = fn(c.ReverseProxy, c.ReversePort, c.ReverseUpstream, c.ReverseTLS, c.ReversePreserveHost)
+}
+
+// SetListenersApplier 注入「下发附加监听端列表」的回调(装配层调用),并立即以持久化的
+// 当前配置应用一次。
+func (s *Service) SetListenersApplier(fn func([]ListenerConfig) error) {
+	s.applyListeners = fn
+	_ = fn(s.cfg.get().Listeners)
+}
+
+// SetDNSApplier 注入「开关 / 重建内置 DNS 服务端」的回调(装配层调用),并立即以持久化的
+// 当前配置应用一次。
+func (s *Service) SetDNSApplier(fn func(enabled bool, port int, upstream, hosts string) error) {
+	s.applyDNS = fn
+	c := s.cfg.get()
+	_ = fn(c.DNSServer, c.DNSPort, c.DNSUpstream, c.DNSHosts)
+}
+
+// SetGRPCReflectionApplier 注入「开关 gRPC 服务端反射」的回调(装配层调用),并立即以持久化的
+// 当前配置应用一次。
+func (s *Service) SetGRPCReflectionApplier(fn func(enabled bool) error) {
+	s.applyGRPCReflection = fn
+	_ = fn(s.cfg.get().GRPCReflection)
+}
+
+// SetTLSMirrorApplier 注入「开关上游 TLS 参数镜像」的回调(装配层调用),并立即以持久化的
+// 当前配置应用一次。
+func (s *Service) SetTLSMirrorApplier(fn func(enabled bool, hosts []string) error) {
+	s.applyTLSMirror = fn
+	c := s.cfg.get()
+	_ = fn(c.TLSMirror, c.TLSMirrorHosts)
+}
+
+// SetUpstreamVerifyApplier 注入「上游证书校验」的回调(装配层调用),并立即以持久化的当前配置
+// 应用一次。
+func (s *Service) SetUpstreamVerifyApplier(fn func(mode string, roots []*x509.Certificate) error) {
+	s.applyUpstreamVerify = fn
+	c := s.cfg.get()
+	_ = fn(c.UpstreamVerify, parseTrustRoots(c.UpstreamTrustRoots))
+}
+
+// SetUpstreamRoutesApplier 注入「下发上游路由表」的回调(装配层调用),并立即以持久化的当前配置
+// 应用一次。
+func (s *Service) SetUpstreamRoutesApplier(fn func(table string) error) {
+	s.applyUpstreamRoutes = fn
+	_ = fn(s.cfg.get().UpstreamRoutes)
+}
+
+// SetHostMappingsApplier 注入「下发主机映射表」的回调(装配层调用),并立即以持久化的当前配置
+// 应用一次。
+func (s *Service) SetHostMappingsApplier(fn func(table string) error) {
+	s.applyHostMappings = fn
+	_ = fn(s.cfg.get().HostMappings)
+}
+
+// SetPACApplier 注入「在代理端口提供 PAC 脚本」的回调(装配层调用),并立即以持久化的当前配置
+// 应用一次。
+func (s *Service) SetPACApplier(fn func(enabled bool, include, exclude []string) error) {
+	s.applyPAC = fn
+	c := s.cfg.get()
+	_ = fn(c.PAC, c.PACInclude, c.PACExclude)
+}
+
+// PACScript 生成代理地址为 proxy(host:port)的 PAC 脚本;未开启 PAC 时返回 false。
+func (s *Service) PACScript(proxy string) ([]byte, bool) {
+	c := s.cfg.get()
+	if !c.PAC {
+		return nil, false
+	}
+	return pac.Script(proxy, c.PACInclude, c.PACExclude), true
+}
+
+// SetAutoBypassApplier 注入「握手失败自动绕过」的回调(装配层调用),并立即以持久化的当前配置
+// 应用一次。
+func (s *Service) SetAutoBypassApplier(fn func(enabled bool, threshold int, window time.Duration) error) {
+	s.applyAutoBypass = fn
+	s.applyAutoBypassConfig(s.cfg.get())
+}
+
+func (s *Service) applyAutoBypassConfig(c AppConfig) {
+	if s.applyAutoBypass != nil {
+		_ = s.applyAutoBypass(c.AutoBypass, c.AutoBypassThreshold, time.Duration(c.AutoBypassWindowSec)*time.Second)
+	}
+}
+
+// SetAutoBypassControl 注入自动绕过列表的读取 / 重置 / 恢复回调(装配层调用);开启持久化时
+// 立即以保存的列表恢复。
+func (s *Service) SetAutoBypassControl(list func() []AutoBypassHostDTO, reset func(host string), restore func(hosts []string)) {
+	s.listAutoBypass, s.resetAutoBypass = list, reset
+	if c := s.cfg.get(); c.AutoBypassPersist && restore != nil {
+		restore(c.AutoBypassHosts)
+	}
+}
+
+// AutoBypassHosts 返回当前因握手反复失败而直通的主机。
+func (s *Service) AutoBypassHosts() []AutoBypassHostDTO {
+	if s.listAutoBypass == nil {
+		return []AutoBypassHostDTO{}
+	}
+	return s.listAutoBypass()
+}
+
+// ResetAutoBypass 把主机移出自动绕过列表、恢复解密;host 为空时清空全部。
+func (s *Service) ResetAutoBypass(host string) {
+	if s.resetAutoBypass != nil {
+		s.resetAutoBypass(host)
+	}
+}
+
+// RecordAutoBypassHosts 是引擎侧自动绕过列表变化的回调:开启持久化时把列表写入配置。
+func (s *Service) RecordAutoBypassHosts(hosts []string) {
+	if s.cfg.get().AutoBypassPersist {
+		s.cfg.setAutoBypassHosts(hosts)
+	}
+}
+
+// SetWSInjector 注入「向打开中的 WebSocket 会话注入一帧」的回调(装配层调用)。
+func (s *Service) SetWSInjector(fn func(sessionID, direction, frameType string, data []byte) error) {
+	s.injectWS = fn
+}
+
 // UpdateConfig 合并配置补丁、持久化,并把受影响的项下发到运行时。整个过程由 applyMu
 // 串行;配置更新是用户手动触发的低频操作,串行化的代价可以忽略。
 func (s *Service) UpdateConfig(patch map[string]any) AppConfig {
@@ -500,9 +819,92 @@ func (s *Service) UpdateConfig(patch map[string]any) AppConfig {
 	if (passthroughChanged || passthroughSizeChanged) && s.applyPassthrough != nil {
 		_ = s.applyPassthrough(c.LargeBodyPassthrough, c.LargeBodyKiB*1024)
 	}
+	// 附加监听端:引擎按条目比对,只重建绑定参数变化的那些。
+	if _, ok := patch["listeners"]; ok && s.applyListeners != nil {
+		_ = s.applyListeners(c.Listeners)
+	}
+	// 反向代理设置变化时重建监听端;无关配置变更不打断其上的连接。
+	if s.applyReverseProxy != nil && reverseProxyPatched(patch) {
+		_ = s.applyReverseProxy(c.ReverseProxy, c.ReversePort, c.ReverseUpstream, c.ReverseTLS, c.ReversePreserveHost)
+	}
+	if s.applyDNS != nil && dnsPatched(patch) {
+		_ = s.applyDNS(c.DNSServer, c.DNSPort, c.DNSUpstream, c.DNSHosts)
+	}
+	if _, ok := patch["grpcReflection"].(bool); ok && s.applyGRPCReflection != nil {
+		_ = s.applyGRPCReflection(c.GRPCReflection)
+	}
+	_, mirrorChanged := patch["tlsMirror"].(bool)
+	_, mirrorHostsChanged := patch["tlsMirrorHosts"]
+	if (mirrorChanged || mirrorHostsChanged) && s.applyTLSMirror != nil {
+		_ = s.applyTLSMirror(c.TLSMirror, c.TLSMirrorHosts)
+	}
+	if autoBypassPatched(patch) {
+		s.applyAutoBypassConfig(c)
+	}
+	if _, ok := patch["upstreamRoutes"].(string); ok && s.applyUpstreamRoutes != nil {
+		_ = s.applyUpstreamRoutes(c.UpstreamRoutes)
+	}
+	if _, ok := patch["hostMappings"].(string); ok && s.applyHostMappings != nil {
+		_ = s.applyHostMappings(c.HostMappings)
+	}
+	_, pacChanged := patch["pac"].(bool)
+	_, pacIncludeChanged := patch["pacInclude"]
+	_, pacExcludeChanged := patch["pacExclude"]
+	if (pacChanged || pacIncludeChanged || pacExcludeChanged) && s.applyPAC != nil {
+		_ = s.applyPAC(c.PAC, c.PACInclude, c.PACExclude)
+	}
+	// 系统代理已接管时,PAC 模式切换(PAC 本身开关或「系统代理用 PAC」)需要重设系统代理。
+	_, sysPACChanged := patch["systemProxyPac"].(bool)
+	if (pacChanged || sysPACChanged) && c.SystemProxy && prevSystemProxy && s.applySystemProxy != nil {
+		_ = s.applySystemProxy(true)
+	}
+	_, verifyChanged := patch["upstreamVerify"].(string)
+	_, rootsChanged := patch["upstreamTrustRoots"].(string)
+	if (verifyChanged || rootsChanged) && s.applyUpstreamVerify != nil {
+		_ = s.applyUpstreamVerify(c.UpstreamVerify, parseTrustRoots(c.UpstreamTrustRoots))
+	}
+	// 开启持久化时立即保存当前列表;关闭时 configStore 已清掉保存的列表。
+	if v, ok := patch["autoBypassPersist"].(bool); ok && v {
+		hosts := []string{}
+		for _, h := range s.AutoBypassHosts() {
+			hosts = append(hosts, h.Host)
+		}
+		s.cfg.setAutoBypassHosts(hosts)
+		c.AutoBypassHosts = hosts
+	}
 	return c
 }
 
+// dnsPatched 报告补丁是否涉及内置 DNS 服务端设置。
+func dnsPatched(patch map[string]any) bool {
+	for _, k := range []string{"dnsServer", "dnsPort", "dnsUpstream", "dnsHosts"} {
+		if _, ok := patch[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// autoBypassPatched 报告补丁是否涉及握手失败自动绕过的开关、阈值或窗口。
+func autoBypassPatched(patch map[string]any) bool {
+	for _, k := range []string{"autoBypass", "autoBypassThreshold", "autoBypassWindowSec"} {
+		if _, ok := patch[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseProxyPatched 报告补丁是否涉及反向代理设置。
+func reverseProxyPatched(patch map[string]any) bool {
+	for _, k := range []string{"reverseProxy", "reversePort", "reverseUpstream", "reverseTLS", "reversePreserveHost"} {
+		if _, ok := patch[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // ---- 证书 ----
 
 // CertificatePEM 返回根 CA 证书 PEM。
@@ -555,6 +957,51 @@ func (s *Service) DeleteServerCert(id string) {
 	s.applyServerCerts()
 }
 
+// ---- 上游客户端证书(mTLS) ----
+
+// SetClientCertsApplier 注入「下发上游客户端证书到引擎」的回调(装配层调用),并立即以持久化的
+// 证书应用一次。
+func (s *Service) SetClientCertsApplier(fn func([]ClientCertBinding) error) {
+	s.applyClientCertsFn = fn
+	s.applyClientCerts()
+}
+
+func (s *Service) applyClientCerts() {
+	if s.applyClientCertsFn != nil {
+		_ = s.applyClientCertsFn(s.clientCerts.bindings())
+	}
+}
+
+// ClientCerts 返回上游客户端证书摘要(不含私钥)。
+func (s *Service) ClientCerts() []ClientCertDTO { return s.clientCerts.dtos() }
+
+// ImportClientCert 校验并保存一张 PEM 客户端证书(可带中间证书)+ 私钥,对 hosts 匹配的上游生效,
+// 随后热下发。同一张证书再次导入即更新其主机范围。
+func (s *Service) ImportClientCert(certPEM, keyPEM string, hosts []string) (ClientCertDTO, error) {
+	dto, err := s.clientCerts.importPEM(certPEM, keyPEM, hosts)
+	if err != nil {
+		return ClientCertDTO{}, err
+	}
+	s.applyClientCerts()
+	return dto, nil
+}
+
+// ImportClientCertPKCS12 同 ImportClientCert,证书与私钥取自 PKCS#12(.p12/.pfx)。
+func (s *Service) ImportClientCertPKCS12(data []byte, password string, hosts []string) (ClientCertDTO, error) {
+	dto, err := s.clientCerts.importPKCS12(data, password, hosts)
+	if err != nil {
+		return ClientCertDTO{}, err
+	}
+	s.applyClientCerts()
+	return dto, nil
+}
+
+// DeleteClientCert 按证书指纹删除客户端证书并热下发。
+func (s *Service) DeleteClientCert(id string) {
+	s.clientCerts.delete(id)
+	s.applyClientCerts()
+}
+
 // ---- 重发(外部产生的 flow,不受 recording 开关限制) ----
 
 // ImportFlowStarted 广播一条进行中的外部 flow(如重发)并存入会话。
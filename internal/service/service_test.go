@@ -7,6 +7,7 @@ package service
 
 import (
 	"encoding/base64"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -85,6 +86,21 @@ func TestRecordingGateDropsCapture(t *testing.T) {
 			record: func(s *Service) { s.RecordStreamSession(&flow.StreamSession{ID: "st1", Kind: flow.StreamSSE}) },
 			count:  func(s *Service) int { _, n := s.StreamSessions(1, 10); return n },
 		},
+		{
+			name:   "TCP 会话",
+			record: func(s *Service) { s.RecordTCPSession(&flow.TCPSession{ID: "tcp1", Target: "10.0.0.1:22"}) },
+			count:  func(s *Service) int { _, n := s.TCPSessions(1, 10); return n },
+		},
+		{
+			name:   "MQTT 会话",
+			record: func(s *Service) { s.RecordMQTTSession(&flow.MQTTSession{ID: "mqtt1", Broker: "10.0.0.1:1883"}) },
+			count:  func(s *Service) int { _, n := s.MQTTSessions(1, 10); return n },
+		},
+		{
+			name:   "DNS 查询",
+			record: func(s *Service) { s.RecordDNSQuery(&flow.DNSQuery{ID: "dns1", Name: "example.com", Type: "A"}) },
+			count:  func(s *Service) int { _, n := s.DNSQueries(1, 10); return n },
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -493,6 +509,155 @@ func TestWSAndStreamSessionLookup(t *testing.T) {
 	}
 }
 
+func TestSendAndResendWSFrame(t *testing.T) {
+	t.Parallel()
+	svc := newTestService(t)
+	type injected struct {
+		id, direction, frameType string
+		data                     []byte
+	}
+	var got []injected
+	svc.SetWSInjector(func(id, direction, frameType string, data []byte) error {
+		got = append(got, injected{id, direction, frameType, data})
+		return nil
+	})
+	svc.RecordWSSession(&flow.WSSession{
+		ID: "ws-open", Status: "open", StartTime: time.Now(),
+		Messages: []flow.WSMessage{{ID: "m1", Direction: flow.WSServerToClient, Type: flow.WSBinary, Data: []byte{0xff, 0x00}}},
+	})
+	svc.RecordWSSession(&flow.WSSession{ID: "ws-closed", Status: "closed", StartTime: time.Now()})
+
+	if err := svc.SendWSFrame("ws-open", WSFrameInput{Direction: "outbound", Type: flow.WSText, Data: "hi"}); err != nil {
+		t.Fatalf("SendWSFrame(text): %v", err)
+	}
+	bin := WSFrameInput{Direction: "inbound", Type: flow.WSBinary, Data: base64.StdEncoding.EncodeToString([]byte{1, 2}), Binary: true}
+	if err := svc.SendWSFrame("ws-open", bin); err != nil {
+		t.Fatalf("SendWSFrame(binary): %v", err)
+	}
+	if err := svc.ResendWSMessage("ws-open", "m1"); err != nil {
+		t.Fatalf("ResendWSMessage: %v", err)
+	}
+	want := []injected{
+		{"ws-open", flow.WSClientToServer, flow.WSText, []byte("hi")},
+		{"ws-open", flow.WSServerToClient, flow.WSBinary, []byte{1, 2}},
+		{"ws-open", flow.WSServerToClient, flow.WSBinary, []byte{0xff, 0x00}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("注入次数 = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].id != want[i].id || got[i].direction != want[i].direction ||
+			got[i].frameType != want[i].frameType || string(got[i].data) != string(want[i].data) {
+			t.Errorf("第 %d 次注入 = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if err := svc.SendWSFrame("ws-closed", WSFrameInput{Direction: "outbound", Type: flow.WSText}); !errors.Is(err, ErrWSSessionNotFound) {
+		t.Errorf("已关闭会话 err = %v", err)
+	}
+	if err := svc.ResendWSMessage("ws-open", "nope"); !errors.Is(err, ErrWSMessageNotFound) {
+		t.Errorf("未知消息 err = %v", err)
+	}
+	for _, in := range []WSFrameInput{
+		{Direction: "sideways", Type: flow.WSText},
+		{Direction: "outbound", Type: flow.WSBinary, Data: "not base64!", Binary: true},
+	} {
+		var invalid interface{ InvalidInput() bool }
+		if err := svc.SendWSFrame("ws-open", in); !errors.As(err, &invalid) {
+			t.Errorf("SendWSFrame(%+v) err = %v, want 输入错误", in, err)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("失败的请求不应到达注入回调")
+	}
+}
+
+func TestTCPSessionLookup(t *testing.T) {
+	t.Parallel()
+	svc := newTestService(t)
+	end := time.Now()
+	svc.RecordTCPSession(&flow.TCPSession{
+		ID: "tcp-1", ClientAddr: "127.0.0.1:5000", Target: "10.0.0.1:22", Status: "closed",
+		StartTime: time.Now(), EndTime: &end, BytesSent: 3, BytesRecv: 2, ChunkCount: 2,
+		Chunks: []flow.TCPChunk{
+			{ID: "c0", Direction: flow.WSClientToServer, Data: []byte("abc"), Size: 3, Seq: 0},
+			{ID: "c1", Direction: flow.WSServerToClient, Data: []byte{0xff, 0x00}, Size: 2, Seq: 1},
+		},
+	})
+
+	dto, ok := svc.TCPSession("tcp-1")
+	if !ok || dto.Target != "10.0.0.1:22" || dto.EndTime == "" || dto.BytesReceived != 2 {
+		t.Fatalf("TCP 会话 = %+v ok=%v", dto, ok)
+	}
+	if len(dto.Chunks) != 2 || dto.Chunks[0].Direction != "outbound" || dto.Chunks[0].Data != "abc" {
+		t.Fatalf("客户端分块 = %+v", dto.Chunks)
+	}
+	if c := dto.Chunks[1]; c.Direction != "inbound" || !c.Binary || c.Data != "/wA=" {
+		t.Fatalf("二进制分块应 base64 编码: %+v", c)
+	}
+	if _, ok := svc.TCPSession("nope"); ok {
+		t.Error("未知 TCP 会话不应查到")
+	}
+	if list, total := svc.TCPSessions(1, 10); total != 1 || len(list) != 1 {
+		t.Errorf("TCP 分页 = %d/%d", len(list), total)
+	}
+}
+
+func TestMQTTSessionLookup(t *testing.T) {
+	t.Parallel()
+	svc := newTestService(t)
+	svc.RecordMQTTSession(&flow.MQTTSession{
+		ID: "mq-1", Broker: "10.0.0.1:1883", ClientID: "sensor-7", ProtocolVersion: 4, Status: "open", StartTime: time.Now(),
+		Messages: []flow.MQTTMessage{
+			{ID: "m1", Direction: flow.WSClientToServer, Type: flow.MQTTPublish, Topic: "t/1", QoS: 1, PacketID: 3, Payload: []byte("21.5"), Size: 14},
+			{ID: "m2", Direction: flow.WSServerToClient, Type: flow.MQTTPublish, Topic: "t/2", Payload: []byte{0x00, 0xff}, Dropped: true},
+		},
+		MessageCount: 2,
+	})
+	svc.RecordMQTTSession(&flow.MQTTSession{ID: "mq-2", Broker: "10.0.0.2:1883", Status: "closed"})
+
+	dto, ok := svc.MQTTSession("mq-1")
+	if !ok || dto.ClientID != "sensor-7" || len(dto.Messages) != 2 {
+		t.Fatalf("MQTT 会话 = %+v ok=%v", dto, ok)
+	}
+	if m := dto.Messages[0]; m.Payload != "21.5" || m.Binary || m.Topic != "t/1" || m.QoS != 1 || m.Direction != "outbound" {
+		t.Errorf("文本 PUBLISH = %+v", m)
+	}
+	if m := dto.Messages[1]; !m.Binary || !m.Dropped || m.Direction != "inbound" {
+		t.Errorf("二进制 PUBLISH = %+v", m)
+	}
+	if _, ok := svc.MQTTSession("nope"); ok {
+		t.Error("未知 MQTT 会话不应查到")
+	}
+	list, total := svc.MQTTSessions(1, 10)
+	if total != 2 || len(list) != 2 || list[0].ID != "mq-2" || list[0].Messages == nil {
+		t.Errorf("MQTT 分页应新的在前且报文段非 nil: %+v total=%d", list, total)
+	}
+}
+
+func TestDNSQueryLookup(t *testing.T) {
+	t.Parallel()
+	svc := newTestService(t)
+	svc.RecordDNSQuery(&flow.DNSQuery{
+		ID: "dns-1", ClientAddr: "192.168.1.20:5353", Transport: "udp", Name: "api.example.com", Type: "A",
+		RCode: "NOERROR", Overridden: true, StartTime: time.Now(), DurationMs: 1,
+		Answers: []flow.DNSAnswer{{Name: "api.example.com", Type: "A", TTL: 60, Data: "192.168.1.10"}},
+	})
+	svc.RecordDNSQuery(&flow.DNSQuery{ID: "dns-2", Name: "www.example.org", Type: "AAAA", RCode: "SERVFAIL", Error: "timeout"})
+
+	dto, ok := svc.DNSQuery("dns-1")
+	if !ok || !dto.Overridden || dto.Timestamp == "" || len(dto.Answers) != 1 || dto.Answers[0].Data != "192.168.1.10" {
+		t.Fatalf("DNS 查询 = %+v ok=%v", dto, ok)
+	}
+	if _, ok := svc.DNSQuery("nope"); ok {
+		t.Error("未知 DNS 查询不应查到")
+	}
+	list, total := svc.DNSQueries(1, 10)
+	if total != 2 || len(list) != 2 || list[0].ID != "dns-2" || list[0].Answers == nil {
+		t.Errorf("DNS 分页应新的在前且应答段非 nil: %+v total=%d", list, total)
+	}
+}
+
 func TestUptimeSecondsCountsFromStart(t *testing.T) {
 	t.Parallel()
 	svc := newTestService(t)
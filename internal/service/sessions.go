@@ -296,3 +296,174 @@ func (s *streamStore) clear() {
 	s.items = make(map[string]*flow.StreamSession)
 	s.order = nil
 }
+
+// tcpStore 存储原始 TCP 中继会话,结构同 wsStore。
+type tcpStore struct {
+	mu    sync.RWMutex
+	order []string
+	items map[string]*flow.TCPSession
+	cap   int
+}
+
+func newTCPStore(capacity int) *tcpStore {
+	if capacity <= 0 {
+		capacity = 2000
+	}
+	return &tcpStore{items: make(map[string]*flow.TCPSession), cap: capacity}
+}
+
+func (s *tcpStore) put(ts *flow.TCPSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.items[ts.ID]; !exists {
+		s.order = append(s.order, ts.ID)
+		for len(s.order) > s.cap {
+			oldest := s.order[0]
+			s.order = s.order[1:]
+			delete(s.items, oldest)
+		}
+	}
+	s.items[ts.ID] = ts
+}
+
+func (s *tcpStore) get(id string) (*flow.TCPSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ts, ok := s.items[id]
+	return ts, ok
+}
+
+func (s *tcpStore) list(page, pageSize int) ([]*flow.TCPSession, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	total := len(s.order)
+	start, end := pageBounds(total, page, pageSize)
+	out := make([]*flow.TCPSession, 0, end-start)
+	for i := total - 1 - start; i >= total-end; i-- {
+		if ts, ok := s.items[s.order[i]]; ok {
+			out = append(out, ts)
+		}
+	}
+	return out, total
+}
+
+func (s *tcpStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]*flow.TCPSession)
+	s.order = nil
+}
+
+// mqttStore 存储 MQTT 中继会话,结构同 tcpStore。
+type mqttStore struct {
+	mu    sync.RWMutex
+	order []string
+	items map[string]*flow.MQTTSession
+	cap   int
+}
+
+func newMQTTStore(capacity int) *mqttStore {
+	if capacity <= 0 {
+		capacity = 2000
+	}
+	return &mqttStore{items: make(map[string]*flow.MQTTSession), cap: capacity}
+}
+
+func (s *mqttStore) put(ms *flow.MQTTSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.items[ms.ID]; !exists {
+		s.order = append(s.order, ms.ID)
+		for len(s.order) > s.cap {
+			oldest := s.order[0]
+			s.order = s.order[1:]
+			delete(s.items, oldest)
+		}
+	}
+	s.items[ms.ID] = ms
+}
+
+func (s *mqttStore) get(id string) (*flow.MQTTSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ms, ok := s.items[id]
+	return ms, ok
+}
+
+func (s *mqttStore) list(page, pageSize int) ([]*flow.MQTTSession, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	total := len(s.order)
+	start, end := pageBounds(total, page, pageSize)
+	out := make([]*flow.MQTTSession, 0, end-start)
+	for i := total - 1 - start; i >= total-end; i-- {
+		if ms, ok := s.items[s.order[i]]; ok {
+			out = append(out, ms)
+		}
+	}
+	return out, total
+}
+
+func (s *mqttStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]*flow.MQTTSession)
+	s.order = nil
+}
+
+// dnsStore 存储内置 DNS 服务端的查询记录,结构同 tcpStore。
+type dnsStore struct {
+	mu    sync.RWMutex
+	order []string
+	items map[string]*flow.DNSQuery
+	cap   int
+}
+
+func newDNSStore(capacity int) *dnsStore {
+	if capacity <= 0 {
+		capacity = 2000
+	}
+	return &dnsStore{items: make(map[string]*flow.DNSQuery), cap: capacity}
+}
+
+func (s *dnsStore) put(q *flow.DNSQuery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.items[q.ID]; !exists {
+		s.order = append(s.order, q.ID)
+		for len(s.order) > s.cap {
+			oldest := s.order[0]
+			s.order = s.order[1:]
+			delete(s.items, oldest)
+		}
+	}
+	s.items[q.ID] = q
+}
+
+func (s *dnsStore) get(id string) (*flow.DNSQuery, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, ok := s.items[id]
+	return q, ok
+}
+
+func (s *dnsStore) list(page, pageSize int) ([]*flow.DNSQuery, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	total := len(s.order)
+	start, end := pageBounds(total, page, pageSize)
+	out := make([]*flow.DNSQuery, 0, end-start)
+	for i := total - 1 - start; i >= total-end; i-- {
+		if q, ok := s.items[s.order[i]]; ok {
+			out = append(out, q)
+		}
+	}
+	return out, total
+}
+
+func (s *dnsStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]*flow.DNSQuery)
+	s.order = nil
+}
@@ -37,6 +37,9 @@ func TestStoreCapacityDefaults(t *testing.T) {
 		{"会话存储显式容量", newSessionStore(7).cap, 7},
 		{"WebSocket 存储", newWSStore(0).cap, 2000},
 		{"流式存储", newStreamStore(0).cap, 2000},
+		{"TCP 存储", newTCPStore(0).cap, 2000},
+		{"MQTT 存储", newMQTTStore(0).cap, 2000},
+		{"DNS 存储", newDNSStore(0).cap, 2000},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
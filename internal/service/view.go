@@ -6,7 +6,9 @@
 package service
 
 import (
+	"crypto/tls"
 	"encoding/base64"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -49,14 +51,28 @@ type HTTPResponseDTO struct {
 
 // HTTPSessionDTO 对应前端 HttpSession。
 type HTTPSessionDTO struct {
-	ID       string           `json:"id"`
-	Request  HTTPRequestDTO   `json:"request"`
-	Response *HTTPResponseDTO `json:"response,omitempty"`
-	Duration int64            `json:"duration,omitempty"`
-	Status   string           `json:"status"`
-	Blocked  bool             `json:"blocked,omitempty"`
-	Modified bool             `json:"modified,omitempty"`
-	Error    string           `json:"error,omitempty"` // 处理出错时的原因(如 TLS 握手失败),供 UI 展示
+	ID       string             `json:"id"`
+	Request  HTTPRequestDTO     `json:"request"`
+	Response *HTTPResponseDTO   `json:"response,omitempty"`
+	Duration int64              `json:"duration,omitempty"`
+	Status   string             `json:"status"`
+	Blocked  bool               `json:"blocked,omitempty"`
+	Modified bool               `json:"modified,omitempty"`
+	Error    string             `json:"error,omitempty"`    // 处理出错时的原因(如 TLS 握手失败),供 UI 展示
+	Listener string             `json:"listener,omitempty"` // 到达的附加监听端标识,主监听端为空
+	TLS      *TLSFingerprintDTO `json:"tls,omitempty"`      // 客户端 TLS 指纹,明文请求为空
+	// UpstreamTLS 是上游连接实际发出的 ClientHello 指纹,用于核对服务端看到的内容。
+	UpstreamTLS *TLSFingerprintDTO `json:"upstreamTls,omitempty"`
+	// ClientCert 是按主机选用、上游要求时出示的客户端证书(mTLS)。
+	ClientCert *ClientCertInfoDTO `json:"clientCert,omitempty"`
+	// UpstreamConn 是承载请求的上游 TLS 连接:版本、套件、ALPN、证书链与校验结论。
+	UpstreamConn *UpstreamConnDTO `json:"upstreamConn,omitempty"`
+	// CertRejected 表示上游证书未通过校验、请求被拒(enforce 模式),区别于一般的上游失败。
+	CertRejected bool `json:"certRejected,omitempty"`
+	// Timing 是上游请求的分阶段耗时与连接信息,未经转发器(mock、拦截等)时为空。
+	Timing *TimingDTO `json:"timing,omitempty"`
+	// Route 是请求选用的上游路由:DIRECT 或不含凭据的代理地址。
+	Route string `json:"route,omitempty"`
 
 	ProcessName  string `json:"processName,omitempty"`
 	ProcessID    uint32 `json:"processId,omitempty"`
@@ -69,6 +85,141 @@ type HTTPSessionDTO struct {
 	IconCategory string `json:"iconCategory,omitempty"`
 }
 
+// TLSFingerprintDTO 是 ClientHello 指纹。版本、套件、扩展与曲线为线上原始数值(含 GREASE)。
+type TLSFingerprintDTO struct {
+	JA3        string   `json:"ja3"`
+	JA3Hash    string   `json:"ja3Hash"`
+	JA4        string   `json:"ja4"`
+	SNI        string   `json:"sni,omitempty"`
+	Version    uint16   `json:"version"`
+	Ciphers    []uint16 `json:"ciphers"`
+	Extensions []uint16 `json:"extensions"`
+	ALPN       []string `json:"alpn,omitempty"`
+	Curves     []uint16 `json:"curves,omitempty"`
+	Versions   []uint16 `json:"versions,omitempty"`
+}
+
+func tlsFingerprintDTO(fp *flow.TLSFingerprint) *TLSFingerprintDTO {
+	if fp == nil {
+		return nil
+	}
+	return &TLSFingerprintDTO{
+		JA3:        fp.JA3,
+		JA3Hash:    fp.JA3Hash,
+		JA4:        fp.JA4,
+		SNI:        fp.SNI,
+		Version:    fp.Version,
+		Ciphers:    fp.Ciphers,
+		Extensions: fp.Extensions,
+		ALPN:       fp.ALPN,
+		Curves:     fp.Curves,
+		Versions:   fp.Versions,
+	}
+}
+
+// ClientCertInfoDTO 是为请求选用的上游客户端证书摘要。
+type ClientCertInfoDTO struct {
+	ID      string `json:"id"`
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+}
+
+func clientCertInfoDTO(c *flow.ClientCertInfo) *ClientCertInfoDTO {
+	if c == nil {
+		return nil
+	}
+	return &ClientCertInfoDTO{ID: c.ID, Subject: c.Subject, Issuer: c.Issuer}
+}
+
+// UpstreamConnDTO 是上游 TLS 连接摘要。Version / Cipher 为可读名称。
+type UpstreamConnDTO struct {
+	Version     string            `json:"version"`
+	Cipher      string            `json:"cipher"`
+	ALPN        string            `json:"alpn,omitempty"`
+	Chain       []UpstreamCertDTO `json:"chain,omitempty"` // 叶子在前
+	Verified    bool              `json:"verified"`
+	VerifyError string            `json:"verifyError,omitempty"`
+}
+
+// UpstreamCertDTO 是上游证书链中的一张证书;有效期为 RFC3339 时间。
+type UpstreamCertDTO struct {
+	Subject   string   `json:"subject"`
+	Issuer    string   `json:"issuer"`
+	DNSNames  []string `json:"dnsNames,omitempty"`
+	NotBefore string   `json:"notBefore"`
+	NotAfter  string   `json:"notAfter"`
+	SHA256    string   `json:"sha256"`
+}
+
+func upstreamConnDTO(uc *flow.UpstreamConn) *UpstreamConnDTO {
+	if uc == nil {
+		return nil
+	}
+	dto := &UpstreamConnDTO{
+		Version:     tls.VersionName(uc.Version),
+		Cipher:      tls.CipherSuiteName(uc.CipherSuite),
+		ALPN:        uc.ALPN,
+		Chain:       make([]UpstreamCertDTO, len(uc.Chain)),
+		Verified:    uc.Verified,
+		VerifyError: uc.VerifyError,
+	}
+	for i, c := range uc.Chain {
+		dto.Chain[i] = UpstreamCertDTO{
+			Subject:   c.Subject,
+			Issuer:    c.Issuer,
+			DNSNames:  c.DNSNames,
+			NotBefore: c.NotBefore.Format(time.RFC3339),
+			NotAfter:  c.NotAfter.Format(time.RFC3339),
+			SHA256:    c.SHA256,
+		}
+	}
+	return dto
+}
+
+// TimingDTO 是上游请求的分阶段耗时(毫秒,口径同 HAR timings,-1 表示该阶段未发生)。
+type TimingDTO struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"` // 仅 TCP 建连,不含 TLS
+	SSL      float64 `json:"ssl"`
+	Send     float64 `json:"send"`
+	Wait     float64 `json:"wait"`
+	Receive  float64 `json:"receive"`
+	TTFB     int64   `json:"ttfb"`
+	Reused   bool    `json:"reused"`             // 复用了已有连接
+	ServerIP string  `json:"serverIp,omitempty"` // 实际连接的对端 IP(经代理时为代理)
+}
+
+func timingDTO(t flow.Timing) *TimingDTO {
+	ph := t.Phases
+	if ph == nil {
+		return nil
+	}
+	ip := ph.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return &TimingDTO{
+		DNS:      ph.DNSMs,
+		Connect:  ph.ConnectMs,
+		SSL:      ph.TLSMs,
+		Send:     ph.SendMs,
+		Wait:     ph.WaitMs,
+		Receive:  ph.ReceiveMs,
+		TTFB:     t.TTFBMs,
+		Reused:   ph.Reused,
+		ServerIP: ip,
+	}
+}
+
+// AutoBypassHostDTO 是一条因 MITM 握手反复失败而自动直通的主机。Since 为 RFC3339 时间,
+// 从持久化恢复的条目无失败次数与时间。
+type AutoBypassHostDTO struct {
+	Host      string `json:"host"`
+	Failures  int    `json:"failures"`
+	LastError string `json:"lastError,omitempty"`
+	Since     string `json:"since,omitempty"`
+}
+
 // HTTPSessionMetadata 是不含头部、Body 和进程图标的轻量会话索引，供需要先筛选
 // 再构造完整 DTO 的调用方使用。
 type HTTPSessionMetadata struct {
@@ -187,12 +338,20 @@ func SessionDTO(f *flow.Flow) HTTPSessionDTO {
 
 func sessionDTO(f *flow.Flow, includeRequestBody, includeResponseBody bool) HTTPSessionDTO {
 	dto := HTTPSessionDTO{
-		ID:       f.ID,
-		Status:   stateToStatus(f.State),
-		Duration: f.Timing.DurationMs,
-		Blocked:  f.State == flow.StateBlocked,
-		Modified: f.Modified,
-		Error:    f.Error,
+		ID:           f.ID,
+		Status:       stateToStatus(f.State),
+		Duration:     f.Timing.DurationMs,
+		Blocked:      f.State == flow.StateBlocked,
+		Modified:     f.Modified,
+		Error:        f.Error,
+		Listener:     f.Listener,
+		TLS:          tlsFingerprintDTO(f.TLSFingerprint()),
+		UpstreamTLS:  tlsFingerprintDTO(f.UpstreamTLSFingerprint()),
+		ClientCert:   clientCertInfoDTO(f.ClientCert()),
+		UpstreamConn: upstreamConnDTO(f.UpstreamConn()),
+		CertRejected: f.State == flow.StateCertRejected,
+		Timing:       timingDTO(f.Timing),
+		Route:        f.UpstreamRoute(),
 	}
 	if f.Request != nil {
 		ua := ""
@@ -272,6 +431,15 @@ type WSMessageDTO struct {
 	Binary    bool   `json:"binary,omitempty"` // true 时 Data 为 base64,前端按需 hex 展示
 	Timestamp string `json:"timestamp"`
 	Size      int64  `json:"size"`
+	Injected  bool   `json:"injected,omitempty"` // 经注入 / 重发发出
+}
+
+// WSFrameInput 是要注入 WebSocket 会话的一帧,字段口径同 WSMessageDTO。
+type WSFrameInput struct {
+	Direction string `json:"direction"` // outbound(发往服务端)|inbound(发往客户端)
+	Type      string `json:"type"`      // text|binary|ping|pong|close
+	Data      string `json:"data"`      // Binary 为 true 时为 base64
+	Binary    bool   `json:"binary,omitempty"`
 }
 
 // wsMessageData 把一帧 WebSocket 消息编码为前端可展示的字符串。
@@ -318,6 +486,16 @@ func wsDirectionToFrontend(d string) string {
 	return "inbound"
 }
 
+func wsDirectionFromFrontend(d string) (string, error) {
+	switch d {
+	case "outbound":
+		return flow.WSClientToServer, nil
+	case "inbound":
+		return flow.WSServerToClient, nil
+	}
+	return "", invalidInput("direction 须为 outbound 或 inbound")
+}
+
 // StreamMessageDTO 对应前端 StreamMessage(SSE 事件 / gRPC 消息 / 分块)。
 type StreamMessageDTO struct {
 	ID        string `json:"id"`
@@ -325,8 +503,11 @@ type StreamMessageDTO struct {
 	Direction string `json:"direction"` // inbound|outbound
 	Kind      string `json:"kind"`      // sse|grpc|chunk
 	EventType string `json:"eventType,omitempty"`
-	Data      string `json:"data"`             // 文本按原文,二进制 base64
-	Binary    bool   `json:"binary,omitempty"` // true 时 Data 为 base64
+	Encoding  string `json:"encoding,omitempty"` // gRPC:压缩帧的 grpc-encoding,Data 为解压后的载荷
+	Data      string `json:"data"`               // 文本按原文,二进制 base64
+	Binary    bool   `json:"binary,omitempty"`   // true 时 Data 为 base64
+	JSON      string `json:"json,omitempty"`     // gRPC:protobuf 解码后的 JSON 视图
+	Schema    string `json:"schema,omitempty"`   // gRPC:视图的消息类型全名,或 "wire"
 	Timestamp string `json:"timestamp"`
 	Seq       int    `json:"seq"`
 	Size      int64  `json:"size"`
@@ -381,8 +562,11 @@ func StreamSessionDTO(ss *flow.StreamSession) StreamSessionDTOType {
 			Direction: wsDirectionToFrontend(m.Direction),
 			Kind:      m.Kind,
 			EventType: m.EventType,
+			Encoding:  m.Encoding,
 			Data:      data,
 			Binary:    binary,
+			JSON:      m.JSON,
+			Schema:    m.Schema,
 			Timestamp: rfc3339(m.Timestamp),
 			Seq:       m.Seq,
 			Size:      int64(len(m.Data)),
@@ -414,6 +598,230 @@ func StreamSessionDTO(ss *flow.StreamSession) StreamSessionDTOType {
 	return dto
 }
 
+// TCPChunkDTO 对应前端 TCPChunk(原始 TCP 中继的一个数据块)。
+type TCPChunkDTO struct {
+	ID        string `json:"id"`
+	SessionID string `json:"sessionId"`
+	Direction string `json:"direction"` // inbound|outbound
+	Data      string `json:"data"`      // 文本按原文,二进制 base64
+	Binary    bool   `json:"binary,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"` // Data 只是该块的前缀
+	Offset    int64  `json:"offset"`
+	Timestamp string `json:"timestamp"`
+	Seq       int    `json:"seq"`
+	Size      int64  `json:"size"`
+}
+
+// TCPSessionDTOType 对应前端 TCPSession。
+type TCPSessionDTOType struct {
+	ID            string        `json:"id"`
+	ClientAddr    string        `json:"clientAddr"`
+	Target        string        `json:"target"`
+	Status        string        `json:"status"` // open|closed|error
+	Error         string        `json:"error,omitempty"`
+	StartTime     string        `json:"startTime"`
+	EndTime       string        `json:"endTime,omitempty"`
+	BytesSent     int64         `json:"bytesSent"`
+	BytesReceived int64         `json:"bytesReceived"`
+	ChunkCount    int           `json:"chunkCount"`
+	Chunks        []TCPChunkDTO `json:"chunks"`
+
+	ProcessName  string `json:"processName,omitempty"`
+	ProcessID    uint32 `json:"processId,omitempty"`
+	IconData     string `json:"iconData,omitempty"`
+	IconType     string `json:"iconType,omitempty"`
+	HasIcon      bool   `json:"hasIcon,omitempty"`
+	IconCategory string `json:"iconCategory,omitempty"`
+}
+
+// TCPSessionDTO 把 flow.TCPSession 转换为前端 TCPSession 形状。
+func TCPSessionDTO(ts *flow.TCPSession) TCPSessionDTOType {
+	chunks := make([]TCPChunkDTO, 0, len(ts.Chunks))
+	for _, c := range ts.Chunks {
+		data, binary := streamMessageData(flow.StreamMessage{Data: c.Data})
+		chunks = append(chunks, TCPChunkDTO{
+			ID:        c.ID,
+			SessionID: ts.ID,
+			Direction: wsDirectionToFrontend(c.Direction),
+			Data:      data,
+			Binary:    binary,
+			Truncated: c.Truncated,
+			Offset:    c.Offset,
+			Timestamp: rfc3339(c.Timestamp),
+			Seq:       c.Seq,
+			Size:      int64(c.Size),
+		})
+	}
+	dto := TCPSessionDTOType{
+		ID:            ts.ID,
+		ClientAddr:    ts.ClientAddr,
+		Target:        ts.Target,
+		Status:        ts.Status,
+		Error:         ts.Error,
+		StartTime:     rfc3339(ts.StartTime),
+		BytesSent:     ts.BytesSent,
+		BytesReceived: ts.BytesRecv,
+		ChunkCount:    ts.ChunkCount,
+		Chunks:        chunks,
+	}
+	if ts.EndTime != nil {
+		dto.EndTime = rfc3339(*ts.EndTime)
+	}
+	if ts.Process != nil {
+		dto.ProcessName = ts.Process.Name
+		dto.ProcessID = ts.Process.PID
+		dto.IconData = ts.Process.IconData
+		dto.IconType = ts.Process.IconType
+		dto.HasIcon = ts.Process.HasIcon
+		dto.IconCategory = ts.Process.IconCategory
+	}
+	return dto
+}
+
+// MQTTMessageDTO 对应前端 MQTTMessage。
+type MQTTMessageDTO struct {
+	ID        string   `json:"id"`
+	SessionID string   `json:"sessionId"`
+	Direction string   `json:"direction"` // inbound|outbound
+	Type      string   `json:"type"`      // CONNECT|PUBLISH|SUBSCRIBE|...
+	Topic     string   `json:"topic,omitempty"`
+	Topics    []string `json:"topics,omitempty"`
+	QoS       byte     `json:"qos"`
+	Retain    bool     `json:"retain,omitempty"`
+	Dup       bool     `json:"dup,omitempty"`
+	PacketID  uint16   `json:"packetId,omitempty"`
+	Payload   string   `json:"payload,omitempty"` // 文本按原文,二进制 base64
+	Binary    bool     `json:"binary,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Dropped   bool     `json:"dropped,omitempty"`
+	Modified  bool     `json:"modified,omitempty"`
+	Timestamp string   `json:"timestamp"`
+	Seq       int      `json:"seq"`
+	Size      int64    `json:"size"`
+}
+
+// MQTTSessionDTOType 对应前端 MQTTSession。
+type MQTTSessionDTOType struct {
+	ID              string           `json:"id"`
+	ClientAddr      string           `json:"clientAddr"`
+	Broker          string           `json:"broker"`
+	ClientID        string           `json:"clientId,omitempty"`
+	Username        string           `json:"username,omitempty"`
+	ProtocolVersion byte             `json:"protocolVersion,omitempty"`
+	Status          string           `json:"status"` // open|closed|error
+	Error           string           `json:"error,omitempty"`
+	StartTime       string           `json:"startTime"`
+	EndTime         string           `json:"endTime,omitempty"`
+	MessageCount    int              `json:"messageCount"`
+	Messages        []MQTTMessageDTO `json:"messages"`
+
+	ProcessName  string `json:"processName,omitempty"`
+	ProcessID    uint32 `json:"processId,omitempty"`
+	IconData     string `json:"iconData,omitempty"`
+	IconType     string `json:"iconType,omitempty"`
+	HasIcon      bool   `json:"hasIcon,omitempty"`
+	IconCategory string `json:"iconCategory,omitempty"`
+}
+
+// MQTTSessionDTO 把 flow.MQTTSession 转换为前端 MQTTSession 形状。
+func MQTTSessionDTO(ms *flow.MQTTSession) MQTTSessionDTOType {
+	messages := make([]MQTTMessageDTO, 0, len(ms.Messages))
+	for _, m := range ms.Messages {
+		payload, binary := streamMessageData(flow.StreamMessage{Data: m.Payload})
+		messages = append(messages, MQTTMessageDTO{
+			ID:        m.ID,
+			SessionID: ms.ID,
+			Direction: wsDirectionToFrontend(m.Direction),
+			Type:      m.Type,
+			Topic:     m.Topic,
+			Topics:    m.Topics,
+			QoS:       m.QoS,
+			Retain:    m.Retain,
+			Dup:       m.Dup,
+			PacketID:  m.PacketID,
+			Payload:   payload,
+			Binary:    binary,
+			Truncated: m.Truncated,
+			Dropped:   m.Dropped,
+			Modified:  m.Modified,
+			Timestamp: rfc3339(m.Timestamp),
+			Seq:       m.Seq,
+			Size:      int64(m.Size),
+		})
+	}
+	dto := MQTTSessionDTOType{
+		ID:              ms.ID,
+		ClientAddr:      ms.ClientAddr,
+		Broker:          ms.Broker,
+		ClientID:        ms.ClientID,
+		Username:        ms.Username,
+		ProtocolVersion: ms.ProtocolVersion,
+		Status:          ms.Status,
+		Error:           ms.Error,
+		StartTime:       rfc3339(ms.StartTime),
+		MessageCount:    ms.MessageCount,
+		Messages:        messages,
+	}
+	if ms.EndTime != nil {
+		dto.EndTime = rfc3339(*ms.EndTime)
+	}
+	if ms.Process != nil {
+		dto.ProcessName = ms.Process.Name
+		dto.ProcessID = ms.Process.PID
+		dto.IconData = ms.Process.IconData
+		dto.IconType = ms.Process.IconType
+		dto.HasIcon = ms.Process.HasIcon
+		dto.IconCategory = ms.Process.IconCategory
+	}
+	return dto
+}
+
+// DNSAnswerDTO 对应前端 DNSAnswer。
+type DNSAnswerDTO struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"`
+}
+
+// DNSQueryDTOType 对应前端 DNSQuery(内置 DNS 服务端应答的一次查询)。
+type DNSQueryDTOType struct {
+	ID         string         `json:"id"`
+	ClientAddr string         `json:"clientAddr"`
+	Transport  string         `json:"transport"` // udp|tcp
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	RCode      string         `json:"rcode"`
+	Answers    []DNSAnswerDTO `json:"answers"`
+	Overridden bool           `json:"overridden,omitempty"`
+	Upstream   string         `json:"upstream,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Timestamp  string         `json:"timestamp"`
+	DurationMs int64          `json:"durationMs"`
+}
+
+// DNSQueryDTO 把 flow.DNSQuery 转换为前端 DNSQuery 形状。
+func DNSQueryDTO(q *flow.DNSQuery) DNSQueryDTOType {
+	answers := make([]DNSAnswerDTO, 0, len(q.Answers))
+	for _, a := range q.Answers {
+		answers = append(answers, DNSAnswerDTO(a))
+	}
+	return DNSQueryDTOType{
+		ID:         q.ID,
+		ClientAddr: q.ClientAddr,
+		Transport:  q.Transport,
+		Name:       q.Name,
+		Type:       q.Type,
+		RCode:      q.RCode,
+		Answers:    answers,
+		Overridden: q.Overridden,
+		Upstream:   q.Upstream,
+		Error:      q.Error,
+		Timestamp:  rfc3339(q.StartTime),
+		DurationMs: q.DurationMs,
+	}
+}
+
 // WSSessionDTO 把 flow.WSSession 转换为前端 WebSocketSession 形状。
 func WSSessionDTO(ws *flow.WSSession) WSSessionDTOType {
 	msgs := make([]WSMessageDTO, 0, len(ws.Messages))
@@ -432,6 +840,7 @@ func WSSessionDTO(ws *flow.WSSession) WSSessionDTOType {
 			Binary:    binary,
 			Timestamp: rfc3339(m.Timestamp),
 			Size:      int64(len(m.Data)),
+			Injected:  m.Injected,
 		})
 	}
 	dto := WSSessionDTOType{
@@ -236,6 +236,26 @@ func TestSessionDTOPartialFlows(t *testing.T) {
 			t.Errorf("出错会话 = %+v", dto)
 		}
 	})
+
+	t.Run("TLS 指纹", func(t *testing.T) {
+		t.Parallel()
+		f := newFlow("tls")
+		if dto := SessionDTO(f); dto.TLS != nil {
+			t.Errorf("没有指纹时不应输出: %+v", dto.TLS)
+		}
+		f.SetTLSFingerprint(&flow.TLSFingerprint{JA3Hash: "h", JA4: "t13d", Ciphers: []uint16{0x1301}, ALPN: []string{"h2"}})
+		dto := SessionDTO(f)
+		if dto.TLS == nil || dto.TLS.JA3Hash != "h" || dto.TLS.JA4 != "t13d" || dto.TLS.Ciphers[0] != 0x1301 || dto.TLS.ALPN[0] != "h2" {
+			t.Errorf("TLS 指纹 = %+v", dto.TLS)
+		}
+		if dto.UpstreamTLS != nil {
+			t.Errorf("未记录上游指纹时不应输出: %+v", dto.UpstreamTLS)
+		}
+		f.SetUpstreamTLSFingerprint(&flow.TLSFingerprint{JA4: "t13d_up", Curves: []uint16{29}})
+		if dto := SessionDTO(f); dto.UpstreamTLS == nil || dto.UpstreamTLS.JA4 != "t13d_up" || dto.UpstreamTLS.Curves[0] != 29 {
+			t.Errorf("上游 TLS 指纹 = %+v", dto.UpstreamTLS)
+		}
+	})
 }
 
 // TestSessionDTODropsBinaryBody 列表里的 body 只是预览,二进制内容一律丢空,由前端按需走 MessageBody 取。
@@ -0,0 +1,61 @@
+// Copyright 2026 The mintfog Authors
+// SPDX-License-Identifier: Apache-2.0
+// Use of this source code is governed by an Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package socksproto 定义 SOCKS5(RFC 1928)及其用户名/密码认证(RFC 1929)的协议常量,
+// 供 SOCKS5 入口与上游 SOCKS5 客户端共用。
+package socksproto
+
+import "fmt"
+
+// 协议版本、认证方式、命令与地址类型。
+const (
+	Version     = 0x05
+	AuthVersion = 0x01 // 用户名/密码子协商版本
+
+	MethodNoAuth       = 0x00
+	MethodUserPass     = 0x02
+	MethodNoAcceptable = 0xFF
+
+	CmdConnect = 0x01
+
+	AtypIPv4   = 0x01
+	AtypDomain = 0x03
+	AtypIPv6   = 0x04
+
+	AuthSuccess = 0x00
+	AuthFailure = 0x01
+)
+
+// 应答码(REP)。
+const (
+	RepSucceeded           = 0x00
+	RepGeneralFailure      = 0x01
+	RepNotAllowed          = 0x02
+	RepNetworkUnreachable  = 0x03
+	RepHostUnreachable     = 0x04
+	RepConnectionRefused   = 0x05
+	RepTTLExpired          = 0x06
+	RepCommandNotSupported = 0x07
+	RepAddrNotSupported    = 0x08
+)
+
+var replyTexts = map[byte]string{
+	RepGeneralFailure:      "一般性失败",
+	RepNotAllowed:          "规则不允许",
+	RepNetworkUnreachable:  "网络不可达",
+	RepHostUnreachable:     "主机不可达",
+	RepConnectionRefused:   "连接被拒绝",
+	RepTTLExpired:          "TTL 过期",
+	RepCommandNotSupported: "不支持的命令",
+	RepAddrNotSupported:    "不支持的地址类型",
+}
+
+// ReplyText 返回应答码 rep 的说明,未知应答码给出其数值。
+func ReplyText(rep byte) string {
+	if s, ok := replyTexts[rep]; ok {
+		return s
+	}
+	return fmt.Sprintf("应答码 %#x", rep)
+}
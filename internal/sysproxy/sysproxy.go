@@ -5,8 +5,8 @@
 
 // Package sysproxy 控制操作系统级的 HTTP/HTTPS 代理设置,使本机其它应用的流量
 // 经 Sniffy 监听端口转发。Set 把系统代理指向 host:port 并排除本地直连地址,
-// Clear 关闭系统代理。两者均为平台相关实现(见 sysproxy_<goos>.go),不支持的
-// 平台返回错误。
+// Clear 关闭系统代理;SetPAC / ClearPAC 则改为设置、关闭自动代理配置(PAC)URL。
+// 均为平台相关实现(见 sysproxy_<goos>.go),不支持的平台返回错误。
 package sysproxy
 
 import "strings"
@@ -46,3 +46,22 @@ func parseGetWebProxy(raw string) (enabled bool, server, port string) {
 	}
 	return
 }
+
+// parseGetAutoProxyURL 解析 `networksetup -getautoproxyurl <svc>`(macOS)的输出,
+// 形如 "URL: http://127.0.0.1:8080/proxy.pac / Enabled: Yes"。
+func parseGetAutoProxyURL(raw string) (enabled bool, pacURL string) {
+	for _, ln := range strings.Split(raw, "\n") {
+		key, val, ok := strings.Cut(ln, ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch strings.TrimSpace(key) {
+		case "Enabled":
+			enabled = strings.EqualFold(val, "Yes")
+		case "URL":
+			pacURL = val
+		}
+	}
+	return
+}
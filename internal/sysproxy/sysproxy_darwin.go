@@ -72,6 +72,50 @@ func PointsTo(host string, port int) bool {
 	return false
 }
 
+// SetPAC 把所有启用的网络服务的自动代理配置指向 pacURL(同时开启该项)。
+func SetPAC(pacURL string) error {
+	svcs, err := activeServices()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, svc := range svcs {
+		errs = append(errs, run("-setautoproxyurl", svc, pacURL))
+	}
+	return errors.Join(errs...)
+}
+
+// ClearPAC 关闭所有网络服务的自动代理配置。
+func ClearPAC() error {
+	svcs, err := activeServices()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, svc := range svcs {
+		errs = append(errs, run("-setautoproxystate", svc, "off"))
+	}
+	return errors.Join(errs...)
+}
+
+// PointsToPAC 报告是否有任一启用的网络服务的自动代理配置当前已开启且为 pacURL。
+func PointsToPAC(pacURL string) bool {
+	svcs, err := activeServices()
+	if err != nil {
+		return false
+	}
+	for _, svc := range svcs {
+		out, err := exec.Command("networksetup", "-getautoproxyurl", svc).Output()
+		if err != nil {
+			continue
+		}
+		if enabled, u := parseGetAutoProxyURL(string(out)); enabled && u == pacURL {
+			return true
+		}
+	}
+	return false
+}
+
 // activeServices 返回当前启用的网络服务名(如 Wi-Fi、Ethernet)。
 func activeServices() ([]string, error) {
 	out, err := exec.Command("networksetup", "-listallnetworkservices").Output()
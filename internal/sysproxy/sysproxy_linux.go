@@ -66,6 +66,37 @@ func PointsTo(host string, port int) bool {
 		getString("org.gnome.system.proxy.http", "port") == strconv.Itoa(port)
 }
 
+// SetPAC 通过 GNOME 代理设置把自动配置 URL 指向 pacURL,并切到 auto 模式。
+func SetPAC(pacURL string) error {
+	if !hasGsettings() {
+		return errNoGsettings
+	}
+	steps := [][]string{
+		{"set", "org.gnome.system.proxy", "autoconfig-url", pacURL},
+		{"set", "org.gnome.system.proxy", "mode", "auto"},
+	}
+	for _, s := range steps {
+		if err := run(s...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearPAC 把 GNOME 代理模式切回 none(直连)。
+func ClearPAC() error {
+	return Clear()
+}
+
+// PointsToPAC 报告 GNOME 系统代理当前是否为 auto 且自动配置 URL 为 pacURL。
+func PointsToPAC(pacURL string) bool {
+	if !hasGsettings() {
+		return false
+	}
+	return getString("org.gnome.system.proxy", "mode") == "auto" &&
+		getString("org.gnome.system.proxy", "autoconfig-url") == pacURL
+}
+
 func hasGsettings() bool {
 	_, err := exec.LookPath("gsettings")
 	return err == nil
@@ -14,3 +14,6 @@ var errUnsupported = errors.New("当前平台不支持设置系统代理")
 func Set(host string, port int) error     { return errUnsupported }
 func Clear() error                        { return errUnsupported }
 func PointsTo(host string, port int) bool { return false }
+func SetPAC(pacURL string) error          { return errUnsupported }
+func ClearPAC() error                     { return errUnsupported }
+func PointsToPAC(pacURL string) bool      { return false }
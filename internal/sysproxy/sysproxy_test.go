@@ -36,3 +36,13 @@ func TestParseGetWebProxy(t *testing.T) {
 	off, _, _ := parseGetWebProxy("Enabled: No\nServer:\nPort: 0\n")
 	assert.False(t, off)
 }
+
+func TestParseGetAutoProxyURL(t *testing.T) {
+	enabled, u := parseGetAutoProxyURL("URL: http://127.0.0.1:8080/proxy.pac\nEnabled: Yes\n")
+	assert.True(t, enabled)
+	assert.Equal(t, "http://127.0.0.1:8080/proxy.pac", u)
+
+	off, empty := parseGetAutoProxyURL("URL: (null)\nEnabled: No\n")
+	assert.False(t, off)
+	assert.Equal(t, "(null)", empty)
+}
@@ -8,6 +8,7 @@
 package sysproxy
 
 import (
+	"errors"
 	"fmt"
 
 	"golang.org/x/sys/windows"
@@ -71,6 +72,45 @@ func PointsTo(host string, port int) bool {
 	return server == fmt.Sprintf("%s:%d", host, port)
 }
 
+// SetPAC 写入 AutoConfigURL(「使用设置脚本」),然后通知 WinINet 立即重读。
+func SetPAC(pacURL string) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("打开注册表失败: %w", err)
+	}
+	defer k.Close()
+	if err := k.SetStringValue("AutoConfigURL", pacURL); err != nil {
+		return err
+	}
+	refresh()
+	return nil
+}
+
+// ClearPAC 删除 AutoConfigURL 并通知 WinINet 立即重读;本就没有该值时视为成功。
+func ClearPAC() error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("打开注册表失败: %w", err)
+	}
+	defer k.Close()
+	if err := k.DeleteValue("AutoConfigURL"); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return err
+	}
+	refresh()
+	return nil
+}
+
+// PointsToPAC 报告 WinINet 的 AutoConfigURL 当前是否为 pacURL。
+func PointsToPAC(pacURL string) bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsPath, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	u, _, err := k.GetStringValue("AutoConfigURL")
+	return err == nil && u == pacURL
+}
+
 // refresh 通知 WinINet 立即重读代理设置;否则已运行的应用不会感知本次变更。
 // 调用失败不致命:设置已落注册表,新启动的应用仍会生效。
 func refresh() {
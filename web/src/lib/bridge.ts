@@ -52,6 +52,15 @@ export function sessionBodyUrl(id: string, source: 'request' | 'response'): stri
   return `/body/${encodeURIComponent(id)}?source=${source}`
 }
 
+/** 要注入 WebSocket 会话的一帧（对应 Go 侧 service.WSFrameInput）。 */
+export interface WSFrameInput {
+  direction: 'inbound' | 'outbound'
+  type: 'text' | 'binary' | 'ping' | 'pong' | 'close'
+  /** binary=true 时为 base64 */
+  data: string
+  binary?: boolean
+}
+
 export interface StreamSessionPage {
   data: StreamSession[]
   total: number
@@ -76,6 +85,36 @@ export interface AppConfig {
   throttleKiBps?: number
   /** 关闭主窗口后是否留在系统托盘;false 则关闭 = 完全退出。 */
   runInBackground?: boolean
+  /** 遇到未知 gRPC 方法时经上游查询服务端反射,取回描述符解码消息。 */
+  grpcReflection?: boolean
+  /** https 上游连接按客户端 ClientHello 的套件、版本、曲线与 ALPN 握手。 */
+  tlsMirror?: boolean
+  /** 镜像生效的主机通配模式;为空时对全部主机生效。 */
+  tlsMirrorHosts?: string[]
+  /** 同一主机窗口内 MITM 握手失败达到阈值(多为固定证书)后自动改为直通。 */
+  autoBypass?: boolean
+  /** 触发自动绕过的握手失败次数(1-100)。 */
+  autoBypassThreshold?: number
+  /** 失败计数窗口,秒(1-86400)。 */
+  autoBypassWindowSec?: number
+  /** 自动绕过列表是否持久化,重启后恢复。 */
+  autoBypassPersist?: boolean
+  /** 上游证书校验:off 不校验、warn 记入会话但照常转发、enforce 未通过即拒绝连接。 */
+  upstreamVerify?: 'off' | 'warn' | 'enforce'
+  /** 在系统根之外追加信任的根证书(PEM,可多张)。 */
+  upstreamTrustRoots?: string
+  /** 上游路由表:每行「主机模式[,主机模式...] 目标」,目标为 DIRECT 或代理地址;按顺序首条命中。 */
+  upstreamRoutes?: string
+  /** 主机映射表:每行「主机模式[,主机模式...] 目标」,直连上游时改连目标地址,SNI 与 Host 头不变;经上游代理时不映射。 */
+  hostMappings?: string
+  /** 在代理端口(/proxy.pac、/wpad.dat)与管理 API 提供 PAC 脚本。 */
+  pac?: boolean
+  /** PAC 中经 Sniffy 的主机通配模式;为空时除排除项外全部经 Sniffy。 */
+  pacInclude?: string[]
+  /** PAC 中始终直连的主机通配模式,优先于 pacInclude。 */
+  pacExclude?: string[]
+  /** 系统代理改设为 PAC URL(需同时开启 pac)。 */
+  systemProxyPac?: boolean
 }
 
 /** 代理实际监听的绑定地址/端口（对应 Go 侧 ListenInfo，只读）。 */
@@ -111,6 +150,37 @@ export interface ServerCert {
   notAfter: string
 }
 
+/** 一张出示给上游的客户端证书摘要（对应 Go 侧 service.ClientCertDTO，不含私钥）。 */
+export interface ClientCert {
+  /** 叶子证书指纹（SHA-256 hex），删除时按它引用。 */
+  id: string
+  /** 生效的主机通配模式。 */
+  hosts: string[]
+  subject: string
+  issuer: string
+  /** 证书有效期截止（RFC3339）。 */
+  notAfter: string
+}
+
+/** 一条因 MITM 握手反复失败而自动直通的主机（对应 Go 侧 service.AutoBypassHostDTO）。 */
+export interface AutoBypassHost {
+  host: string
+  /** 触发时窗口内的失败次数；从持久化恢复的为 0。 */
+  failures: number
+  lastError?: string
+  /** 加入时间（RFC3339）；从持久化恢复的为空。 */
+  since?: string
+}
+
+/** 一个上传的 protobuf 描述符集摘要（对应 Go 侧 service.ProtoSetDTO）。 */
+export interface ProtoSet {
+  name: string
+  /** 其中定义的 gRPC 服务全名。 */
+  services: string[]
+  size: number
+  importedAt: string
+}
+
 /** 全局断点开关状态（对应 Go 侧 GlobalBreakState）。 */
 export interface GlobalBreakState {
   onRequest: boolean
@@ -123,6 +193,10 @@ export interface BreakRule {
   url: string
   onRequest: boolean
   onResponse: boolean
+  /** 同时断该 URL 上 WebSocket 会话的消息，可再按方向与内容收窄。 */
+  onWebSocket: boolean
+  direction?: 'client->server' | 'server->client'
+  content?: string
   enabled: boolean
 }
 
@@ -146,6 +220,10 @@ export const Bridge = {
   // WebSocket 会话（实时帧仍经 ws_message 事件推送；这里用于启动/重连时回填历史会话）
   getWSSessions: (page: number, pageSize: number) => call<WSSessionPage>('GetWSSessions', page, pageSize),
   getWSSession: (id: string) => call<WebSocketSession | null>('GetWSSession', id),
+  /** 向打开中的会话注入一帧；会话不存在/已关闭或输入非法时 reject。 */
+  sendWSFrame: (sessionId: string, frame: WSFrameInput) => call<void>('SendWSFrame', sessionId, frame),
+  /** 把会话中一条已捕获的消息按原方向再发一次。 */
+  resendWSMessage: (sessionId: string, messageId: string) => call<void>('ResendWSMessage', sessionId, messageId),
 
   // 流式会话(SSE / gRPC / 分块流;实时消息经 stream_message 事件推送,这里回填历史)
   getStreamSessions: (page: number, pageSize: number) => call<StreamSessionPage>('GetStreamSessions', page, pageSize),
@@ -199,6 +277,32 @@ export const Bridge = {
   /** 按证书指纹删除导入证书。 */
   deleteServerCert: (id: string) => call<void>('DeleteServerCert', id),
 
+  // 上游客户端证书(mTLS:上游要求客户端认证时按主机出示)
+  /** 列出客户端证书摘要(不含私钥)。 */
+  getClientCerts: () => call<ClientCert[]>('GetClientCerts'),
+  /** 导入 PEM 证书(可带中间证书)+ 私钥,对 hosts 匹配的上游生效;同一证书再次导入即更新主机范围。 */
+  importClientCert: (certPEM: string, keyPEM: string, hosts: string[]) =>
+    call<ClientCert>('ImportClientCert', certPEM, keyPEM, hosts),
+  /** 导入 PKCS#12(data 为 base64)。 */
+  importClientCertPKCS12: (data: string, password: string, hosts: string[]) =>
+    call<ClientCert>('ImportClientCertPKCS12', data, password, hosts),
+  /** 按证书指纹删除客户端证书。 */
+  deleteClientCert: (id: string) => call<void>('DeleteClientCert', id),
+
+  // 握手失败自动绕过(固定证书的主机改为直通)
+  /** 列出因 MITM 握手反复失败而自动直通的主机。 */
+  getAutoBypassHosts: () => call<AutoBypassHost[]>('GetAutoBypassHosts'),
+  /** 把主机移出自动绕过列表、恢复解密;host 为空时清空全部。 */
+  resetAutoBypass: (host: string) => call<void>('ResetAutoBypass', host),
+
+  // protobuf 描述符集(按类型解码 gRPC 消息)
+  /** 列出已上传的描述符集摘要。 */
+  getProtoSets: () => call<ProtoSet[]>('GetProtoSets'),
+  /** 上传一个 FileDescriptorSet(data 为 base64),同名覆盖;无效或不含服务时 reject。 */
+  importProtoSet: (name: string, data: string) => call<ProtoSet>('ImportProtoSet', name, data),
+  /** 按名称删除描述符集。 */
+  deleteProtoSet: (name: string) => call<void>('DeleteProtoSet', name),
+
   // 插件
   getPlugins: () => call<PluginMeta[]>('GetPlugins'),
   enablePlugin: (id: string, enabled: boolean) => call<void>('EnablePlugin', id, enabled),
@@ -216,6 +320,12 @@ export const Bridge = {
   setGlobalBreak: (onRequest: boolean, onResponse: boolean) =>
     call<void>('SetGlobalBreak', onRequest, onResponse),
   getGlobalBreak: () => call<GlobalBreakState>('GetGlobalBreak'),
+  getWSBreakpoints: () => call<unknown[]>('GetWSBreakpoints'),
+  /** data 为 null 表示原样放行；binary 时 data 为 base64。 */
+  resumeWSBreakpoint: (id: string, data: string | null, binary: boolean) =>
+    call<boolean>('ResumeWSBreakpoint', id, data, binary),
+  setWebSocketBreak: (on: boolean) => call<void>('SetWebSocketBreak', on),
+  getWebSocketBreak: () => call<boolean>('GetWebSocketBreak'),
 
   // URL 断点规则
   getBreakRules: () => call<BreakRule[]>('GetBreakRules'),
@@ -225,6 +335,8 @@ export const Bridge = {
     call<boolean>('UpdateBreakRule', id, url, onRequest, onResponse, enabled),
   toggleBreakRule: (id: string, enabled: boolean) => call<boolean>('ToggleBreakRule', id, enabled),
   deleteBreakRule: (id: string) => call<void>('DeleteBreakRule', id),
+  setBreakRuleWebSocket: (id: string, on: boolean, direction: string, content: string) =>
+    call<boolean>('SetBreakRuleWebSocket', id, on, direction, content),
 
   // 窗口（桌面外壳）
   /** 打开（或聚焦已存在的）独立系统窗口承载某个页面：settings | tools | about。 */
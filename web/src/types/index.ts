@@ -42,6 +42,20 @@ export interface HttpSession {
   processId?: number
   processPath?: string
   processUser?: string
+  /** 客户端 TLS 指纹;明文请求或未能解析 ClientHello 时缺省 */
+  tls?: TLSFingerprint
+  /** 上游连接实际发出的 ClientHello 指纹;仅 https 经保真转发时有值 */
+  upstreamTls?: TLSFingerprint
+  /** 按主机选用、上游要求时出示的客户端证书(mTLS) */
+  clientCert?: { id: string; subject: string; issuer: string }
+  /** 承载请求的上游 TLS 连接:版本、套件、ALPN、证书链与校验结论 */
+  upstreamConn?: UpstreamConn
+  /** 上游证书未通过校验、请求被拒(enforce 模式) */
+  certRejected?: boolean
+  /** 上游请求的分阶段耗时与连接信息;未经转发(mock、拦截等)时缺省 */
+  timing?: SessionTiming
+  /** 选用的上游路由:DIRECT 或不含凭据的代理地址 */
+  route?: string
   // 进程图标信息
   iconData?: string     // Base64编码的图标数据
   iconType?: string     // 图标类型 (ico, png, svg)
@@ -50,6 +64,43 @@ export interface HttpSession {
   iconCategory?: string // 图标类别 (browser, development, system, etc.)
 }
 
+// 客户端 ClientHello 指纹;原始列表按线上顺序,含 GREASE
+export interface TLSFingerprint {
+  ja3: string
+  ja3Hash: string
+  ja4: string
+  sni?: string
+  version: number
+  ciphers: number[]
+  extensions: number[]
+  alpn?: string[]
+  curves?: number[]
+  versions?: number[]
+}
+
+// 上游 TLS 连接摘要;chain 叶子在前,有效期为 RFC3339
+export interface UpstreamConn {
+  version: string
+  cipher: string
+  alpn?: string
+  chain?: { subject: string; issuer: string; dnsNames?: string[]; notBefore: string; notAfter: string; sha256: string }[]
+  verified: boolean
+  verifyError?: string
+}
+
+// 上游请求分阶段耗时(毫秒,口径同 HAR timings,-1 表示该阶段未发生);connect 不含 TLS
+export interface SessionTiming {
+  dns: number
+  connect: number
+  ssl: number
+  send: number
+  wait: number
+  receive: number
+  ttfb: number
+  reused: boolean
+  serverIp?: string
+}
+
 // WebSocket 类型
 export interface WebSocketMessage {
   id: string
@@ -62,6 +113,8 @@ export interface WebSocketMessage {
   binary?: boolean
   timestamp: string
   size: number
+  /** 经注入 / 重发发出，而非两端实际发送 */
+  injected?: boolean
 }
 
 export interface WebSocketSession {
@@ -88,7 +141,7 @@ export interface WebSocketSession {
 }
 
 // 双向流类型(SSE / gRPC / 分块流)。StreamSession.id == 所属 HTTP Flow 的 id。
-export type StreamKind = 'sse' | 'grpc' | 'chunk'
+export type StreamKind = 'sse' | 'grpc' | 'grpc-web' | 'connect' | 'chunk'
 
 export interface StreamMessage {
   id: string
@@ -97,9 +150,15 @@ export interface StreamMessage {
   kind: StreamKind
   /** SSE 的 event 名;gRPC/chunk 为空 */
   eventType?: string
+  /** gRPC:压缩帧的 grpc-encoding(gzip/deflate/zstd);data 为解压后的载荷 */
+  encoding?: string
   /** 文本按原文;二进制(binary=true)为 base64 */
   data: string
   binary?: boolean
+  /** gRPC:protobuf 解码后的 JSON 视图;解不了时缺省 */
+  json?: string
+  /** gRPC:视图的消息类型全名;无描述符时为 'wire'(线缆格式,键为「字段号:类型」) */
+  schema?: string
   timestamp: string
   seq: number
   size: number
@@ -253,6 +312,8 @@ export type ConditionType =
   | 'time_of_day' | 'day_of_week'
   // 其他
   | 'client_ip' | 'server_ip' | 'user_agent'
+  // 客户端 TLS 指纹(ja3 为 MD5,ja3_full 为原文)
+  | 'ja3' | 'ja3_full' | 'ja4' | 'tls_sni' | 'tls_alpn'
 
 // 操作符类型
 export type ConditionOperator = 
@@ -34,6 +34,21 @@ function queryArray(url: string): { name: string; value: string }[] {
   return out
 }
 
+/** HAR timings:有分阶段计时时按阶段填写(HAR 的 connect 含 ssl),否则整段记为 wait。 */
+function harTimings(r: TrafficRow) {
+  const tm = r.timing
+  if (!tm) return { send: 0, wait: r.durationMs ?? 0, receive: 0 }
+  return {
+    blocked: -1,
+    dns: tm.dns,
+    connect: tm.connect < 0 ? -1 : tm.connect + Math.max(tm.ssl, 0),
+    ssl: tm.ssl,
+    send: Math.max(tm.send, 0),
+    wait: Math.max(tm.wait, 0),
+    receive: Math.max(tm.receive, 0),
+  }
+}
+
 /** 把流量行序列化为 HAR 1.2 并触发下载（仅含 HTTP 行；WS 不适合 HAR）。 */
 export function exportHar(rows: TrafficRow[]): void {
   const httpRows = rows.filter((r) => r.kind === 'http')
@@ -72,7 +87,8 @@ export function exportHar(rows: TrafficRow[]): void {
         bodySize: resBodyBytes,
       },
       cache: {},
-      timings: { send: 0, wait: r.durationMs ?? 0, receive: 0 },
+      timings: harTimings(r),
+      ...(r.timing?.serverIp ? { serverIPAddress: r.timing.serverIp } : {}),
       // clientIP 是「下游客户端」地址，并非 HAR 规范的 serverIPAddress（上游服务器），用自定义字段避免误读
       ...(r.clientIP ? { _clientIPAddress: r.clientIP } : {}),
     }
@@ -162,8 +162,9 @@ export function methodText(method: string): string {
   }
 }
 
-export function statusLabel(row: Pick<TrafficRow, 'state' | 'status' | 'blocked'>): string {
+export function statusLabel(row: Pick<TrafficRow, 'state' | 'status' | 'blocked' | 'certRejected'>): string {
   if (row.blocked) return 'BLOCKED'
+  if (row.certRejected) return 'CERT'
   if (row.state === 'pending') return '···'
   if (row.state === 'error') return 'ERR'
   return row.status ? String(row.status) : '—'
@@ -213,6 +214,16 @@ export function toRowFromHttp(s: HttpSession, seq: number): TrafficRow {
     sizeBytes: s.response?.size,
     clientIP: s.request.clientIP,
     process: s.processName,
+    ja3: s.tls?.ja3Hash,
+    ja4: s.tls?.ja4,
+    upstreamJa4: s.upstreamTls?.ja4,
+    clientCert: s.clientCert && (s.clientCert.subject || s.clientCert.id.slice(0, 16)),
+    upstreamTls: s.upstreamConn && [s.upstreamConn.version, s.upstreamConn.cipher, s.upstreamConn.alpn].filter(Boolean).join(' · '),
+    upstreamChain: s.upstreamConn?.chain?.map((c) => c.subject),
+    upstreamVerifyError: s.upstreamConn?.verified ? s.upstreamConn.verifyError ?? '' : undefined,
+    certRejected: s.certRejected,
+    timing: s.timing,
+    route: s.route,
     iconData: s.iconData,
     iconType: s.iconType,
     startedAt,
@@ -19,7 +19,7 @@ import type {
 
 /* ───── 本地(UI)模型 ───── */
 
-export type ConditionType = 'url' | 'host' | 'path' | 'method' | 'reqHeader' | 'status' | 'query'
+export type ConditionType = 'url' | 'host' | 'path' | 'method' | 'reqHeader' | 'status' | 'query' | 'ja3' | 'ja4'
 export type ConditionOp = 'eq' | 'contains' | 'regex' | 'prefix' | 'suffix' | 'ne'
 export type ActionType =
   | 'redirect'
@@ -79,6 +79,8 @@ const COND_TYPE_TO_CANON: Record<ConditionType, CanonConditionType> = {
   reqHeader: 'request_header',
   status: 'response_status',
   query: 'url_query',
+  ja3: 'ja3',
+  ja4: 'ja4',
 }
 const COND_TYPE_FROM_CANON: Partial<Record<CanonConditionType, ConditionType>> = {
   url: 'url',
@@ -88,6 +90,8 @@ const COND_TYPE_FROM_CANON: Partial<Record<CanonConditionType, ConditionType>> =
   request_header: 'reqHeader',
   response_status: 'status',
   url_query: 'query',
+  ja3: 'ja3',
+  ja4: 'ja4',
 }
 
 const COND_OP_TO_CANON: Record<ConditionOp, ConditionOperator> = {
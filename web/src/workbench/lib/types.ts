@@ -7,6 +7,21 @@ export type RowKind = 'http' | 'ws'
 
 export type RowState = 'pending' | 'completed' | 'error'
 
+/** 上游请求分阶段耗时(毫秒,口径同 HAR timings);connect 仅 TCP,不含 TLS */
+export interface RowTiming {
+  dns: number
+  connect: number
+  ssl: number
+  send: number
+  wait: number
+  receive: number
+  ttfb: number
+  /** 复用了已有连接(此时 dns / connect / ssl 为 -1) */
+  reused: boolean
+  /** 实际连接的对端 IP(经上游代理时为代理) */
+  serverIp?: string
+}
+
 export type ContentKind =
   | 'json'
   | 'html'
@@ -52,6 +67,25 @@ export interface TrafficRow {
   sizeBytes?: number
   clientIP?: string
   process?: string
+  /** 客户端 TLS 指纹(仅 HTTPS):JA3 取 MD5 */
+  ja3?: string
+  ja4?: string
+  /** 上游连接实际发出的 ClientHello 的 JA4 */
+  upstreamJa4?: string
+  /** 出示给上游的客户端证书主题(mTLS) */
+  clientCert?: string
+  /** 上游 TLS 连接:版本 · 套件 · ALPN */
+  upstreamTls?: string
+  /** 上游证书链主题,叶子在前 */
+  upstreamChain?: string[]
+  /** 上游证书校验结论:undefined 未校验,'' 通过,否则为失败原因 */
+  upstreamVerifyError?: string
+  /** 上游证书未通过校验、请求被拒 */
+  certRejected?: boolean
+  /** 上游请求分阶段耗时与连接信息(-1 表示该阶段未发生) */
+  timing?: RowTiming
+  /** 选用的上游路由:DIRECT 或代理地址 */
+  route?: string
   iconData?: string
   iconType?: string
   /** 起始时间（epoch ms），用于排序与展示 */
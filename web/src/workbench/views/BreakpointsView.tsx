@@ -1,9 +1,9 @@
 import { useEffect, useRef, useState } from 'react'
 import { useTranslation } from 'react-i18next'
 import { Events } from '@wailsio/runtime'
-import { ArrowDownToLine, ArrowUpFromLine, CircleDot, Plus, Trash2 } from 'lucide-react'
+import { ArrowDownToLine, ArrowLeftRight, ArrowUpFromLine, CircleDot, Plus, Trash2 } from 'lucide-react'
 import { Bridge, type BreakRule } from '@/lib/bridge'
-import { Button, Field, Panel, TextInput, Toggle } from '../ui/controls'
+import { Button, Field, Panel, Select, TextInput, Toggle } from '../ui/controls'
 import { Chip, cx, EmptyState, IconButton, MethodTag } from '../ui/primitives'
 import { PageShell } from './PageShell'
 
@@ -24,6 +24,50 @@ interface RawFlow {
   pausedAt?: Phase
 }
 
+/** 后端推送的暂停中的 WebSocket 消息（pausedAt 恒为 'message'，data 为 base64）。 */
+interface RawWSMessage {
+  id: string
+  url?: string
+  direction: 'client->server' | 'server->client'
+  type: string
+  data?: string | null
+  pausedAt: 'message'
+}
+
+interface PausedMessage {
+  id: string
+  url: string
+  direction: RawWSMessage['direction']
+  binary: boolean
+  /** 文本消息为原文，二进制消息为 base64。 */
+  data: string
+  size: number
+}
+
+function toPausedMessage(m: RawWSMessage): PausedMessage | null {
+  if (!m || !m.id) return null
+  const b64 = m.data ?? ''
+  let bytes = new Uint8Array()
+  try {
+    bytes = Uint8Array.from(atob(b64), (c) => c.charCodeAt(0))
+  } catch {
+    /* 非法 base64，按空内容展示 */
+  }
+  const binary = m.type !== 'text'
+  return {
+    id: m.id,
+    url: m.url ?? '',
+    direction: m.direction,
+    binary,
+    data: binary ? b64 : new TextDecoder().decode(bytes),
+    size: bytes.length,
+  }
+}
+
+function isWSMessage(x: unknown): x is RawWSMessage {
+  return !!x && (x as { pausedAt?: string }).pausedAt === 'message'
+}
+
 function toPaused(f: RawFlow): PausedItem | null {
   if (!f || !f.id) return null
   return {
@@ -39,7 +83,9 @@ export function BreakpointsView() {
   const [reqBreak, setReqBreak] = useState(false)
   const [respBreak, setRespBreak] = useState(false)
   const [rules, setRules] = useState<BreakRule[]>([])
+  const [wsBreak, setWsBreak] = useState(false)
   const [paused, setPaused] = useState<PausedItem[]>([])
+  const [messages, setMessages] = useState<PausedMessage[]>([])
 
   // 初始加载 + 事件订阅。
   useEffect(() => {
@@ -52,6 +98,9 @@ export function BreakpointsView() {
         }
       })
       .catch(() => {})
+    Bridge.getWebSocketBreak()
+      .then((on) => alive && setWsBreak(!!on))
+      .catch(() => {})
     Bridge.getBreakRules()
       .then((rs) => alive && rs && setRules(rs))
       .catch(() => {})
@@ -67,11 +116,27 @@ export function BreakpointsView() {
         })
       })
       .catch(() => {})
+    Bridge.getWSBreakpoints()
+      .then((list) => {
+        if (!alive || !list) return
+        const snapshot = (list as RawWSMessage[]).map(toPausedMessage).filter((x): x is PausedMessage => x !== null)
+        setMessages((prev) => {
+          const byId = new Map(prev.map((m) => [m.id, m]))
+          for (const item of snapshot) if (!byId.has(item.id)) byId.set(item.id, item)
+          return [...byId.values()]
+        })
+      })
+      .catch(() => {})
 
     const offs: Array<() => void> = []
     try {
       offs.push(
         Events.On('breakpoint_hit', (e) => {
+          if (isWSMessage(e.data)) {
+            const msg = toPausedMessage(e.data)
+            if (msg) setMessages((ms) => (ms.some((m) => m.id === msg.id) ? ms : [...ms, msg]))
+            return
+          }
           const item = toPaused(e.data as RawFlow)
           if (!item) return
           setPaused((ps) =>
@@ -81,8 +146,9 @@ export function BreakpointsView() {
       )
       offs.push(
         Events.On('breakpoint_resolved', (e) => {
-          const f = e.data as RawFlow
-          if (f?.id) setPaused((ps) => ps.filter((p) => p.id !== f.id))
+          const f = e.data as RawFlow | RawWSMessage
+          if (isWSMessage(f)) setMessages((ms) => ms.filter((m) => m.id !== f.id))
+          else if (f?.id) setPaused((ps) => ps.filter((p) => p.id !== f.id))
         }),
       )
     } catch {
@@ -107,6 +173,11 @@ export function BreakpointsView() {
     Bridge.setGlobalBreak(onReq, onResp).catch(() => {})
   }
 
+  const setWebSocket = (on: boolean) => {
+    setWsBreak(on)
+    Bridge.setWebSocketBreak(on).catch(() => {})
+  }
+
   // URL 断点规则。
   const addRule = async () => {
     // 用具体的占位 URL（带 * 通配，只匹配 example.com）而非裸 'https://'：
@@ -117,7 +188,11 @@ export function BreakpointsView() {
   const patchRule = (rule: BreakRule, patch: Partial<BreakRule>) => {
     const next = { ...rule, ...patch }
     setRules((rs) => rs.map((r) => (r.id === rule.id ? next : r)))
-    Bridge.updateBreakRule(next.id, next.url, next.onRequest, next.onResponse, next.enabled).catch(() => {})
+    if ('onWebSocket' in patch || 'direction' in patch || 'content' in patch) {
+      Bridge.setBreakRuleWebSocket(next.id, next.onWebSocket, next.direction ?? '', next.content ?? '').catch(() => {})
+    } else {
+      Bridge.updateBreakRule(next.id, next.url, next.onRequest, next.onResponse, next.enabled).catch(() => {})
+    }
   }
   const removeRule = (id: string) => {
     setRules((rs) => rs.filter((r) => r.id !== id))
@@ -134,6 +209,16 @@ export function BreakpointsView() {
     Bridge.abortBreakpoint(id).catch(() => {})
   }
 
+  // 暂停的 WebSocket 消息：内容未改则原样放行（传 null）。
+  const forwardMessage = (msg: PausedMessage, data: string) => {
+    setMessages((ms) => ms.filter((m) => m.id !== msg.id))
+    Bridge.resumeWSBreakpoint(msg.id, data === msg.data ? null : data, msg.binary).catch(() => {})
+  }
+  const dropMessage = (id: string) => {
+    setMessages((ms) => ms.filter((m) => m.id !== id))
+    Bridge.abortBreakpoint(id).catch(() => {})
+  }
+
   return (
     <PageShell icon={CircleDot} title={t('breakpoints.title')} subtitle={t('breakpoints.subtitle')}>
       {/* 全局断点 */}
@@ -144,6 +229,9 @@ export function BreakpointsView() {
         <Field label={t('breakpoints.global.responseLabel')} hint={t('breakpoints.global.responseHint')}>
           <Toggle checked={respBreak} onChange={(v) => setGlobal(reqBreak, v)} />
         </Field>
+        <Field label={t('breakpoints.global.wsLabel')} hint={t('breakpoints.global.wsHint')}>
+          <Toggle checked={wsBreak} onChange={setWebSocket} />
+        </Field>
       </Panel>
 
       {/* 断点规则 */}
@@ -180,9 +268,9 @@ export function BreakpointsView() {
       <Panel
         title={t('breakpoints.paused.title')}
         icon={<CircleDot className="h-4 w-4" />}
-        right={<Chip count={paused.length}>{t('breakpoints.paused.waiting')}</Chip>}
+        right={<Chip count={paused.length + messages.length}>{t('breakpoints.paused.waiting')}</Chip>}
       >
-        {paused.length === 0 ? (
+        {paused.length + messages.length === 0 ? (
           <div className="px-3 py-8">
             <EmptyState
               icon={<CircleDot className="h-7 w-7" />}
@@ -191,14 +279,24 @@ export function BreakpointsView() {
             />
           </div>
         ) : (
-          paused.map((item) => (
-            <PausedRow
-              key={item.id}
-              item={item}
-              onResolve={() => resolvePaused(item.id)}
-              onAbort={() => abortPaused(item.id)}
-            />
-          ))
+          <>
+            {paused.map((item) => (
+              <PausedRow
+                key={item.id}
+                item={item}
+                onResolve={() => resolvePaused(item.id)}
+                onAbort={() => abortPaused(item.id)}
+              />
+            ))}
+            {messages.map((msg) => (
+              <PausedMessageRow
+                key={msg.id}
+                msg={msg}
+                onForward={(data) => forwardMessage(msg, data)}
+                onDrop={() => dropMessage(msg.id)}
+              />
+            ))}
+          </>
         )}
       </Panel>
     </PageShell>
@@ -218,51 +316,97 @@ function RuleRow({
 }) {
   const { t } = useTranslation()
   const [url, setUrl] = useState(rule.url)
+  const [content, setContent] = useState(rule.content ?? '')
   const timer = useRef<ReturnType<typeof setTimeout> | undefined>(undefined)
-  useEffect(() => () => clearTimeout(timer.current), [])
+  const contentTimer = useRef<ReturnType<typeof setTimeout> | undefined>(undefined)
+  useEffect(
+    () => () => {
+      clearTimeout(timer.current)
+      clearTimeout(contentTimer.current)
+    },
+    [],
+  )
 
   const onUrl = (v: string) => {
     setUrl(v)
     clearTimeout(timer.current)
     timer.current = setTimeout(() => onPatch({ url: v }), 400)
   }
+  const onContent = (v: string) => {
+    setContent(v)
+    clearTimeout(contentTimer.current)
+    contentTimer.current = setTimeout(() => onPatch({ content: v }), 400)
+  }
 
   return (
-    <div className={cx('flex items-center gap-2.5 px-3 py-2', !rule.enabled && 'opacity-70')}>
-      <Toggle checked={rule.enabled} onChange={(v) => onPatch({ enabled: v })} />
-      <TextInput
-        value={url}
-        onChange={(e) => onUrl(e.target.value)}
-        width="100%"
-        placeholder="https://example.com/*"
-        title={url}
-        className="flex-1 font-mono text-[11.5px]"
-      />
-      <button
-        type="button"
-        onClick={() => onPatch({ onRequest: !rule.onRequest })}
-        className={cx(
-          'shrink-0 rounded-full px-2 py-px text-[10px] font-medium transition-colors',
-          rule.onRequest ? 'bg-info/15 text-info' : 'bg-fg-faint/10 text-fg-faint hover:text-fg',
-        )}
-        title={t('breakpoints.rules.requestPhaseTitle')}
-      >
-        {t('breakpoints.phase.request')}
-      </button>
-      <button
-        type="button"
-        onClick={() => onPatch({ onResponse: !rule.onResponse })}
-        className={cx(
-          'shrink-0 rounded-full px-2 py-px text-[10px] font-medium transition-colors',
-          rule.onResponse ? 'bg-iris/15 text-iris' : 'bg-fg-faint/10 text-fg-faint hover:text-fg',
-        )}
-        title={t('breakpoints.rules.responsePhaseTitle')}
-      >
-        {t('breakpoints.phase.response')}
-      </button>
-      <IconButton size="sm" tone="danger" onClick={onRemove} title={t('breakpoints.rules.delete')}>
-        <Trash2 className="h-3.5 w-3.5" />
-      </IconButton>
+    <div className={cx('px-3 py-2', !rule.enabled && 'opacity-70')}>
+      <div className="flex items-center gap-2.5">
+        <Toggle checked={rule.enabled} onChange={(v) => onPatch({ enabled: v })} />
+        <TextInput
+          value={url}
+          onChange={(e) => onUrl(e.target.value)}
+          width="100%"
+          placeholder="https://example.com/*"
+          title={url}
+          className="flex-1 font-mono text-[11.5px]"
+        />
+        <button
+          type="button"
+          onClick={() => onPatch({ onRequest: !rule.onRequest })}
+          className={cx(
+            'shrink-0 rounded-full px-2 py-px text-[10px] font-medium transition-colors',
+            rule.onRequest ? 'bg-info/15 text-info' : 'bg-fg-faint/10 text-fg-faint hover:text-fg',
+          )}
+          title={t('breakpoints.rules.requestPhaseTitle')}
+        >
+          {t('breakpoints.phase.request')}
+        </button>
+        <button
+          type="button"
+          onClick={() => onPatch({ onResponse: !rule.onResponse })}
+          className={cx(
+            'shrink-0 rounded-full px-2 py-px text-[10px] font-medium transition-colors',
+            rule.onResponse ? 'bg-iris/15 text-iris' : 'bg-fg-faint/10 text-fg-faint hover:text-fg',
+          )}
+          title={t('breakpoints.rules.responsePhaseTitle')}
+        >
+          {t('breakpoints.phase.response')}
+        </button>
+        <button
+          type="button"
+          onClick={() => onPatch({ onWebSocket: !rule.onWebSocket })}
+          className={cx(
+            'shrink-0 rounded-full px-2 py-px text-[10px] font-medium transition-colors',
+            rule.onWebSocket ? 'bg-warn/15 text-warn' : 'bg-fg-faint/10 text-fg-faint hover:text-fg',
+          )}
+          title={t('breakpoints.rules.wsPhaseTitle')}
+        >
+          {t('breakpoints.phase.message')}
+        </button>
+        <IconButton size="sm" tone="danger" onClick={onRemove} title={t('breakpoints.rules.delete')}>
+          <Trash2 className="h-3.5 w-3.5" />
+        </IconButton>
+      </div>
+      {rule.onWebSocket && (
+        <div className="mt-1.5 flex items-center gap-2.5 pl-[46px]">
+          <Select
+            value={rule.direction ?? ''}
+            onChange={(e) => onPatch({ direction: (e.target.value || undefined) as BreakRule['direction'] })}
+            options={[
+              { value: '', label: t('breakpoints.rules.directionBoth') },
+              { value: 'client->server', label: t('breakpoints.rules.directionOutbound') },
+              { value: 'server->client', label: t('breakpoints.rules.directionInbound') },
+            ]}
+          />
+          <TextInput
+            value={content}
+            onChange={(e) => onContent(e.target.value)}
+            width="100%"
+            placeholder={t('breakpoints.rules.contentPlaceholder')}
+            className="flex-1 font-mono text-[11.5px]"
+          />
+        </div>
+      )}
     </div>
   )
 }
@@ -309,3 +453,60 @@ function PausedRow({
     </div>
   )
 }
+
+/* ───────────────────────── 暂停的 WebSocket 消息 ───────────────────────── */
+
+function PausedMessageRow({
+  msg,
+  onForward,
+  onDrop,
+}: {
+  msg: PausedMessage
+  onForward: (data: string) => void
+  onDrop: () => void
+}) {
+  const { t } = useTranslation()
+  const [data, setData] = useState(msg.data)
+  const outbound = msg.direction === 'client->server'
+  const DirIcon = outbound ? ArrowUpFromLine : ArrowDownToLine
+
+  return (
+    <div className="px-3 py-2.5">
+      <div className="flex items-center gap-2.5">
+        <span className="inline-flex w-12 shrink-0 items-center justify-center gap-1 rounded px-1 font-mono text-[10px] font-semibold text-warn">
+          <ArrowLeftRight className="h-3 w-3" />
+          WS
+        </span>
+        <span className="min-w-0 flex-1 truncate font-mono text-[11.5px] text-fg-muted" title={msg.url}>
+          {msg.url}
+        </span>
+        <span
+          className={cx(
+            'inline-flex shrink-0 items-center gap-1 rounded-full px-2 py-px text-[10px] font-medium',
+            outbound ? 'bg-info/15 text-info' : 'bg-iris/15 text-iris',
+          )}
+          title={outbound ? t('breakpoints.rules.directionOutbound') : t('breakpoints.rules.directionInbound')}
+        >
+          <DirIcon className="h-3 w-3" />
+          {t('breakpoints.paused.messageBytes', { size: msg.size })}
+        </span>
+        <div className="flex shrink-0 items-center gap-1.5">
+          <Button variant="primary" size="sm" onClick={() => onForward(data)}>
+            {t('breakpoints.paused.forward')}
+          </Button>
+          <Button variant="danger" size="sm" onClick={onDrop}>
+            {t('breakpoints.paused.drop')}
+          </Button>
+        </div>
+      </div>
+      <textarea
+        value={data}
+        onChange={(e) => setData(e.target.value)}
+        spellCheck={false}
+        rows={Math.min(8, Math.max(2, data.split('\n').length))}
+        title={msg.binary ? t('breakpoints.paused.binaryHint') : undefined}
+        className="mt-1.5 w-full resize-y rounded-wb border border-line bg-inset px-2 py-1 font-mono text-[11.5px] text-fg outline-none transition-colors focus:border-accent focus:bg-surface"
+      />
+    </div>
+  )
+}
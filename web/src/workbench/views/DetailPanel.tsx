@@ -1,5 +1,6 @@
 import { type PointerEvent as ReactPointerEvent, type ReactNode, useCallback, useState } from 'react'
 import { useTranslation } from 'react-i18next'
+import type { TFunction } from 'i18next'
 import { Check, Copy, Download, X } from 'lucide-react'
 import { usePrefs } from '../prefs'
 import { useElementSize } from '../lib/useElementSize'
@@ -20,7 +21,7 @@ import {
   statusLabel,
   statusTone,
 } from '../lib/format'
-import type { TrafficRow, Tone } from '../lib/types'
+import type { RowTiming, TrafficRow, Tone } from '../lib/types'
 import { cx } from '../ui/primitives'
 import { KVTable } from '../ui/controls'
 import { BodyViewer, RawCode, UrlHighlight } from './BodyViewer'
@@ -283,10 +284,33 @@ export function RequestPane({ row, onClose, showClose = true }: { row: TrafficRo
   )
 }
 
+function rowStateLabel(row: TrafficRow, t: TFunction): string {
+  if (row.certRejected) return t('detail.overview.stateCertRejected')
+  if (row.state === 'pending') return t('detail.overview.statePending')
+  return row.state === 'error' ? t('detail.overview.stateError') : t('detail.overview.stateDone')
+}
+
+/** 分阶段耗时表;-1(该阶段未发生)显示为 —。 */
+function timingRows(timing: RowTiming, t: TFunction): [string, string][] {
+  const phase = (ms: number) => (ms < 0 ? '—' : formatDuration(ms))
+  const rows: [string, string][] = [
+    [t('detail.overview.timingDns'), phase(timing.dns)],
+    [t('detail.overview.timingConnect'), phase(timing.connect)],
+    [t('detail.overview.timingTls'), phase(timing.ssl)],
+    [t('detail.overview.timingSend'), phase(timing.send)],
+    [t('detail.overview.timingWait'), phase(timing.wait)],
+    [t('detail.overview.timingReceive'), phase(timing.receive)],
+    ['TTFB', formatDuration(timing.ttfb)],
+    [t('detail.overview.connection'), timing.reused ? t('detail.overview.connReused') : t('detail.overview.connNew')],
+  ]
+  if (timing.serverIp) rows.push([t('detail.overview.serverIp'), timing.serverIp])
+  return rows
+}
+
 function RequestOverview({ row }: { row: TrafficRow }) {
   const { t } = useTranslation()
   const general: [string, string][] = [
-    [t('detail.overview.state'), row.state === 'pending' ? t('detail.overview.statePending') : row.state === 'error' ? t('detail.overview.stateError') : t('detail.overview.stateDone')],
+    [t('detail.overview.state'), rowStateLabel(row, t)],
     [t('detail.overview.method'), row.method],
     [t('detail.overview.scheme'), row.scheme.toUpperCase()],
     [t('detail.overview.statusCode'), `${statusLabel(row)} ${row.statusText ?? ''}`.trim()],
@@ -297,6 +321,16 @@ function RequestOverview({ row }: { row: TrafficRow }) {
     [t('detail.overview.duration'), formatDuration(row.durationMs)],
     [t('detail.overview.size'), formatSize(row.sizeBytes)],
   ]
+  if (row.ja3) general.push(['JA3', row.ja3])
+  if (row.ja4) general.push(['JA4', row.ja4])
+  if (row.upstreamJa4) general.push([t('detail.overview.upstreamJa4'), row.upstreamJa4])
+  if (row.clientCert) general.push([t('detail.overview.clientCert'), row.clientCert])
+  if (row.route) general.push([t('detail.overview.route'), row.route])
+  if (row.upstreamTls) general.push([t('detail.overview.upstreamTls'), row.upstreamTls])
+  if (row.upstreamChain?.length) general.push([t('detail.overview.upstreamChain'), row.upstreamChain.join(' ← ')])
+  if (row.upstreamVerifyError !== undefined) {
+    general.push([t('detail.overview.upstreamVerify'), row.upstreamVerifyError || t('detail.overview.upstreamVerifyOk')])
+  }
   return (
     <div className="h-full overflow-auto">
       <div className="border-b border-line px-3 py-2.5">
@@ -308,6 +342,12 @@ function RequestOverview({ row }: { row: TrafficRow }) {
         </div>
       )}
       <KVTable rows={general} />
+      {row.timing && (
+        <>
+          <GroupLabel>{t('detail.overview.timingGroup')}</GroupLabel>
+          <KVTable rows={timingRows(row.timing, t)} />
+        </>
+      )}
     </div>
   )
 }
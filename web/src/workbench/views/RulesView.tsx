@@ -47,6 +47,8 @@ const conditionTypeOptions = (t: TFunction): { value: ConditionType; label: stri
   { value: 'reqHeader', label: t('rules.cond.type.reqHeader') },
   { value: 'status', label: t('rules.cond.type.status') },
   { value: 'query', label: t('rules.cond.type.query') },
+  { value: 'ja3', label: 'JA3' },
+  { value: 'ja4', label: 'JA4' },
 ]
 
 const conditionOpOptions = (t: TFunction): { value: ConditionOp; label: string }[] => [
@@ -82,6 +84,8 @@ const CONDITION_TYPE_LABEL: Record<ConditionType, string> = {
   reqHeader: 'Header',
   status: 'Status',
   query: 'Query',
+  ja3: 'JA3',
+  ja4: 'JA4',
 }
 
 const ACTION_ICON: Record<ActionType, typeof Shuffle> = {
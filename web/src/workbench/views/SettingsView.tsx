@@ -5,14 +5,23 @@ import {
   AppWindow,
   Database,
   Eraser,
+  FileCode,
+  Fingerprint,
   Gauge,
   Info,
   Network,
   Palette,
+  Plus,
+  RotateCcw,
+  Route,
   ShieldCheck,
+  ShieldOff,
+  Signpost,
   SlidersHorizontal,
+  Trash2,
+  Workflow,
 } from 'lucide-react'
-import { Bridge } from '@/lib/bridge'
+import { Bridge, type AppConfig, type AutoBypassHost, type LANAddr, type ProtoSet } from '@/lib/bridge'
 import { LANG_LABELS, SUPPORTED_LANGS, type Lang } from '@/i18n'
 import { changeLang } from '@/i18n/bridge'
 import {
@@ -28,6 +37,7 @@ import {
 import { Button, Field, Panel, Select, TextInput, Toggle } from '../ui/controls'
 import { cx } from '../ui/primitives'
 import { APP_VERSION, RELEASES_URL, openExternal } from '../lib/links'
+import { readFileBase64 } from '../lib/files'
 import { openAboutWindow, requestMainNav } from '../lib/windows'
 import { ConfirmDialog } from '../ui/ConfirmDialog'
 import { ThrottleDialog } from '../ui/ThrottleDialog'
@@ -47,7 +57,7 @@ function updateProxyAuth(patch: Record<string, unknown>) {
   return next
 }
 
-/** 主机清单编辑器:整行占位的多行输入,每行一条主机模式。用于解密范围的白/黑名单。 */
+/** 主机清单编辑器:整行占位的多行输入,每行一条主机模式。用于解密范围的白/黑名单;也用于粘贴 PEM。 */
 function HostListField({
   label,
   hint,
@@ -77,6 +87,472 @@ function HostListField({
   )
 }
 
+/** gRPC 解码面板:上传 protobuf 描述符集、开关服务端反射。二者都缺时消息按线缆格式展示。 */
+function GrpcDecodePanel() {
+  const { t } = useTranslation()
+  const [sets, setSets] = useState<ProtoSet[]>([])
+  const [reflection, setReflection] = useState(false)
+  const [importing, setImporting] = useState(false)
+  const [error, setError] = useState('')
+  const fileRef = useRef<HTMLInputElement>(null)
+
+  const refresh = () => {
+    Bridge.getProtoSets()
+      .then((list) => setSets(list ?? []))
+      .catch(() => {
+        /* 非 Wails / 未连接:保持空 */
+      })
+  }
+  useEffect(() => {
+    refresh()
+    Bridge.getConfig()
+      .then((cfg) => setReflection(Boolean(cfg?.grpcReflection)))
+      .catch(() => {})
+  }, [])
+
+  const changeReflection = (on: boolean) => {
+    setReflection(on)
+    Bridge.updateConfig({ grpcReflection: on })
+      .then((cfg) => setReflection(Boolean(cfg?.grpcReflection)))
+      .catch(() => setReflection(!on))
+  }
+
+  const onPick = async (file: File | undefined) => {
+    if (!file) return
+    setImporting(true)
+    setError('')
+    try {
+      await Bridge.importProtoSet(file.name.replace(/\.[^.]+$/, ''), await readFileBase64(file))
+      refresh()
+    } catch (e) {
+      setError(e instanceof Error ? e.message : String(e))
+    } finally {
+      setImporting(false)
+      if (fileRef.current) fileRef.current.value = ''
+    }
+  }
+
+  const doDelete = (name: string) => {
+    Bridge.deleteProtoSet(name)
+      .catch(() => {})
+      .finally(refresh)
+  }
+
+  return (
+    <Panel title={t('settings.grpc.title')} icon={<Workflow className="h-4 w-4" />}>
+      <Field label={t('settings.grpc.reflection')} hint={t('settings.grpc.reflectionHint')}>
+        <Toggle checked={reflection} onChange={changeReflection} />
+      </Field>
+      <Field label={t('settings.grpc.descriptorSets')} hint={t('settings.grpc.descriptorSetsHint')}>
+        <input
+          ref={fileRef}
+          type="file"
+          accept=".pb,.protoset,.desc,.bin"
+          className="hidden"
+          onChange={(e) => void onPick(e.target.files?.[0])}
+        />
+        <Button icon={<Plus className="h-3.5 w-3.5" />} onClick={() => fileRef.current?.click()} disabled={importing}>
+          {importing ? t('settings.grpc.importing') : t('settings.grpc.importBtn')}
+        </Button>
+      </Field>
+      {error && <div className="px-3 pb-1 text-2xs leading-relaxed text-danger">{error}</div>}
+      {sets.length > 0 && (
+        <div className="flex flex-col divide-y divide-line border-t border-line">
+          {sets.map((ps) => (
+            <div key={ps.name} className="flex items-center gap-3 px-3 py-2">
+              <div className="min-w-0 flex-1">
+                <div className="truncate font-mono text-[12.5px] text-fg">{ps.name}</div>
+                <div className="truncate text-2xs text-fg-faint" title={ps.services.join('\n')}>
+                  {t('settings.grpc.services', { count: ps.services.length })} · {ps.services.join(', ')}
+                </div>
+              </div>
+              <Button variant="danger" size="sm" icon={<Trash2 className="h-3.5 w-3.5" />} onClick={() => doDelete(ps.name)}>
+                {t('settings.grpc.deleteBtn')}
+              </Button>
+            </div>
+          ))}
+        </div>
+      )}
+    </Panel>
+  )
+}
+
+/** 上游 TLS 镜像面板:按客户端的 ClientHello 参数与上游握手,主机范围留空则对全部主机生效。 */
+function TlsMirrorPanel() {
+  const { t } = useTranslation()
+  const [enabled, setEnabled] = useState(false)
+  const [hosts, setHosts] = useState('')
+
+  const apply = (cfg: AppConfig | undefined) => {
+    setEnabled(Boolean(cfg?.tlsMirror))
+    setHosts((cfg?.tlsMirrorHosts ?? []).join('\n'))
+  }
+  useEffect(() => {
+    Bridge.getConfig()
+      .then(apply)
+      .catch(() => {})
+  }, [])
+
+  const changeEnabled = (on: boolean) => {
+    setEnabled(on)
+    Bridge.updateConfig({ tlsMirror: on })
+      .then(apply)
+      .catch(() => setEnabled(!on))
+  }
+  const commitHosts = () => {
+    const list = hosts
+      .split('\n')
+      .map((h) => h.trim())
+      .filter(Boolean)
+    Bridge.updateConfig({ tlsMirrorHosts: list })
+      .then(apply)
+      .catch(() => {})
+  }
+
+  return (
+    <Panel title={t('settings.tlsMirror.title')} icon={<Fingerprint className="h-4 w-4" />}>
+      <Field label={t('settings.tlsMirror.enabled')} hint={t('settings.tlsMirror.enabledHint')}>
+        <Toggle checked={enabled} onChange={changeEnabled} />
+      </Field>
+      {enabled && (
+        <div onBlur={commitHosts}>
+          <HostListField
+            label={t('settings.tlsMirror.hosts')}
+            hint={t('settings.tlsMirror.hostsHint')}
+            value={hosts}
+            onChange={setHosts}
+            placeholder={t('settings.decrypt.hostListPlaceholder')}
+          />
+        </div>
+      )}
+    </Panel>
+  )
+}
+
+/** 上游证书校验面板:选择校验模式(off / warn / enforce),并可追加信任的根证书(PEM)。 */
+function UpstreamVerifyPanel() {
+  const { t } = useTranslation()
+  const [mode, setMode] = useState<NonNullable<AppConfig['upstreamVerify']>>('warn')
+  const [roots, setRoots] = useState('')
+
+  const apply = (cfg: AppConfig | undefined) => {
+    setMode(cfg?.upstreamVerify ?? 'warn')
+    setRoots(cfg?.upstreamTrustRoots ?? '')
+  }
+  useEffect(() => {
+    Bridge.getConfig()
+      .then(apply)
+      .catch(() => {})
+  }, [])
+
+  const changeMode = (next: NonNullable<AppConfig['upstreamVerify']>) => {
+    const prev = mode
+    setMode(next)
+    Bridge.updateConfig({ upstreamVerify: next })
+      .then(apply)
+      .catch(() => setMode(prev))
+  }
+  const commitRoots = () => {
+    Bridge.updateConfig({ upstreamTrustRoots: roots })
+      .then(apply)
+      .catch(() => {})
+  }
+
+  return (
+    <Panel title={t('settings.upstreamVerify.title')} icon={<ShieldCheck className="h-4 w-4" />}>
+      <Field label={t('settings.upstreamVerify.mode')} hint={t(`settings.upstreamVerify.modeHint.${mode}`)}>
+        <Select
+          value={mode}
+          onChange={(e) => changeMode(e.target.value as NonNullable<AppConfig['upstreamVerify']>)}
+          options={[
+            { value: 'off', label: t('settings.upstreamVerify.off') },
+            { value: 'warn', label: t('settings.upstreamVerify.warn') },
+            { value: 'enforce', label: t('settings.upstreamVerify.enforce') },
+          ]}
+        />
+      </Field>
+      {mode !== 'off' && (
+        <div onBlur={commitRoots}>
+          <HostListField
+            label={t('settings.upstreamVerify.roots')}
+            hint={t('settings.upstreamVerify.rootsHint')}
+            value={roots}
+            onChange={setRoots}
+            placeholder="-----BEGIN CERTIFICATE-----"
+          />
+        </div>
+      )}
+    </Panel>
+  )
+}
+
+/** 上游路由面板:按主机把请求分到直连、HTTP 代理或 SOCKS5 代理,都不命中时走上游代理设置。 */
+function UpstreamRoutesPanel() {
+  const { t } = useTranslation()
+  const [routes, setRoutes] = useState('')
+
+  useEffect(() => {
+    Bridge.getConfig()
+      .then((cfg) => setRoutes(cfg?.upstreamRoutes ?? ''))
+      .catch(() => {})
+  }, [])
+
+  const commit = () => {
+    Bridge.updateConfig({ upstreamRoutes: routes })
+      .then((cfg) => setRoutes(cfg?.upstreamRoutes ?? ''))
+      .catch(() => {})
+  }
+
+  return (
+    <Panel title={t('settings.upstreamRoutes.title')} icon={<Route className="h-4 w-4" />}>
+      <div onBlur={commit}>
+        <HostListField
+          label={t('settings.upstreamRoutes.table')}
+          hint={t('settings.upstreamRoutes.hint')}
+          value={routes}
+          onChange={setRoutes}
+          placeholder={'*.corp.example.com,10.0.0.0/8  DIRECT\n*.example.org  socks5://127.0.0.1:1080'}
+        />
+      </div>
+    </Panel>
+  )
+}
+
+/** 主机映射面板:拨号上游时把命中的主机改连指定地址,类似 hosts 文件,请求本身不变。 */
+function HostMappingsPanel() {
+  const { t } = useTranslation()
+  const [mappings, setMappings] = useState('')
+
+  useEffect(() => {
+    Bridge.getConfig()
+      .then((cfg) => setMappings(cfg?.hostMappings ?? ''))
+      .catch(() => {})
+  }, [])
+
+  const commit = () => {
+    Bridge.updateConfig({ hostMappings: mappings })
+      .then((cfg) => setMappings(cfg?.hostMappings ?? ''))
+      .catch(() => {})
+  }
+
+  return (
+    <Panel title={t('settings.hostMappings.title')} icon={<Signpost className="h-4 w-4" />}>
+      <div onBlur={commit}>
+        <HostListField
+          label={t('settings.hostMappings.table')}
+          hint={t('settings.hostMappings.hint')}
+          value={mappings}
+          onChange={setMappings}
+          placeholder={'api.example.com  127.0.0.1:8443\n*.staging.example.com  10.0.0.5'}
+        />
+      </div>
+    </Panel>
+  )
+}
+
+/** PAC 面板:在代理端口提供代理自动配置脚本,按主机范围决定哪些流量经 Sniffy,并可让系统代理改用它。 */
+function PacPanel() {
+  const { t } = useTranslation()
+  const [enabled, setEnabled] = useState(false)
+  const [include, setInclude] = useState('')
+  const [exclude, setExclude] = useState('')
+  const [systemPac, setSystemPac] = useState(false)
+  const [pacUrl, setPacUrl] = useState('')
+
+  const apply = (cfg: AppConfig | undefined) => {
+    setEnabled(Boolean(cfg?.pac))
+    setInclude((cfg?.pacInclude ?? []).join('\n'))
+    setExclude((cfg?.pacExclude ?? []).join('\n'))
+    setSystemPac(Boolean(cfg?.systemProxyPac))
+  }
+  useEffect(() => {
+    Bridge.getConfig()
+      .then(apply)
+      .catch(() => {})
+    // 给其它设备填写的地址取默认出站网卡的内网 IP;取不到时退回回环地址。
+    Promise.all([Bridge.getListenInfo(), Bridge.getLanIPs().catch((): LANAddr[] => [])])
+      .then(([info, ips]) => {
+        const ip = (ips.find((a) => a.preferred) ?? ips[0])?.ip ?? '127.0.0.1'
+        setPacUrl(`http://${ip}:${info.port}/proxy.pac`)
+      })
+      .catch(() => {})
+  }, [])
+
+  const update = (patch: Record<string, unknown>) => {
+    Bridge.updateConfig(patch)
+      .then(apply)
+      .catch(() => {})
+  }
+  const hostList = (text: string) =>
+    text
+      .split('\n')
+      .map((h) => h.trim())
+      .filter(Boolean)
+
+  return (
+    <Panel title={t('settings.pac.title')} icon={<FileCode className="h-4 w-4" />}>
+      <Field label={t('settings.pac.enabled')} hint={t('settings.pac.enabledHint')}>
+        <Toggle
+          checked={enabled}
+          onChange={(on) => {
+            setEnabled(on)
+            update({ pac: on })
+          }}
+        />
+      </Field>
+      {enabled && (
+        <>
+          {pacUrl && (
+            <Field label={t('settings.pac.url')} hint={t('settings.pac.urlHint')}>
+              <TextInput value={pacUrl} readOnly onFocus={(e) => e.target.select()} width={280} />
+            </Field>
+          )}
+          <div onBlur={() => update({ pacInclude: hostList(include), pacExclude: hostList(exclude) })}>
+            <HostListField
+              label={t('settings.pac.include')}
+              hint={t('settings.pac.includeHint')}
+              value={include}
+              onChange={setInclude}
+              placeholder={t('settings.decrypt.hostListPlaceholder')}
+            />
+            <HostListField
+              label={t('settings.pac.exclude')}
+              hint={t('settings.pac.excludeHint')}
+              value={exclude}
+              onChange={setExclude}
+              placeholder={t('settings.decrypt.hostListPlaceholder')}
+            />
+          </div>
+          <Field label={t('settings.pac.systemProxy')} hint={t('settings.pac.systemProxyHint')}>
+            <Toggle
+              checked={systemPac}
+              onChange={(on) => {
+                setSystemPac(on)
+                update({ systemProxyPac: on })
+              }}
+            />
+          </Field>
+        </>
+      )}
+    </Panel>
+  )
+}
+
+/** 自动绕过面板:握手反复失败(多为固定证书)的主机改为直通,列表可逐条或整体重置恢复解密。 */
+function AutoBypassPanel() {
+  const { t } = useTranslation()
+  const [cfg, setCfg] = useState<AppConfig>({})
+  const [threshold, setThreshold] = useState('')
+  const [windowSec, setWindowSec] = useState('')
+  const [hosts, setHosts] = useState<AutoBypassHost[]>([])
+
+  const apply = (c: AppConfig | undefined) => {
+    setCfg(c ?? {})
+    setThreshold(String(c?.autoBypassThreshold ?? ''))
+    setWindowSec(String(c?.autoBypassWindowSec ?? ''))
+  }
+  const refresh = () => {
+    Bridge.getAutoBypassHosts()
+      .then((list) => setHosts(list ?? []))
+      .catch(() => {
+        /* 非 Wails / 未连接:保持空 */
+      })
+  }
+  useEffect(() => {
+    Bridge.getConfig()
+      .then(apply)
+      .catch(() => {})
+    refresh()
+  }, [])
+
+  const update = (patch: Partial<AppConfig>) => {
+    Bridge.updateConfig(patch)
+      .then(apply)
+      .catch(() => {})
+  }
+  const commitNumber = (key: 'autoBypassThreshold' | 'autoBypassWindowSec', raw: string) => {
+    const n = Number(raw)
+    if (Number.isInteger(n) && n > 0 && n !== cfg[key]) update({ [key]: n })
+    else apply(cfg)
+  }
+  const doReset = (host: string) => {
+    Bridge.resetAutoBypass(host)
+      .catch(() => {})
+      .finally(refresh)
+  }
+
+  return (
+    <Panel title={t('settings.autoBypass.title')} icon={<ShieldOff className="h-4 w-4" />}>
+      <Field label={t('settings.autoBypass.enabled')} hint={t('settings.autoBypass.enabledHint')}>
+        <Toggle checked={Boolean(cfg.autoBypass)} onChange={(on) => update({ autoBypass: on })} />
+      </Field>
+      {cfg.autoBypass && (
+        <>
+          <Field label={t('settings.autoBypass.threshold')} hint={t('settings.autoBypass.thresholdHint')}>
+            <TextInput
+              type="number"
+              min={1}
+              max={100}
+              value={threshold}
+              onChange={(e) => setThreshold(e.target.value)}
+              onBlur={() => commitNumber('autoBypassThreshold', threshold)}
+              width={80}
+            />
+          </Field>
+          <Field label={t('settings.autoBypass.window')} hint={t('settings.autoBypass.windowHint')}>
+            <TextInput
+              type="number"
+              min={1}
+              max={86400}
+              value={windowSec}
+              onChange={(e) => setWindowSec(e.target.value)}
+              onBlur={() => commitNumber('autoBypassWindowSec', windowSec)}
+              width={80}
+            />
+          </Field>
+          <Field label={t('settings.autoBypass.persist')} hint={t('settings.autoBypass.persistHint')}>
+            <Toggle checked={Boolean(cfg.autoBypassPersist)} onChange={(on) => update({ autoBypassPersist: on })} />
+          </Field>
+        </>
+      )}
+      <Field label={t('settings.autoBypass.hosts', { count: hosts.length })} hint={t('settings.autoBypass.hostsHint')}>
+        <div className="flex gap-2">
+          <Button icon={<RotateCcw className="h-3.5 w-3.5" />} onClick={refresh}>
+            {t('settings.autoBypass.refreshBtn')}
+          </Button>
+          <Button
+            variant="danger"
+            icon={<Trash2 className="h-3.5 w-3.5" />}
+            onClick={() => doReset('')}
+            disabled={hosts.length === 0}
+          >
+            {t('settings.autoBypass.resetAllBtn')}
+          </Button>
+        </div>
+      </Field>
+      {hosts.length > 0 && (
+        <div className="flex flex-col divide-y divide-line border-t border-line">
+          {hosts.map((h) => (
+            <div key={h.host} className="flex items-center gap-3 px-3 py-2">
+              <div className="min-w-0 flex-1">
+                <div className="truncate font-mono text-[12.5px] text-fg">{h.host}</div>
+                {h.lastError && (
+                  <div className="truncate text-2xs text-fg-faint" title={h.lastError}>
+                    {t('settings.autoBypass.failures', { count: h.failures })} · {h.lastError}
+                  </div>
+                )}
+              </div>
+              <Button size="sm" icon={<RotateCcw className="h-3.5 w-3.5" />} onClick={() => doReset(h.host)}>
+                {t('settings.autoBypass.resetBtn')}
+              </Button>
+            </div>
+          ))}
+        </div>
+      )}
+    </Panel>
+  )
+}
+
 export function SettingsView() {
   const p = usePrefs()
   const set = p.set
@@ -346,7 +822,7 @@ export function SettingsView() {
         </Field>
         {p.upstream && (
           <>
-            <Field label={t('settings.proxy.upstreamAddr')}>
+            <Field label={t('settings.proxy.upstreamAddr')} hint={t('settings.proxy.upstreamAddrHint')}>
               <TextInput
                 value={p.upstreamAddr}
                 onChange={(e) => set({ upstreamAddr: e.target.value })}
@@ -445,6 +921,20 @@ export function SettingsView() {
         </Field>
       </Panel>
 
+      <AutoBypassPanel />
+
+      <TlsMirrorPanel />
+
+      <UpstreamVerifyPanel />
+
+      <UpstreamRoutesPanel />
+
+      <HostMappingsPanel />
+
+      <PacPanel />
+
+      <GrpcDecodePanel />
+
       <Panel title={t('settings.appearance.title')} icon={<Palette className="h-4 w-4" />}>
         <Field label={t('settings.language')}>
           <Select
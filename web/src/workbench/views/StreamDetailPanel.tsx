@@ -73,11 +73,11 @@ function hexDumpFromBase64(b64: string): string {
 
 const isBinary = (m: StreamMessage) => m.binary === true
 
-/** 消息的短标签:SSE 用 event 名(无则 SSE),gRPC 用 GRPC,chunk 用 DATA。 */
+/** 消息的短标签:SSE 用 event 名(无则 SSE),gRPC 类用流类型(尾部/结束帧用 TRAILERS/END),chunk 用 DATA。 */
 function msgTag(m: StreamMessage): string {
   if (m.kind === 'sse') return (m.eventType || 'message').toUpperCase()
-  if (m.kind === 'grpc') return 'GRPC'
-  return 'DATA'
+  if (m.kind === 'chunk') return 'DATA'
+  return (m.eventType || m.kind).toUpperCase()
 }
 
 const sseTagPalette = [
@@ -98,14 +98,16 @@ function msgTagClass(m: StreamMessage): string {
 }
 
 function previewText(m: StreamMessage): string {
+  if (m.json) return m.json.replace(/\s+/g, ' ').trim().slice(0, 400)
   if (isBinary(m)) return ''
   return m.data.replace(/\s+/g, ' ').trim().slice(0, 400)
 }
 
 /* ───────────────────────── 消息内容查看 ───────────────────────── */
 
-function MessageBody({ msg }: { msg: StreamMessage }) {
+function MessageBody({ msg, raw }: { msg: StreamMessage; raw: boolean }) {
   const { t } = useTranslation()
+  if (msg.json && !raw) return <BodyViewer body={msg.json} kind="json" />
   if (!msg.data) return <div className="px-3 py-6 text-center text-2xs text-fg-faint">{t('body.empty')}</div>
   if (isBinary(msg)) return <RawCode text={hexDumpFromBase64(msg.data)} />
   return <BodyViewer body={msg.data} kind={detectContentKind('text/plain', '', msg.data)} />
@@ -133,7 +135,7 @@ function MessageRow({ msg, selected, onClick }: { msg: StreamMessage; selected:
         {msgTag(msg)}
       </span>
       <span className="min-w-0 flex-1 truncate font-mono text-[11.5px] text-fg-muted">
-        {binary ? <span className="italic text-fg-faint">{t('detail.ws.binary')} · {formatSize(msg.size)}</span> : previewText(msg)}
+        {binary && !msg.json ? <span className="italic text-fg-faint">{t('detail.ws.binary')} · {formatSize(msg.size)}</span> : previewText(msg)}
       </span>
       <span className="shrink-0 font-mono text-[10px] tabular-nums text-fg-faint">{formatSize(msg.size)}</span>
       <span className="shrink-0 font-mono text-[10px] tabular-nums text-fg-faint">{formatClock(Date.parse(msg.timestamp) || undefined)}</span>
@@ -164,7 +166,13 @@ function MessageList({ messages, selectedId, onSelect }: { messages: StreamMessa
 
 /* ───────────────────────── 会话概览（未选中消息时展示） ───────────────────────── */
 
-const kindLabel: Record<string, string> = { sse: 'SSE', grpc: 'gRPC', chunk: 'Stream' }
+const kindLabel: Record<string, string> = {
+  sse: 'SSE',
+  grpc: 'gRPC',
+  'grpc-web': 'gRPC-Web',
+  connect: 'Connect',
+  chunk: 'Stream',
+}
 
 function SessionOverview({ session }: { session: StreamSession }) {
   const { t } = useTranslation()
@@ -192,6 +200,9 @@ function SessionOverview({ session }: { session: StreamSession }) {
 function MessageDetail({ msg }: { msg: StreamMessage }) {
   const { t } = useTranslation()
   const outbound = msg.direction === 'outbound'
+  // gRPC 消息默认展示 protobuf 解码后的 JSON 视图，可切回原始字节。
+  const [raw, setRaw] = useState(false)
+  const showJSON = !!msg.json && !raw
   return (
     <div className="flex min-h-0 flex-1 flex-col">
       <div className="flex h-8 shrink-0 items-center gap-2 border-b border-line bg-surface px-2.5">
@@ -199,13 +210,43 @@ function MessageDetail({ msg }: { msg: StreamMessage }) {
         <span className={cx('rounded-full px-2 py-[1px] font-mono text-2xs font-semibold', msgTagClass(msg))}>{msgTag(msg)}</span>
         <span className="font-mono text-2xs tabular-nums text-fg-faint">#{msg.seq}</span>
         <span className="font-mono text-2xs tabular-nums text-fg-faint">{formatSize(msg.size)}</span>
+        {msg.encoding && (
+          <span className="font-mono text-2xs text-fg-faint" title={t('detail.stream.encodingHint', { encoding: msg.encoding })}>
+            {msg.encoding}
+          </span>
+        )}
         <span className="font-mono text-2xs tabular-nums text-fg-faint">{formatClock(Date.parse(msg.timestamp) || undefined)}</span>
+        {msg.schema && (
+          <span
+            className="min-w-0 truncate font-mono text-2xs text-fg-muted"
+            title={msg.schema === 'wire' ? t('detail.stream.wireHint') : msg.schema}
+          >
+            {msg.schema === 'wire' ? t('detail.stream.wire') : msg.schema}
+          </span>
+        )}
         <div className="ml-auto flex items-center gap-1">
-          <CopyIcon text={msg.data} title={t('body.copy')} />
+          {msg.json && (
+            <div className="flex items-center rounded-control bg-elevated p-[2px] text-2xs">
+              {([false, true] as const).map((r) => (
+                <button
+                  key={String(r)}
+                  type="button"
+                  onClick={() => setRaw(r)}
+                  className={cx(
+                    'rounded-[4px] px-1.5 py-[1px] transition',
+                    raw === r ? 'bg-surface text-fg shadow-raise' : 'text-fg-faint hover:text-fg',
+                  )}
+                >
+                  {r ? t('detail.stream.raw') : t('detail.stream.decoded')}
+                </button>
+              ))}
+            </div>
+          )}
+          <CopyIcon text={showJSON ? msg.json! : msg.data} title={t('body.copy')} />
         </div>
       </div>
       <div className="min-h-0 flex-1">
-        <MessageBody msg={msg} />
+        <MessageBody msg={msg} raw={raw} />
       </div>
     </div>
   )
@@ -128,7 +128,7 @@ interface FlowField {
   nested?: boolean
 }
 
-const ALL_PHASES = ['request', 'response', 'ws', 'stream'] as const
+const ALL_PHASES = ['request', 'response', 'ws', 'stream', 'mqtt'] as const
 
 const FLOW_FIELDS: FlowField[] = [
   { name: 'id', ty: 'string', info: '本次请求/响应的流 ID(只读);WS/流消息钩子里恒为空', phases: ['request', 'response'] },
@@ -140,11 +140,18 @@ const FLOW_FIELDS: FlowField[] = [
   { name: 'body', ty: 'string', info: '请求体文本,可改写', phases: ['request', 'response'], tag: '请求' },
   { name: 'response', ty: 'object', info: '响应对象,onResponse 中可读改;构造伪造响应用 mock()', phases: ['response'], tag: '响应', nested: true },
   { name: 'process', ty: 'object', info: '发起进程 {name, pid, path},可能为空', phases: ['request', 'response'], tag: '进程', nested: true },
-  { name: 'direction', ty: 'string', info: 'client->server | server->client', phases: ['ws', 'stream'], tag: 'WS/流' },
+  { name: 'tls', ty: 'object', info: '客户端 TLS 指纹 {ja3, ja3Hash, ja4, …}(只读);明文请求为空', phases: ['request', 'response'], tag: 'TLS', nested: true },
+  { name: 'direction', ty: 'string', info: 'client->server | server->client', phases: ['ws', 'stream', 'mqtt'], tag: 'WS/流' },
   { name: 'type', ty: 'string', info: 'WS 帧类型:text|binary|close|ping|pong', phases: ['ws'], tag: 'WS' },
-  { name: 'data', ty: 'string', info: '消息负载文本,可就地改写', phases: ['ws', 'stream'], tag: 'WS/流' },
-  { name: 'kind', ty: 'string', info: '流类型:sse|grpc|chunk', phases: ['stream'], tag: '流' },
+  { name: 'data', ty: 'string', info: '消息负载文本,可就地改写', phases: ['ws', 'stream', 'mqtt'], tag: 'WS/流' },
+  { name: 'topic', ty: 'string', info: 'MQTT PUBLISH 的主题(只读)', phases: ['mqtt'], tag: 'MQTT' },
+  { name: 'qos', ty: 'number', info: 'MQTT QoS:0|1|2(只读)', phases: ['mqtt'], tag: 'MQTT' },
+  { name: 'retain', ty: 'boolean', info: 'MQTT retain 标志(只读)', phases: ['mqtt'], tag: 'MQTT' },
+  { name: 'kind', ty: 'string', info: '流类型:sse|grpc|grpc-web|connect|chunk', phases: ['stream'], tag: '流' },
   { name: 'eventType', ty: 'string', info: 'SSE 的 event 名;其余为空', phases: ['stream'], tag: 'SSE' },
+  { name: 'encoding', ty: 'string', info: 'gRPC 压缩帧的 grpc-encoding(gzip|deflate|zstd);data 已解压,改写后按原编码重新压缩', phases: ['stream'], tag: 'gRPC' },
+  { name: 'json', ty: 'string', info: 'gRPC 消息的 protobuf JSON 视图;改写后按 schema 重新编码', phases: ['stream'], tag: 'gRPC' },
+  { name: 'schema', ty: 'string', info: 'JSON 视图的消息类型全名;无描述符时为 wire(线缆格式)', phases: ['stream'], tag: 'gRPC' },
 ]
 
 const RESPONSE_FIELDS: Member[] = [
@@ -161,12 +168,24 @@ const PROCESS_FIELDS: Member[] = [
   prop('path', 'string', '可执行文件路径'),
 ]
 
+const TLS_FIELDS: Member[] = [
+  prop('ja3', 'string', 'JA3 原文:版本,套件,扩展,曲线,点格式'),
+  prop('ja3Hash', 'string', 'JA3 原文的 MD5'),
+  prop('ja4', 'string', 'JA4 指纹'),
+  prop('sni', 'string', 'ClientHello 中的 SNI'),
+  prop('version', 'number', '客户端支持的最高 TLS 版本(如 772 即 TLS 1.3)'),
+  prop('ciphers', 'number[]', '密码套件,线上原始顺序(含 GREASE)'),
+  prop('extensions', 'number[]', '扩展类型,线上原始顺序(含 GREASE)'),
+  prop('alpn', 'string[]', '客户端提议的 ALPN'),
+]
+
 /** 钩子函数名 → 内部阶段标识(与 plugin.go 的 __PHASE__ 一致)。 */
 const HOOK_PHASE: Record<string, string> = {
   onRequest: 'request',
   onResponse: 'response',
   onWebSocketMessage: 'ws',
   onStreamMessage: 'stream',
+  onMQTTMessage: 'mqtt',
 }
 
 const HOOK_SNIPPETS: Completion[] = [
@@ -198,6 +217,13 @@ const HOOK_SNIPPETS: Completion[] = [
     type: 'function',
     boost: 17,
   }),
+  snippetCompletion('function onMQTTMessage(flow) {\n\t${}\n}', {
+    label: 'onMQTTMessage',
+    detail: '钩子 (flow)',
+    info: '每条 MQTT PUBLISH 调用;可改 flow.data、abort() 丢弃',
+    type: 'function',
+    boost: 16,
+  }),
 ]
 
 /**
@@ -408,6 +434,7 @@ function membersForBase(base: string, context: CompletionContext): Completion[]
   if (base === 'flow') return flowMembers(enclosingPhase(context))
   if (base === 'flow.response') return RESPONSE_FIELDS.map(memberCompletion)
   if (base === 'flow.process') return PROCESS_FIELDS.map(memberCompletion)
+  if (base === 'flow.tls') return TLS_FIELDS.map(memberCompletion)
   const ns = NAMESPACES[base]
   if (ns) return ns.map(memberCompletion)
   const es = ES_GLOBALS[base]
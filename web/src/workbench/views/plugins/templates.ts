@@ -29,7 +29,7 @@ export const PLUGIN_TEMPLATES: PluginTemplate[] = [
     labelKey: 'plugins.new.tpl.blank',
     descKey: 'plugins.new.tplDesc.blank',
     source: `// 在此实现你的钩子。可用钩子与 API 见文档 docs/plugins-helpers.md。
-// onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg)
+// onRequest(flow) / onResponse(flow) / onWebSocketMessage(msg) / onStreamMessage(msg) / onMQTTMessage(msg)
 // 处置：mock({status,headers,body}) / abort({status,reason}) / setBreakpoint()
 // 宿主：console.*, store.get/set, settings, notify(title,msg)
 // 助手：base64.*, hex.*, crypto.*, jwt.*, json.*, time.*, url.parse, query.*, header.*, uuid()
@@ -293,12 +293,16 @@ function onWebSocketMessage(msg) {
     labelKey: 'plugins.new.tpl.stream',
     descKey: 'plugins.new.tplDesc.stream',
     source: `// 观察 / 改写流式响应（SSE / gRPC / 分块 JSON）。
-// msg.kind: 'sse' | 'grpc' | 'chunk'；msg.data 是去掉协议外壳的纯载荷。
+// msg.kind: 'sse' | 'grpc' | 'grpc-web' | 'connect' | 'chunk'；msg.data 是去掉协议外壳的纯载荷。
 // SSE 的 msg.eventType 仅在事件带 event: 字段时非空。
-// 同 WebSocket：gRPC 等二进制流经 JSON 边界会被破坏，本模板跳过 grpc；
-// 若要处理二进制流请改用白名单避开，切勿全局启用。
+// gRPC / gRPC-Web / Connect 的 msg.data 通常是二进制 protobuf（+json 编解码时即 JSON 文本），请改读写 msg.json（解码后的 JSON 视图，
+// msg.schema 为消息类型；未上传描述符时为 'wire' 线缆格式），改写后由引擎重新编码。
 function onStreamMessage(msg) {
-  if (msg.kind === 'grpc') return
+  if (msg.kind === 'grpc' || msg.kind === 'grpc-web' || msg.kind === 'connect') {
+    console.log('[' + msg.kind + ']', msg.schema || '', msg.json || '')
+    // const m = JSON.parse(msg.json); m.name = 'bar'; msg.json = JSON.stringify(m)
+    return
+  }
   console.log('[' + msg.kind + ']', msg.eventType || '', msg.data)
 
   // 改写示例（SSE 只需写纯载荷，event:/data: 外壳由引擎补全）：